@@ -49,6 +49,7 @@ import (
 	"github.com/Jeffail/benthos/lib/ratelimit"
 	"github.com/Jeffail/benthos/lib/stream"
 	strmmgr "github.com/Jeffail/benthos/lib/stream/manager"
+	"github.com/Jeffail/benthos/lib/tracer"
 	"github.com/Jeffail/benthos/lib/util/config"
 	yaml "gopkg.in/yaml.v2"
 )
@@ -70,7 +71,9 @@ type Config struct {
 	Manager              manager.Config `json:"resources" yaml:"resources"`
 	Logger               log.Config     `json:"logger" yaml:"logger"`
 	Metrics              metrics.Config `json:"metrics" yaml:"metrics"`
-	SystemCloseTimeoutMS int            `json:"sys_exit_timeout_ms" yaml:"sys_exit_timeout_ms"`
+	Tracer               tracer.Config  `json:"tracer" yaml:"tracer"`
+	ShutdownTimeout      string         `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+	SystemCloseTimeoutMS int            `json:"sys_exit_timeout_ms,omitempty" yaml:"sys_exit_timeout_ms,omitempty"`
 }
 
 // NewConfig returns a new configuration with default values.
@@ -84,7 +87,9 @@ func NewConfig() Config {
 		Manager:              manager.NewConfig(),
 		Logger:               log.NewConfig(),
 		Metrics:              metricsConf,
-		SystemCloseTimeoutMS: 20000,
+		Tracer:               tracer.NewConfig(),
+		ShutdownTimeout:      "20s",
+		SystemCloseTimeoutMS: 0,
 	}
 }
 
@@ -121,6 +126,12 @@ func (c Config) Sanitised() (interface{}, error) {
 		return nil, err
 	}
 
+	var tracConf interface{}
+	tracConf, err = tracer.SanitiseConfig(c.Tracer)
+	if err != nil {
+		return nil, err
+	}
+
 	return struct {
 		HTTP                 interface{} `json:"http" yaml:"http"`
 		Input                interface{} `json:"input" yaml:"input"`
@@ -130,7 +141,9 @@ func (c Config) Sanitised() (interface{}, error) {
 		Manager              interface{} `json:"resources" yaml:"resources"`
 		Logger               interface{} `json:"logger" yaml:"logger"`
 		Metrics              interface{} `json:"metrics" yaml:"metrics"`
-		SystemCloseTimeoutMS interface{} `json:"sys_exit_timeout_ms" yaml:"sys_exit_timeout_ms"`
+		Tracer               interface{} `json:"tracer" yaml:"tracer"`
+		ShutdownTimeout      interface{} `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+		SystemCloseTimeoutMS int         `json:"sys_exit_timeout_ms,omitempty" yaml:"sys_exit_timeout_ms,omitempty"`
 	}{
 		HTTP:                 c.HTTP,
 		Input:                inConf,
@@ -140,6 +153,8 @@ func (c Config) Sanitised() (interface{}, error) {
 		Manager:              c.Manager,
 		Logger:               c.Logger,
 		Metrics:              metConf,
+		Tracer:               tracConf,
+		ShutdownTimeout:      c.ShutdownTimeout,
 		SystemCloseTimeoutMS: c.SystemCloseTimeoutMS,
 	}, nil
 }
@@ -446,6 +461,7 @@ func bootstrap() Config {
 }
 
 type stoppableStreams interface {
+	Connected() (inputs, outputs bool)
 	Stop(timeout time.Duration) error
 }
 
@@ -474,12 +490,35 @@ func main() {
 	}
 	defer stats.Close()
 
+	// Create our tracer type.
+	var trac tracer.Type
+	if trac, err = tracer.New(config.Tracer, tracer.OptSetLogger(logger)); err != nil {
+		logger.Errorf("Failed to initialise tracer: %v\n", err)
+		os.Exit(1)
+	}
+	defer trac.Close()
+
+	// Determine how long we have to shut down gracefully.
+	shutdownTimeout, err := time.ParseDuration(config.ShutdownTimeout)
+	if err != nil {
+		logger.Errorf("Failed to parse shutdown_timeout: %v\n", err)
+		os.Exit(1)
+	}
+	if config.SystemCloseTimeoutMS > 0 {
+		logger.Warnln("The field sys_exit_timeout_ms is deprecated, use shutdown_timeout instead.")
+		shutdownTimeout = time.Millisecond * time.Duration(config.SystemCloseTimeoutMS)
+	}
+
 	// Create HTTP API with a sanitised service config.
 	sanConf, err := config.Sanitised()
 	if err != nil {
 		logger.Warnf("Failed to generate sanitised config: %v\n", err)
 	}
-	httpServer := api.New(Version, DateBuilt, config.HTTP, sanConf, logger, stats)
+	httpServer, err := api.New(Version, DateBuilt, config.HTTP, sanConf, logger, stats)
+	if err != nil {
+		logger.Errorf("Failed to create HTTP API: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Create resource manager.
 	manager, err := manager.New(config.Manager, httpServer, logger, stats)
@@ -498,6 +537,7 @@ func main() {
 			strmmgr.OptSetLogger(logger),
 			strmmgr.OptSetManager(manager),
 			strmmgr.OptSetStats(stats),
+			strmmgr.OptSetTracer(trac),
 		)
 		var streamConfs map[string]stream.Config
 		if streamConfs, err = strmmgr.LoadStreamConfigsFromDirectory(true, *streamsDir); err != nil {
@@ -521,6 +561,7 @@ func main() {
 			stream.OptSetLogger(logger),
 			stream.OptSetStats(stats),
 			stream.OptSetManager(manager),
+			stream.OptSetTracer(trac),
 			stream.OptOnClose(func() {
 				close(dataStreamClosedChan)
 			}),
@@ -531,6 +572,8 @@ func main() {
 		logger.Infoln("Launching a benthos instance, use CTRL+C to close.")
 	}
 
+	httpServer.SetReadinessCheck(dataStream.Connected)
+
 	// Start HTTP server.
 	httpServerClosedChan := make(chan struct{})
 	go func() {
@@ -547,7 +590,7 @@ func main() {
 
 	// Defer clean up.
 	defer func() {
-		tout := time.Millisecond * time.Duration(config.SystemCloseTimeoutMS)
+		tout := shutdownTimeout
 
 		go func() {
 			httpServer.Shutdown(context.Background())
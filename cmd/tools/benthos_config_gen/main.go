@@ -40,6 +40,7 @@ import (
 	"github.com/Jeffail/benthos/lib/pipeline"
 	"github.com/Jeffail/benthos/lib/processor"
 	"github.com/Jeffail/benthos/lib/processor/condition"
+	"github.com/Jeffail/benthos/lib/tracer"
 	yaml "gopkg.in/yaml.v2"
 )
 
@@ -55,6 +56,7 @@ type Config struct {
 	Manager  manager.Config  `json:"resources" yaml:"resources"`
 	Logger   log.Config      `json:"logger" yaml:"logger"`
 	Metrics  metrics.Config  `json:"metrics" yaml:"metrics"`
+	Tracer   tracer.Config   `json:"tracer" yaml:"tracer"`
 }
 
 // NewConfig returns a new configuration with default values.
@@ -68,6 +70,7 @@ func NewConfig() Config {
 		Manager:  manager.NewConfig(),
 		Logger:   log.NewConfig(),
 		Metrics:  metrics.NewConfig(),
+		Tracer:   tracer.NewConfig(),
 	}
 }
 
@@ -121,6 +124,7 @@ func (c Config) Sanitised(sanitBuffer, sanitPipe bool) (interface{}, error) {
 		Manager  interface{} `json:"resources" yaml:"resources"`
 		Logger   interface{} `json:"logger" yaml:"logger"`
 		Metrics  interface{} `json:"metrics" yaml:"metrics"`
+		Tracer   interface{} `json:"tracer" yaml:"tracer"`
 	}{
 		HTTP:     c.HTTP,
 		Input:    inConf,
@@ -130,6 +134,7 @@ func (c Config) Sanitised(sanitBuffer, sanitPipe bool) (interface{}, error) {
 		Manager:  mgrConf,
 		Logger:   c.Logger,
 		Metrics:  c.Metrics,
+		Tracer:   c.Tracer,
 	}, nil
 }
 
@@ -291,11 +296,12 @@ func envify(rootPath string, conf interface{}, paths map[string]string) (newConf
 func formatEnvVars(vars map[string]string) []byte {
 	categories := []string{
 		"HTTP", "INPUT", "BUFFER", "PROCESSOR", "OUTPUT", "LOGGER", "METRICS",
+		"TRACER",
 	}
 	priorityVars := []string{
 		"INPUTS", "PROCESSOR_THREADS", "OUTPUTS", "OUTPUTS_PATTERN",
 		"INPUT_TYPE", "BUFFER_TYPE", "PROCESSOR_TYPE",
-		"OUTPUT_TYPE", "METRICS_TYPE",
+		"OUTPUT_TYPE", "METRICS_TYPE", "TRACER_TYPE",
 	}
 
 	sortedVars := []string{}
@@ -435,6 +441,7 @@ func createEnvConf(configsDir string) {
 		Output   interface{} `json:"output"`
 		Logger   interface{} `json:"logger"`
 		Metrics  interface{} `json:"metrics"`
+		Tracer   interface{} `json:"tracer"`
 	}{
 		HTTP: conf.HTTP,
 		Input: struct {
@@ -455,6 +462,7 @@ func createEnvConf(configsDir string) {
 		},
 		Logger:  log.NewConfig(),
 		Metrics: metrics.NewConfig(),
+		Tracer:  tracer.NewConfig(),
 	}
 
 	pathsMap := map[string]string{}
@@ -465,6 +473,7 @@ func createEnvConf(configsDir string) {
 	envConf.Output = envify("OUTPUT", envConf.Output, pathsMap)
 	envConf.Logger = envify("LOGGER", envConf.Logger, pathsMap)
 	envConf.Metrics = envify("METRICS", envConf.Metrics, pathsMap)
+	envConf.Tracer = envify("TRACER", envConf.Tracer, pathsMap)
 
 	createYAML("environment file", filepath.Join(configsDir, "env", "default.yaml"), envConf)
 	create("environment file docs", filepath.Join(configsDir, "env", "README.md"), formatEnvVars(pathsMap))
@@ -40,19 +40,21 @@ import (
 
 // Config contains the configuration fields for the Benthos API.
 type Config struct {
-	Address        string `json:"address" yaml:"address"`
-	ReadTimeoutMS  int    `json:"read_timeout_ms" yaml:"read_timeout_ms"`
-	RootPath       string `json:"root_path" yaml:"root_path"`
-	DebugEndpoints bool   `json:"debug_endpoints" yaml:"debug_endpoints"`
+	Address          string `json:"address" yaml:"address"`
+	ReadTimeoutMS    int    `json:"read_timeout_ms" yaml:"read_timeout_ms"`
+	RootPath         string `json:"root_path" yaml:"root_path"`
+	DebugEndpoints   bool   `json:"debug_endpoints" yaml:"debug_endpoints"`
+	ReadyGracePeriod string `json:"ready_grace_period" yaml:"ready_grace_period"`
 }
 
 // NewConfig creates a new API config with default values.
 func NewConfig() Config {
 	return Config{
-		Address:        "0.0.0.0:4195",
-		ReadTimeoutMS:  5000,
-		RootPath:       "/benthos",
-		DebugEndpoints: false,
+		Address:          "0.0.0.0:4195",
+		ReadTimeoutMS:    5000,
+		RootPath:         "/benthos",
+		DebugEndpoints:   false,
+		ReadyGracePeriod: "30s",
 	}
 }
 
@@ -67,6 +69,8 @@ type Type struct {
 	handlers    map[string]http.HandlerFunc
 	handlersMut sync.RWMutex
 
+	readiness *readiness
+
 	mux    *mux.Router
 	server *http.Server
 }
@@ -79,7 +83,7 @@ func New(
 	wholeConf interface{},
 	log log.Modular,
 	stats metrics.Type,
-) *Type {
+) (*Type, error) {
 	handler := mux.NewRouter()
 	server := &http.Server{
 		Addr:        conf.Address,
@@ -87,10 +91,19 @@ func New(
 		ReadTimeout: time.Millisecond * time.Duration(conf.ReadTimeoutMS),
 	}
 
+	var readyGrace time.Duration
+	if len(conf.ReadyGracePeriod) > 0 {
+		var err error
+		if readyGrace, err = time.ParseDuration(conf.ReadyGracePeriod); err != nil {
+			return nil, fmt.Errorf("failed to parse ready_grace_period: %v", err)
+		}
+	}
+
 	t := &Type{
 		conf:      conf,
 		endpoints: map[string]string{},
 		handlers:  map[string]http.HandlerFunc{},
+		readiness: newReadiness(readyGrace),
 		mux:       handler,
 		server:    server,
 	}
@@ -99,6 +112,19 @@ func New(
 		w.Write([]byte("pong"))
 	}
 
+	handleHealth := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}
+
+	handleReady := func(w http.ResponseWriter, r *http.Request) {
+		if err := t.readiness.ready(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.Write([]byte("OK"))
+	}
+
 	handleStackTrace := func(w http.ResponseWriter, r *http.Request) {
 		stackSlice := make([]byte, 1024*100)
 		s := runtime.Stack(stackSlice, true)
@@ -184,6 +210,16 @@ func New(
 	}
 
 	t.RegisterEndpoint("/ping", "Ping me.", handlePing)
+	t.RegisterEndpoint(
+		"/health", "Returns 200 whilst the service is running, for liveness probes.",
+		handleHealth,
+	)
+	t.RegisterEndpoint(
+		"/ready", "Returns 200 once all inputs and outputs have connected, and"+
+			" 503 whilst they have not or when outputs have been disconnected"+
+			" for longer than the grace period, for readiness probes.",
+		handleReady,
+	)
 	t.RegisterEndpoint("/version", "Returns the service version.", handleVersion)
 	t.RegisterEndpoint("/endpoints", "Returns this map of endpoints.", handleEndpoints)
 
@@ -199,7 +235,16 @@ func New(
 		)
 	}
 
-	return t
+	// If Prometheus is exposed alongside another metrics type then it takes
+	// precedence over the /metrics endpoint.
+	if wPromHandlerFunc, ok := stats.(metrics.WithPrometheusHandlerFunc); ok {
+		t.RegisterEndpoint(
+			"/metrics", "Returns service metrics for Prometheus scraping.",
+			wPromHandlerFunc.PrometheusHandlerFunc(),
+		)
+	}
+
+	return t, nil
 }
 
 // RegisterEndpoint registers a http.HandlerFunc under a path with a
@@ -226,6 +271,12 @@ func (t *Type) RegisterEndpoint(path, desc string, handler http.HandlerFunc) {
 	t.handlers[path] = handler
 }
 
+// SetReadinessCheck sets a function used by the /ready endpoint in order to
+// obtain the connection state of the inputs and outputs of the service.
+func (t *Type) SetReadinessCheck(check func() (inputs, outputs bool)) {
+	t.readiness.setCheck(check)
+}
+
 // ListenAndServe launches the API and blocks until the server closes or fails.
 func (t *Type) ListenAndServe() error {
 	return t.server.ListenAndServe()
@@ -0,0 +1,99 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/util/clock"
+)
+
+//------------------------------------------------------------------------------
+
+// readiness determines whether a service is ready to receive traffic from the
+// connection state of its inputs and outputs. The service is not ready until
+// both have connected at least once, and becomes not ready again when outputs
+// are observed as disconnected for longer than a grace period.
+type readiness struct {
+	mut   sync.Mutex
+	check func() (inputs, outputs bool)
+	grace time.Duration
+	clock clock.Clock
+
+	inputsConnected  bool
+	outputsConnected bool
+	outputsLostAt    time.Time
+}
+
+func newReadiness(grace time.Duration) *readiness {
+	return &readiness{
+		check: func() (bool, bool) { return true, true },
+		grace: grace,
+		clock: clock.Real(),
+	}
+}
+
+// setCheck sets the function used to obtain the connection state of the inputs
+// and outputs of the service, and resets any previously observed state.
+func (r *readiness) setCheck(check func() (inputs, outputs bool)) {
+	r.mut.Lock()
+	r.check = check
+	r.inputsConnected = false
+	r.outputsConnected = false
+	r.outputsLostAt = time.Time{}
+	r.mut.Unlock()
+}
+
+// ready returns nil if the service is ready, otherwise an error describing why
+// it is not.
+func (r *readiness) ready() error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	inputs, outputs := r.check()
+	if inputs {
+		r.inputsConnected = true
+	}
+	if outputs {
+		r.outputsConnected = true
+		r.outputsLostAt = time.Time{}
+	} else if r.outputsConnected && r.outputsLostAt.IsZero() {
+		r.outputsLostAt = r.clock.Now()
+	}
+
+	if !r.inputsConnected {
+		return errors.New("inputs have not yet connected")
+	}
+	if !r.outputsConnected {
+		return errors.New("outputs have not yet connected")
+	}
+	if !r.outputsLostAt.IsZero() {
+		if lostFor := r.clock.Now().Sub(r.outputsLostAt); lostFor > r.grace {
+			return fmt.Errorf("outputs have been disconnected for %v", lostFor)
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,134 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/util/clock"
+)
+
+//------------------------------------------------------------------------------
+
+func TestReadiness(t *testing.T) {
+	fakeClock := clock.NewFake(time.Unix(0, 0))
+
+	r := newReadiness(time.Second * 10)
+	r.clock = fakeClock
+
+	var inputs, outputs bool
+	r.setCheck(func() (bool, bool) {
+		return inputs, outputs
+	})
+
+	if err := r.ready(); err == nil {
+		t.Error("Expected not ready before connecting")
+	}
+
+	inputs = true
+	if err := r.ready(); err == nil {
+		t.Error("Expected not ready before outputs connect")
+	}
+
+	outputs = true
+	if err := r.ready(); err != nil {
+		t.Errorf("Expected ready: %v", err)
+	}
+
+	// Inputs losing their connection do not affect readiness.
+	inputs = false
+	if err := r.ready(); err != nil {
+		t.Errorf("Expected ready: %v", err)
+	}
+
+	outputs = false
+	if err := r.ready(); err != nil {
+		t.Errorf("Expected ready within grace period: %v", err)
+	}
+	fakeClock.Add(time.Second * 5)
+	if err := r.ready(); err != nil {
+		t.Errorf("Expected ready within grace period: %v", err)
+	}
+	fakeClock.Add(time.Second * 6)
+	if err := r.ready(); err == nil {
+		t.Error("Expected not ready after grace period")
+	}
+
+	outputs = true
+	if err := r.ready(); err != nil {
+		t.Errorf("Expected ready after reconnecting: %v", err)
+	}
+
+	// Grace period restarts from the latest disconnection.
+	outputs = false
+	if err := r.ready(); err != nil {
+		t.Errorf("Expected ready within grace period: %v", err)
+	}
+}
+
+func TestReadyBadGracePeriod(t *testing.T) {
+	conf := NewConfig()
+	conf.ReadyGracePeriod = "not a duration"
+	if _, err := New("", "", conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad ready_grace_period")
+	}
+}
+
+func TestReadyEndpoint(t *testing.T) {
+	api, err := New("", "", NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var connected bool
+	api.SetReadinessCheck(func() (bool, bool) {
+		return connected, connected
+	})
+
+	request := func(path string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		res := httptest.NewRecorder()
+		api.mux.ServeHTTP(res, req)
+		return res.Code
+	}
+
+	if exp, act := http.StatusServiceUnavailable, request("/ready"); exp != act {
+		t.Errorf("Wrong status code: %v != %v", act, exp)
+	}
+	if exp, act := http.StatusOK, request("/health"); exp != act {
+		t.Errorf("Wrong status code: %v != %v", act, exp)
+	}
+
+	connected = true
+	if exp, act := http.StatusOK, request("/ready"); exp != act {
+		t.Errorf("Wrong status code: %v != %v", act, exp)
+	}
+	if exp, act := http.StatusOK, request("/benthos/ready"); exp != act {
+		t.Errorf("Wrong status code: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
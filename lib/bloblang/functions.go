@@ -0,0 +1,161 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bloblang
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+//------------------------------------------------------------------------------
+
+// function is executed with the evaluated values of its arguments.
+type function func(ctx *context, args []interface{}) (interface{}, error)
+
+type functionSpec struct {
+	fn          function
+	minArgs     int
+	maxArgs     int
+	description string
+}
+
+var functions = map[string]functionSpec{
+	"content": {
+		fn: func(ctx *context, args []interface{}) (interface{}, error) {
+			return string(ctx.msg.Get(ctx.index).Get()), nil
+		},
+		description: "Returns the raw contents of the message as a string.",
+	},
+	"meta": {
+		fn: func(ctx *context, args []interface{}) (interface{}, error) {
+			key, ok := args[0].(string)
+			if !ok {
+				return nil, errWrongType(args[0], "string")
+			}
+			v := ctx.msg.Get(ctx.index).Metadata().Get(key)
+			if len(v) == 0 {
+				return nil, nil
+			}
+			return v, nil
+		},
+		minArgs:     1,
+		maxArgs:     1,
+		description: "Returns the value of a metadata key of the input message, or `null` if the key does not exist.",
+	},
+	"deleted": {
+		fn: func(ctx *context, args []interface{}) (interface{}, error) {
+			return deleted{}, nil
+		},
+		description: "Returns a value that removes the target of an assignment. Assigning it to `root` removes the message from the batch.",
+	},
+	"uuid_v4": {
+		fn: func(ctx *context, args []interface{}) (interface{}, error) {
+			u, err := uuid.NewV4()
+			if err != nil {
+				return nil, err
+			}
+			return u.String(), nil
+		},
+		description: "Generates a new random UUID.",
+	},
+	"now": {
+		fn: func(ctx *context, args []interface{}) (interface{}, error) {
+			return time.Now().Format(time.RFC3339Nano), nil
+		},
+		description: "Returns the current time as an RFC 3339 string.",
+	},
+	"timestamp_unix": {
+		fn: func(ctx *context, args []interface{}) (interface{}, error) {
+			return time.Now().Unix(), nil
+		},
+		description: "Returns the current unix timestamp in seconds.",
+	},
+	"hostname": {
+		fn: func(ctx *context, args []interface{}) (interface{}, error) {
+			return os.Hostname()
+		},
+		description: "Returns the hostname of the machine running Benthos.",
+	},
+	"env": {
+		fn: func(ctx *context, args []interface{}) (interface{}, error) {
+			key, ok := args[0].(string)
+			if !ok {
+				return nil, errWrongType(args[0], "string")
+			}
+			v, exists := os.LookupEnv(key)
+			if !exists {
+				return nil, nil
+			}
+			return v, nil
+		},
+		minArgs:     1,
+		maxArgs:     1,
+		description: "Returns the value of an environment variable, or `null` if it is not set.",
+	},
+	"batch_index": {
+		fn: func(ctx *context, args []interface{}) (interface{}, error) {
+			return int64(ctx.index), nil
+		},
+		description: "Returns the index of the message within its batch.",
+	},
+	"batch_size": {
+		fn: func(ctx *context, args []interface{}) (interface{}, error) {
+			return int64(ctx.msg.Len()), nil
+		},
+		description: "Returns the number of messages within the batch.",
+	},
+	"throw": {
+		fn: func(ctx *context, args []interface{}) (interface{}, error) {
+			return nil, errors.New(toString(args[0]))
+		},
+		minArgs:     1,
+		maxArgs:     1,
+		description: "Fails the mapping with an error message.",
+	},
+}
+
+//------------------------------------------------------------------------------
+
+// FunctionsDocs returns a markdown list of all functions.
+func FunctionsDocs() string {
+	names := make([]string, 0, len(functions))
+	for k := range functions {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		fmt.Fprintf(&buf, "- `%v`: %v", name, functions[name].description)
+	}
+	return buf.String()
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,207 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bloblang
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+)
+
+//------------------------------------------------------------------------------
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNewline
+	tokIdent
+	tokString
+	tokNumber
+	tokSymbol
+)
+
+type token struct {
+	kind tokenKind
+	val  string
+	line int
+	col  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of input"
+	case tokNewline:
+		return "line break"
+	case tokString:
+		return strconv.Quote(t.val)
+	}
+	return "'" + t.val + "'"
+}
+
+// ErrPosition is an error that occurred at a specific position of a mapping.
+type ErrPosition struct {
+	Line int
+	Col  int
+	Err  error
+}
+
+// Error returns a human readable description of the error.
+func (e *ErrPosition) Error() string {
+	return fmt.Sprintf("line %v char %v: %v", e.Line, e.Col, e.Err)
+}
+
+func errAt(line, col int, format string, args ...interface{}) error {
+	return &ErrPosition{
+		Line: line,
+		Col:  col,
+		Err:  fmt.Errorf(format, args...),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var doubleSymbols = map[string]struct{}{
+	"==": {}, "!=": {}, ">=": {}, "<=": {}, "&&": {}, "||": {},
+}
+
+const singleSymbols = "=><+-*/%!.,(){}[]:"
+
+// lex breaks a mapping down into tokens. Line breaks are only emitted when they
+// are not within brackets, which allows expressions to span multiple lines.
+func lex(script string) ([]token, error) {
+	var tokens []token
+	runes := []rune(script)
+	line, col := 1, 1
+	depth := 0
+
+	emit := func(kind tokenKind, val string, l, c int) {
+		tokens = append(tokens, token{kind: kind, val: val, line: l, col: c})
+	}
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		startLine, startCol := line, col
+
+		switch {
+		case r == '\n':
+			if depth == 0 && len(tokens) > 0 && tokens[len(tokens)-1].kind != tokNewline {
+				emit(tokNewline, "\n", line, col)
+			}
+			i++
+			line++
+			col = 1
+			continue
+		case r == ' ' || r == '\t' || r == '\r':
+			i++
+			col++
+			continue
+		case r == '#':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+				col++
+			}
+			continue
+		case r == '_' || unicode.IsLetter(r):
+			j := i
+			for j < len(runes) && (runes[j] == '_' || unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) {
+				j++
+			}
+			emit(tokIdent, string(runes[i:j]), startLine, startCol)
+			col += j - i
+			i = j
+			continue
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && unicode.IsDigit(runes[j]) {
+				j++
+			}
+			if j+1 < len(runes) && runes[j] == '.' && unicode.IsDigit(runes[j+1]) {
+				j++
+				for j < len(runes) && unicode.IsDigit(runes[j]) {
+					j++
+				}
+			}
+			emit(tokNumber, string(runes[i:j]), startLine, startCol)
+			col += j - i
+			i = j
+			continue
+		case r == '"':
+			j := i + 1
+			for ; j < len(runes) && runes[j] != '"'; j++ {
+				if runes[j] == '\\' {
+					j++
+				}
+				if j < len(runes) && runes[j] == '\n' {
+					return nil, errAt(startLine, startCol, "unterminated string literal")
+				}
+			}
+			if j >= len(runes) {
+				return nil, errAt(startLine, startCol, "unterminated string literal")
+			}
+			str, err := strconv.Unquote(string(runes[i : j+1]))
+			if err != nil {
+				return nil, errAt(startLine, startCol, "invalid string literal: %v", err)
+			}
+			emit(tokString, str, startLine, startCol)
+			col += j + 1 - i
+			i = j + 1
+			continue
+		}
+
+		if i+1 < len(runes) {
+			if _, exists := doubleSymbols[string(runes[i:i+2])]; exists {
+				emit(tokSymbol, string(runes[i:i+2]), startLine, startCol)
+				i += 2
+				col += 2
+				continue
+			}
+		}
+
+		found := false
+		for _, s := range singleSymbols {
+			if r == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errAt(startLine, startCol, "unexpected character '%c'", r)
+		}
+		switch r {
+		case '(', '{', '[':
+			depth++
+		case ')', '}', ']':
+			if depth > 0 {
+				depth--
+			}
+		}
+		emit(tokSymbol, string(r), startLine, startCol)
+		i++
+		col++
+	}
+
+	emit(tokEOF, "", line, col)
+	return tokens, nil
+}
+
+//------------------------------------------------------------------------------
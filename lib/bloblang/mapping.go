@@ -0,0 +1,185 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bloblang
+
+import (
+	"fmt"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// Mapping is a parsed mapping that can be executed against messages.
+type Mapping struct {
+	assignments []assignment
+}
+
+// NewMapping parses a mapping, returning an error if the mapping is invalid.
+func NewMapping(script string) (*Mapping, error) {
+	assignments, err := parse(script)
+	if err != nil {
+		return nil, err
+	}
+	return &Mapping{
+		assignments: assignments,
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// MapPart executes the mapping against a part of a message batch and returns
+// the resulting part, or nil if the mapping deleted it. Queries reference the
+// input part, therefore assignments do not affect the results of subsequent
+// queries.
+func (m *Mapping) MapPart(index int, msg types.Message) (types.Part, error) {
+	input := msg.Get(index)
+	part := input.Copy()
+
+	var doc interface{}
+	var docErr error
+	var docParsed bool
+
+	ctx := &context{
+		value: func() (interface{}, error) {
+			if !docParsed {
+				doc, docErr = input.JSON()
+				docParsed = true
+			}
+			if docErr != nil {
+				return nil, fmt.Errorf("failed to parse message as JSON: %v", docErr)
+			}
+			return doc, nil
+		},
+		msg:   msg,
+		index: index,
+	}
+
+	var root interface{}
+	var rootSet bool
+
+	for _, a := range m.assignments {
+		v, err := a.value.exec(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute assignment at line %v: %v", a.line, err)
+		}
+		if _, ok := v.(nothing); ok {
+			continue
+		}
+		if a.meta {
+			if err = assignMeta(part.Metadata(), a.metaKey, v); err != nil {
+				return nil, fmt.Errorf("failed to execute assignment at line %v: %v", a.line, err)
+			}
+			continue
+		}
+		root = assignPath(root, a.path, deepCopy(v))
+		rootSet = true
+	}
+
+	if !rootSet {
+		return part, nil
+	}
+	switch t := root.(type) {
+	case deleted:
+		return nil, nil
+	case string:
+		part.Set([]byte(t))
+	default:
+		if err := part.SetJSON(t); err != nil {
+			return nil, fmt.Errorf("failed to set JSON result: %v", err)
+		}
+	}
+	return part, nil
+}
+
+//------------------------------------------------------------------------------
+
+// assignPath sets a value at a path of a document, creating objects along the
+// path as required, and returns the resulting document.
+func assignPath(root interface{}, path []string, v interface{}) interface{} {
+	if len(path) == 0 {
+		return v
+	}
+
+	_, del := v.(deleted)
+	obj, ok := root.(map[string]interface{})
+	if !ok {
+		if del {
+			return root
+		}
+		obj = map[string]interface{}{}
+	}
+
+	if len(path) == 1 {
+		if del {
+			delete(obj, path[0])
+		} else {
+			obj[path[0]] = v
+		}
+		return obj
+	}
+
+	child, exists := obj[path[0]]
+	if del && !exists {
+		return obj
+	}
+	obj[path[0]] = assignPath(child, path[1:], v)
+	return obj
+}
+
+// assignMeta sets a metadata key to a value, where null or deleted values
+// remove the key. An empty key targets all metadata.
+func assignMeta(meta types.Metadata, key string, v interface{}) error {
+	if len(key) > 0 {
+		switch v.(type) {
+		case nil, deleted:
+			meta.Delete(key)
+		default:
+			meta.Set(key, toString(v))
+		}
+		return nil
+	}
+
+	var keys []string
+	meta.Iter(func(k, _ string) error {
+		keys = append(keys, k)
+		return nil
+	})
+
+	switch t := v.(type) {
+	case deleted:
+		for _, k := range keys {
+			meta.Delete(k)
+		}
+	case map[string]interface{}:
+		for _, k := range keys {
+			meta.Delete(k)
+		}
+		for k, e := range t {
+			meta.Set(k, toString(e))
+		}
+	default:
+		return fmt.Errorf("expected object or deleted value for meta assignment, found %v", typeOf(v))
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
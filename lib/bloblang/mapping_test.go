@@ -0,0 +1,317 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bloblang
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/message"
+)
+
+func TestMappings(t *testing.T) {
+	type part struct {
+		content string
+		meta    map[string]string
+	}
+
+	tests := map[string]struct {
+		mapping string
+		input   part
+		output  *part
+		err     string
+	}{
+		"copy document": {
+			mapping: `root = this`,
+			input:   part{content: `{"foo":"bar"}`},
+			output:  &part{content: `{"foo":"bar"}`},
+		},
+		"restructure document": {
+			mapping: `
+root.id = this.user.id
+root.name = this.user.first + " " + this.user.last
+root.tags = this.tags.map_each(this.uppercase())
+`,
+			input:  part{content: `{"user":{"id":5,"first":"ash","last":"jeffs"},"tags":["a","b"]}`},
+			output: &part{content: `{"id":5,"name":"ash jeffs","tags":["A","B"]}`},
+		},
+		"shorthand targets and fields": {
+			mapping: `foo.bar = baz
+"quoted key" = this."a b"`,
+			input:  part{content: `{"baz":1,"a b":2}`},
+			output: &part{content: `{"foo":{"bar":1},"quoted key":2}`},
+		},
+		"delete field": {
+			mapping: `root = this
+root.password = deleted()
+root.nested.secret = deleted()
+root.missing.path = deleted()`,
+			input:  part{content: `{"user":"foo","password":"bar","nested":{"secret":1,"public":2}}`},
+			output: &part{content: `{"nested":{"public":2},"user":"foo"}`},
+		},
+		"delete message": {
+			mapping: `root = if this.type == "noise" { deleted() } else { this }`,
+			input:   part{content: `{"type":"noise"}`},
+			output:  nil,
+		},
+		"if without else skips assignment": {
+			mapping: `root.a = "default"
+root.a = if this.flag { "flagged" }
+root.b = if this.flag == false { "not flagged" }`,
+			input:  part{content: `{"flag":false}`},
+			output: &part{content: `{"a":"default","b":"not flagged"}`},
+		},
+		"else if chain": {
+			mapping: `root.size = if this.n > 100 { "large" } else if this.n > 10 { "medium" } else { "small" }`,
+			input:   part{content: `{"n":50}`},
+			output:  &part{content: `{"size":"medium"}`},
+		},
+		"arithmetic": {
+			mapping: `root.a = this.n * 2 + 1
+root.b = (this.n - 1) / 2
+root.c = 7 % 3
+root.d = -this.n
+root.e = 10 / 4`,
+			input:  part{content: `{"n":5}`},
+			output: &part{content: `{"a":11,"b":2,"c":1,"d":-5,"e":2.5}`},
+		},
+		"logic": {
+			mapping: `root.a = this.n > 1 && this.n < 10
+root.b = this.n == 5 || this.missing.field == 1
+root.c = !this.flag
+root.d = this.name >= "b"`,
+			input:  part{content: `{"n":5,"flag":true,"name":"c"}`},
+			output: &part{content: `{"a":true,"b":true,"c":false,"d":true}`},
+		},
+		"literals": {
+			mapping: `root = {
+  "str": "foo",
+  num: 1.5,
+  "arr": [ 1, "two", null, true ],
+  "nested": { "a": this.a }
+}`,
+			input:  part{content: `{"a":"b"}`},
+			output: &part{content: `{"arr":[1,"two",null,true],"nested":{"a":"b"},"num":1.5,"str":"foo"}`},
+		},
+		"raw string result": {
+			mapping: `root = content().uppercase()`,
+			input:   part{content: `hello world`},
+			output:  &part{content: `HELLO WORLD`},
+		},
+		"no root assignment keeps content": {
+			mapping: `meta foo = "bar"`,
+			input:   part{content: `not json`},
+			output: &part{
+				content: `not json`,
+				meta:    map[string]string{"foo": "bar"},
+			},
+		},
+		"metadata": {
+			mapping: `meta topic = this.topic
+meta count = this.count
+meta removed = deleted()
+root.key = meta("key")
+root.missing = meta("nope").or("default")`,
+			input: part{
+				content: `{"topic":"foo","count":3}`,
+				meta:    map[string]string{"key": "bar", "removed": "baz"},
+			},
+			output: &part{
+				content: `{"key":"bar","missing":"default"}`,
+				meta:    map[string]string{"key": "bar", "topic": "foo", "count": "3"},
+			},
+		},
+		"replace all metadata": {
+			mapping: `meta = {"a": "b"}`,
+			input: part{
+				content: `foo`,
+				meta:    map[string]string{"c": "d"},
+			},
+			output: &part{
+				content: `foo`,
+				meta:    map[string]string{"a": "b"},
+			},
+		},
+		"input is not modified by assignments": {
+			mapping: `root = this
+root.foo = "changed"
+root.bar = this.foo`,
+			input:  part{content: `{"foo":"original"}`},
+			output: &part{content: `{"bar":"original","foo":"changed"}`},
+		},
+		"array index": {
+			mapping: `root.first = this.items.0.name
+root.last = this.items.index(-1).name
+root.none = this.items.5`,
+			input:  part{content: `{"items":[{"name":"a"},{"name":"b"}]}`},
+			output: &part{content: `{"first":"a","last":"b","none":null}`},
+		},
+		"comments": {
+			mapping: `# set the id
+root.id = this.id # inline comment`,
+			input:  part{content: `{"id":"foo"}`},
+			output: &part{content: `{"id":"foo"}`},
+		},
+		"invalid json": {
+			mapping: `root.id = this.id`,
+			input:   part{content: `not json`},
+			err:     "failed to execute assignment at line 1: failed to parse message as JSON",
+		},
+		"wrong type": {
+			mapping: `
+root.id = this.id.uppercase()`,
+			input: part{content: `{"id":5}`},
+			err:   "failed to execute assignment at line 2: method 'uppercase': expected string value, found number",
+		},
+		"catch error": {
+			mapping: `root.id = this.id.uppercase().catch("unknown")`,
+			input:   part{content: `{"id":5}`},
+			output:  &part{content: `{"id":"unknown"}`},
+		},
+		"throw": {
+			mapping: `root = if this.id == null { throw("missing id") } else { this }`,
+			input:   part{content: `{}`},
+			err:     "function 'throw': missing id",
+		},
+	}
+
+	for name, test := range tests {
+		m, err := NewMapping(test.mapping)
+		if err != nil {
+			t.Errorf("%v: failed to parse: %v", name, err)
+			continue
+		}
+
+		msg := message.New([][]byte{[]byte(test.input.content)})
+		for k, v := range test.input.meta {
+			msg.Get(0).Metadata().Set(k, v)
+		}
+
+		res, err := m.MapPart(0, msg)
+		if len(test.err) > 0 {
+			if err == nil {
+				t.Errorf("%v: expected error", name)
+			} else if !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%v: wrong error: %v does not contain %v", name, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", name, err)
+			continue
+		}
+
+		if test.output == nil {
+			if res != nil {
+				t.Errorf("%v: expected deleted part, got: %s", name, res.Get())
+			}
+			continue
+		}
+		if res == nil {
+			t.Errorf("%v: unexpected deleted part", name)
+			continue
+		}
+		if exp, act := test.output.content, string(res.Get()); exp != act {
+			t.Errorf("%v: wrong result: %v != %v", name, act, exp)
+		}
+
+		expMeta := test.output.meta
+		if expMeta == nil {
+			expMeta = test.input.meta
+		}
+		if expMeta == nil {
+			expMeta = map[string]string{}
+		}
+		actMeta := map[string]string{}
+		res.Metadata().Iter(func(k, v string) error {
+			actMeta[k] = v
+			return nil
+		})
+		if !reflect.DeepEqual(expMeta, actMeta) {
+			t.Errorf("%v: wrong metadata: %v != %v", name, actMeta, expMeta)
+		}
+
+		if exp, act := test.input.content, string(msg.Get(0).Get()); exp != act {
+			t.Errorf("%v: input was modified: %v != %v", name, act, exp)
+		}
+	}
+}
+
+func TestMappingBatchFunctions(t *testing.T) {
+	m, err := NewMapping(`root.index = batch_index()
+root.size = batch_size()`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{[]byte(`{}`), []byte(`{}`), []byte(`{}`)})
+	res, err := m.MapPart(1, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := `{"index":1,"size":3}`, string(res.Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func TestMappingLazyParse(t *testing.T) {
+	m, err := NewMapping(`root = if content().has_prefix("{") { this.foo } else { content() }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := m.MapPart(0, message.New([][]byte{[]byte(`not json {`)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := `not json {`, string(res.Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func TestMappingParseErrors(t *testing.T) {
+	tests := map[string]string{
+		`root.foo = `:                "line 1 char 12: expected query, found end of input",
+		`root.foo = this.bar baz`:    "line 1 char 21: expected end of assignment, found 'baz'",
+		`this.foo = "bar"`:           "line 1 char 1: cannot assign to this",
+		`root = nope()`:              "line 1 char 8: unrecognised function 'nope'",
+		`root = this.nope()`:         "line 1 char 13: unrecognised method 'nope'",
+		`root = this.replace("a")`:   "line 1 char 13: 'replace' expected 2 arguments, received 1",
+		`root = this.slice()`:        "line 1 char 13: 'slice' expected between 1 and 2 arguments, received 0",
+		"root.a = 1\nroot.b = \"foo": "line 2 char 10: unterminated string literal",
+		`root = if this.a { "b" `:    "line 1 char 24: expected '}', found end of input",
+		`root = root.foo`:            "line 1 char 8: cannot query root",
+		`root = this @ 5`:            "line 1 char 13: unexpected character '@'",
+		`= 5`:                        "line 1 char 1: expected assignment target, found '='",
+	}
+
+	for mapping, exp := range tests {
+		_, err := NewMapping(mapping)
+		if err == nil {
+			t.Errorf("Expected error from mapping: %v", mapping)
+			continue
+		}
+		if !strings.Contains(err.Error(), exp) {
+			t.Errorf("Wrong error for mapping '%v': %v does not contain %v", mapping, err, exp)
+		}
+	}
+}
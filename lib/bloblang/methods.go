@@ -0,0 +1,766 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bloblang
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/OneOfOne/xxhash"
+)
+
+//------------------------------------------------------------------------------
+
+// method is executed with the value of its target and its unevaluated
+// arguments, which allows methods to execute arguments in a new context.
+type method func(ctx *context, v interface{}, args []query) (interface{}, error)
+
+type methodSpec struct {
+	fn method
+
+	// onErr, when set, is called instead of fn when the target fails.
+	onErr func(ctx *context, err error, args []query) (interface{}, error)
+
+	minArgs     int
+	maxArgs     int
+	description string
+}
+
+func evalArgs(ctx *context, args []query) ([]interface{}, error) {
+	vals := make([]interface{}, len(args))
+	for i, a := range args {
+		var err error
+		if vals[i], err = a.exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return vals, nil
+}
+
+// simpleMethod creates a method from a function that operates on the evaluated
+// values of its arguments.
+func simpleMethod(fn func(v interface{}, args []interface{}) (interface{}, error)) method {
+	return func(ctx *context, v interface{}, args []query) (interface{}, error) {
+		vals, err := evalArgs(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+		return fn(v, vals)
+	}
+}
+
+// stringMethod creates a method that operates on a string target.
+func stringMethod(fn func(s string, args []interface{}) (interface{}, error)) method {
+	return simpleMethod(func(v interface{}, args []interface{}) (interface{}, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, errWrongType(v, "string")
+		}
+		return fn(s, args)
+	})
+}
+
+// numberMethod creates a method that operates on a number target.
+func numberMethod(fn func(f float64) float64) method {
+	return simpleMethod(func(v interface{}, args []interface{}) (interface{}, error) {
+		if i, ok := v.(int64); ok {
+			return i, nil
+		}
+		f, ok := toNumber(v)
+		if !ok {
+			return nil, errWrongType(v, "number")
+		}
+		return fn(f), nil
+	})
+}
+
+func stringArg(args []interface{}, i int) (string, error) {
+	s, ok := args[i].(string)
+	if !ok {
+		return "", fmt.Errorf("argument %v: %v", i, errWrongType(args[i], "string"))
+	}
+	return s, nil
+}
+
+func intArg(args []interface{}, i int) (int, error) {
+	f, ok := toNumber(args[i])
+	if !ok || f != math.Trunc(f) {
+		return 0, fmt.Errorf("argument %v: %v", i, errWrongType(args[i], "integer"))
+	}
+	return int(f), nil
+}
+
+// parseNumber parses a string as an int64 when it is an integer within range,
+// and as a float64 otherwise.
+func parseNumber(s string) (interface{}, error) {
+	s = strings.TrimSpace(s)
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("failed to parse '%v' as a number", s)
+	}
+	return f, nil
+}
+
+// resolveIndex converts a possibly negative index into an absolute one for a
+// sequence of a given length, clamped to the bounds of the sequence.
+func resolveIndex(i, length int) int {
+	if i < 0 {
+		i = length + i
+	}
+	if i < 0 {
+		return 0
+	}
+	if i > length {
+		return length
+	}
+	return i
+}
+
+func orDefault(ctx *context, err error, args []query) (interface{}, error) {
+	return args[0].exec(ctx)
+}
+
+//------------------------------------------------------------------------------
+
+var hashers = map[string]func() hash.Hash{
+	"md5":      md5.New,
+	"sha1":     sha1.New,
+	"sha256":   sha256.New,
+	"sha512":   sha512.New,
+	"xxhash64": func() hash.Hash { return xxhash.New64() },
+}
+
+var methods = map[string]methodSpec{
+	"or": {
+		fn: func(ctx *context, v interface{}, args []query) (interface{}, error) {
+			if v == nil {
+				return args[0].exec(ctx)
+			}
+			return v, nil
+		},
+		onErr:       orDefault,
+		minArgs:     1,
+		maxArgs:     1,
+		description: "Returns the argument if the target is `null` or fails.",
+	},
+	"catch": {
+		fn: func(ctx *context, v interface{}, args []query) (interface{}, error) {
+			return v, nil
+		},
+		onErr:       orDefault,
+		minArgs:     1,
+		maxArgs:     1,
+		description: "Returns the argument if the target fails.",
+	},
+	"not_null": {
+		fn: simpleMethod(func(v interface{}, args []interface{}) (interface{}, error) {
+			if v == nil {
+				return nil, errors.New("value is null")
+			}
+			return v, nil
+		}),
+		description: "Fails the mapping if the target is `null`.",
+	},
+	"type": {
+		fn: simpleMethod(func(v interface{}, args []interface{}) (interface{}, error) {
+			return typeOf(v), nil
+		}),
+		description: "Returns the type of the target as a string.",
+	},
+	"string": {
+		fn: simpleMethod(func(v interface{}, args []interface{}) (interface{}, error) {
+			return toString(v), nil
+		}),
+		description: "Converts the target into a string, structured values are serialised as JSON.",
+	},
+	"number": {
+		fn: simpleMethod(func(v interface{}, args []interface{}) (interface{}, error) {
+			switch t := v.(type) {
+			case string:
+				return parseNumber(t)
+			case bool:
+				if t {
+					return int64(1), nil
+				}
+				return int64(0), nil
+			case json.Number:
+				return parseNumber(t.String())
+			case int64, float64:
+				return t, nil
+			}
+			return nil, errWrongType(v, "number, string or bool")
+		}),
+		description: "Converts a string or bool into a number. Strings containing an integer within the range of a signed 64-bit integer are converted exactly, other values are converted to the nearest 64-bit floating point value. Strings that cannot be parsed, that are out of the range of a 64-bit float, or that are NaN or infinity result in an error. Booleans are converted to 1 or 0.",
+	},
+	"bool": {
+		fn: simpleMethod(func(v interface{}, args []interface{}) (interface{}, error) {
+			switch t := v.(type) {
+			case bool:
+				return t, nil
+			case string:
+				b, err := strconv.ParseBool(strings.TrimSpace(t))
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse '%v' as a bool", t)
+				}
+				return b, nil
+			}
+			if f, ok := toNumber(v); ok {
+				return f != 0, nil
+			}
+			return nil, errWrongType(v, "bool, string or number")
+		}),
+		description: "Converts a string or number into a bool. Accepted strings are `true`, `false`, `1`, `0`, `t`, `f` and their upper case variants, numbers are `true` when they are not zero.",
+	},
+	"timestamp": {
+		fn: simpleMethod(func(v interface{}, args []interface{}) (interface{}, error) {
+			var t time.Time
+			switch x := v.(type) {
+			case string:
+				layout := time.RFC3339Nano
+				if len(args) > 0 {
+					var err error
+					if layout, err = stringArg(args, 0); err != nil {
+						return nil, err
+					}
+				}
+				var err error
+				if t, err = time.Parse(layout, strings.TrimSpace(x)); err != nil {
+					return nil, fmt.Errorf("failed to parse '%v' as a timestamp: %v", x, err)
+				}
+			default:
+				f, ok := toNumber(v)
+				if !ok {
+					return nil, errWrongType(v, "string or number")
+				}
+				if i, isInt := v.(int64); isInt {
+					t = time.Unix(i, 0)
+				} else {
+					t = time.Unix(0, int64(f*float64(time.Second)))
+				}
+			}
+			return t.UTC().Format(time.RFC3339Nano), nil
+		}),
+		maxArgs:     1,
+		description: "Converts a unix timestamp in seconds, or a string following an optional Go layout (RFC 3339 by default), into an RFC 3339 timestamp string in UTC.",
+	},
+	"length": {
+		fn: simpleMethod(func(v interface{}, args []interface{}) (interface{}, error) {
+			switch t := v.(type) {
+			case string:
+				return int64(utf8.RuneCountInString(t)), nil
+			case []interface{}:
+				return int64(len(t)), nil
+			case map[string]interface{}:
+				return int64(len(t)), nil
+			}
+			return nil, errWrongType(v, "string, array or object")
+		}),
+		description: "Returns the length of a string, array or object.",
+	},
+	"uppercase": {
+		fn: stringMethod(func(s string, args []interface{}) (interface{}, error) {
+			return strings.ToUpper(s), nil
+		}),
+		description: "Converts a string to upper case.",
+	},
+	"lowercase": {
+		fn: stringMethod(func(s string, args []interface{}) (interface{}, error) {
+			return strings.ToLower(s), nil
+		}),
+		description: "Converts a string to lower case.",
+	},
+	"trim": {
+		fn: stringMethod(func(s string, args []interface{}) (interface{}, error) {
+			if len(args) == 0 {
+				return strings.TrimSpace(s), nil
+			}
+			cutset, err := stringArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			return strings.Trim(s, cutset), nil
+		}),
+		maxArgs:     1,
+		description: "Removes leading and trailing whitespace, or the characters of an optional cutset argument, from a string.",
+	},
+	"has_prefix": {
+		fn: stringMethod(func(s string, args []interface{}) (interface{}, error) {
+			prefix, err := stringArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			return strings.HasPrefix(s, prefix), nil
+		}),
+		minArgs:     1,
+		maxArgs:     1,
+		description: "Checks whether a string begins with the argument.",
+	},
+	"has_suffix": {
+		fn: stringMethod(func(s string, args []interface{}) (interface{}, error) {
+			suffix, err := stringArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			return strings.HasSuffix(s, suffix), nil
+		}),
+		minArgs:     1,
+		maxArgs:     1,
+		description: "Checks whether a string ends with the argument.",
+	},
+	"contains": {
+		fn: simpleMethod(func(v interface{}, args []interface{}) (interface{}, error) {
+			switch t := v.(type) {
+			case string:
+				sub, err := stringArg(args, 0)
+				if err != nil {
+					return nil, err
+				}
+				return strings.Contains(t, sub), nil
+			case []interface{}:
+				target := normalise(args[0])
+				for _, e := range t {
+					if reflect.DeepEqual(normalise(e), target) {
+						return true, nil
+					}
+				}
+				return false, nil
+			}
+			return nil, errWrongType(v, "string or array")
+		}),
+		minArgs:     1,
+		maxArgs:     1,
+		description: "Checks whether a string contains a substring, or an array contains an element.",
+	},
+	"replace": {
+		fn: stringMethod(func(s string, args []interface{}) (interface{}, error) {
+			old, err := stringArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			with, err := stringArg(args, 1)
+			if err != nil {
+				return nil, err
+			}
+			return strings.Replace(s, old, with, -1), nil
+		}),
+		minArgs:     2,
+		maxArgs:     2,
+		description: "Replaces all occurrences of the first argument within a string with the second.",
+	},
+	"split": {
+		fn: stringMethod(func(s string, args []interface{}) (interface{}, error) {
+			delim, err := stringArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			parts := strings.Split(s, delim)
+			arr := make([]interface{}, len(parts))
+			for i, p := range parts {
+				arr[i] = p
+			}
+			return arr, nil
+		}),
+		minArgs:     1,
+		maxArgs:     1,
+		description: "Splits a string into an array of strings by a delimiter.",
+	},
+	"join": {
+		fn: simpleMethod(func(v interface{}, args []interface{}) (interface{}, error) {
+			arr, ok := v.([]interface{})
+			if !ok {
+				return nil, errWrongType(v, "array")
+			}
+			delim := ""
+			if len(args) > 0 {
+				var err error
+				if delim, err = stringArg(args, 0); err != nil {
+					return nil, err
+				}
+			}
+			strs := make([]string, len(arr))
+			for i, e := range arr {
+				s, ok := e.(string)
+				if !ok {
+					return nil, fmt.Errorf("element %v: %v", i, errWrongType(e, "string"))
+				}
+				strs[i] = s
+			}
+			return strings.Join(strs, delim), nil
+		}),
+		maxArgs:     1,
+		description: "Joins an array of strings with an optional delimiter.",
+	},
+	"slice": {
+		fn: simpleMethod(func(v interface{}, args []interface{}) (interface{}, error) {
+			low, err := intArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			var length int
+			switch t := v.(type) {
+			case string:
+				length = len(t)
+			case []interface{}:
+				length = len(t)
+			default:
+				return nil, errWrongType(v, "string or array")
+			}
+			high := length
+			if len(args) > 1 {
+				if high, err = intArg(args, 1); err != nil {
+					return nil, err
+				}
+			}
+			low, high = resolveIndex(low, length), resolveIndex(high, length)
+			if high < low {
+				high = low
+			}
+			if s, ok := v.(string); ok {
+				return s[low:high], nil
+			}
+			return v.([]interface{})[low:high], nil
+		}),
+		minArgs:     1,
+		maxArgs:     2,
+		description: "Extracts a range of a string or array from a start index up to an optional end index (exclusive), negative indexes count back from the end.",
+	},
+	"index": {
+		fn: simpleMethod(func(v interface{}, args []interface{}) (interface{}, error) {
+			arr, ok := v.([]interface{})
+			if !ok {
+				return nil, errWrongType(v, "array")
+			}
+			i, err := intArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			if i < 0 {
+				i = len(arr) + i
+			}
+			if i < 0 || i >= len(arr) {
+				return nil, nil
+			}
+			return arr[i], nil
+		}),
+		minArgs:     1,
+		maxArgs:     1,
+		description: "Returns the element of an array at an index, negative indexes count back from the end.",
+	},
+	"floor": {
+		fn:          numberMethod(math.Floor),
+		description: "Rounds a number down.",
+	},
+	"ceil": {
+		fn:          numberMethod(math.Ceil),
+		description: "Rounds a number up.",
+	},
+	"round": {
+		fn:          numberMethod(math.Round),
+		description: "Rounds a number to the nearest integer.",
+	},
+	"abs": {
+		fn: simpleMethod(func(v interface{}, args []interface{}) (interface{}, error) {
+			if i, ok := v.(int64); ok {
+				if i < 0 {
+					return -i, nil
+				}
+				return i, nil
+			}
+			f, ok := toNumber(v)
+			if !ok {
+				return nil, errWrongType(v, "number")
+			}
+			return math.Abs(f), nil
+		}),
+		description: "Returns the absolute value of a number.",
+	},
+	"parse_timestamp": {
+		fn: stringMethod(func(s string, args []interface{}) (interface{}, error) {
+			layout := time.RFC3339Nano
+			if len(args) > 0 {
+				var err error
+				if layout, err = stringArg(args, 0); err != nil {
+					return nil, err
+				}
+			}
+			t, err := time.Parse(layout, s)
+			if err != nil {
+				return nil, err
+			}
+			if t.Nanosecond() == 0 {
+				return t.Unix(), nil
+			}
+			return float64(t.UnixNano()) / float64(time.Second), nil
+		}),
+		maxArgs:     1,
+		description: "Parses a string as a timestamp following an optional Go layout (RFC 3339 by default) and returns a unix timestamp in seconds.",
+	},
+	"format_timestamp": {
+		fn: simpleMethod(func(v interface{}, args []interface{}) (interface{}, error) {
+			f, ok := toNumber(v)
+			if !ok {
+				return nil, errWrongType(v, "number")
+			}
+			layout := time.RFC3339Nano
+			if len(args) > 0 {
+				var err error
+				if layout, err = stringArg(args, 0); err != nil {
+					return nil, err
+				}
+			}
+			secs, frac := math.Modf(f)
+			return time.Unix(int64(secs), int64(frac*float64(time.Second))).UTC().Format(layout), nil
+		}),
+		maxArgs:     1,
+		description: "Formats a unix timestamp in seconds as a string in UTC following an optional Go layout (RFC 3339 by default).",
+	},
+	"hash": {
+		fn: stringMethod(func(s string, args []interface{}) (interface{}, error) {
+			algo, err := stringArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			ctor, exists := hashers[algo]
+			if !exists {
+				return nil, fmt.Errorf("unrecognised hash algorithm: %v", algo)
+			}
+			h := ctor()
+			h.Write([]byte(s))
+			return hex.EncodeToString(h.Sum(nil)), nil
+		}),
+		minArgs:     1,
+		maxArgs:     1,
+		description: "Hashes a string and returns the result hex encoded. Supported algorithms are md5, sha1, sha256, sha512 and xxhash64.",
+	},
+	"encode": {
+		fn: stringMethod(func(s string, args []interface{}) (interface{}, error) {
+			scheme, err := stringArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			switch scheme {
+			case "base64":
+				return base64.StdEncoding.EncodeToString([]byte(s)), nil
+			case "hex":
+				return hex.EncodeToString([]byte(s)), nil
+			}
+			return nil, fmt.Errorf("unrecognised encoding scheme: %v", scheme)
+		}),
+		minArgs:     1,
+		maxArgs:     1,
+		description: "Encodes a string with a scheme, supported schemes are base64 and hex.",
+	},
+	"decode": {
+		fn: stringMethod(func(s string, args []interface{}) (interface{}, error) {
+			scheme, err := stringArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			var b []byte
+			switch scheme {
+			case "base64":
+				b, err = base64.StdEncoding.DecodeString(s)
+			case "hex":
+				b, err = hex.DecodeString(s)
+			default:
+				return nil, fmt.Errorf("unrecognised encoding scheme: %v", scheme)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return string(b), nil
+		}),
+		minArgs:     1,
+		maxArgs:     1,
+		description: "Decodes a string with a scheme, supported schemes are base64 and hex.",
+	},
+	"parse_json": {
+		fn: stringMethod(func(s string, args []interface{}) (interface{}, error) {
+			var v interface{}
+			if err := json.Unmarshal([]byte(s), &v); err != nil {
+				return nil, err
+			}
+			return v, nil
+		}),
+		description: "Parses a string as a JSON document.",
+	},
+	"format_json": {
+		fn: simpleMethod(func(v interface{}, args []interface{}) (interface{}, error) {
+			var buf bytes.Buffer
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(false)
+			if err := enc.Encode(v); err != nil {
+				return nil, err
+			}
+			return strings.TrimSuffix(buf.String(), "\n"), nil
+		}),
+		description: "Serialises the target as a JSON string.",
+	},
+	"keys": {
+		fn: simpleMethod(func(v interface{}, args []interface{}) (interface{}, error) {
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, errWrongType(v, "object")
+			}
+			keys := sortedKeys(obj)
+			arr := make([]interface{}, len(keys))
+			for i, k := range keys {
+				arr[i] = k
+			}
+			return arr, nil
+		}),
+		description: "Returns the keys of an object as a sorted array.",
+	},
+	"map_each": {
+		fn: func(ctx *context, v interface{}, args []query) (interface{}, error) {
+			switch t := v.(type) {
+			case []interface{}:
+				arr := make([]interface{}, 0, len(t))
+				for i, e := range t {
+					res, err := args[0].exec(ctx.withValue(e))
+					if err != nil {
+						return nil, fmt.Errorf("element %v: %v", i, err)
+					}
+					switch res.(type) {
+					case deleted:
+						continue
+					case nothing:
+						res = e
+					}
+					arr = append(arr, res)
+				}
+				return arr, nil
+			case map[string]interface{}:
+				obj := make(map[string]interface{}, len(t))
+				for _, k := range sortedKeys(t) {
+					res, err := args[0].exec(ctx.withValue(map[string]interface{}{
+						"key":   k,
+						"value": t[k],
+					}))
+					if err != nil {
+						return nil, fmt.Errorf("field %v: %v", k, err)
+					}
+					switch res.(type) {
+					case deleted:
+						continue
+					case nothing:
+						res = t[k]
+					}
+					obj[k] = res
+				}
+				return obj, nil
+			}
+			return nil, errWrongType(v, "array or object")
+		},
+		minArgs:     1,
+		maxArgs:     1,
+		description: "Executes the argument for each element of an array, where `this` is the element, and replaces the element with the result. For objects `this` is an object with the fields `key` and `value`, and the value is replaced with the result. Elements resulting in `deleted()` are removed.",
+	},
+	"filter": {
+		fn: func(ctx *context, v interface{}, args []query) (interface{}, error) {
+			keep := func(this interface{}) (bool, error) {
+				res, err := args[0].exec(ctx.withValue(this))
+				if err != nil {
+					return false, err
+				}
+				b, ok := res.(bool)
+				if !ok {
+					return false, errWrongType(res, "bool")
+				}
+				return b, nil
+			}
+			switch t := v.(type) {
+			case []interface{}:
+				arr := make([]interface{}, 0, len(t))
+				for i, e := range t {
+					ok, err := keep(e)
+					if err != nil {
+						return nil, fmt.Errorf("element %v: %v", i, err)
+					}
+					if ok {
+						arr = append(arr, e)
+					}
+				}
+				return arr, nil
+			case map[string]interface{}:
+				obj := make(map[string]interface{}, len(t))
+				for _, k := range sortedKeys(t) {
+					ok, err := keep(map[string]interface{}{
+						"key":   k,
+						"value": t[k],
+					})
+					if err != nil {
+						return nil, fmt.Errorf("field %v: %v", k, err)
+					}
+					if ok {
+						obj[k] = t[k]
+					}
+				}
+				return obj, nil
+			}
+			return nil, errWrongType(v, "array or object")
+		},
+		minArgs:     1,
+		maxArgs:     1,
+		description: "Executes the argument for each element of an array, where `this` is the element, and removes elements that do not result in `true`. For objects `this` is an object with the fields `key` and `value`.",
+	},
+}
+
+//------------------------------------------------------------------------------
+
+// MethodsDocs returns a markdown list of all methods.
+func MethodsDocs() string {
+	names := make([]string, 0, len(methods))
+	for k := range methods {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		fmt.Fprintf(&buf, "- `%v`: %v", name, methods[name].description)
+	}
+	return buf.String()
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,137 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bloblang
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/message"
+)
+
+func TestMethods(t *testing.T) {
+	input := `{"str":" Foo Bar ","num":2.5,"list":[3,1,2],"obj":{"a":1,"b":2},"ts":"2020-01-02T03:04:05Z"}`
+
+	tests := map[string]string{
+		`this.str.trim()`:                           `Foo Bar`,
+		`this.str.trim().lowercase()`:               `foo bar`,
+		`this.str.trim().split(" ")`:                `["Foo","Bar"]`,
+		`this.str.trim().split(" ").join("-")`:      `Foo-Bar`,
+		`this.str.trim().replace("Bar", "Baz")`:     `Foo Baz`,
+		`this.str.trim().slice(0, 3)`:               `Foo`,
+		`this.str.trim().has_prefix("Foo")`:         `true`,
+		`this.str.contains("Baz")`:                  `false`,
+		`this.str.length()`:                         `9`,
+		`this.num.floor()`:                          `2`,
+		`this.num.ceil()`:                           `3`,
+		`this.num.round()`:                          `3`,
+		`(0 - this.num).abs()`:                      `2.5`,
+		`this.num.string()`:                         `2.5`,
+		`"10".number() + 1`:                         `11`,
+		`this.list.length()`:                        `3`,
+		`this.list.filter(this > 1)`:                `[3,2]`,
+		`this.list.map_each(this * 10)`:             `[30,10,20]`,
+		`this.list.slice(-2)`:                       `[1,2]`,
+		`this.list.contains(2)`:                     `true`,
+		`this.obj.keys()`:                           `["a","b"]`,
+		`this.obj.filter(this.value > 1)`:           `{"b":2}`,
+		`this.obj.map_each(this.key + "x")`:         `{"a":"ax","b":"bx"}`,
+		`this.obj.type()`:                           `object`,
+		`this.missing.type()`:                       `null`,
+		`this.missing.or("default")`:                `default`,
+		`this.str.not_null()`:                       ` Foo Bar `,
+		`"foo".hash("sha256")`:                      `2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae`,
+		`"foo".hash("md5")`:                         `acbd18db4cc2f85cedef654fccc4a4d8`,
+		`"foo".encode("base64")`:                    `Zm9v`,
+		`"Zm9v".decode("base64")`:                   `foo`,
+		`this.ts.parse_timestamp()`:                 `1577934245`,
+		`1577934245.format_timestamp()`:             `2020-01-02T03:04:05Z`,
+		`1577934245.format_timestamp("2006-01-02")`: `2020-01-02`,
+		`"{\"a\":[1,2]}".parse_json().a.index(1)`:   `2`,
+		`this.obj.format_json()`:                    `{"a":1,"b":2}`,
+		`env("BLOBLANG_TEST_VAR")`:                  `foo`,
+		`"9007199254740993".number()`:               `9007199254740993`,
+		`" 1.5 ".number() * 2`:                      `3`,
+		`"1e3".number()`:                            `1000`,
+		`true.number()`:                             `1`,
+		`this.num.number()`:                         `2.5`,
+		`"TRUE".bool()`:                             `true`,
+		`"0".bool()`:                                `false`,
+		`this.num.bool()`:                           `true`,
+		`this.ts.timestamp()`:                       `2020-01-02T03:04:05Z`,
+		`"2020-01-02T05:04:05+02:00".timestamp()`:   `2020-01-02T03:04:05Z`,
+		`"02/01/2020".timestamp("02/01/2006")`:      `2020-01-02T00:00:00Z`,
+		`1577934245.timestamp()`:                    `2020-01-02T03:04:05Z`,
+		`1577934245.5.timestamp()`:                  `2020-01-02T03:04:05.5Z`,
+	}
+
+	os.Setenv("BLOBLANG_TEST_VAR", "foo")
+	defer os.Unsetenv("BLOBLANG_TEST_VAR")
+
+	for query, exp := range tests {
+		m, err := NewMapping("root = " + query)
+		if err != nil {
+			t.Errorf("%v: failed to parse: %v", query, err)
+			continue
+		}
+		res, err := m.MapPart(0, message.New([][]byte{[]byte(input)}))
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", query, err)
+			continue
+		}
+		if act := string(res.Get()); act != exp {
+			t.Errorf("%v: wrong result: %v != %v", query, act, exp)
+		}
+	}
+}
+
+func TestMethodErrors(t *testing.T) {
+	tests := map[string]string{
+		`this.num.uppercase()`:       "method 'uppercase': expected string value, found number",
+		`this.str.hash("nope")`:      "method 'hash': unrecognised hash algorithm: nope",
+		`this.missing.not_null()`:    "method 'not_null': value is null",
+		`this.str.parse_timestamp()`: "method 'parse_timestamp'",
+		`this.num / 0`:               "divide by zero",
+		`"foo".number()`:             "failed to parse 'foo' as a number",
+		`"1e400".number()`:           "failed to parse '1e400' as a number",
+		`"NaN".number()`:             "failed to parse 'NaN' as a number",
+		`this.str.bool()`:            "failed to parse 'foo' as a bool",
+		`null.bool()`:                "expected bool, string or number value, found null",
+		`this.str.timestamp()`:       "failed to parse 'foo' as a timestamp",
+	}
+
+	for query, exp := range tests {
+		m, err := NewMapping("root = " + query)
+		if err != nil {
+			t.Errorf("%v: failed to parse: %v", query, err)
+			continue
+		}
+		_, err = m.MapPart(0, message.New([][]byte{[]byte(`{"str":"foo","num":5}`)}))
+		if err == nil {
+			t.Errorf("%v: expected error", query)
+			continue
+		}
+		if !strings.Contains(err.Error(), exp) {
+			t.Errorf("%v: wrong error: %v does not contain %v", query, err, exp)
+		}
+	}
+}
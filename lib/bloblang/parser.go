@@ -0,0 +1,453 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bloblang
+
+import (
+	"strconv"
+	"strings"
+)
+
+//------------------------------------------------------------------------------
+
+// assignment is a single statement of a mapping.
+type assignment struct {
+	line int
+
+	// meta is true if the assignment targets the metadata of the message
+	// rather than the document.
+	meta    bool
+	metaKey string
+
+	// path is the target path within the document, an empty path targets the
+	// root of the document.
+	path []string
+
+	value query
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peekAt(offset int) token {
+	if i := p.pos + offset; i < len(p.tokens) {
+		return p.tokens[i]
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isSymbol(s string) bool {
+	t := p.peek()
+	return t.kind == tokSymbol && t.val == s
+}
+
+func (p *parser) isIdent(s string) bool {
+	t := p.peek()
+	return t.kind == tokIdent && t.val == s
+}
+
+func (p *parser) expectSymbol(s string) error {
+	if t := p.next(); t.kind != tokSymbol || t.val != s {
+		return errAt(t.line, t.col, "expected '%v', found %v", s, t)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// parse parses a mapping into a list of assignments.
+func parse(script string) ([]assignment, error) {
+	tokens, err := lex(script)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+
+	var assignments []assignment
+	for {
+		for p.peek().kind == tokNewline {
+			p.next()
+		}
+		if p.peek().kind == tokEOF {
+			break
+		}
+		a, err := p.parseAssignment()
+		if err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, a)
+		if t := p.peek(); t.kind != tokNewline && t.kind != tokEOF {
+			return nil, errAt(t.line, t.col, "expected end of assignment, found %v", t)
+		}
+	}
+	return assignments, nil
+}
+
+func (p *parser) parseAssignment() (assignment, error) {
+	start := p.peek()
+	a := assignment{line: start.line}
+
+	switch {
+	case start.kind == tokIdent && start.val == "meta":
+		p.next()
+		a.meta = true
+		if t := p.peek(); t.kind == tokIdent || t.kind == tokString {
+			a.metaKey = p.next().val
+		}
+	case start.kind == tokIdent && start.val == "root":
+		p.next()
+		for p.isSymbol(".") {
+			p.next()
+			t := p.next()
+			if t.kind != tokIdent && t.kind != tokString {
+				return a, errAt(t.line, t.col, "expected field name, found %v", t)
+			}
+			a.path = append(a.path, t.val)
+		}
+	case start.kind == tokIdent && start.val == "this":
+		return a, errAt(start.line, start.col, "cannot assign to this, assign to root instead")
+	case start.kind == tokIdent || start.kind == tokString:
+		a.path = append(a.path, p.next().val)
+		for p.isSymbol(".") {
+			p.next()
+			t := p.next()
+			if t.kind != tokIdent && t.kind != tokString {
+				return a, errAt(t.line, t.col, "expected field name, found %v", t)
+			}
+			a.path = append(a.path, t.val)
+		}
+	default:
+		return a, errAt(start.line, start.col, "expected assignment target, found %v", start)
+	}
+
+	if err := p.expectSymbol("="); err != nil {
+		return a, err
+	}
+
+	var err error
+	a.value, err = p.parseQuery()
+	return a, err
+}
+
+//------------------------------------------------------------------------------
+
+func (p *parser) parseQuery() (query, error) {
+	return p.parseBinary(0)
+}
+
+// binaryPrecedence lists binary operators from the lowest to highest binding.
+var binaryPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", ">", ">=", "<", "<="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) parseBinary(level int) (query, error) {
+	if level >= len(binaryPrecedence) {
+		return p.parseUnary()
+	}
+
+	lhs, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := p.peek()
+		if t.kind != tokSymbol {
+			return lhs, nil
+		}
+		matched := false
+		for _, op := range binaryPrecedence[level] {
+			if t.val == op {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return lhs, nil
+		}
+		p.next()
+
+		rhs, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		lhs = &binaryNode{op: t.val, lhs: lhs, rhs: rhs}
+	}
+}
+
+func (p *parser) parseUnary() (query, error) {
+	switch {
+	case p.isSymbol("!"):
+		p.next()
+		target, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{target: target}, nil
+	case p.isSymbol("-"):
+		p.next()
+		target, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &negNode{target: target}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (query, error) {
+	target, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isSymbol(".") {
+		p.next()
+		t := p.next()
+		switch t.kind {
+		case tokIdent:
+			if !p.isSymbol("(") {
+				target = &fieldNode{target: target, key: t.val}
+				continue
+			}
+			spec, exists := methods[t.val]
+			if !exists {
+				return nil, errAt(t.line, t.col, "unrecognised method '%v'", t.val)
+			}
+			args, err := p.parseArgs(t, spec.minArgs, spec.maxArgs)
+			if err != nil {
+				return nil, err
+			}
+			target = &methodNode{name: t.val, target: target, spec: spec, args: args}
+		case tokString:
+			target = &fieldNode{target: target, key: t.val}
+		case tokNumber:
+			i, err := strconv.Atoi(t.val)
+			if err != nil {
+				return nil, errAt(t.line, t.col, "expected array index, found %v", t)
+			}
+			target = &indexNode{target: target, index: i}
+		default:
+			return nil, errAt(t.line, t.col, "expected field or method name, found %v", t)
+		}
+	}
+	return target, nil
+}
+
+func (p *parser) parseArgs(name token, min, max int) ([]query, error) {
+	if err := p.expectSymbol("("); err != nil {
+		return nil, err
+	}
+	var args []query
+	for !p.isSymbol(")") {
+		arg, err := p.parseQuery()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if !p.isSymbol(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expectSymbol(")"); err != nil {
+		return nil, err
+	}
+	if len(args) < min || len(args) > max {
+		expected := strconv.Itoa(min)
+		if max > min {
+			expected = "between " + expected + " and " + strconv.Itoa(max)
+		}
+		return nil, errAt(name.line, name.col, "'%v' expected %v arguments, received %v", name.val, expected, len(args))
+	}
+	return args, nil
+}
+
+func (p *parser) parsePrimary() (query, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		if !strings.Contains(t.val, ".") {
+			if i, err := strconv.ParseInt(t.val, 10, 64); err == nil {
+				return &literalNode{value: i}, nil
+			}
+		}
+		f, err := strconv.ParseFloat(t.val, 64)
+		if err != nil {
+			return nil, errAt(t.line, t.col, "invalid number: %v", err)
+		}
+		return &literalNode{value: f}, nil
+	case tokString:
+		return &literalNode{value: t.val}, nil
+	case tokSymbol:
+		switch t.val {
+		case "(":
+			q, err := p.parseQuery()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expectSymbol(")"); err != nil {
+				return nil, err
+			}
+			return q, nil
+		case "[":
+			return p.parseArray()
+		case "{":
+			return p.parseObject()
+		}
+	case tokIdent:
+		switch t.val {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		case "this":
+			return &thisNode{}, nil
+		case "if":
+			return p.parseIf()
+		case "root":
+			return nil, errAt(t.line, t.col, "cannot query root, query the input document with this instead")
+		}
+		if !p.isSymbol("(") {
+			// A bare field name is shorthand for a field of this.
+			return &fieldNode{target: &thisNode{}, key: t.val}, nil
+		}
+		spec, exists := functions[t.val]
+		if !exists {
+			return nil, errAt(t.line, t.col, "unrecognised function '%v'", t.val)
+		}
+		args, err := p.parseArgs(t, spec.minArgs, spec.maxArgs)
+		if err != nil {
+			return nil, err
+		}
+		return &functionNode{name: t.val, fn: spec.fn, args: args}, nil
+	}
+	return nil, errAt(t.line, t.col, "expected query, found %v", t)
+}
+
+func (p *parser) parseArray() (query, error) {
+	n := &arrayNode{}
+	for !p.isSymbol("]") {
+		e, err := p.parseQuery()
+		if err != nil {
+			return nil, err
+		}
+		n.elements = append(n.elements, e)
+		if !p.isSymbol(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expectSymbol("]"); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (p *parser) parseObject() (query, error) {
+	n := &objectNode{}
+	for !p.isSymbol("}") {
+		k := p.next()
+		if k.kind != tokString && k.kind != tokIdent {
+			return nil, errAt(k.line, k.col, "expected object key, found %v", k)
+		}
+		if err := p.expectSymbol(":"); err != nil {
+			return nil, err
+		}
+		v, err := p.parseQuery()
+		if err != nil {
+			return nil, err
+		}
+		n.keys = append(n.keys, k.val)
+		n.values = append(n.values, v)
+		if !p.isSymbol(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expectSymbol("}"); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (p *parser) parseIf() (query, error) {
+	n := &ifNode{}
+	for {
+		cond, err := p.parseQuery()
+		if err != nil {
+			return nil, err
+		}
+		branch, err := p.parseBlock()
+		if err != nil {
+			return nil, err
+		}
+		n.conditions = append(n.conditions, cond)
+		n.branches = append(n.branches, branch)
+
+		if !p.isIdent("else") {
+			return n, nil
+		}
+		p.next()
+		if !p.isIdent("if") {
+			if n.elseBranch, err = p.parseBlock(); err != nil {
+				return nil, err
+			}
+			return n, nil
+		}
+		p.next()
+	}
+}
+
+func (p *parser) parseBlock() (query, error) {
+	if err := p.expectSymbol("{"); err != nil {
+		return nil, err
+	}
+	q, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expectSymbol("}"); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,529 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bloblang
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// deleted is a value returned by the deleted() function, when assigned to a
+// target the target is removed.
+type deleted struct{}
+
+// nothing is a value returned by an if expression without a matching branch,
+// when assigned to a target the assignment is skipped.
+type nothing struct{}
+
+// context contains the state available to queries during execution.
+type context struct {
+	// value returns the document referenced by `this`, which is resolved
+	// lazily in order to avoid parsing messages that are never queried.
+	value func() (interface{}, error)
+	msg   types.Message
+	index int
+}
+
+// withValue returns a copy of the context where `this` references v.
+func (c *context) withValue(v interface{}) *context {
+	return &context{
+		value: func() (interface{}, error) {
+			return v, nil
+		},
+		msg:   c.msg,
+		index: c.index,
+	}
+}
+
+// query is a parsed expression that is executed against a context.
+type query interface {
+	exec(ctx *context) (interface{}, error)
+}
+
+//------------------------------------------------------------------------------
+
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case float64, int64, json.Number:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case deleted:
+		return "delete"
+	case nothing:
+		return "nothing"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func errWrongType(v interface{}, exp string) error {
+	return fmt.Errorf("expected %v value, found %v", exp, typeOf(v))
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case int64:
+		return float64(t), true
+	case json.Number:
+		f, err := t.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case nil:
+		return "null"
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	}
+	if b, err := json.Marshal(v); err == nil {
+		return string(b)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// normalise converts numbers within a structure to float64 so that structures
+// can be compared regardless of how their numbers were created.
+func normalise(v interface{}) interface{} {
+	switch t := v.(type) {
+	case int64, json.Number:
+		f, _ := toNumber(t)
+		return f
+	case []interface{}:
+		n := make([]interface{}, len(t))
+		for i, e := range t {
+			n[i] = normalise(e)
+		}
+		return n
+	case map[string]interface{}:
+		n := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			n[k] = normalise(e)
+		}
+		return n
+	}
+	return v
+}
+
+// deepCopy clones a structured value so that it can be modified without
+// affecting the original.
+func deepCopy(v interface{}) interface{} {
+	switch t := v.(type) {
+	case []interface{}:
+		n := make([]interface{}, len(t))
+		for i, e := range t {
+			n[i] = deepCopy(e)
+		}
+		return n
+	case map[string]interface{}:
+		n := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			n[k] = deepCopy(e)
+		}
+		return n
+	}
+	return v
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//------------------------------------------------------------------------------
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) exec(ctx *context) (interface{}, error) {
+	return deepCopy(n.value), nil
+}
+
+type thisNode struct{}
+
+func (n *thisNode) exec(ctx *context) (interface{}, error) {
+	return ctx.value()
+}
+
+type fieldNode struct {
+	target query
+	key    string
+}
+
+func (n *fieldNode) exec(ctx *context) (interface{}, error) {
+	v, err := n.target.exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch t := v.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return t[n.key], nil
+	}
+	return nil, fmt.Errorf("failed to get field '%v': %v", n.key, errWrongType(v, "object"))
+}
+
+type indexNode struct {
+	target query
+	index  int
+}
+
+func (n *indexNode) exec(ctx *context) (interface{}, error) {
+	v, err := n.target.exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch t := v.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		if n.index >= len(t) {
+			return nil, nil
+		}
+		return t[n.index], nil
+	}
+	return nil, fmt.Errorf("failed to get index %v: %v", n.index, errWrongType(v, "array"))
+}
+
+type arrayNode struct {
+	elements []query
+}
+
+func (n *arrayNode) exec(ctx *context) (interface{}, error) {
+	arr := make([]interface{}, 0, len(n.elements))
+	for _, e := range n.elements {
+		v, err := e.exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		switch v.(type) {
+		case deleted, nothing:
+			continue
+		}
+		arr = append(arr, v)
+	}
+	return arr, nil
+}
+
+type objectNode struct {
+	keys   []string
+	values []query
+}
+
+func (n *objectNode) exec(ctx *context) (interface{}, error) {
+	obj := make(map[string]interface{}, len(n.keys))
+	for i, k := range n.keys {
+		v, err := n.values[i].exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		switch v.(type) {
+		case deleted, nothing:
+			continue
+		}
+		obj[k] = v
+	}
+	return obj, nil
+}
+
+type ifNode struct {
+	conditions []query
+	branches   []query
+	elseBranch query
+}
+
+func (n *ifNode) exec(ctx *context) (interface{}, error) {
+	for i, cond := range n.conditions {
+		v, err := cond.exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("if condition: %v", errWrongType(v, "bool"))
+		}
+		if b {
+			return n.branches[i].exec(ctx)
+		}
+	}
+	if n.elseBranch != nil {
+		return n.elseBranch.exec(ctx)
+	}
+	return nothing{}, nil
+}
+
+//------------------------------------------------------------------------------
+
+type notNode struct {
+	target query
+}
+
+func (n *notNode) exec(ctx *context) (interface{}, error) {
+	v, err := n.target.exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return nil, fmt.Errorf("operator '!': %v", errWrongType(v, "bool"))
+	}
+	return !b, nil
+}
+
+type negNode struct {
+	target query
+}
+
+func (n *negNode) exec(ctx *context) (interface{}, error) {
+	v, err := n.target.exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch t := v.(type) {
+	case int64:
+		return -t, nil
+	}
+	f, ok := toNumber(v)
+	if !ok {
+		return nil, fmt.Errorf("operator '-': %v", errWrongType(v, "number"))
+	}
+	return -f, nil
+}
+
+type binaryNode struct {
+	op  string
+	lhs query
+	rhs query
+}
+
+func (n *binaryNode) exec(ctx *context) (interface{}, error) {
+	lhs, err := n.lhs.exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Logical operators short circuit.
+	if n.op == "&&" || n.op == "||" {
+		lb, ok := lhs.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator '%v': %v", n.op, errWrongType(lhs, "bool"))
+		}
+		if (n.op == "&&" && !lb) || (n.op == "||" && lb) {
+			return lb, nil
+		}
+		rhs, err := n.rhs.exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		rb, ok := rhs.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator '%v': %v", n.op, errWrongType(rhs, "bool"))
+		}
+		return rb, nil
+	}
+
+	rhs, err := n.rhs.exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return reflect.DeepEqual(normalise(lhs), normalise(rhs)), nil
+	case "!=":
+		return !reflect.DeepEqual(normalise(lhs), normalise(rhs)), nil
+	case ">", ">=", "<", "<=":
+		return compare(n.op, lhs, rhs)
+	case "+":
+		if ls, ok := lhs.(string); ok {
+			rs, ok := rhs.(string)
+			if !ok {
+				return nil, fmt.Errorf("operator '+': %v", errWrongType(rhs, "string"))
+			}
+			return ls + rs, nil
+		}
+	}
+	return arithmetic(n.op, lhs, rhs)
+}
+
+func compare(op string, lhs, rhs interface{}) (interface{}, error) {
+	var c int
+	if ls, ok := lhs.(string); ok {
+		rs, ok := rhs.(string)
+		if !ok {
+			return nil, fmt.Errorf("operator '%v': %v", op, errWrongType(rhs, "string"))
+		}
+		switch {
+		case ls < rs:
+			c = -1
+		case ls > rs:
+			c = 1
+		}
+	} else {
+		lf, ok := toNumber(lhs)
+		if !ok {
+			return nil, fmt.Errorf("operator '%v': %v", op, errWrongType(lhs, "number"))
+		}
+		rf, ok := toNumber(rhs)
+		if !ok {
+			return nil, fmt.Errorf("operator '%v': %v", op, errWrongType(rhs, "number"))
+		}
+		switch {
+		case lf < rf:
+			c = -1
+		case lf > rf:
+			c = 1
+		}
+	}
+	switch op {
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	case "<":
+		return c < 0, nil
+	}
+	return c <= 0, nil
+}
+
+func arithmetic(op string, lhs, rhs interface{}) (interface{}, error) {
+	li, lInt := lhs.(int64)
+	ri, rInt := rhs.(int64)
+	if lInt && rInt && op != "/" {
+		switch op {
+		case "+":
+			return li + ri, nil
+		case "-":
+			return li - ri, nil
+		case "*":
+			return li * ri, nil
+		case "%":
+			if ri == 0 {
+				return nil, fmt.Errorf("operator '%v': attempted to divide by zero", op)
+			}
+			return li % ri, nil
+		}
+	}
+
+	lf, ok := toNumber(lhs)
+	if !ok {
+		return nil, fmt.Errorf("operator '%v': %v", op, errWrongType(lhs, "number"))
+	}
+	rf, ok := toNumber(rhs)
+	if !ok {
+		return nil, fmt.Errorf("operator '%v': %v", op, errWrongType(rhs, "number"))
+	}
+	switch op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, fmt.Errorf("operator '%v': attempted to divide by zero", op)
+		}
+		return lf / rf, nil
+	case "%":
+		if rf == 0 {
+			return nil, fmt.Errorf("operator '%v': attempted to divide by zero", op)
+		}
+		return math.Mod(lf, rf), nil
+	}
+	return nil, fmt.Errorf("unknown operator '%v'", op)
+}
+
+//------------------------------------------------------------------------------
+
+type functionNode struct {
+	name string
+	fn   function
+	args []query
+}
+
+func (n *functionNode) exec(ctx *context) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, a := range n.args {
+		var err error
+		if args[i], err = a.exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+	v, err := n.fn(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("function '%v': %v", n.name, err)
+	}
+	return v, nil
+}
+
+type methodNode struct {
+	name   string
+	target query
+	spec   methodSpec
+	args   []query
+}
+
+func (n *methodNode) exec(ctx *context) (interface{}, error) {
+	v, err := n.target.exec(ctx)
+	if err != nil {
+		if n.spec.onErr != nil {
+			return n.spec.onErr(ctx, err, n.args)
+		}
+		return nil, err
+	}
+	if v, err = n.spec.fn(ctx, v, n.args); err != nil {
+		return nil, fmt.Errorf("method '%v': %v", n.name, err)
+	}
+	return v, nil
+}
+
+//------------------------------------------------------------------------------
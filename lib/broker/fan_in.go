@@ -109,6 +109,17 @@ func (i *FanIn) loop() {
 	}
 }
 
+// Connected returns a boolean indicating whether all inputs of the broker are
+// currently connected to their targets.
+func (i *FanIn) Connected() bool {
+	for _, closable := range i.closables {
+		if !types.IsConnected(closable) {
+			return false
+		}
+	}
+	return true
+}
+
 // CloseAsync shuts down the FanIn broker and stops processing requests.
 func (i *FanIn) CloseAsync() {
 	for _, closable := range i.closables {
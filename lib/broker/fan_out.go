@@ -166,6 +166,17 @@ func (o *FanOut) loop() {
 	}
 }
 
+// Connected returns a boolean indicating whether all outputs of the broker are
+// currently connected to their targets.
+func (o *FanOut) Connected() bool {
+	for _, out := range o.outputs {
+		if !types.IsConnected(out) {
+			return false
+		}
+	}
+	return true
+}
+
 // CloseAsync shuts down the FanOut broker and stops processing requests.
 func (o *FanOut) CloseAsync() {
 	if atomic.CompareAndSwapInt32(&o.running, 1, 0) {
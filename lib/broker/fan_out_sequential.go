@@ -0,0 +1,191 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package broker
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// FanOutSequential is a broker that implements types.Consumer and sends each
+// message to an array of outputs one at a time in the order in which they are
+// listed. An output is only sent a message once all outputs before it have
+// successfully sent it, and if an output fails to send a message the remaining
+// outputs are skipped and the error is returned to the source.
+type FanOutSequential struct {
+	running int32
+
+	logger log.Modular
+	stats  metrics.Type
+
+	transactions <-chan types.Transaction
+
+	outputTsChans []chan types.Transaction
+	outputs       []types.Output
+
+	closedChan chan struct{}
+	closeChan  chan struct{}
+}
+
+// NewFanOutSequential creates a new FanOutSequential type by providing outputs.
+func NewFanOutSequential(
+	outputs []types.Output, logger log.Modular, stats metrics.Type,
+) (*FanOutSequential, error) {
+	o := &FanOutSequential{
+		running:      1,
+		stats:        stats,
+		logger:       logger.NewModule(".broker.fan_out_sequential"),
+		transactions: nil,
+		outputs:      outputs,
+		closedChan:   make(chan struct{}),
+		closeChan:    make(chan struct{}),
+	}
+
+	o.outputTsChans = make([]chan types.Transaction, len(o.outputs))
+	for i := range o.outputTsChans {
+		o.outputTsChans[i] = make(chan types.Transaction)
+		if err := o.outputs[i].Consume(o.outputTsChans[i]); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Consume assigns a new transactions channel for the broker to read.
+func (o *FanOutSequential) Consume(transactions <-chan types.Transaction) error {
+	if o.transactions != nil {
+		return types.ErrAlreadyStarted
+	}
+	o.transactions = transactions
+
+	go o.loop()
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// loop is an internal loop that brokers incoming messages to many outputs.
+func (o *FanOutSequential) loop() {
+	defer func() {
+		for _, c := range o.outputTsChans {
+			close(c)
+		}
+		close(o.closedChan)
+	}()
+
+	var (
+		mMsgsRcvd  = o.stats.GetCounter("broker.fan_out_sequential.messages.received")
+		mOutputErr = o.stats.GetCounter("broker.fan_out_sequential.output.error")
+		mMsgsSnt   = o.stats.GetCounter("broker.fan_out_sequential.messages.sent")
+		mLatencies = make([]metrics.StatTimer, len(o.outputs))
+		mErrs      = make([]metrics.StatCounter, len(o.outputs))
+	)
+	for i := range o.outputs {
+		mLatencies[i] = o.stats.GetTimer(fmt.Sprintf("broker.fan_out_sequential.%v.latency", i))
+		mErrs[i] = o.stats.GetCounter(fmt.Sprintf("broker.fan_out_sequential.%v.error", i))
+	}
+
+	resChan := make(chan types.Response)
+	for atomic.LoadInt32(&o.running) == 1 {
+		var ts types.Transaction
+		var open bool
+
+		select {
+		case ts, open = <-o.transactions:
+			if !open {
+				return
+			}
+		case <-o.closeChan:
+			return
+		}
+		mMsgsRcvd.Incr(1)
+
+		var res types.Response = response.NewAck()
+		for i, ot := range o.outputTsChans {
+			started := time.Now()
+			select {
+			case ot <- types.NewTransaction(ts.Payload.Copy(), resChan):
+			case <-o.closeChan:
+				return
+			}
+			select {
+			case res = <-resChan:
+			case <-o.closeChan:
+				return
+			}
+			mLatencies[i].Timing(time.Since(started).Nanoseconds())
+			if res.Error() != nil {
+				o.logger.Errorf("Failed to dispatch sequential fan out message to output %v: %v\n", i, res.Error())
+				mOutputErr.Incr(1)
+				mErrs[i].Incr(1)
+				break
+			}
+			mMsgsSnt.Incr(1)
+		}
+
+		select {
+		case ts.ResponseChan <- res:
+		case <-o.closeChan:
+			return
+		}
+	}
+}
+
+// Connected returns a boolean indicating whether all outputs of the broker are
+// currently connected to their targets.
+func (o *FanOutSequential) Connected() bool {
+	for _, out := range o.outputs {
+		if !types.IsConnected(out) {
+			return false
+		}
+	}
+	return true
+}
+
+// CloseAsync shuts down the FanOutSequential broker and stops processing
+// requests.
+func (o *FanOutSequential) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&o.running, 1, 0) {
+		close(o.closeChan)
+	}
+}
+
+// WaitForClose blocks until the FanOutSequential broker has closed down.
+func (o *FanOutSequential) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-o.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,139 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package broker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func TestFanOutSequentialInterfaces(t *testing.T) {
+	f := &FanOutSequential{}
+	if types.Consumer(f) == nil {
+		t.Errorf("FanOutSequential: nil types.Consumer")
+	}
+	if types.Closable(f) == nil {
+		t.Errorf("FanOutSequential: nil types.Closable")
+	}
+}
+
+func TestFanOutSequentialOrdered(t *testing.T) {
+	mockOne := MockOutputType{}
+	mockTwo := MockOutputType{}
+
+	outputs := []types.Output{&mockOne, &mockTwo}
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	stats := metrics.NewLocal()
+	oTM, err := NewFanOutSequential(outputs, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(readChan); err == nil {
+		t.Error("Expected error on duplicate receive call")
+	}
+
+	testErr := errors.New("this is a test")
+	for _, errOutput := range []int{-1, 0, 1} {
+		select {
+		case readChan <- types.NewTransaction(message.New([][]byte{[]byte("hello world")}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker send")
+		}
+
+		var expErr error
+		for i, mock := range []*MockOutputType{&mockOne, &mockTwo} {
+			var ts types.Transaction
+			select {
+			case ts = <-mock.TChan:
+			case <-resChan:
+				t.Fatalf("Received premature response from broker at output %v", i)
+			case <-time.After(time.Second):
+				t.Fatalf("Timed out waiting for output %v", i)
+			}
+			if exp, act := "hello world", string(ts.Payload.Get(0).Get()); exp != act {
+				t.Errorf("Wrong content returned %s != %s", act, exp)
+			}
+
+			var res types.Response = response.NewAck()
+			if i == errOutput {
+				res = response.NewError(testErr)
+				expErr = testErr
+			}
+			select {
+			case ts.ResponseChan <- res:
+			case <-time.After(time.Second):
+				t.Fatal("Timed out responding to broker")
+			}
+			if expErr != nil {
+				break
+			}
+		}
+
+		select {
+		case <-mockTwo.TChan:
+			t.Error("Received message to output after failure")
+		case res := <-resChan:
+			if act := res.Error(); expErr != act {
+				t.Errorf("Wrong error returned: %v != %v", act, expErr)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker response")
+		}
+	}
+
+	close(readChan)
+	if err := oTM.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+
+	expCounters := map[string]int64{
+		"broker.fan_out_sequential.messages.received": 3,
+		"broker.fan_out_sequential.messages.sent":     3,
+		"broker.fan_out_sequential.output.error":      2,
+		"broker.fan_out_sequential.0.error":           1,
+		"broker.fan_out_sequential.1.error":           1,
+	}
+	counters := stats.GetCounters()
+	for k, exp := range expCounters {
+		if act := counters[k]; exp != act {
+			t.Errorf("Wrong count for %v: %v != %v", k, act, exp)
+		}
+	}
+	if _, exists := stats.GetTimings()["broker.fan_out_sequential.1.latency"]; !exists {
+		t.Error("Expected latency timing for output 1")
+	}
+}
+
+//------------------------------------------------------------------------------
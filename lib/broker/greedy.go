@@ -29,8 +29,9 @@ import (
 //------------------------------------------------------------------------------
 
 // Greedy is a broker that implements types.Consumer and sends each message
-// out to a single consumer chosen from an array in round-robin fashion.
-// Consumers that apply backpressure will block all consumers.
+// out to a single consumer chosen from an array by allowing each consumer to
+// claim messages as soon as it is able to process them. Consumers that apply
+// backpressure therefore do not block the other consumers.
 type Greedy struct {
 	outputs []types.Output
 }
@@ -56,6 +57,17 @@ func (g *Greedy) Consume(ts <-chan types.Transaction) error {
 
 //------------------------------------------------------------------------------
 
+// Connected returns a boolean indicating whether any output of the broker is
+// currently connected to its target.
+func (g *Greedy) Connected() bool {
+	for _, out := range g.outputs {
+		if types.IsConnected(out) {
+			return true
+		}
+	}
+	return false
+}
+
 // CloseAsync shuts down the Greedy broker and stops processing requests.
 func (g *Greedy) CloseAsync() {
 	for _, out := range g.outputs {
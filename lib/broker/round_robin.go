@@ -121,6 +121,17 @@ func (o *RoundRobin) loop() {
 	}
 }
 
+// Connected returns a boolean indicating whether all outputs of the broker are
+// currently connected to their targets.
+func (o *RoundRobin) Connected() bool {
+	for _, out := range o.outputs {
+		if !types.IsConnected(out) {
+			return false
+		}
+	}
+	return true
+}
+
 // CloseAsync shuts down the RoundRobin broker and stops processing requests.
 func (o *RoundRobin) CloseAsync() {
 	if atomic.CompareAndSwapInt32(&o.running, 1, 0) {
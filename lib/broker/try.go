@@ -34,6 +34,11 @@ import (
 // Try is a broker that implements types.Consumer and attempts to send each
 // message to a single output, but on failure will attempt the next output in
 // the list.
+//
+// Outputs that are not connected are skipped without an attempt, as a
+// disconnected output holds on to messages until it reconnects, which would
+// otherwise block the outputs that follow it. The last output of the list is
+// always attempted.
 type Try struct {
 	running int32
 
@@ -94,11 +99,18 @@ func (t *Try) loop() {
 
 	var (
 		mMsgsRcvd = t.stats.GetCounter("broker.try.messages.received")
-		mErrs     = []metrics.StatCounter{}
+		mAttempts = make([]metrics.StatCounter, len(t.outputs))
+		mSuccs    = make([]metrics.StatCounter, len(t.outputs))
+		mErrs     = make([]metrics.StatCounter, len(t.outputs))
+		mSkips    = make([]metrics.StatCounter, len(t.outputs))
 	)
 	for i := range t.outputs {
-		mErrs = append(mErrs, t.stats.GetCounter(fmt.Sprintf("broker.try.%v.failed", i)))
+		mAttempts[i] = t.stats.GetCounter(fmt.Sprintf("broker.try.%v.attempt", i))
+		mSuccs[i] = t.stats.GetCounter(fmt.Sprintf("broker.try.%v.success", i))
+		mErrs[i] = t.stats.GetCounter(fmt.Sprintf("broker.try.%v.failed", i))
+		mSkips[i] = t.stats.GetCounter(fmt.Sprintf("broker.try.%v.skipped", i))
 	}
+	lastIndex := len(t.outputs) - 1
 
 	var open bool
 	resChan := make(chan types.Response)
@@ -117,6 +129,11 @@ func (t *Try) loop() {
 
 	triesLoop:
 		for i, ot := range t.outputTsChans {
+			if i < lastIndex && !types.IsConnected(t.outputs[i]) {
+				mSkips[i].Incr(1)
+				continue
+			}
+			mAttempts[i].Incr(1)
 			select {
 			case ot <- types.NewTransaction(ts.Payload, resChan):
 			case <-t.closeChan:
@@ -130,6 +147,7 @@ func (t *Try) loop() {
 				if res.Error() != nil {
 					mErrs[i].Incr(1)
 				} else {
+					mSuccs[i].Incr(1)
 					break triesLoop
 				}
 			case <-t.closeChan:
@@ -144,6 +162,17 @@ func (t *Try) loop() {
 	}
 }
 
+// Connected returns a boolean indicating whether any output of the broker is
+// currently connected to its target.
+func (t *Try) Connected() bool {
+	for _, out := range t.outputs {
+		if types.IsConnected(out) {
+			return true
+		}
+	}
+	return false
+}
+
 // CloseAsync shuts down the Try broker and stops processing requests.
 func (t *Try) CloseAsync() {
 	if atomic.CompareAndSwapInt32(&t.running, 1, 0) {
@@ -23,6 +23,7 @@ package broker
 import (
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -316,3 +317,106 @@ func TestTryAllFail(t *testing.T) {
 }
 
 //------------------------------------------------------------------------------
+
+type mockConnOutputType struct {
+	*MockOutputType
+	connected int32
+}
+
+func (m *mockConnOutputType) Connected() bool {
+	return atomic.LoadInt32(&m.connected) == 1
+}
+
+func TestTrySkipDisconnected(t *testing.T) {
+	mockOutputs := []*mockConnOutputType{
+		{MockOutputType: &MockOutputType{}, connected: 0},
+		{MockOutputType: &MockOutputType{}, connected: 1},
+		{MockOutputType: &MockOutputType{}, connected: 0},
+	}
+
+	outputs := []types.Output{}
+	for _, o := range mockOutputs {
+		outputs = append(outputs, o)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	stats := metrics.NewLocal()
+	oTM, err := NewTry(outputs, stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	testErr := errors.New("test error")
+	sendAndRespond := func(content string, expOutputs []int, res types.Response) {
+		select {
+		case readChan <- types.NewTransaction(message.New([][]byte{[]byte(content)}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker send")
+		}
+		for _, i := range expOutputs {
+			var ts types.Transaction
+			select {
+			case ts = <-mockOutputs[i].TChan:
+			case <-mockOutputs[(i+1)%3].TChan:
+				t.Fatalf("Received message on wrong output: %v", (i+1)%3)
+			case <-mockOutputs[(i+2)%3].TChan:
+				t.Fatalf("Received message on wrong output: %v", (i+2)%3)
+			case <-time.After(time.Second):
+				t.Fatalf("Timed out waiting for output %v", i)
+			}
+			if exp, act := content, string(ts.Payload.Get(0).Get()); exp != act {
+				t.Errorf("Wrong content returned %s != %s", act, exp)
+			}
+			select {
+			case ts.ResponseChan <- res:
+			case <-time.After(time.Second):
+				t.Fatal("Timed out responding to broker")
+			}
+		}
+		select {
+		case actRes := <-resChan:
+			if exp, act := res.Error(), actRes.Error(); exp != act {
+				t.Errorf("Wrong error returned: %v != %v", act, exp)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker response")
+		}
+	}
+
+	// The first output is skipped, the last output is always attempted.
+	sendAndRespond("foo", []int{1}, response.NewAck())
+	sendAndRespond("bar", []int{1, 2}, response.NewError(testErr))
+
+	atomic.StoreInt32(&mockOutputs[0].connected, 1)
+	sendAndRespond("baz", []int{0}, response.NewAck())
+
+	oTM.CloseAsync()
+	if err := oTM.WaitForClose(time.Second * 10); err != nil {
+		t.Error(err)
+	}
+
+	expCounters := map[string]int64{
+		"broker.try.messages.received": 3,
+		"broker.try.0.attempt":         1,
+		"broker.try.0.success":         1,
+		"broker.try.0.skipped":         2,
+		"broker.try.1.attempt":         2,
+		"broker.try.1.success":         1,
+		"broker.try.1.failed":          1,
+		"broker.try.2.attempt":         1,
+		"broker.try.2.failed":          1,
+	}
+	counters := stats.GetCounters()
+	for k, exp := range expCounters {
+		if act := counters[k]; exp != act {
+			t.Errorf("Wrong count for %v: %v != %v", k, act, exp)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,89 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package buffer
+
+import (
+	"github.com/Jeffail/benthos/lib/buffer/single"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/processor/condition"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeBatch] = TypeSpec{
+		constructor: NewBatch,
+		description: `
+The batch buffer type combines the parts of messages received from the input
+into batches, which are sent on to the pipeline once either:
+
+- The ` + "`byte_size`" + ` field is non-zero and the total size of the batch in
+  bytes matches or exceeds it.
+- The ` + "`count`" + ` field is non-zero and the total number of message parts
+  in the batch matches or exceeds it.
+- A message added to the batch causes the condition to resolve ` + "`true`" + `.
+- The ` + "`period`" + ` field is non-empty and that duration has passed since
+  the first message of the batch was added.
+
+This decouples the batching of the output from the input, allowing a slow
+stream of messages to be coalesced into larger batches before being sent to an
+output. Messages are acknowledged as soon as they are added to a batch, and a
+batch that fails to send is retried until it succeeds.
+
+Unlike the ` + "`batch`" + ` processor, the ` + "`period`" + ` is measured by a
+timer and therefore a batch is flushed even when no further messages arrive.
+When the buffer is closed gracefully the pending batch is flushed regardless of
+its size.
+
+Writes to the buffer block whilst the next batch is waiting to be sent, applying
+back pressure to the input. Conditions are created without access to resources,
+and therefore ` + "`resource`" + ` conditions are not supported.`,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			condSanit, err := condition.SanitiseConfig(conf.Batch.Condition)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"byte_size": conf.Batch.ByteSize,
+				"count":     conf.Batch.Count,
+				"condition": condSanit,
+				"period":    conf.Batch.Period,
+			}, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewBatch creates a buffer that combines messages into batches.
+func NewBatch(config Config, log log.Modular, stats metrics.Type) (Type, error) {
+	b, err := single.NewBatch(
+		config.Batch, types.NoopMgr(), log.NewModule(".buffer.batch"), stats,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return NewSingleWrapper(config, b, log, stats), nil
+}
+
+//------------------------------------------------------------------------------
@@ -39,8 +39,9 @@ import (
 
 // TypeSpec is a constructor and usage description for each buffer type.
 type TypeSpec struct {
-	constructor func(conf Config, log log.Modular, stats metrics.Type) (Type, error)
-	description string
+	constructor        func(conf Config, log log.Modular, stats metrics.Type) (Type, error)
+	description        string
+	sanitiseConfigFunc func(conf Config) (interface{}, error)
 }
 
 // Constructors is a map of all buffer types with their specs.
@@ -50,6 +51,8 @@ var Constructors = map[string]TypeSpec{}
 
 // String constants representing each buffer type.
 const (
+	TypeBatch  = "batch"
+	TypeFile   = "file"
 	TypeMemory = "memory"
 	TypeMMAP   = "mmap_file"
 	TypeNone   = "none"
@@ -60,6 +63,8 @@ const (
 // Config is the all encompassing configuration struct for all buffer types.
 type Config struct {
 	Type   string                  `json:"type" yaml:"type"`
+	Batch  single.BatchConfig      `json:"batch" yaml:"batch"`
+	File   single.FileConfig       `json:"file" yaml:"file"`
 	Memory single.MemoryConfig     `json:"memory" yaml:"memory"`
 	Mmap   single.MmapBufferConfig `json:"mmap_file" yaml:"mmap_file"`
 	None   struct{}                `json:"none" yaml:"none"`
@@ -69,6 +74,8 @@ type Config struct {
 func NewConfig() Config {
 	return Config{
 		Type:   "none",
+		Batch:  single.NewBatchConfig(),
+		File:   single.NewFileConfig(),
 		Memory: single.NewMemoryConfig(),
 		Mmap:   single.NewMmapBufferConfig(),
 		None:   struct{}{},
@@ -90,7 +97,13 @@ func SanitiseConfig(conf Config) (interface{}, error) {
 
 	outputMap := config.Sanitised{}
 	outputMap["type"] = hashMap["type"]
-	outputMap[conf.Type] = hashMap[conf.Type]
+	if sfunc := Constructors[conf.Type].sanitiseConfigFunc; sfunc != nil {
+		if outputMap[conf.Type], err = sfunc(conf); err != nil {
+			return nil, err
+		}
+	} else {
+		outputMap[conf.Type] = hashMap[conf.Type]
+	}
 
 	return outputMap, nil
 }
@@ -120,14 +133,21 @@ different options and their qualities:
 | Type      | Throughput | Consumers | Capacity |
 | --------- | ---------- | --------- | -------- |
 | Memory    | Highest    | Parallel  | RAM      |
+| Batch     | High       | Single    | RAM      |
 | Mmap File | High       | Single    | Disk     |
+| File      | Medium     | Single    | Disk     |
 
 #### Delivery Guarantees
 
 | Type      | On Restart | On Crash  | On Disk Corruption |
 | --------- | ---------- | --------- | ------------------ |
 | Memory    | Lost       | Lost      | Lost               |
-| Mmap File | Persisted  | Lost      | Lost               |`
+| Batch     | Lost       | Lost      | Lost               |
+| Mmap File | Persisted  | Lost      | Lost               |
+| File      | Persisted  | Persisted | Lost               |
+
+The crash guarantee of the file buffer depends on its sync policy, where only
+the ` + "`always`" + ` policy persists every message.`
 
 // Descriptions returns a formatted string of collated descriptions of each type.
 func Descriptions() string {
@@ -72,7 +72,10 @@ func TestSanitise(t *testing.T) {
 	exp = `{` +
 		`"type":"memory",` +
 		`"memory":{` +
-		`"limit":20` +
+		`"ack_on_delivery":false,` +
+		`"limit":20,` +
+		`"message_limit":0,` +
+		`"when_full":"block"` +
 		`}` +
 		`}`
 
@@ -0,0 +1,85 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package buffer
+
+import (
+	"github.com/Jeffail/benthos/lib/buffer/single"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeFile] = TypeSpec{
+		constructor: NewFile,
+		description: `
+The file buffer type appends messages to segment files within a directory, which
+is created if it does not already exist. A message is only removed from the
+buffer once it has been acknowledged by the output, and the position of the
+oldest unacknowledged message is recorded in a tracker file. Therefore, when the
+service is restarted with the same directory any messages that were not yet
+acknowledged are sent again, along with their metadata.
+
+Segments are rotated once they reach ` + "`segment_size`" + ` bytes, and are
+deleted once all of their messages have been acknowledged. When the total size
+of unacknowledged messages reaches ` + "`max_size`" + ` writes to the buffer
+block until space is freed, applying backpressure to the input.
+
+Each message is stored with a checksum, and a message that fails its checksum
+is skipped. A partially written message at the end of the buffer, which can be
+left by a crash, is discarded on startup.
+
+The number of unacknowledged messages and the age in nanoseconds of the oldest
+of them are exposed as the gauges ` + "`buffer.backlog_messages`" + ` and
+` + "`buffer.backlog_age`" + `, which are useful for alerting on a buffer that is
+not being drained.
+
+### Sync Policy
+
+The ` + "`sync`" + ` field determines how often written data and the tracker
+are flushed to disk, which is a tradeoff between durability and throughput:
+
+- ` + "`always`" + `: Data is flushed after every write and acknowledgement.
+  Messages written to the buffer survive a crash of the machine, but throughput
+  is limited by the latency of the disk.
+- ` + "`interval`" + `: Data is flushed every ` + "`sync_interval`" + `. A
+  crash of the machine can lose messages written within the last interval, and
+  messages acknowledged within the last interval might be sent again.
+- ` + "`none`" + `: Data is only flushed when segments are rotated and when
+  the buffer is closed, leaving it to the operating system. This has the highest
+  throughput, and data survives a crash of the service but not necessarily of
+  the machine.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewFile creates a buffer persisted to segment files within a directory.
+func NewFile(config Config, log log.Modular, stats metrics.Type) (Type, error) {
+	b, err := single.NewFile(config.File, log.NewModule(".buffer.file"), stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewSingleWrapper(config, b, log, stats), nil
+}
+
+//------------------------------------------------------------------------------
@@ -21,7 +21,10 @@
 package buffer
 
 import (
+	"fmt"
+
 	"github.com/Jeffail/benthos/lib/buffer/parallel"
+	"github.com/Jeffail/benthos/lib/buffer/single"
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 )
@@ -34,7 +37,37 @@ func init() {
 		description: `
 The memory buffer type simply allocates a set amount of RAM for buffering
 messages. This can be useful when reading from sources that produce large bursts
-of data. Messages inside the buffer are lost if the service is stopped.`,
+of data. Messages inside the buffer are lost if the service is stopped.
+
+The ` + "`limit`" + ` field caps the total size in bytes of the messages held
+by the buffer, and ` + "`message_limit`" + ` optionally caps the number of
+messages, where zero means unlimited. Messages that have been read by an output
+but not yet acknowledged count towards both limits.
+
+The ` + "`when_full`" + ` field determines what happens when a message is
+written and either limit has been reached:
+
+- ` + "`block`" + `: Writes to the buffer block until space is freed, applying
+  backpressure to the input.
+- ` + "`drop_oldest`" + `: The oldest messages that have not yet been read are
+  dropped until the new message fits, which keeps latency bounded for data where
+  freshness matters more than completeness. If the space is taken by messages
+  that have been read but not yet acknowledged the write blocks.
+- ` + "`drop_newest`" + `: The message being written is dropped.
+
+Dropped messages are acknowledged to the input and counted by the metric
+` + "`buffer.memory.dropped`" + `. The percentage of the most saturated limit in
+use is exposed as the gauge ` + "`buffer.memory.utilisation`" + `. When the
+service is shut down gracefully messages remaining in the buffer are sent before
+the outputs are closed.
+
+By default a message is acknowledged to the input as soon as it has been
+written to the buffer. When ` + "`ack_on_delivery`" + ` is set to
+` + "`true`" + ` the acknowledgement is instead held back until the message has
+been successfully sent by the output, which preserves at-least-once delivery
+at the cost of throughput. Inputs that wait for each acknowledgement before
+reading the next message will then only have one message in the buffer at a
+time.`,
 	}
 }
 
@@ -42,7 +75,23 @@ of data. Messages inside the buffer are lost if the service is stopped.`,
 
 // NewMemory - Create a buffer held in memory.
 func NewMemory(config Config, log log.Modular, stats metrics.Type) (Type, error) {
-	return NewParallelWrapper(config, parallel.NewMemory(config.Memory.Limit), log, stats), nil
+	var overflow parallel.OverflowPolicy
+	switch config.Memory.WhenFull {
+	case single.MemoryWhenFullBlock:
+		overflow = parallel.OverflowBlock
+	case single.MemoryWhenFullDropOldest:
+		overflow = parallel.OverflowDropOldest
+	case single.MemoryWhenFullDropNewest:
+		overflow = parallel.OverflowDropNewest
+	default:
+		return nil, fmt.Errorf("when_full policy not recognised: %v", config.Memory.WhenFull)
+	}
+	return NewParallelWrapper(config, parallel.NewMemory(
+		config.Memory.Limit,
+		parallel.OptMessageLimit(config.Memory.MessageLimit),
+		parallel.OptOverflowPolicy(overflow),
+		parallel.OptMetrics(stats),
+	), log, stats), nil
 }
 
 //------------------------------------------------------------------------------
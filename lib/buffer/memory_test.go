@@ -21,6 +21,7 @@
 package buffer
 
 import (
+	"errors"
 	"os"
 	"testing"
 	"time"
@@ -101,3 +102,163 @@ func TestMemoryBuffer(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestMemoryBufferAckOnDelivery(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "memory"
+	conf.Memory.AckOnDelivery = true
+
+	stats := metrics.NewLocal()
+	buf, err := New(conf, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tChan, resChan := make(chan types.Transaction), make(chan types.Response)
+	if err = buf.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{[]byte(`one`)}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	var outTr types.Transaction
+	select {
+	case outTr = <-buf.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	// The input must not be acknowledged until the output is.
+	select {
+	case <-resChan:
+		t.Fatal("Input acknowledged before delivery")
+	case <-time.After(time.Millisecond * 50):
+	}
+	if exp, act := int64(1), stats.GetCounters()["buffer.backlog_messages"]; exp != act {
+		t.Errorf("Wrong backlog count: %v != %v", act, exp)
+	}
+
+	// A failed send is retried and still not acknowledged to the input.
+	select {
+	case outTr.ResponseChan <- response.NewError(errors.New("nope")):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	select {
+	case outTr = <-buf.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	select {
+	case <-resChan:
+		t.Fatal("Input acknowledged before delivery")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	select {
+	case outTr.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	select {
+	case res := <-resChan:
+		if res.Error() != nil {
+			t.Error(res.Error())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	buf.CloseAsync()
+	if err := buf.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+	if exp, act := int64(0), stats.GetCounters()["buffer.backlog_messages"]; exp != act {
+		t.Errorf("Wrong backlog count: %v != %v", act, exp)
+	}
+}
+
+func TestMemoryBufferBadWhenFull(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "memory"
+	conf.Memory.WhenFull = "nope"
+
+	if _, err := New(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad when_full policy")
+	}
+}
+
+func TestMemoryBufferDropOldest(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "memory"
+	conf.Memory.MessageLimit = 2
+	conf.Memory.WhenFull = "drop_oldest"
+
+	stats := metrics.NewLocal()
+	buf, err := New(conf, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tChan, resChan := make(chan types.Transaction), make(chan types.Response)
+	if err = buf.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	// Writes never block whilst nothing is being read.
+	for _, content := range []string{"one", "two", "three", "four"} {
+		select {
+		case tChan <- types.NewTransaction(message.New([][]byte{[]byte(content)}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Error(res.Error())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	// The remaining messages are flushed on shutdown. The first message might
+	// have been read before the buffer filled up, in which case it could not
+	// be dropped.
+	buf.StopConsuming()
+	var received []string
+	for open := true; open; {
+		select {
+		case outTr, ok := <-buf.TransactionChan():
+			if open = ok; !open {
+				break
+			}
+			received = append(received, string(outTr.Payload.Get(0).Get()))
+			select {
+			case outTr.ResponseChan <- response.NewAck():
+			case <-time.After(time.Second):
+				t.Fatal("Timed out")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	if err := buf.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+	if len(received) == 0 || received[len(received)-1] != "four" {
+		t.Errorf("Wrong messages received: %v", received)
+	}
+	counters := stats.GetCounters()
+	if exp, act := int64(4-len(received)), counters["buffer.memory.dropped"]; exp != act {
+		t.Errorf("Wrong dropped count: %v != %v", act, exp)
+	}
+	if exp, act := int64(0), counters["buffer.backlog_messages"]; exp != act {
+		t.Errorf("Wrong backlog count: %v != %v", act, exp)
+	}
+}
@@ -23,34 +23,123 @@ package parallel
 import (
 	"sync"
 
+	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 )
 
 //------------------------------------------------------------------------------
 
+// memoryEntry is a message held by a Memory buffer along with its size in
+// bytes and an optional function to call once the message is acknowledged.
+type memoryEntry struct {
+	msg   types.Message
+	size  int
+	onAck func()
+}
+
+// OverflowPolicy determines the behaviour of a Memory buffer when a message is
+// pushed and the buffer is full.
+type OverflowPolicy int
+
+// Overflow policies of a Memory buffer.
+const (
+	// OverflowBlock blocks the push until space is freed.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropOldest drops the oldest messages that have not yet been read
+	// until there is space for the new message. When the space is occupied by
+	// messages that have been read but not yet acknowledged the push blocks.
+	OverflowDropOldest
+
+	// OverflowDropNewest drops the message being pushed.
+	OverflowDropNewest
+)
+
 // Memory is a parallel buffer implementation that allows multiple parallel
 // consumers to read and purge messages from the buffer asynchronously.
 type Memory struct {
-	messages []types.Message
+	messages []memoryEntry
 	bytes    int
+	count    int
 
-	cap  int
-	cond *sync.Cond
+	cap      int
+	countCap int
+	overflow OverflowPolicy
+	cond     *sync.Cond
 
 	closed bool
+
+	stats        metrics.Type
+	mDropped     metrics.StatCounter
+	mUtilisation metrics.StatGauge
 }
 
 // NewMemory creates a memory based parallel buffer.
-func NewMemory(cap int) *Memory {
-	return &Memory{
+func NewMemory(cap int, opts ...func(*Memory)) *Memory {
+	m := &Memory{
 		bytes: 0,
 		cap:   cap,
 		cond:  sync.NewCond(&sync.Mutex{}),
+		stats: metrics.Noop(),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.mDropped = m.stats.GetCounter("buffer.memory.dropped")
+	m.mUtilisation = m.stats.GetGauge("buffer.memory.utilisation")
+	return m
+}
+
+// OptMessageLimit sets the maximum number of messages that can be held by the
+// buffer, including those that have been read but not yet acknowledged. A
+// limit of zero or less means the number of messages is unlimited.
+func OptMessageLimit(limit int) func(*Memory) {
+	return func(m *Memory) {
+		m.countCap = limit
+	}
+}
+
+// OptOverflowPolicy sets the behaviour of the buffer when a message is pushed
+// and either limit has been reached.
+func OptOverflowPolicy(policy OverflowPolicy) func(*Memory) {
+	return func(m *Memory) {
+		m.overflow = policy
+	}
+}
+
+// OptMetrics sets the metrics aggregator used to expose the number of dropped
+// messages and the utilisation of the buffer.
+func OptMetrics(stats metrics.Type) func(*Memory) {
+	return func(m *Memory) {
+		m.stats = stats
 	}
 }
 
 //------------------------------------------------------------------------------
 
+// isFull returns whether pushing a message of a given size would exceed either
+// limit, must be called with the lock held.
+func (m *Memory) isFull(size int) bool {
+	return m.bytes+size > m.cap || (m.countCap > 0 && m.count >= m.countCap)
+}
+
+// updateUtilisation sets the utilisation gauge to the percentage of the most
+// saturated limit in use, must be called with the lock held.
+func (m *Memory) updateUtilisation() {
+	var util int64
+	if m.cap > 0 {
+		util = int64(m.bytes) * 100 / int64(m.cap)
+	}
+	if m.countCap > 0 {
+		if countUtil := int64(m.count) * 100 / int64(m.countCap); countUtil > util {
+			util = countUtil
+		}
+	}
+	m.mUtilisation.Set(util)
+}
+
+//------------------------------------------------------------------------------
+
 // NextMessage reads the next oldest message, the message is preserved until the
 // returned AckFunc is called.
 func (m *Memory) NextMessage() (types.Message, AckFunc, error) {
@@ -64,17 +153,12 @@ func (m *Memory) NextMessage() (types.Message, AckFunc, error) {
 		return nil, nil, types.ErrTypeClosed
 	}
 
-	msg := m.messages[0]
+	entry := m.messages[0]
+	msg := entry.msg
 
-	m.messages[0] = nil
+	m.messages[0] = memoryEntry{}
 	m.messages = m.messages[1:]
 
-	messageSize := 0
-	msg.Iter(func(i int, b types.Part) error {
-		messageSize += len(b.Get())
-		return nil
-	})
-
 	m.cond.L.Unlock()
 
 	return msg, func(ack bool) (int, error) {
@@ -84,21 +168,33 @@ func (m *Memory) NextMessage() (types.Message, AckFunc, error) {
 			return 0, types.ErrTypeClosed
 		}
 		if ack {
-			m.bytes -= messageSize
+			m.bytes -= entry.size
+			m.count--
+			m.updateUtilisation()
 		} else {
-			m.messages = append([]types.Message{msg}, m.messages...)
+			m.messages = append([]memoryEntry{entry}, m.messages...)
 		}
 		m.cond.Broadcast()
 
 		backlog := m.bytes
 		m.cond.L.Unlock()
 
+		if ack && entry.onAck != nil {
+			entry.onAck()
+		}
 		return backlog, nil
 	}, nil
 }
 
 // PushMessage adds a new message to the stack. Returns the backlog in bytes.
 func (m *Memory) PushMessage(msg types.Message) (int, error) {
+	return m.PushMessageNotify(msg, nil)
+}
+
+// PushMessageNotify adds a new message to the stack, and calls onAck once the
+// message has been read and acknowledged, or once it has been dropped by the
+// overflow policy. Returns the backlog in bytes.
+func (m *Memory) PushMessageNotify(msg types.Message, onAck func()) (int, error) {
 	extraBytes := 0
 	msg.Iter(func(i int, b types.Part) error {
 		extraBytes += len(b.Get())
@@ -116,7 +212,21 @@ func (m *Memory) PushMessage(msg types.Message) (int, error) {
 		return 0, types.ErrTypeClosed
 	}
 
-	for (m.bytes + extraBytes) > m.cap {
+	var dropped []memoryEntry
+	for m.isFull(extraBytes) {
+		if m.overflow == OverflowDropNewest {
+			dropped = append(dropped, memoryEntry{msg: msg, onAck: onAck})
+			break
+		}
+		if m.overflow == OverflowDropOldest && len(m.messages) > 0 {
+			entry := m.messages[0]
+			m.messages[0] = memoryEntry{}
+			m.messages = m.messages[1:]
+			m.bytes -= entry.size
+			m.count--
+			dropped = append(dropped, entry)
+			continue
+		}
 		m.cond.Wait()
 		if m.closed {
 			m.cond.L.Unlock()
@@ -124,14 +234,30 @@ func (m *Memory) PushMessage(msg types.Message) (int, error) {
 		}
 	}
 
-	m.messages = append(m.messages, msg.DeepCopy())
-	m.bytes += extraBytes
+	if len(dropped) == 0 || m.overflow != OverflowDropNewest {
+		m.messages = append(m.messages, memoryEntry{
+			msg:   msg.DeepCopy(),
+			size:  extraBytes,
+			onAck: onAck,
+		})
+		m.bytes += extraBytes
+		m.count++
+	}
+	m.updateUtilisation()
 
 	backlog := m.bytes
 
 	m.cond.Broadcast()
 	m.cond.L.Unlock()
 
+	// Dropped messages are considered finished with, and are therefore
+	// acknowledged.
+	m.mDropped.Incr(int64(len(dropped)))
+	for _, entry := range dropped {
+		if entry.onAck != nil {
+			entry.onAck()
+		}
+	}
 	return backlog, nil
 }
 
@@ -141,7 +267,7 @@ func (m *Memory) PushMessage(msg types.Message) (int, error) {
 // until the close is completed.
 func (m *Memory) CloseOnceEmpty() {
 	m.cond.L.Lock()
-	for m.bytes > 0 && !m.closed {
+	for m.count > 0 && !m.closed {
 		m.cond.Wait()
 	}
 	if !m.closed {
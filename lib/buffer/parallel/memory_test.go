@@ -28,6 +28,7 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 )
 
@@ -297,3 +298,185 @@ func TestMemoryRejectLargeMessage(t *testing.T) {
 		t.Errorf("Unexpected error: %v != %v", exp, actual)
 	}
 }
+
+func TestMemoryMessageLimit(t *testing.T) {
+	block := NewMemory(1000, OptMessageLimit(2))
+
+	for i := 0; i < 2; i++ {
+		if _, err := block.PushMessage(message.New([][]byte{[]byte("hello")})); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pushed := make(chan error)
+	go func() {
+		_, err := block.PushMessage(message.New([][]byte{[]byte("world")}))
+		pushed <- err
+	}()
+
+	// Reading a message should not free space until it is acknowledged.
+	_, ackFunc, err := block.NextMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-pushed:
+		t.Fatal("Push did not block at message limit")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	if _, err = ackFunc(false); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-pushed:
+		t.Fatal("Push did not block after message was rejected")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	if _, ackFunc, err = block.NextMessage(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ackFunc(true); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err = <-pushed:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Push did not unblock")
+	}
+}
+
+func TestMemoryPushNotify(t *testing.T) {
+	block := NewMemory(1000)
+
+	acked := 0
+	if _, err := block.PushMessageNotify(message.New([][]byte{[]byte("hello")}), func() {
+		acked++
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, ackFunc, err := block.NextMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ackFunc(false); err != nil {
+		t.Fatal(err)
+	}
+	if acked != 0 {
+		t.Errorf("Notified of rejected message")
+	}
+
+	if _, ackFunc, err = block.NextMessage(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ackFunc(true); err != nil {
+		t.Fatal(err)
+	}
+	if acked != 1 {
+		t.Errorf("Wrong count of notifications: %v != %v", acked, 1)
+	}
+}
+
+func TestMemoryDropOldest(t *testing.T) {
+	stats := metrics.NewLocal()
+	block := NewMemory(10, OptOverflowPolicy(OverflowDropOldest), OptMetrics(stats))
+
+	dropped := 0
+	for _, content := range []string{"foo", "bar", "baz", "buz"} {
+		if _, err := block.PushMessageNotify(message.New([][]byte{[]byte(content)}), func() {
+			dropped++
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if exp, act := 1, dropped; exp != act {
+		t.Errorf("Wrong count of dropped notifications: %v != %v", act, exp)
+	}
+	if exp, act := int64(1), stats.GetCounters()["buffer.memory.dropped"]; exp != act {
+		t.Errorf("Wrong dropped count: %v != %v", act, exp)
+	}
+	if exp, act := int64(90), stats.GetCounters()["buffer.memory.utilisation"]; exp != act {
+		t.Errorf("Wrong utilisation: %v != %v", act, exp)
+	}
+
+	// Messages that have been read cannot be dropped, and so the push blocks
+	// until they are acknowledged.
+	var ackFuncs []AckFunc
+	for _, exp := range []string{"bar", "baz", "buz"} {
+		msg, ackFunc, err := block.NextMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := string(msg.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message: %v != %v", act, exp)
+		}
+		ackFuncs = append(ackFuncs, ackFunc)
+	}
+
+	pushed := make(chan error)
+	go func() {
+		_, err := block.PushMessage(message.New([][]byte{[]byte("quz")}))
+		pushed <- err
+	}()
+	select {
+	case <-pushed:
+		t.Fatal("Push did not block with unacknowledged messages")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	if _, err := ackFuncs[0](true); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-pushed:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Push did not unblock")
+	}
+}
+
+func TestMemoryDropNewest(t *testing.T) {
+	stats := metrics.NewLocal()
+	block := NewMemory(1000, OptMessageLimit(2), OptOverflowPolicy(OverflowDropNewest), OptMetrics(stats))
+
+	dropped := 0
+	for _, content := range []string{"foo", "bar", "baz"} {
+		if _, err := block.PushMessageNotify(message.New([][]byte{[]byte(content)}), func() {
+			dropped++
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if exp, act := 1, dropped; exp != act {
+		t.Errorf("Wrong count of dropped notifications: %v != %v", act, exp)
+	}
+	if exp, act := int64(1), stats.GetCounters()["buffer.memory.dropped"]; exp != act {
+		t.Errorf("Wrong dropped count: %v != %v", act, exp)
+	}
+	if exp, act := int64(100), stats.GetCounters()["buffer.memory.utilisation"]; exp != act {
+		t.Errorf("Wrong utilisation: %v != %v", act, exp)
+	}
+
+	for _, exp := range []string{"foo", "bar"} {
+		msg, ackFunc, err := block.NextMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := string(msg.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message: %v != %v", act, exp)
+		}
+		if _, err = ackFunc(true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if exp, act := int64(0), stats.GetCounters()["buffer.memory.utilisation"]; exp != act {
+		t.Errorf("Wrong utilisation: %v != %v", act, exp)
+	}
+}
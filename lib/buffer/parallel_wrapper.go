@@ -58,15 +58,25 @@ type Parallel interface {
 	Close()
 }
 
+// parallelNotifier is implemented by Parallel buffers that are able to signal
+// when a pushed message has been read and acknowledged, or dropped.
+type parallelNotifier interface {
+	PushMessageNotify(msg types.Message, onAck func()) (int, error)
+}
+
 //------------------------------------------------------------------------------
 
 // ParallelWrapper wraps a buffer with a Producer/Consumer interface.
 type ParallelWrapper struct {
+	backlogCount int64
+
 	stats metrics.Type
 	log   log.Modular
 
-	buffer      Parallel
-	errThrottle *throttle.Type
+	buffer        Parallel
+	notifier      parallelNotifier
+	ackOnDelivery bool
+	errThrottle   *throttle.Type
 
 	running   int32
 	consuming int32
@@ -99,6 +109,12 @@ func NewParallelWrapper(
 		closeChan:         make(chan struct{}),
 		closedChan:        make(chan struct{}),
 	}
+	if notifier, ok := buffer.(parallelNotifier); ok {
+		m.notifier = notifier
+		m.ackOnDelivery = conf.Memory.AckOnDelivery
+	} else if conf.Memory.AckOnDelivery {
+		log.Warnln("Buffer does not support acknowledgement on delivery, messages will be acknowledged once buffered")
+	}
 	m.errThrottle = throttle.New(throttle.OptCloseChan(m.closeChan))
 	return &m
 }
@@ -113,9 +129,10 @@ func (m *ParallelWrapper) inputLoop() {
 	}()
 
 	var (
-		mWriteCount   = m.stats.GetCounter("buffer.write.count")
-		mWriteErr     = m.stats.GetCounter("buffer.write.error")
-		mWriteBacklog = m.stats.GetGauge("buffer.backlog")
+		mWriteCount    = m.stats.GetCounter("buffer.write.count")
+		mWriteErr      = m.stats.GetCounter("buffer.write.error")
+		mWriteBacklog  = m.stats.GetGauge("buffer.backlog")
+		mWriteMessages = m.stats.GetGauge("buffer.backlog_messages")
 	)
 
 	for atomic.LoadInt32(&m.consuming) == 1 {
@@ -129,11 +146,37 @@ func (m *ParallelWrapper) inputLoop() {
 		case <-m.stopConsumingChan:
 			return
 		}
-		backlog, err := m.buffer.PushMessage(tr.Payload)
+		// The count is incremented before the push as the message could be
+		// acknowledged before the push returns.
+		atomic.AddInt64(&m.backlogCount, 1)
+
+		var backlog int
+		var err error
+		if m.notifier != nil {
+			resChan := tr.ResponseChan
+			backlog, err = m.notifier.PushMessageNotify(tr.Payload, func() {
+				mWriteMessages.Set(atomic.AddInt64(&m.backlogCount, -1))
+				if !m.ackOnDelivery {
+					return
+				}
+				select {
+				case resChan <- response.NewAck():
+				case <-m.closeChan:
+				}
+			})
+		} else {
+			backlog, err = m.buffer.PushMessage(tr.Payload)
+		}
 		if err == nil {
 			mWriteCount.Incr(1)
 			mWriteBacklog.Set(int64(backlog))
+			mWriteMessages.Set(atomic.LoadInt64(&m.backlogCount))
+			if m.ackOnDelivery {
+				// The response is sent once the message is acknowledged.
+				continue
+			}
 		} else {
+			atomic.AddInt64(&m.backlogCount, -1)
 			mWriteErr.Incr(1)
 		}
 		select {
@@ -160,6 +203,7 @@ func (m *ParallelWrapper) outputLoop() {
 		mAckErr      = m.stats.GetCounter("buffer.ack.error")
 		mLatency     = m.stats.GetTimer("buffer.latency")
 		mBacklog     = m.stats.GetGauge("buffer.backlog")
+		mMessages    = m.stats.GetGauge("buffer.backlog_messages")
 	)
 
 	for atomic.LoadInt32(&m.running) == 1 {
@@ -204,6 +248,9 @@ func (m *ParallelWrapper) outputLoop() {
 				}
 			} else {
 				mBacklog.Set(int64(blog))
+				if doAck && m.notifier == nil {
+					mMessages.Set(atomic.AddInt64(&m.backlogCount, -1))
+				}
 			}
 		}(resChan, ackFunc)
 	}
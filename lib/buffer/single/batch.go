@@ -0,0 +1,308 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package single
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/processor/condition"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// BatchConfig is config values for a buffer that accumulates messages into
+// batches.
+type BatchConfig struct {
+	ByteSize  int              `json:"byte_size" yaml:"byte_size"`
+	Count     int              `json:"count" yaml:"count"`
+	Condition condition.Config `json:"condition" yaml:"condition"`
+	Period    string           `json:"period" yaml:"period"`
+}
+
+// NewBatchConfig creates a new BatchConfig with default values.
+func NewBatchConfig() BatchConfig {
+	cond := condition.NewConfig()
+	cond.Type = "static"
+	cond.Static = false
+	return BatchConfig{
+		ByteSize:  0,
+		Count:     0,
+		Condition: cond,
+		Period:    "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// batchMaxReady is the number of completed batches that can be held before
+// writes block, which allows the next batch to accumulate whilst the previous
+// one is being delivered.
+const batchMaxReady = 2
+
+// Batch is a memory based buffer that combines the parts of written messages
+// into batches, which are only made available to the reader once a count,
+// byte size, condition or period threshold is met.
+type Batch struct {
+	byteSize int
+	count    int
+	period   time.Duration
+	check    condition.Type
+
+	log log.Modular
+
+	parts     []types.Part
+	sizeTally int
+
+	// Completed batches in the order they were flushed, the first of which is
+	// preserved until ShiftMessage is called.
+	batches    []types.Message
+	batchSizes []int
+
+	// Incremented on each flush in order to invalidate the period timer of the
+	// previous batch.
+	generation uint64
+	timer      *time.Timer
+
+	closed bool
+	cond   *sync.Cond
+
+	mPendingParts metrics.StatGauge
+	mPendingBytes metrics.StatGauge
+	mCountBatch   metrics.StatCounter
+	mSizeBatch    metrics.StatCounter
+	mCondBatch    metrics.StatCounter
+	mPeriodBatch  metrics.StatCounter
+	mCloseBatch   metrics.StatCounter
+	mSent         metrics.StatCounter
+	mSentParts    metrics.StatCounter
+}
+
+// NewBatch creates a buffer that combines messages into batches.
+func NewBatch(
+	conf BatchConfig, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (*Batch, error) {
+	var period time.Duration
+	if len(conf.Period) > 0 {
+		var err error
+		if period, err = time.ParseDuration(conf.Period); err != nil {
+			return nil, fmt.Errorf("failed to parse period: %v", err)
+		}
+	}
+	check, err := condition.New(
+		conf.Condition, mgr, log.NewModule(".condition"),
+		metrics.Namespaced(stats, "buffer.batch"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create condition: %v", err)
+	}
+	if conf.ByteSize <= 0 && conf.Count <= 0 && period <= 0 {
+		log.Warnln("Batch buffer configured without a count, byte_size or" +
+			" period cap. It's possible that a batch will never resolve.")
+	}
+
+	return &Batch{
+		byteSize: conf.ByteSize,
+		count:    conf.Count,
+		period:   period,
+		check:    check,
+		log:      log,
+		cond:     sync.NewCond(&sync.Mutex{}),
+
+		mPendingParts: stats.GetGauge("buffer.batch.pending.parts"),
+		mPendingBytes: stats.GetGauge("buffer.batch.pending.bytes"),
+		mCountBatch:   stats.GetCounter("buffer.batch.on_count"),
+		mSizeBatch:    stats.GetCounter("buffer.batch.on_size"),
+		mCondBatch:    stats.GetCounter("buffer.batch.on_condition"),
+		mPeriodBatch:  stats.GetCounter("buffer.batch.on_period"),
+		mCloseBatch:   stats.GetCounter("buffer.batch.on_close"),
+		mSent:         stats.GetCounter("buffer.batch.sent"),
+		mSentParts:    stats.GetCounter("buffer.batch.parts.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// backlog returns the size in bytes of all pending and completed batches, must
+// be called with the lock held.
+func (b *Batch) backlog() int {
+	total := b.sizeTally
+	for _, s := range b.batchSizes {
+		total += s
+	}
+	return total
+}
+
+// flush moves the pending batch to the completed batches, must be called with
+// the lock held.
+func (b *Batch) flush() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.generation++
+
+	if len(b.parts) == 0 {
+		return
+	}
+
+	msg := message.New(nil)
+	msg.Append(b.parts...)
+
+	b.batches = append(b.batches, msg)
+	b.batchSizes = append(b.batchSizes, b.sizeTally)
+	b.parts = nil
+	b.sizeTally = 0
+
+	b.mPendingParts.Set(0)
+	b.mPendingBytes.Set(0)
+	b.cond.Broadcast()
+}
+
+// startTimer schedules a flush of the pending batch once the period has
+// elapsed, must be called with the lock held.
+func (b *Batch) startTimer() {
+	generation := b.generation
+	b.timer = time.AfterFunc(b.period, func() {
+		b.cond.L.Lock()
+		defer b.cond.L.Unlock()
+
+		if b.closed || b.generation != generation {
+			return
+		}
+		b.mPeriodBatch.Incr(1)
+		b.log.Traceln("Batching based on period")
+		b.flush()
+	})
+}
+
+//------------------------------------------------------------------------------
+
+// CloseOnceEmpty flushes the pending batch and closes the buffer once all
+// batches have been read.
+func (b *Batch) CloseOnceEmpty() {
+	b.cond.L.Lock()
+	if !b.closed && len(b.parts) > 0 {
+		b.mCloseBatch.Incr(1)
+		b.log.Traceln("Batching based on close")
+		b.flush()
+	}
+	for len(b.batches) > 0 && !b.closed {
+		b.cond.Wait()
+	}
+	b.cond.L.Unlock()
+	b.Close()
+}
+
+// Close unblocks any blocked calls and prevents further writing to the buffer.
+// Any pending or unread batches are lost.
+func (b *Batch) Close() {
+	b.cond.L.Lock()
+	b.closed = true
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.cond.Broadcast()
+	b.cond.L.Unlock()
+}
+
+// ShiftMessage removes the oldest batch from the buffer. Returns the backlog
+// in bytes.
+func (b *Batch) ShiftMessage() (int, error) {
+	b.cond.L.Lock()
+	defer b.cond.L.Unlock()
+
+	if len(b.batches) > 0 {
+		b.batches[0] = nil
+		b.batches = b.batches[1:]
+		b.batchSizes = b.batchSizes[1:]
+		b.cond.Broadcast()
+	}
+	return b.backlog(), nil
+}
+
+// NextMessage reads the oldest completed batch, this call blocks until a batch
+// is available. The batch is preserved until ShiftMessage is called.
+func (b *Batch) NextMessage() (types.Message, error) {
+	b.cond.L.Lock()
+	defer b.cond.L.Unlock()
+
+	for len(b.batches) == 0 && !b.closed {
+		b.cond.Wait()
+	}
+	if b.closed {
+		return nil, types.ErrTypeClosed
+	}
+
+	msg := b.batches[0]
+	b.mSent.Incr(1)
+	b.mSentParts.Incr(int64(msg.Len()))
+	return msg, nil
+}
+
+// PushMessage adds the parts of a message to the pending batch, flushing it if
+// a threshold is met. This call blocks whilst the completed batches are
+// waiting to be read. Returns the backlog in bytes.
+func (b *Batch) PushMessage(msg types.Message) (int, error) {
+	b.cond.L.Lock()
+	defer b.cond.L.Unlock()
+
+	for len(b.batches) >= batchMaxReady && !b.closed {
+		b.cond.Wait()
+	}
+	if b.closed {
+		return 0, types.ErrTypeClosed
+	}
+
+	if len(b.parts) == 0 && b.period > 0 {
+		b.startTimer()
+	}
+	msg.Iter(func(i int, p types.Part) error {
+		b.sizeTally += len(p.Get())
+		b.parts = append(b.parts, p.Copy())
+		return nil
+	})
+	b.mPendingParts.Set(int64(len(b.parts)))
+	b.mPendingBytes.Set(int64(b.sizeTally))
+
+	if b.count > 0 && len(b.parts) >= b.count {
+		b.mCountBatch.Incr(1)
+		b.log.Traceln("Batching based on count")
+		b.flush()
+	} else if b.byteSize > 0 && b.sizeTally >= b.byteSize {
+		b.mSizeBatch.Incr(1)
+		b.log.Traceln("Batching based on byte_size")
+		b.flush()
+	} else if b.check.Check(msg) {
+		b.mCondBatch.Incr(1)
+		b.log.Traceln("Batching based on condition")
+		b.flush()
+	}
+	return b.backlog(), nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,271 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package single
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func newTestBatch(t *testing.T, conf BatchConfig, stats metrics.Type) *Batch {
+	t.Helper()
+
+	b, err := NewBatch(conf, types.NoopMgr(), log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func pushBatchTestMessages(t *testing.T, b *Batch, contents ...string) {
+	t.Helper()
+	for _, c := range contents {
+		if _, err := b.PushMessage(message.New([][]byte{[]byte(c)})); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readBatchTestMessage(t *testing.T, b *Batch) []string {
+	t.Helper()
+
+	msg, err := b.NextMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = b.ShiftMessage(); err != nil {
+		t.Fatal(err)
+	}
+	var contents []string
+	msg.Iter(func(i int, p types.Part) error {
+		contents = append(contents, string(p.Get()))
+		return nil
+	})
+	return contents
+}
+
+func assertBatchPending(t *testing.T, b *Batch) {
+	t.Helper()
+
+	read := make(chan error, 1)
+	go func() {
+		_, err := b.NextMessage()
+		read <- err
+	}()
+	select {
+	case err := <-read:
+		t.Errorf("Batch was flushed early: %v", err)
+	case <-time.After(time.Millisecond * 50):
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func TestBatchBadConfig(t *testing.T) {
+	conf := NewBatchConfig()
+	conf.Period = "nope"
+	if _, err := NewBatch(conf, types.NoopMgr(), log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad period")
+	}
+
+	conf = NewBatchConfig()
+	conf.Condition.Type = "nope"
+	if _, err := NewBatch(conf, types.NoopMgr(), log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad condition")
+	}
+}
+
+func TestBatchCount(t *testing.T) {
+	conf := NewBatchConfig()
+	conf.Count = 3
+
+	b := newTestBatch(t, conf, metrics.Noop())
+	defer b.Close()
+
+	pushBatchTestMessages(t, b, "foo", "bar")
+	assertBatchPending(t, b)
+
+	pushBatchTestMessages(t, b, "baz")
+	if exp, act := "[foo bar baz]", fmt.Sprintf("%v", readBatchTestMessage(t, b)); exp != act {
+		t.Errorf("Wrong batch: %v != %v", act, exp)
+	}
+}
+
+func TestBatchByteSize(t *testing.T) {
+	conf := NewBatchConfig()
+	conf.ByteSize = 10
+
+	b := newTestBatch(t, conf, metrics.Noop())
+	defer b.Close()
+
+	pushBatchTestMessages(t, b, "foo", "bar", "baz", "buz", "quz")
+	if exp, act := "[foo bar baz buz]", fmt.Sprintf("%v", readBatchTestMessage(t, b)); exp != act {
+		t.Errorf("Wrong batch: %v != %v", act, exp)
+	}
+	assertBatchPending(t, b)
+}
+
+func TestBatchCondition(t *testing.T) {
+	conf := NewBatchConfig()
+	conf.Condition.Type = "text"
+	conf.Condition.Text.Operator = "equals"
+	conf.Condition.Text.Arg = "end"
+
+	b := newTestBatch(t, conf, metrics.Noop())
+	defer b.Close()
+
+	pushBatchTestMessages(t, b, "foo", "bar", "end", "baz")
+	if exp, act := "[foo bar end]", fmt.Sprintf("%v", readBatchTestMessage(t, b)); exp != act {
+		t.Errorf("Wrong batch: %v != %v", act, exp)
+	}
+	assertBatchPending(t, b)
+}
+
+func TestBatchPeriod(t *testing.T) {
+	conf := NewBatchConfig()
+	conf.Period = "100ms"
+
+	b := newTestBatch(t, conf, metrics.Noop())
+	defer b.Close()
+
+	pushBatchTestMessages(t, b, "foo", "bar")
+
+	read := make(chan []string)
+	go func() {
+		read <- readBatchTestMessage(t, b)
+	}()
+
+	select {
+	case contents := <-read:
+		if exp, act := "[foo bar]", fmt.Sprintf("%v", contents); exp != act {
+			t.Errorf("Wrong batch: %v != %v", act, exp)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Batch was not flushed after period")
+	}
+}
+
+func TestBatchCloseOnceEmpty(t *testing.T) {
+	conf := NewBatchConfig()
+	conf.Count = 10
+
+	b := newTestBatch(t, conf, metrics.Noop())
+	pushBatchTestMessages(t, b, "foo", "bar")
+
+	closed := make(chan struct{})
+	go func() {
+		b.CloseOnceEmpty()
+		close(closed)
+	}()
+
+	if exp, act := "[foo bar]", fmt.Sprintf("%v", readBatchTestMessage(t, b)); exp != act {
+		t.Errorf("Wrong batch: %v != %v", act, exp)
+	}
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Buffer did not close once empty")
+	}
+	if _, err := b.NextMessage(); err != types.ErrTypeClosed {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrTypeClosed)
+	}
+}
+
+func TestBatchBlocksWhenFull(t *testing.T) {
+	conf := NewBatchConfig()
+	conf.Count = 1
+
+	b := newTestBatch(t, conf, metrics.Noop())
+	defer b.Close()
+
+	pushBatchTestMessages(t, b, "foo", "bar")
+
+	pushed := make(chan error)
+	go func() {
+		_, err := b.PushMessage(message.New([][]byte{[]byte("baz")}))
+		pushed <- err
+	}()
+
+	// Reading a batch does not free space until it is shifted.
+	if _, err := b.NextMessage(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-pushed:
+		t.Fatal("Push did not block")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	if _, err := b.ShiftMessage(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-pushed:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Push did not unblock")
+	}
+
+	for _, exp := range []string{"[bar]", "[baz]"} {
+		if act := fmt.Sprintf("%v", readBatchTestMessage(t, b)); exp != act {
+			t.Errorf("Wrong batch: %v != %v", act, exp)
+		}
+	}
+}
+
+func TestBatchMetrics(t *testing.T) {
+	conf := NewBatchConfig()
+	conf.Count = 3
+
+	stats := metrics.NewLocal()
+	b := newTestBatch(t, conf, stats)
+	defer b.Close()
+
+	pushBatchTestMessages(t, b, "foo", "bar")
+	if exp, act := int64(2), stats.GetCounters()["buffer.batch.pending.parts"]; exp != act {
+		t.Errorf("Wrong pending parts: %v != %v", act, exp)
+	}
+	if exp, act := int64(6), stats.GetCounters()["buffer.batch.pending.bytes"]; exp != act {
+		t.Errorf("Wrong pending bytes: %v != %v", act, exp)
+	}
+
+	pushBatchTestMessages(t, b, "baz")
+	readBatchTestMessage(t, b)
+
+	counters := stats.GetCounters()
+	if exp, act := int64(0), counters["buffer.batch.pending.parts"]; exp != act {
+		t.Errorf("Wrong pending parts: %v != %v", act, exp)
+	}
+	if exp, act := int64(1), counters["buffer.batch.on_count"]; exp != act {
+		t.Errorf("Wrong on_count: %v != %v", act, exp)
+	}
+	if exp, act := int64(3), counters["buffer.batch.parts.sent"]; exp != act {
+		t.Errorf("Wrong parts sent: %v != %v", act, exp)
+	}
+}
@@ -0,0 +1,784 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package single
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// Sync policies of the file buffer.
+const (
+	FileSyncAlways   = "always"
+	FileSyncInterval = "interval"
+	FileSyncNone     = "none"
+)
+
+// FileConfig is config options for the File buffer type.
+type FileConfig struct {
+	Directory    string `json:"directory" yaml:"directory"`
+	MaxSize      int64  `json:"max_size" yaml:"max_size"`
+	SegmentSize  int64  `json:"segment_size" yaml:"segment_size"`
+	Sync         string `json:"sync" yaml:"sync"`
+	SyncInterval string `json:"sync_interval" yaml:"sync_interval"`
+}
+
+// NewFileConfig creates a new FileConfig with default values.
+func NewFileConfig() FileConfig {
+	return FileConfig{
+		Directory:    "",
+		MaxSize:      1024 * 1024 * 1024, // 1GiB
+		SegmentSize:  64 * 1024 * 1024,   // 64MiB
+		Sync:         FileSyncInterval,
+		SyncInterval: "1s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	fileSegmentPrefix = "segment_"
+	fileTrackerName   = "tracker"
+
+	// Each record is prefixed with the length and CRC32 checksum of its
+	// payload.
+	fileRecordHeaderLen = 8
+
+	// Each payload begins with the time at which it was written as unix
+	// nanoseconds.
+	fileRecordTimeLen = 8
+
+	// The interval at which gauges are refreshed.
+	fileStatsInterval = time.Second
+)
+
+// File is a buffer that appends messages to segment files within a directory,
+// and tracks the position of the oldest unacknowledged message in a tracker
+// file, allowing unacknowledged messages to be read again after a restart.
+type File struct {
+	conf         FileConfig
+	syncInterval time.Duration
+
+	log       log.Modular
+	mSync     metrics.StatCounter
+	mErr      metrics.StatCounter
+	mMessages metrics.StatGauge
+	mAge      metrics.StatGauge
+
+	cond *sync.Cond
+
+	tracker *os.File
+
+	writeSeg    int
+	writeFile   *os.File
+	writeOffset int64
+
+	readSeg    int
+	readFile   *os.File
+	readOffset int64
+
+	// The length of the record returned by the last call to NextMessage,
+	// which is skipped by the following call to ShiftMessage.
+	pendingLen int64
+
+	backlog  int64
+	messages int64
+	dirty    bool
+	closed   bool
+
+	// The time at which the oldest unacknowledged message was written.
+	oldest time.Time
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewFile creates a buffer persisted to segment files within a directory. Any
+// messages that were not acknowledged before a previous instance was closed
+// are read first.
+func NewFile(conf FileConfig, log log.Modular, stats metrics.Type) (*File, error) {
+	if len(conf.Directory) == 0 {
+		return nil, errors.New("a directory must be specified")
+	}
+	if conf.SegmentSize <= 0 {
+		return nil, errors.New("segment size must be larger than zero")
+	}
+	if conf.MaxSize < conf.SegmentSize {
+		return nil, errors.New("max size must be at least the segment size")
+	}
+
+	var syncInterval time.Duration
+	switch conf.Sync {
+	case FileSyncAlways, FileSyncNone:
+	case FileSyncInterval:
+		var err error
+		if syncInterval, err = time.ParseDuration(conf.SyncInterval); err != nil {
+			return nil, fmt.Errorf("failed to parse sync interval: %v", err)
+		}
+		if syncInterval <= 0 {
+			return nil, errors.New("sync interval must be larger than zero")
+		}
+	default:
+		return nil, fmt.Errorf("sync policy not recognised: %v", conf.Sync)
+	}
+
+	if err := os.MkdirAll(conf.Directory, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	f := &File{
+		conf:         conf,
+		syncInterval: syncInterval,
+		log:          log,
+		mSync:        stats.GetCounter("buffer.file.sync"),
+		mErr:         stats.GetCounter("buffer.file.error"),
+		mMessages:    stats.GetGauge("buffer.backlog_messages"),
+		mAge:         stats.GetGauge("buffer.backlog_age"),
+		cond:         sync.NewCond(&sync.Mutex{}),
+		closeChan:    make(chan struct{}),
+		closedChan:   make(chan struct{}),
+	}
+	if err := f.open(); err != nil {
+		f.closeFiles()
+		return nil, err
+	}
+
+	f.log.Infof("Storing messages to file in: %s\n", conf.Directory)
+	if f.backlog > 0 {
+		f.log.Infof(
+			"Replaying %v unacknowledged messages (%v bytes)\n",
+			f.messages, f.backlog,
+		)
+	}
+	f.updateGauges()
+
+	go f.loop()
+	return f, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (f *File) segmentPath(seg int) string {
+	return filepath.Join(f.conf.Directory, fileSegmentPrefix+strconv.Itoa(seg))
+}
+
+// listSegments returns the indexes of all segment files in ascending order.
+func (f *File) listSegments() ([]int, error) {
+	infos, err := ioutil.ReadDir(f.conf.Directory)
+	if err != nil {
+		return nil, err
+	}
+	var segs []int
+	for _, info := range infos {
+		name := info.Name()
+		if !strings.HasPrefix(name, fileSegmentPrefix) {
+			continue
+		}
+		if seg, err := strconv.Atoi(strings.TrimPrefix(name, fileSegmentPrefix)); err == nil {
+			segs = append(segs, seg)
+		}
+	}
+	sort.Ints(segs)
+	return segs, nil
+}
+
+// open recovers the read and write positions from the files of a previous
+// instance, or creates them if they do not exist.
+func (f *File) open() error {
+	var err error
+	if f.tracker, err = os.OpenFile(
+		filepath.Join(f.conf.Directory, fileTrackerName), os.O_RDWR|os.O_CREATE, 0644,
+	); err != nil {
+		return fmt.Errorf("failed to open tracker: %v", err)
+	}
+
+	trackerBytes := make([]byte, 16)
+	if _, err = f.tracker.ReadAt(trackerBytes, 0); err == nil {
+		f.readSeg = int(binary.BigEndian.Uint64(trackerBytes[:8]))
+		f.readOffset = int64(binary.BigEndian.Uint64(trackerBytes[8:]))
+	} else if err != io.EOF {
+		return fmt.Errorf("failed to read tracker: %v", err)
+	}
+
+	segs, err := f.listSegments()
+	if err != nil {
+		return fmt.Errorf("failed to list segments: %v", err)
+	}
+
+	// Remove segments that have already been read.
+	for len(segs) > 0 && segs[0] < f.readSeg {
+		os.Remove(f.segmentPath(segs[0]))
+		segs = segs[1:]
+	}
+	if len(segs) == 0 || segs[0] > f.readSeg {
+		// The segment being read no longer exists, so we continue from the
+		// oldest segment that does.
+		if len(segs) > 0 {
+			f.readSeg = segs[0]
+		}
+		f.readOffset = 0
+	}
+
+	f.writeSeg = f.readSeg
+	if len(segs) > 0 {
+		f.writeSeg = segs[len(segs)-1]
+	}
+	if f.writeFile, err = os.OpenFile(f.segmentPath(f.writeSeg), os.O_RDWR|os.O_CREATE, 0644); err != nil {
+		return fmt.Errorf("failed to open segment: %v", err)
+	}
+
+	// Find the end of the last valid record of the write segment, anything
+	// beyond it is the result of an interrupted write and is truncated.
+	scanFrom := int64(0)
+	if f.writeSeg == f.readSeg {
+		scanFrom = f.readOffset
+	}
+	var writeCount int64
+	if f.writeOffset, writeCount, err = scanRecords(f.writeFile, scanFrom); err != nil {
+		return fmt.Errorf("failed to scan segment: %v", err)
+	}
+	if err = f.writeFile.Truncate(f.writeOffset); err != nil {
+		return fmt.Errorf("failed to truncate segment: %v", err)
+	}
+	if f.writeSeg == f.readSeg && f.readOffset > f.writeOffset {
+		f.readOffset = f.writeOffset
+	}
+
+	if f.readFile, err = os.Open(f.segmentPath(f.readSeg)); err != nil {
+		return fmt.Errorf("failed to open segment: %v", err)
+	}
+
+	// Calculate the backlog of unread messages. Segments prior to the write
+	// segment were complete when they were rotated and so only the record
+	// headers are walked.
+	for _, seg := range segs {
+		if seg == f.writeSeg {
+			break
+		}
+		offset := int64(0)
+		if seg == f.readSeg {
+			offset = f.readOffset
+		}
+		size, count, err := countRecords(f.segmentPath(seg), offset)
+		if err != nil {
+			return fmt.Errorf("failed to read segment: %v", err)
+		}
+		f.backlog += size
+		f.messages += count
+	}
+	f.backlog += f.writeOffset - f.readOffset
+	f.messages += writeCount
+
+	if f.messages > 0 {
+		if f.oldest, err = readRecordTime(f.readFile, f.readOffset); err != nil {
+			return fmt.Errorf("failed to read segment: %v", err)
+		}
+	}
+	return nil
+}
+
+// scanRecords returns the offset of the end of the last valid record in a
+// file and the number of valid records, starting from a given offset.
+func scanRecords(file *os.File, offset int64) (int64, int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	var count int64
+	header := make([]byte, fileRecordHeaderLen)
+	for offset+fileRecordHeaderLen <= info.Size() {
+		if _, err = file.ReadAt(header, offset); err != nil {
+			return 0, 0, err
+		}
+		payloadLen := int64(binary.BigEndian.Uint32(header[:4]))
+		if offset+fileRecordHeaderLen+payloadLen > info.Size() {
+			break
+		}
+		payload := make([]byte, payloadLen)
+		if _, err = file.ReadAt(payload, offset+fileRecordHeaderLen); err != nil {
+			return 0, 0, err
+		}
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+			break
+		}
+		offset += fileRecordHeaderLen + payloadLen
+		count++
+	}
+	return offset, count, nil
+}
+
+// countRecords walks the record headers of a segment file starting from a
+// given offset, returning the remaining size of the file and the number of
+// records within it. Checksums are not verified.
+func countRecords(path string, offset int64) (int64, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	var count int64
+	header := make([]byte, fileRecordHeaderLen)
+	for i := offset; i+fileRecordHeaderLen <= info.Size(); count++ {
+		if _, err = file.ReadAt(header, i); err != nil {
+			return 0, 0, err
+		}
+		i += fileRecordHeaderLen + int64(binary.BigEndian.Uint32(header[:4]))
+	}
+	return info.Size() - offset, count, nil
+}
+
+// readRecordTime reads the time at which the record at an offset was written.
+func readRecordTime(file *os.File, offset int64) (time.Time, error) {
+	timeBytes := make([]byte, fileRecordTimeLen)
+	if _, err := file.ReadAt(timeBytes, offset+fileRecordHeaderLen); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(timeBytes))), nil
+}
+
+//------------------------------------------------------------------------------
+
+// encodeFileRecord serialises a message including the metadata of each part
+// and the time at which it was written.
+func encodeFileRecord(msg types.Message, t time.Time) []byte {
+	body := message.ToBytes(msg)
+
+	payload := make([]byte, fileRecordTimeLen+4, fileRecordTimeLen+4+len(body))
+	binary.BigEndian.PutUint64(payload, uint64(t.UnixNano()))
+	binary.BigEndian.PutUint32(payload[fileRecordTimeLen:], uint32(len(body)))
+	payload = append(payload, body...)
+
+	var lenBytes [4]byte
+	appendString := func(s string) {
+		binary.BigEndian.PutUint32(lenBytes[:], uint32(len(s)))
+		payload = append(payload, lenBytes[:]...)
+		payload = append(payload, s...)
+	}
+	msg.Iter(func(i int, p types.Part) error {
+		var keys, values []string
+		p.Metadata().Iter(func(k, v string) error {
+			keys = append(keys, k)
+			values = append(values, v)
+			return nil
+		})
+		binary.BigEndian.PutUint32(lenBytes[:], uint32(len(keys)))
+		payload = append(payload, lenBytes[:]...)
+		for i := range keys {
+			appendString(keys[i])
+			appendString(values[i])
+		}
+		return nil
+	})
+
+	record := make([]byte, fileRecordHeaderLen, fileRecordHeaderLen+len(payload))
+	binary.BigEndian.PutUint32(record[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:], crc32.ChecksumIEEE(payload))
+	return append(record, payload...)
+}
+
+// decodeFileRecord deserialises the payload of a record into a message and the
+// time at which it was written.
+func decodeFileRecord(payload []byte) (types.Message, time.Time, error) {
+	if len(payload) < fileRecordTimeLen {
+		return nil, time.Time{}, types.ErrBlockCorrupted
+	}
+	t := time.Unix(0, int64(binary.BigEndian.Uint64(payload)))
+	payload = payload[fileRecordTimeLen:]
+
+	readLen := func() (int, error) {
+		if len(payload) < 4 {
+			return 0, types.ErrBlockCorrupted
+		}
+		l := int(binary.BigEndian.Uint32(payload))
+		payload = payload[4:]
+		if l > len(payload) {
+			return 0, types.ErrBlockCorrupted
+		}
+		return l, nil
+	}
+	readString := func() (string, error) {
+		l, err := readLen()
+		if err != nil {
+			return "", err
+		}
+		s := string(payload[:l])
+		payload = payload[l:]
+		return s, nil
+	}
+
+	bodyLen, err := readLen()
+	if err != nil {
+		return nil, t, err
+	}
+	msg, err := message.FromBytes(payload[:bodyLen])
+	if err != nil {
+		return nil, t, err
+	}
+	payload = payload[bodyLen:]
+
+	err = msg.Iter(func(i int, p types.Part) error {
+		count, err := readLen()
+		if err != nil {
+			return err
+		}
+		for j := 0; j < count; j++ {
+			k, err := readString()
+			if err != nil {
+				return err
+			}
+			v, err := readString()
+			if err != nil {
+				return err
+			}
+			p.Metadata().Set(k, v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, t, err
+	}
+	return msg, t, nil
+}
+
+//------------------------------------------------------------------------------
+
+// loop periodically refreshes gauges and, when the sync policy is interval,
+// flushes written data and the tracker to disk.
+func (f *File) loop() {
+	defer close(f.closedChan)
+
+	var syncChan <-chan time.Time
+	if f.syncInterval > 0 {
+		syncTicker := time.NewTicker(f.syncInterval)
+		defer syncTicker.Stop()
+		syncChan = syncTicker.C
+	}
+
+	statsTicker := time.NewTicker(fileStatsInterval)
+	defer statsTicker.Stop()
+
+	for {
+		select {
+		case <-syncChan:
+			f.cond.L.Lock()
+			if !f.closed && f.dirty {
+				f.sync()
+			}
+			f.cond.L.Unlock()
+		case <-statsTicker.C:
+			f.cond.L.Lock()
+			f.updateGauges()
+			f.cond.L.Unlock()
+		case <-f.closeChan:
+			return
+		}
+	}
+}
+
+// updateGauges sets the message count and age of the backlog, must be called
+// with the lock held.
+func (f *File) updateGauges() {
+	f.mMessages.Set(f.messages)
+	if f.messages == 0 {
+		f.mAge.Set(0)
+	} else {
+		f.mAge.Set(time.Since(f.oldest).Nanoseconds())
+	}
+}
+
+// sync flushes written data and the tracker to disk, must be called with the
+// lock held.
+func (f *File) sync() {
+	f.dirty = false
+	f.mSync.Incr(1)
+	if err := f.writeFile.Sync(); err != nil {
+		f.mErr.Incr(1)
+		f.log.Errorf("Failed to sync segment: %v\n", err)
+	}
+	if err := f.tracker.Sync(); err != nil {
+		f.mErr.Incr(1)
+		f.log.Errorf("Failed to sync tracker: %v\n", err)
+	}
+}
+
+// written marks that data has been written, and syncs it immediately if the
+// policy requires it. Must be called with the lock held.
+func (f *File) written() {
+	f.dirty = true
+	if f.conf.Sync == FileSyncAlways {
+		f.sync()
+	}
+}
+
+func (f *File) writeTracker() error {
+	trackerBytes := make([]byte, 16)
+	binary.BigEndian.PutUint64(trackerBytes[:8], uint64(f.readSeg))
+	binary.BigEndian.PutUint64(trackerBytes[8:], uint64(f.readOffset))
+	_, err := f.tracker.WriteAt(trackerBytes, 0)
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+// ShiftMessage removes the oldest message from the stack. Returns the backlog
+// in bytes.
+func (f *File) ShiftMessage() (int, error) {
+	f.cond.L.Lock()
+	defer f.cond.L.Unlock()
+
+	if f.closed {
+		return 0, types.ErrTypeClosed
+	}
+	if f.pendingLen == 0 {
+		return int(f.backlog), nil
+	}
+
+	f.readOffset += f.pendingLen
+	f.backlog -= f.pendingLen
+	f.pendingLen = 0
+	if f.messages > 0 {
+		f.messages--
+	}
+	f.updateGauges()
+
+	if err := f.writeTracker(); err != nil {
+		f.mErr.Incr(1)
+		return int(f.backlog), fmt.Errorf("failed to write tracker: %v", err)
+	}
+	f.written()
+	f.cond.Broadcast()
+	return int(f.backlog), nil
+}
+
+// nextSegment moves the reader onto the next segment and deletes the previous
+// one, must be called with the lock held.
+func (f *File) nextSegment() error {
+	prevSeg := f.readSeg
+	f.readFile.Close()
+
+	f.readSeg++
+	f.readOffset = 0
+	var err error
+	if f.readFile, err = os.Open(f.segmentPath(f.readSeg)); err != nil {
+		return err
+	}
+	if err = f.writeTracker(); err != nil {
+		return err
+	}
+	f.written()
+	return os.Remove(f.segmentPath(prevSeg))
+}
+
+// NextMessage reads the oldest message, the message is preserved until
+// ShiftMessage is called.
+func (f *File) NextMessage() (types.Message, error) {
+	f.cond.L.Lock()
+	defer f.cond.L.Unlock()
+
+	for {
+		for !f.closed && f.readSeg == f.writeSeg && f.readOffset >= f.writeOffset {
+			f.cond.Wait()
+		}
+		if f.closed {
+			return nil, types.ErrTypeClosed
+		}
+
+		info, err := f.readFile.Stat()
+		if err != nil {
+			f.mErr.Incr(1)
+			return nil, err
+		}
+		size := info.Size()
+		if f.readSeg == f.writeSeg {
+			size = f.writeOffset
+		}
+		if f.readOffset >= size {
+			if err = f.nextSegment(); err != nil {
+				f.mErr.Incr(1)
+				return nil, fmt.Errorf("failed to move to next segment: %v", err)
+			}
+			continue
+		}
+
+		header := make([]byte, fileRecordHeaderLen)
+		if f.readOffset+fileRecordHeaderLen > size {
+			f.pendingLen = size - f.readOffset
+			return nil, types.ErrBlockCorrupted
+		}
+		if _, err = f.readFile.ReadAt(header, f.readOffset); err != nil {
+			f.mErr.Incr(1)
+			return nil, err
+		}
+
+		payloadLen := int64(binary.BigEndian.Uint32(header[:4]))
+		if f.readOffset+fileRecordHeaderLen+payloadLen > size {
+			f.pendingLen = size - f.readOffset
+			return nil, types.ErrBlockCorrupted
+		}
+		f.pendingLen = fileRecordHeaderLen + payloadLen
+
+		payload := make([]byte, payloadLen)
+		if _, err = f.readFile.ReadAt(payload, f.readOffset+fileRecordHeaderLen); err != nil {
+			f.mErr.Incr(1)
+			return nil, err
+		}
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+			return nil, types.ErrBlockCorrupted
+		}
+		msg, written, err := decodeFileRecord(payload)
+		if err == nil {
+			f.oldest = written
+		}
+		return msg, err
+	}
+}
+
+// PushMessage adds a new message to the stack. Returns the backlog in bytes.
+func (f *File) PushMessage(msg types.Message) (int, error) {
+	now := time.Now()
+	record := encodeFileRecord(msg, now)
+	recordLen := int64(len(record))
+	if recordLen > f.conf.MaxSize {
+		return 0, types.ErrMessageTooLarge
+	}
+
+	f.cond.L.Lock()
+	defer f.cond.L.Unlock()
+
+	for !f.closed && f.backlog > 0 && f.backlog+recordLen > f.conf.MaxSize {
+		f.cond.Wait()
+	}
+	if f.closed {
+		return 0, types.ErrTypeClosed
+	}
+
+	if f.writeOffset > 0 && f.writeOffset+recordLen > f.conf.SegmentSize {
+		if err := f.rotate(); err != nil {
+			f.mErr.Incr(1)
+			return 0, fmt.Errorf("failed to create segment: %v", err)
+		}
+	}
+
+	if _, err := f.writeFile.WriteAt(record, f.writeOffset); err != nil {
+		f.mErr.Incr(1)
+		return 0, fmt.Errorf("failed to write segment: %v", err)
+	}
+	f.writeOffset += recordLen
+	f.backlog += recordLen
+	if f.messages++; f.messages == 1 {
+		f.oldest = now
+	}
+	f.updateGauges()
+	f.written()
+
+	f.cond.Broadcast()
+	return int(f.backlog), nil
+}
+
+// rotate closes the current write segment and creates the next one, must be
+// called with the lock held.
+func (f *File) rotate() error {
+	if f.conf.Sync != FileSyncNone {
+		if err := f.writeFile.Sync(); err != nil {
+			return err
+		}
+	}
+	nextFile, err := os.OpenFile(f.segmentPath(f.writeSeg+1), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	f.writeFile.Close()
+	f.writeFile = nextFile
+	f.writeSeg++
+	f.writeOffset = 0
+	return nil
+}
+
+// closeFiles syncs and closes all open files.
+func (f *File) closeFiles() {
+	for _, file := range []*os.File{f.writeFile, f.tracker} {
+		if file != nil {
+			if f.conf.Sync != FileSyncNone {
+				file.Sync()
+			}
+			file.Close()
+		}
+	}
+	if f.readFile != nil {
+		f.readFile.Close()
+	}
+}
+
+// CloseOnceEmpty closes the Buffer once the buffer has been emptied, this is a
+// way for a writer to signal to a reader that it is finished writing messages,
+// and therefore the reader can close once it is caught up. This call blocks
+// until the close is completed.
+func (f *File) CloseOnceEmpty() {
+	f.cond.L.Lock()
+	for f.backlog > 0 && !f.closed {
+		f.cond.Wait()
+	}
+	f.cond.L.Unlock()
+	f.Close()
+}
+
+// Close closes the Buffer so that blocked readers or writers become
+// unblocked. Unacknowledged messages remain on disk and are read again by the
+// next buffer opened with the same directory.
+func (f *File) Close() {
+	f.cond.L.Lock()
+	if f.closed {
+		f.cond.L.Unlock()
+		return
+	}
+	f.closed = true
+	close(f.closeChan)
+	f.closeFiles()
+	f.cond.Broadcast()
+	f.cond.L.Unlock()
+
+	<-f.closedChan
+}
+
+//------------------------------------------------------------------------------
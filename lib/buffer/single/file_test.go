@@ -0,0 +1,396 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package single
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func newTestFileConf(t *testing.T) (FileConfig, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "benthos_file_buffer_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewFileConfig()
+	conf.Directory = dir
+	conf.MaxSize = 1000
+	conf.SegmentSize = 100
+	conf.Sync = FileSyncNone
+	return conf, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func newTestFile(t *testing.T, conf FileConfig) *File {
+	t.Helper()
+
+	f, err := NewFile(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func pushFileTestMessages(t *testing.T, f *File, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		if _, err := f.PushMessage(message.New([][]byte{
+			[]byte(fmt.Sprintf("test%v", i)),
+		})); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFileTestMessages(t *testing.T, f *File, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		msg, err := f.NextMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp, act := fmt.Sprintf("test%v", i), string(msg.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message: %v != %v", act, exp)
+		}
+		if _, err = f.ShiftMessage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func TestFileBufferBadConfig(t *testing.T) {
+	conf, cleanup := newTestFileConf(t)
+	defer cleanup()
+
+	badConfs := map[string]func(c *FileConfig){
+		"no directory":  func(c *FileConfig) { c.Directory = "" },
+		"bad segment":   func(c *FileConfig) { c.SegmentSize = 0 },
+		"small max":     func(c *FileConfig) { c.MaxSize = c.SegmentSize - 1 },
+		"bad policy":    func(c *FileConfig) { c.Sync = "nope" },
+		"bad interval":  func(c *FileConfig) { c.Sync, c.SyncInterval = FileSyncInterval, "nope" },
+		"zero interval": func(c *FileConfig) { c.Sync, c.SyncInterval = FileSyncInterval, "0s" },
+	}
+	for name, fn := range badConfs {
+		c := conf
+		fn(&c)
+		if _, err := NewFile(c, log.Noop(), metrics.Noop()); err == nil {
+			t.Errorf("Expected error from %v", name)
+		}
+	}
+}
+
+func TestFileBufferBasic(t *testing.T) {
+	conf, cleanup := newTestFileConf(t)
+	defer cleanup()
+
+	conf.MaxSize = 10000
+	for _, policy := range []string{FileSyncAlways, FileSyncInterval, FileSyncNone} {
+		conf.Sync = policy
+		conf.SyncInterval = "10ms"
+
+		f := newTestFile(t, conf)
+		pushFileTestMessages(t, f, 0, 50)
+		readFileTestMessages(t, f, 0, 50)
+
+		if backlog, _ := f.ShiftMessage(); backlog != 0 {
+			t.Errorf("Non-zero backlog: %v", backlog)
+		}
+		f.Close()
+	}
+
+	// Segments should have been rotated and removed as they were read.
+	segs, err := ioutil.ReadDir(conf.Directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 2, len(segs); exp != act {
+		t.Errorf("Wrong count of files remaining: %v != %v", act, exp)
+	}
+}
+
+func TestFileBufferMetadata(t *testing.T) {
+	conf, cleanup := newTestFileConf(t)
+	defer cleanup()
+
+	f := newTestFile(t, conf)
+	defer f.Close()
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	msg.Get(0).Metadata().Set("a", "1").Set("b", "2")
+	msg.Get(1).Metadata().Set("c", "")
+
+	if _, err := f.PushMessage(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := f.NextMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := [][]byte{[]byte("foo"), []byte("bar")}, message.GetAllBytes(res); fmt.Sprintf("%s", act) != fmt.Sprintf("%s", exp) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	for i, exp := range []map[string]string{{"a": "1", "b": "2"}, {"c": ""}} {
+		act := map[string]string{}
+		res.Get(i).Metadata().Iter(func(k, v string) error {
+			act[k] = v
+			return nil
+		})
+		if fmt.Sprint(act) != fmt.Sprint(exp) {
+			t.Errorf("Wrong metadata for part %v: %v != %v", i, act, exp)
+		}
+	}
+}
+
+func TestFileBufferReplay(t *testing.T) {
+	conf, cleanup := newTestFileConf(t)
+	defer cleanup()
+
+	f := newTestFile(t, conf)
+	pushFileTestMessages(t, f, 0, 20)
+	readFileTestMessages(t, f, 0, 5)
+
+	// Read a message without acknowledging it.
+	if _, err := f.NextMessage(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	f = newTestFile(t, conf)
+	pushFileTestMessages(t, f, 20, 25)
+	readFileTestMessages(t, f, 5, 25)
+	f.Close()
+
+	f = newTestFile(t, conf)
+	defer f.Close()
+	if backlog, _ := f.ShiftMessage(); backlog != 0 {
+		t.Errorf("Non-zero backlog: %v", backlog)
+	}
+}
+
+func TestFileBufferTruncatedWrite(t *testing.T) {
+	conf, cleanup := newTestFileConf(t)
+	defer cleanup()
+
+	conf.SegmentSize = 1000
+	f := newTestFile(t, conf)
+	pushFileTestMessages(t, f, 0, 3)
+	f.Close()
+
+	// Simulate a write that was interrupted by a crash.
+	segPath := filepath.Join(conf.Directory, fileSegmentPrefix+"0")
+	seg, err := os.OpenFile(segPath, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = seg.Write([]byte{0, 0, 0, 50, 1, 2, 3, 4, 5}); err != nil {
+		t.Fatal(err)
+	}
+	seg.Close()
+
+	f = newTestFile(t, conf)
+	defer f.Close()
+
+	pushFileTestMessages(t, f, 3, 5)
+	readFileTestMessages(t, f, 0, 5)
+}
+
+func TestFileBufferCorruptedRecord(t *testing.T) {
+	conf, cleanup := newTestFileConf(t)
+	defer cleanup()
+
+	conf.SegmentSize = 1000
+	f := newTestFile(t, conf)
+	pushFileTestMessages(t, f, 0, 3)
+	f.Close()
+
+	// Flip a byte in the payload of the second record.
+	segPath := filepath.Join(conf.Directory, fileSegmentPrefix+"0")
+	segBytes, err := ioutil.ReadFile(segPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordLen := len(segBytes) / 3
+	segBytes[recordLen+fileRecordHeaderLen+2] ^= 0xFF
+	if err = ioutil.WriteFile(segPath, segBytes, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f = newTestFile(t, conf)
+	defer f.Close()
+
+	// The truncation on startup removes the corrupted record and everything
+	// after it, as it is indistinguishable from an interrupted write.
+	readFileTestMessages(t, f, 0, 1)
+	if backlog, _ := f.ShiftMessage(); backlog != 0 {
+		t.Errorf("Non-zero backlog: %v", backlog)
+	}
+}
+
+func TestFileBufferMaxSize(t *testing.T) {
+	conf, cleanup := newTestFileConf(t)
+	defer cleanup()
+
+	conf.MaxSize = 123
+	f := newTestFile(t, conf)
+	defer f.Close()
+
+	if _, err := f.PushMessage(message.New([][]byte{make([]byte, 124)})); err != types.ErrMessageTooLarge {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrMessageTooLarge)
+	}
+
+	// Each record of a five byte message is 41 bytes.
+	pushFileTestMessages(t, f, 0, 3)
+
+	pushed := make(chan error)
+	go func() {
+		_, err := f.PushMessage(message.New([][]byte{[]byte("test3")}))
+		pushed <- err
+	}()
+
+	// Reading a message does not free space until it is acknowledged.
+	if _, err := f.NextMessage(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-pushed:
+		t.Fatal("Push did not block at max size")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	if _, err := f.ShiftMessage(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-pushed:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Push did not unblock")
+	}
+	readFileTestMessages(t, f, 1, 4)
+}
+
+func TestFileBufferClose(t *testing.T) {
+	conf, cleanup := newTestFileConf(t)
+	defer cleanup()
+
+	f := newTestFile(t, conf)
+
+	read := make(chan error)
+	go func() {
+		_, err := f.NextMessage()
+		read <- err
+	}()
+
+	f.Close()
+	select {
+	case err := <-read:
+		if err != types.ErrTypeClosed {
+			t.Errorf("Wrong error returned: %v != %v", err, types.ErrTypeClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Read did not unblock")
+	}
+
+	if _, err := f.PushMessage(message.New([][]byte{[]byte("foo")})); err != types.ErrTypeClosed {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrTypeClosed)
+	}
+}
+
+func TestFileBufferCloseOnceEmpty(t *testing.T) {
+	conf, cleanup := newTestFileConf(t)
+	defer cleanup()
+
+	f := newTestFile(t, conf)
+	pushFileTestMessages(t, f, 0, 2)
+
+	closed := make(chan struct{})
+	go func() {
+		f.CloseOnceEmpty()
+		close(closed)
+	}()
+
+	readFileTestMessages(t, f, 0, 2)
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Buffer did not close once empty")
+	}
+}
+
+func TestFileBufferGauges(t *testing.T) {
+	conf, cleanup := newTestFileConf(t)
+	defer cleanup()
+
+	stats := metrics.NewLocal()
+	f, err := NewFile(conf, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Spread the messages across multiple segments.
+	pushFileTestMessages(t, f, 0, 5)
+	readFileTestMessages(t, f, 0, 1)
+	if exp, act := int64(4), stats.GetCounters()["buffer.backlog_messages"]; exp != act {
+		t.Errorf("Wrong backlog messages: %v != %v", act, exp)
+	}
+	f.Close()
+
+	<-time.After(time.Millisecond * 10)
+
+	stats = metrics.NewLocal()
+	if f, err = NewFile(conf, log.Noop(), stats); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if exp, act := int64(4), stats.GetCounters()["buffer.backlog_messages"]; exp != act {
+		t.Errorf("Wrong backlog messages after restart: %v != %v", act, exp)
+	}
+	if age := stats.GetCounters()["buffer.backlog_age"]; age < int64(time.Millisecond*10) {
+		t.Errorf("Backlog age too low after restart: %v", age)
+	}
+
+	readFileTestMessages(t, f, 1, 5)
+	if exp, act := int64(0), stats.GetCounters()["buffer.backlog_messages"]; exp != act {
+		t.Errorf("Wrong backlog messages: %v != %v", act, exp)
+	}
+	if exp, act := int64(0), stats.GetCounters()["buffer.backlog_age"]; exp != act {
+		t.Errorf("Wrong backlog age: %v != %v", act, exp)
+	}
+}
@@ -29,15 +29,28 @@ import (
 
 //------------------------------------------------------------------------------
 
+// Overflow policies of the memory buffer.
+const (
+	MemoryWhenFullBlock      = "block"
+	MemoryWhenFullDropOldest = "drop_oldest"
+	MemoryWhenFullDropNewest = "drop_newest"
+)
+
 // MemoryConfig is config values for a purely memory based ring buffer type.
 type MemoryConfig struct {
-	Limit int `json:"limit" yaml:"limit"`
+	Limit         int    `json:"limit" yaml:"limit"`
+	MessageLimit  int    `json:"message_limit" yaml:"message_limit"`
+	WhenFull      string `json:"when_full" yaml:"when_full"`
+	AckOnDelivery bool   `json:"ack_on_delivery" yaml:"ack_on_delivery"`
 }
 
 // NewMemoryConfig creates a new MemoryConfig with default values.
 func NewMemoryConfig() MemoryConfig {
 	return MemoryConfig{
-		Limit: 1024 * 1024 * 500, // 500MB
+		Limit:         1024 * 1024 * 500, // 500MB
+		MessageLimit:  0,
+		WhenFull:      MemoryWhenFullBlock,
+		AckOnDelivery: false,
 	}
 }
 
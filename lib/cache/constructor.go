@@ -49,31 +49,40 @@ var Constructors = map[string]TypeSpec{}
 
 // String constants representing each cache type.
 const (
-	TypeDynamoDB  = "dynamodb"
-	TypeMemcached = "memcached"
-	TypeMemory    = "memory"
-	TypeRedis     = "redis"
+	TypeDynamoDB   = "dynamodb"
+	TypeFile       = "file"
+	TypeMemcached  = "memcached"
+	TypeMemory     = "memory"
+	TypeMultilevel = "multilevel"
+	TypeRedis      = "redis"
+	TypeS3         = "s3"
 )
 
 //------------------------------------------------------------------------------
 
 // Config is the all encompassing configuration struct for all cache types.
 type Config struct {
-	Type      string          `json:"type" yaml:"type"`
-	DynamoDB  DynamoDBConfig  `json:"dynamodb" yaml:"dynamodb"`
-	Memcached MemcachedConfig `json:"memcached" yaml:"memcached"`
-	Memory    MemoryConfig    `json:"memory" yaml:"memory"`
-	Redis     RedisConfig     `json:"redis" yaml:"redis"`
+	Type       string           `json:"type" yaml:"type"`
+	DynamoDB   DynamoDBConfig   `json:"dynamodb" yaml:"dynamodb"`
+	File       FileConfig       `json:"file" yaml:"file"`
+	Memcached  MemcachedConfig  `json:"memcached" yaml:"memcached"`
+	Memory     MemoryConfig     `json:"memory" yaml:"memory"`
+	Multilevel MultilevelConfig `json:"multilevel" yaml:"multilevel"`
+	Redis      RedisConfig      `json:"redis" yaml:"redis"`
+	S3         S3Config         `json:"s3" yaml:"s3"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Type:      "memory",
-		DynamoDB:  NewDynamoDBConfig(),
-		Memcached: NewMemcachedConfig(),
-		Memory:    NewMemoryConfig(),
-		Redis:     NewRedisConfig(),
+		Type:       "memory",
+		DynamoDB:   NewDynamoDBConfig(),
+		File:       NewFileConfig(),
+		Memcached:  NewMemcachedConfig(),
+		Memory:     NewMemoryConfig(),
+		Multilevel: NewMultilevelConfig(),
+		Redis:      NewRedisConfig(),
+		S3:         NewS3Config(),
 	}
 }
 
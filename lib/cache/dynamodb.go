@@ -29,6 +29,7 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/aws/session"
+	"github.com/Jeffail/benthos/lib/util/clock"
 	"github.com/Jeffail/benthos/lib/util/retries"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -108,6 +109,7 @@ type DynamoDB struct {
 	ttl         time.Duration
 	backoffCtor func() backoff.BackOff
 	boffPool    sync.Pool
+	clock       clock.Clock
 
 	mLatency         metrics.StatTimer
 	mGetCount        metrics.StatCounter
@@ -147,6 +149,7 @@ func NewDynamoDB(conf Config, mgr types.Manager, log log.Modular, stats metrics.
 		log:   log.NewModule(".cache.dynamodb"),
 		stats: stats,
 		table: aws.String(conf.DynamoDB.Table),
+		clock: clock.Real(),
 
 		mLatency:         stats.GetTimer("cache.dynamodb.latency"),
 		mGetCount:        stats.GetCounter("cache.dynamodb.get.count"),
@@ -230,7 +233,7 @@ func (d *DynamoDB) Get(key string) ([]byte, error) {
 		if wait == backoff.Stop {
 			break
 		}
-		time.Sleep(wait)
+		d.clock.Sleep(wait)
 		d.mGetRetry.Incr(1)
 		result, err = d.get(key)
 	}
@@ -285,7 +288,7 @@ func (d *DynamoDB) Set(key string, value []byte) error {
 		if wait == backoff.Stop {
 			break
 		}
-		time.Sleep(wait)
+		d.clock.Sleep(wait)
 		d.mSetRetry.Incr(1)
 		_, err = d.client.PutItem(d.putItemInput(key, value))
 	}
@@ -343,7 +346,7 @@ func (d *DynamoDB) SetMulti(items map[string][]byte) error {
 			if wait == backoff.Stop {
 				break
 			}
-			time.After(wait)
+			d.clock.Sleep(wait)
 			d.mSetMultiRetry.Incr(1)
 		}
 	}
@@ -377,7 +380,7 @@ func (d *DynamoDB) Add(key string, value []byte) error {
 		if wait == backoff.Stop {
 			break
 		}
-		time.Sleep(wait)
+		d.clock.Sleep(wait)
 		d.mAddRetry.Incr(1)
 		err = d.add(key, value)
 	}
@@ -435,7 +438,7 @@ func (d *DynamoDB) Delete(key string) error {
 		if wait == backoff.Stop {
 			break
 		}
-		time.Sleep(wait)
+		d.clock.Sleep(wait)
 		d.mDelRetry.Incr(1)
 		err = d.delete(key)
 	}
@@ -482,7 +485,7 @@ func (d *DynamoDB) putItemInput(key string, value []byte) *dynamodb.PutItemInput
 
 	if d.ttl != 0 && d.conf.TTLKey != "" {
 		input.Item[d.conf.TTLKey] = &dynamodb.AttributeValue{
-			S: aws.String(time.Now().Add(d.ttl).Format(time.RFC3339Nano)),
+			S: aws.String(d.clock.Now().Add(d.ttl).Format(time.RFC3339Nano)),
 		}
 	}
 
@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/clock"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -16,6 +18,37 @@ import (
 	"github.com/ory/dockertest"
 )
 
+func TestDynamoDBTTL(t *testing.T) {
+	conf := NewDynamoDBConfig()
+	conf.Table = "foo"
+	conf.HashKey = "id"
+	conf.DataKey = "data"
+	conf.TTLKey = "ttl"
+
+	d := DynamoDB{
+		conf:  conf,
+		table: aws.String(conf.Table),
+		ttl:   time.Hour,
+		clock: clock.NewFake(time.Date(2018, 11, 26, 10, 0, 0, 0, time.UTC)),
+	}
+
+	input := d.putItemInput("bar", []byte("baz"))
+	if exp, act := "bar", *input.Item["id"].S; exp != act {
+		t.Errorf("Wrong key: %v != %v", act, exp)
+	}
+	if exp, act := "baz", string(input.Item["data"].B); exp != act {
+		t.Errorf("Wrong data: %v != %v", act, exp)
+	}
+	if exp, act := "2018-11-26T11:00:00Z", *input.Item["ttl"].S; exp != act {
+		t.Errorf("Wrong ttl: %v != %v", act, exp)
+	}
+
+	d.ttl = 0
+	if _, exists := d.putItemInput("bar", []byte("baz")).Item["ttl"]; exists {
+		t.Error("Unexpected ttl without a configured duration")
+	}
+}
+
 func TestDynamoDBIntegration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping integration test in short mode")
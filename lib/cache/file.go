@@ -0,0 +1,310 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeFile] = TypeSpec{
+		constructor: NewFile,
+		description: `
+The file cache stores each item as a file within a directory, which is created
+if it does not already exist. This cache persists across restarts of the service
+without any external dependencies, but can only be shared by Benthos instances
+with access to the same filesystem.
+
+Files are named after a SHA-256 hash of their key, so any key can be stored
+regardless of which characters it contains. Items are written to a temporary
+file first and then moved into place, so a partially written item is never
+read. The ` + "`add`" + ` operation is resolved with an exclusive link of the
+temporary file, and therefore only one of multiple concurrent attempts to add
+the same key succeeds.
+
+When a ` + "`ttl`" + ` is set, items that were last written longer ago than the
+TTL are treated as missing, and are removed by a background cleanup that runs
+every ` + "`cleanup_interval`" + `. An empty ` + "`ttl`" + ` disables
+expiration.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// FileConfig contains config fields for the File cache type.
+type FileConfig struct {
+	Directory       string `json:"directory" yaml:"directory"`
+	TTL             string `json:"ttl" yaml:"ttl"`
+	CleanupInterval string `json:"cleanup_interval" yaml:"cleanup_interval"`
+}
+
+// NewFileConfig creates a FileConfig populated with default values.
+func NewFileConfig() FileConfig {
+	return FileConfig{
+		Directory:       "",
+		TTL:             "",
+		CleanupInterval: "1m",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+const fileCacheTmpPrefix = ".tmp-"
+
+// File is a file system based cache implementation.
+type File struct {
+	dir string
+	ttl time.Duration
+
+	log log.Modular
+
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewFile creates a new File cache type.
+func NewFile(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (types.Cache, error) {
+	if len(conf.File.Directory) == 0 {
+		return nil, fmt.Errorf("a directory must be specified")
+	}
+
+	var ttl, cleanupInterval time.Duration
+	var err error
+	if len(conf.File.TTL) > 0 {
+		if ttl, err = time.ParseDuration(conf.File.TTL); err != nil {
+			return nil, fmt.Errorf("failed to parse ttl: %v", err)
+		}
+	}
+	if len(conf.File.CleanupInterval) > 0 {
+		if cleanupInterval, err = time.ParseDuration(conf.File.CleanupInterval); err != nil {
+			return nil, fmt.Errorf("failed to parse cleanup interval: %v", err)
+		}
+	}
+
+	if err = os.MkdirAll(conf.File.Directory, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	f := &File{
+		dir:        conf.File.Directory,
+		ttl:        ttl,
+		log:        log.NewModule(".cache.file"),
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}
+
+	if ttl > 0 && cleanupInterval > 0 {
+		go f.cleanupLoop(cleanupInterval)
+	} else {
+		close(f.closedChan)
+	}
+	return f, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (f *File) cleanupLoop(interval time.Duration) {
+	defer close(f.closedChan)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			f.cleanup()
+		case <-f.closeChan:
+			return
+		}
+	}
+}
+
+// cleanup removes all expired items, as well as temporary files that were
+// abandoned during a write.
+func (f *File) cleanup() {
+	infos, err := ioutil.ReadDir(f.dir)
+	if err != nil {
+		f.log.Errorf("Failed to read directory for cleanup: %v\n", err)
+		return
+	}
+	for _, info := range infos {
+		if info.IsDir() || !f.expired(info) {
+			continue
+		}
+		if err = os.Remove(filepath.Join(f.dir, info.Name())); err != nil && !os.IsNotExist(err) {
+			f.log.Errorf("Failed to remove expired item: %v\n", err)
+		}
+	}
+}
+
+func (f *File) expired(info os.FileInfo) bool {
+	return f.ttl > 0 && time.Since(info.ModTime()) >= f.ttl
+}
+
+func (f *File) pathFor(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(f.dir, hex.EncodeToString(hash[:]))
+}
+
+// writeTmp writes a value to a new temporary file within the cache directory
+// and returns its path.
+func (f *File) writeTmp(value []byte) (string, error) {
+	tmp, err := ioutil.TempFile(f.dir, fileCacheTmpPrefix)
+	if err != nil {
+		return "", err
+	}
+	if _, err = tmp.Write(value); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// Get attempts to locate and return a cached value by its key, returns an error
+// if the key does not exist.
+func (f *File) Get(key string) ([]byte, error) {
+	path := f.pathFor(key)
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, types.ErrKeyNotFound
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	if f.ttl > 0 {
+		info, err := file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if f.expired(info) {
+			return nil, types.ErrKeyNotFound
+		}
+	}
+	return ioutil.ReadAll(file)
+}
+
+// Set attempts to set the value of a key.
+func (f *File) Set(key string, value []byte) error {
+	tmpPath, err := f.writeTmp(value)
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, f.pathFor(key)); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// SetMulti attempts to set the value of multiple keys, returns an error if any
+// keys fail.
+func (f *File) SetMulti(items map[string][]byte) error {
+	for k, v := range items {
+		if err := f.Set(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Add attempts to set the value of a key only if the key does not already exist
+// and returns an error if the key already exists.
+func (f *File) Add(key string, value []byte) error {
+	tmpPath, err := f.writeTmp(value)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+
+	path := f.pathFor(key)
+
+	// Linking fails if the target already exists, which makes the check and
+	// the write a single atomic operation.
+	if err = os.Link(tmpPath, path); err == nil || !os.IsExist(err) {
+		return err
+	}
+
+	// An expired item does not count as existing, so it is removed and the
+	// link is attempted once more.
+	info, serr := os.Stat(path)
+	if serr != nil || !f.expired(info) {
+		return types.ErrKeyAlreadyExists
+	}
+	if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err = os.Link(tmpPath, path); os.IsExist(err) {
+		return types.ErrKeyAlreadyExists
+	}
+	return err
+}
+
+// Delete attempts to remove a key.
+func (f *File) Delete(key string) error {
+	if err := os.Remove(f.pathFor(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// CloseAsync shuts down the background cleanup of the cache.
+func (f *File) CloseAsync() {
+	f.closeOnce.Do(func() {
+		close(f.closeChan)
+	})
+}
+
+// WaitForClose blocks until the cache has closed down.
+func (f *File) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-f.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
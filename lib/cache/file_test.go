@@ -0,0 +1,223 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func newTestFileCache(t *testing.T, ttl string) (types.Cache, string) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "benthos_file_cache_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewConfig()
+	conf.File.Directory = filepath.Join(dir, "nested")
+	conf.File.TTL = ttl
+
+	c, err := NewFile(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return c, dir
+}
+
+func TestFileCacheGetAndSet(t *testing.T) {
+	c, dir := newTestFileCache(t, "")
+	defer os.RemoveAll(dir)
+
+	if _, err := c.Get("foo"); err != types.ErrKeyNotFound {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyNotFound)
+	}
+
+	if err := c.Set("foo", []byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("foo", []byte("baz")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetMulti(map[string][]byte{
+		"../escape/me": []byte("qux"),
+		"":             []byte("empty"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for k, exp := range map[string]string{
+		"foo":          "baz",
+		"../escape/me": "qux",
+		"":             "empty",
+	} {
+		act, err := c.Get(k)
+		if err != nil {
+			t.Errorf("Failed to get %v: %v", k, err)
+		} else if exp != string(act) {
+			t.Errorf("Wrong value for %v: %s != %v", k, act, exp)
+		}
+	}
+
+	if err := c.Delete("foo"); err != nil {
+		t.Error(err)
+	}
+	if err := c.Delete("foo"); err != nil {
+		t.Error(err)
+	}
+	if _, err := c.Get("foo"); err != types.ErrKeyNotFound {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyNotFound)
+	}
+
+	infos, err := ioutil.ReadDir(filepath.Join(dir, "nested"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 2, len(infos); exp != act {
+		t.Errorf("Wrong count of files left in directory: %v != %v", act, exp)
+	}
+}
+
+func TestFileCacheAdd(t *testing.T) {
+	c, dir := newTestFileCache(t, "")
+	defer os.RemoveAll(dir)
+
+	if err := c.Add("foo", []byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Add("foo", []byte("baz")); err != types.ErrKeyAlreadyExists {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyAlreadyExists)
+	}
+	if act, err := c.Get("foo"); err != nil {
+		t.Error(err)
+	} else if exp := "bar"; exp != string(act) {
+		t.Errorf("Wrong value: %s != %v", act, exp)
+	}
+}
+
+func TestFileCacheAddConcurrent(t *testing.T) {
+	c, dir := newTestFileCache(t, "")
+	defer os.RemoveAll(dir)
+
+	wg := sync.WaitGroup{}
+	startChan := make(chan struct{})
+	results := make(chan error, 20)
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-startChan
+			results <- c.Add("foo", []byte("bar"))
+		}()
+	}
+	close(startChan)
+	wg.Wait()
+	close(results)
+
+	var added int
+	for err := range results {
+		switch err {
+		case nil:
+			added++
+		case types.ErrKeyAlreadyExists:
+		default:
+			t.Error(err)
+		}
+	}
+	if exp, act := 1, added; exp != act {
+		t.Errorf("Wrong count of successful adds: %v != %v", act, exp)
+	}
+}
+
+func TestFileCacheTTL(t *testing.T) {
+	c, dir := newTestFileCache(t, "1h")
+	defer os.RemoveAll(dir)
+
+	if err := c.Set("foo", []byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	path := c.(*File).pathFor("foo")
+	past := time.Now().Add(-time.Hour * 2)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Get("foo"); err != types.ErrKeyNotFound {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyNotFound)
+	}
+	if err := c.Add("foo", []byte("baz")); err != nil {
+		t.Fatal(err)
+	}
+	if act, err := c.Get("foo"); err != nil {
+		t.Error(err)
+	} else if exp := "baz"; exp != string(act) {
+		t.Errorf("Wrong value: %s != %v", act, exp)
+	}
+
+	if err := c.Set("bar", []byte("expired")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(c.(*File).pathFor("bar"), past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	c.(*File).cleanup()
+
+	if _, err := os.Stat(c.(*File).pathFor("bar")); !os.IsNotExist(err) {
+		t.Errorf("Expected expired item to be removed: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected item to remain: %v", err)
+	}
+
+	c.(*File).CloseAsync()
+	if err := c.(*File).WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFileCacheBadConfig(t *testing.T) {
+	conf := NewConfig()
+	if _, err := NewFile(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing directory")
+	}
+
+	conf.File.Directory = os.TempDir()
+	conf.File.TTL = "nope"
+	if _, err := NewFile(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad ttl")
+	}
+}
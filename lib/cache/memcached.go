@@ -17,10 +17,14 @@
 // LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 // THE SOFTWARE.
-
 package cache
 
 import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"net"
+	"sort"
 	"strings"
 	"time"
 
@@ -38,12 +42,33 @@ func init() {
 		constructor: NewMemcached,
 		description: `
 Connects to a cluster of memcached services, a prefix can be specified to allow
-multiple cache types to share a memcached cluster under different namespaces.`,
+multiple cache types to share a memcached cluster under different namespaces.
+
+Keys are distributed across the addresses with consistent hashing, so adding or
+removing an address only remaps a proportional share of keys. Failures of
+individual nodes are counted with the metric
+` + "`cache.memcached.node.<index>.failed.error`" + `, where ` + "`<index>`" + `
+is the position of the node within the list of addresses.
+
+The ` + "`add`" + ` operation maps to the memcached ` + "`add`" + ` command,
+which only stores a value when the key does not already exist, making it safe to
+use for deduplication across multiple Benthos instances.
+
+Items where the combined size of the key and value exceed the memcached item
+limit of 1MB are rejected with an error without being sent.`,
 	}
 }
 
 //------------------------------------------------------------------------------
 
+// ErrMemcachedItemTooLarge is returned when attempting to store a value that
+// exceeds the maximum item size of memcached.
+var ErrMemcachedItemTooLarge = errors.New("item exceeds the memcached size limit of 1MB")
+
+const memcachedMaxItemSize = 1024 * 1024
+
+//------------------------------------------------------------------------------
+
 // MemcachedConfig is a config struct for a memcached connection.
 type MemcachedConfig struct {
 	Addresses     []string `json:"addresses" yaml:"addresses"`
@@ -66,6 +91,86 @@ func NewMemcachedConfig() MemcachedConfig {
 
 //------------------------------------------------------------------------------
 
+// memcachedRingReplicas is the number of points each node occupies on the hash
+// ring, which smooths the distribution of keys across nodes.
+const memcachedRingReplicas = 160
+
+type memcachedRingPoint struct {
+	hash uint32
+	node int
+}
+
+// memcachedRing is a memcache.ServerSelector that distributes keys across
+// servers with consistent hashing.
+type memcachedRing struct {
+	addrs  []net.Addr
+	points []memcachedRingPoint
+}
+
+func newMemcachedRing(servers []string) (*memcachedRing, error) {
+	var sl memcache.ServerList
+	if err := sl.SetServers(servers...); err != nil {
+		return nil, err
+	}
+
+	r := &memcachedRing{}
+	sl.Each(func(addr net.Addr) error {
+		r.addrs = append(r.addrs, addr)
+		return nil
+	})
+
+	// Points are derived from the configured server names rather than resolved
+	// addresses so that the distribution is stable across DNS changes.
+	for i, server := range servers {
+		for j := 0; j < memcachedRingReplicas; j++ {
+			r.points = append(r.points, memcachedRingPoint{
+				hash: crc32.ChecksumIEEE([]byte(fmt.Sprintf("%v-%v", server, j))),
+				node: i,
+			})
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool {
+		return r.points[i].hash < r.points[j].hash
+	})
+	return r, nil
+}
+
+// nodeFor returns the index of the server that owns a key.
+func (r *memcachedRing) nodeFor(key string) int {
+	if len(r.addrs) < 2 {
+		return 0
+	}
+	h := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].hash >= h
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.points[i].node
+}
+
+// PickServer returns the server address that a given item should be shared
+// onto.
+func (r *memcachedRing) PickServer(key string) (net.Addr, error) {
+	if len(r.addrs) == 0 {
+		return nil, memcache.ErrNoServers
+	}
+	return r.addrs[r.nodeFor(key)], nil
+}
+
+// Each iterates over each server calling the given function.
+func (r *memcachedRing) Each(f func(net.Addr) error) error {
+	for _, a := range r.addrs {
+		if err := f(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
 // Memcached is a cache that connects to memcached servers.
 type Memcached struct {
 	conf  Config
@@ -76,18 +181,20 @@ type Memcached struct {
 	mGetCount      metrics.StatCounter
 	mGetRetry      metrics.StatCounter
 	mGetFailed     metrics.StatCounter
+	mGetNotFound   metrics.StatCounter
 	mGetSuccess    metrics.StatCounter
 	mGetLatency    metrics.StatTimer
 	mSetCount      metrics.StatCounter
 	mSetRetry      metrics.StatCounter
 	mSetFailed     metrics.StatCounter
+	mSetTooLarge   metrics.StatCounter
 	mSetSuccess    metrics.StatCounter
 	mSetLatency    metrics.StatTimer
 	mAddCount      metrics.StatCounter
-	mAddDupe       metrics.StatCounter
 	mAddRetry      metrics.StatCounter
 	mAddFailedDupe metrics.StatCounter
 	mAddFailedErr  metrics.StatCounter
+	mAddTooLarge   metrics.StatCounter
 	mAddSuccess    metrics.StatCounter
 	mAddLatency    metrics.StatTimer
 	mDelCount      metrics.StatCounter
@@ -95,7 +202,9 @@ type Memcached struct {
 	mDelFailedErr  metrics.StatCounter
 	mDelSuccess    metrics.StatCounter
 	mDelLatency    metrics.StatTimer
+	mNodeFailed    []metrics.StatCounter
 
+	ring        *memcachedRing
 	mc          *memcache.Client
 	retryPeriod time.Duration
 }
@@ -112,6 +221,14 @@ func NewMemcached(
 			}
 		}
 	}
+	ring, err := newMemcachedRing(addresses)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve addresses: %v", err)
+	}
+	mNodeFailed := make([]metrics.StatCounter, len(addresses))
+	for i := range addresses {
+		mNodeFailed[i] = stats.GetCounter(fmt.Sprintf("cache.memcached.node.%v.failed.error", i))
+	}
 	return &Memcached{
 		conf:  conf,
 		log:   log.NewModule(".cache.memcached"),
@@ -121,18 +238,20 @@ func NewMemcached(
 		mGetCount:      stats.GetCounter("cache.memcached.get.count"),
 		mGetRetry:      stats.GetCounter("cache.memcached.get.retry"),
 		mGetFailed:     stats.GetCounter("cache.memcached.get.failed.error"),
+		mGetNotFound:   stats.GetCounter("cache.memcached.get.failed.not_found"),
 		mGetSuccess:    stats.GetCounter("cache.memcached.get.success"),
 		mGetLatency:    stats.GetTimer("cache.memcached.get.latency"),
 		mSetCount:      stats.GetCounter("cache.memcached.set.count"),
 		mSetRetry:      stats.GetCounter("cache.memcached.set.retry"),
 		mSetFailed:     stats.GetCounter("cache.memcached.set.failed.error"),
+		mSetTooLarge:   stats.GetCounter("cache.memcached.set.failed.too_large"),
 		mSetSuccess:    stats.GetCounter("cache.memcached.set.success"),
 		mSetLatency:    stats.GetTimer("cache.memcached.set.latency"),
 		mAddCount:      stats.GetCounter("cache.memcached.add.count"),
-		mAddDupe:       stats.GetCounter("cache.memcached.add.failed.duplicate"),
 		mAddRetry:      stats.GetCounter("cache.memcached.add.retry"),
 		mAddFailedDupe: stats.GetCounter("cache.memcached.add.failed.duplicate"),
 		mAddFailedErr:  stats.GetCounter("cache.memcached.add.failed.error"),
+		mAddTooLarge:   stats.GetCounter("cache.memcached.add.failed.too_large"),
 		mAddSuccess:    stats.GetCounter("cache.memcached.add.success"),
 		mAddLatency:    stats.GetTimer("cache.memcached.add.latency"),
 		mDelCount:      stats.GetCounter("cache.memcached.delete.count"),
@@ -140,9 +259,11 @@ func NewMemcached(
 		mDelFailedErr:  stats.GetCounter("cache.memcached.delete.failed.error"),
 		mDelSuccess:    stats.GetCounter("cache.memcached.delete.success"),
 		mDelLatency:    stats.GetTimer("cache.memcached.del.latency"),
+		mNodeFailed:    mNodeFailed,
 
 		retryPeriod: time.Duration(conf.Memcached.RetryPeriodMS) * time.Millisecond,
-		mc:          memcache.New(addresses...),
+		ring:        ring,
+		mc:          memcache.NewFromSelector(ring),
 	}, nil
 }
 
@@ -157,6 +278,18 @@ func (m *Memcached) getItemFor(key string, value []byte) *memcache.Item {
 	}
 }
 
+// tooLarge returns true if an item exceeds the memcached item size limit.
+func (m *Memcached) tooLarge(key string, value []byte) bool {
+	return len(m.conf.Memcached.Prefix)+len(key)+len(value) > memcachedMaxItemSize
+}
+
+// nodeFailed records a failed command against the node that owns a key.
+func (m *Memcached) nodeFailed(key string) {
+	if len(m.mNodeFailed) > 0 {
+		m.mNodeFailed[m.ring.nodeFor(m.conf.Memcached.Prefix+key)].Incr(1)
+	}
+}
+
 // Get attempts to locate and return a cached value by its key, returns an error
 // if the key does not exist or if the operation failed.
 func (m *Memcached) Get(key string) ([]byte, error) {
@@ -164,7 +297,7 @@ func (m *Memcached) Get(key string) ([]byte, error) {
 	tStarted := time.Now()
 
 	item, err := m.mc.Get(m.conf.Memcached.Prefix + key)
-	for i := 0; i < m.conf.Memcached.Retries && err != nil; i++ {
+	for i := 0; i < m.conf.Memcached.Retries && err != nil && err != memcache.ErrCacheMiss; i++ {
 		m.log.Errorf("Get command failed: %v\n", err)
 		<-time.After(m.retryPeriod)
 		m.mGetRetry.Incr(1)
@@ -175,8 +308,13 @@ func (m *Memcached) Get(key string) ([]byte, error) {
 	m.mGetLatency.Timing(latency)
 	m.mLatency.Timing(latency)
 
+	if err == memcache.ErrCacheMiss {
+		m.mGetNotFound.Incr(1)
+		return nil, types.ErrKeyNotFound
+	}
 	if err != nil {
 		m.mGetFailed.Incr(1)
+		m.nodeFailed(key)
 		return nil, err
 	}
 
@@ -187,6 +325,10 @@ func (m *Memcached) Get(key string) ([]byte, error) {
 // Set attempts to set the value of a key.
 func (m *Memcached) Set(key string, value []byte) error {
 	m.mSetCount.Incr(1)
+	if m.tooLarge(key, value) {
+		m.mSetTooLarge.Incr(1)
+		return ErrMemcachedItemTooLarge
+	}
 	tStarted := time.Now()
 
 	err := m.mc.Set(m.getItemFor(key, value))
@@ -198,6 +340,7 @@ func (m *Memcached) Set(key string, value []byte) error {
 	}
 	if err != nil {
 		m.mSetFailed.Incr(1)
+		m.nodeFailed(key)
 	} else {
 		m.mSetSuccess.Incr(1)
 	}
@@ -225,36 +368,29 @@ func (m *Memcached) SetMulti(items map[string][]byte) error {
 // and returns an error if the key already exists or if the operation fails.
 func (m *Memcached) Add(key string, value []byte) error {
 	m.mAddCount.Incr(1)
+	if m.tooLarge(key, value) {
+		m.mAddTooLarge.Incr(1)
+		return ErrMemcachedItemTooLarge
+	}
 	tStarted := time.Now()
 
 	err := m.mc.Add(m.getItemFor(key, value))
-	if memcache.ErrNotStored == err {
-		m.mAddFailedDupe.Incr(1)
-
-		latency := int64(time.Since(tStarted))
-		m.mAddLatency.Timing(latency)
-		m.mLatency.Timing(latency)
-
-		return types.ErrKeyAlreadyExists
-	}
-	for i := 0; i < m.conf.Memcached.Retries && err != nil; i++ {
+	for i := 0; i < m.conf.Memcached.Retries && err != nil && err != memcache.ErrNotStored; i++ {
 		m.log.Errorf("Add command failed: %v\n", err)
 		<-time.After(m.retryPeriod)
 		m.mAddRetry.Incr(1)
-		if err := m.mc.Add(m.getItemFor(key, value)); memcache.ErrNotStored == err {
-			m.mAddFailedDupe.Incr(1)
-
-			latency := int64(time.Since(tStarted))
-			m.mAddLatency.Timing(latency)
-			m.mLatency.Timing(latency)
-
-			return types.ErrKeyAlreadyExists
-		}
+		err = m.mc.Add(m.getItemFor(key, value))
 	}
-	if err != nil {
-		m.mAddFailedErr.Incr(1)
-	} else {
+
+	switch err {
+	case nil:
 		m.mAddSuccess.Incr(1)
+	case memcache.ErrNotStored:
+		m.mAddFailedDupe.Incr(1)
+		err = types.ErrKeyAlreadyExists
+	default:
+		m.mAddFailedErr.Incr(1)
+		m.nodeFailed(key)
 	}
 
 	latency := int64(time.Since(tStarted))
@@ -283,6 +419,7 @@ func (m *Memcached) Delete(key string) error {
 	}
 	if err != nil {
 		m.mDelFailedErr.Incr(1)
+		m.nodeFailed(key)
 	} else {
 		m.mDelSuccess.Incr(1)
 	}
@@ -71,6 +71,93 @@ func TestMemcachedIntegration(t *testing.T) {
 	t.Run("TestMemcachedGetAndSet", func(te *testing.T) {
 		testMemcachedGetAndSet(addrs, te)
 	})
+	t.Run("TestMemcachedGetNotFound", func(te *testing.T) {
+		testMemcachedGetNotFound(addrs, te)
+	})
+}
+
+func TestMemcachedRing(t *testing.T) {
+	servers := []string{"localhost:11211", "localhost:11212", "localhost:11213", "localhost:11214"}
+
+	ring, err := newMemcachedRing(servers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	smallRing, err := newMemcachedRing(servers[:3])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make([]int, len(servers))
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("key-%v", i)
+
+		node := ring.nodeFor(key)
+		counts[node]++
+
+		addr, err := ring.PickServer(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp, act := ring.addrs[node].String(), addr.String(); exp != act {
+			t.Errorf("Wrong address picked: %v != %v", act, exp)
+		}
+
+		// Removing a node should only remap the keys that it owned.
+		if node != 3 {
+			if smallNode := smallRing.nodeFor(key); smallNode != node {
+				t.Errorf("Key %v remapped from node %v to %v", key, node, smallNode)
+			}
+		}
+	}
+
+	for i, c := range counts {
+		if c < 1500 || c > 3500 {
+			t.Errorf("Uneven distribution for node %v: %v", i, counts)
+		}
+	}
+}
+
+func TestMemcachedTooLarge(t *testing.T) {
+	conf := NewConfig()
+	conf.Memcached.Addresses = []string{"localhost:1"}
+	conf.Memcached.Retries = 0
+
+	c, err := NewMemcached(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := make([]byte, memcachedMaxItemSize)
+	if err = c.Set("foo", value); err != ErrMemcachedItemTooLarge {
+		t.Errorf("Wrong error returned: %v != %v", err, ErrMemcachedItemTooLarge)
+	}
+	if err = c.Add("foo", value); err != ErrMemcachedItemTooLarge {
+		t.Errorf("Wrong error returned: %v != %v", err, ErrMemcachedItemTooLarge)
+	}
+}
+
+func TestMemcachedNodeFailures(t *testing.T) {
+	conf := NewConfig()
+	conf.Memcached.Addresses = []string{"localhost:1"}
+	conf.Memcached.Retries = 0
+
+	stats := metrics.NewLocal()
+	c, err := NewMemcached(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = c.Get("foo"); err == nil || err == types.ErrKeyNotFound {
+		t.Errorf("Expected connection error from get: %v", err)
+	}
+	if err = c.Add("foo", []byte("bar")); err == nil || err == types.ErrKeyAlreadyExists {
+		t.Errorf("Expected connection error from add: %v", err)
+	}
+
+	if exp, act := int64(2), stats.GetCounters()["cache.memcached.node.0.failed.error"]; exp != act {
+		t.Errorf("Wrong count of node failures: %v != %v", act, exp)
+	}
 }
 
 func testMemcachedAddDuplicate(addrs []string, t *testing.T) {
@@ -112,6 +199,20 @@ func testMemcachedAddDuplicate(addrs []string, t *testing.T) {
 	}
 }
 
+func testMemcachedGetNotFound(addrs []string, t *testing.T) {
+	conf := NewConfig()
+	conf.Memcached.Addresses = addrs
+
+	c, err := NewMemcached(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = c.Get("benthos_test_not_exist"); err != types.ErrKeyNotFound {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyNotFound)
+	}
+}
+
 func testMemcachedGetAndSet(addrs []string, t *testing.T) {
 	conf := NewConfig()
 	conf.Memcached.Addresses = addrs
@@ -17,11 +17,12 @@
 // LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 // THE SOFTWARE.
-
 package cache
 
 import (
+	"container/list"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -37,12 +38,25 @@ func init() {
 		description: `
 The memory cache simply stores key/value pairs in a map held in memory. This
 cache is therefore reset every time the service restarts. Each item in the cache
-has a TTL set from the moment it was last edited, after which it will be removed
-during the next compaction.
+has a TTL set from the moment it was last edited, after which it is no longer
+returned and will be removed during the next compaction.
+
+A compaction occurs in the background every compaction interval, and also
+during a write where the time since the last compaction is above the compaction
+interval.
+
+The number of items and their total size (keys plus values) can be capped with
+` + "`max_items`" + ` and ` + "`max_bytes`" + `, where zero means unlimited.
+When a write exceeds a cap the least recently used items are evicted.
 
-A compaction only occurs during a write where the time since the last compaction
-is above the compaction interval. It is therefore possible to obtain values of
-keys that have expired between compactions.`,
+Items are distributed across a number of ` + "`shards`" + `, each with its own
+lock, which reduces contention when the cache is used by many processing
+threads. Caps are divided evenly between shards and least recently used
+eviction is performed within a shard, and therefore eviction is approximate.
+
+The current number of items and the approximate size of the cache in bytes are
+exposed as the gauges ` + "`cache.memory.items`" + ` and
+` + "`cache.memory.bytes`" + `.`,
 	}
 }
 
@@ -50,8 +64,11 @@ keys that have expired between compactions.`,
 
 // MemoryConfig contains config fields for the Memory cache type.
 type MemoryConfig struct {
-	TTL                 int `json:"ttl" yaml:"ttl"`
-	CompactionIntervalS int `json:"compaction_interval_s" yaml:"compaction_interval_s"`
+	TTL                 int   `json:"ttl" yaml:"ttl"`
+	CompactionIntervalS int   `json:"compaction_interval_s" yaml:"compaction_interval_s"`
+	MaxItems            int   `json:"max_items" yaml:"max_items"`
+	MaxBytes            int64 `json:"max_bytes" yaml:"max_bytes"`
+	Shards              int   `json:"shards" yaml:"shards"`
 }
 
 // NewMemoryConfig creates a MemoryConfig populated with default values.
@@ -59,101 +76,307 @@ func NewMemoryConfig() MemoryConfig {
 	return MemoryConfig{
 		TTL:                 300, // 5 Mins
 		CompactionIntervalS: 60,
+		MaxItems:            0,
+		MaxBytes:            0,
+		Shards:              16,
 	}
 }
 
 //------------------------------------------------------------------------------
 
 type item struct {
+	key   string
 	value []byte
 	ts    time.Time
 }
 
+func (i *item) size() int64 {
+	return int64(len(i.key) + len(i.value))
+}
+
+// memoryShard is a portion of the cache with its own lock, where elements of
+// the list are ordered from most to least recently used.
+type memoryShard struct {
+	items    map[string]*list.Element
+	lru      *list.List
+	bytes    int64
+	maxItems int
+	maxBytes int64
+	sync.Mutex
+}
+
 // Memory is a memory based cache implementation.
 type Memory struct {
-	items          map[string]item
-	ttl            time.Duration
-	compInterval   time.Duration
-	lastCompaction time.Time
-	sync.RWMutex
+	shards       []*memoryShard
+	ttl          time.Duration
+	compInterval time.Duration
+
+	// Unix nano timestamp of the last compaction.
+	lastCompaction int64
+
+	items int64
+	bytes int64
+
+	mItems metrics.StatGauge
+	mBytes metrics.StatGauge
+
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
 }
 
 // NewMemory creates a new Memory cache type.
 func NewMemory(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (types.Cache, error) {
-	return &Memory{
-		items:          map[string]item{},
+	nShards := conf.Memory.Shards
+	if nShards < 1 {
+		nShards = 1
+	}
+
+	// Caps are rounded up so that the sum of shard caps is never lower than the
+	// configured cap.
+	maxItems := conf.Memory.MaxItems
+	if maxItems > 0 {
+		maxItems = (maxItems + nShards - 1) / nShards
+	}
+	maxBytes := conf.Memory.MaxBytes
+	if maxBytes > 0 {
+		maxBytes = (maxBytes + int64(nShards) - 1) / int64(nShards)
+	}
+
+	m := &Memory{
 		ttl:            time.Second * time.Duration(conf.Memory.TTL),
 		compInterval:   time.Second * time.Duration(conf.Memory.CompactionIntervalS),
-		lastCompaction: time.Now(),
-	}, nil
+		lastCompaction: time.Now().UnixNano(),
+		mItems:         stats.GetGauge("cache.memory.items"),
+		mBytes:         stats.GetGauge("cache.memory.bytes"),
+		closeChan:      make(chan struct{}),
+		closedChan:     make(chan struct{}),
+	}
+	for i := 0; i < nShards; i++ {
+		m.shards = append(m.shards, &memoryShard{
+			items:    map[string]*list.Element{},
+			lru:      list.New(),
+			maxItems: maxItems,
+			maxBytes: maxBytes,
+		})
+	}
+
+	if m.compInterval > 0 {
+		go m.compactionLoop()
+	} else {
+		close(m.closedChan)
+	}
+	return m, nil
 }
 
 //------------------------------------------------------------------------------
 
+func (m *Memory) compactionLoop() {
+	defer close(m.closedChan)
+
+	ticker := time.NewTicker(m.compInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			atomic.StoreInt64(&m.lastCompaction, time.Now().UnixNano())
+			m.compactAll()
+		case <-m.closeChan:
+			return
+		}
+	}
+}
+
+// compaction removes expired items from all shards if the time since the last
+// compaction is above the compaction interval.
 func (m *Memory) compaction() {
-	if time.Since(m.lastCompaction) < m.compInterval {
+	last := atomic.LoadInt64(&m.lastCompaction)
+	now := time.Now().UnixNano()
+	if time.Duration(now-last) < m.compInterval {
 		return
 	}
-	for k, v := range m.items {
-		if time.Since(v.ts) >= m.ttl {
-			delete(m.items, k)
+	if !atomic.CompareAndSwapInt64(&m.lastCompaction, last, now) {
+		// Another write is already performing the compaction.
+		return
+	}
+	m.compactAll()
+}
+
+func (m *Memory) compactAll() {
+	for _, s := range m.shards {
+		s.Lock()
+		for k, e := range s.items {
+			if time.Since(e.Value.(*item).ts) >= m.ttl {
+				m.remove(s, k, e)
+			}
 		}
+		s.Unlock()
 	}
+	m.updateGauges()
 }
 
+func (m *Memory) updateGauges() {
+	m.mItems.Set(atomic.LoadInt64(&m.items))
+	m.mBytes.Set(atomic.LoadInt64(&m.bytes))
+}
+
+func (m *Memory) shardFor(key string) *memoryShard {
+	if len(m.shards) == 1 {
+		return m.shards[0]
+	}
+	// FNV-1a, calculated inline in order to avoid allocations.
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return m.shards[h%uint32(len(m.shards))]
+}
+
+// remove deletes an element from a shard, the shard must be locked.
+func (m *Memory) remove(s *memoryShard, key string, e *list.Element) {
+	size := e.Value.(*item).size()
+	s.lru.Remove(e)
+	delete(s.items, key)
+	s.bytes -= size
+	atomic.AddInt64(&m.items, -1)
+	atomic.AddInt64(&m.bytes, -size)
+}
+
+// set writes an item to a shard and evicts the least recently used items until
+// the shard is within its caps, the shard must be locked.
+func (m *Memory) set(s *memoryShard, key string, value []byte) {
+	newItem := &item{key: key, value: value, ts: time.Now()}
+	if e, exists := s.items[key]; exists {
+		diff := newItem.size() - e.Value.(*item).size()
+		e.Value = newItem
+		s.lru.MoveToFront(e)
+		s.bytes += diff
+		atomic.AddInt64(&m.bytes, diff)
+	} else {
+		s.items[key] = s.lru.PushFront(newItem)
+		s.bytes += newItem.size()
+		atomic.AddInt64(&m.items, 1)
+		atomic.AddInt64(&m.bytes, newItem.size())
+	}
+
+	for s.lru.Len() > 1 &&
+		((s.maxItems > 0 && s.lru.Len() > s.maxItems) ||
+			(s.maxBytes > 0 && s.bytes > s.maxBytes)) {
+		e := s.lru.Back()
+		m.remove(s, e.Value.(*item).key, e)
+	}
+}
+
+// expired returns true if an item has outlived the TTL of the cache. A TTL of
+// zero only removes items during compaction.
+func (m *Memory) expired(i *item) bool {
+	return m.ttl > 0 && time.Since(i.ts) >= m.ttl
+}
+
+//------------------------------------------------------------------------------
+
 // Get attempts to locate and return a cached value by its key, returns an error
 // if the key does not exist.
 func (m *Memory) Get(key string) ([]byte, error) {
-	m.RLock()
-	k, exists := m.items[key]
-	m.RUnlock()
-	if !exists {
+	s := m.shardFor(key)
+	s.Lock()
+	defer s.Unlock()
+
+	e, exists := s.items[key]
+	if !exists || m.expired(e.Value.(*item)) {
 		return nil, types.ErrKeyNotFound
 	}
-	return k.value, nil
+	s.lru.MoveToFront(e)
+	return e.Value.(*item).value, nil
 }
 
 // Set attempts to set the value of a key.
 func (m *Memory) Set(key string, value []byte) error {
-	m.Lock()
 	m.compaction()
-	m.items[key] = item{value: value, ts: time.Now()}
-	m.Unlock()
+
+	s := m.shardFor(key)
+	s.Lock()
+	m.set(s, key, value)
+	s.Unlock()
+
+	m.updateGauges()
 	return nil
 }
 
 // SetMulti attempts to set the value of multiple keys, returns an error if any
 // keys fail.
 func (m *Memory) SetMulti(items map[string][]byte) error {
-	m.Lock()
 	m.compaction()
+
 	for k, v := range items {
-		m.items[k] = item{value: v, ts: time.Now()}
+		s := m.shardFor(k)
+		s.Lock()
+		m.set(s, k, v)
+		s.Unlock()
 	}
-	m.Unlock()
+
+	m.updateGauges()
 	return nil
 }
 
 // Add attempts to set the value of a key only if the key does not already exist
 // and returns an error if the key already exists.
 func (m *Memory) Add(key string, value []byte) error {
-	m.Lock()
-	if _, exists := m.items[key]; exists {
-		m.Unlock()
+	s := m.shardFor(key)
+	s.Lock()
+	if e, exists := s.items[key]; exists && !m.expired(e.Value.(*item)) {
+		s.Unlock()
 		return types.ErrKeyAlreadyExists
 	}
+	s.Unlock()
+
 	m.compaction()
-	m.items[key] = item{value: value, ts: time.Now()}
-	m.Unlock()
+
+	s.Lock()
+	if e, exists := s.items[key]; exists && !m.expired(e.Value.(*item)) {
+		s.Unlock()
+		return types.ErrKeyAlreadyExists
+	}
+	m.set(s, key, value)
+	s.Unlock()
+
+	m.updateGauges()
 	return nil
 }
 
 // Delete attempts to remove a key.
 func (m *Memory) Delete(key string) error {
-	m.Lock()
 	m.compaction()
-	delete(m.items, key)
-	m.Unlock()
+
+	s := m.shardFor(key)
+	s.Lock()
+	if e, exists := s.items[key]; exists {
+		m.remove(s, key, e)
+	}
+	s.Unlock()
+
+	m.updateGauges()
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// CloseAsync shuts down the background compaction of the cache.
+func (m *Memory) CloseAsync() {
+	m.closeOnce.Do(func() {
+		close(m.closeChan)
+	})
+}
+
+// WaitForClose blocks until the cache has closed down.
+func (m *Memory) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-m.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
 	return nil
 }
 
@@ -21,8 +21,11 @@
 package cache
 
 import (
+	"fmt"
 	"os"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
@@ -141,4 +144,184 @@ func TestMemoryCacheCompaction(t *testing.T) {
 	}
 }
 
+func TestMemoryCacheMaxItems(t *testing.T) {
+	conf := NewConfig()
+	conf.Memory.MaxItems = 3
+	conf.Memory.Shards = 1
+
+	stats := metrics.NewLocal()
+	c, err := NewMemory(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, k := range []string{"a", "b", "c"} {
+		if err = c.Set(k, []byte(k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Reading a makes b the least recently used item.
+	if _, err = c.Get("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Set("d", []byte("d")); err != nil {
+		t.Fatal(err)
+	}
+
+	for k, exists := range map[string]bool{"a": true, "b": false, "c": true, "d": true} {
+		if _, err = c.Get(k); exists && err != nil {
+			t.Errorf("Expected key %v to exist: %v", k, err)
+		} else if !exists && err != types.ErrKeyNotFound {
+			t.Errorf("Expected key %v to be evicted: %v", k, err)
+		}
+	}
+
+	if exp, act := int64(3), stats.GetCounters()["cache.memory.items"]; exp != act {
+		t.Errorf("Wrong items gauge: %v != %v", act, exp)
+	}
+	if exp, act := int64(6), stats.GetCounters()["cache.memory.bytes"]; exp != act {
+		t.Errorf("Wrong bytes gauge: %v != %v", act, exp)
+	}
+}
+
+func TestMemoryCacheMaxBytes(t *testing.T) {
+	conf := NewConfig()
+	conf.Memory.MaxBytes = 10
+	conf.Memory.Shards = 1
+
+	stats := metrics.NewLocal()
+	c, err := NewMemory(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Set("a", []byte("1234")); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Set("b", []byte("1234")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.Get("a"); err != nil {
+		t.Errorf("Expected key a to exist: %v", err)
+	}
+
+	// Growing b beyond the cap evicts a, but b itself is always kept.
+	if err = c.Set("b", []byte("1234567890")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.Get("a"); err != types.ErrKeyNotFound {
+		t.Errorf("Expected key a to be evicted: %v", err)
+	}
+	if act, err := c.Get("b"); err != nil {
+		t.Error(err)
+	} else if exp := "1234567890"; exp != string(act) {
+		t.Errorf("Wrong result: %s != %v", act, exp)
+	}
+
+	if err = c.Delete("b"); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := int64(0), stats.GetCounters()["cache.memory.items"]; exp != act {
+		t.Errorf("Wrong items gauge: %v != %v", act, exp)
+	}
+	if exp, act := int64(0), stats.GetCounters()["cache.memory.bytes"]; exp != act {
+		t.Errorf("Wrong bytes gauge: %v != %v", act, exp)
+	}
+}
+
+func TestMemoryCacheShardedCaps(t *testing.T) {
+	conf := NewConfig()
+	conf.Memory.MaxItems = 100
+	conf.Memory.Shards = 4
+
+	stats := metrics.NewLocal()
+	c, err := NewMemory(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		if err = c.Set(strconv.Itoa(i), []byte("foo")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if act := stats.GetCounters()["cache.memory.items"]; act > 100 || act < 90 {
+		t.Errorf("Wrong count of items after eviction: %v", act)
+	}
+}
+
+func TestMemoryCacheExpiry(t *testing.T) {
+	conf := NewConfig()
+	conf.Memory.TTL = 1
+	conf.Memory.CompactionIntervalS = 1
+
+	stats := metrics.NewLocal()
+	c, err := NewMemory(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		c.(*Memory).CloseAsync()
+		if err := c.(*Memory).WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if err = c.Set("foo", []byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Add("foo", []byte("baz")); err != types.ErrKeyAlreadyExists {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyAlreadyExists)
+	}
+
+	<-time.After(time.Millisecond * 2500)
+
+	if _, err = c.Get("foo"); err != types.ErrKeyNotFound {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyNotFound)
+	}
+	if exp, act := int64(0), stats.GetCounters()["cache.memory.items"]; exp != act {
+		t.Errorf("Expected background compaction to remove item: %v != %v", act, exp)
+	}
+	if err = c.Add("foo", []byte("baz")); err != nil {
+		t.Error(err)
+	}
+}
+
+func BenchmarkMemoryCacheParallel(b *testing.B) {
+	for _, shards := range []int{1, 16} {
+		b.Run(fmt.Sprintf("shards_%v", shards), func(b *testing.B) {
+			conf := NewConfig()
+			conf.Memory.Shards = shards
+
+			c, err := NewMemory(conf, nil, log.Noop(), metrics.Noop())
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			keys := make([]string, 1024)
+			for i := range keys {
+				keys[i] = strconv.Itoa(i)
+			}
+			value := []byte("hello world")
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					key := keys[i%len(keys)]
+					if i%4 == 0 {
+						c.Set(key, value)
+					} else {
+						c.Get(key)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
 //------------------------------------------------------------------------------
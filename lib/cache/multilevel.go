@@ -0,0 +1,245 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cache
+
+import (
+	"fmt"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeMultilevel] = TypeSpec{
+		constructor: NewMultilevel,
+		description: `
+The multilevel cache combines an ordered list of other cache resources into a
+single tiered cache, where earlier levels are expected to be faster and the last
+level is considered the authority. For example, a dedupe processor could check
+an in-memory cache first and fall back to Redis:
+
+` + "``` yaml" + `
+resources:
+  caches:
+    dedupe_cache:
+      type: multilevel
+      multilevel:
+        levels: [ local, shared ]
+    local:
+      type: memory
+      memory:
+        ttl: 60
+    shared:
+      type: redis
+      redis:
+        url: tcp://localhost:6379
+        expiration: 1h
+` + "```" + `
+
+A get attempts each level in order, and when an item is found at a level it is
+also written to each of the levels before it. Sets and deletes are written
+through to all levels, starting with the last level. An add succeeds only if
+the add succeeds at the last level, after which the item is written to all
+other levels.
+
+The hits, misses and errors of each level are counted with the metrics
+` + "`cache.multilevel.level.<index>.hit`" + `,
+` + "`cache.multilevel.level.<index>.miss`" + ` and
+` + "`cache.multilevel.level.<index>.error`" + `, where the first level has
+the index zero.
+
+A level must not be a multilevel cache that refers back to this cache.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// MultilevelConfig contains config fields for the Multilevel cache type.
+type MultilevelConfig struct {
+	Levels []string `json:"levels" yaml:"levels"`
+}
+
+// NewMultilevelConfig creates a MultilevelConfig populated with default values.
+func NewMultilevelConfig() MultilevelConfig {
+	return MultilevelConfig{
+		Levels: []string{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Multilevel is a cache that combines a list of cache resources into tiers.
+type Multilevel struct {
+	mgr    types.Manager
+	levels []string
+
+	log log.Modular
+
+	mHit  []metrics.StatCounter
+	mMiss []metrics.StatCounter
+	mErr  []metrics.StatCounter
+}
+
+// NewMultilevel creates a new Multilevel cache type.
+func NewMultilevel(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (types.Cache, error) {
+	if len(conf.Multilevel.Levels) < 2 {
+		return nil, fmt.Errorf("expected at least two cache levels, found %v", len(conf.Multilevel.Levels))
+	}
+
+	m := &Multilevel{
+		mgr:    mgr,
+		levels: conf.Multilevel.Levels,
+		log:    log.NewModule(".cache.multilevel"),
+	}
+	for i, name := range m.levels {
+		if _, err := mgr.GetCache(name); err != nil {
+			return nil, fmt.Errorf("failed to obtain cache resource '%v': %v", name, err)
+		}
+		m.mHit = append(m.mHit, stats.GetCounter(fmt.Sprintf("cache.multilevel.level.%v.hit", i)))
+		m.mMiss = append(m.mMiss, stats.GetCounter(fmt.Sprintf("cache.multilevel.level.%v.miss", i)))
+		m.mErr = append(m.mErr, stats.GetCounter(fmt.Sprintf("cache.multilevel.level.%v.error", i)))
+	}
+	return m, nil
+}
+
+//------------------------------------------------------------------------------
+
+// getLevel obtains the cache resource of a level. Resources are obtained at
+// call time as they might not yet exist when this cache is constructed.
+func (m *Multilevel) getLevel(i int) (types.Cache, error) {
+	c, err := m.mgr.GetCache(m.levels[i])
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain cache resource '%v': %v", m.levels[i], err)
+	}
+	if c == nil {
+		return nil, fmt.Errorf("cache resource '%v' is not yet initialised", m.levels[i])
+	}
+	return c, nil
+}
+
+// Get attempts to locate and return a cached value by its key, returns an
+// error if the key does not exist or if the operation failed at the last
+// level.
+func (m *Multilevel) Get(key string) ([]byte, error) {
+	var lastErr error
+	for i := range m.levels {
+		c, err := m.getLevel(i)
+		if err == nil {
+			var value []byte
+			if value, err = c.Get(key); err == nil {
+				m.mHit[i].Incr(1)
+				m.populate(i, key, value)
+				return value, nil
+			}
+		}
+		if err == types.ErrKeyNotFound {
+			m.mMiss[i].Incr(1)
+		} else {
+			m.mErr[i].Incr(1)
+			m.log.Debugf("Failed to get key '%v' from level %v: %v\n", key, i, err)
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// populate writes a value found at a level to all levels before it.
+func (m *Multilevel) populate(found int, key string, value []byte) {
+	for i := found - 1; i >= 0; i-- {
+		c, err := m.getLevel(i)
+		if err == nil {
+			err = c.Set(key, value)
+		}
+		if err != nil {
+			m.mErr[i].Incr(1)
+			m.log.Debugf("Failed to populate key '%v' at level %v: %v\n", key, i, err)
+		}
+	}
+}
+
+// writeThrough applies a function to each level, starting with the last, and
+// returns the first error encountered after attempting all levels.
+func (m *Multilevel) writeThrough(levels int, fn func(c types.Cache) error) error {
+	var firstErr error
+	for i := levels - 1; i >= 0; i-- {
+		c, err := m.getLevel(i)
+		if err == nil {
+			err = fn(c)
+		}
+		if err != nil {
+			m.mErr[i].Incr(1)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// Set attempts to set the value of a key at all levels.
+func (m *Multilevel) Set(key string, value []byte) error {
+	return m.writeThrough(len(m.levels), func(c types.Cache) error {
+		return c.Set(key, value)
+	})
+}
+
+// SetMulti attempts to set the value of multiple keys at all levels.
+func (m *Multilevel) SetMulti(items map[string][]byte) error {
+	return m.writeThrough(len(m.levels), func(c types.Cache) error {
+		return c.SetMulti(items)
+	})
+}
+
+// Add attempts to set the value of a key only if the key does not already
+// exist at the last level, and writes the value to all other levels when
+// successful.
+func (m *Multilevel) Add(key string, value []byte) error {
+	last := len(m.levels) - 1
+	c, err := m.getLevel(last)
+	if err == nil {
+		err = c.Add(key, value)
+	}
+	if err != nil {
+		if err != types.ErrKeyAlreadyExists {
+			m.mErr[last].Incr(1)
+		}
+		return err
+	}
+
+	if err = m.writeThrough(last, func(c types.Cache) error {
+		return c.Set(key, value)
+	}); err != nil {
+		m.log.Debugf("Failed to write added key '%v' to all levels: %v\n", key, err)
+	}
+	return nil
+}
+
+// Delete attempts to remove a key from all levels.
+func (m *Multilevel) Delete(key string) error {
+	return m.writeThrough(len(m.levels), func(c types.Cache) error {
+		return c.Delete(key)
+	})
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,269 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cache
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+type fakeMgr struct {
+	caches map[string]types.Cache
+}
+
+func (f *fakeMgr) RegisterEndpoint(path, desc string, h http.HandlerFunc) {
+}
+func (f *fakeMgr) GetCache(name string) (types.Cache, error) {
+	if c, exists := f.caches[name]; exists {
+		return c, nil
+	}
+	return nil, types.ErrCacheNotFound
+}
+func (f *fakeMgr) GetCondition(name string) (types.Condition, error) {
+	return nil, types.ErrConditionNotFound
+}
+func (f *fakeMgr) GetRateLimit(name string) (types.RateLimit, error) {
+	return nil, types.ErrRateLimitNotFound
+}
+func (f *fakeMgr) GetPipe(name string) (<-chan types.Transaction, error) {
+	return nil, types.ErrPipeNotFound
+}
+func (f *fakeMgr) SetPipe(name string, prod <-chan types.Transaction)   {}
+func (f *fakeMgr) UnsetPipe(name string, prod <-chan types.Transaction) {}
+
+type erroringCache struct {
+	err error
+}
+
+func (e erroringCache) Get(key string) ([]byte, error)         { return nil, e.err }
+func (e erroringCache) Set(key string, value []byte) error     { return e.err }
+func (e erroringCache) SetMulti(items map[string][]byte) error { return e.err }
+func (e erroringCache) Add(key string, value []byte) error     { return e.err }
+func (e erroringCache) Delete(key string) error                { return e.err }
+
+func newTestMultilevel(t *testing.T, levels map[string]types.Cache, names ...string) (types.Cache, *metrics.Local) {
+	t.Helper()
+
+	conf := NewConfig()
+	conf.Multilevel.Levels = names
+
+	stats := metrics.NewLocal()
+	c, err := NewMultilevel(conf, &fakeMgr{caches: levels}, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, stats
+}
+
+func newTestMemoryLevel(t *testing.T) types.Cache {
+	t.Helper()
+
+	c, err := NewMemory(NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+//------------------------------------------------------------------------------
+
+func TestMultilevelCacheBadConfig(t *testing.T) {
+	mgr := &fakeMgr{caches: map[string]types.Cache{
+		"foo": newTestMemoryLevel(t),
+	}}
+
+	conf := NewConfig()
+	conf.Multilevel.Levels = []string{"foo"}
+	if _, err := NewMultilevel(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from single level")
+	}
+
+	conf.Multilevel.Levels = []string{"foo", "bar"}
+	if _, err := NewMultilevel(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing level")
+	}
+}
+
+func TestMultilevelCacheGet(t *testing.T) {
+	l1, l2 := newTestMemoryLevel(t), newTestMemoryLevel(t)
+	c, stats := newTestMultilevel(t, map[string]types.Cache{
+		"l1": l1, "l2": l2,
+	}, "l1", "l2")
+
+	if _, err := c.Get("foo"); err != types.ErrKeyNotFound {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyNotFound)
+	}
+
+	if err := l2.Set("foo", []byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+	if act, err := c.Get("foo"); err != nil {
+		t.Fatal(err)
+	} else if exp := "bar"; string(act) != exp {
+		t.Errorf("Wrong result: %s != %v", act, exp)
+	}
+
+	// The hit at the second level should have populated the first.
+	if act, err := l1.Get("foo"); err != nil {
+		t.Fatal(err)
+	} else if exp := "bar"; string(act) != exp {
+		t.Errorf("Wrong result: %s != %v", act, exp)
+	}
+	if act, err := c.Get("foo"); err != nil {
+		t.Fatal(err)
+	} else if exp := "bar"; string(act) != exp {
+		t.Errorf("Wrong result: %s != %v", act, exp)
+	}
+
+	counters := stats.GetCounters()
+	for k, exp := range map[string]int64{
+		"cache.multilevel.level.0.hit":   1,
+		"cache.multilevel.level.0.miss":  2,
+		"cache.multilevel.level.1.hit":   1,
+		"cache.multilevel.level.1.miss":  1,
+		"cache.multilevel.level.0.error": 0,
+		"cache.multilevel.level.1.error": 0,
+	} {
+		if act := counters[k]; act != exp {
+			t.Errorf("Wrong count for %v: %v != %v", k, act, exp)
+		}
+	}
+}
+
+func TestMultilevelCacheGetErrors(t *testing.T) {
+	errTest := errors.New("test err")
+	l2 := newTestMemoryLevel(t)
+	c, stats := newTestMultilevel(t, map[string]types.Cache{
+		"l1": erroringCache{err: errTest}, "l2": l2,
+	}, "l1", "l2")
+
+	if err := l2.Set("foo", []byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+	if act, err := c.Get("foo"); err != nil {
+		t.Fatal(err)
+	} else if exp := "bar"; string(act) != exp {
+		t.Errorf("Wrong result: %s != %v", act, exp)
+	}
+	if _, err := c.Get("baz"); err != types.ErrKeyNotFound {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyNotFound)
+	}
+
+	// Errors at the first level and the populate of the first level.
+	if exp, act := int64(3), stats.GetCounters()["cache.multilevel.level.0.error"]; exp != act {
+		t.Errorf("Wrong error count: %v != %v", act, exp)
+	}
+
+	c, _ = newTestMultilevel(t, map[string]types.Cache{
+		"l1": newTestMemoryLevel(t), "l2": erroringCache{err: errTest},
+	}, "l1", "l2")
+	if _, err := c.Get("foo"); err != errTest {
+		t.Errorf("Wrong error returned: %v != %v", err, errTest)
+	}
+}
+
+func TestMultilevelCacheWriteThrough(t *testing.T) {
+	l1, l2 := newTestMemoryLevel(t), newTestMemoryLevel(t)
+	c, _ := newTestMultilevel(t, map[string]types.Cache{
+		"l1": l1, "l2": l2,
+	}, "l1", "l2")
+
+	if err := c.Set("foo", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetMulti(map[string][]byte{"bar": []byte("2")}); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []types.Cache{l1, l2} {
+		for k, exp := range map[string]string{"foo": "1", "bar": "2"} {
+			if act, err := l.Get(k); err != nil {
+				t.Error(err)
+			} else if string(act) != exp {
+				t.Errorf("Wrong result: %s != %v", act, exp)
+			}
+		}
+	}
+
+	if err := c.Delete("foo"); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []types.Cache{l1, l2} {
+		if _, err := l.Get("foo"); err != types.ErrKeyNotFound {
+			t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyNotFound)
+		}
+	}
+
+	errTest := errors.New("test err")
+	c, _ = newTestMultilevel(t, map[string]types.Cache{
+		"l1": erroringCache{err: errTest}, "l2": l2,
+	}, "l1", "l2")
+	if err := c.Set("baz", []byte("3")); err != errTest {
+		t.Errorf("Wrong error returned: %v != %v", err, errTest)
+	}
+	if act, err := l2.Get("baz"); err != nil {
+		t.Error(err)
+	} else if exp := "3"; string(act) != exp {
+		t.Errorf("Wrong result: %s != %v", act, exp)
+	}
+}
+
+func TestMultilevelCacheAdd(t *testing.T) {
+	l1, l2 := newTestMemoryLevel(t), newTestMemoryLevel(t)
+	c, _ := newTestMultilevel(t, map[string]types.Cache{
+		"l1": l1, "l2": l2,
+	}, "l1", "l2")
+
+	// A key at the first level only does not block an add.
+	if err := l1.Set("foo", []byte("old")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Add("foo", []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []types.Cache{l1, l2} {
+		if act, err := l.Get("foo"); err != nil {
+			t.Error(err)
+		} else if exp := "new"; string(act) != exp {
+			t.Errorf("Wrong result: %s != %v", act, exp)
+		}
+	}
+
+	if err := c.Add("foo", []byte("newer")); err != types.ErrKeyAlreadyExists {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyAlreadyExists)
+	}
+
+	// A failure to populate an earlier level does not fail an add.
+	c, _ = newTestMultilevel(t, map[string]types.Cache{
+		"l1": erroringCache{err: errors.New("test err")}, "l2": l2,
+	}, "l1", "l2")
+	if err := c.Add("bar", []byte("1")); err != nil {
+		t.Error(err)
+	}
+}
+
+//------------------------------------------------------------------------------
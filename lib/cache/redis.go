@@ -38,7 +38,18 @@ func init() {
 		constructor: NewRedis,
 		description: `
 Use a Redis instance as a cache. The expiration can be set to zero or an empty
-string in order to set no expiration.`,
+string in order to set no expiration.
+
+When ` + "`cluster`" + ` is set to ` + "`true`" + ` the ` + "`url`" + ` is
+used as a seed node of a Redis Cluster, from which the remaining nodes of the
+cluster are discovered, and keys are routed to the node that owns them.
+
+The ` + "`add`" + ` operation is performed atomically with a single
+` + "`SET`" + ` command with the ` + "`NX`" + ` option, making it safe to use for
+deduplication across multiple Benthos instances sharing the same Redis server.
+
+This cache supports setting a TTL per key, which can be done with the
+` + "[`cache` processor](../processors/README.md#cache)" + `.`,
 	}
 }
 
@@ -47,6 +58,7 @@ string in order to set no expiration.`,
 // RedisConfig is a config struct for a redis connection.
 type RedisConfig struct {
 	URL           string `json:"url" yaml:"url"`
+	Cluster       bool   `json:"cluster" yaml:"cluster"`
 	Prefix        string `json:"prefix" yaml:"prefix"`
 	Expiration    string `json:"expiration" yaml:"expiration"`
 	Retries       int    `json:"retries" yaml:"retries"`
@@ -57,6 +69,7 @@ type RedisConfig struct {
 func NewRedisConfig() RedisConfig {
 	return RedisConfig{
 		URL:           "tcp://localhost:6379",
+		Cluster:       false,
 		Prefix:        "",
 		Expiration:    "24h",
 		Retries:       3,
@@ -85,7 +98,6 @@ type Redis struct {
 	mSetSuccess    metrics.StatCounter
 	mSetLatency    metrics.StatTimer
 	mAddCount      metrics.StatCounter
-	mAddDupe       metrics.StatCounter
 	mAddRetry      metrics.StatCounter
 	mAddFailedDupe metrics.StatCounter
 	mAddFailedErr  metrics.StatCounter
@@ -98,7 +110,7 @@ type Redis struct {
 	mDelSuccess    metrics.StatCounter
 	mDelLatency    metrics.StatTimer
 
-	client      *redis.Client
+	client      redis.UniversalClient
 	ttl         time.Duration
 	prefix      string
 	retryPeriod time.Duration
@@ -125,11 +137,20 @@ func NewRedis(
 	if url.User != nil {
 		pass, _ = url.User.Password()
 	}
-	client := redis.NewClient(&redis.Options{
-		Addr:     url.Host,
-		Network:  url.Scheme,
-		Password: pass,
-	})
+
+	var client redis.UniversalClient
+	if conf.Redis.Cluster {
+		client = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:    []string{url.Host},
+			Password: pass,
+		})
+	} else {
+		client = redis.NewClient(&redis.Options{
+			Addr:     url.Host,
+			Network:  url.Scheme,
+			Password: pass,
+		})
+	}
 
 	return &Redis{
 		conf:  conf,
@@ -149,7 +170,6 @@ func NewRedis(
 		mSetSuccess:    stats.GetCounter("cache.redis.set.success"),
 		mSetLatency:    stats.GetTimer("cache.redis.set.latency"),
 		mAddCount:      stats.GetCounter("cache.redis.add.count"),
-		mAddDupe:       stats.GetCounter("cache.redis.add.failed.duplicate"),
 		mAddRetry:      stats.GetCounter("cache.redis.add.retry"),
 		mAddFailedDupe: stats.GetCounter("cache.redis.add.failed.duplicate"),
 		mAddFailedErr:  stats.GetCounter("cache.redis.add.failed.error"),
@@ -211,17 +231,27 @@ func (r *Redis) Get(key string) ([]byte, error) {
 
 // Set attempts to set the value of a key.
 func (r *Redis) Set(key string, value []byte) error {
+	return r.SetWithTTL(key, value, nil)
+}
+
+// SetWithTTL attempts to set the value of a key with an expiration, if the
+// expiration is nil then the default expiration of the cache is used.
+func (r *Redis) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
 	r.mSetCount.Incr(1)
 	tStarted := time.Now()
 
 	key = r.prefix + key
+	expiration := r.ttl
+	if ttl != nil {
+		expiration = *ttl
+	}
 
-	err := r.client.Set(key, value, r.ttl).Err()
+	err := r.client.Set(key, value, expiration).Err()
 	for i := 0; i < r.conf.Redis.Retries && err != nil; i++ {
 		r.log.Errorf("Set command failed: %v\n", err)
 		<-time.After(r.retryPeriod)
 		r.mSetRetry.Incr(1)
-		err = r.client.Set(key, value, r.ttl).Err()
+		err = r.client.Set(key, value, expiration).Err()
 	}
 	if err != nil {
 		r.mSetFailed.Incr(1)
@@ -251,39 +281,41 @@ func (r *Redis) SetMulti(items map[string][]byte) error {
 // Add attempts to set the value of a key only if the key does not already exist
 // and returns an error if the key already exists or if the operation fails.
 func (r *Redis) Add(key string, value []byte) error {
+	return r.AddWithTTL(key, value, nil)
+}
+
+// AddWithTTL attempts to set the value of a key with an expiration only if the
+// key does not already exist, if the expiration is nil then the default
+// expiration of the cache is used. This is performed atomically with a single
+// SET NX command and returns types.ErrKeyAlreadyExists if the key exists.
+func (r *Redis) AddWithTTL(key string, value []byte, ttl *time.Duration) error {
 	r.mAddCount.Incr(1)
 	tStarted := time.Now()
 
 	key = r.prefix + key
-
-	set, err := r.client.SetNX(key, value, r.ttl).Result()
-	if !set {
-		r.mAddFailedDupe.Incr(1)
-
-		latency := int64(time.Since(tStarted))
-		r.mAddLatency.Timing(latency)
-		r.mLatency.Timing(latency)
-
-		return types.ErrKeyAlreadyExists
+	expiration := r.ttl
+	if ttl != nil {
+		expiration = *ttl
 	}
+
+	set, err := r.client.SetNX(key, value, expiration).Result()
 	for i := 0; i < r.conf.Redis.Retries && err != nil; i++ {
 		r.log.Errorf("Add command failed: %v\n", err)
 		<-time.After(r.retryPeriod)
 		r.mAddRetry.Incr(1)
-		if set, err = r.client.SetNX(key, value, r.ttl).Result(); !set {
-			r.mAddFailedDupe.Incr(1)
-
-			latency := int64(time.Since(tStarted))
-			r.mAddLatency.Timing(latency)
-			r.mLatency.Timing(latency)
-
-			return types.ErrKeyAlreadyExists
-		}
+		set, err = r.client.SetNX(key, value, expiration).Result()
 	}
-	if err != nil {
-		r.mAddFailedErr.Incr(1)
-	} else {
+	if err == nil && !set {
+		err = types.ErrKeyAlreadyExists
+	}
+
+	switch err {
+	case nil:
 		r.mAddSuccess.Incr(1)
+	case types.ErrKeyAlreadyExists:
+		r.mAddFailedDupe.Incr(1)
+	default:
+		r.mAddFailedErr.Incr(1)
 	}
 
 	latency := int64(time.Since(tStarted))
@@ -301,18 +333,16 @@ func (r *Redis) Delete(key string) error {
 	key = r.prefix + key
 
 	deleted, err := r.client.Del(key).Result()
-	if deleted == 0 {
+	if err == nil && deleted == 0 {
 		r.mDelNotFound.Incr(1)
-		err = nil
 	}
 
 	for i := 0; i < r.conf.Redis.Retries && err != nil; i++ {
 		r.log.Errorf("Delete command failed: %v\n", err)
 		<-time.After(r.retryPeriod)
 		r.mDelRetry.Incr(1)
-		if deleted, err = r.client.Del(key).Result(); deleted == 0 {
+		if deleted, err = r.client.Del(key).Result(); err == nil && deleted == 0 {
 			r.mDelNotFound.Incr(1)
-			err = nil
 		}
 	}
 	if err != nil {
@@ -22,7 +22,9 @@ package cache
 
 import (
 	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
@@ -30,6 +32,30 @@ import (
 	"github.com/ory/dockertest"
 )
 
+func TestRedisConnectionErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Redis.URL = "tcp://localhost:1"
+	conf.Redis.Retries = 0
+
+	c, err := NewRedis(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = c.Get("foo"); err == nil || err == types.ErrKeyNotFound {
+		t.Errorf("Expected connection error from get: %v", err)
+	}
+	if err = c.Set("foo", []byte("bar")); err == nil {
+		t.Error("Expected connection error from set")
+	}
+	if err = c.Add("foo", []byte("bar")); err == nil || err == types.ErrKeyAlreadyExists {
+		t.Errorf("Expected connection error from add: %v", err)
+	}
+	if err = c.Delete("foo"); err == nil {
+		t.Error("Expected connection error from delete")
+	}
+}
+
 func TestRedisIntegration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping integration test in short mode")
@@ -73,6 +99,15 @@ func TestRedisIntegration(t *testing.T) {
 	t.Run("TestRedisGetAndSet", func(te *testing.T) {
 		testRedisGetAndSet(url, te)
 	})
+	t.Run("TestRedisAddConcurrent", func(te *testing.T) {
+		testRedisAddConcurrent(url, te)
+	})
+	t.Run("TestRedisSetWithTTL", func(te *testing.T) {
+		testRedisSetWithTTL(url, te)
+	})
+	t.Run("TestRedisAddWithTTL", func(te *testing.T) {
+		testRedisAddWithTTL(url, te)
+	})
 }
 
 func testRedisAddDuplicate(url string, t *testing.T) {
@@ -158,3 +193,120 @@ func testRedisGetAndSet(url string, t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func testRedisAddConcurrent(url string, t *testing.T) {
+	conf := NewConfig()
+	conf.Redis.URL = url
+
+	c, err := NewRedis(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Delete("benthos_test_concurrent"); err != nil {
+		t.Fatal(err)
+	}
+
+	nWorkers := 20
+	errs := make([]error, nWorkers)
+	startChan := make(chan struct{})
+
+	wg := sync.WaitGroup{}
+	wg.Add(nWorkers)
+	for i := 0; i < nWorkers; i++ {
+		go func(index int) {
+			defer wg.Done()
+			<-startChan
+			errs[index] = c.Add("benthos_test_concurrent", []byte(fmt.Sprintf("%v", index)))
+		}(i)
+	}
+	close(startChan)
+	wg.Wait()
+
+	successes := 0
+	for _, err := range errs {
+		if err == nil {
+			successes++
+		} else if err != types.ErrKeyAlreadyExists {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if successes != 1 {
+		t.Errorf("Wrong count of successful adds: %v != 1", successes)
+	}
+
+	if err = c.Delete("benthos_test_concurrent"); err != nil {
+		t.Error(err)
+	}
+}
+
+func testRedisSetWithTTL(url string, t *testing.T) {
+	conf := NewConfig()
+	conf.Redis.URL = url
+
+	c, err := NewRedis(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ttlCache, ok := c.(types.CacheWithTTL)
+	if !ok {
+		t.Fatal("Redis cache does not support TTLs")
+	}
+
+	ttl := time.Millisecond * 500
+	if err = ttlCache.SetWithTTL("benthos_test_ttl", []byte("foo"), &ttl); err != nil {
+		t.Fatal(err)
+	}
+	if act, err := c.Get("benthos_test_ttl"); err != nil {
+		t.Error(err)
+	} else if exp := "foo"; string(act) != exp {
+		t.Errorf("Wrong value returned: %s != %s", act, exp)
+	}
+
+	<-time.After(time.Second)
+
+	if _, err = c.Get("benthos_test_ttl"); err != types.ErrKeyNotFound {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyNotFound)
+	}
+}
+
+func testRedisAddWithTTL(url string, t *testing.T) {
+	conf := NewConfig()
+	conf.Redis.URL = url
+
+	c, err := NewRedis(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ttlCache, ok := c.(types.CacheWithTTL)
+	if !ok {
+		t.Fatal("Redis cache does not support TTLs")
+	}
+
+	if err = c.Delete("benthos_test_add_ttl"); err != nil {
+		t.Fatal(err)
+	}
+
+	ttl := time.Millisecond * 500
+	if err = ttlCache.AddWithTTL("benthos_test_add_ttl", []byte("foo"), &ttl); err != nil {
+		t.Fatal(err)
+	}
+	if err = ttlCache.AddWithTTL("benthos_test_add_ttl", []byte("bar"), &ttl); err != types.ErrKeyAlreadyExists {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyAlreadyExists)
+	}
+
+	<-time.After(time.Second)
+
+	if err = ttlCache.AddWithTTL("benthos_test_add_ttl", []byte("baz"), &ttl); err != nil {
+		t.Errorf("Expected add to succeed after expiry: %v", err)
+	}
+	if act, err := c.Get("benthos_test_add_ttl"); err != nil {
+		t.Error(err)
+	} else if exp := "baz"; string(act) != exp {
+		t.Errorf("Wrong value returned: %s != %s", act, exp)
+	}
+
+	if err = c.Delete("benthos_test_add_ttl"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,431 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/aws/session"
+	"github.com/Jeffail/benthos/lib/util/clock"
+	"github.com/Jeffail/benthos/lib/util/retries"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/cenkalti/backoff"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeS3] = TypeSpec{
+		constructor: NewS3,
+		description: `
+The s3 cache stores each item as an object within an S3 bucket, where the key
+of the object is the cache key with an optional ` + "`prefix`" + `. This cache is
+suited to very large sets of keys, or large values, that are shared across many
+Benthos instances, at the cost of higher latency per operation.
+
+When a ` + "`ttl`" + ` is set each object is written with the metadata field
+` + "`benthos-expires-at`" + `, containing the RFC 3339 time at which the item
+expires. Expired items are treated as missing when read, but are not removed
+from the bucket. In order to remove expired objects you should configure a
+lifecycle rule on the bucket that expires objects under the prefix after a
+period at least as long as the TTL.
+
+S3 does not support conditional writes, therefore the ` + "`add`" + ` operation
+is performed by checking for an existing object before writing, and is only a
+best effort. Concurrent attempts to add the same key might all succeed, and
+therefore this cache should not be used for deduplication where exactly once
+semantics are required.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// S3Config contains config fields for the S3 cache type.
+type S3Config struct {
+	sessionConfig  `json:",inline" yaml:",inline"`
+	Bucket         string `json:"bucket" yaml:"bucket"`
+	Prefix         string `json:"prefix" yaml:"prefix"`
+	ContentType    string `json:"content_type" yaml:"content_type"`
+	TTL            string `json:"ttl" yaml:"ttl"`
+	retries.Config `json:",inline" yaml:",inline"`
+}
+
+// NewS3Config creates a S3Config populated with default values.
+func NewS3Config() S3Config {
+	rConf := retries.NewConfig()
+	rConf.MaxRetries = 3
+	rConf.Backoff.InitialInterval = "1s"
+	rConf.Backoff.MaxInterval = "5s"
+	rConf.Backoff.MaxElapsedTime = "30s"
+	return S3Config{
+		sessionConfig: sessionConfig{
+			Config: session.NewConfig(),
+		},
+		Bucket:      "",
+		Prefix:      "",
+		ContentType: "application/octet-stream",
+		TTL:         "",
+		Config:      rConf,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+const s3ExpiresAtKey = "benthos-expires-at"
+
+// S3 is a S3 based cache implementation.
+type S3 struct {
+	client      s3iface.S3API
+	conf        S3Config
+	log         log.Modular
+	stats       metrics.Type
+	bucket      *string
+	ttl         time.Duration
+	backoffCtor func() backoff.BackOff
+	boffPool    sync.Pool
+	clock       clock.Clock
+
+	mLatency       metrics.StatTimer
+	mGetCount      metrics.StatCounter
+	mGetRetry      metrics.StatCounter
+	mGetFailed     metrics.StatCounter
+	mGetSuccess    metrics.StatCounter
+	mGetLatency    metrics.StatTimer
+	mGetNotFound   metrics.StatCounter
+	mSetCount      metrics.StatCounter
+	mSetRetry      metrics.StatCounter
+	mSetFailed     metrics.StatCounter
+	mSetSuccess    metrics.StatCounter
+	mSetLatency    metrics.StatTimer
+	mAddCount      metrics.StatCounter
+	mAddRetry      metrics.StatCounter
+	mAddFailedDupe metrics.StatCounter
+	mAddFailedErr  metrics.StatCounter
+	mAddSuccess    metrics.StatCounter
+	mAddLatency    metrics.StatTimer
+	mDelCount      metrics.StatCounter
+	mDelRetry      metrics.StatCounter
+	mDelFailedErr  metrics.StatCounter
+	mDelSuccess    metrics.StatCounter
+	mDelLatency    metrics.StatTimer
+}
+
+// NewS3 creates a new S3 cache type.
+func NewS3(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (types.Cache, error) {
+	sess, err := conf.S3.GetSession()
+	if err != nil {
+		return nil, err
+	}
+	return newS3(s3.New(sess), conf.S3, log, stats)
+}
+
+func newS3(client s3iface.S3API, conf S3Config, log log.Modular, stats metrics.Type) (*S3, error) {
+	if len(conf.Bucket) == 0 {
+		return nil, errors.New("a bucket must be specified")
+	}
+
+	s := S3{
+		client: client,
+		conf:   conf,
+		log:    log.NewModule(".cache.s3"),
+		stats:  stats,
+		bucket: aws.String(conf.Bucket),
+		clock:  clock.Real(),
+
+		mLatency:       stats.GetTimer("cache.s3.latency"),
+		mGetCount:      stats.GetCounter("cache.s3.get.count"),
+		mGetRetry:      stats.GetCounter("cache.s3.get.retry"),
+		mGetFailed:     stats.GetCounter("cache.s3.get.failed.error"),
+		mGetNotFound:   stats.GetCounter("cache.s3.get.failed.not_found"),
+		mGetSuccess:    stats.GetCounter("cache.s3.get.success"),
+		mGetLatency:    stats.GetTimer("cache.s3.get.latency"),
+		mSetCount:      stats.GetCounter("cache.s3.set.count"),
+		mSetRetry:      stats.GetCounter("cache.s3.set.retry"),
+		mSetFailed:     stats.GetCounter("cache.s3.set.failed.error"),
+		mSetSuccess:    stats.GetCounter("cache.s3.set.success"),
+		mSetLatency:    stats.GetTimer("cache.s3.set.latency"),
+		mAddCount:      stats.GetCounter("cache.s3.add.count"),
+		mAddRetry:      stats.GetCounter("cache.s3.add.retry"),
+		mAddFailedDupe: stats.GetCounter("cache.s3.add.failed.duplicate"),
+		mAddFailedErr:  stats.GetCounter("cache.s3.add.failed.error"),
+		mAddSuccess:    stats.GetCounter("cache.s3.add.success"),
+		mAddLatency:    stats.GetTimer("cache.s3.add.latency"),
+		mDelCount:      stats.GetCounter("cache.s3.delete.count"),
+		mDelRetry:      stats.GetCounter("cache.s3.delete.retry"),
+		mDelFailedErr:  stats.GetCounter("cache.s3.delete.failed.error"),
+		mDelSuccess:    stats.GetCounter("cache.s3.delete.success"),
+		mDelLatency:    stats.GetTimer("cache.s3.delete.latency"),
+	}
+
+	if conf.TTL != "" {
+		ttl, err := time.ParseDuration(conf.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ttl: %v", err)
+		}
+		s.ttl = ttl
+	}
+
+	var err error
+	if s.backoffCtor, err = conf.Config.GetCtor(); err != nil {
+		return nil, err
+	}
+	s.boffPool = sync.Pool{
+		New: func() interface{} {
+			return s.backoffCtor()
+		},
+	}
+	return &s, nil
+}
+
+//------------------------------------------------------------------------------
+
+func isS3NotFound(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case s3.ErrCodeNoSuchKey, "NotFound":
+			return true
+		}
+	}
+	return false
+}
+
+// expired returns true if the metadata of an object indicates that it has
+// passed its expiration time.
+func (s *S3) expired(meta map[string]*string) bool {
+	for k, v := range meta {
+		if !strings.EqualFold(k, s3ExpiresAtKey) || v == nil {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, *v)
+		if err != nil {
+			s.log.Warnf("Failed to parse expiration of object: %v\n", err)
+			return false
+		}
+		return !s.clock.Now().Before(t)
+	}
+	return false
+}
+
+// retry attempts an operation until it succeeds, returns an error that should
+// not be retried, or the backoff is exhausted.
+func (s *S3) retry(fn func() error, isFinal func(error) bool, mRetry metrics.StatCounter) error {
+	boff := s.boffPool.Get().(backoff.BackOff)
+	defer func() {
+		boff.Reset()
+		s.boffPool.Put(boff)
+	}()
+
+	err := fn()
+	for err != nil && !isFinal(err) {
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			break
+		}
+		s.log.Errorf("Command failed: %v\n", err)
+		s.clock.Sleep(wait)
+		mRetry.Incr(1)
+		err = fn()
+	}
+	return err
+}
+
+func isCacheResult(err error) bool {
+	return err == types.ErrKeyNotFound || err == types.ErrKeyAlreadyExists
+}
+
+//------------------------------------------------------------------------------
+
+// Get attempts to locate and return a cached value by its key, returns an error
+// if the key does not exist.
+func (s *S3) Get(key string) ([]byte, error) {
+	s.mGetCount.Incr(1)
+	tStarted := time.Now()
+
+	var result []byte
+	err := s.retry(func() error {
+		var gerr error
+		result, gerr = s.get(key)
+		return gerr
+	}, isCacheResult, s.mGetRetry)
+
+	switch err {
+	case nil:
+		s.mGetSuccess.Incr(1)
+	case types.ErrKeyNotFound:
+		s.mGetNotFound.Incr(1)
+	default:
+		s.mGetFailed.Incr(1)
+	}
+
+	latency := int64(time.Since(tStarted))
+	s.mGetLatency.Timing(latency)
+	s.mLatency.Timing(latency)
+	return result, err
+}
+
+func (s *S3) get(key string) ([]byte, error) {
+	res, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: s.bucket,
+		Key:    aws.String(s.conf.Prefix + key),
+	})
+	if err != nil {
+		if isS3NotFound(err) {
+			return nil, types.ErrKeyNotFound
+		}
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if s.expired(res.Metadata) {
+		return nil, types.ErrKeyNotFound
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+// Set attempts to set the value of a key.
+func (s *S3) Set(key string, value []byte) error {
+	s.mSetCount.Incr(1)
+	tStarted := time.Now()
+
+	err := s.retry(func() error {
+		return s.put(key, value)
+	}, isCacheResult, s.mSetRetry)
+
+	if err == nil {
+		s.mSetSuccess.Incr(1)
+	} else {
+		s.mSetFailed.Incr(1)
+	}
+
+	latency := int64(time.Since(tStarted))
+	s.mSetLatency.Timing(latency)
+	s.mLatency.Timing(latency)
+	return err
+}
+
+func (s *S3) put(key string, value []byte) error {
+	input := &s3.PutObjectInput{
+		Bucket:      s.bucket,
+		Key:         aws.String(s.conf.Prefix + key),
+		Body:        bytes.NewReader(value),
+		ContentType: aws.String(s.conf.ContentType),
+	}
+	if s.ttl > 0 {
+		input.Metadata = map[string]*string{
+			s3ExpiresAtKey: aws.String(s.clock.Now().Add(s.ttl).UTC().Format(time.RFC3339Nano)),
+		}
+	}
+	_, err := s.client.PutObject(input)
+	return err
+}
+
+// SetMulti attempts to set the value of multiple keys, if any keys fail to be
+// set an error is returned.
+func (s *S3) SetMulti(items map[string][]byte) error {
+	for k, v := range items {
+		if err := s.Set(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Add attempts to set the value of a key only if the key does not already exist
+// and returns an error if the key already exists.
+func (s *S3) Add(key string, value []byte) error {
+	s.mAddCount.Incr(1)
+	tStarted := time.Now()
+
+	err := s.retry(func() error {
+		return s.add(key, value)
+	}, isCacheResult, s.mAddRetry)
+
+	switch err {
+	case nil:
+		s.mAddSuccess.Incr(1)
+	case types.ErrKeyAlreadyExists:
+		s.mAddFailedDupe.Incr(1)
+	default:
+		s.mAddFailedErr.Incr(1)
+	}
+
+	latency := int64(time.Since(tStarted))
+	s.mAddLatency.Timing(latency)
+	s.mLatency.Timing(latency)
+	return err
+}
+
+func (s *S3) add(key string, value []byte) error {
+	res, err := s.client.HeadObject(&s3.HeadObjectInput{
+		Bucket: s.bucket,
+		Key:    aws.String(s.conf.Prefix + key),
+	})
+	if err == nil {
+		if !s.expired(res.Metadata) {
+			return types.ErrKeyAlreadyExists
+		}
+	} else if !isS3NotFound(err) {
+		return err
+	}
+	return s.put(key, value)
+}
+
+// Delete attempts to remove a key.
+func (s *S3) Delete(key string) error {
+	s.mDelCount.Incr(1)
+	tStarted := time.Now()
+
+	err := s.retry(func() error {
+		_, derr := s.client.DeleteObject(&s3.DeleteObjectInput{
+			Bucket: s.bucket,
+			Key:    aws.String(s.conf.Prefix + key),
+		})
+		if isS3NotFound(derr) {
+			return nil
+		}
+		return derr
+	}, isCacheResult, s.mDelRetry)
+
+	if err == nil {
+		s.mDelSuccess.Incr(1)
+	} else {
+		s.mDelFailedErr.Incr(1)
+	}
+
+	latency := int64(time.Since(tStarted))
+	s.mDelLatency.Timing(latency)
+	s.mLatency.Timing(latency)
+	return err
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,297 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/clock"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+type mockS3Object struct {
+	body        []byte
+	contentType string
+	meta        map[string]*string
+}
+
+type mockS3 struct {
+	s3iface.S3API
+
+	sync.Mutex
+	objects map[string]mockS3Object
+	fails   int
+}
+
+func newMockS3() *mockS3 {
+	return &mockS3{objects: map[string]mockS3Object{}}
+}
+
+func (m *mockS3) fail() error {
+	if m.fails > 0 {
+		m.fails--
+		return errors.New("simulated failure")
+	}
+	return nil
+}
+
+func (m *mockS3) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	m.Lock()
+	defer m.Unlock()
+	if err := m.fail(); err != nil {
+		return nil, err
+	}
+	obj, exists := m.objects[*input.Bucket+"/"+*input.Key]
+	if !exists {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "not found", nil)
+	}
+	return &s3.GetObjectOutput{
+		Body:     ioutil.NopCloser(bytes.NewReader(obj.body)),
+		Metadata: obj.meta,
+	}, nil
+}
+
+func (m *mockS3) HeadObject(input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	m.Lock()
+	defer m.Unlock()
+	if err := m.fail(); err != nil {
+		return nil, err
+	}
+	obj, exists := m.objects[*input.Bucket+"/"+*input.Key]
+	if !exists {
+		return nil, awserr.NewRequestFailure(awserr.New("NotFound", "not found", nil), http.StatusNotFound, "")
+	}
+	return &s3.HeadObjectOutput{Metadata: obj.meta}, nil
+}
+
+func (m *mockS3) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	m.Lock()
+	defer m.Unlock()
+	if err := m.fail(); err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.objects[*input.Bucket+"/"+*input.Key] = mockS3Object{
+		body:        body,
+		contentType: *input.ContentType,
+		meta:        input.Metadata,
+	}
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (m *mockS3) DeleteObject(input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	m.Lock()
+	defer m.Unlock()
+	if err := m.fail(); err != nil {
+		return nil, err
+	}
+	delete(m.objects, *input.Bucket+"/"+*input.Key)
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func newTestS3(t *testing.T, conf S3Config) (*S3, *mockS3, *metrics.Local) {
+	t.Helper()
+
+	mock := newMockS3()
+	stats := metrics.NewLocal()
+	s, err := newS3(mock, conf, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, mock, stats
+}
+
+func TestS3GetSetDelete(t *testing.T) {
+	conf := NewS3Config()
+	conf.Bucket = "foo"
+	conf.Prefix = "cache/"
+	conf.ContentType = "text/plain"
+
+	s, mock, stats := newTestS3(t, conf)
+
+	if _, err := s.Get("bar"); err != types.ErrKeyNotFound {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyNotFound)
+	}
+	if err := s.SetMulti(map[string][]byte{
+		"bar": []byte("baz"),
+		"qux": []byte("quz"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	obj, exists := mock.objects["foo/cache/bar"]
+	if !exists {
+		t.Fatal("Object not stored under prefix")
+	}
+	if exp, act := "text/plain", obj.contentType; exp != act {
+		t.Errorf("Wrong content type: %v != %v", act, exp)
+	}
+	if obj.meta != nil {
+		t.Errorf("Unexpected metadata without a ttl: %v", obj.meta)
+	}
+
+	if act, err := s.Get("bar"); err != nil {
+		t.Error(err)
+	} else if exp := "baz"; exp != string(act) {
+		t.Errorf("Wrong value: %s != %v", act, exp)
+	}
+
+	if err := s.Delete("bar"); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.Get("bar"); err != types.ErrKeyNotFound {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyNotFound)
+	}
+
+	counters := stats.GetCounters()
+	for k, exp := range map[string]int64{
+		"cache.s3.get.success":          1,
+		"cache.s3.get.failed.not_found": 2,
+		"cache.s3.set.success":          2,
+		"cache.s3.delete.success":       1,
+	} {
+		if act := counters[k]; exp != act {
+			t.Errorf("Wrong count for %v: %v != %v", k, act, exp)
+		}
+	}
+}
+
+func TestS3Add(t *testing.T) {
+	conf := NewS3Config()
+	conf.Bucket = "foo"
+
+	s, _, _ := newTestS3(t, conf)
+
+	if err := s.Add("bar", []byte("baz")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add("bar", []byte("qux")); err != types.ErrKeyAlreadyExists {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyAlreadyExists)
+	}
+	if act, err := s.Get("bar"); err != nil {
+		t.Error(err)
+	} else if exp := "baz"; exp != string(act) {
+		t.Errorf("Wrong value: %s != %v", act, exp)
+	}
+}
+
+func TestS3TTL(t *testing.T) {
+	conf := NewS3Config()
+	conf.Bucket = "foo"
+	conf.TTL = "1h"
+
+	s, mock, _ := newTestS3(t, conf)
+	fakeClock := clock.NewFake(time.Date(2018, 11, 26, 10, 0, 0, 0, time.UTC))
+	s.clock = fakeClock
+
+	if err := s.Set("bar", []byte("baz")); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "2018-11-26T11:00:00Z", aws.StringValue(mock.objects["foo/bar"].meta[s3ExpiresAtKey]); exp != act {
+		t.Errorf("Wrong expiration: %v != %v", act, exp)
+	}
+
+	// Metadata keys are canonicalised by S3 when read back.
+	obj := mock.objects["foo/bar"]
+	obj.meta = map[string]*string{"Benthos-Expires-At": obj.meta[s3ExpiresAtKey]}
+	mock.objects["foo/bar"] = obj
+
+	if _, err := s.Get("bar"); err != nil {
+		t.Error(err)
+	}
+	if err := s.Add("bar", []byte("qux")); err != types.ErrKeyAlreadyExists {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyAlreadyExists)
+	}
+
+	fakeClock.Add(time.Hour)
+
+	if _, err := s.Get("bar"); err != types.ErrKeyNotFound {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyNotFound)
+	}
+	if err := s.Add("bar", []byte("qux")); err != nil {
+		t.Error(err)
+	}
+	if act, err := s.Get("bar"); err != nil {
+		t.Error(err)
+	} else if exp := "qux"; exp != string(act) {
+		t.Errorf("Wrong value: %s != %v", act, exp)
+	}
+}
+
+func TestS3Retries(t *testing.T) {
+	conf := NewS3Config()
+	conf.Bucket = "foo"
+	conf.MaxRetries = 2
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+
+	s, mock, stats := newTestS3(t, conf)
+
+	mock.fails = 2
+	if err := s.Set("bar", []byte("baz")); err != nil {
+		t.Fatal(err)
+	}
+
+	mock.fails = 3
+	if _, err := s.Get("bar"); err == nil {
+		t.Error("Expected error after retries exhausted")
+	}
+
+	counters := stats.GetCounters()
+	for k, exp := range map[string]int64{
+		"cache.s3.set.retry":        2,
+		"cache.s3.set.success":      1,
+		"cache.s3.get.retry":        2,
+		"cache.s3.get.failed.error": 1,
+	} {
+		if act := counters[k]; exp != act {
+			t.Errorf("Wrong count for %v: %v != %v", k, act, exp)
+		}
+	}
+}
+
+func TestS3BadConfig(t *testing.T) {
+	conf := NewS3Config()
+	if _, err := newS3(newMockS3(), conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing bucket")
+	}
+
+	conf.Bucket = "foo"
+	conf.TTL = "nope"
+	if _, err := newS3(newMockS3(), conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad ttl")
+	}
+}
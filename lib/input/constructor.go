@@ -67,6 +67,7 @@ const (
 	TypeFile          = "file"
 	TypeFiles         = "files"
 	TypeGCPPubSub     = "gcp_pubsub"
+	TypeGRPC          = "grpc"
 	TypeHDFS          = "hdfs"
 	TypeHTTPClient    = "http_client"
 	TypeHTTPServer    = "http_server"
@@ -84,6 +85,7 @@ const (
 	TypeRedisPubSub   = "redis_pubsub"
 	TypeRedisStreams  = "redis_streams"
 	TypeS3            = "s3"
+	TypeSocketServer  = "socket_server"
 	TypeSQS           = "sqs"
 	TypeSTDIN         = "stdin"
 	TypeWebsocket     = "websocket"
@@ -101,6 +103,7 @@ type Config struct {
 	File          FileConfig                 `json:"file" yaml:"file"`
 	Files         reader.FilesConfig         `json:"files" yaml:"files"`
 	GCPPubSub     reader.GCPPubSubConfig     `json:"gcp_pubsub" yaml:"gcp_pubsub"`
+	GRPC          GRPCConfig                 `json:"grpc" yaml:"grpc"`
 	HDFS          reader.HDFSConfig          `json:"hdfs" yaml:"hdfs"`
 	HTTPClient    HTTPClientConfig           `json:"http_client" yaml:"http_client"`
 	HTTPServer    HTTPServerConfig           `json:"http_server" yaml:"http_server"`
@@ -119,11 +122,16 @@ type Config struct {
 	RedisPubSub   reader.RedisPubSubConfig   `json:"redis_pubsub" yaml:"redis_pubsub"`
 	RedisStreams  reader.RedisStreamsConfig  `json:"redis_streams" yaml:"redis_streams"`
 	S3            reader.AmazonS3Config      `json:"s3" yaml:"s3"`
+	SocketServer  SocketServerConfig         `json:"socket_server" yaml:"socket_server"`
 	SQS           reader.AmazonSQSConfig     `json:"sqs" yaml:"sqs"`
 	STDIN         STDINConfig                `json:"stdin" yaml:"stdin"`
 	Websocket     reader.WebsocketConfig     `json:"websocket" yaml:"websocket"`
 	ZMQ4          *reader.ZMQ4Config         `json:"zmq4,omitempty" yaml:"zmq4,omitempty"`
 	Processors    []processor.Config         `json:"processors" yaml:"processors"`
+
+	PauseOnOutputDisconnect bool   `json:"pause_on_output_disconnect" yaml:"pause_on_output_disconnect"`
+	PauseGracePeriod        string `json:"pause_grace_period" yaml:"pause_grace_period"`
+	MessageTTL              string `json:"message_ttl" yaml:"message_ttl"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
@@ -136,6 +144,7 @@ func NewConfig() Config {
 		File:          NewFileConfig(),
 		Files:         reader.NewFilesConfig(),
 		GCPPubSub:     reader.NewGCPPubSubConfig(),
+		GRPC:          NewGRPCConfig(),
 		HDFS:          reader.NewHDFSConfig(),
 		HTTPClient:    NewHTTPClientConfig(),
 		HTTPServer:    NewHTTPServerConfig(),
@@ -154,11 +163,16 @@ func NewConfig() Config {
 		RedisPubSub:   reader.NewRedisPubSubConfig(),
 		RedisStreams:  reader.NewRedisStreamsConfig(),
 		S3:            reader.NewAmazonS3Config(),
+		SocketServer:  NewSocketServerConfig(),
 		SQS:           reader.NewAmazonSQSConfig(),
 		STDIN:         NewSTDINConfig(),
 		Websocket:     reader.NewWebsocketConfig(),
 		ZMQ4:          reader.NewZMQ4Config(),
 		Processors:    []processor.Config{},
+
+		PauseOnOutputDisconnect: false,
+		PauseGracePeriod:        "10s",
+		MessageTTL:              "",
 	}
 }
 
@@ -196,6 +210,14 @@ func SanitiseConfig(conf Config) (interface{}, error) {
 		}
 	}
 
+	if conf.PauseOnOutputDisconnect {
+		outputMap["pause_on_output_disconnect"] = true
+		outputMap["pause_grace_period"] = conf.PauseGracePeriod
+	}
+	if len(conf.MessageTTL) > 0 {
+		outputMap["message_ttl"] = conf.MessageTTL
+	}
+
 	if len(conf.Processors) == 0 {
 		return outputMap, nil
 	}
@@ -306,7 +328,37 @@ input:
 
 Note that in this example we have specified a processor at the broker level
 which will be applied to _all_ inputs, and we also have a processor at the baz
-level which is only applied to messages from the baz input.`
+level which is only applied to messages from the baz input.
+
+### Pausing on Output Disconnect
+
+By default an input continues to consume data whilst the output of its stream
+is disconnected, which fills any buffer and results in messages being retried
+against the output. When the field ` + "`pause_on_output_disconnect`" + ` of the
+root input is set to ` + "`true`" + ` the stream instead stops reading from the
+input once the output has been disconnected for longer than
+` + "`pause_grace_period`" + `, and resumes as soon as the output reconnects.
+Messages that are already in flight are unaffected.
+
+Whilst paused an input is not read from and therefore stops pulling new data,
+e.g. an SQS input stops polling, although some clients such as Kafka might
+continue to prefetch a limited amount of data in the background. These fields
+have no effect on inputs that are children of a broker.
+
+### Message TTL
+
+When the field ` + "`message_ttl`" + ` of the root input is set to a duration
+each message is stamped with the time at which it was read, and is checked again
+immediately before it is written by the output. Messages that have existed for
+longer than the TTL, for example after sitting in a large buffer backlog, are
+dropped and acknowledged rather than written, and are counted by the metric
+` + "`output.dropped_stale`" + `. The check is applied to each part of a batch
+individually.
+
+The ingestion time is carried through the pipeline as the metadata key
+` + "`benthos_ingested_at`" + ` (in nanoseconds since the Unix epoch), which is
+removed before messages reach the output. Processors that discard metadata also
+discard this stamp, and messages without it are never dropped.`
 
 // Descriptions returns a formatted string of descriptions for each type.
 func Descriptions() string {
@@ -374,6 +426,7 @@ func New(
 				if err != nil {
 					return nil, fmt.Errorf("failed to create processor '%v': %v", procConf.Type, err)
 				}
+				processors[i] = processor.WrapTimed("input", i, procConf, processors[i], stats)
 			}
 			return pipeline.NewProcessor(log, stats, processors...), nil
 		}}, pipelines...)
@@ -395,6 +448,10 @@ func New(
 		}
 		return WrapWithPipelines(input, pipelines...)
 	}
+	if conf.Type == TypeZMQ4 {
+		// The zmq4 input is only registered when built with the ZMQ4 tag.
+		return nil, types.ErrZMQ4NotCompiled
+	}
 	return nil, types.ErrInvalidInputType
 }
 
@@ -0,0 +1,300 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	bgrpc "github.com/Jeffail/benthos/lib/util/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeGRPC] = TypeSpec{
+		constructor: NewGRPC,
+		description: `
+Serves a gRPC method that clients can stream messages to. Each message received
+on the stream becomes a single part message and the payload is the serialized
+request message (usually protobuf) as raw bytes, no decoding takes place.
+
+The method is specified in the form ` + "`package.Service/Method`" + `. If
+` + "`proto_descriptor_set`" + ` is set to the path of a serialized
+FileDescriptorSet (as produced by ` + "`protoc --descriptor_set_out`" + `) then
+the method is checked against it at startup, and the method must be client
+streaming or unary. If the method is left empty then calls to any method are
+accepted.
+
+Each message must be acknowledged before the next message of the stream is
+read, which applies backpressure to the client. Once the client closes the
+stream an empty response message is returned. If a message fails to be
+delivered, or is not accepted within ` + "`timeout_ms`" + `, then the call is
+terminated with an error status.
+
+TLS is enabled when ` + "`cert_file` and `key_file`" + ` are specified.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- grpc_server_method
+- All request metadata (only first values are taken)
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// GRPCConfig contains configuration for the GRPC input type.
+type GRPCConfig struct {
+	Address            string `json:"address" yaml:"address"`
+	Method             string `json:"method" yaml:"method"`
+	ProtoDescriptorSet string `json:"proto_descriptor_set" yaml:"proto_descriptor_set"`
+	TimeoutMS          int64  `json:"timeout_ms" yaml:"timeout_ms"`
+	CertFile           string `json:"cert_file" yaml:"cert_file"`
+	KeyFile            string `json:"key_file" yaml:"key_file"`
+}
+
+// NewGRPCConfig creates a new GRPCConfig with default values.
+func NewGRPCConfig() GRPCConfig {
+	return GRPCConfig{
+		Address:            "0.0.0.0:50051",
+		Method:             "",
+		ProtoDescriptorSet: "",
+		TimeoutMS:          5000,
+		CertFile:           "",
+		KeyFile:            "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// GRPC is an input type that serves a streaming gRPC method, where each
+// message received on a stream is sent through Benthos.
+type GRPC struct {
+	running int32
+
+	conf  GRPCConfig
+	stats metrics.Type
+	log   log.Modular
+
+	method   string
+	listener net.Listener
+	server   *grpc.Server
+
+	transactions chan types.Transaction
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+
+	mCount    metrics.StatCounter
+	mCountF   metrics.StatCounter
+	mRejected metrics.StatCounter
+	mTimeout  metrics.StatCounter
+	mErr      metrics.StatCounter
+	mErrF     metrics.StatCounter
+	mSucc     metrics.StatCounter
+	mSuccF    metrics.StatCounter
+}
+
+// NewGRPC creates a new GRPC input type.
+func NewGRPC(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	g := GRPC{
+		running:      1,
+		conf:         conf.GRPC,
+		stats:        stats,
+		log:          log.NewModule(".input.grpc"),
+		transactions: make(chan types.Transaction),
+		closeChan:    make(chan struct{}),
+		closedChan:   make(chan struct{}),
+
+		mCount:    stats.GetCounter("input.grpc.count"),
+		mCountF:   stats.GetCounter("input.count"),
+		mRejected: stats.GetCounter("input.grpc.rejected"),
+		mTimeout:  stats.GetCounter("input.grpc.send.timeout"),
+		mErr:      stats.GetCounter("input.grpc.send.error"),
+		mErrF:     stats.GetCounter("input.send.error"),
+		mSucc:     stats.GetCounter("input.grpc.send.success"),
+		mSuccF:    stats.GetCounter("input.send.success"),
+	}
+
+	if len(g.conf.Method) > 0 {
+		method, err := bgrpc.ResolveMethod(g.conf.ProtoDescriptorSet, g.conf.Method)
+		if err != nil {
+			return nil, err
+		}
+		if method.ServerStreaming {
+			return nil, fmt.Errorf("method '%v' is server streaming, which is not supported", method.FullName)
+		}
+		g.method = method.FullName
+	} else if len(g.conf.ProtoDescriptorSet) > 0 {
+		return nil, fmt.Errorf("a method must be specified when using a proto descriptor set")
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.CustomCodec(bgrpc.Codec{}),
+		grpc.UnknownServiceHandler(g.streamHandler),
+	}
+	if len(g.conf.CertFile) > 0 || len(g.conf.KeyFile) > 0 {
+		creds, err := credentials.NewServerTLSFromFile(g.conf.CertFile, g.conf.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	var err error
+	if g.listener, err = net.Listen("tcp", g.conf.Address); err != nil {
+		return nil, err
+	}
+	g.server = grpc.NewServer(opts...)
+
+	go g.loop()
+	return &g, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (g *GRPC) streamHandler(srv interface{}, stream grpc.ServerStream) error {
+	if atomic.LoadInt32(&g.running) != 1 {
+		return status.Error(codes.Unavailable, "server closing")
+	}
+
+	method, _ := grpc.MethodFromServerStream(stream)
+	if len(g.method) > 0 && method != g.method {
+		g.mRejected.Incr(1)
+		return status.Errorf(codes.Unimplemented, "method %v not implemented", method)
+	}
+
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	resChan := make(chan types.Response)
+
+	for {
+		var msgBytes []byte
+		if err := stream.RecvMsg(&msgBytes); err != nil {
+			if err == io.EOF {
+				return stream.SendMsg(&[]byte{})
+			}
+			return err
+		}
+		g.mCount.Incr(1)
+		g.mCountF.Incr(1)
+
+		msg := message.New([][]byte{msgBytes})
+		meta := msg.Get(0).Metadata()
+		bgrpc.MetadataFromGRPC(md, meta)
+		meta.Set("grpc_server_method", method)
+
+		select {
+		case g.transactions <- types.NewTransaction(msg, resChan):
+		case <-time.After(time.Millisecond * time.Duration(g.conf.TimeoutMS)):
+			g.mTimeout.Incr(1)
+			return status.Error(codes.DeadlineExceeded, "message timed out")
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-g.closeChan:
+			return status.Error(codes.Unavailable, "server closing")
+		}
+
+		select {
+		case res, open := <-resChan:
+			if !open {
+				return status.Error(codes.Unavailable, "server closing")
+			} else if res.Error() != nil {
+				g.mErr.Incr(1)
+				g.mErrF.Incr(1)
+				return status.Error(codes.Internal, res.Error().Error())
+			}
+			g.mSucc.Incr(1)
+			g.mSuccF.Incr(1)
+		case <-g.closeChan:
+			return status.Error(codes.Unavailable, "server closing")
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (g *GRPC) loop() {
+	mRunning := g.stats.GetGauge("input.grpc.running")
+
+	defer func() {
+		atomic.StoreInt32(&g.running, 0)
+		g.server.Stop()
+
+		mRunning.Decr(1)
+
+		close(g.transactions)
+		close(g.closedChan)
+	}()
+	mRunning.Incr(1)
+
+	go func() {
+		g.log.Infof("Receiving gRPC messages at: %v\n", g.listener.Addr())
+		if err := g.server.Serve(g.listener); err != nil && err != grpc.ErrServerStopped {
+			g.log.Errorf("Server error: %v\n", err)
+		}
+	}()
+
+	<-g.closeChan
+}
+
+// TransactionChan returns a transactions channel for consuming messages from
+// this input.
+func (g *GRPC) TransactionChan() <-chan types.Transaction {
+	return g.transactions
+}
+
+// CloseAsync shuts down the GRPC input and stops processing requests.
+func (g *GRPC) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&g.running, 1, 0) {
+		close(g.closeChan)
+	}
+}
+
+// WaitForClose blocks until the GRPC input has closed down.
+func (g *GRPC) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-g.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,238 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+	bgrpc "github.com/Jeffail/benthos/lib/util/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+//------------------------------------------------------------------------------
+
+func newGRPCTestClient(t *testing.T, conf Config) (*GRPC, *grpc.ClientConn) {
+	t.Helper()
+
+	i, err := NewGRPC(conf, nil, log.Noop(), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := i.(*GRPC)
+
+	conn, err := grpc.Dial(
+		g.listener.Addr().String(),
+		grpc.WithInsecure(),
+		grpc.WithDefaultCallOptions(grpc.CallCustomCodec(bgrpc.Codec{})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g, conn
+}
+
+func TestGRPCUnary(t *testing.T) {
+	t.Parallel()
+
+	conf := NewConfig()
+	conf.GRPC.Address = "localhost:0"
+	conf.GRPC.Method = "foo.Bar/Baz"
+
+	g, conn := newGRPCTestClient(t, conf)
+	defer func() {
+		conn.Close()
+		g.CloseAsync()
+		if err := g.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	errChan := make(chan error)
+	go func() {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "foo", "bar")
+		req, res := []byte("hello world"), []byte{}
+		errChan <- conn.Invoke(ctx, "/foo.Bar/Baz", &req, &res)
+	}()
+
+	var ts types.Transaction
+	select {
+	case ts = <-g.TransactionChan():
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for message")
+	}
+	if exp, act := "hello world", string(ts.Payload.Get(0).Get()); exp != act {
+		t.Errorf("Wrong payload: %v != %v", act, exp)
+	}
+	meta := ts.Payload.Get(0).Metadata()
+	if exp, act := "bar", meta.Get("foo"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+	if exp, act := "/foo.Bar/Baz", meta.Get("grpc_server_method"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+	ts.ResponseChan <- response.NewAck()
+
+	select {
+	case err := <-errChan:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for call")
+	}
+}
+
+func TestGRPCClientStream(t *testing.T) {
+	t.Parallel()
+
+	conf := NewConfig()
+	conf.GRPC.Address = "localhost:0"
+
+	g, conn := newGRPCTestClient(t, conf)
+	defer func() {
+		conn.Close()
+		g.CloseAsync()
+		if err := g.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	inputs := []string{"foo", "bar", "baz"}
+
+	errChan := make(chan error)
+	go func() {
+		stream, err := conn.NewStream(
+			context.Background(), &grpc.StreamDesc{ClientStreams: true}, "/foo.Bar/Stream",
+		)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		for _, in := range inputs {
+			b := []byte(in)
+			if err = stream.SendMsg(&b); err != nil {
+				errChan <- err
+				return
+			}
+		}
+		if err = stream.CloseSend(); err != nil {
+			errChan <- err
+			return
+		}
+		var res []byte
+		errChan <- stream.RecvMsg(&res)
+	}()
+
+	for i, exp := range inputs {
+		var ts types.Transaction
+		select {
+		case ts = <-g.TransactionChan():
+		case <-time.After(time.Second * 5):
+			t.Fatal("Timed out waiting for message")
+		}
+		if act := string(ts.Payload.Get(0).Get()); exp != act {
+			t.Errorf("Wrong payload %v: %v != %v", i, act, exp)
+		}
+		ts.ResponseChan <- response.NewAck()
+	}
+
+	select {
+	case err := <-errChan:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for call")
+	}
+}
+
+func TestGRPCErrors(t *testing.T) {
+	t.Parallel()
+
+	conf := NewConfig()
+	conf.GRPC.Address = "localhost:0"
+	conf.GRPC.Method = "foo.Bar/Baz"
+
+	g, conn := newGRPCTestClient(t, conf)
+	defer func() {
+		conn.Close()
+		g.CloseAsync()
+		if err := g.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	req, res := []byte("hello world"), []byte{}
+	err := conn.Invoke(context.Background(), "/foo.Bar/Nope", &req, &res)
+	if exp, act := codes.Unimplemented, status.Code(err); exp != act {
+		t.Errorf("Wrong status code: %v != %v", act, exp)
+	}
+
+	errChan := make(chan error)
+	go func() {
+		errChan <- conn.Invoke(context.Background(), "/foo.Bar/Baz", &req, &res)
+	}()
+
+	select {
+	case ts := <-g.TransactionChan():
+		ts.ResponseChan <- response.NewError(errors.New("nope"))
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for message")
+	}
+
+	select {
+	case err := <-errChan:
+		if exp, act := codes.Internal, status.Code(err); exp != act {
+			t.Errorf("Wrong status code: %v != %v", act, exp)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for call")
+	}
+}
+
+func TestGRPCBadConfig(t *testing.T) {
+	t.Parallel()
+
+	conf := NewConfig()
+	conf.GRPC.Address = "localhost:0"
+	conf.GRPC.ProtoDescriptorSet = "/does/not/exist"
+
+	if _, err := NewGRPC(conf, nil, log.Noop(), metrics.DudType{}); err == nil {
+		t.Error("Expected error from descriptor set without method")
+	}
+
+	conf.GRPC.Method = "foo.Bar/Baz"
+	if _, err := NewGRPC(conf, nil, log.Noop(), metrics.DudType{}); err == nil {
+		t.Error("Expected error from missing descriptor set")
+	}
+}
+
+//------------------------------------------------------------------------------
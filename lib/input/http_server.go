@@ -37,6 +37,7 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/throttle"
+	"github.com/Jeffail/benthos/lib/util/tracecontext"
 	"github.com/gorilla/websocket"
 )
 
@@ -63,7 +64,9 @@ This input adds the following metadata fields to each message:
 ` + "```" + `
 
 You can access these metadata fields using
-[function interpolation](../config_interpolation.md#metadata).`,
+[function interpolation](../config_interpolation.md#metadata).
+
+` + tracecontext.Documentation,
 	}
 }
 
@@ -71,23 +74,25 @@ You can access these metadata fields using
 
 // HTTPServerConfig contains configuration for the HTTPServer input type.
 type HTTPServerConfig struct {
-	Address   string `json:"address" yaml:"address"`
-	Path      string `json:"path" yaml:"path"`
-	WSPath    string `json:"ws_path" yaml:"ws_path"`
-	TimeoutMS int64  `json:"timeout_ms" yaml:"timeout_ms"`
-	CertFile  string `json:"cert_file" yaml:"cert_file"`
-	KeyFile   string `json:"key_file" yaml:"key_file"`
+	Address               string `json:"address" yaml:"address"`
+	Path                  string `json:"path" yaml:"path"`
+	WSPath                string `json:"ws_path" yaml:"ws_path"`
+	TimeoutMS             int64  `json:"timeout_ms" yaml:"timeout_ms"`
+	CertFile              string `json:"cert_file" yaml:"cert_file"`
+	KeyFile               string `json:"key_file" yaml:"key_file"`
+	PropagateTraceContext bool   `json:"propagate_trace_context" yaml:"propagate_trace_context"`
 }
 
 // NewHTTPServerConfig creates a new HTTPServerConfig with default values.
 func NewHTTPServerConfig() HTTPServerConfig {
 	return HTTPServerConfig{
-		Address:   "",
-		Path:      "/post",
-		WSPath:    "/post/ws",
-		TimeoutMS: 5000,
-		CertFile:  "",
-		KeyFile:   "",
+		Address:               "",
+		Path:                  "/post",
+		WSPath:                "/post/ws",
+		TimeoutMS:             5000,
+		CertFile:              "",
+		KeyFile:               "",
+		PropagateTraceContext: false,
 	}
 }
 
@@ -248,6 +253,9 @@ func (h *HTTPServer) postHandler(w http.ResponseWriter, r *http.Request) {
 	for _, c := range r.Cookies() {
 		meta.Set(c.Name, c.Value)
 	}
+	if h.conf.HTTPServer.PropagateTraceContext {
+		tracecontext.Extract(r.Header.Get, meta)
+	}
 	message.SetAllMetadata(msg, meta)
 
 	resChan := make(chan types.Response)
@@ -336,6 +344,9 @@ func (h *HTTPServer) wsHandler(w http.ResponseWriter, r *http.Request) {
 		for _, c := range r.Cookies() {
 			meta.Set(c.Name, c.Value)
 		}
+		if h.conf.HTTPServer.PropagateTraceContext {
+			tracecontext.Extract(r.Header.Get, meta)
+		}
 
 		select {
 		case h.transactions <- types.NewTransaction(msg, resChan):
@@ -144,6 +144,64 @@ func TestHTTPBasic(t *testing.T) {
 	*/
 }
 
+func TestHTTPTraceContext(t *testing.T) {
+	t.Parallel()
+
+	parent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	conf := NewConfig()
+	conf.HTTPServer.Address = "localhost:1244"
+	conf.HTTPServer.Path = "/testpost"
+	conf.HTTPServer.PropagateTraceContext = true
+
+	h, err := NewHTTPServer(conf, nil, log.Noop(), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	<-time.After(time.Millisecond * 1000)
+
+	go func() {
+		req, err := http.NewRequest(
+			"POST", "http://localhost:1244/testpost",
+			bytes.NewBuffer([]byte("test")),
+		)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/octet-stream")
+		req.Header.Set("Traceparent", parent)
+		req.Header.Set("Tracestate", "foo=bar")
+		if res, err := http.DefaultClient.Do(req); err != nil {
+			t.Error(err)
+		} else if res.StatusCode != 200 {
+			t.Errorf("Wrong error code returned: %v", res.StatusCode)
+		}
+	}()
+
+	var ts types.Transaction
+	select {
+	case ts = <-h.TransactionChan():
+		meta := ts.Payload.Get(0).Metadata()
+		if exp, act := parent, meta.Get("traceparent"); exp != act {
+			t.Errorf("Wrong traceparent: %v != %v", act, exp)
+		}
+		if exp, act := "foo=bar", meta.Get("tracestate"); exp != act {
+			t.Errorf("Wrong tracestate: %v != %v", act, exp)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for message")
+	}
+	select {
+	case ts.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for response")
+	}
+
+	h.CloseAsync()
+}
+
 func TestHTTPBadRequests(t *testing.T) {
 	t.Parallel()
 
@@ -25,7 +25,9 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/kafka/sasl"
 	"github.com/Jeffail/benthos/lib/util/tls"
+	"github.com/Jeffail/benthos/lib/util/tracecontext"
 )
 
 //------------------------------------------------------------------------------
@@ -34,12 +36,35 @@ func init() {
 	Constructors[TypeKafkaBalanced] = TypeSpec{
 		constructor: NewKafkaBalanced,
 		description: `
-Connects to a kafka (0.9+) server. Offsets are managed within kafka as per the
+Connects to a kafka (0.10.2+) server. Offsets are managed within kafka as per the
 consumer group (set via config), and partitions are automatically balanced
 across any members of the consumer group.
 
+### Partition Balancing
+
+The ` + "`partition_strategy`" + ` field determines how partitions are assigned
+to members of the group, and can be ` + "`range`" + ` (the default),
+` + "`roundrobin`" + ` or ` + "`sticky`" + `. The range strategy assigns
+contiguous partitions of each topic separately, which can leave members idle
+when consuming from multiple topics or when there are more members than
+partitions of a topic. The roundrobin strategy spreads partitions of all topics
+evenly across members. The sticky strategy also balances partitions evenly, but
+keeps as many existing assignments as possible when members join or leave. The
+` + "`cooperative-sticky`" + ` strategy is not supported, as the underlying
+client does not implement cooperative rebalancing.
+
+When a rebalance occurs this input stops consuming from its partitions, and then
+waits for messages already read from each partition to be acknowledged before
+committing their offsets and releasing the partition. Partitions without
+messages in flight are released immediately. If the messages of a partition are
+not acknowledged within ` + "`rebalance_drain_ms`" + ` the partition is released
+anyway, and those messages are redelivered to the new owner of the partition.
+The same applies when the input is shut down.
+
 ` + tls.Documentation + `
 
+` + sasl.Documentation + `
+
 ### Metadata
 
 This input adds the following metadata fields to each message:
@@ -54,7 +79,9 @@ This input adds the following metadata fields to each message:
 ` + "```" + `
 
 You can access these metadata fields using
-[function interpolation](../config_interpolation.md#metadata).`,
+[function interpolation](../config_interpolation.md#metadata).
+
+` + tracecontext.Documentation,
 	}
 }
 
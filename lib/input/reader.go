@@ -26,6 +26,7 @@ import (
 
 	"github.com/Jeffail/benthos/lib/input/reader"
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/throttle"
@@ -33,9 +34,16 @@ import (
 
 //------------------------------------------------------------------------------
 
+// pendingAgeInterval is the period at which the age of a message that has not
+// yet been acknowledged is reported.
+const pendingAgeInterval = time.Millisecond * 100
+
+//------------------------------------------------------------------------------
+
 // Reader is an input implementation that reads messages from a reader.Type.
 type Reader struct {
-	running int32
+	running   int32
+	connected int32
 
 	typeStr string
 	reader  reader.Type
@@ -106,12 +114,25 @@ func (r *Reader) loop() {
 		mLostConnF    = r.stats.GetCounter("input.connection.lost")
 		mLatency      = r.stats.GetTimer("input." + r.typeStr + ".latency")
 		mLatencyF     = r.stats.GetTimer("input.latency")
+		mSize         = r.stats.GetTimer("input." + r.typeStr + ".size")
+		mSizeF        = r.stats.GetTimer("input.size")
+		mPendingAge   = r.stats.GetGauge("input." + r.typeStr + ".pending.age")
+		mPendingAgeF  = r.stats.GetGauge("input.pending.age")
 	)
 
+	ageTicker := time.NewTicker(pendingAgeInterval)
+	setPendingAge := func(age int64) {
+		mPendingAge.Set(age)
+		mPendingAgeF.Set(age)
+	}
+
 	defer func() {
 		err := r.reader.WaitForClose(time.Second)
 		for ; err != nil; err = r.reader.WaitForClose(time.Second) {
 		}
+		ageTicker.Stop()
+		setPendingAge(0)
+		atomic.StoreInt32(&r.connected, 0)
 		mRunning.Decr(1)
 		mRunningF.Decr(1)
 
@@ -138,6 +159,7 @@ func (r *Reader) loop() {
 		}
 	}
 	mConn.Incr(1)
+	atomic.StoreInt32(&r.connected, 1)
 	mConnF.Incr(1)
 
 	for atomic.LoadInt32(&r.running) == 1 {
@@ -146,6 +168,7 @@ func (r *Reader) loop() {
 		// If our reader says it is not connected.
 		if err == types.ErrNotConnected {
 			mLostConn.Incr(1)
+			atomic.StoreInt32(&r.connected, 0)
 			mLostConnF.Incr(1)
 
 			// Continue to try to reconnect while still active.
@@ -165,6 +188,7 @@ func (r *Reader) loop() {
 					}
 				} else if msg, err = r.reader.Read(); err != types.ErrNotConnected {
 					mConn.Incr(1)
+					atomic.StoreInt32(&r.connected, 1)
 					mConnF.Incr(1)
 					r.connThrot.Reset()
 					break
@@ -193,41 +217,60 @@ func (r *Reader) loop() {
 			mCountF.Incr(1)
 			mReadSuccess.Incr(1)
 			mReadSuccessF.Incr(1)
+			size := int64(message.GetAllBytesLen(msg))
+			mSize.Timing(size)
+			mSizeF.Timing(size)
 		}
 
-		select {
-		case r.transactions <- types.NewTransaction(msg, r.responses):
-		case <-r.closeChan:
-			return
+		tran := types.NewTransaction(msg, r.responses)
+	sendLoop:
+		for {
+			select {
+			case r.transactions <- tran:
+				break sendLoop
+			case <-ageTicker.C:
+				setPendingAge(time.Since(msg.CreatedAt()).Nanoseconds())
+			case <-r.closeChan:
+				return
+			}
 		}
 
-		select {
-		case res, open := <-r.responses:
-			if !open {
+		var res types.Response
+	resLoop:
+		for {
+			var open bool
+			select {
+			case res, open = <-r.responses:
+				if !open {
+					return
+				}
+				break resLoop
+			case <-ageTicker.C:
+				setPendingAge(time.Since(msg.CreatedAt()).Nanoseconds())
+			case <-r.closeChan:
 				return
 			}
-			if res.Error() != nil {
-				mSendError.Incr(1)
-				mSendErrorF.Incr(1)
+		}
+		if res.Error() != nil {
+			mSendError.Incr(1)
+			mSendErrorF.Incr(1)
+		} else {
+			mSendSuccess.Incr(1)
+			mSendSuccessF.Incr(1)
+		}
+		if res.Error() != nil || !res.SkipAck() {
+			if err = r.reader.Acknowledge(res.Error()); err != nil {
+				mAckError.Incr(1)
+				mAckErrorF.Incr(1)
 			} else {
-				mSendSuccess.Incr(1)
-				mSendSuccessF.Incr(1)
-			}
-			if res.Error() != nil || !res.SkipAck() {
-				if err = r.reader.Acknowledge(res.Error()); err != nil {
-					mAckError.Incr(1)
-					mAckErrorF.Incr(1)
-				} else {
-					tTaken := time.Since(msg.CreatedAt()).Nanoseconds()
-					mLatency.Timing(tTaken)
-					mLatencyF.Timing(tTaken)
-					mAckSuccess.Incr(1)
-					mAckSuccessF.Incr(1)
-				}
+				tTaken := time.Since(msg.CreatedAt()).Nanoseconds()
+				mLatency.Timing(tTaken)
+				mLatencyF.Timing(tTaken)
+				mAckSuccess.Incr(1)
+				mAckSuccessF.Incr(1)
 			}
-		case <-r.closeChan:
-			return
 		}
+		setPendingAge(0)
 	}
 }
 
@@ -237,6 +280,12 @@ func (r *Reader) TransactionChan() <-chan types.Transaction {
 	return r.transactions
 }
 
+// Connected returns a boolean indicating whether this input is currently
+// connected to its target.
+func (r *Reader) Connected() bool {
+	return atomic.LoadInt32(&r.connected) == 1
+}
+
 // CloseAsync shuts down the Reader input and stops processing requests.
 func (r *Reader) CloseAsync() {
 	if atomic.CompareAndSwapInt32(&r.running, 1, 0) {
@@ -26,6 +26,7 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/tracer"
 	"github.com/Jeffail/benthos/lib/types"
 	sess "github.com/Jeffail/benthos/lib/util/aws/session"
 	"github.com/aws/aws-sdk-go/aws"
@@ -35,6 +36,10 @@ import (
 
 //------------------------------------------------------------------------------
 
+// sqsTraceHeaderAttribute is the system attribute of an SQS message that
+// contains an AWS X-Ray trace header.
+const sqsTraceHeaderAttribute = "AWSTraceHeader"
+
 // AmazonSQSConfig contains configuration values for the input type.
 type AmazonSQSConfig struct {
 	sess.Config `json:",inline" yaml:",inline"`
@@ -108,6 +113,7 @@ func (a *AmazonSQS) Read() (types.Message, error) {
 		QueueUrl:            aws.String(a.conf.URL),
 		MaxNumberOfMessages: aws.Int64(1),
 		WaitTimeSeconds:     aws.Int64(a.conf.TimeoutS),
+		AttributeNames:      []*string{aws.String(sqsTraceHeaderAttribute)},
 	})
 	if err != nil {
 		return nil, err
@@ -128,7 +134,11 @@ func (a *AmazonSQS) Read() (types.Message, error) {
 		}
 
 		if sqsMsg.Body != nil {
-			msg.Append(message.NewPart([]byte(*sqsMsg.Body)))
+			part := message.NewPart([]byte(*sqsMsg.Body))
+			if header, exists := sqsMsg.Attributes[sqsTraceHeaderAttribute]; exists && header != nil {
+				part.Metadata().Set(tracer.XRayTraceHeaderKey, *header)
+			}
+			msg.Append(part)
 		}
 	}
 
@@ -31,7 +31,9 @@ import (
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/kafka/sasl"
 	btls "github.com/Jeffail/benthos/lib/util/tls"
+	"github.com/Jeffail/benthos/lib/util/tracecontext"
 	"github.com/Shopify/sarama"
 )
 
@@ -39,29 +41,33 @@ import (
 
 // KafkaConfig contains configuration fields for the Kafka input type.
 type KafkaConfig struct {
-	Addresses       []string    `json:"addresses" yaml:"addresses"`
-	ClientID        string      `json:"client_id" yaml:"client_id"`
-	ConsumerGroup   string      `json:"consumer_group" yaml:"consumer_group"`
-	CommitPeriodMS  int         `json:"commit_period_ms" yaml:"commit_period_ms"`
-	Topic           string      `json:"topic" yaml:"topic"`
-	Partition       int32       `json:"partition" yaml:"partition"`
-	StartFromOldest bool        `json:"start_from_oldest" yaml:"start_from_oldest"`
-	TargetVersion   string      `json:"target_version" yaml:"target_version"`
-	TLS             btls.Config `json:"tls" yaml:"tls"`
+	Addresses             []string    `json:"addresses" yaml:"addresses"`
+	ClientID              string      `json:"client_id" yaml:"client_id"`
+	ConsumerGroup         string      `json:"consumer_group" yaml:"consumer_group"`
+	CommitPeriodMS        int         `json:"commit_period_ms" yaml:"commit_period_ms"`
+	Topic                 string      `json:"topic" yaml:"topic"`
+	Partition             int32       `json:"partition" yaml:"partition"`
+	StartFromOldest       bool        `json:"start_from_oldest" yaml:"start_from_oldest"`
+	TargetVersion         string      `json:"target_version" yaml:"target_version"`
+	TLS                   btls.Config `json:"tls" yaml:"tls"`
+	SASL                  sasl.Config `json:"sasl" yaml:"sasl"`
+	PropagateTraceContext bool        `json:"propagate_trace_context" yaml:"propagate_trace_context"`
 }
 
 // NewKafkaConfig creates a new KafkaConfig with default values.
 func NewKafkaConfig() KafkaConfig {
 	return KafkaConfig{
-		Addresses:       []string{"localhost:9092"},
-		ClientID:        "benthos_kafka_input",
-		ConsumerGroup:   "benthos_consumer_group",
-		CommitPeriodMS:  1000,
-		Topic:           "benthos_stream",
-		Partition:       0,
-		StartFromOldest: true,
-		TargetVersion:   sarama.V1_0_0_0.String(),
-		TLS:             btls.NewConfig(),
+		Addresses:             []string{"localhost:9092"},
+		ClientID:              "benthos_kafka_input",
+		ConsumerGroup:         "benthos_consumer_group",
+		CommitPeriodMS:        1000,
+		Topic:                 "benthos_stream",
+		Partition:             0,
+		StartFromOldest:       true,
+		TargetVersion:         sarama.V1_0_0_0.String(),
+		TLS:                   btls.NewConfig(),
+		SASL:                  sasl.NewConfig(),
+		PropagateTraceContext: false,
 	}
 }
 
@@ -110,6 +116,9 @@ func NewKafka(
 			return nil, err
 		}
 	}
+	if err := conf.SASL.Validate(); err != nil {
+		return nil, err
+	}
 
 	var err error
 	if k.version, err = sarama.ParseKafkaVersion(conf.TargetVersion); err != nil {
@@ -186,10 +195,13 @@ func (k *Kafka) Connect() error {
 	if k.conf.TLS.Enabled {
 		config.Net.TLS.Config = k.tlsConf
 	}
+	if err := k.conf.SASL.Apply(config); err != nil {
+		return err
+	}
 
 	k.client, err = sarama.NewClient(k.addresses, config)
 	if err != nil {
-		return err
+		return k.conf.SASL.ConnectErr(err)
 	}
 
 	k.coordinator, err = k.client.Coordinator(k.conf.ConsumerGroup)
@@ -288,6 +300,9 @@ func (k *Kafka) Read() (types.Message, error) {
 	for _, hdr := range data.Headers {
 		meta.Set(string(hdr.Key), string(hdr.Value))
 	}
+	if k.conf.PropagateTraceContext {
+		tracecontext.Extract(kafkaHeaderGetter(data.Headers), meta)
+	}
 
 	return msg, nil
 }
@@ -323,7 +338,7 @@ func (k *Kafka) commit() error {
 
 	commitReq := sarama.OffsetCommitRequest{}
 	commitReq.ConsumerGroup = k.conf.ConsumerGroup
-	commitReq.AddBlock(k.conf.Topic, k.conf.Partition, k.offset, 0, "")
+	commitReq.AddBlock(k.conf.Topic, k.conf.Partition, k.offset, 0, 0, "")
 
 	commitRes, err := coordinator.CommitOffset(&commitReq)
 	if err == nil {
@@ -358,6 +373,19 @@ func (k *Kafka) commit() error {
 	return nil
 }
 
+// kafkaHeaderGetter returns a function that performs case insensitive lookups
+// of Kafka record header values.
+func kafkaHeaderGetter(headers []*sarama.RecordHeader) func(string) string {
+	return func(key string) string {
+		for _, hdr := range headers {
+			if strings.EqualFold(string(hdr.Key), key) {
+				return string(hdr.Value)
+			}
+		}
+		return ""
+	}
+}
+
 // CloseAsync shuts down the Kafka input and stops processing requests.
 func (k *Kafka) CloseAsync() {
 	go k.closeClients()
@@ -21,7 +21,10 @@
 package reader
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 	"sync"
@@ -31,61 +34,121 @@ import (
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/clock"
+	"github.com/Jeffail/benthos/lib/util/kafka/sasl"
 	btls "github.com/Jeffail/benthos/lib/util/tls"
+	"github.com/Jeffail/benthos/lib/util/tracecontext"
 	"github.com/Shopify/sarama"
-	cluster "github.com/bsm/sarama-cluster"
 )
 
 //------------------------------------------------------------------------------
 
 // KafkaBalancedConfig contains configuration for the KafkaBalanced input type.
 type KafkaBalancedConfig struct {
-	Addresses       []string    `json:"addresses" yaml:"addresses"`
-	ClientID        string      `json:"client_id" yaml:"client_id"`
-	ConsumerGroup   string      `json:"consumer_group" yaml:"consumer_group"`
-	CommitPeriodMS  int         `json:"commit_period_ms" yaml:"commit_period_ms"`
-	Topics          []string    `json:"topics" yaml:"topics"`
-	StartFromOldest bool        `json:"start_from_oldest" yaml:"start_from_oldest"`
-	TargetVersion   string      `json:"target_version" yaml:"target_version"`
-	TLS             btls.Config `json:"tls" yaml:"tls"`
+	Addresses             []string    `json:"addresses" yaml:"addresses"`
+	ClientID              string      `json:"client_id" yaml:"client_id"`
+	ConsumerGroup         string      `json:"consumer_group" yaml:"consumer_group"`
+	CommitPeriodMS        int         `json:"commit_period_ms" yaml:"commit_period_ms"`
+	Topics                []string    `json:"topics" yaml:"topics"`
+	StartFromOldest       bool        `json:"start_from_oldest" yaml:"start_from_oldest"`
+	TargetVersion         string      `json:"target_version" yaml:"target_version"`
+	PartitionStrategy     string      `json:"partition_strategy" yaml:"partition_strategy"`
+	RebalanceDrainMS      int         `json:"rebalance_drain_ms" yaml:"rebalance_drain_ms"`
+	TLS                   btls.Config `json:"tls" yaml:"tls"`
+	SASL                  sasl.Config `json:"sasl" yaml:"sasl"`
+	PropagateTraceContext bool        `json:"propagate_trace_context" yaml:"propagate_trace_context"`
 }
 
 // NewKafkaBalancedConfig creates a new KafkaBalancedConfig with default values.
 func NewKafkaBalancedConfig() KafkaBalancedConfig {
 	return KafkaBalancedConfig{
-		Addresses:       []string{"localhost:9092"},
-		ClientID:        "benthos_kafka_input",
-		ConsumerGroup:   "benthos_consumer_group",
-		CommitPeriodMS:  1000,
-		Topics:          []string{"benthos_stream"},
-		StartFromOldest: true,
-		TargetVersion:   sarama.V1_0_0_0.String(),
-		TLS:             btls.NewConfig(),
+		Addresses:             []string{"localhost:9092"},
+		ClientID:              "benthos_kafka_input",
+		ConsumerGroup:         "benthos_consumer_group",
+		CommitPeriodMS:        1000,
+		Topics:                []string{"benthos_stream"},
+		StartFromOldest:       true,
+		TargetVersion:         sarama.V1_0_0_0.String(),
+		PartitionStrategy:     "range",
+		RebalanceDrainMS:      100,
+		TLS:                   btls.NewConfig(),
+		SASL:                  sasl.NewConfig(),
+		PropagateTraceContext: false,
 	}
 }
 
 //------------------------------------------------------------------------------
 
+func strToPartitionStrategy(str string) (sarama.BalanceStrategy, error) {
+	switch str {
+	case "range":
+		return sarama.BalanceStrategyRange, nil
+	case "roundrobin":
+		return sarama.BalanceStrategyRoundRobin, nil
+	case "sticky":
+		return sarama.BalanceStrategySticky, nil
+	case "cooperative-sticky":
+		return nil, errors.New("partition strategy cooperative-sticky is not supported as the kafka client does not implement cooperative rebalancing")
+	}
+	return nil, fmt.Errorf("partition strategy not recognised: %v", str)
+}
+
+//------------------------------------------------------------------------------
+
+type kafkaTopicPartition struct {
+	topic     string
+	partition int32
+}
+
+// kafkaBalancedOffset is the latest offset of a partition that has been read
+// but not yet acknowledged, along with the session that claims the partition.
+type kafkaBalancedOffset struct {
+	session sarama.ConsumerGroupSession
+	offset  int64
+}
+
+// kafkaBalancedRecord is a message consumed from a partition claim, which is
+// registered as outstanding by Read before the claim continues.
+type kafkaBalancedRecord struct {
+	msg        *sarama.ConsumerMessage
+	session    sarama.ConsumerGroupSession
+	registered chan struct{}
+}
+
 // KafkaBalanced is an input type that reads from a Kafka cluster by balancing
 // partitions across other consumers of the same consumer group.
 type KafkaBalanced struct {
-	consumer *cluster.Consumer
 	version  sarama.KafkaVersion
-	cMut     sync.Mutex
+	strategy sarama.BalanceStrategy
+
+	cancelFn     context.CancelFunc
+	recordChan   chan kafkaBalancedRecord
+	consumerDone chan struct{}
+	cMut         sync.Mutex
 
 	tlsConf *tls.Config
 
-	offsetLastCommitted time.Time
-	offsets             map[string]map[int32]int64
+	// outstanding holds the offsets of each partition that have been read but
+	// not yet acknowledged, and ackSignal is closed and replaced each time
+	// they are acknowledged.
+	outstanding map[kafkaTopicPartition]kafkaBalancedOffset
+	ackSignal   chan struct{}
+	offsetsMut  sync.Mutex
+
+	clock clock.Clock
 
 	mRcvErr     metrics.StatCounter
 	mRebalanced metrics.StatCounter
+	mDrainErr   metrics.StatCounter
 
 	addresses []string
 	topics    []string
 	conf      KafkaBalancedConfig
 	stats     metrics.Type
 	log       log.Modular
+
+	closeOnce sync.Once
+	closeChan chan struct{}
 }
 
 // NewKafkaBalanced creates a new KafkaBalanced input type.
@@ -97,8 +160,12 @@ func NewKafkaBalanced(
 		stats:       stats,
 		mRcvErr:     stats.GetCounter("input.kafka_balanced.recv.error"),
 		mRebalanced: stats.GetCounter("input.kafka_balanced.rebalanced"),
-		offsets:     map[string]map[int32]int64{},
+		mDrainErr:   stats.GetCounter("input.kafka_balanced.drain.error"),
+		outstanding: map[kafkaTopicPartition]kafkaBalancedOffset{},
+		ackSignal:   make(chan struct{}),
+		clock:       clock.Real(),
 		log:         log.NewModule(".input.kafka_balanced"),
+		closeChan:   make(chan struct{}),
 	}
 	if conf.TLS.Enabled {
 		var err error
@@ -106,6 +173,9 @@ func NewKafkaBalanced(
 			return nil, err
 		}
 	}
+	if err := conf.SASL.Validate(); err != nil {
+		return nil, err
+	}
 	for _, addr := range conf.Addresses {
 		for _, splitAddr := range strings.Split(addr, ",") {
 			if len(splitAddr) > 0 {
@@ -124,28 +194,28 @@ func NewKafkaBalanced(
 	if k.version, err = sarama.ParseKafkaVersion(conf.TargetVersion); err != nil {
 		return nil, err
 	}
+	if !k.version.IsAtLeast(sarama.V0_10_2_0) {
+		return nil, errors.New("consumer groups require a target_version of at least 0.10.2.0")
+	}
+	if k.strategy, err = strToPartitionStrategy(conf.PartitionStrategy); err != nil {
+		return nil, err
+	}
+	if conf.RebalanceDrainMS <= 0 {
+		return nil, errors.New("rebalance_drain_ms must be greater than zero")
+	}
 	return &k, nil
 }
 
 //------------------------------------------------------------------------------
 
-// closeClients closes the kafka clients, this interrupts Read().
+// closeClients cancels the consumer group session, which revokes all claims
+// and eventually interrupts Read().
 func (k *KafkaBalanced) closeClients() {
 	k.cMut.Lock()
 	defer k.cMut.Unlock()
-	if k.consumer != nil {
-		k.consumer.CommitOffsets()
-		k.consumer.Close()
-
-		// Drain all channels
-		for range k.consumer.Messages() {
-		}
-		for range k.consumer.Notifications() {
-		}
-		for range k.consumer.Errors() {
-		}
-
-		k.consumer = nil
+	if k.cancelFn != nil {
+		k.cancelFn()
+		k.cancelFn = nil
 	}
 }
 
@@ -156,92 +226,192 @@ func (k *KafkaBalanced) Connect() error {
 	k.cMut.Lock()
 	defer k.cMut.Unlock()
 
-	if k.consumer != nil {
+	select {
+	case <-k.closeChan:
+		return types.ErrTypeClosed
+	default:
+	}
+
+	if k.recordChan != nil {
 		return nil
 	}
 
-	config := cluster.NewConfig()
+	config := sarama.NewConfig()
 	config.ClientID = k.conf.ClientID
 	config.Net.DialTimeout = time.Second
 	config.Version = k.version
 	config.Consumer.Return.Errors = true
-	config.Group.Return.Notifications = true
+	config.Consumer.Offsets.AutoCommit.Interval = time.Millisecond * time.Duration(k.conf.CommitPeriodMS)
+	config.Consumer.Group.Rebalance.Strategy = k.strategy
 	config.Net.TLS.Enable = k.conf.TLS.Enabled
 	if k.conf.TLS.Enabled {
 		config.Net.TLS.Config = k.tlsConf
 	}
+	if err := k.conf.SASL.Apply(config); err != nil {
+		return err
+	}
 
 	if k.conf.StartFromOldest {
 		config.Consumer.Offsets.Initial = sarama.OffsetOldest
 	}
 
-	var consumer *cluster.Consumer
-	var err error
-
-	if consumer, err = cluster.NewConsumer(
-		k.addresses,
-		k.conf.ConsumerGroup,
-		k.topics,
-		config,
-	); err != nil {
-		return err
+	group, err := sarama.NewConsumerGroup(k.addresses, k.conf.ConsumerGroup, config)
+	if err != nil {
+		return k.conf.SASL.ConnectErr(err)
 	}
 
 	go func() {
+		for err := range group.Errors() {
+			if err != nil {
+				k.log.Errorf("KafkaBalanced message recv error: %v\n", err)
+				k.mRcvErr.Incr(1)
+			}
+		}
+	}()
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	recordChan := make(chan kafkaBalancedRecord)
+	consumerDone := make(chan struct{})
+
+	go func() {
+		defer func() {
+			group.Close()
+			close(recordChan)
+			close(consumerDone)
+		}()
+		handler := &kafkaBalancedHandler{k: k, recordChan: recordChan}
 		for {
-			select {
-			case err, open := <-consumer.Errors():
-				if !open {
-					return
-				}
-				if err != nil {
-					k.log.Errorf("KafkaBalanced message recv error: %v\n", err)
+			// Consume blocks for the lifetime of a session, which ends on each
+			// rebalance.
+			if err := group.Consume(ctx, k.topics, handler); err != nil {
+				if ctx.Err() == nil {
+					k.log.Errorf("KafkaBalanced consumer group error: %v\n", err)
 					k.mRcvErr.Incr(1)
 				}
-			case _, open := <-consumer.Notifications():
-				if !open {
-					return
-				}
-				k.mRebalanced.Incr(1)
+				return
+			}
+			if ctx.Err() != nil {
+				return
 			}
 		}
 	}()
 
-	k.consumer = consumer
+	k.cancelFn = cancelFn
+	k.recordChan = recordChan
+	k.consumerDone = consumerDone
 	k.log.Infof("Receiving KafkaBalanced messages from addresses: %s\n", k.addresses)
 	return nil
 }
 
-func (k *KafkaBalanced) setOffset(topic string, partition int32, offset int64) {
-	var topicMap map[int32]int64
-	var exists bool
-	if topicMap, exists = k.offsets[topic]; !exists {
-		topicMap = map[int32]int64{}
-		k.offsets[topic] = topicMap
+//------------------------------------------------------------------------------
+
+// kafkaBalancedHandler implements sarama.ConsumerGroupHandler, forwarding the
+// messages of each claim to Read.
+type kafkaBalancedHandler struct {
+	k          *KafkaBalanced
+	recordChan chan<- kafkaBalancedRecord
+}
+
+// Setup is run at the beginning of a new session, before ConsumeClaim.
+func (h *kafkaBalancedHandler) Setup(sess sarama.ConsumerGroupSession) error {
+	h.k.mRebalanced.Incr(1)
+	h.k.log.Infof("Rebalanced partitions, claimed: %v\n", sess.Claims())
+	return nil
+}
+
+// Cleanup is run at the end of a session, once all ConsumeClaim goroutines
+// have exited.
+func (h *kafkaBalancedHandler) Cleanup(sess sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// ConsumeClaim forwards the messages of a claim to Read until the claim is
+// revoked, and then waits for the messages already read to be acknowledged
+// before returning, which allows their offsets to be committed before the
+// partition is released.
+func (h *kafkaBalancedHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	tp := kafkaTopicPartition{topic: claim.Topic(), partition: claim.Partition()}
+	registered := make(chan struct{})
+	for {
+		msg, open := <-claim.Messages()
+		if !open {
+			h.k.drain(tp)
+			return nil
+		}
+		select {
+		case h.recordChan <- kafkaBalancedRecord{
+			msg:        msg,
+			session:    sess,
+			registered: registered,
+		}:
+			<-registered
+		case <-sess.Context().Done():
+			// The message was not read and will be consumed again by the
+			// next owner of the partition.
+		}
+	}
+}
+
+// drain blocks until the messages read from a partition have been
+// acknowledged, or until rebalance_drain_ms has elapsed, after which any
+// outstanding offsets are abandoned.
+func (k *KafkaBalanced) drain(tp kafkaTopicPartition) {
+	timeout := k.clock.After(time.Millisecond * time.Duration(k.conf.RebalanceDrainMS))
+	for {
+		k.offsetsMut.Lock()
+		_, pending := k.outstanding[tp]
+		ackSignal := k.ackSignal
+		if !pending {
+			k.offsetsMut.Unlock()
+			return
+		}
+		k.offsetsMut.Unlock()
+
+		select {
+		case <-ackSignal:
+		case <-timeout:
+			k.offsetsMut.Lock()
+			delete(k.outstanding, tp)
+			k.offsetsMut.Unlock()
+
+			k.mDrainErr.Incr(1)
+			k.log.Warnf("Released partition %v of topic %v with unacknowledged messages\n", tp.partition, tp.topic)
+			return
+		}
 	}
-	topicMap[partition] = offset
 }
 
+//------------------------------------------------------------------------------
+
 // Read attempts to read a message from a KafkaBalanced topic.
 func (k *KafkaBalanced) Read() (types.Message, error) {
-	var consumer *cluster.Consumer
-
 	k.cMut.Lock()
-	if k.consumer != nil {
-		consumer = k.consumer
-	}
+	recordChan := k.recordChan
 	k.cMut.Unlock()
 
-	if consumer == nil {
+	if recordChan == nil {
 		return nil, types.ErrNotConnected
 	}
 
-	data, open := <-consumer.Messages()
+	record, open := <-recordChan
 	if !open {
-		k.closeClients()
+		k.cMut.Lock()
+		if k.recordChan == recordChan {
+			k.recordChan = nil
+		}
+		k.cMut.Unlock()
 		return nil, types.ErrNotConnected
 	}
 
+	data := record.msg
+	k.offsetsMut.Lock()
+	k.outstanding[kafkaTopicPartition{topic: data.Topic, partition: data.Partition}] = kafkaBalancedOffset{
+		session: record.session,
+		offset:  data.Offset,
+	}
+	k.offsetsMut.Unlock()
+	record.registered <- struct{}{}
+
 	msg := message.New([][]byte{data.Value})
 
 	meta := msg.Get(0).Metadata()
@@ -253,53 +423,53 @@ func (k *KafkaBalanced) Read() (types.Message, error) {
 	for _, hdr := range data.Headers {
 		meta.Set(string(hdr.Key), string(hdr.Value))
 	}
-
-	k.setOffset(data.Topic, data.Partition, data.Offset)
+	if k.conf.PropagateTraceContext {
+		tracecontext.Extract(kafkaHeaderGetter(data.Headers), meta)
+	}
 	return msg, nil
 }
 
-// Acknowledge instructs whether the current offset should be committed.
+// Acknowledge instructs whether the offsets of read messages should be marked
+// for commit. Marked offsets are committed periodically according to
+// commit_period_ms, and when partitions are released.
 func (k *KafkaBalanced) Acknowledge(err error) error {
-	if err == nil {
-		k.cMut.Lock()
-		if k.consumer != nil {
-			for topic, v := range k.offsets {
-				for part, offset := range v {
-					k.consumer.MarkPartitionOffset(topic, part, offset, "")
-				}
-			}
-		}
-		k.cMut.Unlock()
-	}
-
-	if time.Since(k.offsetLastCommitted) <
-		(time.Millisecond * time.Duration(k.conf.CommitPeriodMS)) {
+	if err != nil {
 		return nil
 	}
 
-	var commitErr error
-	k.cMut.Lock()
-	if k.consumer != nil {
-		commitErr = k.consumer.CommitOffsets()
-	} else {
-		commitErr = types.ErrNotConnected
-	}
-	k.cMut.Unlock()
-
-	if commitErr == nil {
-		k.offsetLastCommitted = time.Now()
+	k.offsetsMut.Lock()
+	for tp, o := range k.outstanding {
+		o.session.MarkOffset(tp.topic, tp.partition, o.offset+1, "")
+		delete(k.outstanding, tp)
 	}
-
-	return commitErr
+	close(k.ackSignal)
+	k.ackSignal = make(chan struct{})
+	k.offsetsMut.Unlock()
+	return nil
 }
 
 // CloseAsync shuts down the KafkaBalanced input and stops processing requests.
 func (k *KafkaBalanced) CloseAsync() {
-	go k.closeClients()
+	k.closeOnce.Do(func() {
+		close(k.closeChan)
+	})
+	k.closeClients()
 }
 
 // WaitForClose blocks until the KafkaBalanced input has closed down.
 func (k *KafkaBalanced) WaitForClose(timeout time.Duration) error {
+	k.cMut.Lock()
+	consumerDone := k.consumerDone
+	k.cMut.Unlock()
+
+	if consumerDone == nil {
+		return nil
+	}
+	select {
+	case <-consumerDone:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
 	return nil
 }
 
@@ -0,0 +1,380 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/util/clock"
+	"github.com/Shopify/sarama"
+)
+
+//------------------------------------------------------------------------------
+
+func TestKafkaBalancedPartitionStrategy(t *testing.T) {
+	tests := map[string]sarama.BalanceStrategy{
+		"range":      sarama.BalanceStrategyRange,
+		"roundrobin": sarama.BalanceStrategyRoundRobin,
+		"sticky":     sarama.BalanceStrategySticky,
+	}
+	for str, exp := range tests {
+		conf := NewKafkaBalancedConfig()
+		conf.PartitionStrategy = str
+
+		k, err := NewKafkaBalanced(conf, log.Noop(), metrics.DudType{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if k.strategy != exp {
+			t.Errorf("Wrong strategy: %v != %v", k.strategy.Name(), exp.Name())
+		}
+	}
+
+	for _, str := range []string{"cooperative-sticky", "nope"} {
+		conf := NewKafkaBalancedConfig()
+		conf.PartitionStrategy = str
+		if _, err := NewKafkaBalanced(conf, log.Noop(), metrics.DudType{}); err == nil {
+			t.Errorf("Expected error from strategy: %v", str)
+		}
+	}
+
+	conf := NewKafkaBalancedConfig()
+	conf.RebalanceDrainMS = 0
+	if _, err := NewKafkaBalanced(conf, log.Noop(), metrics.DudType{}); err == nil {
+		t.Error("Expected error from zero drain period")
+	}
+
+	conf = NewKafkaBalancedConfig()
+	conf.TargetVersion = "0.9.0.0"
+	if _, err := NewKafkaBalanced(conf, log.Noop(), metrics.DudType{}); err == nil {
+		t.Error("Expected error from old target version")
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type mockMarkedOffset struct {
+	topic     string
+	partition int32
+	offset    int64
+}
+
+type mockConsumerGroupSession struct {
+	ctx    context.Context
+	mut    sync.Mutex
+	marked []mockMarkedOffset
+}
+
+func (s *mockConsumerGroupSession) Claims() map[string][]int32 { return nil }
+func (s *mockConsumerGroupSession) MemberID() string           { return "foo_member" }
+func (s *mockConsumerGroupSession) GenerationID() int32        { return 1 }
+func (s *mockConsumerGroupSession) Context() context.Context   { return s.ctx }
+
+func (s *mockConsumerGroupSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	s.mut.Lock()
+	s.marked = append(s.marked, mockMarkedOffset{topic, partition, offset})
+	s.mut.Unlock()
+}
+
+func (s *mockConsumerGroupSession) ResetOffset(topic string, partition int32, offset int64, metadata string) {
+}
+
+func (s *mockConsumerGroupSession) Commit() {}
+
+func (s *mockConsumerGroupSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.MarkOffset(msg.Topic, msg.Partition, msg.Offset+1, metadata)
+}
+
+func (s *mockConsumerGroupSession) markedOffsets() []mockMarkedOffset {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return append([]mockMarkedOffset{}, s.marked...)
+}
+
+type mockConsumerGroupClaim struct {
+	msgChan chan *sarama.ConsumerMessage
+}
+
+func (c *mockConsumerGroupClaim) Topic() string                            { return "foo" }
+func (c *mockConsumerGroupClaim) Partition() int32                         { return 0 }
+func (c *mockConsumerGroupClaim) InitialOffset() int64                     { return 5 }
+func (c *mockConsumerGroupClaim) HighWaterMarkOffset() int64               { return 7 }
+func (c *mockConsumerGroupClaim) Messages() <-chan *sarama.ConsumerMessage { return c.msgChan }
+
+// testKafkaBalancedClaim starts consuming a claim of partition 0 of the topic
+// foo with the messages at offsets 5 and 6, and returns a channel that is
+// closed once ConsumeClaim returns.
+func testKafkaBalancedClaim(
+	t *testing.T, k *KafkaBalanced, sess *mockConsumerGroupSession,
+) (*mockConsumerGroupClaim, <-chan struct{}) {
+	t.Helper()
+
+	claim := &mockConsumerGroupClaim{msgChan: make(chan *sarama.ConsumerMessage, 2)}
+	for _, offset := range []int64{5, 6} {
+		claim.msgChan <- &sarama.ConsumerMessage{
+			Topic:     "foo",
+			Partition: 0,
+			Offset:    offset,
+			Value:     []byte("hello world"),
+		}
+	}
+
+	recordChan := make(chan kafkaBalancedRecord)
+	k.recordChan = recordChan
+	handler := &kafkaBalancedHandler{k: k, recordChan: recordChan}
+	if err := handler.Setup(sess); err != nil {
+		t.Fatal(err)
+	}
+
+	doneChan := make(chan struct{})
+	go func() {
+		if err := handler.ConsumeClaim(sess, claim); err != nil {
+			t.Error(err)
+		}
+		close(doneChan)
+	}()
+	return claim, doneChan
+}
+
+func TestKafkaBalancedRebalanceDrain(t *testing.T) {
+	stats := metrics.NewLocal()
+	k, err := NewKafkaBalanced(NewKafkaBalancedConfig(), log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeClock := clock.NewFake(time.Unix(0, 0))
+	k.clock = fakeClock
+
+	sess := &mockConsumerGroupSession{ctx: context.Background()}
+	claim, doneChan := testKafkaBalancedClaim(t, k, sess)
+
+	for _, exp := range []string{"5", "6"} {
+		msg, err := k.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := msg.Get(0).Metadata().Get("kafka_offset"); exp != act {
+			t.Errorf("Wrong offset: %v != %v", act, exp)
+		}
+		if exp == "5" {
+			if err = k.Acknowledge(nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// Revoke the claim while the message at offset 6 is unacknowledged.
+	close(claim.msgChan)
+	fakeClock.BlockUntil(1)
+	select {
+	case <-doneChan:
+		t.Fatal("Claim released before outstanding message was acknowledged")
+	default:
+	}
+
+	if err = k.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-doneChan:
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out")
+	}
+
+	exp := []mockMarkedOffset{{"foo", 0, 6}, {"foo", 0, 7}}
+	if act := sess.markedOffsets(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong marked offsets: %v != %v", act, exp)
+	}
+	if exp, act := int64(1), stats.GetCounters()["input.kafka_balanced.rebalanced"]; exp != act {
+		t.Errorf("Wrong count of rebalances: %v != %v", act, exp)
+	}
+	if act := stats.GetCounters()["input.kafka_balanced.drain.error"]; act != 0 {
+		t.Errorf("Unexpected drain errors: %v", act)
+	}
+}
+
+func TestKafkaBalancedRebalanceDrainTimeout(t *testing.T) {
+	stats := metrics.NewLocal()
+	k, err := NewKafkaBalanced(NewKafkaBalancedConfig(), log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeClock := clock.NewFake(time.Unix(0, 0))
+	k.clock = fakeClock
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	sess := &mockConsumerGroupSession{ctx: ctx}
+	claim, doneChan := testKafkaBalancedClaim(t, k, sess)
+
+	if _, err = k.Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Revoke the claim and let the drain period elapse without an
+	// acknowledgement.
+	cancelFn()
+	close(claim.msgChan)
+	fakeClock.BlockUntil(1)
+	fakeClock.Add(time.Millisecond * time.Duration(k.conf.RebalanceDrainMS))
+	select {
+	case <-doneChan:
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out")
+	}
+
+	// Acknowledging after the partition was released does not mark its
+	// offset.
+	if err = k.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+	if act := sess.markedOffsets(); len(act) > 0 {
+		t.Errorf("Unexpected marked offsets: %v", act)
+	}
+	if exp, act := int64(1), stats.GetCounters()["input.kafka_balanced.drain.error"]; exp != act {
+		t.Errorf("Wrong count of drain errors: %v != %v", act, exp)
+	}
+}
+
+func TestKafkaBalancedRevokedUnread(t *testing.T) {
+	k, err := NewKafkaBalanced(NewKafkaBalancedConfig(), log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.clock = clock.NewFake(time.Unix(0, 0))
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	sess := &mockConsumerGroupSession{ctx: ctx}
+	claim, doneChan := testKafkaBalancedClaim(t, k, sess)
+
+	// Messages that were not read before the session ends are not waited for.
+	cancelFn()
+	close(claim.msgChan)
+	select {
+	case <-doneChan:
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out")
+	}
+	if act := sess.markedOffsets(); len(act) > 0 {
+		t.Errorf("Unexpected marked offsets: %v", act)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// committedOffset returns the offset of a partition within the last offset
+// commit request received by a mock broker, or -1 if none was received.
+func committedOffset(broker *sarama.MockBroker, topic string, partition int32) int64 {
+	offset := int64(-1)
+	for _, rr := range broker.History() {
+		req, ok := interface{}(rr.Request).(*sarama.OffsetCommitRequest)
+		if !ok {
+			continue
+		}
+		if o, _, err := req.Offset(topic, partition); err == nil {
+			offset = o
+		}
+	}
+	return offset
+}
+
+func TestKafkaBalancedCommitOnClose(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("foo", 0, broker.BrokerID()),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "foo_group", broker),
+		"JoinGroupRequest": sarama.NewMockJoinGroupResponse(t).
+			SetGenerationId(1).
+			SetGroupProtocol("range").
+			SetLeaderId("bar_member").
+			SetMemberId("foo_member"),
+		"SyncGroupRequest": sarama.NewMockSyncGroupResponse(t).
+			SetMemberAssignment(&sarama.ConsumerGroupMemberAssignment{
+				Topics: map[string][]int32{"foo": {0}},
+			}),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset("foo_group", "foo", 0, 0, "", sarama.ErrNoError),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("foo", 0, sarama.OffsetOldest, 0).
+			SetOffset("foo", 0, sarama.OffsetNewest, 1),
+		"FetchRequest": sarama.NewMockFetchResponse(t, 1).
+			SetMessage("foo", 0, 0, sarama.StringEncoder("hello world")),
+		"HeartbeatRequest":    sarama.NewMockHeartbeatResponse(t),
+		"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t),
+		"LeaveGroupRequest":   sarama.NewMockLeaveGroupResponse(t),
+	})
+
+	conf := NewKafkaBalancedConfig()
+	conf.Addresses = []string{broker.Addr()}
+	conf.ConsumerGroup = "foo_group"
+	conf.Topics = []string{"foo"}
+	conf.TargetVersion = "0.10.2.0"
+	conf.CommitPeriodMS = 3600000
+
+	k, err := NewKafkaBalanced(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeClock := clock.NewFake(time.Unix(0, 0))
+	k.clock = fakeClock
+
+	if err = k.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := k.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "hello world", string(msg.Get(0).Get()); exp != act {
+		t.Errorf("Wrong message contents: %v != %v", act, exp)
+	}
+
+	// Closing revokes the claim, which waits for the message to be
+	// acknowledged before committing.
+	k.CloseAsync()
+	fakeClock.BlockUntil(1)
+	if act := committedOffset(broker, "foo", 0); act != -1 {
+		t.Errorf("Unexpected offset committed before acknowledgement: %v", act)
+	}
+
+	if err = k.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = k.WaitForClose(time.Second * 5); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := int64(1), committedOffset(broker, "foo", 0); exp != act {
+		t.Errorf("Wrong committed offset: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
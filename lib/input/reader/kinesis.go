@@ -33,7 +33,6 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/kinesis"
 )
 
@@ -42,14 +41,16 @@ import (
 // KinesisConfig is configuration values for the input type.
 type KinesisConfig struct {
 	sess.Config     `json:",inline" yaml:",inline"`
-	Limit           int64  `json:"limit" yaml:"limit"`
-	Stream          string `json:"stream" yaml:"stream"`
-	Shard           string `json:"shard" yaml:"shard"`
-	DynamoDBTable   string `json:"dynamodb_table" yaml:"dynamodb_table"`
-	ClientID        string `json:"client_id" yaml:"client_id"`
-	CommitPeriodMS  int    `json:"commit_period_ms" yaml:"commit_period_ms"`
-	StartFromOldest bool   `json:"start_from_oldest" yaml:"start_from_oldest"`
-	TimeoutMS       int64  `json:"timeout_ms" yaml:"timeout_ms"`
+	Limit           int64                     `json:"limit" yaml:"limit"`
+	Stream          string                    `json:"stream" yaml:"stream"`
+	Shard           string                    `json:"shard" yaml:"shard"`
+	DynamoDBTable   string                    `json:"dynamodb_table" yaml:"dynamodb_table"`
+	Checkpointer    KinesisCheckpointerConfig `json:"checkpointer" yaml:"checkpointer"`
+	ClientID        string                    `json:"client_id" yaml:"client_id"`
+	CommitPeriodMS  int                       `json:"commit_period_ms" yaml:"commit_period_ms"`
+	StartFromOldest bool                      `json:"start_from_oldest" yaml:"start_from_oldest"`
+	TimeoutMS       int64                     `json:"timeout_ms" yaml:"timeout_ms"`
+	EnhancedFanOut  bool                      `json:"enhanced_fan_out" yaml:"enhanced_fan_out"`
 }
 
 // NewKinesisConfig creates a new Config with default values.
@@ -60,10 +61,12 @@ func NewKinesisConfig() KinesisConfig {
 		Stream:          "",
 		Shard:           "0",
 		DynamoDBTable:   "",
+		Checkpointer:    NewKinesisCheckpointerConfig(),
 		ClientID:        "benthos_consumer",
 		CommitPeriodMS:  1000,
 		StartFromOldest: true,
 		TimeoutMS:       5000,
+		EnhancedFanOut:  false,
 	}
 }
 
@@ -74,9 +77,9 @@ func NewKinesisConfig() KinesisConfig {
 type Kinesis struct {
 	conf KinesisConfig
 
-	session *session.Session
-	kinesis *kinesis.Kinesis
-	dynamo  *dynamodb.DynamoDB
+	session      *session.Session
+	kinesis      *kinesis.Kinesis
+	checkpointer KinesisCheckpointer
 
 	offsetLastCommitted time.Time
 	sharditerCommit     string
@@ -115,37 +118,18 @@ func (k *Kinesis) Connect() error {
 		return err
 	}
 
-	dynamo := dynamodb.New(sess)
+	checkpointer, err := newKinesisCheckpointer(k.conf, sess, k.timeout)
+	if err != nil {
+		return err
+	}
 	kin := kinesis.New(sess)
 
-	if len(k.sharditer) == 0 && len(k.conf.DynamoDBTable) > 0 {
-		resp, err := dynamo.GetItemWithContext(
-			aws.BackgroundContext(),
-			&dynamodb.GetItemInput{
-				TableName:      aws.String(k.conf.DynamoDBTable),
-				ConsistentRead: aws.Bool(true),
-				Key: map[string]*dynamodb.AttributeValue{
-					"namespace": {
-						S: aws.String(k.namespace),
-					},
-					"shard_id": {
-						S: aws.String(k.conf.Shard),
-					},
-				},
-			},
-			request.WithResponseReadTimeout(k.timeout),
-		)
-		if err != nil {
-			if err.Error() == request.ErrCodeResponseTimeout {
-				return types.ErrTimeout
-			}
+	if len(k.sharditer) == 0 {
+		if k.sharditer, err = checkpointer.Get(
+			aws.BackgroundContext(), k.namespace, k.conf.Shard, "sequence_number",
+		); err != nil {
 			return err
 		}
-		if seqAttr := resp.Item["sequence_number"]; seqAttr != nil {
-			if seqAttr.S != nil {
-				k.sharditer = *seqAttr.S
-			}
-		}
 	}
 
 	if len(k.sharditer) == 0 {
@@ -183,7 +167,7 @@ func (k *Kinesis) Connect() error {
 	k.sharditerCommit = k.sharditer
 
 	k.kinesis = kin
-	k.dynamo = dynamo
+	k.checkpointer = checkpointer
 	k.session = sess
 
 	k.log.Infof("Receiving Amazon Kinesis messages from stream: %v\n", k.conf.Stream)
@@ -239,29 +223,12 @@ func (k *Kinesis) commit() error {
 	if k.session == nil {
 		return nil
 	}
-	if len(k.conf.DynamoDBTable) > 0 {
-		if _, err := k.dynamo.PutItemWithContext(
-			aws.BackgroundContext(),
-			&dynamodb.PutItemInput{
-				TableName: aws.String(k.conf.DynamoDBTable),
-				Item: map[string]*dynamodb.AttributeValue{
-					"namespace": {
-						S: aws.String(k.namespace),
-					},
-					"shard_id": {
-						S: aws.String(k.conf.Shard),
-					},
-					"sequence_number": {
-						S: aws.String(k.sharditerCommit),
-					},
-				},
-			},
-			request.WithResponseReadTimeout(k.timeout),
-		); err != nil {
-			return err
-		}
-		k.offsetLastCommitted = time.Now()
+	if err := k.checkpointer.Set(
+		aws.BackgroundContext(), k.namespace, k.conf.Shard, "sequence_number", k.sharditerCommit,
+	); err != nil {
+		return err
 	}
+	k.offsetLastCommitted = time.Now()
 	return nil
 }
 
@@ -0,0 +1,335 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+//------------------------------------------------------------------------------
+
+// String constants representing each Kinesis checkpointer type.
+const (
+	KinesisCheckpointerDynamoDB = "dynamodb"
+	KinesisCheckpointerFile     = "file"
+	KinesisCheckpointerMemory   = "memory"
+)
+
+// KinesisFileCheckpointerConfig contains configuration fields for a file based
+// Kinesis checkpointer.
+type KinesisFileCheckpointerConfig struct {
+	Path string `json:"path" yaml:"path"`
+}
+
+// KinesisCheckpointerConfig contains configuration fields for the store used
+// to persist the position of a Kinesis consumer within a shard.
+type KinesisCheckpointerConfig struct {
+	Type string                        `json:"type" yaml:"type"`
+	File KinesisFileCheckpointerConfig `json:"file" yaml:"file"`
+}
+
+// NewKinesisCheckpointerConfig creates a KinesisCheckpointerConfig with default
+// values.
+func NewKinesisCheckpointerConfig() KinesisCheckpointerConfig {
+	return KinesisCheckpointerConfig{
+		Type: KinesisCheckpointerDynamoDB,
+		File: KinesisFileCheckpointerConfig{
+			Path: "",
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// KinesisCheckpointer is a store for the position of a Kinesis consumer within
+// a shard. Checkpoints are keyed by a namespace, a shard ID and a field, where
+// the field distinguishes between the types of checkpoint written by different
+// modes of consumption.
+type KinesisCheckpointer interface {
+	// Get returns the checkpoint stored for a key, or an empty string if one
+	// does not exist.
+	Get(ctx context.Context, namespace, shardID, field string) (string, error)
+
+	// Set stores the checkpoint for a key.
+	Set(ctx context.Context, namespace, shardID, field, value string) error
+}
+
+// newKinesisCheckpointer creates the checkpointer selected by the config of a
+// Kinesis reader.
+func newKinesisCheckpointer(
+	conf KinesisConfig, sess *session.Session, timeout time.Duration,
+) (KinesisCheckpointer, error) {
+	switch conf.Checkpointer.Type {
+	case KinesisCheckpointerDynamoDB, "":
+		if len(conf.DynamoDBTable) == 0 {
+			return noopKinesisCheckpointer{}, nil
+		}
+		return &dynamoDBKinesisCheckpointer{
+			table:   conf.DynamoDBTable,
+			dynamo:  dynamodb.New(sess),
+			timeout: timeout,
+		}, nil
+	case KinesisCheckpointerMemory:
+		return newMemoryKinesisCheckpointer(), nil
+	case KinesisCheckpointerFile:
+		if len(conf.Checkpointer.File.Path) == 0 {
+			return nil, fmt.Errorf("a path must be specified for the %v checkpointer", KinesisCheckpointerFile)
+		}
+		return newFileKinesisCheckpointer(conf.Checkpointer.File.Path), nil
+	}
+	return nil, fmt.Errorf("checkpointer type not recognised: %v", conf.Checkpointer.Type)
+}
+
+//------------------------------------------------------------------------------
+
+type noopKinesisCheckpointer struct{}
+
+func (n noopKinesisCheckpointer) Get(ctx context.Context, namespace, shardID, field string) (string, error) {
+	return "", nil
+}
+
+func (n noopKinesisCheckpointer) Set(ctx context.Context, namespace, shardID, field, value string) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// dynamoDBKinesisCheckpointer stores checkpoints in a DynamoDB table with
+// `namespace` as the primary key and `shard_id` as a sort key.
+type dynamoDBKinesisCheckpointer struct {
+	table   string
+	dynamo  *dynamodb.DynamoDB
+	timeout time.Duration
+}
+
+func (d *dynamoDBKinesisCheckpointer) Get(ctx context.Context, namespace, shardID, field string) (string, error) {
+	resp, err := d.dynamo.GetItemWithContext(
+		ctx,
+		&dynamodb.GetItemInput{
+			TableName:      aws.String(d.table),
+			ConsistentRead: aws.Bool(true),
+			Key: map[string]*dynamodb.AttributeValue{
+				"namespace": {
+					S: aws.String(namespace),
+				},
+				"shard_id": {
+					S: aws.String(shardID),
+				},
+			},
+		},
+		request.WithResponseReadTimeout(d.timeout),
+	)
+	if err != nil {
+		if err.Error() == request.ErrCodeResponseTimeout {
+			return "", types.ErrTimeout
+		}
+		return "", err
+	}
+	if attr := resp.Item[field]; attr != nil && attr.S != nil {
+		return *attr.S, nil
+	}
+	return "", nil
+}
+
+func (d *dynamoDBKinesisCheckpointer) Set(ctx context.Context, namespace, shardID, field, value string) error {
+	_, err := d.dynamo.PutItemWithContext(
+		ctx,
+		&dynamodb.PutItemInput{
+			TableName: aws.String(d.table),
+			Item: map[string]*dynamodb.AttributeValue{
+				"namespace": {
+					S: aws.String(namespace),
+				},
+				"shard_id": {
+					S: aws.String(shardID),
+				},
+				field: {
+					S: aws.String(value),
+				},
+			},
+		},
+		request.WithResponseReadTimeout(d.timeout),
+	)
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+// kinesisCheckpoints is a map of namespaces to shard IDs to fields to
+// checkpoints.
+type kinesisCheckpoints map[string]map[string]map[string]string
+
+func (k kinesisCheckpoints) get(namespace, shardID, field string) string {
+	return k[namespace][shardID][field]
+}
+
+func (k kinesisCheckpoints) set(namespace, shardID, field, value string) {
+	shards, exists := k[namespace]
+	if !exists {
+		shards = map[string]map[string]string{}
+		k[namespace] = shards
+	}
+	// Mirror DynamoDB by replacing all fields of the shard.
+	shards[shardID] = map[string]string{
+		field: value,
+	}
+}
+
+// memoryKinesisCheckpointer stores checkpoints in memory only, and therefore
+// does not survive restarts.
+type memoryKinesisCheckpointer struct {
+	mut         sync.Mutex
+	checkpoints kinesisCheckpoints
+}
+
+func newMemoryKinesisCheckpointer() *memoryKinesisCheckpointer {
+	return &memoryKinesisCheckpointer{
+		checkpoints: kinesisCheckpoints{},
+	}
+}
+
+func (m *memoryKinesisCheckpointer) Get(ctx context.Context, namespace, shardID, field string) (string, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	return m.checkpoints.get(namespace, shardID, field), nil
+}
+
+func (m *memoryKinesisCheckpointer) Set(ctx context.Context, namespace, shardID, field, value string) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.checkpoints.set(namespace, shardID, field, value)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// kinesisFileMuts guards each checkpoint file path, since multiple readers of
+// different shards may share a file.
+var (
+	kinesisFileMutsMut sync.Mutex
+	kinesisFileMuts    = map[string]*sync.Mutex{}
+)
+
+func kinesisFileMut(path string) *sync.Mutex {
+	kinesisFileMutsMut.Lock()
+	defer kinesisFileMutsMut.Unlock()
+	mut, exists := kinesisFileMuts[path]
+	if !exists {
+		mut = &sync.Mutex{}
+		kinesisFileMuts[path] = mut
+	}
+	return mut
+}
+
+// fileKinesisCheckpointer stores checkpoints as a JSON document within a file,
+// which is replaced atomically on each write.
+type fileKinesisCheckpointer struct {
+	path string
+	mut  *sync.Mutex
+}
+
+func newFileKinesisCheckpointer(path string) *fileKinesisCheckpointer {
+	path = filepath.Clean(path)
+	return &fileKinesisCheckpointer{
+		path: path,
+		mut:  kinesisFileMut(path),
+	}
+}
+
+func (f *fileKinesisCheckpointer) read() (kinesisCheckpoints, error) {
+	checkpoints := kinesisCheckpoints{}
+	fileBytes, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return checkpoints, nil
+		}
+		return nil, err
+	}
+	if err = json.Unmarshal(fileBytes, &checkpoints); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint file: %v", err)
+	}
+	return checkpoints, nil
+}
+
+// write replaces the checkpoint file by writing to a temporary file within the
+// same directory and renaming it over the original.
+func (f *fileKinesisCheckpointer) write(checkpoints kinesisCheckpoints) error {
+	fileBytes, err := json.Marshal(checkpoints)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), filepath.Base(f.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(fileBytes); err == nil {
+		err = tmp.Sync()
+	}
+	if cErr := tmp.Close(); err == nil {
+		err = cErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), f.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func (f *fileKinesisCheckpointer) Get(ctx context.Context, namespace, shardID, field string) (string, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	checkpoints, err := f.read()
+	if err != nil {
+		return "", err
+	}
+	return checkpoints.get(namespace, shardID, field), nil
+}
+
+func (f *fileKinesisCheckpointer) Set(ctx context.Context, namespace, shardID, field, value string) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	checkpoints, err := f.read()
+	if err != nil {
+		return err
+	}
+	checkpoints.set(namespace, shardID, field, value)
+	return f.write(checkpoints)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,169 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+//------------------------------------------------------------------------------
+
+func testKinesisCheckpointer(t *testing.T, c KinesisCheckpointer) {
+	ctx := context.Background()
+
+	if v, err := c.Get(ctx, "foo", "0", "sequence_number"); err != nil {
+		t.Fatal(err)
+	} else if v != "" {
+		t.Errorf("Expected empty checkpoint: %v", v)
+	}
+
+	if err := c.Set(ctx, "foo", "0", "sequence_number", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(ctx, "foo", "1", "sequence_number", "baz"); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := c.Get(ctx, "foo", "0", "sequence_number"); err != nil {
+		t.Fatal(err)
+	} else if exp, act := "bar", v; exp != act {
+		t.Errorf("Wrong checkpoint: %v != %v", act, exp)
+	}
+	if v, err := c.Get(ctx, "foo", "1", "sequence_number"); err != nil {
+		t.Fatal(err)
+	} else if exp, act := "baz", v; exp != act {
+		t.Errorf("Wrong checkpoint: %v != %v", act, exp)
+	}
+	if v, err := c.Get(ctx, "bar", "0", "sequence_number"); err != nil {
+		t.Fatal(err)
+	} else if v != "" {
+		t.Errorf("Expected empty checkpoint: %v", v)
+	}
+
+	if err := c.Set(ctx, "foo", "0", "continuation_sequence_number", "qux"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Get(ctx, "foo", "0", "sequence_number"); err != nil {
+		t.Fatal(err)
+	} else if v != "" {
+		t.Errorf("Expected replaced checkpoint: %v", v)
+	}
+	if v, err := c.Get(ctx, "foo", "0", "continuation_sequence_number"); err != nil {
+		t.Fatal(err)
+	} else if exp, act := "qux", v; exp != act {
+		t.Errorf("Wrong checkpoint: %v != %v", act, exp)
+	}
+}
+
+func TestKinesisCheckpointerMemory(t *testing.T) {
+	conf := NewKinesisConfig()
+	conf.Checkpointer.Type = KinesisCheckpointerMemory
+
+	c, err := newKinesisCheckpointer(conf, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testKinesisCheckpointer(t, c)
+}
+
+func TestKinesisCheckpointerFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_kinesis_checkpoint_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewKinesisConfig()
+	conf.Checkpointer.Type = KinesisCheckpointerFile
+	conf.Checkpointer.File.Path = filepath.Join(dir, "checkpoints.json")
+
+	c, err := newKinesisCheckpointer(conf, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testKinesisCheckpointer(t, c)
+
+	// A new checkpointer of the same file, as if after a restart.
+	if c, err = newKinesisCheckpointer(conf, nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Get(context.Background(), "foo", "1", "sequence_number"); err != nil {
+		t.Fatal(err)
+	} else if exp, act := "baz", v; exp != act {
+		t.Errorf("Wrong checkpoint: %v != %v", act, exp)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 1, len(files); exp != act {
+		t.Errorf("Wrong count of files left in directory: %v != %v", act, exp)
+	}
+}
+
+func TestKinesisCheckpointerFileCorrupt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_kinesis_checkpoint_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "checkpoints.json")
+	if err = ioutil.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newFileKinesisCheckpointer(path)
+	if _, err = c.Get(context.Background(), "foo", "0", "sequence_number"); err == nil {
+		t.Error("Expected error from corrupt file")
+	}
+	if err = c.Set(context.Background(), "foo", "0", "sequence_number", "bar"); err == nil {
+		t.Error("Expected error from corrupt file")
+	}
+}
+
+func TestKinesisCheckpointerConfig(t *testing.T) {
+	conf := NewKinesisConfig()
+	c, err := newKinesisCheckpointer(conf, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.(noopKinesisCheckpointer); !ok {
+		t.Errorf("Expected noop checkpointer without a DynamoDB table: %T", c)
+	}
+
+	conf.Checkpointer.Type = KinesisCheckpointerFile
+	if _, err = newKinesisCheckpointer(conf, nil, 0); err == nil {
+		t.Error("Expected error from missing file path")
+	}
+
+	conf.Checkpointer.Type = "not a type"
+	if _, err = newKinesisCheckpointer(conf, nil, 0); err == nil {
+		t.Error("Expected error from bad checkpointer type")
+	}
+}
+
+//------------------------------------------------------------------------------
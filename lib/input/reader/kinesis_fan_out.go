@@ -0,0 +1,403 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/private/protocol/eventstream"
+	"github.com/aws/aws-sdk-go/private/protocol/eventstream/eventstreamapi"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+)
+
+//------------------------------------------------------------------------------
+
+// The version of the AWS SDK used here predates the SubscribeToShard API, and
+// therefore the request and event types are declared here and the event stream
+// is decoded manually.
+
+type kinesisStartingPosition struct {
+	Type           *string `type:"string" required:"true"`
+	SequenceNumber *string `type:"string"`
+}
+
+type kinesisSubscribeToShardInput struct {
+	_ struct{} `type:"structure"`
+
+	ConsumerARN      *string                  `type:"string" required:"true"`
+	ShardId          *string                  `type:"string" required:"true"`
+	StartingPosition *kinesisStartingPosition `type:"structure" required:"true"`
+}
+
+type kinesisFanOutRecord struct {
+	Data           []byte `json:"Data"`
+	PartitionKey   string `json:"PartitionKey"`
+	SequenceNumber string `json:"SequenceNumber"`
+}
+
+type kinesisSubscribeToShardEvent struct {
+	ContinuationSequenceNumber string                `json:"ContinuationSequenceNumber"`
+	MillisBehindLatest         int64                 `json:"MillisBehindLatest"`
+	Records                    []kinesisFanOutRecord `json:"Records"`
+}
+
+const kinesisSubscribeToShardEventType = "SubscribeToShardEvent"
+
+//------------------------------------------------------------------------------
+
+// KinesisFanOut is a benthos reader.Type implementation that reads messages
+// from a shard of an Amazon Kinesis stream using an enhanced fan-out consumer,
+// where records are pushed to the consumer over a subscription.
+type KinesisFanOut struct {
+	conf KinesisConfig
+
+	session      *session.Session
+	kinesis      *kinesis.Kinesis
+	checkpointer KinesisCheckpointer
+
+	consumerARN string
+
+	subscription io.ReadCloser
+	decoder      *eventstream.Decoder
+	payloadBuf   []byte
+
+	offsetLastCommitted time.Time
+	sequenceCommit      string
+	sequence            string
+	namespace           string
+
+	timeout time.Duration
+
+	ctx  context.Context
+	done func()
+
+	log   log.Modular
+	stats metrics.Type
+
+	mSubscribe   metrics.StatCounter
+	mResubscribe metrics.StatCounter
+	mMillisBL    metrics.StatGauge
+}
+
+// NewKinesisFanOut creates a new Amazon Kinesis enhanced fan-out reader.Type.
+func NewKinesisFanOut(
+	conf KinesisConfig,
+	log log.Modular,
+	stats metrics.Type,
+) *KinesisFanOut {
+	ctx, done := context.WithCancel(context.Background())
+	return &KinesisFanOut{
+		conf:         conf,
+		log:          log.NewModule(".input.kinesis"),
+		timeout:      time.Duration(conf.TimeoutMS) * time.Millisecond,
+		namespace:    fmt.Sprintf("%v-%v", conf.ClientID, conf.Stream),
+		ctx:          ctx,
+		done:         done,
+		stats:        stats,
+		mSubscribe:   stats.GetCounter("input.kinesis.subscribe"),
+		mResubscribe: stats.GetCounter("input.kinesis.resubscribe"),
+		mMillisBL:    stats.GetGauge("input.kinesis.millis_behind_latest"),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// registerConsumer registers the client ID as an enhanced fan-out consumer of
+// the stream, or obtains the existing registration, and returns the ARN of the
+// consumer once it is active.
+func (k *KinesisFanOut) registerConsumer(kin *kinesis.Kinesis) (string, error) {
+	summary, err := kin.DescribeStreamSummaryWithContext(
+		k.ctx,
+		&kinesis.DescribeStreamSummaryInput{
+			StreamName: aws.String(k.conf.Stream),
+		},
+		request.WithResponseReadTimeout(k.timeout),
+	)
+	if err != nil {
+		return "", err
+	}
+	streamARN := summary.StreamDescriptionSummary.StreamARN
+
+	if _, err = kin.RegisterStreamConsumerWithContext(
+		k.ctx,
+		&kinesis.RegisterStreamConsumerInput{
+			ConsumerName: aws.String(k.conf.ClientID),
+			StreamARN:    streamARN,
+		},
+		request.WithResponseReadTimeout(k.timeout),
+	); err != nil {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != kinesis.ErrCodeResourceInUseException {
+			return "", err
+		}
+	}
+
+	desc, err := kin.DescribeStreamConsumerWithContext(
+		k.ctx,
+		&kinesis.DescribeStreamConsumerInput{
+			ConsumerName: aws.String(k.conf.ClientID),
+			StreamARN:    streamARN,
+		},
+		request.WithResponseReadTimeout(k.timeout),
+	)
+	if err != nil {
+		return "", err
+	}
+	if status := aws.StringValue(desc.ConsumerDescription.ConsumerStatus); status != kinesis.ConsumerStatusActive {
+		return "", fmt.Errorf("consumer '%v' is not yet active: %v", k.conf.ClientID, status)
+	}
+	return aws.StringValue(desc.ConsumerDescription.ConsumerARN), nil
+}
+
+// Connect attempts to register an enhanced fan-out consumer of the target
+// Kinesis stream.
+func (k *KinesisFanOut) Connect() error {
+	if k.session != nil {
+		return nil
+	}
+
+	sess, err := k.conf.GetSession()
+	if err != nil {
+		return err
+	}
+
+	checkpointer, err := newKinesisCheckpointer(k.conf, sess, k.timeout)
+	if err != nil {
+		return err
+	}
+	kin := kinesis.New(sess)
+
+	if len(k.consumerARN) == 0 {
+		if k.consumerARN, err = k.registerConsumer(kin); err != nil {
+			return err
+		}
+	}
+
+	if len(k.sequence) == 0 {
+		if k.sequence, err = checkpointer.Get(
+			k.ctx, k.namespace, k.conf.Shard, "continuation_sequence_number",
+		); err != nil {
+			return err
+		}
+	}
+
+	k.sequenceCommit = k.sequence
+
+	k.kinesis = kin
+	k.checkpointer = checkpointer
+	k.session = sess
+
+	k.log.Infof("Receiving Amazon Kinesis messages from stream with enhanced fan-out: %v\n", k.conf.Stream)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// subscribe opens a subscription to the shard starting after the last read
+// sequence number, subscriptions expire after five minutes at which point a
+// new subscription must be opened.
+func (k *KinesisFanOut) subscribe() error {
+	iterType := kinesis.ShardIteratorTypeTrimHorizon
+	if !k.conf.StartFromOldest {
+		iterType = kinesis.ShardIteratorTypeLatest
+	}
+	startPos := &kinesisStartingPosition{
+		Type: aws.String(iterType),
+	}
+	if len(k.sequence) > 0 {
+		startPos.Type = aws.String(kinesis.ShardIteratorTypeAfterSequenceNumber)
+		startPos.SequenceNumber = aws.String(k.sequence)
+	}
+
+	req := k.kinesis.NewRequest(&request.Operation{
+		Name:       "SubscribeToShard",
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}, &kinesisSubscribeToShardInput{
+		ConsumerARN:      aws.String(k.consumerARN),
+		ShardId:          aws.String(k.conf.Shard),
+		StartingPosition: startPos,
+	}, nil)
+	req.SetContext(k.ctx)
+
+	// The response body is an event stream and must not be consumed.
+	req.Handlers.Unmarshal.Clear()
+
+	if err := req.Send(); err != nil {
+		return err
+	}
+
+	k.subscription = req.HTTPResponse.Body
+	k.decoder = eventstream.NewDecoder(k.subscription)
+	k.mSubscribe.Incr(1)
+	return nil
+}
+
+func (k *KinesisFanOut) closeSubscription() {
+	if k.subscription != nil {
+		k.subscription.Close()
+		k.subscription = nil
+		k.decoder = nil
+	}
+}
+
+// nextEvent reads the next records event of the subscription, skipping other
+// event types.
+func (k *KinesisFanOut) nextEvent() (*kinesisSubscribeToShardEvent, error) {
+	for {
+		msg, err := k.decoder.Decode(k.payloadBuf)
+		if err != nil {
+			return nil, err
+		}
+
+		msgType, err := eventstreamapi.GetHeaderString(msg, eventstreamapi.MessageTypeHeader)
+		if err != nil {
+			return nil, err
+		}
+
+		switch msgType {
+		case eventstreamapi.EventMessageType:
+			eventType, err := eventstreamapi.GetHeaderString(msg, eventstreamapi.EventTypeHeader)
+			if err != nil {
+				return nil, err
+			}
+			if eventType != kinesisSubscribeToShardEventType {
+				continue
+			}
+			event := &kinesisSubscribeToShardEvent{}
+			if err = json.Unmarshal(msg.Payload, event); err != nil {
+				return nil, fmt.Errorf("failed to parse event: %v", err)
+			}
+			return event, nil
+		case eventstreamapi.ExceptionMessageType:
+			exceptionType, _ := eventstreamapi.GetHeaderString(msg, eventstreamapi.ExceptionTypeHeader)
+			return nil, fmt.Errorf("subscription exception %v: %s", exceptionType, msg.Payload)
+		case eventstreamapi.ErrorMessageType:
+			errCode, _ := eventstreamapi.GetHeaderString(msg, eventstreamapi.ErrorCodeHeader)
+			errMsg, _ := eventstreamapi.GetHeaderString(msg, eventstreamapi.ErrorMessageHeader)
+			return nil, fmt.Errorf("subscription error %v: %v", errCode, errMsg)
+		}
+	}
+}
+
+// Read attempts to read a new message from the target Kinesis shard.
+func (k *KinesisFanOut) Read() (types.Message, error) {
+	if k.session == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	if k.subscription == nil {
+		if err := k.subscribe(); err != nil {
+			if k.ctx.Err() != nil {
+				return nil, types.ErrTypeClosed
+			}
+			return nil, err
+		}
+	}
+
+	event, err := k.nextEvent()
+	if err != nil {
+		k.closeSubscription()
+		if k.ctx.Err() != nil {
+			return nil, types.ErrTypeClosed
+		}
+		if err == io.EOF {
+			k.log.Debugln("Subscription expired, resubscribing")
+			k.mResubscribe.Incr(1)
+			return nil, types.ErrTimeout
+		}
+		return nil, err
+	}
+
+	if len(event.ContinuationSequenceNumber) > 0 {
+		k.sequence = event.ContinuationSequenceNumber
+	}
+	k.mMillisBL.Set(event.MillisBehindLatest)
+
+	msg := message.New(nil)
+	for _, rec := range event.Records {
+		if rec.Data != nil {
+			part := message.NewPart(rec.Data)
+			part.Metadata().Set("kinesis_shard", k.conf.Shard)
+			part.Metadata().Set("kinesis_stream", k.conf.Stream)
+
+			msg.Append(part)
+		}
+	}
+
+	if msg.Len() == 0 {
+		return nil, types.ErrTimeout
+	}
+	return msg, nil
+}
+
+func (k *KinesisFanOut) commit() error {
+	if k.session == nil || len(k.sequenceCommit) == 0 {
+		return nil
+	}
+	if err := k.checkpointer.Set(
+		aws.BackgroundContext(), k.namespace, k.conf.Shard, "continuation_sequence_number", k.sequenceCommit,
+	); err != nil {
+		return err
+	}
+	k.offsetLastCommitted = time.Now()
+	return nil
+}
+
+// Acknowledge confirms whether or not our unacknowledged messages have been
+// successfully propagated or not.
+func (k *KinesisFanOut) Acknowledge(err error) error {
+	if err == nil {
+		k.sequenceCommit = k.sequence
+	}
+
+	if time.Since(k.offsetLastCommitted) <
+		(time.Millisecond * time.Duration(k.conf.CommitPeriodMS)) {
+		return nil
+	}
+
+	return k.commit()
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (k *KinesisFanOut) CloseAsync() {
+	k.done()
+	go k.commit()
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (k *KinesisFanOut) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
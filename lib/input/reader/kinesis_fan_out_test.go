@@ -0,0 +1,182 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/aws/aws-sdk-go/private/protocol/eventstream"
+)
+
+//------------------------------------------------------------------------------
+
+func writeKinesisEvent(t *testing.T, w http.ResponseWriter, eventType string, payload interface{}) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = eventstream.NewEncoder(w).Encode(eventstream.Message{
+		Headers: eventstream.Headers{
+			{Name: ":message-type", Value: eventstream.StringValue("event")},
+			{Name: ":event-type", Value: eventstream.StringValue(eventType)},
+		},
+		Payload: payloadBytes,
+	}); err != nil {
+		t.Error(err)
+	}
+	w.(http.Flusher).Flush()
+}
+
+func TestKinesisFanOut(t *testing.T) {
+	var startMut sync.Mutex
+	var starts []kinesisStartingPosition
+
+	subscriptions := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("X-Amz-Target") {
+		case "Kinesis_20131202.DescribeStreamSummary":
+			w.Write([]byte(`{"StreamDescriptionSummary":{"StreamARN":"arn:foo"}}`))
+		case "Kinesis_20131202.RegisterStreamConsumer":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ResourceInUseException","message":"already registered"}`))
+		case "Kinesis_20131202.DescribeStreamConsumer":
+			w.Write([]byte(`{"ConsumerDescription":{"ConsumerARN":"arn:foo:consumer","ConsumerStatus":"ACTIVE"}}`))
+		case "Kinesis_20131202.SubscribeToShard":
+			body, _ := ioutil.ReadAll(r.Body)
+			input := kinesisSubscribeToShardInput{}
+			if err := json.Unmarshal(body, &input); err != nil {
+				t.Error(err)
+			}
+			if exp, act := "arn:foo:consumer", *input.ConsumerARN; exp != act {
+				t.Errorf("Wrong consumer ARN: %v != %v", act, exp)
+			}
+			startMut.Lock()
+			starts = append(starts, *input.StartingPosition)
+			startMut.Unlock()
+
+			w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+			writeKinesisEvent(t, w, "initial-response", map[string]interface{}{})
+
+			subscriptions++
+			if subscriptions == 1 {
+				writeKinesisEvent(t, w, kinesisSubscribeToShardEventType, kinesisSubscribeToShardEvent{
+					ContinuationSequenceNumber: "2",
+					Records: []kinesisFanOutRecord{
+						{Data: []byte("foo"), SequenceNumber: "1"},
+						{Data: []byte("bar"), SequenceNumber: "2"},
+					},
+				})
+				return
+			}
+			writeKinesisEvent(t, w, kinesisSubscribeToShardEventType, kinesisSubscribeToShardEvent{
+				ContinuationSequenceNumber: "3",
+				Records: []kinesisFanOutRecord{
+					{Data: []byte("baz"), SequenceNumber: "3"},
+				},
+			})
+			<-r.Context().Done()
+		default:
+			t.Errorf("Unexpected request: %v", r.Header.Get("X-Amz-Target"))
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	conf := NewKinesisConfig()
+	conf.Endpoint = server.URL
+	conf.Credentials.ID = "foo"
+	conf.Credentials.Secret = "bar"
+	conf.Stream = "foo"
+	conf.EnhancedFanOut = true
+	conf.Checkpointer.Type = KinesisCheckpointerMemory
+
+	r := NewKinesisFanOut(conf, log.Noop(), metrics.Noop())
+	if err := r.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := [][]byte{[]byte("foo"), []byte("bar")}, message.GetAllBytes(msg); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if exp, act := "foo", msg.Get(0).Metadata().Get("kinesis_stream"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+	if err = r.Acknowledge(nil); err != nil {
+		t.Error(err)
+	}
+	if seq, err := r.checkpointer.Get(context.Background(), "benthos_consumer-foo", "0", "continuation_sequence_number"); err != nil {
+		t.Error(err)
+	} else if exp, act := "2", seq; exp != act {
+		t.Errorf("Wrong checkpoint: %v != %v", act, exp)
+	}
+
+	if _, err = r.Read(); err != types.ErrTimeout {
+		t.Errorf("Expected timeout after subscription expired: %v", err)
+	}
+
+	if msg, err = r.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := [][]byte{[]byte("baz")}, message.GetAllBytes(msg); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+
+	startMut.Lock()
+	if exp, act := 2, len(starts); exp != act {
+		t.Fatalf("Wrong count of subscriptions: %v != %v", act, exp)
+	}
+	if exp, act := "TRIM_HORIZON", *starts[0].Type; exp != act {
+		t.Errorf("Wrong starting position: %v != %v", act, exp)
+	}
+	if exp, act := "AFTER_SEQUENCE_NUMBER", *starts[1].Type; exp != act {
+		t.Errorf("Wrong starting position: %v != %v", act, exp)
+	}
+	if exp, act := "2", *starts[1].SequenceNumber; exp != act {
+		t.Errorf("Wrong starting sequence number: %v != %v", act, exp)
+	}
+	startMut.Unlock()
+
+	go func() {
+		<-time.After(time.Millisecond * 100)
+		r.CloseAsync()
+	}()
+	if _, err = r.Read(); err != types.ErrTypeClosed {
+		t.Errorf("Expected closed error: %v", err)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -22,6 +22,8 @@ package reader
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -41,6 +43,7 @@ type NATSConfig struct {
 	Subject       string   `json:"subject" yaml:"subject"`
 	QueueID       string   `json:"queue" yaml:"queue"`
 	PrefetchCount int      `json:"prefetch_count" yaml:"prefetch_count"`
+	SplitSubject  bool     `json:"split_subject" yaml:"split_subject"`
 }
 
 // NewNATSConfig creates a new NATSConfig with default values.
@@ -50,6 +53,40 @@ func NewNATSConfig() NATSConfig {
 		Subject:       "benthos_messages",
 		QueueID:       "benthos_queue",
 		PrefetchCount: 32,
+		SplitSubject:  false,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// validateNATSSubject checks that a subject is made of non-empty tokens and
+// that the wildcards '*' and '>' are only used as whole tokens, with '>' only
+// allowed as the final token.
+func validateNATSSubject(subject string) error {
+	tokens := strings.Split(subject, ".")
+	for i, token := range tokens {
+		if len(token) == 0 {
+			return fmt.Errorf("subject '%v' contains an empty token", subject)
+		}
+		if token == "*" || (token == ">" && i == len(tokens)-1) {
+			continue
+		}
+		if strings.ContainsAny(token, "*> \t\r\n") {
+			return fmt.Errorf("subject '%v' contains an invalid token '%v'", subject, token)
+		}
+	}
+	return nil
+}
+
+// natsSubjectMetadata sets metadata keys for the subject of a message, and
+// optionally each token of the subject.
+func natsSubjectMetadata(md types.Metadata, subject string, split bool) {
+	md.Set("nats_subject", subject)
+	if !split {
+		return
+	}
+	for i, token := range strings.Split(subject, ".") {
+		md.Set("nats_subject_token_"+strconv.Itoa(i), token)
 	}
 }
 
@@ -82,6 +119,9 @@ func NewNATS(conf NATSConfig, log log.Modular, stats metrics.Type) (Type, error)
 	if conf.PrefetchCount < 0 {
 		return nil, errors.New("prefetch count must be greater than or equal to zero")
 	}
+	if err := validateNATSSubject(conf.Subject); err != nil {
+		return nil, err
+	}
 
 	return &n, nil
 }
@@ -157,7 +197,7 @@ func (n *NATS) Read() (types.Message, error) {
 	}
 
 	bmsg := message.New([][]byte{msg.Data})
-	bmsg.Get(0).Metadata().Set("nats_subject", msg.Subject)
+	natsSubjectMetadata(bmsg.Get(0).Metadata(), msg.Subject, n.conf.SplitSubject)
 
 	return bmsg, nil
 }
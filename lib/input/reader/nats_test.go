@@ -0,0 +1,107 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message/metadata"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func TestNATSSubjectValidation(t *testing.T) {
+	valid := []string{
+		"foo",
+		"foo.bar",
+		"foo.*",
+		"foo.*.bar",
+		"*.*",
+		"foo.>",
+		">",
+	}
+	for _, subject := range valid {
+		if err := validateNATSSubject(subject); err != nil {
+			t.Errorf("Unexpected error for '%v': %v", subject, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"foo..bar",
+		"foo.",
+		"foo.>.bar",
+		"foo*",
+		"foo.b>",
+		"foo bar",
+	}
+	for _, subject := range invalid {
+		if err := validateNATSSubject(subject); err == nil {
+			t.Errorf("Expected error for '%v'", subject)
+		}
+	}
+
+	conf := NewNATSConfig()
+	conf.Subject = "foo.>.bar"
+	if _, err := NewNATS(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from invalid subject")
+	}
+}
+
+func TestNATSSubjectMetadata(t *testing.T) {
+	md := metadata.New(nil)
+	natsSubjectMetadata(md, "orders.eu.created", false)
+
+	act := map[string]string{}
+	md.Iter(func(k, v string) error {
+		act[k] = v
+		return nil
+	})
+	exp := map[string]string{
+		"nats_subject": "orders.eu.created",
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+
+	md = metadata.New(nil)
+	natsSubjectMetadata(md, "orders.eu.created", true)
+
+	act = map[string]string{}
+	md.Iter(func(k, v string) error {
+		act[k] = v
+		return nil
+	})
+	exp = map[string]string{
+		"nats_subject":         "orders.eu.created",
+		"nats_subject_token_0": "orders",
+		"nats_subject_token_1": "eu",
+		"nats_subject_token_2": "created",
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
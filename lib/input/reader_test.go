@@ -525,6 +525,78 @@ func TestReaderHappyPath(t *testing.T) {
 	}
 }
 
+func TestReaderMessageMetrics(t *testing.T) {
+	t.Parallel()
+
+	readerImpl := newMockReader()
+	readerImpl.msgToSnd = message.New([][]byte{[]byte("foo"), []byte("barbaz")})
+
+	stats := metrics.NewLocal()
+	r, err := NewReader("foo", readerImpl, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case readerImpl.connChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	select {
+	case readerImpl.readChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	var ts types.Transaction
+	select {
+	case ts = <-r.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	if exp, act := int64(9), stats.GetTimings()["input.foo.size"]; exp != act {
+		t.Errorf("Wrong size metric: %v != %v", act, exp)
+	}
+
+	<-time.After(pendingAgeInterval * 3)
+	if act := stats.GetCounters()["input.foo.pending.age"]; act < int64(pendingAgeInterval) {
+		t.Errorf("Expected pending age of at least %v, got %v", pendingAgeInterval, time.Duration(act))
+	}
+
+	go func() {
+		select {
+		case readerImpl.ackChan <- nil:
+		case <-time.After(time.Second):
+			t.Error("Timed out")
+		}
+	}()
+	select {
+	case ts.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	go func() {
+		select {
+		case readerImpl.readChan <- types.ErrTypeClosed:
+		case <-time.After(time.Second):
+		}
+	}()
+
+	r.CloseAsync()
+	if err = r.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+
+	if exp, act := int64(0), stats.GetCounters()["input.foo.pending.age"]; exp != act {
+		t.Errorf("Expected pending age to reset: %v != %v", act, exp)
+	}
+	if act := stats.GetTimings()["input.foo.latency"]; act < int64(pendingAgeInterval) {
+		t.Errorf("Expected latency of at least %v, got %v", pendingAgeInterval, time.Duration(act))
+	}
+}
+
 func TestReaderSadPath(t *testing.T) {
 	t.Parallel()
 
@@ -664,3 +736,57 @@ func TestReaderSkipAcks(t *testing.T) {
 }
 
 //------------------------------------------------------------------------------
+
+func TestReaderConnected(t *testing.T) {
+	t.Parallel()
+
+	readerImpl := newMockReader()
+
+	r, err := NewReader(
+		"foo", readerImpl,
+		log.Noop(), metrics.DudType{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitForConnected := func(exp bool) {
+		t.Helper()
+		for i := 0; i < 100; i++ {
+			if r.(*Reader).Connected() == exp {
+				return
+			}
+			<-time.After(time.Millisecond * 10)
+		}
+		t.Fatalf("Timed out waiting for connected state: %v", exp)
+	}
+
+	if r.(*Reader).Connected() {
+		t.Error("Expected reader to be disconnected before connecting")
+	}
+
+	select {
+	case readerImpl.connChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	waitForConnected(true)
+
+	select {
+	case readerImpl.readChan <- types.ErrNotConnected:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	waitForConnected(false)
+
+	select {
+	case readerImpl.connChan <- types.ErrTypeClosed:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	r.CloseAsync()
+	if err = r.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
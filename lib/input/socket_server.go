@@ -0,0 +1,383 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/codec"
+	"github.com/Jeffail/benthos/lib/util/throttle"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSocketServer] = TypeSpec{
+		constructor: NewSocketServer,
+		description: `
+Creates a server that receives messages over a network protocol, where the
+network can be one of ` + "`tcp`, `unix` or `udp`" + `.
+
+For ` + "`tcp` and `unix`" + ` any number of clients may connect concurrently,
+and the data of each connection is split into single part messages according to
+the configured codec. Each message must be acknowledged before more data is read
+from its connection, which applies backpressure to the client without blocking
+other connections. Empty messages are ignored.
+
+For ` + "`udp`" + ` each datagram is a single message and the codec is ignored.
+Datagrams larger than ` + "`max_buffer`" + ` are truncated.
+
+` + codec.Documentation + `
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- socket_server_remote_addr
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SocketServerConfig contains configuration for the SocketServer input type.
+type SocketServerConfig struct {
+	Network   string `json:"network" yaml:"network"`
+	Address   string `json:"address" yaml:"address"`
+	Codec     string `json:"codec" yaml:"codec"`
+	Delimiter string `json:"delimiter" yaml:"delimiter"`
+	MaxBuffer int    `json:"max_buffer" yaml:"max_buffer"`
+}
+
+// NewSocketServerConfig creates a new SocketServerConfig with default values.
+func NewSocketServerConfig() SocketServerConfig {
+	return SocketServerConfig{
+		Network:   "tcp",
+		Address:   "",
+		Codec:     "lines",
+		Delimiter: "",
+		MaxBuffer: 1000000,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SocketServer is an input type that binds to an address and consumes streams
+// of messages over TCP, Unix sockets or UDP.
+type SocketServer struct {
+	running int32
+
+	conf  SocketServerConfig
+	stats metrics.Type
+	log   log.Modular
+
+	split      bufio.SplitFunc
+	listener   net.Listener
+	packetConn net.PacketConn
+
+	conns    map[net.Conn]struct{}
+	connsMut sync.Mutex
+	connsWG  sync.WaitGroup
+
+	transactions chan types.Transaction
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+
+	mCount     metrics.StatCounter
+	mCountF    metrics.StatCounter
+	mConn      metrics.StatCounter
+	mConnErr   metrics.StatCounter
+	mReadErr   metrics.StatCounter
+	mErr       metrics.StatCounter
+	mErrF      metrics.StatCounter
+	mSucc      metrics.StatCounter
+	mSuccF     metrics.StatCounter
+	mConnected metrics.StatGauge
+}
+
+// NewSocketServer creates a new SocketServer input type.
+func NewSocketServer(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	s := SocketServer{
+		running:      1,
+		conf:         conf.SocketServer,
+		stats:        stats,
+		log:          log.NewModule(".input.socket_server"),
+		conns:        map[net.Conn]struct{}{},
+		transactions: make(chan types.Transaction),
+		closeChan:    make(chan struct{}),
+		closedChan:   make(chan struct{}),
+
+		mCount:     stats.GetCounter("input.socket_server.count"),
+		mCountF:    stats.GetCounter("input.count"),
+		mConn:      stats.GetCounter("input.socket_server.connection.count"),
+		mConnErr:   stats.GetCounter("input.socket_server.connection.error"),
+		mReadErr:   stats.GetCounter("input.socket_server.read.error"),
+		mErr:       stats.GetCounter("input.socket_server.send.error"),
+		mErrF:      stats.GetCounter("input.send.error"),
+		mSucc:      stats.GetCounter("input.socket_server.send.success"),
+		mSuccF:     stats.GetCounter("input.send.success"),
+		mConnected: stats.GetGauge("input.socket_server.connected"),
+	}
+
+	if len(s.conf.Address) == 0 {
+		return nil, errors.New("an address must be specified")
+	}
+
+	var err error
+	switch s.conf.Network {
+	case "tcp", "unix":
+		if s.split, err = codec.NewSplitFunc(s.conf.Codec, s.conf.Delimiter); err != nil {
+			return nil, err
+		}
+		if s.listener, err = net.Listen(s.conf.Network, s.conf.Address); err != nil {
+			return nil, err
+		}
+	case "udp":
+		if s.packetConn, err = net.ListenPacket(s.conf.Network, s.conf.Address); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("network not recognised: %v", s.conf.Network)
+	}
+
+	go s.loop()
+	return &s, nil
+}
+
+//------------------------------------------------------------------------------
+
+// sendMsg sends a message and blocks until it is acknowledged, retrying
+// rejected messages until the input is closed. Returns false if the input
+// closed before the message was delivered.
+func (s *SocketServer) sendMsg(msg types.Message, resChan chan types.Response, throt *throttle.Type) bool {
+	s.mCount.Incr(1)
+	s.mCountF.Incr(1)
+	for {
+		select {
+		case s.transactions <- types.NewTransaction(msg, resChan):
+		case <-s.closeChan:
+			return false
+		}
+		select {
+		case res, open := <-resChan:
+			if !open {
+				return false
+			}
+			if res.Error() == nil {
+				s.mSucc.Incr(1)
+				s.mSuccF.Incr(1)
+				throt.Reset()
+				return true
+			}
+			s.mErr.Incr(1)
+			s.mErrF.Incr(1)
+			if !throt.Retry() {
+				return false
+			}
+		case <-s.closeChan:
+			return false
+		}
+	}
+}
+
+func (s *SocketServer) newMsg(data []byte, remoteAddr net.Addr) types.Message {
+	msg := message.New([][]byte{data})
+	if remoteAddr != nil {
+		msg.Get(0).Metadata().Set("socket_server_remote_addr", remoteAddr.String())
+	}
+	return msg
+}
+
+func (s *SocketServer) handleConn(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.connsMut.Lock()
+		delete(s.conns, conn)
+		s.connsMut.Unlock()
+		s.mConnected.Decr(1)
+		s.connsWG.Done()
+	}()
+	s.mConnected.Incr(1)
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(nil, s.conf.MaxBuffer)
+	scanner.Split(s.split)
+
+	resChan := make(chan types.Response)
+	throt := throttle.New(throttle.OptCloseChan(s.closeChan))
+
+	for scanner.Scan() {
+		data := scanner.Bytes()
+		if len(data) == 0 {
+			continue
+		}
+		msgBytes := make([]byte, len(data))
+		copy(msgBytes, data)
+		if !s.sendMsg(s.newMsg(msgBytes, conn.RemoteAddr()), resChan, throt) {
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil && atomic.LoadInt32(&s.running) == 1 {
+		s.mReadErr.Incr(1)
+		s.log.Warnf("Failed to read from connection %v: %v\n", conn.RemoteAddr(), err)
+	}
+}
+
+func (s *SocketServer) acceptLoop() {
+	defer s.connsWG.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if atomic.LoadInt32(&s.running) != 1 {
+				return
+			}
+			s.mConnErr.Incr(1)
+			s.log.Errorf("Failed to accept connection: %v\n", err)
+			select {
+			case <-time.After(time.Second):
+			case <-s.closeChan:
+				return
+			}
+			continue
+		}
+		s.mConn.Incr(1)
+
+		s.connsMut.Lock()
+		if atomic.LoadInt32(&s.running) != 1 {
+			s.connsMut.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.connsWG.Add(1)
+		s.connsMut.Unlock()
+
+		go s.handleConn(conn)
+	}
+}
+
+func (s *SocketServer) packetLoop() {
+	defer s.connsWG.Done()
+
+	resChan := make(chan types.Response)
+	throt := throttle.New(throttle.OptCloseChan(s.closeChan))
+
+	buf := make([]byte, s.conf.MaxBuffer)
+	for {
+		n, addr, err := s.packetConn.ReadFrom(buf)
+		if err != nil {
+			if atomic.LoadInt32(&s.running) != 1 {
+				return
+			}
+			s.mReadErr.Incr(1)
+			s.log.Errorf("Failed to read datagram: %v\n", err)
+			continue
+		}
+		if n == 0 {
+			continue
+		}
+		msgBytes := make([]byte, n)
+		copy(msgBytes, buf[:n])
+		if !s.sendMsg(s.newMsg(msgBytes, addr), resChan, throt) {
+			return
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (s *SocketServer) loop() {
+	mRunning := s.stats.GetGauge("input.socket_server.running")
+
+	defer func() {
+		atomic.StoreInt32(&s.running, 0)
+
+		if s.listener != nil {
+			s.listener.Close()
+		}
+		if s.packetConn != nil {
+			s.packetConn.Close()
+		}
+		s.connsMut.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.connsMut.Unlock()
+		s.connsWG.Wait()
+
+		mRunning.Decr(1)
+
+		close(s.transactions)
+		close(s.closedChan)
+	}()
+	mRunning.Incr(1)
+
+	s.connsWG.Add(1)
+	if s.listener != nil {
+		s.log.Infof("Receiving %v socket messages at: %v\n", s.conf.Network, s.listener.Addr())
+		go s.acceptLoop()
+	} else {
+		s.log.Infof("Receiving %v socket messages at: %v\n", s.conf.Network, s.packetConn.LocalAddr())
+		go s.packetLoop()
+	}
+
+	<-s.closeChan
+}
+
+// TransactionChan returns a transactions channel for consuming messages from
+// this input.
+func (s *SocketServer) TransactionChan() <-chan types.Transaction {
+	return s.transactions
+}
+
+// CloseAsync shuts down the SocketServer input and stops processing requests.
+func (s *SocketServer) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&s.running, 1, 0) {
+		close(s.closeChan)
+	}
+}
+
+// WaitForClose blocks until the SocketServer input has closed down.
+func (s *SocketServer) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-s.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
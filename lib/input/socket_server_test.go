@@ -0,0 +1,218 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func newSocketServerTest(t *testing.T, conf Config) (*SocketServer, func()) {
+	t.Helper()
+
+	i, err := NewSocketServer(conf, nil, log.Noop(), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := i.(*SocketServer)
+	return s, func() {
+		s.CloseAsync()
+		if err := s.WaitForClose(time.Second * 5); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func readSocketServerTran(t *testing.T, s *SocketServer) types.Transaction {
+	t.Helper()
+
+	select {
+	case ts, open := <-s.TransactionChan():
+		if !open {
+			t.Fatal("Transaction chan closed")
+		}
+		return ts
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for message")
+	}
+	return types.Transaction{}
+}
+
+func TestSocketServerTCPConcurrent(t *testing.T) {
+	t.Parallel()
+
+	conf := NewConfig()
+	conf.SocketServer.Address = "localhost:0"
+
+	s, done := newSocketServerTest(t, conf)
+	defer done()
+
+	addr := s.listener.Addr().String()
+
+	connA, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer connA.Close()
+	connB, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer connB.Close()
+
+	if _, err = connA.Write([]byte("a1\na2\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	tsA := readSocketServerTran(t, s)
+	if exp, act := "a1", string(tsA.Payload.Get(0).Get()); exp != act {
+		t.Errorf("Wrong payload: %v != %v", act, exp)
+	}
+	if exp, act := connA.LocalAddr().String(), tsA.Payload.Get(0).Metadata().Get("socket_server_remote_addr"); exp != act {
+		t.Errorf("Wrong remote addr: %v != %v", act, exp)
+	}
+
+	// While the message of connection A remains unacknowledged connection B
+	// should still be able to deliver.
+	if _, err = connB.Write([]byte("b1\n")); err != nil {
+		t.Fatal(err)
+	}
+	tsB := readSocketServerTran(t, s)
+	if exp, act := "b1", string(tsB.Payload.Get(0).Get()); exp != act {
+		t.Errorf("Wrong payload: %v != %v", act, exp)
+	}
+	tsB.ResponseChan <- response.NewAck()
+
+	// A rejected message should be redelivered.
+	tsA.ResponseChan <- response.NewError(errors.New("nope"))
+	tsA = readSocketServerTran(t, s)
+	if exp, act := "a1", string(tsA.Payload.Get(0).Get()); exp != act {
+		t.Errorf("Wrong payload: %v != %v", act, exp)
+	}
+	tsA.ResponseChan <- response.NewAck()
+
+	tsA = readSocketServerTran(t, s)
+	if exp, act := "a2", string(tsA.Payload.Get(0).Get()); exp != act {
+		t.Errorf("Wrong payload: %v != %v", act, exp)
+	}
+	tsA.ResponseChan <- response.NewAck()
+}
+
+func TestSocketServerUnixLengthPrefixed(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "benthos_socket_server_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	conf := NewConfig()
+	conf.SocketServer.Network = "unix"
+	conf.SocketServer.Address = filepath.Join(tmpDir, "benthos.sock")
+	conf.SocketServer.Codec = "length_prefixed"
+
+	s, done := newSocketServerTest(t, conf)
+	defer done()
+
+	conn, err := net.Dial("unix", conf.SocketServer.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.Write([]byte("\x00\x00\x00\x07foo\nbar\x00\x00\x00\x03baz")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exp := range []string{"foo\nbar", "baz"} {
+		ts := readSocketServerTran(t, s)
+		if act := string(ts.Payload.Get(0).Get()); exp != act {
+			t.Errorf("Wrong payload: %v != %v", act, exp)
+		}
+		ts.ResponseChan <- response.NewAck()
+	}
+}
+
+func TestSocketServerUDP(t *testing.T) {
+	t.Parallel()
+
+	conf := NewConfig()
+	conf.SocketServer.Network = "udp"
+	conf.SocketServer.Address = "localhost:0"
+
+	s, done := newSocketServerTest(t, conf)
+	defer done()
+
+	conn, err := net.Dial("udp", s.packetConn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.Write([]byte("foo\nbar")); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := readSocketServerTran(t, s)
+	if exp, act := "foo\nbar", string(ts.Payload.Get(0).Get()); exp != act {
+		t.Errorf("Wrong payload: %v != %v", act, exp)
+	}
+	if exp, act := conn.LocalAddr().String(), ts.Payload.Get(0).Metadata().Get("socket_server_remote_addr"); exp != act {
+		t.Errorf("Wrong remote addr: %v != %v", act, exp)
+	}
+	ts.ResponseChan <- response.NewAck()
+}
+
+func TestSocketServerBadConfig(t *testing.T) {
+	t.Parallel()
+
+	conf := NewConfig()
+	if _, err := NewSocketServer(conf, nil, log.Noop(), metrics.DudType{}); err == nil {
+		t.Error("Expected error from empty address")
+	}
+
+	conf.SocketServer.Address = "localhost:0"
+	conf.SocketServer.Network = "nope"
+	if _, err := NewSocketServer(conf, nil, log.Noop(), metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad network")
+	}
+
+	conf.SocketServer.Network = "tcp"
+	conf.SocketServer.Codec = "delim"
+	if _, err := NewSocketServer(conf, nil, log.Noop(), metrics.DudType{}); err == nil {
+		t.Error("Expected error from empty delimiter")
+	}
+}
+
+//------------------------------------------------------------------------------
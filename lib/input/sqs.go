@@ -34,7 +34,14 @@ func init() {
 		constructor: NewAmazonSQS,
 		description: `
 Receive messages from an Amazon SQS URL, only the body is extracted into
-messages.`,
+messages.
+
+### Metadata
+
+If a message carries an AWS X-Ray trace header (the ` + "`AWSTraceHeader`" + `
+system attribute) it is added to the metadata of the message part as
+` + "`xray_trace_header`" + `, which allows the ` + "`xray`" + ` tracer to continue the
+trace.`,
 	}
 }
 
@@ -74,6 +74,12 @@ func (i *WithPipeline) TransactionChan() <-chan types.Transaction {
 
 //------------------------------------------------------------------------------
 
+// Connected returns a boolean indicating whether the wrapped input is currently
+// connected to its target.
+func (i *WithPipeline) Connected() bool {
+	return types.IsConnected(i.in)
+}
+
 // CloseAsync triggers a closure of this object but does not block.
 func (i *WithPipeline) CloseAsync() {
 	i.in.CloseAsync()
@@ -41,4 +41,12 @@ type Modular interface {
 	Traceln(message string)
 }
 
+// ModularWithFields is a Modular logger that is also able to branch new loggers
+// that add structured fields to each log event.
+type ModularWithFields interface {
+	Modular
+
+	WithFields(fields map[string]string) Modular
+}
+
 //------------------------------------------------------------------------------
@@ -176,16 +176,23 @@ func New(stream io.Writer, config Config) Modular {
 		level:  logLevelToInt(config.LogLevel),
 	}
 
-	if len(config.StaticFields) > 0 {
-		jBytes, _ := json.Marshal(config.StaticFields)
-		if len(jBytes) > 2 {
-			logger.extraFields = string(jBytes[1:len(jBytes)-1]) + ","
-		}
-	}
-
+	logger.extraFields = fieldsToJSON(config.StaticFields)
 	return &logger
 }
 
+// fieldsToJSON renders a map of fields as a comma terminated list of JSON
+// object members, ready to be embedded within a JSON log event.
+func fieldsToJSON(fields map[string]string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	jBytes, _ := json.Marshal(fields)
+	if len(jBytes) <= 2 {
+		return ""
+	}
+	return string(jBytes[1:len(jBytes)-1]) + ","
+}
+
 // Noop creates and returns a new logger object that writes nothing.
 func Noop() Modular {
 	return &Logger{
@@ -209,6 +216,27 @@ func (l *Logger) NewModule(prefix string) Modular {
 	}
 }
 
+// WithFields creates a new logger object from the previous, using the same
+// configuration, but adds a map of extra fields to each log event. Fields with
+// the same key as a static field take precedence over it.
+func (l *Logger) WithFields(fields map[string]string) Modular {
+	config := l.config
+	config.StaticFields = make(map[string]string, len(l.config.StaticFields)+len(fields))
+	for k, v := range l.config.StaticFields {
+		config.StaticFields[k] = v
+	}
+	for k, v := range fields {
+		config.StaticFields[k] = v
+	}
+
+	return &Logger{
+		stream:      l.stream,
+		config:      config,
+		level:       l.level,
+		extraFields: fieldsToJSON(config.StaticFields),
+	}
+}
+
 //------------------------------------------------------------------------------
 
 // writeFormatted prints a log message with any configured extras prepended.
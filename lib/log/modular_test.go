@@ -122,6 +122,39 @@ func TestStaticFieldsEmpty(t *testing.T) {
 	}
 }
 
+func TestWithFields(t *testing.T) {
+	loggerConfig := NewConfig()
+	loggerConfig.AddTimeStamp = false
+	loggerConfig.JSONFormat = true
+	loggerConfig.Prefix = "root"
+	loggerConfig.LogLevel = "WARN"
+	loggerConfig.StaticFields = map[string]string{
+		"@service": "benthos_service",
+	}
+
+	buf := LogBuffer{data: ""}
+
+	logger := New(&buf, loggerConfig).NewModule(".foo")
+	fLogger, ok := logger.(ModularWithFields)
+	if !ok {
+		t.Fatal("logger does not support fields")
+	}
+
+	fLogger.WithFields(map[string]string{
+		"@service": "overridden",
+		"id":       "bar",
+	}).Warnln("Warning message with fields")
+	logger.Warnln("Warning message without fields")
+
+	expected := `{"@service":"overridden","id":"bar","level":"WARN","component":"root.foo","message":"Warning message with fields"}
+{"@service":"benthos_service","level":"WARN","component":"root.foo","message":"Warning message without fields"}
+`
+
+	if expected != buf.data {
+		t.Errorf("%v != %v", expected, buf.data)
+	}
+}
+
 func TestFormattedLogging(t *testing.T) {
 	loggerConfig := NewConfig()
 	loggerConfig.AddTimeStamp = false
@@ -138,6 +138,15 @@ func New(
 		pipes:      map[string]<-chan types.Transaction{},
 	}
 
+	// Some cache resources (multilevel) refer to other cache resources. When
+	// they are constructed they check with the manager that the resources they
+	// point to are valid, but obtain them at call time. Since we cannot
+	// guarantee an order of initialisation we create placeholder caches during
+	// construction.
+	for k := range conf.Caches {
+		t.caches[k] = nil
+	}
+
 	for k, conf := range conf.Caches {
 		newCache, err := cache.New(conf, t, log.NewModule(".resource."+k), metrics.Namespaced(stats, "resource."+k))
 		if err != nil {
@@ -57,6 +57,46 @@ func TestManagerCache(t *testing.T) {
 	}
 }
 
+func TestManagerMultilevelCache(t *testing.T) {
+	conf := NewConfig()
+	conf.Caches["foo"] = cache.NewConfig()
+	conf.Caches["bar"] = cache.NewConfig()
+
+	multiConf := cache.NewConfig()
+	multiConf.Type = cache.TypeMultilevel
+	multiConf.Multilevel.Levels = []string{"foo", "bar"}
+	conf.Caches["baz"] = multiConf
+
+	mgr, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	multi, err := mgr.GetCache("baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = multi.Set("a", []byte("b")); err != nil {
+		t.Fatal(err)
+	}
+
+	bar, err := mgr.GetCache("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act, err := bar.Get("a"); err != nil {
+		t.Error(err)
+	} else if exp := "b"; string(act) != exp {
+		t.Errorf("Wrong result: %s != %v", act, exp)
+	}
+
+	multiConf.Multilevel.Levels = []string{"foo", "nope"}
+	conf.Caches["baz"] = multiConf
+	if _, err = New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing cache level")
+	}
+}
+
 func TestManagerBadCache(t *testing.T) {
 	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
 
@@ -92,6 +92,20 @@ func (m *lockedMessage) Iter(f func(i int, b types.Part) error) error {
 	return f(0, m.m.Get(m.part).Copy())
 }
 
+// BatchIndex returns the index of the locked part within its original batch.
+func (m *lockedMessage) BatchIndex() int {
+	if m.part < 0 {
+		return m.m.Len() + m.part
+	}
+	return m.part
+}
+
+// BatchLen returns the number of parts within the original batch of the locked
+// part.
+func (m *lockedMessage) BatchLen() int {
+	return m.m.Len()
+}
+
 func (m *lockedMessage) LazyCondition(label string, cond types.Condition) bool {
 	return cond.Check(m)
 }
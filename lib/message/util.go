@@ -54,6 +54,17 @@ func GetAllBytes(m types.Message) [][]byte {
 	return parts
 }
 
+// GetAllBytesLen returns the total byte size of the raw content of all parts
+// of a message.
+func GetAllBytesLen(m types.Message) int {
+	total := 0
+	m.Iter(func(i int, p types.Part) error {
+		total += len(p.Get())
+		return nil
+	})
+	return total
+}
+
 //------------------------------------------------------------------------------
 
 func cloneMap(oldMap map[string]interface{}) (map[string]interface{}, error) {
@@ -43,6 +43,20 @@ func TestGetAllBytes(t *testing.T) {
 	}
 }
 
+func TestGetAllBytesLen(t *testing.T) {
+	if exp, act := 0, GetAllBytesLen(New(nil)); exp != act {
+		t.Errorf("Wrong length: %v != %v", act, exp)
+	}
+	m := New([][]byte{
+		[]byte("foo"),
+		[]byte("barbaz"),
+		[]byte(""),
+	})
+	if exp, act := 9, GetAllBytesLen(m); exp != act {
+		t.Errorf("Wrong length: %v != %v", act, exp)
+	}
+}
+
 func TestSetAllMetadata(t *testing.T) {
 	meta := metadata.New(map[string]string{
 		"foo": "bar",
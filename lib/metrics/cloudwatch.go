@@ -0,0 +1,456 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/util/aws/session"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/cenkalti/backoff"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	constructors[TypeCloudWatch] = typeSpec{
+		constructor: NewCloudWatch,
+		description: `
+Send metrics to AWS CloudWatch using the PutMetricData endpoint.
+
+Metric values are aggregated and pushed to the configured ` + "`namespace`" + `
+once every ` + "`flush_period`" + `, in batches of at most 20 metrics per
+request. Requests that fail, for example due to throttling, are retried with an
+exponential backoff.
+
+The name of each metric is its path with the configured prefix. Labels of
+metrics (such as those set by the
+` + "[`metric`](../processors/README.md#metric)" + ` processor) are sent as
+dimensions, CloudWatch supports a maximum of 10 dimensions per metric and any
+labels beyond this are discarded.
+
+Counters are sent as the sum of increments since the last flush, gauges are
+sent with their current value only when they have been modified since the last
+flush, and timings are sent as a statistic set of the values recorded since the
+last flush.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type sessionConfig struct {
+	session.Config `json:",inline" yaml:",inline"`
+}
+
+// CloudWatchConfig contains config fields for the CloudWatch metrics type.
+type CloudWatchConfig struct {
+	sessionConfig `json:",inline" yaml:",inline"`
+	Namespace     string `json:"namespace" yaml:"namespace"`
+	FlushPeriod   string `json:"flush_period" yaml:"flush_period"`
+}
+
+// NewCloudWatchConfig creates an CloudWatchConfig struct with default values.
+func NewCloudWatchConfig() CloudWatchConfig {
+	return CloudWatchConfig{
+		sessionConfig: sessionConfig{
+			Config: session.NewConfig(),
+		},
+		Namespace:   "Benthos",
+		FlushPeriod: "100ms",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	maxCloudWatchMetricsPerPut = 20
+	maxCloudWatchDimensions    = 10
+)
+
+type cloudWatchKind int
+
+const (
+	cloudWatchCounter cloudWatchKind = iota
+	cloudWatchGauge
+	cloudWatchTimer
+)
+
+// cloudWatchDatum is the aggregated state of a metric between flushes.
+type cloudWatchDatum struct {
+	name       string
+	dimensions []*cloudwatch.Dimension
+	kind       cloudWatchKind
+
+	value int64
+	dirty bool
+
+	min, max, sum, count int64
+}
+
+// CloudWatchStat is a representation of a single metric stat. Interactions
+// with this stat are thread safe.
+type CloudWatchStat struct {
+	id   string
+	kind cloudWatchKind
+
+	name       string
+	dimensions []*cloudwatch.Dimension
+
+	root *CloudWatch
+}
+
+func (c *CloudWatchStat) update(fn func(d *cloudWatchDatum)) {
+	c.root.datumsMut.Lock()
+	d, exists := c.root.datums[c.id]
+	if !exists {
+		d = &cloudWatchDatum{
+			name:       c.name,
+			dimensions: c.dimensions,
+			kind:       c.kind,
+		}
+		c.root.datums[c.id] = d
+	}
+	fn(d)
+	c.root.datumsMut.Unlock()
+}
+
+// Incr increments a metric by an amount.
+func (c *CloudWatchStat) Incr(count int64) error {
+	c.update(func(d *cloudWatchDatum) {
+		d.value += count
+		d.dirty = true
+	})
+	return nil
+}
+
+// Decr decrements a metric by an amount.
+func (c *CloudWatchStat) Decr(count int64) error {
+	c.update(func(d *cloudWatchDatum) {
+		d.value -= count
+		d.dirty = true
+	})
+	return nil
+}
+
+// Timing sets a timing metric.
+func (c *CloudWatchStat) Timing(delta int64) error {
+	c.update(func(d *cloudWatchDatum) {
+		if d.count == 0 || delta < d.min {
+			d.min = delta
+		}
+		if d.count == 0 || delta > d.max {
+			d.max = delta
+		}
+		d.sum += delta
+		d.count++
+		d.dirty = true
+	})
+	return nil
+}
+
+// Set sets a gauge metric.
+func (c *CloudWatchStat) Set(value int64) error {
+	c.update(func(d *cloudWatchDatum) {
+		d.value = value
+		d.dirty = true
+	})
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// CloudWatch is a stats object that aggregates metrics and periodically pushes
+// them to AWS CloudWatch.
+type CloudWatch struct {
+	config      CloudWatchConfig
+	prefix      string
+	namespace   *string
+	flushPeriod time.Duration
+
+	client cloudwatchiface.CloudWatchAPI
+
+	datumsMut sync.Mutex
+	datums    map[string]*cloudWatchDatum
+
+	log log.Modular
+
+	ctx        context.Context
+	close      func()
+	closedChan chan struct{}
+}
+
+// NewCloudWatch creates and returns a new CloudWatch object.
+func NewCloudWatch(config Config, opts ...func(Type)) (Type, error) {
+	flushPeriod, err := time.ParseDuration(config.CloudWatch.FlushPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse flush period: %v", err)
+	}
+	if flushPeriod <= 0 {
+		return nil, fmt.Errorf("flush period must be greater than zero: %v", flushPeriod)
+	}
+
+	c := &CloudWatch{
+		config:      config.CloudWatch,
+		namespace:   aws.String(config.CloudWatch.Namespace),
+		flushPeriod: flushPeriod,
+		datums:      map[string]*cloudWatchDatum{},
+		log:         log.New(ioutil.Discard, log.Config{LogLevel: "OFF"}),
+		closedChan:  make(chan struct{}),
+	}
+	c.ctx, c.close = context.WithCancel(context.Background())
+	if len(config.Prefix) > 0 {
+		c.prefix = strings.TrimSuffix(config.Prefix, ".") + "."
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	sess, err := config.CloudWatch.GetSession()
+	if err != nil {
+		return nil, err
+	}
+	c.client = cloudwatch.New(sess)
+
+	go c.loop()
+	return c, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (c *CloudWatch) newStat(kind cloudWatchKind, path string, names, values []string) *CloudWatchStat {
+	var dimensions []*cloudwatch.Dimension
+	id := c.prefix + path
+	for i := 0; i < len(names) && i < len(values) && i < maxCloudWatchDimensions; i++ {
+		dimensions = append(dimensions, &cloudwatch.Dimension{
+			Name:  aws.String(names[i]),
+			Value: aws.String(values[i]),
+		})
+	}
+	if len(dimensions) > 0 {
+		sort.Slice(dimensions, func(i, j int) bool {
+			return *dimensions[i].Name < *dimensions[j].Name
+		})
+		for _, d := range dimensions {
+			id += "," + *d.Name + "=" + *d.Value
+		}
+	}
+	return &CloudWatchStat{
+		id:         fmt.Sprintf("%v:%v", kind, id),
+		kind:       kind,
+		name:       c.prefix + path,
+		dimensions: dimensions,
+		root:       c,
+	}
+}
+
+// GetCounter returns a stat counter object for a path.
+func (c *CloudWatch) GetCounter(path string) StatCounter {
+	return c.newStat(cloudWatchCounter, path, nil, nil)
+}
+
+// GetCounterVec returns a stat counter object for a path with the labels
+// emitted as dimensions.
+func (c *CloudWatch) GetCounterVec(path string, n []string) StatCounterVec {
+	return &cloudWatchCounterVec{
+		f: func(labels []string) StatCounter {
+			return c.newStat(cloudWatchCounter, path, n, labels)
+		},
+	}
+}
+
+// GetTimer returns a stat timer object for a path.
+func (c *CloudWatch) GetTimer(path string) StatTimer {
+	return c.newStat(cloudWatchTimer, path, nil, nil)
+}
+
+// GetTimerVec returns a stat timer object for a path with the labels emitted
+// as dimensions.
+func (c *CloudWatch) GetTimerVec(path string, n []string) StatTimerVec {
+	return &cloudWatchTimerVec{
+		f: func(labels []string) StatTimer {
+			return c.newStat(cloudWatchTimer, path, n, labels)
+		},
+	}
+}
+
+// GetGauge returns a stat gauge object for a path.
+func (c *CloudWatch) GetGauge(path string) StatGauge {
+	return c.newStat(cloudWatchGauge, path, nil, nil)
+}
+
+// GetGaugeVec returns a stat gauge object for a path with the labels emitted
+// as dimensions.
+func (c *CloudWatch) GetGaugeVec(path string, n []string) StatGaugeVec {
+	return &cloudWatchGaugeVec{
+		f: func(labels []string) StatGauge {
+			return c.newStat(cloudWatchGauge, path, n, labels)
+		},
+	}
+}
+
+type cloudWatchCounterVec struct {
+	f func(labels []string) StatCounter
+}
+
+func (c *cloudWatchCounterVec) With(labels ...string) StatCounter {
+	return c.f(labels)
+}
+
+type cloudWatchTimerVec struct {
+	f func(labels []string) StatTimer
+}
+
+func (c *cloudWatchTimerVec) With(labels ...string) StatTimer {
+	return c.f(labels)
+}
+
+type cloudWatchGaugeVec struct {
+	f func(labels []string) StatGauge
+}
+
+func (c *cloudWatchGaugeVec) With(labels ...string) StatGauge {
+	return c.f(labels)
+}
+
+//------------------------------------------------------------------------------
+
+// collect drains the aggregated metrics into a slice of datums, resetting
+// counters and timers and clearing the modified flag of gauges.
+func (c *CloudWatch) collect() []*cloudwatch.MetricDatum {
+	now := time.Now()
+
+	c.datumsMut.Lock()
+	defer c.datumsMut.Unlock()
+
+	datums := []*cloudwatch.MetricDatum{}
+	for id, d := range c.datums {
+		if !d.dirty {
+			continue
+		}
+		datum := &cloudwatch.MetricDatum{
+			MetricName: aws.String(d.name),
+			Dimensions: d.dimensions,
+			Timestamp:  aws.Time(now),
+		}
+		switch d.kind {
+		case cloudWatchCounter:
+			datum.Unit = aws.String(cloudwatch.StandardUnitCount)
+			datum.Value = aws.Float64(float64(d.value))
+			delete(c.datums, id)
+		case cloudWatchGauge:
+			datum.Unit = aws.String(cloudwatch.StandardUnitNone)
+			datum.Value = aws.Float64(float64(d.value))
+			d.dirty = false
+		case cloudWatchTimer:
+			datum.Unit = aws.String(cloudwatch.StandardUnitNone)
+			datum.StatisticValues = &cloudwatch.StatisticSet{
+				Minimum:     aws.Float64(float64(d.min)),
+				Maximum:     aws.Float64(float64(d.max)),
+				Sum:         aws.Float64(float64(d.sum)),
+				SampleCount: aws.Float64(float64(d.count)),
+			}
+			delete(c.datums, id)
+		}
+		datums = append(datums, datum)
+	}
+	return datums
+}
+
+// flush pushes all metrics modified since the last flush to CloudWatch, retrying
+// failed requests with a backoff unless retries are disabled.
+func (c *CloudWatch) flush(retry bool) error {
+	datums := c.collect()
+	for len(datums) > 0 {
+		input := &cloudwatch.PutMetricDataInput{
+			Namespace: c.namespace,
+		}
+		if len(datums) > maxCloudWatchMetricsPerPut {
+			input.MetricData, datums = datums[:maxCloudWatchMetricsPerPut], datums[maxCloudWatchMetricsPerPut:]
+		} else {
+			input.MetricData, datums = datums, nil
+		}
+
+		put := func() error {
+			_, err := c.client.PutMetricData(input)
+			return err
+		}
+
+		var err error
+		if retry {
+			boff := backoff.NewExponentialBackOff()
+			boff.InitialInterval = c.flushPeriod
+			boff.MaxInterval = c.flushPeriod * 10
+			boff.MaxElapsedTime = c.flushPeriod * 100
+			err = backoff.Retry(put, backoff.WithContext(boff, c.ctx))
+		} else {
+			err = put()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *CloudWatch) loop() {
+	ticker := time.NewTicker(c.flushPeriod)
+	defer func() {
+		ticker.Stop()
+		if err := c.flush(false); err != nil {
+			c.log.Errorf("Failed to send metrics: %v\n", err)
+		}
+		close(c.closedChan)
+	}()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.flush(true); err != nil {
+				c.log.Errorf("Failed to send metrics: %v\n", err)
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// SetLogger sets the logger used to print connection errors.
+func (c *CloudWatch) SetLogger(log log.Modular) {
+	c.log = log
+}
+
+// Close stops the CloudWatch object from aggregating metrics and cleans up
+// resources.
+func (c *CloudWatch) Close() error {
+	c.close()
+	<-c.closedChan
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,230 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+)
+
+type mockCloudWatchClient struct {
+	cloudwatchiface.CloudWatchAPI
+
+	sync.Mutex
+	errs   []error
+	inputs []*cloudwatch.PutMetricDataInput
+}
+
+func (m *mockCloudWatchClient) PutMetricData(input *cloudwatch.PutMetricDataInput) (*cloudwatch.PutMetricDataOutput, error) {
+	m.Lock()
+	defer m.Unlock()
+	if len(m.errs) > 0 {
+		err := m.errs[0]
+		m.errs = m.errs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	m.inputs = append(m.inputs, input)
+	return &cloudwatch.PutMetricDataOutput{}, nil
+}
+
+func newTestCloudWatch(client cloudwatchiface.CloudWatchAPI) *CloudWatch {
+	c := &CloudWatch{
+		config:      NewCloudWatchConfig(),
+		prefix:      "benthos.",
+		namespace:   aws.String("Test"),
+		flushPeriod: time.Millisecond,
+		client:      client,
+		datums:      map[string]*cloudWatchDatum{},
+		log:         log.Noop(),
+		closedChan:  make(chan struct{}),
+	}
+	c.ctx, c.close = context.WithCancel(context.Background())
+	return c
+}
+
+func datumsByName(inputs []*cloudwatch.PutMetricDataInput) map[string]*cloudwatch.MetricDatum {
+	datums := map[string]*cloudwatch.MetricDatum{}
+	for _, input := range inputs {
+		for _, d := range input.MetricData {
+			name := *d.MetricName
+			for _, dim := range d.Dimensions {
+				name += "," + *dim.Name + "=" + *dim.Value
+			}
+			datums[name] = d
+		}
+	}
+	return datums
+}
+
+func TestCloudWatchInterface(t *testing.T) {
+	if Type(&CloudWatch{}) == nil {
+		t.Errorf("Type does not satisfy Type interface.")
+	}
+}
+
+func TestCloudWatchAggregation(t *testing.T) {
+	client := &mockCloudWatchClient{}
+	c := newTestCloudWatch(client)
+
+	ctr := c.GetCounter("foo.count")
+	ctr.Incr(1)
+	ctr.Incr(2)
+
+	gge := c.GetGauge("foo.gauge")
+	gge.Set(10)
+	gge.Incr(5)
+
+	tmr := c.GetTimer("foo.timer")
+	tmr.Timing(30)
+	tmr.Timing(10)
+	tmr.Timing(20)
+
+	c.GetCounterVec("foo.vec", []string{"b", "a"}).With("bval", "aval").Incr(4)
+
+	if err := c.flush(false); err != nil {
+		t.Fatal(err)
+	}
+
+	datums := datumsByName(client.inputs)
+	if exp, act := 4, len(datums); exp != act {
+		t.Fatalf("Wrong count of datums: %v != %v", act, exp)
+	}
+	if exp, act := "Test", *client.inputs[0].Namespace; exp != act {
+		t.Errorf("Wrong namespace: %v != %v", act, exp)
+	}
+
+	d := datums["benthos.foo.count"]
+	if exp, act := float64(3), *d.Value; exp != act {
+		t.Errorf("Wrong counter value: %v != %v", act, exp)
+	}
+	if exp, act := cloudwatch.StandardUnitCount, *d.Unit; exp != act {
+		t.Errorf("Wrong counter unit: %v != %v", act, exp)
+	}
+
+	if exp, act := float64(15), *datums["benthos.foo.gauge"].Value; exp != act {
+		t.Errorf("Wrong gauge value: %v != %v", act, exp)
+	}
+
+	stats := datums["benthos.foo.timer"].StatisticValues
+	if stats == nil {
+		t.Fatal("Expected timer statistic values")
+	}
+	if *stats.Minimum != 10 || *stats.Maximum != 30 || *stats.Sum != 60 || *stats.SampleCount != 3 {
+		t.Errorf("Wrong timer statistics: %v", stats)
+	}
+
+	if d = datums["benthos.foo.vec,a=aval,b=bval"]; d == nil {
+		t.Errorf("Missing vec metric with dimensions: %v", datums)
+	} else if exp, act := float64(4), *d.Value; exp != act {
+		t.Errorf("Wrong vec value: %v != %v", act, exp)
+	}
+
+	// Unmodified metrics are not sent again.
+	client.inputs = nil
+	if err := c.flush(false); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 0, len(client.inputs); exp != act {
+		t.Errorf("Unexpected requests: %v != %v", act, exp)
+	}
+
+	// Gauges retain their value between flushes.
+	gge.Incr(1)
+	ctr.Incr(1)
+	if err := c.flush(false); err != nil {
+		t.Fatal(err)
+	}
+	datums = datumsByName(client.inputs)
+	if exp, act := float64(16), *datums["benthos.foo.gauge"].Value; exp != act {
+		t.Errorf("Wrong gauge value: %v != %v", act, exp)
+	}
+	if exp, act := float64(1), *datums["benthos.foo.count"].Value; exp != act {
+		t.Errorf("Wrong counter value: %v != %v", act, exp)
+	}
+}
+
+func TestCloudWatchBatching(t *testing.T) {
+	client := &mockCloudWatchClient{}
+	c := newTestCloudWatch(client)
+
+	for i := 0; i < 45; i++ {
+		c.GetCounterVec("foo", []string{"index"}).With(string('a' + rune(i))).Incr(1)
+	}
+	if err := c.flush(false); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := 3, len(client.inputs); exp != act {
+		t.Fatalf("Wrong count of requests: %v != %v", act, exp)
+	}
+	for i, exp := range []int{20, 20, 5} {
+		if act := len(client.inputs[i].MetricData); exp != act {
+			t.Errorf("Wrong batch size of request %v: %v != %v", i, act, exp)
+		}
+	}
+}
+
+func TestCloudWatchRetries(t *testing.T) {
+	client := &mockCloudWatchClient{
+		errs: []error{errors.New("throttled"), errors.New("throttled again")},
+	}
+	c := newTestCloudWatch(client)
+	c.GetCounter("foo").Incr(1)
+
+	if err := c.flush(false); err == nil {
+		t.Error("Expected error without retries")
+	}
+
+	c.GetCounter("foo").Incr(1)
+	if err := c.flush(true); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 1, len(client.inputs); exp != act {
+		t.Fatalf("Wrong count of requests: %v != %v", act, exp)
+	}
+}
+
+func TestCloudWatchClose(t *testing.T) {
+	client := &mockCloudWatchClient{}
+	c := newTestCloudWatch(client)
+	go c.loop()
+
+	c.GetCounter("foo").Incr(1)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	client.Lock()
+	defer client.Unlock()
+	if exp, act := float64(1), *datumsByName(client.inputs)["benthos.foo"].Value; exp != act {
+		t.Errorf("Wrong counter value: %v != %v", act, exp)
+	}
+}
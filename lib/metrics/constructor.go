@@ -52,8 +52,10 @@ var constructors = map[string]typeSpec{}
 
 // String constants representing each metric type.
 const (
+	TypeCloudWatch = "cloudwatch"
 	TypeHTTPServer = "http_server"
 	TypePrometheus = "prometheus"
+	TypeRelabel    = "relabel"
 	TypeStatsd     = "statsd"
 )
 
@@ -62,24 +64,58 @@ const (
 // Config is the all encompassing configuration struct for all metric output
 // types.
 type Config struct {
-	Type       string       `json:"type" yaml:"type"`
-	Prefix     string       `json:"prefix" yaml:"prefix"`
-	HTTP       struct{}     `json:"http_server" yaml:"http_server"`
-	Prometheus struct{}     `json:"prometheus" yaml:"prometheus"`
-	Statsd     StatsdConfig `json:"statsd" yaml:"statsd"`
+	Type             string           `json:"type" yaml:"type"`
+	Prefix           string           `json:"prefix" yaml:"prefix"`
+	ExposePrometheus bool             `json:"expose_prometheus" yaml:"expose_prometheus"`
+	CloudWatch       CloudWatchConfig `json:"cloudwatch" yaml:"cloudwatch"`
+	HTTP             struct{}         `json:"http_server" yaml:"http_server"`
+	Prometheus       PrometheusConfig `json:"prometheus" yaml:"prometheus"`
+	Relabel          RelabelConfig    `json:"relabel" yaml:"relabel"`
+	Statsd           StatsdConfig     `json:"statsd" yaml:"statsd"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Type:       "http_server",
-		Prefix:     "benthos",
-		HTTP:       struct{}{},
-		Prometheus: struct{}{},
-		Statsd:     NewStatsdConfig(),
+		Type:             "http_server",
+		Prefix:           "benthos",
+		ExposePrometheus: false,
+		CloudWatch:       NewCloudWatchConfig(),
+		HTTP:             struct{}{},
+		Prometheus:       NewPrometheusConfig(),
+		Relabel:          NewRelabelConfig(),
+		Statsd:           NewStatsdConfig(),
 	}
 }
 
+// UnmarshalJSON ensures that when parsing child configs the default values are
+// still applied.
+func (c *Config) UnmarshalJSON(bytes []byte) error {
+	type confAlias Config
+	aliased := confAlias(NewConfig())
+
+	if err := json.Unmarshal(bytes, &aliased); err != nil {
+		return err
+	}
+
+	*c = Config(aliased)
+	return nil
+}
+
+// UnmarshalYAML ensures that when parsing child configs the default values are
+// still applied.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type confAlias Config
+	aliased := confAlias(NewConfig())
+
+	if err := unmarshal(&aliased); err != nil {
+		return err
+	}
+
+	*c = Config(aliased)
+	return nil
+}
+
 // SanitiseConfig returns a sanitised version of the Config, meaning sections
 // that aren't relevant to behaviour are removed.
 func SanitiseConfig(conf Config) (interface{}, error) {
@@ -96,6 +132,19 @@ func SanitiseConfig(conf Config) (interface{}, error) {
 	outputMap := map[string]interface{}{}
 	outputMap["type"] = hashMap["type"]
 	outputMap[conf.Type] = hashMap[conf.Type]
+	if conf.Type == TypeRelabel && conf.Relabel.Child != nil {
+		childMap, ok := hashMap[conf.Type].(map[string]interface{})
+		if !ok {
+			return nil, errors.New("failed to sanitise relabel config")
+		}
+		if childMap["child"], err = SanitiseConfig(*conf.Relabel.Child); err != nil {
+			return nil, err
+		}
+	}
+	if conf.ExposePrometheus {
+		outputMap["expose_prometheus"] = true
+		outputMap["prometheus"] = hashMap["prometheus"]
+	}
 
 	return outputMap, nil
 }
@@ -127,6 +176,8 @@ func Descriptions() string {
 	buf.WriteString("\n\n")
 	buf.WriteString("This document has been generated with `benthos --list-metrics`.")
 	buf.WriteString("\n\n")
+	buf.WriteString(exposePrometheusDescription)
+	buf.WriteString("\n\n")
 
 	// Append each description
 	for i, name := range names {
@@ -143,13 +194,21 @@ func Descriptions() string {
 
 // New creates a metric output type based on a configuration.
 func New(conf Config, opts ...func(Type)) (Type, error) {
+	var t Type
 	if conf.Type == "none" {
-		return DudType{}, nil
+		t = DudType{}
+	} else if c, ok := constructors[conf.Type]; ok {
+		var err error
+		if t, err = c.constructor(conf, opts...); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, ErrInvalidMetricOutputType
 	}
-	if c, ok := constructors[conf.Type]; ok {
-		return c.constructor(conf, opts...)
+	if !conf.ExposePrometheus || conf.Type == TypePrometheus {
+		return t, nil
 	}
-	return nil, ErrInvalidMetricOutputType
+	return exposePrometheus(conf, t, opts...)
 }
 
 //------------------------------------------------------------------------------
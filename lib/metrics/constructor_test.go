@@ -48,6 +48,7 @@ func TestSanitise(t *testing.T) {
 			"address":      "foo",
 			"flush_period": "100ms",
 			"network":      "udp",
+			"tag_format":   "none",
 		},
 	}
 
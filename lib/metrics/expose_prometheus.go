@@ -0,0 +1,91 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metrics
+
+import "net/http"
+
+//------------------------------------------------------------------------------
+
+var exposePrometheusDescription = `### Exposing Prometheus
+
+When the field ` + "`expose_prometheus`" + ` is set to ` + "`true`" + ` metrics
+are fed into a Prometheus registry in addition to the configured metrics type,
+and the HTTP endpoint ` + "`/metrics`" + ` serves them for Prometheus scraping.
+The registry also includes Go runtime and process metrics, which can be scraped
+for debugging regardless of where metrics are primarily sent. This field has no
+effect when the type is ` + "`prometheus`" + `.`
+
+//------------------------------------------------------------------------------
+
+// WithPrometheusHandlerFunc is an interface for metrics types that expose a
+// Prometheus scraping endpoint alongside any other metrics they provide. If a
+// Type can be cast into WithPrometheusHandlerFunc then its handler should be
+// registered under the /metrics path of an HTTP server.
+type WithPrometheusHandlerFunc interface {
+	PrometheusHandlerFunc() http.HandlerFunc
+}
+
+//------------------------------------------------------------------------------
+
+// promExposedWrapper feeds all metrics into both a primary Type and a
+// Prometheus Type and exposes the Prometheus scraping endpoint.
+type promExposedWrapper struct {
+	Type
+	prom *Prometheus
+}
+
+// promExposedHandlerWrapper is a promExposedWrapper where the primary Type also
+// exposes its own HTTP endpoint.
+type promExposedHandlerWrapper struct {
+	promExposedWrapper
+	handler WithHandlerFunc
+}
+
+func exposePrometheus(conf Config, t Type, opts ...func(Type)) (Type, error) {
+	p, err := NewPrometheus(conf, opts...)
+	if err != nil {
+		t.Close()
+		return nil, err
+	}
+	w := promExposedWrapper{
+		Type: Combine(t, p),
+		prom: p.(*Prometheus),
+	}
+	if wHandlerFunc, ok := t.(WithHandlerFunc); ok {
+		return &promExposedHandlerWrapper{
+			promExposedWrapper: w,
+			handler:            wHandlerFunc,
+		}, nil
+	}
+	return &w, nil
+}
+
+// PrometheusHandlerFunc returns an http.HandlerFunc for scraping metrics.
+func (w *promExposedWrapper) PrometheusHandlerFunc() http.HandlerFunc {
+	return w.prom.HandlerFunc()
+}
+
+// HandlerFunc returns the http.HandlerFunc of the primary metrics type.
+func (w *promExposedHandlerWrapper) HandlerFunc() http.HandlerFunc {
+	return w.handler.HandlerFunc()
+}
+
+//------------------------------------------------------------------------------
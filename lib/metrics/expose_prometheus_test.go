@@ -0,0 +1,118 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+//------------------------------------------------------------------------------
+
+func TestExposePrometheusHTTPServer(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeHTTPServer
+	conf.Prefix = "exposetesthttp"
+	conf.ExposePrometheus = true
+
+	m, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	wHandlerFunc, ok := m.(WithHandlerFunc)
+	if !ok {
+		t.Fatal("Expected http_server handler to be exposed")
+	}
+	wPromHandlerFunc, ok := m.(WithPrometheusHandlerFunc)
+	if !ok {
+		t.Fatal("Expected Prometheus handler to be exposed")
+	}
+
+	m.GetCounter("foo.bar").Incr(3)
+
+	rec := httptest.NewRecorder()
+	wPromHandlerFunc.PrometheusHandlerFunc()(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := ioutil.ReadAll(rec.Body)
+	if exp, act := "exposetesthttp_foo_bar 3", string(body); !strings.Contains(act, exp) {
+		t.Errorf("Expected '%v' in Prometheus output: %s", exp, act)
+	}
+	if exp, act := "go_goroutines", string(body); !strings.Contains(act, exp) {
+		t.Errorf("Expected '%v' in Prometheus output: %s", exp, act)
+	}
+
+	rec = httptest.NewRecorder()
+	wHandlerFunc.HandlerFunc()(rec, httptest.NewRequest("GET", "/stats", nil))
+	body, _ = ioutil.ReadAll(rec.Body)
+	if exp, act := `"foo":{"bar":3}`, string(body); !strings.Contains(act, exp) {
+		t.Errorf("Expected '%v' in JSON output: %s", exp, act)
+	}
+}
+
+func TestExposePrometheusNoHandler(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "none"
+	conf.Prefix = "exposetestnone"
+	conf.ExposePrometheus = true
+
+	m, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if _, ok := m.(WithHandlerFunc); ok {
+		t.Error("Did not expect a JSON handler to be exposed")
+	}
+	wPromHandlerFunc, ok := m.(WithPrometheusHandlerFunc)
+	if !ok {
+		t.Fatal("Expected Prometheus handler to be exposed")
+	}
+
+	m.GetGauge("baz").Set(5)
+
+	rec := httptest.NewRecorder()
+	wPromHandlerFunc.PrometheusHandlerFunc()(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := ioutil.ReadAll(rec.Body)
+	if exp, act := "exposetestnone_baz 5", string(body); !strings.Contains(act, exp) {
+		t.Errorf("Expected '%v' in Prometheus output: %s", exp, act)
+	}
+}
+
+func TestExposePrometheusDisabled(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeHTTPServer
+
+	m, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if _, ok := m.(WithPrometheusHandlerFunc); ok {
+		t.Error("Did not expect Prometheus handler to be exposed")
+	}
+}
+
+//------------------------------------------------------------------------------
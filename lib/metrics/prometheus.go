@@ -21,13 +21,18 @@
 package metrics
 
 import (
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
 )
 
 //------------------------------------------------------------------------------
@@ -35,7 +40,27 @@ import (
 func init() {
 	constructors[TypePrometheus] = typeSpec{
 		constructor: NewPrometheus,
-		description: `Host endpoints for Prometheus scraping.`,
+		description: `
+Host endpoints for Prometheus scraping.
+
+Timing metrics are recorded in nanoseconds as histograms, allowing latencies to
+be aggregated across instances. The buckets of these histograms can be set
+with the field ` + "`histogram_buckets`" + `, and when left empty the default
+buckets of the Prometheus client (ranging from 5ms to 10s) are used.
+
+### Push Gateway
+
+Short lived instances of Benthos, such as backfill jobs, might finish before
+Prometheus is able to scrape them. When the field ` + "`push_url`" + ` is set
+metrics are also pushed to a
+[Prometheus Push Gateway](https://github.com/prometheus/pushgateway) under the
+job name ` + "`push_job_name`" + `. Metrics are pushed once every
+` + "`push_interval`" + `, when set to a non-empty duration, and a final push is
+always made when Benthos shuts down.
+
+Pushes are performed in the background and never block the flow of messages.
+Failed pushes are logged and counted with the counter
+` + "`metrics.prometheus.push.error`" + `.`,
 	}
 }
 
@@ -43,13 +68,25 @@ func init() {
 
 // PrometheusConfig is config for the Prometheus metrics type.
 type PrometheusConfig struct {
+	HistogramBuckets []float64 `json:"histogram_buckets" yaml:"histogram_buckets"`
+	PushURL          string    `json:"push_url" yaml:"push_url"`
+	PushInterval     string    `json:"push_interval" yaml:"push_interval"`
+	PushJobName      string    `json:"push_job_name" yaml:"push_job_name"`
 }
 
 // NewPrometheusConfig creates an PrometheusConfig struct with default values.
 func NewPrometheusConfig() PrometheusConfig {
-	return PrometheusConfig{}
+	return PrometheusConfig{
+		HistogramBuckets: []float64{},
+		PushURL:          "",
+		PushInterval:     "",
+		PushJobName:      "benthos_push",
+	}
 }
 
+// promPushTimeout is the maximum period to wait for a push to complete.
+var promPushTimeout = time.Second * 10
+
 //------------------------------------------------------------------------------
 
 // PromGauge is a representation of a single metric stat. Interactions with this
@@ -91,12 +128,12 @@ func (p *PromCounter) Incr(count int64) error {
 // PromTiming is a representation of a single metric stat. Interactions with
 // this stat are thread safe.
 type PromTiming struct {
-	sum prometheus.Observer
+	hist prometheus.Observer
 }
 
 // Timing sets a timing metric.
 func (p *PromTiming) Timing(val int64) error {
-	p.sum.Observe(float64(val))
+	p.hist.Observe(float64(val))
 	return nil
 }
 
@@ -116,13 +153,13 @@ func (p *PromCounterVec) With(labelValues ...string) StatCounter {
 
 // PromTimingVec creates StatTimers with dynamic labels.
 type PromTimingVec struct {
-	sum *prometheus.SummaryVec
+	hist *prometheus.HistogramVec
 }
 
 // With returns a StatTimer with a set of label values.
 func (p *PromTimingVec) With(labelValues ...string) StatTimer {
 	return &PromTiming{
-		sum: p.sum.WithLabelValues(labelValues...),
+		hist: p.hist.WithLabelValues(labelValues...),
 	}
 }
 
@@ -143,12 +180,23 @@ func (p *PromGaugeVec) With(labelValues ...string) StatGauge {
 // Prometheus is a stats object with capability to hold internal stats as a JSON
 // endpoint.
 type Prometheus struct {
-	config Config
-	prefix string
+	config  Config
+	prefix  string
+	buckets []float64
 
 	counters map[string]*prometheus.CounterVec
 	gauges   map[string]*prometheus.GaugeVec
-	timers   map[string]*prometheus.SummaryVec
+	timers   map[string]*prometheus.HistogramVec
+
+	pusher       *push.Pusher
+	pushInterval time.Duration
+	mPushErr     StatCounter
+
+	log log.Modular
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+	closeOnce  sync.Once
 
 	sync.Mutex
 }
@@ -156,22 +204,88 @@ type Prometheus struct {
 // NewPrometheus creates and returns a new Prometheus object.
 func NewPrometheus(config Config, opts ...func(Type)) (Type, error) {
 	p := &Prometheus{
-		config:   config,
-		prefix:   toPromName(config.Prefix),
-		counters: map[string]*prometheus.CounterVec{},
-		gauges:   map[string]*prometheus.GaugeVec{},
-		timers:   map[string]*prometheus.SummaryVec{},
+		config:     config,
+		prefix:     toPromName(config.Prefix),
+		buckets:    config.Prometheus.HistogramBuckets,
+		counters:   map[string]*prometheus.CounterVec{},
+		gauges:     map[string]*prometheus.GaugeVec{},
+		timers:     map[string]*prometheus.HistogramVec{},
+		log:        log.New(ioutil.Discard, log.Config{LogLevel: "OFF"}),
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}
+	if len(p.buckets) == 0 {
+		p.buckets = make([]float64, len(prometheus.DefBuckets))
+		for i, b := range prometheus.DefBuckets {
+			p.buckets[i] = b * float64(time.Second)
+		}
+	}
+
+	if len(config.Prometheus.PushURL) > 0 {
+		if len(config.Prometheus.PushJobName) == 0 {
+			return nil, errors.New("push_job_name must not be empty when push_url is set")
+		}
+		if len(config.Prometheus.PushInterval) > 0 {
+			var err error
+			if p.pushInterval, err = time.ParseDuration(config.Prometheus.PushInterval); err != nil {
+				return nil, fmt.Errorf("failed to parse push interval: %v", err)
+			}
+			if p.pushInterval <= 0 {
+				return nil, fmt.Errorf("push interval must be greater than zero: %v", p.pushInterval)
+			}
+		}
+		p.pusher = push.New(config.Prometheus.PushURL, config.Prometheus.PushJobName).
+			Gatherer(prometheus.DefaultGatherer).
+			Client(&http.Client{Timeout: promPushTimeout})
+		p.mPushErr = p.GetCounter("metrics.prometheus.push.error")
 	}
 
 	for _, opt := range opts {
 		opt(p)
 	}
 
+	if p.pusher != nil {
+		go p.pushLoop()
+	} else {
+		close(p.closedChan)
+	}
 	return p, nil
 }
 
 //------------------------------------------------------------------------------
 
+// pushMetrics pushes the current state of all metrics to the push gateway.
+func (p *Prometheus) pushMetrics() {
+	if err := p.pusher.Push(); err != nil {
+		p.mPushErr.Incr(1)
+		p.log.Warnf("Failed to push metrics: %v\n", err)
+	}
+}
+
+func (p *Prometheus) pushLoop() {
+	defer func() {
+		p.pushMetrics()
+		close(p.closedChan)
+	}()
+
+	var tick <-chan time.Time
+	if p.pushInterval > 0 {
+		ticker := time.NewTicker(p.pushInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-tick:
+			p.pushMetrics()
+		case <-p.closeChan:
+			return
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
 // HandlerFunc returns an http.HandlerFunc for scraping metrics.
 func (p *Prometheus) HandlerFunc() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -215,15 +329,16 @@ func (p *Prometheus) GetCounter(path string) StatCounter {
 func (p *Prometheus) GetTimer(path string) StatTimer {
 	stat := toPromName(path)
 
-	var tmr *prometheus.SummaryVec
+	var tmr *prometheus.HistogramVec
 
 	p.Lock()
 	var exists bool
 	if tmr, exists = p.timers[stat]; !exists {
-		tmr = prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		tmr = prometheus.NewHistogramVec(prometheus.HistogramOpts{
 			Namespace: p.prefix,
 			Name:      stat,
 			Help:      "Benthos Timing metric",
+			Buckets:   p.buckets,
 		}, nil)
 		prometheus.MustRegister(tmr)
 		p.timers[stat] = tmr
@@ -231,7 +346,7 @@ func (p *Prometheus) GetTimer(path string) StatTimer {
 	p.Unlock()
 
 	return &PromTiming{
-		sum: tmr.WithLabelValues(),
+		hist: tmr.WithLabelValues(),
 	}
 }
 
@@ -291,15 +406,16 @@ func (p *Prometheus) GetCounterVec(path string, labelNames []string) StatCounter
 func (p *Prometheus) GetTimerVec(path string, labelNames []string) StatTimerVec {
 	stat := toPromName(path)
 
-	var tmr *prometheus.SummaryVec
+	var tmr *prometheus.HistogramVec
 
 	p.Lock()
 	var exists bool
 	if tmr, exists = p.timers[stat]; !exists {
-		tmr = prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		tmr = prometheus.NewHistogramVec(prometheus.HistogramOpts{
 			Namespace: p.prefix,
 			Name:      stat,
 			Help:      "Benthos Timing metric",
+			Buckets:   p.buckets,
 		}, labelNames)
 		prometheus.MustRegister(tmr)
 		p.timers[stat] = tmr
@@ -307,7 +423,7 @@ func (p *Prometheus) GetTimerVec(path string, labelNames []string) StatTimerVec
 	p.Unlock()
 
 	return &PromTimingVec{
-		sum: tmr,
+		hist: tmr,
 	}
 }
 
@@ -337,13 +453,18 @@ func (p *Prometheus) GetGaugeVec(path string, labelNames []string) StatGaugeVec
 	}
 }
 
-// SetLogger does nothing.
+// SetLogger sets the logger used to print push errors.
 func (p *Prometheus) SetLogger(log log.Modular) {
+	p.log = log
 }
 
 // Close stops the Prometheus object from aggregating metrics and cleans up
-// resources.
+// resources, performing a final push when a push gateway is configured.
 func (p *Prometheus) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.closeChan)
+	})
+	<-p.closedChan
 	return nil
 }
 
@@ -0,0 +1,199 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+//------------------------------------------------------------------------------
+
+func scrapePrometheus(t *testing.T) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	promhttp.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	return w.Body.String()
+}
+
+func assertPromLines(t *testing.T, exp ...string) {
+	t.Helper()
+	body := scrapePrometheus(t)
+	for _, e := range exp {
+		if !strings.Contains(body, e+"\n") {
+			t.Errorf("Expected line '%v' in scrape: %v", e, body)
+		}
+	}
+}
+
+func TestPrometheusHistogram(t *testing.T) {
+	conf := NewConfig()
+	conf.Prefix = "promhisttest"
+	conf.Prometheus.HistogramBuckets = []float64{10, 100}
+
+	p, err := NewPrometheus(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	tmr := p.GetTimer("foo")
+	tmr.Timing(5)
+	tmr.Timing(50)
+	tmr.Timing(500)
+
+	p.GetTimerVec("bar", []string{"label"}).With("baz").Timing(50)
+
+	assertPromLines(t,
+		"# TYPE promhisttest_foo histogram",
+		`promhisttest_foo_bucket{le="10"} 1`,
+		`promhisttest_foo_bucket{le="100"} 2`,
+		`promhisttest_foo_bucket{le="+Inf"} 3`,
+		"promhisttest_foo_count 3",
+		"# TYPE promhisttest_bar histogram",
+		`promhisttest_bar_bucket{label="baz",le="100"} 1`,
+	)
+}
+
+func TestPrometheusDefaultBuckets(t *testing.T) {
+	conf := NewConfig()
+	conf.Prefix = "promdefbuckettest"
+
+	p, err := NewPrometheus(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	p.GetTimer("foo").Timing(int64(time.Millisecond * 20))
+
+	body := scrapePrometheus(t)
+	if exp, act := len(prometheus.DefBuckets)+1, strings.Count(body, "promdefbuckettest_foo_bucket{"); exp != act {
+		t.Errorf("Wrong count of buckets: %v != %v", act, exp)
+	}
+	assertPromLines(t,
+		`promdefbuckettest_foo_bucket{le="1e+07"} 0`,
+		`promdefbuckettest_foo_bucket{le="2.5e+07"} 1`,
+		`promdefbuckettest_foo_bucket{le="1e+10"} 1`,
+	)
+}
+
+func TestPrometheusBadPushConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Prometheus.PushURL = "http://localhost:9091"
+	conf.Prometheus.PushInterval = "not a duration"
+	if _, err := NewPrometheus(conf); err == nil {
+		t.Error("Expected error from bad push interval")
+	}
+
+	conf.Prometheus.PushInterval = ""
+	conf.Prometheus.PushJobName = ""
+	if _, err := NewPrometheus(conf); err == nil {
+		t.Error("Expected error from empty job name")
+	}
+}
+
+func TestPrometheusPush(t *testing.T) {
+	var pushesMut sync.Mutex
+	var pushes []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushesMut.Lock()
+		pushes = append(pushes, r.Method+" "+r.URL.Path)
+		pushesMut.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	conf := NewConfig()
+	conf.Prefix = "prompushtest"
+	conf.Prometheus.PushURL = server.URL
+	conf.Prometheus.PushJobName = "foo"
+
+	p, err := NewPrometheus(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.GetCounter("bar").Incr(1)
+
+	// Without an interval only the final push is made.
+	if err = p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	pushesMut.Lock()
+	if exp, act := []string{"PUT /metrics/job/foo"}, pushes; len(act) != 1 || act[0] != exp[0] {
+		t.Errorf("Wrong pushes: %v != %v", act, exp)
+	}
+	pushes = nil
+	pushesMut.Unlock()
+
+	conf.Prefix = "prompushintervaltest"
+	conf.Prometheus.PushInterval = "1ms"
+	if p, err = NewPrometheus(conf); err != nil {
+		t.Fatal(err)
+	}
+	<-time.After(time.Millisecond * 50)
+	if err = p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	pushesMut.Lock()
+	if act := len(pushes); act < 2 {
+		t.Errorf("Expected multiple pushes, got %v", act)
+	}
+	pushesMut.Unlock()
+
+	assertPromLines(t,
+		"prompushtest_metrics_prometheus_push_error 0",
+		"prompushintervaltest_metrics_prometheus_push_error 0",
+	)
+}
+
+func TestPrometheusPushError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	conf := NewConfig()
+	conf.Prefix = "prompusherrtest"
+	conf.Prometheus.PushURL = server.URL
+
+	p, err := NewPrometheus(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	assertPromLines(t, "prompusherrtest_metrics_prometheus_push_error 1")
+}
+
+//------------------------------------------------------------------------------
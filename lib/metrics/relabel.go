@@ -0,0 +1,396 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/Jeffail/benthos/lib/log"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	constructors[TypeRelabel] = typeSpec{
+		constructor: NewRelabel,
+		description: `
+Filters and renames the metric paths of a child metrics type, allowing the
+number of series sent to a metrics target to be reduced. Paths are matched
+before the prefix of the child type is added.
+
+` + "``` yaml" + `
+metrics:
+  type: relabel
+  relabel:
+    include:
+    - ^output\.
+    exclude:
+    - \.latency$
+    rules:
+    - pattern: ^output\.broker\.outputs\.([0-9]+)\.(.*)$
+      value: output.broker.$2
+      to_label:
+        output: $1
+    child:
+      type: statsd
+      statsd:
+        address: localhost:8125
+        flush_period: 100ms
+` + "```" + `
+
+When the list ` + "`include`" + ` is not empty only metrics whose paths match
+at least one of its regular expressions are kept, and metrics whose paths match
+any regular expression of ` + "`exclude`" + ` are always dropped. The
+expressions are not anchored unless they contain ` + "`^`" + ` and
+` + "`$`" + `.
+
+The ` + "`rules`" + ` are then applied in order to the paths that remain, each
+to the result of the previous. When the ` + "`pattern`" + ` of a rule matches a
+path each match is replaced with ` + "`value`" + `, which can reference capture
+groups with ` + "`$1`" + `, ` + "`${name}`" + `, etc. A rule can also lift parts
+of a path into labels with ` + "`to_label`" + `, a map of label names to values
+that can reference capture groups in the same way.
+
+Labels are only supported by some metrics targets, such as
+` + "`prometheus`" + `, and are ignored by the rest. Metrics targets that
+support labels might require the label names of a path to be consistent across
+all metrics that share it.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// RelabelRuleConfig contains config fields for a single rename rule of the
+// Relabel metrics type.
+type RelabelRuleConfig struct {
+	Pattern string            `json:"pattern" yaml:"pattern"`
+	Value   string            `json:"value" yaml:"value"`
+	ToLabel map[string]string `json:"to_label" yaml:"to_label"`
+}
+
+// RelabelConfig contains config fields for the Relabel metrics type.
+type RelabelConfig struct {
+	Include []string            `json:"include" yaml:"include"`
+	Exclude []string            `json:"exclude" yaml:"exclude"`
+	Rules   []RelabelRuleConfig `json:"rules" yaml:"rules"`
+	Child   *Config             `json:"child" yaml:"child"`
+}
+
+// NewRelabelConfig returns a RelabelConfig with default values.
+func NewRelabelConfig() RelabelConfig {
+	return RelabelConfig{
+		Include: []string{},
+		Exclude: []string{},
+		Rules:   []RelabelRuleConfig{},
+		Child:   nil,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dummyRelabelConfig struct {
+	Include []string            `json:"include" yaml:"include"`
+	Exclude []string            `json:"exclude" yaml:"exclude"`
+	Rules   []RelabelRuleConfig `json:"rules" yaml:"rules"`
+	Child   interface{}         `json:"child" yaml:"child"`
+}
+
+func (r RelabelConfig) dummy() dummyRelabelConfig {
+	dummy := dummyRelabelConfig{
+		Include: r.Include,
+		Exclude: r.Exclude,
+		Rules:   r.Rules,
+		Child:   r.Child,
+	}
+	if r.Child == nil {
+		dummy.Child = struct{}{}
+	}
+	return dummy
+}
+
+// MarshalJSON prints an empty object instead of nil.
+func (r RelabelConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.dummy())
+}
+
+// MarshalYAML prints an empty object instead of nil.
+func (r RelabelConfig) MarshalYAML() (interface{}, error) {
+	return r.dummy(), nil
+}
+
+//------------------------------------------------------------------------------
+
+type relabelRule struct {
+	pattern *regexp.Regexp
+	value   string
+	labels  []string
+	tmpls   []string
+}
+
+// Relabel is a metrics type that filters and renames the paths of metrics
+// before passing them to a child metrics type.
+type Relabel struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+	rules   []relabelRule
+
+	child Type
+}
+
+func compilePatterns(field string, patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile %v pattern '%v': %v", field, p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// NewRelabel creates and returns a new Relabel object.
+func NewRelabel(config Config, opts ...func(Type)) (Type, error) {
+	if config.Relabel.Child == nil {
+		return nil, errors.New("cannot create relabel metrics without a child")
+	}
+
+	r := &Relabel{}
+
+	var err error
+	if r.include, err = compilePatterns("include", config.Relabel.Include); err != nil {
+		return nil, err
+	}
+	if r.exclude, err = compilePatterns("exclude", config.Relabel.Exclude); err != nil {
+		return nil, err
+	}
+	for _, rConf := range config.Relabel.Rules {
+		rule := relabelRule{
+			value: rConf.Value,
+		}
+		if rule.pattern, err = regexp.Compile(rConf.Pattern); err != nil {
+			return nil, fmt.Errorf("failed to compile rule pattern '%v': %v", rConf.Pattern, err)
+		}
+		for k := range rConf.ToLabel {
+			rule.labels = append(rule.labels, k)
+		}
+		sort.Strings(rule.labels)
+		for _, k := range rule.labels {
+			rule.tmpls = append(rule.tmpls, rConf.ToLabel[k])
+		}
+		r.rules = append(r.rules, rule)
+	}
+
+	if r.child, err = New(*config.Relabel.Child, opts...); err != nil {
+		return nil, fmt.Errorf("failed to create child metrics '%v': %v", config.Relabel.Child.Type, err)
+	}
+
+	// Expose any HTTP endpoints of the child.
+	h, hasHandler := r.child.(WithHandlerFunc)
+	p, hasProm := r.child.(WithPrometheusHandlerFunc)
+	switch {
+	case hasHandler && hasProm:
+		return struct {
+			Type
+			WithHandlerFunc
+			WithPrometheusHandlerFunc
+		}{r, h, p}, nil
+	case hasHandler:
+		return struct {
+			Type
+			WithHandlerFunc
+		}{r, h}, nil
+	case hasProm:
+		return struct {
+			Type
+			WithPrometheusHandlerFunc
+		}{r, p}, nil
+	}
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+// relabel returns the new path and any labels of a metric, or false if the
+// metric should be dropped.
+func (r *Relabel) relabel(path string) (newPath string, labels, values []string, keep bool) {
+	if len(r.include) > 0 {
+		for _, re := range r.include {
+			if keep = re.MatchString(path); keep {
+				break
+			}
+		}
+		if !keep {
+			return
+		}
+	}
+	for _, re := range r.exclude {
+		if re.MatchString(path) {
+			return "", nil, nil, false
+		}
+	}
+
+	for _, rule := range r.rules {
+		match := rule.pattern.FindStringSubmatchIndex(path)
+		if match == nil {
+			continue
+		}
+		for i, tmpl := range rule.tmpls {
+			labels = append(labels, rule.labels[i])
+			values = append(values, string(rule.pattern.ExpandString(nil, tmpl, path, match)))
+		}
+		path = rule.pattern.ReplaceAllString(path, rule.value)
+	}
+	return path, labels, values, true
+}
+
+// GetCounter returns a stat counter object for a path.
+func (r *Relabel) GetCounter(path string) StatCounter {
+	newPath, labels, values, keep := r.relabel(path)
+	if !keep {
+		return DudStat{}
+	}
+	if len(labels) == 0 {
+		return r.child.GetCounter(newPath)
+	}
+	return r.child.GetCounterVec(newPath, labels).With(values...)
+}
+
+// GetCounterVec returns a stat counter object for a path with labels, where the
+// values of any labels lifted from the path are appended to those given.
+func (r *Relabel) GetCounterVec(path string, n []string) StatCounterVec {
+	newPath, labels, values, keep := r.relabel(path)
+	if !keep {
+		return fakeCounterVec(func() StatCounter {
+			return DudStat{}
+		})
+	}
+	vec := r.child.GetCounterVec(newPath, append(append([]string{}, n...), labels...))
+	if len(labels) == 0 {
+		return vec
+	}
+	return &relabelCounterVec{vec: vec, values: values}
+}
+
+// GetTimer returns a stat timer object for a path.
+func (r *Relabel) GetTimer(path string) StatTimer {
+	newPath, labels, values, keep := r.relabel(path)
+	if !keep {
+		return DudStat{}
+	}
+	if len(labels) == 0 {
+		return r.child.GetTimer(newPath)
+	}
+	return r.child.GetTimerVec(newPath, labels).With(values...)
+}
+
+// GetTimerVec returns a stat timer object for a path with labels, where the
+// values of any labels lifted from the path are appended to those given.
+func (r *Relabel) GetTimerVec(path string, n []string) StatTimerVec {
+	newPath, labels, values, keep := r.relabel(path)
+	if !keep {
+		return fakeTimerVec(func() StatTimer {
+			return DudStat{}
+		})
+	}
+	vec := r.child.GetTimerVec(newPath, append(append([]string{}, n...), labels...))
+	if len(labels) == 0 {
+		return vec
+	}
+	return &relabelTimerVec{vec: vec, values: values}
+}
+
+// GetGauge returns a stat gauge object for a path.
+func (r *Relabel) GetGauge(path string) StatGauge {
+	newPath, labels, values, keep := r.relabel(path)
+	if !keep {
+		return DudStat{}
+	}
+	if len(labels) == 0 {
+		return r.child.GetGauge(newPath)
+	}
+	return r.child.GetGaugeVec(newPath, labels).With(values...)
+}
+
+// GetGaugeVec returns a stat gauge object for a path with labels, where the
+// values of any labels lifted from the path are appended to those given.
+func (r *Relabel) GetGaugeVec(path string, n []string) StatGaugeVec {
+	newPath, labels, values, keep := r.relabel(path)
+	if !keep {
+		return fakeGaugeVec(func() StatGauge {
+			return DudStat{}
+		})
+	}
+	vec := r.child.GetGaugeVec(newPath, append(append([]string{}, n...), labels...))
+	if len(labels) == 0 {
+		return vec
+	}
+	return &relabelGaugeVec{vec: vec, values: values}
+}
+
+// SetLogger sets the logger of the child metrics type.
+func (r *Relabel) SetLogger(log log.Modular) {
+	r.child.SetLogger(log)
+}
+
+// Close stops aggregating stats and cleans up resources.
+func (r *Relabel) Close() error {
+	return r.child.Close()
+}
+
+//------------------------------------------------------------------------------
+
+// relabelCounterVec appends the values of lifted labels to those of each
+// counter.
+type relabelCounterVec struct {
+	vec    StatCounterVec
+	values []string
+}
+
+func (r *relabelCounterVec) With(labelValues ...string) StatCounter {
+	return r.vec.With(append(append([]string{}, labelValues...), r.values...)...)
+}
+
+// relabelTimerVec appends the values of lifted labels to those of each timer.
+type relabelTimerVec struct {
+	vec    StatTimerVec
+	values []string
+}
+
+func (r *relabelTimerVec) With(labelValues ...string) StatTimer {
+	return r.vec.With(append(append([]string{}, labelValues...), r.values...)...)
+}
+
+// relabelGaugeVec appends the values of lifted labels to those of each gauge.
+type relabelGaugeVec struct {
+	vec    StatGaugeVec
+	values []string
+}
+
+func (r *relabelGaugeVec) With(labelValues ...string) StatGauge {
+	return r.vec.With(append(append([]string{}, labelValues...), r.values...)...)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,269 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metrics
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	yaml "gopkg.in/yaml.v2"
+)
+
+//------------------------------------------------------------------------------
+
+// recordingType records the paths and labels of counters it has been given.
+type recordingType struct {
+	DudType
+
+	sync.Mutex
+	values map[string]int64
+}
+
+func (r *recordingType) key(path string, labels, values []string) string {
+	if len(labels) == 0 {
+		return path
+	}
+	pairs := make([]string, len(labels))
+	for i, l := range labels {
+		pairs[i] = l + "=" + values[i]
+	}
+	return path + "{" + strings.Join(pairs, ",") + "}"
+}
+
+type recordingCounter struct {
+	r   *recordingType
+	key string
+}
+
+func (r *recordingCounter) Incr(count int64) error {
+	r.r.Lock()
+	r.r.values[r.key] += count
+	r.r.Unlock()
+	return nil
+}
+
+func (r *recordingType) GetCounter(path string) StatCounter {
+	return &recordingCounter{r: r, key: path}
+}
+
+func (r *recordingType) GetCounterVec(path string, labels []string) StatCounterVec {
+	return &recordingCounterVec{r: r, path: path, labels: labels}
+}
+
+type recordingCounterVec struct {
+	r      *recordingType
+	path   string
+	labels []string
+}
+
+func (r *recordingCounterVec) With(values ...string) StatCounter {
+	return &recordingCounter{r: r.r, key: r.r.key(r.path, r.labels, values)}
+}
+
+func (r *recordingType) SetLogger(log.Modular) {}
+
+//------------------------------------------------------------------------------
+
+func newRelabelWithRecorder(t *testing.T, conf Config) (*Relabel, *recordingType) {
+	t.Helper()
+
+	childConf := NewConfig()
+	childConf.Type = "none"
+	conf.Type = TypeRelabel
+	conf.Relabel.Child = &childConf
+
+	m, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, ok := m.(*Relabel)
+	if !ok {
+		t.Fatalf("Wrong type: %T", m)
+	}
+
+	rec := &recordingType{values: map[string]int64{}}
+	r.child = rec
+	return r, rec
+}
+
+func TestRelabelErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeRelabel
+	if _, err := New(conf); err == nil {
+		t.Error("Expected error from missing child")
+	}
+
+	childConf := NewConfig()
+	childConf.Type = "none"
+	conf.Relabel.Child = &childConf
+	conf.Relabel.Include = []string{"("}
+	if _, err := New(conf); err == nil {
+		t.Error("Expected error from bad include pattern")
+	}
+
+	conf.Relabel.Include = nil
+	conf.Relabel.Rules = []RelabelRuleConfig{{Pattern: "("}}
+	if _, err := New(conf); err == nil {
+		t.Error("Expected error from bad rule pattern")
+	}
+}
+
+func TestRelabelFilter(t *testing.T) {
+	conf := NewConfig()
+	conf.Relabel.Include = []string{`^output\.`, `^input\.received$`}
+	conf.Relabel.Exclude = []string{`\.error$`}
+
+	r, rec := newRelabelWithRecorder(t, conf)
+
+	for _, path := range []string{
+		"output.broker.outputs.0.send.success",
+		"output.broker.outputs.0.send.error",
+		"input.received",
+		"input.received.bytes",
+		"pipeline.processor.0.count",
+	} {
+		r.GetCounter(path).Incr(1)
+		r.GetCounterVec(path, nil).With().Incr(1)
+		r.GetTimer(path).Timing(1)
+		r.GetGauge(path).Set(1)
+	}
+
+	exp := map[string]int64{
+		"output.broker.outputs.0.send.success": 2,
+		"input.received":                       2,
+	}
+	if !reflect.DeepEqual(exp, rec.values) {
+		t.Errorf("Wrong counters: %v != %v", rec.values, exp)
+	}
+}
+
+func TestRelabelRules(t *testing.T) {
+	conf := NewConfig()
+	conf.Relabel.Rules = []RelabelRuleConfig{
+		{
+			Pattern: `^output\.broker\.outputs\.([0-9]+)\.(.*)$`,
+			Value:   "output.broker.$2",
+			ToLabel: map[string]string{
+				"output": "$1",
+			},
+		},
+		{
+			Pattern: `\.send\.`,
+			Value:   ".",
+		},
+		{
+			Pattern: `^(?P<layer>[a-z]+)\.broker\.success$`,
+			Value:   "broker.success",
+			ToLabel: map[string]string{
+				"layer": "${layer}",
+			},
+		},
+	}
+
+	r, rec := newRelabelWithRecorder(t, conf)
+
+	r.GetCounter("output.broker.outputs.0.send.success").Incr(1)
+	r.GetCounter("output.broker.outputs.1.send.success").Incr(2)
+	r.GetCounter("output.broker.outputs.1.send.error").Incr(3)
+	r.GetCounter("input.send.error").Incr(4)
+	r.GetCounterVec("output.broker.outputs.2.send.success", []string{"foo"}).With("bar").Incr(5)
+	r.GetCounter("input.received").Incr(6)
+
+	exp := map[string]int64{
+		"broker.success{output=0,layer=output}":         1,
+		"broker.success{output=1,layer=output}":         2,
+		"output.broker.error{output=1}":                 3,
+		"input.error":                                   4,
+		"broker.success{foo=bar,output=2,layer=output}": 5,
+		"input.received":                                6,
+	}
+	if !reflect.DeepEqual(exp, rec.values) {
+		t.Errorf("Wrong counters: %v != %v", rec.values, exp)
+	}
+}
+
+func TestRelabelSanitise(t *testing.T) {
+	childConf := NewConfig()
+	childConf.Type = TypeStatsd
+
+	conf := NewConfig()
+	conf.Type = TypeRelabel
+	conf.Relabel.Child = &childConf
+
+	sanit, err := SanitiseConfig(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	child := sanit.(map[string]interface{})["relabel"].(map[string]interface{})["child"].(map[string]interface{})
+	if _, exists := child["prometheus"]; exists {
+		t.Errorf("Expected child config to be sanitised: %v", child)
+	}
+	if exp, act := TypeStatsd, child["type"]; exp != act {
+		t.Errorf("Wrong child type: %v != %v", act, exp)
+	}
+}
+
+func TestRelabelExposesHandler(t *testing.T) {
+	childConf := NewConfig()
+	childConf.Type = TypeHTTPServer
+
+	conf := NewConfig()
+	conf.Type = TypeRelabel
+	conf.Relabel.Child = &childConf
+
+	m, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if _, ok := m.(WithHandlerFunc); !ok {
+		t.Error("Expected relabel to expose the handler of its child")
+	}
+}
+
+func TestRelabelChildDefaults(t *testing.T) {
+	conf := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+type: relabel
+relabel:
+  child:
+    type: statsd
+`), &conf); err != nil {
+		t.Fatal(err)
+	}
+
+	if conf.Relabel.Child == nil {
+		t.Fatal("Expected child config")
+	}
+	if exp, act := NewStatsdConfig(), conf.Relabel.Child.Statsd; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong child statsd config: %v != %v", act, exp)
+	}
+	if exp, act := "benthos", conf.Relabel.Child.Prefix; exp != act {
+		t.Errorf("Wrong child prefix: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -23,10 +23,12 @@ package metrics
 import (
 	"fmt"
 	"io/ioutil"
+	"strings"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/quipo/statsd"
+	"github.com/quipo/statsd/event"
 )
 
 //------------------------------------------------------------------------------
@@ -34,7 +36,14 @@ import (
 func init() {
 	constructors[TypeStatsd] = typeSpec{
 		constructor: NewStatsd,
-		description: `Use the statsd protocol.`,
+		description: `
+Use the statsd protocol.
+
+The field ` + "`tag_format`" + ` determines how the labels of metrics (such as
+those set by the ` + "[`metric`](../processors/README.md#metric)" + ` processor)
+are emitted. Labels are discarded when set to ` + "`none`" + `, otherwise they
+are sent as native tags in either the ` + "`datadog`" + ` (DogStatsD) or
+` + "`influxdb`" + ` format.`,
 	}
 }
 
@@ -50,11 +59,19 @@ func (w *wrappedLogger) Println(v ...interface{}) {
 
 //------------------------------------------------------------------------------
 
+// Statsd tag formats.
+const (
+	TagFormatNone     = "none"
+	TagFormatDatadog  = "datadog"
+	TagFormatInfluxDB = "influxdb"
+)
+
 // StatsdConfig is config for the Statsd metrics type.
 type StatsdConfig struct {
 	Address     string `json:"address" yaml:"address"`
 	FlushPeriod string `json:"flush_period" yaml:"flush_period"`
 	Network     string `json:"network" yaml:"network"`
+	TagFormat   string `json:"tag_format" yaml:"tag_format"`
 }
 
 // NewStatsdConfig creates an StatsdConfig struct with default values.
@@ -63,6 +80,7 @@ func NewStatsdConfig() StatsdConfig {
 		Address:     "localhost:4040",
 		FlushPeriod: "100ms",
 		Network:     "udp",
+		TagFormat:   TagFormatNone,
 	}
 }
 
@@ -101,12 +119,94 @@ func (s *StatsdStat) Set(value int64) error {
 
 //------------------------------------------------------------------------------
 
+var tagReplacer = strings.NewReplacer(
+	",", "_", "=", "_", ":", "_", "|", "_", "#", "_", " ", "_",
+)
+
+// taggedPath encodes a set of labels into a metric path as a comma separated
+// list of key=value pairs, which is later used by taggedEvent in order to
+// render the tags in the configured format.
+func taggedPath(path string, names, values []string) string {
+	if len(names) == 0 {
+		return path
+	}
+	tags := make([]string, 0, len(names))
+	for i, name := range names {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		tags = append(tags, tagReplacer.Replace(name)+"="+tagReplacer.Replace(value))
+	}
+	return path + "," + strings.Join(tags, ",")
+}
+
+// taggedEvent wraps a statsd event and renders the tags encoded within its key
+// in a given format.
+type taggedEvent struct {
+	event.Event
+	format string
+}
+
+// Stats returns the statsd lines of the event with tags rendered.
+func (e taggedEvent) Stats() []string {
+	stats := e.Event.Stats()
+	key := e.Key()
+	i := strings.IndexByte(key, ',')
+	if i == -1 {
+		return stats
+	}
+	name, tags := key[:i], key[i+1:]
+
+	tagged := make([]string, 0, len(stats))
+	for _, stat := range stats {
+		if !strings.HasPrefix(stat, key) {
+			tagged = append(tagged, stat)
+			continue
+		}
+		rest := stat[len(key):]
+		j := strings.IndexByte(rest, ':')
+		if j == -1 {
+			tagged = append(tagged, stat)
+			continue
+		}
+		suffix, value := rest[:j], rest[j+1:]
+		switch e.format {
+		case TagFormatDatadog:
+			stat = name + suffix + ":" + value + "|#" + strings.Replace(tags, "=", ":", -1)
+		case TagFormatInfluxDB:
+			stat = name + suffix + "," + tags + ":" + value
+		}
+		tagged = append(tagged, stat)
+	}
+	return tagged
+}
+
+// taggedClient is a statsd client that renders tags of events in a given format
+// before sending them.
+type taggedClient struct {
+	*statsd.StatsdClient
+	format string
+}
+
+// SendEvents sends stats from all events with their tags rendered.
+func (c *taggedClient) SendEvents(events map[string]event.Event) error {
+	tagged := make(map[string]event.Event, len(events))
+	for k, e := range events {
+		tagged[k] = taggedEvent{Event: e, format: c.format}
+	}
+	return c.StatsdClient.SendEvents(tagged)
+}
+
+//------------------------------------------------------------------------------
+
 // Statsd is a stats object with capability to hold internal stats as a JSON
 // endpoint.
 type Statsd struct {
 	config Config
 	s      statsd.Statsd
 	log    log.Modular
+	tagged bool
 }
 
 // NewStatsd creates and returns a new Statsd object.
@@ -118,6 +218,8 @@ func NewStatsd(config Config, opts ...func(Type)) (Type, error) {
 	s := &Statsd{
 		config: config,
 		log:    log.New(ioutil.Discard, log.Config{LogLevel: "OFF"}),
+		tagged: config.Statsd.TagFormat == TagFormatDatadog ||
+			config.Statsd.TagFormat == TagFormatInfluxDB,
 	}
 	for _, opt := range opts {
 		opt(s)
@@ -128,10 +230,20 @@ func NewStatsd(config Config, opts ...func(Type)) (Type, error) {
 		prefix = prefix + "."
 	}
 
-	statsdclient := statsd.NewStatsdBuffer(
-		flushPeriod,
-		statsd.NewStatsdClient(config.Statsd.Address, prefix),
-	)
+	var client statsd.Statsd
+	switch config.Statsd.TagFormat {
+	case TagFormatNone, "":
+		client = statsd.NewStatsdClient(config.Statsd.Address, prefix)
+	case TagFormatDatadog, TagFormatInfluxDB:
+		client = &taggedClient{
+			StatsdClient: statsd.NewStatsdClient(config.Statsd.Address, prefix),
+			format:       config.Statsd.TagFormat,
+		}
+	default:
+		return nil, fmt.Errorf("tag format '%v' was not recognised", config.Statsd.TagFormat)
+	}
+
+	statsdclient := statsd.NewStatsdBuffer(flushPeriod, client)
 	statsdclient.Logger = &wrappedLogger{m: s.log}
 	if config.Statsd.Network == "udp" {
 		if err := statsdclient.CreateSocket(); err != nil {
@@ -157,14 +269,24 @@ func (h *Statsd) GetCounter(path string) StatCounter {
 }
 
 // GetCounterVec returns a stat counter object for a path with the labels
-// discarded.
+// emitted as tags, or discarded when the tag format is none.
 func (h *Statsd) GetCounterVec(path string, n []string) StatCounterVec {
-	return fakeCounterVec(func() StatCounter {
-		return &StatsdStat{
-			path: path,
-			s:    h.s,
-		}
-	})
+	if !h.tagged {
+		return fakeCounterVec(func() StatCounter {
+			return &StatsdStat{
+				path: path,
+				s:    h.s,
+			}
+		})
+	}
+	return &statsdCounterVec{
+		f: func(labels []string) StatCounter {
+			return &StatsdStat{
+				path: taggedPath(path, n, labels),
+				s:    h.s,
+			}
+		},
+	}
 }
 
 // GetTimer returns a stat timer object for a path.
@@ -175,15 +297,25 @@ func (h *Statsd) GetTimer(path string) StatTimer {
 	}
 }
 
-// GetTimerVec returns a stat timer object for a path with the labels
-// discarded.
+// GetTimerVec returns a stat timer object for a path with the labels emitted
+// as tags, or discarded when the tag format is none.
 func (h *Statsd) GetTimerVec(path string, n []string) StatTimerVec {
-	return fakeTimerVec(func() StatTimer {
-		return &StatsdStat{
-			path: path,
-			s:    h.s,
-		}
-	})
+	if !h.tagged {
+		return fakeTimerVec(func() StatTimer {
+			return &StatsdStat{
+				path: path,
+				s:    h.s,
+			}
+		})
+	}
+	return &statsdTimerVec{
+		f: func(labels []string) StatTimer {
+			return &StatsdStat{
+				path: taggedPath(path, n, labels),
+				s:    h.s,
+			}
+		},
+	}
 }
 
 // GetGauge returns a stat gauge object for a path.
@@ -194,17 +326,53 @@ func (h *Statsd) GetGauge(path string) StatGauge {
 	}
 }
 
-// GetGaugeVec returns a stat timer object for a path with the labels
-// discarded.
+// GetGaugeVec returns a stat gauge object for a path with the labels emitted
+// as tags, or discarded when the tag format is none.
 func (h *Statsd) GetGaugeVec(path string, n []string) StatGaugeVec {
-	return fakeGaugeVec(func() StatGauge {
-		return &StatsdStat{
-			path: path,
-			s:    h.s,
-		}
-	})
+	if !h.tagged {
+		return fakeGaugeVec(func() StatGauge {
+			return &StatsdStat{
+				path: path,
+				s:    h.s,
+			}
+		})
+	}
+	return &statsdGaugeVec{
+		f: func(labels []string) StatGauge {
+			return &StatsdStat{
+				path: taggedPath(path, n, labels),
+				s:    h.s,
+			}
+		},
+	}
+}
+
+type statsdCounterVec struct {
+	f func(labels []string) StatCounter
+}
+
+func (s *statsdCounterVec) With(labels ...string) StatCounter {
+	return s.f(labels)
+}
+
+type statsdTimerVec struct {
+	f func(labels []string) StatTimer
+}
+
+func (s *statsdTimerVec) With(labels ...string) StatTimer {
+	return s.f(labels)
 }
 
+type statsdGaugeVec struct {
+	f func(labels []string) StatGauge
+}
+
+func (s *statsdGaugeVec) With(labels ...string) StatGauge {
+	return s.f(labels)
+}
+
+//------------------------------------------------------------------------------
+
 // SetLogger sets the logger used to print connection errors.
 func (h *Statsd) SetLogger(log log.Modular) {
 	h.log = log
@@ -0,0 +1,185 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metrics
+
+import (
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quipo/statsd/event"
+)
+
+func TestStatsdTaggedEvent(t *testing.T) {
+	type testCase struct {
+		name   string
+		format string
+		path   string
+		labels []string
+		values []string
+		stats  []string
+		exp    []string
+	}
+
+	tests := []testCase{
+		{
+			name:   "no labels",
+			format: TagFormatDatadog,
+			path:   "foo.bar",
+			stats:  []string{"foo.bar:1|c"},
+			exp:    []string{"foo.bar:1|c"},
+		},
+		{
+			name:   "datadog counter",
+			format: TagFormatDatadog,
+			path:   "foo.bar",
+			labels: []string{"a", "b"},
+			values: []string{"x", "y z"},
+			stats:  []string{"%v:1|c"},
+			exp:    []string{"foo.bar:1|c|#a:x,b:y_z"},
+		},
+		{
+			name:   "influxdb counter",
+			format: TagFormatInfluxDB,
+			path:   "foo.bar",
+			labels: []string{"a", "b"},
+			values: []string{"x", "y,z"},
+			stats:  []string{"%v:1|c"},
+			exp:    []string{"foo.bar,a=x,b=y_z:1|c"},
+		},
+		{
+			name:   "datadog timing",
+			format: TagFormatDatadog,
+			path:   "foo",
+			labels: []string{"a"},
+			values: []string{"x"},
+			stats:  []string{"%v.count:2|c", "%v.avg:5|ms"},
+			exp:    []string{"foo.count:2|c|#a:x", "foo.avg:5|ms|#a:x"},
+		},
+		{
+			name:   "influxdb timing",
+			format: TagFormatInfluxDB,
+			path:   "foo",
+			labels: []string{"a"},
+			values: []string{"x"},
+			stats:  []string{"%v.count:2|c", "%v.avg:5|ms"},
+			exp:    []string{"foo.count,a=x:2|c", "foo.avg,a=x:5|ms"},
+		},
+	}
+
+	for _, test := range tests {
+		key := taggedPath(test.path, test.labels, test.values)
+		stats := make([]string, len(test.stats))
+		for i, s := range test.stats {
+			stats[i] = strings.Replace(s, "%v", key, -1)
+		}
+		e := taggedEvent{
+			Event:  fakeEvent{key: key, stats: stats},
+			format: test.format,
+		}
+		if act := e.Stats(); !reflect.DeepEqual(test.exp, act) {
+			t.Errorf("Wrong result for '%v': %v != %v", test.name, act, test.exp)
+		}
+	}
+}
+
+type fakeEvent struct {
+	key   string
+	stats []string
+}
+
+func (f fakeEvent) Stats() []string             { return f.stats }
+func (f fakeEvent) Type() int                   { return 0 }
+func (f fakeEvent) TypeString() string          { return "fake" }
+func (f fakeEvent) Payload() interface{}        { return nil }
+func (f fakeEvent) Update(e2 event.Event) error { return nil }
+func (f fakeEvent) String() string              { return f.key }
+func (f fakeEvent) Key() string                 { return f.key }
+func (f fakeEvent) SetKey(key string)           {}
+
+func TestStatsdTagFormats(t *testing.T) {
+	tests := map[string][]string{
+		TagFormatNone: {
+			"benthos.foo.vec:2|c",
+			"benthos.foo:1|c",
+		},
+		TagFormatDatadog: {
+			"benthos.foo.vec:2|c|#bar:baz",
+			"benthos.foo:1|c",
+		},
+		TagFormatInfluxDB: {
+			"benthos.foo.vec,bar=baz:2|c",
+			"benthos.foo:1|c",
+		},
+	}
+
+	for format, exp := range tests {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		conf := NewConfig()
+		conf.Prefix = "benthos"
+		conf.Statsd.Address = conn.LocalAddr().String()
+		conf.Statsd.FlushPeriod = "10ms"
+		conf.Statsd.TagFormat = format
+
+		s, err := NewStatsd(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s.GetCounter("foo").Incr(1)
+		s.GetCounterVec("foo.vec", []string{"bar"}).With("baz").Incr(2)
+
+		act := []string{}
+		buf := make([]byte, 1024)
+		for len(act) < len(exp) {
+			conn.SetReadDeadline(time.Now().Add(time.Second))
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				t.Fatalf("Failed to read metrics for format '%v': %v", format, err)
+			}
+			for _, line := range strings.Split(strings.TrimSpace(string(buf[:n])), "\n") {
+				act = append(act, line)
+			}
+		}
+		sort.Strings(act)
+		if !reflect.DeepEqual(exp, act) {
+			t.Errorf("Wrong metrics for format '%v': %v != %v", format, act, exp)
+		}
+
+		s.Close()
+		conn.Close()
+	}
+}
+
+func TestStatsdBadTagFormat(t *testing.T) {
+	conf := NewConfig()
+	conf.Statsd.TagFormat = "nope"
+	if _, err := NewStatsd(conf); err == nil {
+		t.Error("Expected error from bad tag format")
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/output/writer"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/metadata"
 )
 
 //------------------------------------------------------------------------------
@@ -35,7 +36,7 @@ func init() {
 		description: `
 Sends messages to an AMQP (0.91) exchange. AMQP is a messaging protocol used by
 various message brokers, including RabbitMQ. The metadata from each message are
-delivered as headers.
+delivered as headers, filtered according to the ` + "`metadata`" + ` section.
 
 It's possible for this output type to create the target exchange by setting
 ` + "`exchange_declare.enabled` to `true`" + `, if the exchange already exists
@@ -47,7 +48,9 @@ TLS is automatic when connecting to an ` + "`amqps`" + ` URL, but custom
 settings can be enabled in the ` + "`tls`" + ` section.
 
 The field 'key' can be dynamically set using function interpolations described
-[here](../config_interpolation.md#functions).`,
+[here](../config_interpolation.md#functions).
+
+` + metadata.Documentation,
 	}
 }
 
@@ -79,6 +79,19 @@ through Benthos. If an output applies back pressure it will block all subsequent
 messages, and if an output fails to send a message it will be retried
 continuously until completion or service shut down.
 
+#### ` + "`fan_out_sequential`" + `
+
+Similar to the fan out pattern except outputs are written to sequentially,
+meaning an output is only sent a message once all outputs before it in the list
+have successfully sent it. If an output fails to send a message, after
+exhausting its own retry behaviour, then the outputs that follow it are skipped
+and the error is returned to the input, which will typically result in the
+message being sent to all outputs again.
+
+The latency of each output is exposed as the metric
+` + "`broker.fan_out_sequential.<index>.latency`" + `, where the index is the
+position of the output within the list.
+
 #### ` + "`round_robin`" + `
 
 With the round robin pattern each message will be assigned a single output
@@ -106,6 +119,19 @@ targets have broken. For example, if you had an output type ` + "`http_client`"
 but wished to reroute messages whenever the endpoint becomes unreachable you
 could use a try broker.
 
+Outputs that are disconnected from their targets are skipped without an attempt,
+with the exception of the last output in the list, which is always attempted.
+However, an output only reports an error once its own retry behaviour has been
+exhausted, and therefore an output configured to retry a failed send
+indefinitely (or for a long duration) will delay the failover. For outputs that
+support them it is therefore recommended to set finite ` + "`max_retries`" + ` and
+` + "`backoff.max_elapsed_time`" + ` fields on all but the last output.
+
+The number of attempts, successes, failures and skips of each output are exposed
+as the metrics ` + "`broker.try.<index>.attempt`" + `,
+` + "`broker.try.<index>.success`" + `, ` + "`broker.try.<index>.failed`" + ` and
+` + "`broker.try.<index>.skipped`" + ` respectively.
+
 ### Utilising More Outputs
 
 When using brokered outputs with patterns such as round robin or greedy it is
@@ -113,6 +139,30 @@ possible to have multiple messages in-flight at the same time. In order to fully
 utilise this you either need to have a greater number of input sources than
 output sources [or use a buffer](../buffers/README.md).
 
+### Copies
+
+The field ` + "`copies`" + ` allows you to replicate the list of outputs a
+number of times, which is useful for scaling writes to a single slow target
+without repeating its config. For example, the following config distributes
+messages across four identical HTTP clients, where each message is sent to
+whichever client is free first:
+
+` + "``` yaml" + `
+output:
+  type: broker
+  broker:
+    pattern: greedy
+    copies: 4
+    outputs:
+    - type: http_client
+      http_client:
+        url: http://localhost:4195/post
+` + "```" + `
+
+With the greedy pattern an output that is blocked, e.g. whilst retrying a failed
+send, does not prevent the other outputs from consuming messages, whereas with
+the round robin pattern it blocks all messages until it frees up.
+
 ### Processors
 
 It is possible to configure [processors](../processors/README.md) at the broker
@@ -194,6 +244,8 @@ func NewBroker(
 	switch conf.Broker.Pattern {
 	case "fan_out":
 		return broker.NewFanOut(outputs, log, stats)
+	case "fan_out_sequential":
+		return broker.NewFanOutSequential(outputs, log, stats)
 	case "round_robin":
 		return broker.NewRoundRobin(outputs, stats)
 	case "greedy":
@@ -60,113 +60,143 @@ var Constructors = map[string]TypeSpec{}
 
 // String constants representing each output type.
 const (
-	TypeAMQP          = "amqp"
-	TypeBroker        = "broker"
-	TypeCache         = "cache"
-	TypeDynamic       = "dynamic"
-	TypeDynamoDB      = "dynamodb"
-	TypeElasticsearch = "elasticsearch"
-	TypeFile          = "file"
-	TypeFiles         = "files"
-	TypeGCPPubSub     = "gcp_pubsub"
-	TypeHDFS          = "hdfs"
-	TypeHTTPClient    = "http_client"
-	TypeHTTPServer    = "http_server"
-	TypeInproc        = "inproc"
-	TypeKafka         = "kafka"
-	TypeKinesis       = "kinesis"
-	TypeMQTT          = "mqtt"
-	TypeNanomsg       = "nanomsg"
-	TypeNATS          = "nats"
-	TypeNATSStream    = "nats_stream"
-	TypeNSQ           = "nsq"
-	TypeRedisList     = "redis_list"
-	TypeRedisPubSub   = "redis_pubsub"
-	TypeRedisStreams  = "redis_streams"
-	TypeRetry         = "retry"
-	TypeS3            = "s3"
-	TypeSQS           = "sqs"
-	TypeSTDOUT        = "stdout"
-	TypeSwitch        = "switch"
-	TypeWebsocket     = "websocket"
-	TypeZMQ4          = "zmq4"
+	TypeAMQP            = "amqp"
+	TypeBroker          = "broker"
+	TypeCache           = "cache"
+	TypeDeadLetter      = "dead_letter"
+	TypeDrop            = "drop"
+	TypeDropOn          = "drop_on"
+	TypeDropOnError     = "drop_on_error"
+	TypeDynamic         = "dynamic"
+	TypeDynamoDB        = "dynamodb"
+	TypeElasticsearch   = "elasticsearch"
+	TypeFile            = "file"
+	TypeFiles           = "files"
+	TypeGCPPubSub       = "gcp_pubsub"
+	TypeGRPC            = "grpc"
+	TypeHDFS            = "hdfs"
+	TypeHTTPClient      = "http_client"
+	TypeHTTPServer      = "http_server"
+	TypeInproc          = "inproc"
+	TypeKafka           = "kafka"
+	TypeKinesis         = "kinesis"
+	TypeKinesisFirehose = "kinesis_firehose"
+	TypeMQTT            = "mqtt"
+	TypeNanomsg         = "nanomsg"
+	TypeNATS            = "nats"
+	TypeNATSStream      = "nats_stream"
+	TypeNSQ             = "nsq"
+	TypeRedis           = "redis"
+	TypeRedisList       = "redis_list"
+	TypeRedisPubSub     = "redis_pubsub"
+	TypeRedisStreams    = "redis_streams"
+	TypeReject          = "reject"
+	TypeRetry           = "retry"
+	TypeS3              = "s3"
+	TypeSocket          = "socket"
+	TypeSQS             = "sqs"
+	TypeSTDOUT          = "stdout"
+	TypeSwitch          = "switch"
+	TypeWebsocket       = "websocket"
+	TypeWithDeadLetter  = "with_dead_letter"
+	TypeZMQ4            = "zmq4"
 )
 
 //------------------------------------------------------------------------------
 
 // Config is the all encompassing configuration struct for all output types.
 type Config struct {
-	Type          string                     `json:"type" yaml:"type"`
-	AMQP          writer.AMQPConfig          `json:"amqp" yaml:"amqp"`
-	Broker        BrokerConfig               `json:"broker" yaml:"broker"`
-	Cache         writer.CacheConfig         `json:"cache" yaml:"cache"`
-	Dynamic       DynamicConfig              `json:"dynamic" yaml:"dynamic"`
-	DynamoDB      writer.DynamoDBConfig      `json:"dynamodb" yaml:"dynamodb"`
-	Elasticsearch writer.ElasticsearchConfig `json:"elasticsearch" yaml:"elasticsearch"`
-	File          FileConfig                 `json:"file" yaml:"file"`
-	Files         writer.FilesConfig         `json:"files" yaml:"files"`
-	GCPPubSub     writer.GCPPubSubConfig     `json:"gcp_pubsub" yaml:"gcp_pubsub"`
-	HDFS          writer.HDFSConfig          `json:"hdfs" yaml:"hdfs"`
-	HTTPClient    writer.HTTPClientConfig    `json:"http_client" yaml:"http_client"`
-	HTTPServer    HTTPServerConfig           `json:"http_server" yaml:"http_server"`
-	Inproc        InprocConfig               `json:"inproc" yaml:"inproc"`
-	Kafka         writer.KafkaConfig         `json:"kafka" yaml:"kafka"`
-	Kinesis       writer.KinesisConfig       `json:"kinesis" yaml:"kinesis"`
-	MQTT          writer.MQTTConfig          `json:"mqtt" yaml:"mqtt"`
-	Nanomsg       writer.NanomsgConfig       `json:"nanomsg" yaml:"nanomsg"`
-	NATS          writer.NATSConfig          `json:"nats" yaml:"nats"`
-	NATSStream    writer.NATSStreamConfig    `json:"nats_stream" yaml:"nats_stream"`
-	NSQ           writer.NSQConfig           `json:"nsq" yaml:"nsq"`
-	Plugin        interface{}                `json:"plugin,omitempty" yaml:"plugin,omitempty"`
-	RedisList     writer.RedisListConfig     `json:"redis_list" yaml:"redis_list"`
-	RedisPubSub   writer.RedisPubSubConfig   `json:"redis_pubsub" yaml:"redis_pubsub"`
-	RedisStreams  writer.RedisStreamsConfig  `json:"redis_streams" yaml:"redis_streams"`
-	Retry         RetryConfig                `json:"retry" yaml:"retry"`
-	S3            writer.AmazonS3Config      `json:"s3" yaml:"s3"`
-	SQS           writer.AmazonSQSConfig     `json:"sqs" yaml:"sqs"`
-	STDOUT        STDOUTConfig               `json:"stdout" yaml:"stdout"`
-	Switch        SwitchConfig               `json:"switch" yaml:"switch"`
-	Websocket     writer.WebsocketConfig     `json:"websocket" yaml:"websocket"`
-	ZMQ4          *writer.ZMQ4Config         `json:"zmq4,omitempty" yaml:"zmq4,omitempty"`
-	Processors    []processor.Config         `json:"processors" yaml:"processors"`
+	Type            string                       `json:"type" yaml:"type"`
+	AMQP            writer.AMQPConfig            `json:"amqp" yaml:"amqp"`
+	Broker          BrokerConfig                 `json:"broker" yaml:"broker"`
+	Cache           writer.CacheConfig           `json:"cache" yaml:"cache"`
+	DeadLetter      DeadLetterConfig             `json:"dead_letter" yaml:"dead_letter"`
+	Drop            writer.DropConfig            `json:"drop" yaml:"drop"`
+	DropOn          DropOnConfig                 `json:"drop_on" yaml:"drop_on"`
+	DropOnError     DropOnErrorConfig            `json:"drop_on_error" yaml:"drop_on_error"`
+	Dynamic         DynamicConfig                `json:"dynamic" yaml:"dynamic"`
+	DynamoDB        writer.DynamoDBConfig        `json:"dynamodb" yaml:"dynamodb"`
+	Elasticsearch   writer.ElasticsearchConfig   `json:"elasticsearch" yaml:"elasticsearch"`
+	File            FileConfig                   `json:"file" yaml:"file"`
+	Files           writer.FilesConfig           `json:"files" yaml:"files"`
+	GCPPubSub       writer.GCPPubSubConfig       `json:"gcp_pubsub" yaml:"gcp_pubsub"`
+	GRPC            writer.GRPCConfig            `json:"grpc" yaml:"grpc"`
+	HDFS            writer.HDFSConfig            `json:"hdfs" yaml:"hdfs"`
+	HTTPClient      writer.HTTPClientConfig      `json:"http_client" yaml:"http_client"`
+	HTTPServer      HTTPServerConfig             `json:"http_server" yaml:"http_server"`
+	Inproc          InprocConfig                 `json:"inproc" yaml:"inproc"`
+	Kafka           writer.KafkaConfig           `json:"kafka" yaml:"kafka"`
+	Kinesis         writer.KinesisConfig         `json:"kinesis" yaml:"kinesis"`
+	KinesisFirehose writer.KinesisFirehoseConfig `json:"kinesis_firehose" yaml:"kinesis_firehose"`
+	MQTT            writer.MQTTConfig            `json:"mqtt" yaml:"mqtt"`
+	Nanomsg         writer.NanomsgConfig         `json:"nanomsg" yaml:"nanomsg"`
+	NATS            writer.NATSConfig            `json:"nats" yaml:"nats"`
+	NATSStream      writer.NATSStreamConfig      `json:"nats_stream" yaml:"nats_stream"`
+	NSQ             writer.NSQConfig             `json:"nsq" yaml:"nsq"`
+	Plugin          interface{}                  `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	Redis           writer.RedisConfig           `json:"redis" yaml:"redis"`
+	RedisList       writer.RedisListConfig       `json:"redis_list" yaml:"redis_list"`
+	RedisPubSub     writer.RedisPubSubConfig     `json:"redis_pubsub" yaml:"redis_pubsub"`
+	RedisStreams    writer.RedisStreamsConfig    `json:"redis_streams" yaml:"redis_streams"`
+	Reject          RejectConfig                 `json:"reject" yaml:"reject"`
+	Retry           RetryConfig                  `json:"retry" yaml:"retry"`
+	S3              writer.AmazonS3Config        `json:"s3" yaml:"s3"`
+	Socket          writer.SocketConfig          `json:"socket" yaml:"socket"`
+	SQS             writer.AmazonSQSConfig       `json:"sqs" yaml:"sqs"`
+	STDOUT          STDOUTConfig                 `json:"stdout" yaml:"stdout"`
+	Switch          SwitchConfig                 `json:"switch" yaml:"switch"`
+	Websocket       writer.WebsocketConfig       `json:"websocket" yaml:"websocket"`
+	WithDeadLetter  WithDeadLetterConfig         `json:"with_dead_letter" yaml:"with_dead_letter"`
+	ZMQ4            *writer.ZMQ4Config           `json:"zmq4,omitempty" yaml:"zmq4,omitempty"`
+	Processors      []processor.Config           `json:"processors" yaml:"processors"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Type:          "stdout",
-		AMQP:          writer.NewAMQPConfig(),
-		Broker:        NewBrokerConfig(),
-		Cache:         writer.NewCacheConfig(),
-		Dynamic:       NewDynamicConfig(),
-		DynamoDB:      writer.NewDynamoDBConfig(),
-		Elasticsearch: writer.NewElasticsearchConfig(),
-		File:          NewFileConfig(),
-		Files:         writer.NewFilesConfig(),
-		GCPPubSub:     writer.NewGCPPubSubConfig(),
-		HDFS:          writer.NewHDFSConfig(),
-		HTTPClient:    writer.NewHTTPClientConfig(),
-		HTTPServer:    NewHTTPServerConfig(),
-		Inproc:        NewInprocConfig(),
-		Kafka:         writer.NewKafkaConfig(),
-		Kinesis:       writer.NewKinesisConfig(),
-		MQTT:          writer.NewMQTTConfig(),
-		Nanomsg:       writer.NewNanomsgConfig(),
-		NATS:          writer.NewNATSConfig(),
-		NATSStream:    writer.NewNATSStreamConfig(),
-		NSQ:           writer.NewNSQConfig(),
-		Plugin:        nil,
-		RedisList:     writer.NewRedisListConfig(),
-		RedisPubSub:   writer.NewRedisPubSubConfig(),
-		RedisStreams:  writer.NewRedisStreamsConfig(),
-		Retry:         NewRetryConfig(),
-		S3:            writer.NewAmazonS3Config(),
-		SQS:           writer.NewAmazonSQSConfig(),
-		STDOUT:        NewSTDOUTConfig(),
-		Switch:        NewSwitchConfig(),
-		Websocket:     writer.NewWebsocketConfig(),
-		ZMQ4:          writer.NewZMQ4Config(),
-		Processors:    []processor.Config{},
+		Type:            "stdout",
+		AMQP:            writer.NewAMQPConfig(),
+		Broker:          NewBrokerConfig(),
+		Cache:           writer.NewCacheConfig(),
+		DeadLetter:      NewDeadLetterConfig(),
+		Drop:            writer.NewDropConfig(),
+		DropOn:          NewDropOnConfig(),
+		DropOnError:     NewDropOnErrorConfig(),
+		Dynamic:         NewDynamicConfig(),
+		DynamoDB:        writer.NewDynamoDBConfig(),
+		Elasticsearch:   writer.NewElasticsearchConfig(),
+		File:            NewFileConfig(),
+		Files:           writer.NewFilesConfig(),
+		GCPPubSub:       writer.NewGCPPubSubConfig(),
+		GRPC:            writer.NewGRPCConfig(),
+		HDFS:            writer.NewHDFSConfig(),
+		HTTPClient:      writer.NewHTTPClientConfig(),
+		HTTPServer:      NewHTTPServerConfig(),
+		Inproc:          NewInprocConfig(),
+		Kafka:           writer.NewKafkaConfig(),
+		Kinesis:         writer.NewKinesisConfig(),
+		KinesisFirehose: writer.NewKinesisFirehoseConfig(),
+		MQTT:            writer.NewMQTTConfig(),
+		Nanomsg:         writer.NewNanomsgConfig(),
+		NATS:            writer.NewNATSConfig(),
+		NATSStream:      writer.NewNATSStreamConfig(),
+		NSQ:             writer.NewNSQConfig(),
+		Plugin:          nil,
+		Redis:           writer.NewRedisConfig(),
+		RedisList:       writer.NewRedisListConfig(),
+		RedisPubSub:     writer.NewRedisPubSubConfig(),
+		RedisStreams:    writer.NewRedisStreamsConfig(),
+		Reject:          NewRejectConfig(),
+		Retry:           NewRetryConfig(),
+		S3:              writer.NewAmazonS3Config(),
+		Socket:          writer.NewSocketConfig(),
+		SQS:             writer.NewAmazonSQSConfig(),
+		STDOUT:          NewSTDOUTConfig(),
+		Switch:          NewSwitchConfig(),
+		Websocket:       writer.NewWebsocketConfig(),
+		WithDeadLetter:  NewWithDeadLetterConfig(),
+		ZMQ4:            writer.NewZMQ4Config(),
+		Processors:      []processor.Config{},
 	}
 }
 
@@ -376,6 +406,7 @@ func New(
 				if err != nil {
 					return nil, fmt.Errorf("failed to create processor '%v': %v", procConf.Type, err)
 				}
+				processors[i] = processor.WrapTimed("output", i, procConf, processors[i], stats)
 			}
 			return pipeline.NewProcessor(log, stats, processors...), nil
 		}}...)
@@ -397,6 +428,10 @@ func New(
 		}
 		return WrapWithPipelines(output, pipelines...)
 	}
+	if conf.Type == TypeZMQ4 {
+		// The zmq4 output is only registered when built with the ZMQ4 tag.
+		return nil, types.ErrZMQ4NotCompiled
+	}
 	return nil, types.ErrInvalidOutputType
 }
 
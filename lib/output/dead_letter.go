@@ -0,0 +1,354 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeDeadLetter] = TypeSpec{
+		constructor: NewDeadLetter,
+		description: `
+Writes messages to a child output (the dead letter queue) after stamping each
+message part with metadata that schedules its next retry. This output is
+intended to be used as the fallback of a ` + "[`broker`](#broker)" + ` with the
+pattern ` + "`try`" + `, combined with the
+` + "[`delay`](../processors/README.md#delay)" + ` processor on the input that
+consumes the queue, in order to build an exponential retry loop without an
+external scheduler.
+
+The following metadata fields are set on each message part:
+
+- ` + "`dead_letter_attempts`" + `: The number of times the message has been
+  written to the dead letter queue, incremented from any existing value.
+- ` + "`dead_letter_retry_at`" + `: A unix timestamp in milliseconds before
+  which the message should not be retried.
+- ` + "`dead_letter_exhausted`" + `: Set to ` + "`true`" + ` once the attempts
+  exceed ` + "`max_attempts`" + `, when set to a non-zero value.
+
+The retry period starts at ` + "`initial_interval`" + ` and is multiplied by
+` + "`multiplier`" + ` on each subsequent attempt up to ` + "`max_interval`" + `.
+
+Messages that have been exhausted are still written to the child output, and
+can be routed elsewhere with a ` + "[`switch`](#switch)" + ` output using a
+` + "[`metadata`](../conditions/README.md#metadata)" + ` condition on the
+` + "`dead_letter_exhausted`" + ` key.
+
+Acknowledgements from the child output are propagated back to the source of the
+message, therefore a message is only acknowledged once it has either been
+delivered successfully or written to the dead letter queue, giving at-least-once
+delivery semantics throughout the loop. A message may be delivered more than
+once if a failure occurs after delivery but before the acknowledgement reaches
+the input.`,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			confBytes, err := json.Marshal(conf.DeadLetter)
+			if err != nil {
+				return nil, err
+			}
+
+			confMap := map[string]interface{}{}
+			if err = json.Unmarshal(confBytes, &confMap); err != nil {
+				return nil, err
+			}
+
+			var outputSanit interface{} = struct{}{}
+			if conf.DeadLetter.Output != nil {
+				if outputSanit, err = SanitiseConfig(*conf.DeadLetter.Output); err != nil {
+					return nil, err
+				}
+			}
+			confMap["output"] = outputSanit
+			return confMap, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	deadLetterAttemptsKey  = "dead_letter_attempts"
+	deadLetterRetryAtKey   = "dead_letter_retry_at"
+	deadLetterExhaustedKey = "dead_letter_exhausted"
+)
+
+// DeadLetterConfig contains configuration values for the DeadLetter output
+// type.
+type DeadLetterConfig struct {
+	Output          *Config `json:"output" yaml:"output"`
+	InitialInterval string  `json:"initial_interval" yaml:"initial_interval"`
+	MaxInterval     string  `json:"max_interval" yaml:"max_interval"`
+	Multiplier      float64 `json:"multiplier" yaml:"multiplier"`
+	MaxAttempts     int     `json:"max_attempts" yaml:"max_attempts"`
+}
+
+// NewDeadLetterConfig creates a new DeadLetterConfig with default values.
+func NewDeadLetterConfig() DeadLetterConfig {
+	return DeadLetterConfig{
+		Output:          nil,
+		InitialInterval: "1s",
+		MaxInterval:     "5m",
+		Multiplier:      2,
+		MaxAttempts:     0,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dummyDeadLetterConfig struct {
+	Output          interface{} `json:"output" yaml:"output"`
+	InitialInterval string      `json:"initial_interval" yaml:"initial_interval"`
+	MaxInterval     string      `json:"max_interval" yaml:"max_interval"`
+	Multiplier      float64     `json:"multiplier" yaml:"multiplier"`
+	MaxAttempts     int         `json:"max_attempts" yaml:"max_attempts"`
+}
+
+func (d DeadLetterConfig) dummy() dummyDeadLetterConfig {
+	dummy := dummyDeadLetterConfig{
+		Output:          d.Output,
+		InitialInterval: d.InitialInterval,
+		MaxInterval:     d.MaxInterval,
+		Multiplier:      d.Multiplier,
+		MaxAttempts:     d.MaxAttempts,
+	}
+	if d.Output == nil {
+		dummy.Output = struct{}{}
+	}
+	return dummy
+}
+
+// MarshalJSON prints an empty object instead of nil.
+func (d DeadLetterConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.dummy())
+}
+
+// MarshalYAML prints an empty object instead of nil.
+func (d DeadLetterConfig) MarshalYAML() (interface{}, error) {
+	return d.dummy(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// DeadLetter is an output type that stamps messages with a retry schedule
+// before writing them to a child output.
+type DeadLetter struct {
+	running int32
+
+	initInterval time.Duration
+	maxInterval  time.Duration
+	multiplier   float64
+	maxAttempts  int
+
+	wrapped Type
+	nowFn   func() time.Time
+
+	stats metrics.Type
+	log   log.Modular
+
+	transactionsIn  <-chan types.Transaction
+	transactionsOut chan types.Transaction
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewDeadLetter creates a new DeadLetter output type.
+func NewDeadLetter(
+	conf Config,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	if conf.DeadLetter.Output == nil {
+		return nil, errors.New("cannot create dead_letter output without a child")
+	}
+
+	d := &DeadLetter{
+		running:     1,
+		multiplier:  conf.DeadLetter.Multiplier,
+		maxAttempts: conf.DeadLetter.MaxAttempts,
+
+		nowFn:           time.Now,
+		log:             log.NewModule(".output.dead_letter"),
+		stats:           stats,
+		transactionsOut: make(chan types.Transaction),
+
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}
+
+	var err error
+	if d.initInterval, err = time.ParseDuration(conf.DeadLetter.InitialInterval); err != nil {
+		return nil, fmt.Errorf("failed to parse initial_interval: %v", err)
+	}
+	if d.maxInterval, err = time.ParseDuration(conf.DeadLetter.MaxInterval); err != nil {
+		return nil, fmt.Errorf("failed to parse max_interval: %v", err)
+	}
+	if d.multiplier < 1 {
+		return nil, fmt.Errorf("multiplier must be at least 1, got %v", d.multiplier)
+	}
+
+	if d.wrapped, err = New(*conf.DeadLetter.Output, mgr, log, stats); err != nil {
+		return nil, fmt.Errorf("failed to create output '%v': %v", conf.DeadLetter.Output.Type, err)
+	}
+	return d, nil
+}
+
+//------------------------------------------------------------------------------
+
+// retryInterval returns the period to wait before the given attempt.
+func (d *DeadLetter) retryInterval(attempt int) time.Duration {
+	interval := float64(d.initInterval)
+	for i := 1; i < attempt && interval < float64(d.maxInterval); i++ {
+		interval *= d.multiplier
+	}
+	if interval > float64(d.maxInterval) {
+		return d.maxInterval
+	}
+	return time.Duration(interval)
+}
+
+// stamp returns a copy of a message where each part has been stamped with its
+// retry schedule, and the number of parts that have exhausted their attempts.
+func (d *DeadLetter) stamp(msg types.Message) (types.Message, int) {
+	newMsg := msg.Copy()
+	now := d.nowFn()
+	exhausted := 0
+
+	newMsg.Iter(func(i int, p types.Part) error {
+		meta := p.Metadata()
+
+		attempt := 1
+		if prev := meta.Get(deadLetterAttemptsKey); len(prev) > 0 {
+			if n, err := strconv.Atoi(prev); err == nil && n > 0 {
+				attempt = n + 1
+			} else {
+				d.log.Warnf("Ignoring invalid %v value '%v'\n", deadLetterAttemptsKey, prev)
+			}
+		}
+
+		retryAt := now.Add(d.retryInterval(attempt))
+		meta.Set(deadLetterAttemptsKey, strconv.Itoa(attempt))
+		meta.Set(deadLetterRetryAtKey, strconv.FormatInt(retryAt.UnixNano()/int64(time.Millisecond), 10))
+		if d.maxAttempts > 0 && attempt > d.maxAttempts {
+			meta.Set(deadLetterExhaustedKey, "true")
+			exhausted++
+		}
+		return nil
+	})
+	return newMsg, exhausted
+}
+
+func (d *DeadLetter) loop() {
+	var (
+		mRunning        = d.stats.GetGauge("output.dead_letter.running")
+		mCount          = d.stats.GetCounter("output.dead_letter.count")
+		mPartsCount     = d.stats.GetCounter("output.dead_letter.parts.count")
+		mPartsExhausted = d.stats.GetCounter("output.dead_letter.parts.exhausted")
+	)
+
+	defer func() {
+		close(d.transactionsOut)
+		d.wrapped.CloseAsync()
+		err := d.wrapped.WaitForClose(time.Second)
+		for ; err != nil; err = d.wrapped.WaitForClose(time.Second) {
+		}
+		mRunning.Decr(1)
+		close(d.closedChan)
+	}()
+	mRunning.Incr(1)
+
+	for atomic.LoadInt32(&d.running) == 1 {
+		var ts types.Transaction
+		var open bool
+		select {
+		case ts, open = <-d.transactionsIn:
+			if !open {
+				return
+			}
+		case <-d.closeChan:
+			return
+		}
+
+		mCount.Incr(1)
+		mPartsCount.Incr(int64(ts.Payload.Len()))
+
+		stamped, exhausted := d.stamp(ts.Payload)
+		if exhausted > 0 {
+			mPartsExhausted.Incr(int64(exhausted))
+		}
+
+		select {
+		case d.transactionsOut <- types.NewTransaction(stamped, ts.ResponseChan):
+		case <-d.closeChan:
+			return
+		}
+	}
+}
+
+// Consume assigns a messages channel for the output to read.
+func (d *DeadLetter) Consume(ts <-chan types.Transaction) error {
+	if d.transactionsIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	if err := d.wrapped.Consume(d.transactionsOut); err != nil {
+		return err
+	}
+	d.transactionsIn = ts
+	go d.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether the wrapped output is currently
+// connected to its target.
+func (d *DeadLetter) Connected() bool {
+	return types.IsConnected(d.wrapped)
+}
+
+// CloseAsync shuts down the DeadLetter output and stops processing requests.
+func (d *DeadLetter) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&d.running, 1, 0) {
+		close(d.closeChan)
+	}
+}
+
+// WaitForClose blocks until the DeadLetter output has closed down.
+func (d *DeadLetter) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-d.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
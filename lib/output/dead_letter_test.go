@@ -0,0 +1,200 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func TestDeadLetterConfigErrs(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeDeadLetter
+
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing child output")
+	}
+
+	oConf := NewConfig()
+	conf.DeadLetter.Output = &oConf
+	conf.DeadLetter.InitialInterval = "not a time period"
+
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad initial interval")
+	}
+
+	conf.DeadLetter.InitialInterval = "1s"
+	conf.DeadLetter.Multiplier = 0.5
+
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad multiplier")
+	}
+}
+
+func TestDeadLetterInterval(t *testing.T) {
+	conf := NewConfig()
+	childConf := NewConfig()
+	conf.DeadLetter.Output = &childConf
+	conf.DeadLetter.InitialInterval = "1s"
+	conf.DeadLetter.MaxInterval = "10s"
+	conf.DeadLetter.Multiplier = 3
+
+	output, err := NewDeadLetter(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := output.(*DeadLetter)
+
+	exp := []time.Duration{
+		time.Second, time.Second * 3, time.Second * 9, time.Second * 10, time.Second * 10,
+	}
+	for i, e := range exp {
+		if act := d.retryInterval(i + 1); act != e {
+			t.Errorf("Wrong interval for attempt %v: %v != %v", i+1, act, e)
+		}
+	}
+	if act := d.retryInterval(1000); act != time.Second*10 {
+		t.Errorf("Wrong interval for large attempt: %v", act)
+	}
+}
+
+func TestDeadLetterStamp(t *testing.T) {
+	conf := NewConfig()
+	childConf := NewConfig()
+	conf.DeadLetter.Output = &childConf
+	conf.DeadLetter.InitialInterval = "1s"
+	conf.DeadLetter.MaxInterval = "1m"
+	conf.DeadLetter.MaxAttempts = 2
+
+	stats := metrics.NewLocal()
+	output, err := NewDeadLetter(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := output.(*DeadLetter)
+	d.nowFn = func() time.Time {
+		return time.Unix(1000, 0)
+	}
+
+	mOut := &mockOutput{
+		ts: make(chan types.Transaction),
+	}
+	d.wrapped = mOut
+
+	tChan := make(chan types.Transaction)
+
+	// Responses from the child are passed directly back to the source.
+	resChan := make(chan types.Response, 1)
+
+	if err = d.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	testMsg := message.New([][]byte{
+		[]byte("first"),
+		[]byte("second"),
+		[]byte("third"),
+	})
+	testMsg.Get(1).Metadata().Set("dead_letter_attempts", "1")
+	testMsg.Get(2).Metadata().Set("dead_letter_attempts", "2")
+
+	go func() {
+		select {
+		case tChan <- types.NewTransaction(testMsg, resChan):
+		case <-time.After(time.Second):
+			t.Error("timed out")
+		}
+	}()
+
+	var tran types.Transaction
+	select {
+	case tran = <-mOut.ts:
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	exp := []map[string]string{
+		{
+			"dead_letter_attempts": "1",
+			"dead_letter_retry_at": "1001000",
+		},
+		{
+			"dead_letter_attempts": "2",
+			"dead_letter_retry_at": "1002000",
+		},
+		{
+			"dead_letter_attempts":  "3",
+			"dead_letter_retry_at":  "1004000",
+			"dead_letter_exhausted": "true",
+		},
+	}
+	for i, e := range exp {
+		act := map[string]string{}
+		tran.Payload.Get(i).Metadata().Iter(func(k, v string) error {
+			act[k] = v
+			return nil
+		})
+		if len(act) != len(e) {
+			t.Errorf("Wrong metadata for part %v: %v != %v", i, act, e)
+			continue
+		}
+		for k, v := range e {
+			if act[k] != v {
+				t.Errorf("Wrong metadata for part %v: %v != %v", i, act, e)
+			}
+		}
+	}
+
+	if exp, act := "2", testMsg.Get(2).Metadata().Get("dead_letter_attempts"); exp != act {
+		t.Errorf("Original message was modified: %v != %v", act, exp)
+	}
+
+	select {
+	case tran.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	select {
+	case res := <-resChan:
+		if err = res.Error(); err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	if exp, act := int64(1), stats.GetCounters()["output.dead_letter.parts.exhausted"]; exp != act {
+		t.Errorf("Wrong count of exhausted parts: %v != %v", act, exp)
+	}
+
+	output.CloseAsync()
+	if err = output.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,342 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeDropOn] = TypeSpec{
+		constructor: NewDropOn,
+		description: `
+Attempts to write messages to a child output and if the write fails for one of
+a list of configurable reasons the message is dropped instead of being reattempted.
+
+Regular Benthos outputs will apply back pressure when downstream services
+aren't accessible, and Benthos retries (or nacks) all messages that fail to be
+delivered. However, in some circumstances, or for certain output types, we
+instead might want to relax these mechanisms, which is when this output becomes
+useful. Dropping messages is never the default behaviour and only happens to
+outputs explicitly wrapped with this type.
+
+The conditions for dropping a message are:
+
+- ` + "`error`" + `: When ` + "`true`" + ` a message is acknowledged and
+  dropped whenever the child output returns an error for it.
+- ` + "`back_pressure`" + `: When set to a non-empty duration string (e.g.
+  ` + "`10s`" + `) a message is acknowledged and dropped once this period has
+  elapsed since it was received without the child output having accepted and
+  delivered it.
+
+At least one condition must be set. A message abandoned by the back pressure
+condition may already be held by the child output, in which case it could
+still be delivered at a later time.
+
+The number of dropped messages is exposed as the counter
+` + "`output.drop_on.dropped`" + `.`,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			confBytes, err := json.Marshal(conf.DropOn)
+			if err != nil {
+				return nil, err
+			}
+
+			confMap := map[string]interface{}{}
+			if err = json.Unmarshal(confBytes, &confMap); err != nil {
+				return nil, err
+			}
+
+			var outputSanit interface{} = struct{}{}
+			if conf.DropOn.Output != nil {
+				if outputSanit, err = SanitiseConfig(*conf.DropOn.Output); err != nil {
+					return nil, err
+				}
+			}
+			confMap["output"] = outputSanit
+			return confMap, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// DropOnConfig contains configuration values for the DropOn output type.
+type DropOnConfig struct {
+	Error        bool    `json:"error" yaml:"error"`
+	BackPressure string  `json:"back_pressure" yaml:"back_pressure"`
+	Output       *Config `json:"output" yaml:"output"`
+}
+
+// NewDropOnConfig creates a new DropOnConfig with default values.
+func NewDropOnConfig() DropOnConfig {
+	return DropOnConfig{
+		Error:        false,
+		BackPressure: "",
+		Output:       nil,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dummyDropOnConfig struct {
+	Error        bool        `json:"error" yaml:"error"`
+	BackPressure string      `json:"back_pressure" yaml:"back_pressure"`
+	Output       interface{} `json:"output" yaml:"output"`
+}
+
+func (d DropOnConfig) dummy() dummyDropOnConfig {
+	dummy := dummyDropOnConfig{
+		Error:        d.Error,
+		BackPressure: d.BackPressure,
+		Output:       d.Output,
+	}
+	if d.Output == nil {
+		dummy.Output = struct{}{}
+	}
+	return dummy
+}
+
+// MarshalJSON prints an empty object instead of nil.
+func (d DropOnConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.dummy())
+}
+
+// MarshalYAML prints an empty object instead of nil.
+func (d DropOnConfig) MarshalYAML() (interface{}, error) {
+	return d.dummy(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// DropOn is an output type that writes messages to a child output and drops
+// them when the child fails to deliver them under configured conditions.
+type DropOn struct {
+	running int32
+
+	onError      bool
+	backPressure time.Duration
+	wrapped      Type
+
+	metricPrefix string
+	stats        metrics.Type
+	log          log.Modular
+
+	transactionsIn  <-chan types.Transaction
+	transactionsOut chan types.Transaction
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewDropOn creates a new DropOn output type.
+func NewDropOn(
+	conf Config,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	if conf.DropOn.Output == nil {
+		return nil, errors.New("cannot create drop_on output without a child")
+	}
+
+	var backPressure time.Duration
+	if len(conf.DropOn.BackPressure) > 0 {
+		var err error
+		if backPressure, err = time.ParseDuration(conf.DropOn.BackPressure); err != nil {
+			return nil, fmt.Errorf("failed to parse back_pressure: %v", err)
+		}
+		if backPressure <= 0 {
+			return nil, fmt.Errorf("back_pressure must be a positive duration, got %v", backPressure)
+		}
+	}
+	if !conf.DropOn.Error && backPressure == 0 {
+		return nil, errors.New("drop_on output requires at least one of error or back_pressure to be set")
+	}
+
+	wrapped, err := New(*conf.DropOn.Output, mgr, log, stats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output '%v': %v", conf.DropOn.Output.Type, err)
+	}
+	return newDropOn(TypeDropOn, conf.DropOn.Error, backPressure, wrapped, log, stats), nil
+}
+
+func newDropOn(
+	typeStr string,
+	onError bool,
+	backPressure time.Duration,
+	wrapped Type,
+	log log.Modular,
+	stats metrics.Type,
+) *DropOn {
+	return &DropOn{
+		running:      1,
+		onError:      onError,
+		backPressure: backPressure,
+		wrapped:      wrapped,
+
+		metricPrefix:    "output." + typeStr,
+		log:             log.NewModule(".output." + typeStr),
+		stats:           stats,
+		transactionsOut: make(chan types.Transaction),
+
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (d *DropOn) loop() {
+	var (
+		mRunning      = d.stats.GetGauge(d.metricPrefix + ".running")
+		mCount        = d.stats.GetCounter(d.metricPrefix + ".count")
+		mSuccess      = d.stats.GetCounter(d.metricPrefix + ".send.success")
+		mError        = d.stats.GetCounter(d.metricPrefix + ".send.error")
+		mDropped      = d.stats.GetCounter(d.metricPrefix + ".dropped")
+		mDroppedError = d.stats.GetCounter(d.metricPrefix + ".dropped.error")
+		mDroppedBP    = d.stats.GetCounter(d.metricPrefix + ".dropped.back_pressure")
+	)
+
+	defer func() {
+		close(d.transactionsOut)
+		d.wrapped.CloseAsync()
+		err := d.wrapped.WaitForClose(time.Second)
+		for ; err != nil; err = d.wrapped.WaitForClose(time.Second) {
+		}
+		mRunning.Decr(1)
+		close(d.closedChan)
+	}()
+	mRunning.Incr(1)
+
+	for atomic.LoadInt32(&d.running) == 1 {
+		var ts types.Transaction
+		var open bool
+		select {
+		case ts, open = <-d.transactionsIn:
+			if !open {
+				return
+			}
+			mCount.Incr(1)
+		case <-d.closeChan:
+			return
+		}
+
+		var deadline <-chan time.Time
+		if d.backPressure > 0 {
+			deadline = time.After(d.backPressure)
+		}
+
+		// The response channel is buffered so that a child output is never
+		// blocked by a message that was abandoned due to back pressure.
+		resChan := make(chan types.Response, 1)
+
+		var resOut types.Response
+		select {
+		case d.transactionsOut <- types.NewTransaction(ts.Payload, resChan):
+			select {
+			case res := <-resChan:
+				if err := res.Error(); err != nil {
+					mError.Incr(1)
+					if d.onError {
+						d.log.Debugf("Dropping message after error: %v\n", err)
+						mDropped.Incr(1)
+						mDroppedError.Incr(1)
+						resOut = response.NewAck()
+					} else {
+						resOut = res
+					}
+				} else {
+					mSuccess.Incr(1)
+					resOut = res
+				}
+			case <-deadline:
+				d.log.Debugln("Dropping message after back pressure period elapsed")
+				mDropped.Incr(1)
+				mDroppedBP.Incr(1)
+				resOut = response.NewAck()
+			case <-d.closeChan:
+				return
+			}
+		case <-deadline:
+			d.log.Debugln("Dropping message after back pressure period elapsed")
+			mDropped.Incr(1)
+			mDroppedBP.Incr(1)
+			resOut = response.NewAck()
+		case <-d.closeChan:
+			return
+		}
+
+		select {
+		case ts.ResponseChan <- resOut:
+		case <-d.closeChan:
+			return
+		}
+	}
+}
+
+// Consume assigns a messages channel for the output to read.
+func (d *DropOn) Consume(ts <-chan types.Transaction) error {
+	if d.transactionsIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	if err := d.wrapped.Consume(d.transactionsOut); err != nil {
+		return err
+	}
+	d.transactionsIn = ts
+	go d.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether the wrapped output is currently
+// connected to its target.
+func (d *DropOn) Connected() bool {
+	return types.IsConnected(d.wrapped)
+}
+
+// CloseAsync shuts down the DropOn output and stops processing requests.
+func (d *DropOn) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&d.running, 1, 0) {
+		close(d.closeChan)
+	}
+}
+
+// WaitForClose blocks until the DropOn output has closed down.
+func (d *DropOn) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-d.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,123 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeDropOnError] = TypeSpec{
+		constructor: NewDropOnError,
+		description: `
+Attempts to write messages to a child output and if the write fails for any
+reason the message is acknowledged and dropped instead of being reattempted.
+This is useful for best-effort outputs, such as a metrics sink, that should
+never block the rest of a pipeline when they are unavailable.
+
+This is equivalent to a ` + "[`drop_on`](#drop_on)" + ` output with the
+` + "`error`" + ` field set to ` + "`true`" + `. The number of dropped messages
+is exposed as the counter ` + "`output.drop_on_error.dropped`" + `.`,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			var outputSanit interface{} = struct{}{}
+			if conf.DropOnError.Output != nil {
+				var err error
+				if outputSanit, err = SanitiseConfig(*conf.DropOnError.Output); err != nil {
+					return nil, err
+				}
+			}
+			return map[string]interface{}{
+				"output": outputSanit,
+			}, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// DropOnErrorConfig contains configuration values for the DropOnError output
+// type.
+type DropOnErrorConfig struct {
+	Output *Config `json:"output" yaml:"output"`
+}
+
+// NewDropOnErrorConfig creates a new DropOnErrorConfig with default values.
+func NewDropOnErrorConfig() DropOnErrorConfig {
+	return DropOnErrorConfig{
+		Output: nil,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dummyDropOnErrorConfig struct {
+	Output interface{} `json:"output" yaml:"output"`
+}
+
+func (d DropOnErrorConfig) dummy() dummyDropOnErrorConfig {
+	dummy := dummyDropOnErrorConfig{
+		Output: d.Output,
+	}
+	if d.Output == nil {
+		dummy.Output = struct{}{}
+	}
+	return dummy
+}
+
+// MarshalJSON prints an empty object instead of nil.
+func (d DropOnErrorConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.dummy())
+}
+
+// MarshalYAML prints an empty object instead of nil.
+func (d DropOnErrorConfig) MarshalYAML() (interface{}, error) {
+	return d.dummy(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// NewDropOnError creates a new DropOn output type that drops messages that
+// fail to be delivered by its child output.
+func NewDropOnError(
+	conf Config,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	if conf.DropOnError.Output == nil {
+		return nil, errors.New("cannot create drop_on_error output without a child")
+	}
+	wrapped, err := New(*conf.DropOnError.Output, mgr, log, stats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output '%v': %v", conf.DropOnError.Output.Type, err)
+	}
+	return newDropOn(TypeDropOnError, true, 0, wrapped, log, stats), nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,227 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func TestDropOnConfigErrs(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeDropOn
+	conf.DropOn.Error = true
+
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing child output")
+	}
+
+	childConf := NewConfig()
+	conf.DropOn.Output = &childConf
+	conf.DropOn.Error = false
+
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing conditions")
+	}
+
+	conf.DropOn.BackPressure = "not a duration"
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad back_pressure")
+	}
+
+	conf.DropOn.BackPressure = "-1s"
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from negative back_pressure")
+	}
+
+	conf = NewConfig()
+	conf.Type = TypeDropOnError
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing child output")
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func newDropOnWithMock(t *testing.T, conf Config, stats metrics.Type) (*DropOn, *mockOutput, chan types.Transaction) {
+	t.Helper()
+
+	childConf := NewConfig()
+	conf.DropOn.Output = &childConf
+	conf.DropOnError.Output = &childConf
+
+	output, err := New(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, ok := output.(*DropOn)
+	if !ok {
+		t.Fatal("Failed to cast")
+	}
+
+	mOut := &mockOutput{}
+	d.wrapped = mOut
+
+	tChan := make(chan types.Transaction)
+	if err = d.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+	return d, mOut, tChan
+}
+
+func sendDropOnMsg(t *testing.T, tChan chan types.Transaction, resChan chan types.Response) {
+	t.Helper()
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func readDropOnRes(t *testing.T, resChan chan types.Response) types.Response {
+	t.Helper()
+	select {
+	case res := <-resChan:
+		return res
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	return nil
+}
+
+func forwardDropOnRes(t *testing.T, mOut *mockOutput, res types.Response) {
+	t.Helper()
+	var tran types.Transaction
+	select {
+	case tran = <-mOut.ts:
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	select {
+	case tran.ResponseChan <- res:
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func TestDropOnError(t *testing.T) {
+	for _, typeStr := range []string{TypeDropOn, TypeDropOnError} {
+		conf := NewConfig()
+		conf.Type = typeStr
+		conf.DropOn.Error = true
+
+		stats := metrics.NewLocal()
+		d, mOut, tChan := newDropOnWithMock(t, conf, stats)
+		resChan := make(chan types.Response)
+
+		sendDropOnMsg(t, tChan, resChan)
+		forwardDropOnRes(t, mOut, response.NewError(errors.New("nope")))
+		if err := readDropOnRes(t, resChan).Error(); err != nil {
+			t.Errorf("%v: Expected dropped message to be acked: %v", typeStr, err)
+		}
+
+		sendDropOnMsg(t, tChan, resChan)
+		forwardDropOnRes(t, mOut, response.NewAck())
+		if err := readDropOnRes(t, resChan).Error(); err != nil {
+			t.Errorf("%v: Unexpected error: %v", typeStr, err)
+		}
+
+		d.CloseAsync()
+		if err := d.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+
+		counters := stats.GetCounters()
+		if exp, act := int64(1), counters["output."+typeStr+".dropped"]; exp != act {
+			t.Errorf("%v: Wrong dropped count: %v != %v", typeStr, act, exp)
+		}
+		if exp, act := int64(1), counters["output."+typeStr+".send.success"]; exp != act {
+			t.Errorf("%v: Wrong success count: %v != %v", typeStr, act, exp)
+		}
+	}
+}
+
+func TestDropOnBackPressure(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeDropOn
+	conf.DropOn.BackPressure = "10ms"
+
+	stats := metrics.NewLocal()
+	d, mOut, tChan := newDropOnWithMock(t, conf, stats)
+	resChan := make(chan types.Response)
+
+	// Errors are propagated when not configured to drop on error.
+	sendDropOnMsg(t, tChan, resChan)
+	forwardDropOnRes(t, mOut, response.NewError(errors.New("nope")))
+	if err := readDropOnRes(t, resChan).Error(); err == nil {
+		t.Error("Expected error to be propagated")
+	}
+
+	// The child never accepts the message.
+	sendDropOnMsg(t, tChan, resChan)
+	if err := readDropOnRes(t, resChan).Error(); err != nil {
+		t.Errorf("Expected dropped message to be acked: %v", err)
+	}
+
+	// The child accepts the message but never responds in time.
+	sendDropOnMsg(t, tChan, resChan)
+	var tran types.Transaction
+	select {
+	case tran = <-mOut.ts:
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	if err := readDropOnRes(t, resChan).Error(); err != nil {
+		t.Errorf("Expected dropped message to be acked: %v", err)
+	}
+
+	// A late response must not block the child output.
+	select {
+	case tran.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	d.CloseAsync()
+	if err := d.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+
+	counters := stats.GetCounters()
+	if exp, act := int64(2), counters["output.drop_on.dropped.back_pressure"]; exp != act {
+		t.Errorf("Wrong dropped count: %v != %v", act, exp)
+	}
+	if exp, act := int64(1), counters["output.drop_on.send.error"]; exp != act {
+		t.Errorf("Wrong error count: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -36,9 +36,56 @@ func init() {
 Publishes messages into an Elasticsearch index. This output currently does not
 support creating the target index.
 
-Both the ` + "`id` and `index`" + ` fields can be dynamically set using function
-interpolations described [here](../config_interpolation.md#functions). When
-sending batched messages these interpolations are performed per message part.`,
+The ` + "`id`, `index`, `action` and `routing`" + ` fields can be dynamically
+set using function interpolations described
+[here](../config_interpolation.md#functions). When sending batched messages
+these interpolations are performed per message part.
+
+Messages are written using the bulk API, where the ` + "`action`" + ` field
+determines the bulk action of each message part and can be one of
+` + "`index`, `create`, `update`, `upsert` or `delete`" + `. The
+` + "`update`" + ` action sends the message as a partial document, and the
+` + "`upsert`" + ` action does the same but with ` + "`doc_as_upsert`" + `
+enabled, meaning the document is created if it does not yet exist. The
+` + "`delete`" + ` action ignores the contents of the message.
+
+Bulk items that fail with a retryable status code (429 or 5XX) are resent
+according to the backoff policy without resending items that succeeded, and if
+a bulk request fails outright all of its items are resent in the same way.
+Items that are rejected, or that exhaust their retries, result in an error being
+returned that identifies the failed parts of the batch. Those parts are then
+sent again on their own for as long as each attempt succeeds for some of them,
+after which the error is returned and the batch is resent in full.
+
+A ` + "`create`" + ` action that fails because the document already exists
+(409) is treated as a success, as the document is most likely from a previous
+attempt to send the same message.
+
+### Document Types
+
+The version of Elasticsearch is detected when connecting. For version 8 and
+above document types are no longer supported and the ` + "`type`" + ` field is
+ignored. For version 7 the ` + "`type`" + ` should either be set to
+` + "`_doc`" + ` or left empty in order to omit it.
+
+### Data Streams
+
+When ` + "`data_stream`" + ` is set to ` + "`true`" + ` the ` + "`index`" + `
+field is used as the name of the target data stream, all messages are written
+with the ` + "`create`" + ` action (regardless of the ` + "`action`" + ` field)
+and document types are omitted, as required by data streams.
+
+Documents written to a data stream must contain a top level
+` + "`@timestamp`" + ` field. Message parts that are missing this field are not
+sent and cause the write to fail with an error identifying those parts, you can
+add the field beforehand with a processor if your data does not already have it.
+
+The index name can be derived from the timestamp of each document with the
+` + "[`json_field_timestamp`](../config_interpolation.md#json_field_timestamp)" + `
+function, e.g. ` + "`logs-${!json_field_timestamp:@timestamp,2006.01.02}`" + `
+would write each document to a daily index or data stream according to the time
+the event occurred. Documents without a valid timestamp in that field would be
+written to the index ` + "`logs-`" + `.`,
 	}
 }
 
@@ -25,6 +25,7 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/output/writer"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/metadata"
 )
 
 //------------------------------------------------------------------------------
@@ -34,7 +35,9 @@ func init() {
 		constructor: NewGCPPubSub,
 		description: `
 Sends messages to a GCP Cloud Pub/Sub topic. Metadata from messages are sent as
-attributes.`,
+attributes, filtered according to the ` + "`metadata`" + ` section.
+
+` + metadata.Documentation,
 	}
 }
 
@@ -0,0 +1,71 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeGRPC] = TypeSpec{
+		constructor: NewGRPC,
+		description: `
+Calls a gRPC method with the contents of messages. The payload of each message
+part is sent as the serialized request message (usually protobuf) as raw bytes,
+and responses are discarded.
+
+The method is specified in the form ` + "`package.Service/Method`" + `. If
+` + "`proto_descriptor_set`" + ` is set to the path of a serialized
+FileDescriptorSet (as produced by ` + "`protoc --descriptor_set_out`" + `) then
+the method is checked against it at startup and its streaming modes are
+detected, otherwise the method is assumed to be unary.
+
+Client streaming methods are called once per message, with each part of the
+message sent over the stream. Other methods are called once for each message
+part. Each call is given a deadline of ` + "`timeout_ms`" + `, which is
+disabled when set to zero.
+
+### Metadata
+
+Message metadata is sent as gRPC request metadata, where keys are lowercased and
+keys reserved by gRPC (prefixed with ` + "`grpc-`" + `) are skipped. For client
+streaming methods the metadata of the first message part is used.
+
+` + tls.Documentation,
+	}
+}
+
+// NewGRPC creates a new GRPC output type.
+func NewGRPC(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	w, err := writer.NewGRPC(conf.GRPC, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("grpc", w, log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -25,6 +25,7 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/output/writer"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/tracecontext"
 )
 
 //------------------------------------------------------------------------------
@@ -46,12 +47,35 @@ When the number of retries expires the output will reject the message, the
 behaviour after this will depend on the pipeline but usually this simply means
 the send is attempted again until successful whilst applying back pressure.
 
+The ` + "`rate_limit`" + ` field can be used to specify a rate limit
+[resource](../rate_limits/README.md) to cap the rate of requests across all
+parallel components service wide. Requests block until the rate limit permits
+access, and the metric ` + "`output.http_client.client.http.rate_limit.total_ms`" + `
+counts the total time in milliseconds spent throttled.
+
 The URL and header values of this type can be dynamically set using function
 interpolations described [here](../config_interpolation.md#functions).
 
-The body of the HTTP request is the raw contents of the message payload. If the
-message has multiple parts the request will be sent according to
-[RFC1341](https://www.w3.org/Protocols/rfc1341/7_2_Multipart.html)`,
+The body of the HTTP request is the raw contents of the message payload. The
+field ` + "`batch_as`" + ` determines how messages with multiple parts (batches)
+are sent, and can be one of the following:
+
+- ` + "`multipart`" + `: The batch is sent as a single request according to
+  [RFC1341](https://www.w3.org/Protocols/rfc1341/7_2_Multipart.html).
+- ` + "`ndjson`" + `: The batch is sent as a single request with a body of
+  newline delimited parts. If the ` + "`Content-Type`" + ` header is left as the
+  default it is set to ` + "`application/x-ndjson`" + `.
+- ` + "`none`" + `: Each part of the batch is sent as an individual request in
+  order.
+
+When a batch is sent as a single request the response applies to all parts of
+the batch. When sent as individual requests a failed request causes the whole
+batch to be retried, including parts that were already sent.
+
+` + tracecontext.Documentation + `
+
+For multiple part messages the trace context is taken from the metadata of the
+first part.`,
 	}
 }
 
@@ -25,7 +25,9 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/output/writer"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/kafka/sasl"
 	"github.com/Jeffail/benthos/lib/util/tls"
+	"github.com/Jeffail/benthos/lib/util/tracecontext"
 )
 
 //------------------------------------------------------------------------------
@@ -55,7 +57,42 @@ value. If the key is empty then a partition is chosen at random. You can
 alternatively force the partitioner to round-robin partitions with the field
 ` + "`round_robin_partitions`" + `.
 
-` + tls.Documentation + ``,
+### Delivery Guarantees
+
+Messages are sent with a synchronous producer and are only acknowledged once
+the broker has confirmed the write, giving at-least-once delivery. A message
+may be written more than once if a send times out after the broker has
+committed it, as both the client and Benthos will retry the send. Setting
+` + "`ack_replicas`" + ` to ` + "`true`" + ` prevents writes from being lost
+during a leader election, at the cost of throughput.
+
+Setting ` + "`idempotent_write`" + ` to ` + "`true`" + ` enables the
+idempotent producer, where the broker discards duplicates of a message that are
+caused by the client retrying a send. This forces acknowledgement from all
+replicas (regardless of ` + "`ack_replicas`" + `) and a single in flight
+request per broker, and requires a ` + "`target_version`" + ` of at least
+0.11.0.0. Duplicates are only prevented for retries within the client, a
+message that is resent by Benthos after a failed write is treated as a new
+message by the broker.
+
+Setting ` + "`transactional_id`" + ` enables the transactional producer,
+where each batch is written within a Kafka transaction that is committed once
+all of its messages are acknowledged and is aborted if any of them fail.
+Consumers reading with an isolation level of ` + "`read_committed`" + ` will
+therefore never see the messages of a failed write, only those of the attempt
+that succeeds. Transactions imply ` + "`idempotent_write`" + ` and require a
+` + "`target_version`" + ` of at least 0.11.0.0. Only one batch is sent at a
+time, which limits throughput. The ID should be unique to each Benthos
+instance, as a new producer with the same ID fences off the previous one.
+
+` + tls.Documentation + `
+
+` + sasl.Documentation + `
+
+` + tracecontext.Documentation + `
+
+Trace context headers require a ` + "`target_version`" + ` of at least
+0.11.0.0.`,
 	}
 }
 
@@ -0,0 +1,63 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeKinesisFirehose] = TypeSpec{
+		constructor: NewKinesisFirehose,
+		description: `
+Sends messages to a Kinesis Firehose delivery stream.
+
+Messages are sent with ` + "`PutRecordBatch`" + ` calls of up to 500 records or
+4 MiB each, where each message part is a record. Records that fail to be
+delivered are retried according to the backoff fields, and if any records
+remain undelivered once the retries are exhausted an error is returned, which
+is propagated back to the source of the message.
+
+The ` + "`stream`" + ` field can be dynamically set using function
+interpolations described [here](../config_interpolation.md#functions). When
+sending batched messages the interpolations are performed per message part.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewKinesisFirehose creates a new KinesisFirehose output type.
+func NewKinesisFirehose(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	kin, err := writer.NewKinesisFirehose(conf.KinesisFirehose, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter(
+		"kinesis_firehose", kin, log, stats,
+	)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,86 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeRedis] = TypeSpec{
+		constructor: NewRedis,
+		description: `
+Writes each message part to Redis using a command determined by the field
+` + "`operation`" + `, which can be one of the following:
+
+#### ` + "`hset`" + `
+
+Sets the hash ` + "`field`" + ` of ` + "`key`" + ` to ` + "`value`" + ` using
+the HSET command.
+
+#### ` + "`sadd`" + `
+
+Adds ` + "`value`" + ` as a member of the set ` + "`key`" + ` using the SADD
+command.
+
+#### ` + "`zadd`" + `
+
+Adds ` + "`value`" + ` as a member of the sorted set ` + "`key`" + ` with the
+score ` + "`score`" + ` using the ZADD command. Scores must resolve to a number,
+and message parts with a score that fails to parse are not written. Instead the
+write fails for those parts only, which are then retried the same as any other
+failed write unless the output is wrapped with ` + "`drop_on_error`" + ` or
+` + "`with_dead_letter`" + `.
+
+The fields ` + "`key`, `field`, `value` and `score`" + ` can be dynamically set
+using function interpolations described
+[here](../config_interpolation.md#functions), which are resolved per message
+part. For example, to maintain a leaderboard from JSON documents:
+
+` + "``` yaml" + `
+type: redis
+redis:
+  url: tcp://localhost:6379
+  operation: zadd
+  key: leaderboard
+  value: ${!json_field:player}
+  score: ${!json_field:points}
+` + "```" + ``,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewRedis creates a new Redis output type.
+func NewRedis(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	w, err := writer.NewRedis(conf.Redis, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("redis", w, log, stats)
+}
+
+//------------------------------------------------------------------------------
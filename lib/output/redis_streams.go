@@ -25,6 +25,7 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/output/writer"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/metadata"
 )
 
 //------------------------------------------------------------------------------
@@ -40,9 +41,12 @@ cap is applied only when Redis is able to remove a whole macro node, for
 efficiency.
 
 Redis stream entries are key/value pairs, as such it is necessary to specify the
-key to be set to the body of the message. All metadata fields of the message
-will also be set as key/value pairs, if there is a key collision between
-a metadata item and the body then the body takes precedence.`,
+key to be set to the body of the message. The metadata fields of the message
+that pass the filters of the ` + "`metadata`" + ` section will also be set as
+key/value pairs, if there is a key collision between a metadata item and the
+body then the body takes precedence.
+
+` + metadata.Documentation,
 	}
 }
 
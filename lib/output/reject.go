@@ -0,0 +1,193 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeReject] = TypeSpec{
+		constructor: NewReject,
+		description: `
+Rejects all messages, treating them as though the output destination failed to
+publish them. The value of this field is used as the error message returned
+with each rejection.
+
+Since the message is never acknowledged it is retried (or, depending on the
+input, redelivered), which makes this output useful for testing failure paths.
+
+### Strict Routing
+
+By default a ` + "[`switch`](#switch)" + ` output drops messages that do not
+match any of its conditions. Adding a final ` + "`reject`" + ` output with no
+condition ensures that unmatched messages are never silently acknowledged:
+
+` + "``` yaml" + `
+output:
+  type: switch
+  switch:
+    outputs:
+    - output:
+        type: foo
+      condition:
+        type: text
+        text:
+          operator: contains
+          arg: foo
+    - output:
+        type: reject
+        reject: "message did not match any routing conditions"
+` + "```" + `
+
+Rejected messages are retried by the switch until either they are successfully
+delivered or the service shuts down, and each rejection is logged and counted
+as an output error. This applies back pressure to the pipeline so that routing
+gaps are surfaced rather than resulting in lost data.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// RejectConfig contains configuration fields for the Reject output type.
+type RejectConfig string
+
+// NewRejectConfig creates a new RejectConfig with default values.
+func NewRejectConfig() RejectConfig {
+	return RejectConfig("")
+}
+
+//------------------------------------------------------------------------------
+
+// Reject is an output type that rejects all messages.
+type Reject struct {
+	running int32
+
+	errText string
+	log     log.Modular
+	stats   metrics.Type
+
+	transactionsIn <-chan types.Transaction
+
+	closedChan chan struct{}
+	closeChan  chan struct{}
+}
+
+// NewReject creates a new Reject output type.
+func NewReject(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	return &Reject{
+		running:    1,
+		errText:    string(conf.Reject),
+		log:        log.NewModule(".output.reject"),
+		stats:      stats,
+		closedChan: make(chan struct{}),
+		closeChan:  make(chan struct{}),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// loop is an internal loop that rejects incoming messages.
+func (r *Reject) loop() {
+	var (
+		mRunning  = r.stats.GetGauge("output.running")
+		mRunningF = r.stats.GetGauge("output.reject.running")
+		mCount    = r.stats.GetCounter("output.count")
+		mCountF   = r.stats.GetCounter("output.reject.count")
+		mError    = r.stats.GetCounter("output.send.error")
+		mErrorF   = r.stats.GetCounter("output.reject.send.error")
+	)
+
+	defer func() {
+		mRunning.Decr(1)
+		mRunningF.Decr(1)
+		close(r.closedChan)
+	}()
+	mRunning.Incr(1)
+	mRunningF.Incr(1)
+
+	errText := r.errText
+	if len(errText) == 0 {
+		errText = "message rejected"
+	}
+	rejectErr := errors.New(errText)
+
+	for atomic.LoadInt32(&r.running) == 1 {
+		var ts types.Transaction
+		var open bool
+		select {
+		case ts, open = <-r.transactionsIn:
+			if !open {
+				return
+			}
+		case <-r.closeChan:
+			return
+		}
+		mCount.Incr(1)
+		mCountF.Incr(1)
+		mError.Incr(1)
+		mErrorF.Incr(1)
+
+		select {
+		case ts.ResponseChan <- response.NewError(rejectErr):
+		case <-r.closeChan:
+			return
+		}
+	}
+}
+
+// Consume assigns a messages channel for the output to read.
+func (r *Reject) Consume(ts <-chan types.Transaction) error {
+	if r.transactionsIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	r.transactionsIn = ts
+	go r.loop()
+	return nil
+}
+
+// CloseAsync shuts down the Reject output and stops processing messages.
+func (r *Reject) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&r.running, 1, 0) {
+		close(r.closeChan)
+	}
+}
+
+// WaitForClose blocks until the Reject output has closed down.
+func (r *Reject) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-r.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
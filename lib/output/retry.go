@@ -55,7 +55,16 @@ we want to avoid reapplying to the same message more than once in the pipeline.
 
 Rather than retrying the same output you may wish to retry the send using a
 different output target (a dead letter queue). In which case you should instead
-use the ` + "[`broker`](#broker)" + ` output type with the pattern 'try'.`,
+use the ` + "[`broker`](#broker)" + ` output type with the pattern 'try'.
+
+Once the retries are exhausted the error of the last attempt is propagated back
+to the source of the message rather than the message being dropped. If the
+service is shut down whilst waiting to retry a message then the wait is
+abandoned immediately.
+
+The number of retries is exposed as the counter
+` + "`output.retry.retries`" + `, and the duration of each backoff period is
+exposed as the timing metric ` + "`output.retry.backoff`" + ` in nanoseconds.`,
 		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
 			confBytes, err := json.Marshal(conf.Retry)
 			if err != nil {
@@ -199,6 +208,8 @@ func (r *Retry) loop() {
 		mPartsSuccess = r.stats.GetCounter("output.retry.parts.send.success")
 		mError        = r.stats.GetCounter("output.retry.send.error")
 		mEndOfRetries = r.stats.GetCounter("output.retry.end_of_retries")
+		mRetries      = r.stats.GetCounter("output.retry.retries")
+		mBackoff      = r.stats.GetTimer("output.retry.backoff")
 	)
 
 	defer func() {
@@ -252,9 +263,11 @@ func (r *Retry) loop() {
 				if nextBackoff == backoff.Stop {
 					mEndOfRetries.Incr(1)
 					r.backoff.Reset()
-					resOut = response.NewNoack()
+					resOut = res
 					break retryLoop
 				}
+				mRetries.Incr(1)
+				mBackoff.Timing(nextBackoff.Nanoseconds())
 				select {
 				case <-time.After(nextBackoff):
 				case <-r.closeChan:
@@ -291,6 +304,12 @@ func (r *Retry) Consume(ts <-chan types.Transaction) error {
 	return nil
 }
 
+// Connected returns a boolean indicating whether the wrapped output is currently
+// connected to its target.
+func (r *Retry) Connected() bool {
+	return types.IsConnected(r.wrapped)
+}
+
 // CloseAsync shuts down the Retry input and stops processing requests.
 func (r *Retry) CloseAsync() {
 	if atomic.CompareAndSwapInt32(&r.running, 1, 0) {
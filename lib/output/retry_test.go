@@ -21,6 +21,7 @@
 package output
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -210,3 +211,131 @@ func TestRetrySadPath(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func newRetryWithMock(t *testing.T, conf Config, stats metrics.Type) (*Retry, *mockOutput) {
+	t.Helper()
+
+	childConf := NewConfig()
+	conf.Retry.Output = &childConf
+
+	output, err := NewRetry(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ret, ok := output.(*Retry)
+	if !ok {
+		t.Fatal("Failed to cast")
+	}
+
+	mOut := &mockOutput{}
+	ret.wrapped = mOut
+	return ret, mOut
+}
+
+func TestRetryEndOfRetries(t *testing.T) {
+	conf := NewConfig()
+	conf.Retry.MaxRetries = 2
+	conf.Retry.Backoff.InitialInterval = "10us"
+	conf.Retry.Backoff.MaxInterval = "10us"
+
+	stats := metrics.NewLocal()
+	ret, mOut := newRetryWithMock(t, conf, stats)
+
+	tChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	if err := ret.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case tChan <- types.NewTransaction(message.New(nil), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	errTest := errors.New("test error")
+	for i := 0; i < 3; i++ {
+		var tran types.Transaction
+		select {
+		case tran = <-mOut.ts:
+		case <-resChan:
+			t.Fatalf("Received response after %v attempts", i)
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		select {
+		case tran.ResponseChan <- response.NewError(errTest):
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	select {
+	case res := <-resChan:
+		if exp, act := errTest, res.Error(); exp != act {
+			t.Errorf("Wrong error returned: %v != %v", act, exp)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	ret.CloseAsync()
+	if err := ret.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+
+	expCounters := map[string]int64{
+		"output.retry.send.error":     3,
+		"output.retry.retries":        2,
+		"output.retry.end_of_retries": 1,
+	}
+	counters := stats.GetCounters()
+	for k, exp := range expCounters {
+		if act := counters[k]; exp != act {
+			t.Errorf("Wrong count for %v: %v != %v", k, act, exp)
+		}
+	}
+	if _, exists := stats.GetTimings()["output.retry.backoff"]; !exists {
+		t.Error("Expected backoff timing")
+	}
+}
+
+func TestRetryShutDownDuringBackoff(t *testing.T) {
+	conf := NewConfig()
+	conf.Retry.Backoff.InitialInterval = "1h"
+	conf.Retry.Backoff.MaxInterval = "1h"
+
+	ret, mOut := newRetryWithMock(t, conf, metrics.Noop())
+
+	tChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	if err := ret.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case tChan <- types.NewTransaction(message.New(nil), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	var tran types.Transaction
+	select {
+	case tran = <-mOut.ts:
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	select {
+	case tran.ResponseChan <- response.NewError(errors.New("test error")):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	ret.CloseAsync()
+	if err := ret.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
@@ -37,7 +37,16 @@ Sends message parts as objects to an Amazon S3 bucket. Each object is uploaded
 with the path specified with the ` + "`path`" + ` field. In order to have a
 different path for each object you should use function interpolations described
 [here](../config_interpolation.md#functions), which are calculated per message
-of a batch.`,
+of a batch.
+
+Objects larger than ` + "`part_size`" + ` bytes (minimum 5MB) are uploaded as
+multipart uploads, with up to ` + "`upload_concurrency`" + ` parts being sent in
+parallel. If any part of a multipart upload fails the upload is aborted so that
+no orphaned parts are left in the bucket.
+
+Failed uploads are retried according to the backoff fields, and if an object
+remains unsent once the retries are exhausted an error is returned, which is
+propagated back to the source of the message.`,
 	}
 }
 
@@ -45,8 +54,12 @@ of a batch.`,
 
 // NewAmazonS3 creates a new AmazonS3 output type.
 func NewAmazonS3(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	sthree, err := writer.NewAmazonS3(conf.S3, log, stats)
+	if err != nil {
+		return nil, err
+	}
 	return NewWriter(
-		"s3", writer.NewAmazonS3(conf.S3, log, stats), log, stats,
+		"s3", sthree, log, stats,
 	)
 }
 
@@ -23,6 +23,7 @@ package output
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"sync/atomic"
 	"time"
 
@@ -106,7 +107,23 @@ for an output, it behaves like a static ` + "`true`" + ` condition. If
 continue evaluating additional outputs after finding a match. If an output
 applies back pressure it will block all subsequent messages, and if an output
 fails to send a message, it will be retried continuously until completion or
-service shut down. Messages that do not match any outputs will be dropped.`,
+service shut down. Messages that do not match any outputs will be dropped.
+
+A message is only acknowledged once every output it was sent to has
+acknowledged it. When ` + "`retry_until_success`" + ` is set to
+` + "`false`" + ` a failed send is not retried, and the error is instead
+propagated back to the input, which might result in duplicates being sent to the
+outputs that succeeded.
+
+When ` + "`strict_mode`" + ` is set to ` + "`true`" + ` messages that do not
+match any outputs are rejected with an error rather than dropped, which is
+propagated back to the input.
+
+The number of messages routed to, sent by and failed by each output are exposed
+as the metrics ` + "`broker.switch.output.<index>.routed`" + `,
+` + "`broker.switch.output.<index>.sent`" + ` and
+` + "`broker.switch.output.<index>.error`" + `, where the index is the position of
+the output within the ` + "`outputs`" + ` list.`,
 		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
 			outSlice := []interface{}{}
 			for _, out := range conf.Switch.Outputs {
@@ -126,7 +143,9 @@ service shut down. Messages that do not match any outputs will be dropped.`,
 				outSlice = append(outSlice, sanit)
 			}
 			return map[string]interface{}{
-				"outputs": outSlice,
+				"retry_until_success": conf.Switch.RetryUntilSuccess,
+				"strict_mode":         conf.Switch.StrictMode,
+				"outputs":             outSlice,
 			}, nil
 		},
 	}
@@ -136,13 +155,17 @@ service shut down. Messages that do not match any outputs will be dropped.`,
 
 // SwitchConfig contains configuration fields for the Switch output type.
 type SwitchConfig struct {
-	Outputs []SwitchConfigOutput `json:"outputs" yaml:"outputs"`
+	RetryUntilSuccess bool                 `json:"retry_until_success" yaml:"retry_until_success"`
+	StrictMode        bool                 `json:"strict_mode" yaml:"strict_mode"`
+	Outputs           []SwitchConfigOutput `json:"outputs" yaml:"outputs"`
 }
 
 // NewSwitchConfig creates a new SwitchConfig with default values.
 func NewSwitchConfig() SwitchConfig {
 	return SwitchConfig{
-		Outputs: []SwitchConfigOutput{},
+		RetryUntilSuccess: true,
+		StrictMode:        false,
+		Outputs:           []SwitchConfigOutput{},
 	}
 }
 
@@ -217,6 +240,9 @@ type Switch struct {
 	conditions   []types.Condition
 	fallthroughs []bool
 
+	retryUntilSuccess bool
+	strictMode        bool
+
 	closedChan chan struct{}
 	closeChan  chan struct{}
 }
@@ -244,6 +270,9 @@ func NewSwitch(
 		fallthroughs: make([]bool, lOutputs),
 		closedChan:   make(chan struct{}),
 		closeChan:    make(chan struct{}),
+
+		retryUntilSuccess: conf.Switch.RetryUntilSuccess,
+		strictMode:        conf.Switch.StrictMode,
 	}
 
 	var err error
@@ -293,7 +322,17 @@ func (o *Switch) loop() {
 		mMsgRcvd   = o.stats.GetCounter("broker.switch.messages.received")
 		mMsgSnt    = o.stats.GetCounter("broker.switch.messages.sent")
 		mOutputErr = o.stats.GetCounter("broker.switch.output.error")
+
+		mOutRouted = make([]metrics.StatCounter, len(o.outputs))
+		mOutSent   = make([]metrics.StatCounter, len(o.outputs))
+		mOutErr    = make([]metrics.StatCounter, len(o.outputs))
 	)
+	for i := range o.outputs {
+		prefix := fmt.Sprintf("broker.switch.output.%v", i)
+		mOutRouted[i] = o.stats.GetCounter(prefix + ".routed")
+		mOutSent[i] = o.stats.GetCounter(prefix + ".sent")
+		mOutErr[i] = o.stats.GetCounter(prefix + ".error")
+	}
 
 	defer func() {
 		for i, output := range o.outputs {
@@ -334,15 +373,23 @@ func (o *Switch) loop() {
 			}
 		}
 		if len(outputTargets) == 0 {
+			var res types.Response = response.NewAck()
+			if o.strictMode {
+				res = response.NewError(ErrSwitchNoConditionMet)
+			}
 			select {
-			case ts.ResponseChan <- response.NewAck():
+			case ts.ResponseChan <- res:
 				mMsgDrop.Incr(1)
 			case <-o.closeChan:
 				return
 			}
 			continue
 		}
+		for _, i := range outputTargets {
+			mOutRouted[i].Incr(1)
+		}
 
+		var sendErr error
 		for len(outputTargets) > 0 {
 			for _, i := range outputTargets {
 				msgCopy := ts.Payload.Copy()
@@ -358,29 +405,49 @@ func (o *Switch) loop() {
 				case res := <-o.outputResChans[i]:
 					if res.Error() != nil {
 						newTargets = append(newTargets, i)
+						sendErr = res.Error()
 						o.logger.Errorf("Failed to dispatch switch message: %v\n", res.Error())
 						mOutputErr.Incr(1)
-						if !o.throt.Retry() {
+						mOutErr[i].Incr(1)
+						if o.retryUntilSuccess && !o.throt.Retry() {
 							return
 						}
 					} else {
 						o.throt.Reset()
 						mMsgSnt.Incr(1)
+						mOutSent[i].Incr(1)
 					}
 				case <-o.closeChan:
 					return
 				}
 			}
 			outputTargets = newTargets
+			if !o.retryUntilSuccess {
+				break
+			}
+		}
+		if len(outputTargets) == 0 {
+			sendErr = nil
 		}
 		select {
-		case ts.ResponseChan <- response.NewAck():
+		case ts.ResponseChan <- response.NewError(sendErr):
 		case <-o.closeChan:
 			return
 		}
 	}
 }
 
+// Connected returns a boolean indicating whether all outputs of the switch are
+// currently connected to their targets.
+func (o *Switch) Connected() bool {
+	for _, out := range o.outputs {
+		if !types.IsConnected(out) {
+			return false
+		}
+	}
+	return true
+}
+
 // CloseAsync shuts down the Switch broker and stops processing requests.
 func (o *Switch) CloseAsync() {
 	if atomic.CompareAndSwapInt32(&o.running, 1, 0) {
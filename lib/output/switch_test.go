@@ -30,6 +30,7 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/processor"
 	"github.com/Jeffail/benthos/lib/processor/condition"
 	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
@@ -50,8 +51,12 @@ func TestSwitchInterfaces(t *testing.T) {
 //------------------------------------------------------------------------------
 
 func newSwitch(conf Config, mockOutputs []*MockOutputType) (*Switch, error) {
+	return newSwitchWithStats(conf, mockOutputs, metrics.Noop())
+}
+
+func newSwitchWithStats(conf Config, mockOutputs []*MockOutputType, stats metrics.Type) (*Switch, error) {
 	conf.Type = "switch"
-	genType, err := New(conf, nil, log.Noop(), metrics.Noop())
+	genType, err := New(conf, nil, log.Noop(), stats)
 	if err != nil {
 		return nil, err
 	}
@@ -541,6 +546,142 @@ func TestSwitchAtLeastOnce(t *testing.T) {
 	}
 }
 
+func TestSwitchNoMatchStrict(t *testing.T) {
+	mockOutputs := []*MockOutputType{{}, {}}
+
+	conf := NewConfig()
+	conf.Switch.StrictMode = true
+	for i := 0; i < len(mockOutputs); i++ {
+		outConf := NewSwitchConfigOutput()
+		outConf.Condition.Type = condition.TypeStatic
+		outConf.Condition.Static = false
+		conf.Switch.Outputs = append(conf.Switch.Outputs, outConf)
+	}
+
+	s, err := newSwitch(conf, mockOutputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	if err = s.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case readChan <- types.NewTransaction(message.New([][]byte{[]byte("hello world")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for output send")
+	}
+
+	select {
+	case res := <-resChan:
+		if exp, act := ErrSwitchNoConditionMet, res.Error(); exp != act {
+			t.Errorf("Wrong error returned: %v != %v", act, exp)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out responding to output")
+	}
+
+	s.CloseAsync()
+	if err := s.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSwitchNoRetry(t *testing.T) {
+	mockOne := MockOutputType{}
+	mockTwo := MockOutputType{}
+
+	mockOutputs := []*MockOutputType{
+		&mockOne, &mockTwo,
+	}
+
+	conf := NewConfig()
+	conf.Switch.RetryUntilSuccess = false
+	for i := 0; i < len(mockOutputs); i++ {
+		outConf := NewSwitchConfigOutput()
+		outConf.Fallthrough = true
+		conf.Switch.Outputs = append(conf.Switch.Outputs, outConf)
+	}
+
+	stats := metrics.NewLocal()
+	s, err := newSwitchWithStats(conf, mockOutputs, stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	if err = s.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case readChan <- types.NewTransaction(message.New([][]byte{[]byte("hello world")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for output send")
+	}
+
+	var ts1, ts2 types.Transaction
+	select {
+	case ts1 = <-mockOne.TChan:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for mockOne")
+	}
+	select {
+	case ts2 = <-mockTwo.TChan:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for mockTwo")
+	}
+
+	errTest := errors.New("this is a test")
+	select {
+	case ts1.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out responding to output")
+	}
+	select {
+	case ts2.ResponseChan <- response.NewError(errTest):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out responding to output")
+	}
+
+	select {
+	case <-mockTwo.TChan:
+		t.Error("Received retried message to mockTwo")
+	case res := <-resChan:
+		if exp, act := errTest, res.Error(); exp != act {
+			t.Errorf("Wrong error returned: %v != %v", act, exp)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out responding to output")
+	}
+
+	s.CloseAsync()
+	if err := s.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+
+	expCounters := map[string]int64{
+		"broker.switch.output.0.routed": 1,
+		"broker.switch.output.0.sent":   1,
+		"broker.switch.output.0.error":  0,
+		"broker.switch.output.1.routed": 1,
+		"broker.switch.output.1.sent":   0,
+		"broker.switch.output.1.error":  1,
+	}
+	counters := stats.GetCounters()
+	for k, exp := range expCounters {
+		if act := counters[k]; exp != act {
+			t.Errorf("Wrong count for %v: %v != %v", k, act, exp)
+		}
+	}
+}
+
 func TestSwitchShutDownFromErrorResponse(t *testing.T) {
 	mockOutputs := []*MockOutputType{{}, {}}
 
@@ -708,3 +849,154 @@ func TestSwitchShutDownFromSend(t *testing.T) {
 }
 
 //------------------------------------------------------------------------------
+
+func TestSwitchProcessorFailed(t *testing.T) {
+	mockOutputs := []*MockOutputType{{}, {}}
+
+	conf := NewConfig()
+	for i := 0; i < len(mockOutputs); i++ {
+		conf.Switch.Outputs = append(conf.Switch.Outputs, NewSwitchConfigOutput())
+	}
+	conf.Switch.Outputs[0].Condition.Type = condition.TypeProcessorFailed
+
+	s, err := newSwitch(conf, mockOutputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	if err = s.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	newProc := func(pType string, edit func(conf *processor.Config)) processor.Type {
+		pConf := processor.NewConfig()
+		pConf.Type = pType
+		if edit != nil {
+			edit(&pConf)
+		}
+		proc, perr := processor.New(pConf, nil, log.Noop(), metrics.Noop())
+		if perr != nil {
+			t.Fatal(perr)
+		}
+		return proc
+	}
+
+	jsonProc := newProc(processor.TypeJSON, func(conf *processor.Config) {
+		conf.JSON.Operator = "select"
+		conf.JSON.Path = "foo"
+	})
+	archiveProc := newProc(processor.TypeArchive, func(conf *processor.Config) {
+		conf.Archive.Format = "lines"
+	})
+	splitProc := newProc(processor.TypeSplit, nil)
+	groupByProc := newProc(processor.TypeGroupBy, func(conf *processor.Config) {
+		gConf := processor.GroupByElement{
+			Condition: condition.NewConfig(),
+		}
+		gConf.Condition.Type = condition.TypeText
+		gConf.Condition.Text.Operator = "contains"
+		gConf.Condition.Text.Arg = "a"
+		conf.GroupBy = append(conf.GroupBy, gConf)
+	})
+
+	tests := []struct {
+		name   string
+		input  []string
+		procs  []processor.Type
+		output []int
+	}{
+		{
+			name:   "no failures",
+			input:  []string{`{"foo":"a"}`, `{"foo":"b"}`},
+			procs:  []processor.Type{jsonProc},
+			output: []int{1},
+		},
+		{
+			name:   "failed part",
+			input:  []string{`{"foo":"a"}`, `not json`},
+			procs:  []processor.Type{jsonProc},
+			output: []int{0},
+		},
+		{
+			name:   "failed then archived",
+			input:  []string{`{"foo":"a"}`, `not json`},
+			procs:  []processor.Type{jsonProc, archiveProc},
+			output: []int{0},
+		},
+		{
+			name:   "failed then split",
+			input:  []string{`{"foo":"a"}`, `not json`},
+			procs:  []processor.Type{jsonProc, splitProc},
+			output: []int{1, 0},
+		},
+		{
+			name:   "failed then grouped",
+			input:  []string{`{"foo":"a"}`, `not json`},
+			procs:  []processor.Type{jsonProc, groupByProc},
+			output: []int{1, 0},
+		},
+	}
+
+	for _, test := range tests {
+		msgs := []types.Message{message.New(nil)}
+		for _, input := range test.input {
+			msgs[0].Append(message.NewPart([]byte(input)))
+		}
+		for _, proc := range test.procs {
+			var nextMsgs []types.Message
+			for _, m := range msgs {
+				resMsgs, _ := proc.ProcessMessage(m)
+				nextMsgs = append(nextMsgs, resMsgs...)
+			}
+			msgs = nextMsgs
+		}
+		if len(msgs) != len(test.output) {
+			t.Fatalf("%v: wrong count of messages: %v != %v", test.name, len(msgs), len(test.output))
+		}
+
+		for i, msg := range msgs {
+			select {
+			case readChan <- types.NewTransaction(msg, resChan):
+			case <-time.After(time.Second):
+				t.Fatalf("%v: timed out waiting for switch send", test.name)
+			}
+
+			var ts types.Transaction
+			select {
+			case ts = <-mockOutputs[0].TChan:
+				if exp := test.output[i]; exp != 0 {
+					t.Errorf("%v: message %v routed to wrong output: 0 != %v", test.name, i, exp)
+				}
+			case ts = <-mockOutputs[1].TChan:
+				if exp := test.output[i]; exp != 1 {
+					t.Errorf("%v: message %v routed to wrong output: 1 != %v", test.name, i, exp)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("%v: timed out waiting for output to propagate", test.name)
+			}
+
+			select {
+			case ts.ResponseChan <- response.NewAck():
+			case <-time.After(time.Second):
+				t.Fatalf("%v: timed out responding to output", test.name)
+			}
+			select {
+			case res := <-resChan:
+				if res.Error() != nil {
+					t.Error(res.Error())
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("%v: timed out responding to switch", test.name)
+			}
+		}
+	}
+
+	s.CloseAsync()
+	if err := s.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
+
+//------------------------------------------------------------------------------
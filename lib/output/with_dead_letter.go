@@ -0,0 +1,348 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeWithDeadLetter] = TypeSpec{
+		constructor: NewWithDeadLetter,
+		description: `
+Attempts to write messages to a primary child output and, if the primary output
+fails to deliver a message, writes it to a second child output (the dead letter
+queue) instead.
+
+` + "``` yaml" + `
+output:
+  type: with_dead_letter
+  with_dead_letter:
+    output:
+      type: retry
+      retry:
+        max_retries: 3
+        output:
+          type: http_client
+          http_client:
+            url: http://example.com/post
+    dead_letter:
+      type: file
+      file:
+        path: ./failed.txt
+` + "```" + `
+
+A failure is any error returned by the primary output, therefore in order to
+retry a message before giving up on it the primary output should be wrapped
+with a ` + "[`retry`](#retry)" + ` output. Messages written to the dead letter
+queue are annotated with the metadata field ` + "`dead_letter_error`" + `, which
+contains the error returned by the primary output. The dead letter queue can
+itself be a ` + "[`dead_letter`](#dead_letter)" + ` output in order to schedule
+messages for reprocessing.
+
+A message is acknowledged once it has been delivered by either output. If both
+outputs fail then the error of the dead letter queue is propagated back to the
+source of the message, where it will be retried.
+
+The outcome of each message is exposed as the counters
+` + "`output.with_dead_letter.delivered`" + `,
+` + "`output.with_dead_letter.dead_lettered`" + ` and
+` + "`output.with_dead_letter.lost`" + `, where lost messages are those that
+neither output was able to deliver.`,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			var err error
+			var outputSanit, deadLetterSanit interface{} = struct{}{}, struct{}{}
+			if conf.WithDeadLetter.Output != nil {
+				if outputSanit, err = SanitiseConfig(*conf.WithDeadLetter.Output); err != nil {
+					return nil, err
+				}
+			}
+			if conf.WithDeadLetter.DeadLetter != nil {
+				if deadLetterSanit, err = SanitiseConfig(*conf.WithDeadLetter.DeadLetter); err != nil {
+					return nil, err
+				}
+			}
+			return map[string]interface{}{
+				"output":      outputSanit,
+				"dead_letter": deadLetterSanit,
+			}, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+const withDeadLetterErrorKey = "dead_letter_error"
+
+// WithDeadLetterConfig contains configuration values for the WithDeadLetter
+// output type.
+type WithDeadLetterConfig struct {
+	Output     *Config `json:"output" yaml:"output"`
+	DeadLetter *Config `json:"dead_letter" yaml:"dead_letter"`
+}
+
+// NewWithDeadLetterConfig creates a new WithDeadLetterConfig with default
+// values.
+func NewWithDeadLetterConfig() WithDeadLetterConfig {
+	return WithDeadLetterConfig{
+		Output:     nil,
+		DeadLetter: nil,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dummyWithDeadLetterConfig struct {
+	Output     interface{} `json:"output" yaml:"output"`
+	DeadLetter interface{} `json:"dead_letter" yaml:"dead_letter"`
+}
+
+func (w WithDeadLetterConfig) dummy() dummyWithDeadLetterConfig {
+	dummy := dummyWithDeadLetterConfig{
+		Output:     w.Output,
+		DeadLetter: w.DeadLetter,
+	}
+	if w.Output == nil {
+		dummy.Output = struct{}{}
+	}
+	if w.DeadLetter == nil {
+		dummy.DeadLetter = struct{}{}
+	}
+	return dummy
+}
+
+// MarshalJSON prints empty objects instead of nil.
+func (w WithDeadLetterConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(w.dummy())
+}
+
+// MarshalYAML prints empty objects instead of nil.
+func (w WithDeadLetterConfig) MarshalYAML() (interface{}, error) {
+	return w.dummy(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// WithDeadLetter is an output type that writes messages to a primary child
+// output and routes any that fail to a dead letter child output.
+type WithDeadLetter struct {
+	running int32
+
+	primary    Type
+	deadLetter Type
+
+	stats metrics.Type
+	log   log.Modular
+
+	transactionsIn <-chan types.Transaction
+	primaryOut     chan types.Transaction
+	deadLetterOut  chan types.Transaction
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewWithDeadLetter creates a new WithDeadLetter output type.
+func NewWithDeadLetter(
+	conf Config,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	if conf.WithDeadLetter.Output == nil {
+		return nil, errors.New("cannot create with_dead_letter output without a primary child")
+	}
+	if conf.WithDeadLetter.DeadLetter == nil {
+		return nil, errors.New("cannot create with_dead_letter output without a dead_letter child")
+	}
+
+	w := &WithDeadLetter{
+		running: 1,
+
+		log:           log.NewModule(".output.with_dead_letter"),
+		stats:         stats,
+		primaryOut:    make(chan types.Transaction),
+		deadLetterOut: make(chan types.Transaction),
+
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}
+
+	var err error
+	if w.primary, err = New(*conf.WithDeadLetter.Output, mgr, log, stats); err != nil {
+		return nil, fmt.Errorf("failed to create output '%v': %v", conf.WithDeadLetter.Output.Type, err)
+	}
+	if w.deadLetter, err = New(*conf.WithDeadLetter.DeadLetter, mgr, log, stats); err != nil {
+		w.primary.CloseAsync()
+		return nil, fmt.Errorf("failed to create dead_letter output '%v': %v", conf.WithDeadLetter.DeadLetter.Type, err)
+	}
+	return w, nil
+}
+
+//------------------------------------------------------------------------------
+
+// annotate returns a copy of a message where each part is annotated with the
+// reason for its delivery failure.
+func (w *WithDeadLetter) annotate(msg types.Message, err error) types.Message {
+	newMsg := msg.Copy()
+	newMsg.Iter(func(i int, p types.Part) error {
+		p.Metadata().Set(withDeadLetterErrorKey, err.Error())
+		return nil
+	})
+	return newMsg
+}
+
+func (w *WithDeadLetter) loop() {
+	var (
+		mRunning      = w.stats.GetGauge("output.with_dead_letter.running")
+		mCount        = w.stats.GetCounter("output.with_dead_letter.count")
+		mDelivered    = w.stats.GetCounter("output.with_dead_letter.delivered")
+		mDeadLettered = w.stats.GetCounter("output.with_dead_letter.dead_lettered")
+		mLost         = w.stats.GetCounter("output.with_dead_letter.lost")
+	)
+
+	defer func() {
+		close(w.primaryOut)
+		close(w.deadLetterOut)
+		for _, o := range []Type{w.primary, w.deadLetter} {
+			o.CloseAsync()
+			err := o.WaitForClose(time.Second)
+			for ; err != nil; err = o.WaitForClose(time.Second) {
+			}
+		}
+		mRunning.Decr(1)
+		close(w.closedChan)
+	}()
+	mRunning.Incr(1)
+
+	resChan := make(chan types.Response)
+
+	send := func(tChan chan types.Transaction, msg types.Message) (types.Response, bool) {
+		select {
+		case tChan <- types.NewTransaction(msg, resChan):
+		case <-w.closeChan:
+			return nil, false
+		}
+		select {
+		case res := <-resChan:
+			return res, true
+		case <-w.closeChan:
+			return nil, false
+		}
+	}
+
+	for atomic.LoadInt32(&w.running) == 1 {
+		var ts types.Transaction
+		var open bool
+		select {
+		case ts, open = <-w.transactionsIn:
+			if !open {
+				return
+			}
+			mCount.Incr(1)
+		case <-w.closeChan:
+			return
+		}
+
+		res, ok := send(w.primaryOut, ts.Payload)
+		if !ok {
+			return
+		}
+
+		var resOut types.Response
+		if err := res.Error(); err == nil {
+			mDelivered.Incr(1)
+			resOut = res
+		} else {
+			w.log.Warnf("Routing message to dead letter queue after error: %v\n", err)
+			if res, ok = send(w.deadLetterOut, w.annotate(ts.Payload, err)); !ok {
+				return
+			}
+			if dlErr := res.Error(); dlErr == nil {
+				mDeadLettered.Incr(1)
+				resOut = response.NewAck()
+			} else {
+				w.log.Errorf("Failed to send message to dead letter queue: %v\n", dlErr)
+				mLost.Incr(1)
+				resOut = res
+			}
+		}
+
+		select {
+		case ts.ResponseChan <- resOut:
+		case <-w.closeChan:
+			return
+		}
+	}
+}
+
+// Consume assigns a messages channel for the output to read.
+func (w *WithDeadLetter) Consume(ts <-chan types.Transaction) error {
+	if w.transactionsIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	if err := w.primary.Consume(w.primaryOut); err != nil {
+		return err
+	}
+	if err := w.deadLetter.Consume(w.deadLetterOut); err != nil {
+		return err
+	}
+	w.transactionsIn = ts
+	go w.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether the primary output is
+// currently connected to its target.
+func (w *WithDeadLetter) Connected() bool {
+	return types.IsConnected(w.primary)
+}
+
+// CloseAsync shuts down the WithDeadLetter output and stops processing
+// requests.
+func (w *WithDeadLetter) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&w.running, 1, 0) {
+		close(w.closeChan)
+	}
+}
+
+// WaitForClose blocks until the WithDeadLetter output has closed down.
+func (w *WithDeadLetter) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-w.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
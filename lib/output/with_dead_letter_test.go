@@ -0,0 +1,165 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func TestWithDeadLetterConfigErrs(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeWithDeadLetter
+
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing primary output")
+	}
+
+	childConf := NewConfig()
+	conf.WithDeadLetter.Output = &childConf
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing dead letter output")
+	}
+
+	badConf := NewConfig()
+	badConf.Type = "not a type"
+	conf.WithDeadLetter.DeadLetter = &badConf
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad dead letter output")
+	}
+}
+
+func TestWithDeadLetterRouting(t *testing.T) {
+	conf := NewConfig()
+	childConf := NewConfig()
+	conf.WithDeadLetter.Output = &childConf
+	conf.WithDeadLetter.DeadLetter = &childConf
+
+	stats := metrics.NewLocal()
+	output, err := NewWithDeadLetter(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, ok := output.(*WithDeadLetter)
+	if !ok {
+		t.Fatal("Failed to cast")
+	}
+
+	mPrimary, mDeadLetter := &mockOutput{}, &mockOutput{}
+	w.primary, w.deadLetter = mPrimary, mDeadLetter
+
+	tChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	if err = w.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	testMsg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+
+	sendMsg := func() {
+		t.Helper()
+		select {
+		case tChan <- types.NewTransaction(testMsg, resChan):
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+	respond := func(out *mockOutput, res types.Response) types.Message {
+		t.Helper()
+		var tran types.Transaction
+		select {
+		case tran = <-out.ts:
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		select {
+		case tran.ResponseChan <- res:
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		return tran.Payload
+	}
+	readRes := func() error {
+		t.Helper()
+		select {
+		case res := <-resChan:
+			return res.Error()
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		return nil
+	}
+
+	// Delivered by the primary output.
+	sendMsg()
+	respond(mPrimary, response.NewAck())
+	if err = readRes(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// Routed to the dead letter queue.
+	sendMsg()
+	respond(mPrimary, response.NewError(errors.New("primary failed")))
+	dlMsg := respond(mDeadLetter, response.NewAck())
+	if err = readRes(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	for i := 0; i < dlMsg.Len(); i++ {
+		if exp, act := "primary failed", dlMsg.Get(i).Metadata().Get("dead_letter_error"); exp != act {
+			t.Errorf("Wrong error metadata for part %v: %v != %v", i, act, exp)
+		}
+	}
+	if act := testMsg.Get(0).Metadata().Get("dead_letter_error"); len(act) > 0 {
+		t.Errorf("Original message was modified: %v", act)
+	}
+
+	// Both outputs fail.
+	sendMsg()
+	respond(mPrimary, response.NewError(errors.New("primary failed")))
+	respond(mDeadLetter, response.NewError(errors.New("dead letter failed")))
+	if err = readRes(); err == nil || err.Error() != "dead letter failed" {
+		t.Errorf("Wrong error: %v", err)
+	}
+
+	w.CloseAsync()
+	if err = w.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+
+	counters := stats.GetCounters()
+	for _, k := range []string{"delivered", "dead_lettered", "lost"} {
+		if exp, act := int64(1), counters["output.with_dead_letter."+k]; exp != act {
+			t.Errorf("Wrong %v count: %v != %v", k, act, exp)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
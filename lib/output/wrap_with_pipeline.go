@@ -74,6 +74,12 @@ func (i *WithPipeline) Consume(tsChan <-chan types.Transaction) error {
 
 //------------------------------------------------------------------------------
 
+// Connected returns a boolean indicating whether the wrapped output is currently
+// connected to its target.
+func (i *WithPipeline) Connected() bool {
+	return types.IsConnected(i.out)
+}
+
 // CloseAsync triggers a closure of this object but does not block.
 func (i *WithPipeline) CloseAsync() {
 	i.pipe.CloseAsync()
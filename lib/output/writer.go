@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/output/writer"
 	"github.com/Jeffail/benthos/lib/response"
@@ -36,7 +37,8 @@ import (
 
 // Writer is an output type that writes messages to a writer.Type.
 type Writer struct {
-	running int32
+	running   int32
+	connected int32
 
 	typeStr string
 	writer  writer.Type
@@ -91,6 +93,10 @@ func (w *Writer) loop() {
 		mFailedConnF   = w.stats.GetCounter("output." + w.typeStr + ".connection.failed")
 		mLostConn      = w.stats.GetCounter("output.connection.lost")
 		mLostConnF     = w.stats.GetCounter("output." + w.typeStr + ".connection.lost")
+		mSize          = w.stats.GetTimer("output.size")
+		mSizeF         = w.stats.GetTimer("output." + w.typeStr + ".size")
+		mLatency       = w.stats.GetTimer("output.latency")
+		mLatencyF      = w.stats.GetTimer("output." + w.typeStr + ".latency")
 	)
 
 	defer func() {
@@ -99,6 +105,7 @@ func (w *Writer) loop() {
 		}
 		mRunning.Decr(1)
 		mRunningF.Decr(1)
+		atomic.StoreInt32(&w.connected, 0)
 		close(w.closedChan)
 	}()
 	mRunning.Incr(1)
@@ -124,6 +131,7 @@ func (w *Writer) loop() {
 		}
 	}
 	mConn.Incr(1)
+	atomic.StoreInt32(&w.connected, 1)
 	mConnF.Incr(1)
 
 	for atomic.LoadInt32(&w.running) == 1 {
@@ -136,44 +144,82 @@ func (w *Writer) loop() {
 			}
 			mCount.Incr(1)
 			mCountF.Incr(1)
+			size := int64(message.GetAllBytesLen(ts.Payload))
+			mSize.Timing(size)
+			mSizeF.Timing(size)
 		case <-w.closeChan:
 			return
 		}
 
-		err := w.writer.Write(ts.Payload)
+		// When a write fails for only some parts of a message we attempt only
+		// the failed parts again within this transaction, for as long as each
+		// attempt makes progress. Otherwise the error is returned and a resend
+		// of the transaction is written in full.
+		var err error
+		payload, indexes := ts.Payload, []int(nil)
+		for {
+			err = w.writer.Write(payload)
 
-		// If our writer says it is not connected.
-		if err == types.ErrNotConnected {
-			mLostConn.Incr(1)
-			mLostConnF.Incr(1)
+			// If our writer says it is not connected.
+			if err == types.ErrNotConnected {
+				mLostConn.Incr(1)
+				atomic.StoreInt32(&w.connected, 0)
+				mLostConnF.Incr(1)
 
-			// Continue to try to reconnect while still active.
-			for atomic.LoadInt32(&w.running) == 1 {
-				if err = w.writer.Connect(); err != nil {
-					// Close immediately if our writer is closed.
-					if err == types.ErrTypeClosed {
-						return
-					}
+				// Continue to try to reconnect while still active.
+				for atomic.LoadInt32(&w.running) == 1 {
+					if err = w.writer.Connect(); err != nil {
+						// Close immediately if our writer is closed.
+						if err == types.ErrTypeClosed {
+							return
+						}
 
-					w.log.Errorf("Failed to reconnect to %v: %v\n", w.typeStr, err)
-					mFailedConn.Incr(1)
-					mFailedConnF.Incr(1)
-					if !throt.Retry() {
+						w.log.Errorf("Failed to reconnect to %v: %v\n", w.typeStr, err)
+						mFailedConn.Incr(1)
+						mFailedConnF.Incr(1)
+						if !throt.Retry() {
+							return
+						}
+					} else if err = w.writer.Write(payload); err != types.ErrNotConnected {
+						mConn.Incr(1)
+						atomic.StoreInt32(&w.connected, 1)
+						mConnF.Incr(1)
+						break
+					} else if !throt.Retry() {
 						return
 					}
-				} else if err = w.writer.Write(ts.Payload); err != types.ErrNotConnected {
-					mConn.Incr(1)
-					mConnF.Incr(1)
-					break
-				} else if !throt.Retry() {
-					return
 				}
 			}
-		}
 
-		// Close immediately if our writer is closed.
-		if err == types.ErrTypeClosed {
-			return
+			// Close immediately if our writer is closed.
+			if err == types.ErrTypeClosed {
+				return
+			}
+
+			pErr, isPartial := err.(*types.ErrPartsFailed)
+			if !isPartial {
+				break
+			}
+			failed := pErr.Indexes
+			if indexes != nil {
+				failed = make([]int, len(pErr.Indexes))
+				for i, index := range pErr.Indexes {
+					failed[i] = indexes[index]
+				}
+			}
+			if len(pErr.Indexes) == 0 || len(pErr.Indexes) >= payload.Len() {
+				err = &types.ErrPartsFailed{Indexes: failed, Err: pErr.Err}
+				break
+			}
+
+			w.log.Errorf(
+				"Failed to send %v of %v message parts to %v, retrying them: %v\n",
+				len(pErr.Indexes), payload.Len(), w.typeStr, pErr.Err,
+			)
+			payload, indexes = selectParts(ts.Payload, failed), failed
+			if !throt.Retry() {
+				return
+			}
 		}
 
 		if err != nil {
@@ -188,6 +234,9 @@ func (w *Writer) loop() {
 			mSuccessF.Incr(1)
 			mPartsSuccess.Incr(int64(ts.Payload.Len()))
 			mPartsSuccessF.Incr(int64(ts.Payload.Len()))
+			tTaken := time.Since(ts.Payload.CreatedAt()).Nanoseconds()
+			mLatency.Timing(tTaken)
+			mLatencyF.Timing(tTaken)
 			throt.Reset()
 		}
 		select {
@@ -198,6 +247,16 @@ func (w *Writer) loop() {
 	}
 }
 
+// selectParts returns a message containing only the parts of msg at the
+// provided indexes.
+func selectParts(msg types.Message, indexes []int) types.Message {
+	selected := message.New(nil)
+	for _, index := range indexes {
+		selected.Append(msg.Get(index))
+	}
+	return selected
+}
+
 // Consume assigns a messages channel for the output to read.
 func (w *Writer) Consume(ts <-chan types.Transaction) error {
 	if w.transactions != nil {
@@ -208,6 +267,12 @@ func (w *Writer) Consume(ts <-chan types.Transaction) error {
 	return nil
 }
 
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (w *Writer) Connected() bool {
+	return atomic.LoadInt32(&w.connected) == 1
+}
+
 // CloseAsync shuts down the File output and stops processing messages.
 func (w *Writer) CloseAsync() {
 	if atomic.CompareAndSwapInt32(&w.running, 1, 0) {
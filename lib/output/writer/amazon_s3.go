@@ -22,6 +22,8 @@ package writer
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -29,29 +31,45 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 	sess "github.com/Jeffail/benthos/lib/util/aws/session"
+	"github.com/Jeffail/benthos/lib/util/retries"
 	"github.com/Jeffail/benthos/lib/util/text"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+	"github.com/cenkalti/backoff"
 )
 
 //------------------------------------------------------------------------------
 
 // AmazonS3Config contains configuration fields for the AmazonS3 output type.
 type AmazonS3Config struct {
-	sess.Config `json:",inline" yaml:",inline"`
-	Bucket      string `json:"bucket" yaml:"bucket"`
-	Path        string `json:"path" yaml:"path"`
-	TimeoutS    int64  `json:"timeout_s" yaml:"timeout_s"`
+	sessionConfig     `json:",inline" yaml:",inline"`
+	Bucket            string `json:"bucket" yaml:"bucket"`
+	Path              string `json:"path" yaml:"path"`
+	TimeoutS          int64  `json:"timeout_s" yaml:"timeout_s"`
+	PartSize          int64  `json:"part_size" yaml:"part_size"`
+	UploadConcurrency int    `json:"upload_concurrency" yaml:"upload_concurrency"`
+	retries.Config    `json:",inline" yaml:",inline"`
 }
 
 // NewAmazonS3Config creates a new Config with default values.
 func NewAmazonS3Config() AmazonS3Config {
+	rConf := retries.NewConfig()
+	rConf.MaxRetries = 3
+	rConf.Backoff.InitialInterval = "1s"
+	rConf.Backoff.MaxInterval = "5s"
+	rConf.Backoff.MaxElapsedTime = "30s"
 	return AmazonS3Config{
-		Config:   sess.NewConfig(),
-		Bucket:   "",
-		Path:     "${!count:files}-${!timestamp_unix_nano}.txt",
-		TimeoutS: 5,
+		sessionConfig: sessionConfig{
+			Config: sess.NewConfig(),
+		},
+		Bucket:            "",
+		Path:              "${!count:files}-${!timestamp_unix_nano}.txt",
+		TimeoutS:          5,
+		PartSize:          s3manager.DefaultUploadPartSize,
+		UploadConcurrency: s3manager.DefaultUploadConcurrency,
+		Config:            rConf,
 	}
 }
 
@@ -66,10 +84,14 @@ type AmazonS3 struct {
 	interpolatePath bool
 
 	session  *session.Session
-	uploader *s3manager.Uploader
+	uploader s3manageriface.UploaderAPI
+
+	backoff backoff.BackOff
 
 	log   log.Modular
 	stats metrics.Type
+
+	mRetried metrics.StatCounter
 }
 
 // NewAmazonS3 creates a new Amazon S3 bucket writer.Type.
@@ -77,16 +99,30 @@ func NewAmazonS3(
 	conf AmazonS3Config,
 	log log.Modular,
 	stats metrics.Type,
-) *AmazonS3 {
+) (*AmazonS3, error) {
+	if conf.PartSize < s3manager.MinUploadPartSize {
+		return nil, fmt.Errorf("part_size must be at least %d bytes", s3manager.MinUploadPartSize)
+	}
+	if conf.UploadConcurrency < 1 {
+		return nil, errors.New("upload_concurrency must be at least 1")
+	}
+
 	pathBytes := []byte(conf.Path)
 	interpolatePath := text.ContainsFunctionVariables(pathBytes)
-	return &AmazonS3{
+	a := &AmazonS3{
 		conf:            conf,
 		pathBytes:       pathBytes,
 		interpolatePath: interpolatePath,
 		log:             log.NewModule(".output.amazon_s3"),
 		stats:           stats,
+		mRetried:        stats.GetCounter("output.s3.send.retried"),
+	}
+
+	var err error
+	if a.backoff, err = conf.Config.Get(); err != nil {
+		return nil, fmt.Errorf("failed to parse retry fields: %v", err)
 	}
+	return a, nil
 }
 
 // Connect attempts to establish a connection to the target S3 bucket.
@@ -101,7 +137,11 @@ func (a *AmazonS3) Connect() error {
 	}
 
 	a.session = sess
-	a.uploader = s3manager.NewUploader(sess)
+	a.uploader = s3manager.NewUploader(sess, func(u *s3manager.Uploader) {
+		u.PartSize = a.conf.PartSize
+		u.Concurrency = a.conf.UploadConcurrency
+		u.LeavePartsOnError = false
+	})
 
 	a.log.Infof("Uploading message parts as objects to Amazon S3 bucket: %v\n", a.conf.Bucket)
 	return nil
@@ -119,15 +159,34 @@ func (a *AmazonS3) Write(msg types.Message) error {
 			path = string(text.ReplaceFunctionVariables(message.Lock(msg, i), a.pathBytes))
 		}
 
-		if _, err := a.uploader.Upload(&s3manager.UploadInput{
-			Body:   bytes.NewReader(p.Get()),
+		return a.upload(path, p.Get())
+	})
+}
+
+// upload writes an object to the target bucket, retrying according to the
+// configurable backoff settings. Objects larger than the part size are
+// uploaded in parts concurrently, and a failed multipart upload is aborted
+// before it is retried so that no orphaned parts are left in the bucket.
+func (a *AmazonS3) upload(path string, data []byte) error {
+	a.backoff.Reset()
+	for {
+		_, err := a.uploader.Upload(&s3manager.UploadInput{
+			Body:   bytes.NewReader(data),
 			Bucket: aws.String(a.conf.Bucket),
 			Key:    aws.String(path),
-		}); err != nil {
+		})
+		if err == nil {
+			return nil
+		}
+		a.log.Warnf("Failed to upload object '%v': %v\n", path, err)
+
+		wait := a.backoff.NextBackOff()
+		if wait == backoff.Stop {
 			return err
 		}
-		return nil
-	})
+		a.mRetried.Incr(1)
+		time.Sleep(wait)
+	}
 }
 
 // CloseAsync begins cleaning up resources used by this reader asynchronously.
@@ -0,0 +1,165 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+	"github.com/cenkalti/backoff"
+)
+
+//------------------------------------------------------------------------------
+
+type mockUploader struct {
+	s3manageriface.UploaderAPI
+	fn func(input *s3manager.UploadInput) error
+}
+
+func (m *mockUploader) Upload(input *s3manager.UploadInput, opts ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error) {
+	if err := m.fn(input); err != nil {
+		return nil, err
+	}
+	return &s3manager.UploadOutput{}, nil
+}
+
+func newMockAmazonS3(
+	t *testing.T,
+	path string,
+	boff backoff.BackOff,
+	fn func(input *s3manager.UploadInput) error,
+) *AmazonS3 {
+	t.Helper()
+
+	conf := NewAmazonS3Config()
+	conf.Bucket = "foo"
+	conf.Path = path
+
+	a, err := NewAmazonS3(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.backoff = boff
+	a.session = session.Must(session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials("xxxxx", "xxxxx", "xxxxx"),
+	}))
+	a.uploader = &mockUploader{fn: fn}
+	return a
+}
+
+func TestAmazonS3BadConfig(t *testing.T) {
+	conf := NewAmazonS3Config()
+	conf.PartSize = 1024
+	if _, err := NewAmazonS3(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from small part size")
+	}
+
+	conf = NewAmazonS3Config()
+	conf.UploadConcurrency = 0
+	if _, err := NewAmazonS3(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from zero upload concurrency")
+	}
+
+	conf = NewAmazonS3Config()
+	conf.Backoff.InitialInterval = "not a duration"
+	if _, err := NewAmazonS3(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad backoff")
+	}
+}
+
+func TestAmazonS3Write(t *testing.T) {
+	uploaded := map[string]string{}
+	a := newMockAmazonS3(t, "${!metadata:key}.txt", backoff.NewExponentialBackOff(),
+		func(input *s3manager.UploadInput) error {
+			if exp, act := "foo", *input.Bucket; exp != act {
+				t.Errorf("Wrong bucket: %v != %v", act, exp)
+			}
+			b, err := ioutil.ReadAll(input.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			uploaded[*input.Key] = string(b)
+			return nil
+		},
+	)
+
+	msg := message.New([][]byte{[]byte("hello"), []byte("world")})
+	msg.Get(0).Metadata().Set("key", "first")
+	msg.Get(1).Metadata().Set("key", "second")
+
+	if err := a.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := "hello", uploaded["first.txt"]; exp != act {
+		t.Errorf("Wrong object contents: %v != %v", act, exp)
+	}
+	if exp, act := "world", uploaded["second.txt"]; exp != act {
+		t.Errorf("Wrong object contents: %v != %v", act, exp)
+	}
+}
+
+func TestAmazonS3WriteRetry(t *testing.T) {
+	calls := 0
+	a := newMockAmazonS3(t, "foo.txt", backoff.WithMaxRetries(&backoff.ZeroBackOff{}, 3),
+		func(input *s3manager.UploadInput) error {
+			if calls++; calls < 3 {
+				return errors.New("upload aborted")
+			}
+			return nil
+		},
+	)
+
+	if err := a.Write(message.New([][]byte{[]byte("hello")})); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 3, calls; exp != act {
+		t.Errorf("Wrong count of upload attempts: %v != %v", act, exp)
+	}
+}
+
+func TestAmazonS3WriteRetriesExhausted(t *testing.T) {
+	calls := 0
+	a := newMockAmazonS3(t, "foo.txt", backoff.WithMaxRetries(&backoff.ZeroBackOff{}, 2),
+		func(input *s3manager.UploadInput) error {
+			calls++
+			return errors.New("upload aborted")
+		},
+	)
+
+	if err := a.Write(message.New([][]byte{[]byte("hello")})); err == nil {
+		t.Error("Expected error from exhausted retries")
+	}
+	if exp, act := 3, calls; exp != act {
+		t.Errorf("Wrong count of upload attempts: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
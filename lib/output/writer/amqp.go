@@ -30,6 +30,7 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	bmeta "github.com/Jeffail/benthos/lib/util/metadata"
 	"github.com/Jeffail/benthos/lib/util/text"
 	btls "github.com/Jeffail/benthos/lib/util/tls"
 	"github.com/streadway/amqp"
@@ -56,6 +57,7 @@ type AMQPConfig struct {
 	Mandatory       bool                      `json:"mandatory" yaml:"mandatory"`
 	Immediate       bool                      `json:"immediate" yaml:"immediate"`
 	TLS             btls.Config               `json:"tls" yaml:"tls"`
+	Metadata        bmeta.Config              `json:"metadata" yaml:"metadata"`
 }
 
 // NewAMQPConfig creates a new AMQPConfig with default values.
@@ -73,6 +75,7 @@ func NewAMQPConfig() AMQPConfig {
 		Mandatory:  false,
 		Immediate:  false,
 		TLS:        btls.NewConfig(),
+		Metadata:   bmeta.NewConfig(),
 	}
 }
 
@@ -87,6 +90,7 @@ type AMQP struct {
 
 	conf    AMQPConfig
 	tlsConf *tls.Config
+	meta    *bmeta.Filter
 
 	conn        *amqp.Connection
 	amqpChan    *amqp.Channel
@@ -110,8 +114,11 @@ func NewAMQP(conf AMQPConfig, log log.Modular, stats metrics.Type) (*AMQP, error
 	if conf.Persistent {
 		a.deliveryMode = amqp.Persistent
 	}
+	var err error
+	if a.meta, err = conf.Metadata.Get(); err != nil {
+		return nil, err
+	}
 	if conf.TLS.Enabled {
-		var err error
 		if a.tlsConf, err = conf.TLS.Get(); err != nil {
 			return nil, err
 		}
@@ -215,7 +222,7 @@ func (a *AMQP) Write(msg types.Message) error {
 
 	return msg.Iter(func(i int, p types.Part) error {
 		headers := amqp.Table{}
-		p.Metadata().Iter(func(k, v string) error {
+		a.meta.Iter(p.Metadata(), func(k, v string) error {
 			headers[strings.Replace(k, "_", "-", -1)] = v
 			return nil
 		})
@@ -173,11 +173,9 @@ func (d *DynamoDB) Write(msg types.Message) error {
 		return nil
 	})
 
-	var err error
+	d.backoff.Reset()
 	for len(writeReqs) > 0 {
-		wait := d.backoff.NextBackOff()
-		var batchResult *dynamodb.BatchWriteItemOutput
-		batchResult, err = d.client.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+		batchResult, err := d.client.BatchWriteItem(&dynamodb.BatchWriteItemInput{
 			RequestItems: map[string][]*dynamodb.WriteRequest{
 				*d.table: writeReqs,
 			},
@@ -192,16 +190,13 @@ func (d *DynamoDB) Write(msg types.Message) error {
 		}
 
 		if err != nil {
+			wait := d.backoff.NextBackOff()
 			if wait == backoff.Stop {
-				break
+				return err
 			}
-			time.After(wait)
+			time.Sleep(wait)
 		}
 	}
-
-	if err == nil {
-		d.backoff.Reset()
-	}
 	return nil
 }
 
@@ -0,0 +1,132 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/cenkalti/backoff"
+)
+
+//------------------------------------------------------------------------------
+
+type mockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	fn func(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error)
+}
+
+func (m *mockDynamoDB) BatchWriteItem(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+	return m.fn(input)
+}
+
+func newMockDynamoDB(
+	t *testing.T,
+	fn func(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error),
+) *DynamoDB {
+	t.Helper()
+
+	conf := NewDynamoDBConfig()
+	conf.Table = "foo"
+	conf.StringColumns = map[string]string{
+		"content": "${!content}",
+	}
+
+	db, err := NewDynamoDB(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.backoff = backoff.WithMaxRetries(backoff.NewConstantBackOff(0), 2)
+	db.client = &mockDynamoDB{fn: fn}
+	return db
+}
+
+func TestDynamoDBWriteUnprocessed(t *testing.T) {
+	var calls []int
+	db := newMockDynamoDB(t, func(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+		reqs := input.RequestItems["foo"]
+		calls = append(calls, len(reqs))
+		if len(calls) == 1 {
+			return &dynamodb.BatchWriteItemOutput{
+				UnprocessedItems: map[string][]*dynamodb.WriteRequest{
+					"foo": reqs[1:],
+				},
+			}, nil
+		}
+		return &dynamodb.BatchWriteItemOutput{}, nil
+	})
+
+	if err := db.Write(message.New([][]byte{[]byte("foo"), []byte("bar"), []byte("baz")})); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := []int{3, 2}, calls; len(act) != len(exp) || act[0] != exp[0] || act[1] != exp[1] {
+		t.Errorf("Wrong calls: %v != %v", act, exp)
+	}
+}
+
+func TestDynamoDBWritePersistentThrottling(t *testing.T) {
+	calls := 0
+	db := newMockDynamoDB(t, func(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+		calls++
+		return &dynamodb.BatchWriteItemOutput{
+			UnprocessedItems: input.RequestItems,
+		}, nil
+	})
+
+	msg := message.New([][]byte{[]byte("foo")})
+	if err := db.Write(msg); err == nil {
+		t.Error("Expected error from unprocessed items")
+	}
+	if exp, act := 3, calls; exp != act {
+		t.Errorf("Wrong count of calls: %v != %v", act, exp)
+	}
+
+	// The backoff is reset between writes.
+	calls = 0
+	if err := db.Write(msg); err == nil {
+		t.Error("Expected error from unprocessed items")
+	}
+	if exp, act := 3, calls; exp != act {
+		t.Errorf("Wrong count of calls: %v != %v", act, exp)
+	}
+}
+
+func TestDynamoDBWritePersistentError(t *testing.T) {
+	calls := 0
+	db := newMockDynamoDB(t, func(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+		calls++
+		return nil, errors.New("throttled")
+	})
+
+	if err := db.Write(message.New([][]byte{[]byte("foo")})); err == nil || err.Error() != "throttled" {
+		t.Errorf("Wrong error: %v", err)
+	}
+	if exp, act := 3, calls; exp != act {
+		t.Errorf("Wrong count of calls: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
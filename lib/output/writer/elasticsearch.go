@@ -22,8 +22,11 @@ package writer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -57,8 +60,11 @@ type ElasticsearchConfig struct {
 	URLs           []string             `json:"urls" yaml:"urls"`
 	Sniff          bool                 `json:"sniff" yaml:"sniff"`
 	ID             string               `json:"id" yaml:"id"`
+	Action         string               `json:"action" yaml:"action"`
 	Index          string               `json:"index" yaml:"index"`
 	Pipeline       string               `json:"pipeline" yaml:"pipeline"`
+	Routing        string               `json:"routing" yaml:"routing"`
+	DataStream     bool                 `json:"data_stream" yaml:"data_stream"`
 	Type           string               `json:"type" yaml:"type"`
 	TimeoutMS      int                  `json:"timeout_ms" yaml:"timeout_ms"`
 	Auth           auth.BasicAuthConfig `json:"basic_auth" yaml:"basic_auth"`
@@ -74,14 +80,17 @@ func NewElasticsearchConfig() ElasticsearchConfig {
 	rConf.Backoff.MaxElapsedTime = "30s"
 
 	return ElasticsearchConfig{
-		URLs:      []string{"http://localhost:9200"},
-		Sniff:     true,
-		ID:        "${!count:elastic_ids}-${!timestamp_unix}",
-		Index:     "benthos_index",
-		Pipeline:  "",
-		Type:      "doc",
-		TimeoutMS: 5000,
-		Auth:      auth.NewBasicAuthConfig(),
+		URLs:       []string{"http://localhost:9200"},
+		Sniff:      true,
+		ID:         "${!count:elastic_ids}-${!timestamp_unix}",
+		Action:     "index",
+		Index:      "benthos_index",
+		Pipeline:   "",
+		Routing:    "",
+		DataStream: false,
+		Type:       "doc",
+		TimeoutMS:  5000,
+		Auth:       auth.NewBasicAuthConfig(),
 		AWS: OptionalAWSConfig{
 			Enabled: false,
 			Config:  sess.NewConfig(),
@@ -104,11 +113,18 @@ type Elasticsearch struct {
 	backoff backoff.BackOff
 
 	idStr             *text.InterpolatedString
+	actionStr         *text.InterpolatedString
 	indexStr          *text.InterpolatedString
 	pipelineStr       *text.InterpolatedString
+	routingStr        *text.InterpolatedString
 	interpolatedIndex bool
+	typeless          bool
 
-	eJSONErr metrics.StatCounter
+	eJSONErr   metrics.StatCounter
+	eActionErr metrics.StatCounter
+	eTSErr     metrics.StatCounter
+	eRejected  metrics.StatCounter
+	eRetried   metrics.StatCounter
 
 	client *elastic.Client
 }
@@ -121,10 +137,25 @@ func NewElasticsearch(conf ElasticsearchConfig, log log.Modular, stats metrics.T
 		conf:              conf,
 		sniff:             conf.Sniff,
 		idStr:             text.NewInterpolatedString(conf.ID),
+		actionStr:         text.NewInterpolatedString(conf.Action),
 		indexStr:          text.NewInterpolatedString(conf.Index),
 		pipelineStr:       text.NewInterpolatedString(conf.Pipeline),
+		routingStr:        text.NewInterpolatedString(conf.Routing),
 		interpolatedIndex: text.ContainsFunctionVariables([]byte(conf.Index)),
 		eJSONErr:          stats.GetCounter("output.elasticsearch.error.json"),
+		eActionErr:        stats.GetCounter("output.elasticsearch.error.action"),
+		eTSErr:            stats.GetCounter("output.elasticsearch.error.timestamp"),
+		eRejected:         stats.GetCounter("output.elasticsearch.error.rejected"),
+		eRetried:          stats.GetCounter("output.elasticsearch.retry"),
+	}
+
+	if conf.DataStream {
+		e.actionStr = text.NewInterpolatedString("create")
+		e.typeless = true
+	} else if !text.ContainsFunctionVariables([]byte(conf.Action)) {
+		if _, err := strToBulkAction(conf.Action); err != nil {
+			return nil, err
+		}
 	}
 
 	for _, u := range conf.URLs {
@@ -178,6 +209,16 @@ func (e *Elasticsearch) Connect() error {
 		return err
 	}
 
+	if !e.conf.DataStream {
+		e.typeless = len(e.conf.Type) == 0
+		if version, verr := e.client.ElasticsearchVersion(e.urls[0]); verr != nil {
+			e.log.Warnf("Failed to detect Elasticsearch version: %v\n", verr)
+		} else if major := esMajorVersion(version); major >= 8 {
+			e.log.Infof("Detected Elasticsearch version %v, omitting document types\n", version)
+			e.typeless = true
+		}
+	}
+
 	if err == nil && !e.interpolatedIndex {
 		var indexExists bool
 		indexExists, err = e.client.IndexExists(e.conf.Index).Do(context.Background())
@@ -193,84 +234,232 @@ func (e *Elasticsearch) Connect() error {
 }
 
 func shouldRetry(s int) bool {
+	if s == http.StatusTooManyRequests {
+		return true
+	}
 	if s >= 500 && s <= 599 {
 		return true
 	}
 	return false
 }
 
+// esMajorVersion parses the major version from an Elasticsearch version string,
+// returns zero if the version cannot be parsed.
+func esMajorVersion(version string) int {
+	major, err := strconv.Atoi(strings.SplitN(version, ".", 2)[0])
+	if err != nil {
+		return 0
+	}
+	return major
+}
+
+//------------------------------------------------------------------------------
+
+// dataStreamTimestampField is the field that documents written to a data stream
+// must contain.
+const dataStreamTimestampField = "@timestamp"
+
+type bulkAction int
+
+const (
+	bulkActionIndex bulkAction = iota
+	bulkActionCreate
+	bulkActionUpdate
+	bulkActionUpsert
+	bulkActionDelete
+)
+
+func strToBulkAction(str string) (bulkAction, error) {
+	switch str {
+	case "index":
+		return bulkActionIndex, nil
+	case "create":
+		return bulkActionCreate, nil
+	case "update":
+		return bulkActionUpdate, nil
+	case "upsert":
+		return bulkActionUpsert, nil
+	case "delete":
+		return bulkActionDelete, nil
+	}
+	return bulkActionIndex, fmt.Errorf("bulk action not recognised: %v", str)
+}
+
+// buildRequest creates a bulk request for a message part, the provided message
+// should be locked to the part.
+func (e *Elasticsearch) buildRequest(msg types.Message) (elastic.BulkableRequest, error) {
+	action, err := strToBulkAction(e.actionStr.Get(msg))
+	if err != nil {
+		e.eActionErr.Incr(1)
+		return nil, err
+	}
+
+	index := e.indexStr.Get(msg)
+	id := e.idStr.Get(msg)
+	routing := e.routingStr.Get(msg)
+	docType := e.conf.Type
+	if e.typeless {
+		docType = ""
+	}
+
+	if action == bulkActionDelete {
+		req := elastic.NewBulkDeleteRequest().
+			Index(index).
+			Type(docType).
+			Id(id)
+		if len(routing) > 0 {
+			req.Routing(routing)
+		}
+		return req, nil
+	}
+
+	doc, err := msg.Get(0).JSON()
+	if err != nil {
+		e.eJSONErr.Incr(1)
+		return nil, fmt.Errorf("failed to parse message as JSON: %v", err)
+	}
+	if e.conf.DataStream {
+		if obj, ok := doc.(map[string]interface{}); !ok || obj[dataStreamTimestampField] == nil {
+			e.eTSErr.Incr(1)
+			return nil, fmt.Errorf("document is missing the %v field required by data streams", dataStreamTimestampField)
+		}
+	}
+
+	switch action {
+	case bulkActionUpdate, bulkActionUpsert:
+		req := elastic.NewBulkUpdateRequest().
+			Index(index).
+			Type(docType).
+			Id(id).
+			Doc(doc).
+			DocAsUpsert(action == bulkActionUpsert)
+		if len(routing) > 0 {
+			req.Routing(routing)
+		}
+		return req, nil
+	}
+
+	req := elastic.NewBulkIndexRequest().
+		Index(index).
+		Pipeline(e.pipelineStr.Get(msg)).
+		Type(docType).
+		Id(id).
+		Doc(doc)
+	if action == bulkActionCreate {
+		req.OpType("create")
+	}
+	if len(routing) > 0 {
+		req.Routing(routing)
+	}
+	return req, nil
+}
+
 // Write will attempt to write a message to Elasticsearch, wait for
 // acknowledgement, and returns an error if applicable.
+//
+// All messages are written using the bulk API. Items that fail with a retryable
+// status, or that were part of a bulk request that failed outright, are resent
+// according to the backoff policy without resending items that succeeded. A
+// create action that fails because the document already exists is treated as a
+// success, since the document is most likely from a previous attempt to send
+// the same message. If any items are rejected, fail to build, or exhaust their
+// retries then a types.ErrPartsFailed error is returned detailing the indexes
+// of the failed parts.
 func (e *Elasticsearch) Write(msg types.Message) error {
 	if e.client == nil {
 		return types.ErrNotConnected
 	}
 
-	if msg.Len() == 1 {
-		index := e.indexStr.Get(msg)
-		_, err := e.client.Index().
-			Index(index).
-			Pipeline(e.pipelineStr.Get(msg)).
-			Type(e.conf.Type).
-			Id(e.idStr.Get(msg)).
-			BodyString(string(msg.Get(0).Get())).
-			Do(context.Background())
-		if err == nil {
-			// Flush to make sure the document got written.
-			_, err = e.client.Flush().Index(index).Do(context.Background())
-		}
-		return err
-	}
-
 	e.backoff.Reset()
 
-	b := e.client.Bulk()
-	docs := []interface{}{}
+	failedParts := map[int]error{}
+
+	var reqIndexes []int
+	var reqs []elastic.BulkableRequest
 	msg.Iter(func(i int, part types.Part) error {
-		jObj, ierr := part.JSON()
-		if ierr != nil {
-			e.eJSONErr.Incr(1)
-			e.log.Errorf("Failed to marshal message into JSON document: %v\n", ierr)
+		req, err := e.buildRequest(message.Lock(msg, i))
+		if err != nil {
+			e.log.Errorf("Failed to build request for message part %v: %v\n", i, err)
+			failedParts[i] = err
 			return nil
 		}
-		docs = append(docs, jObj)
-		b.Add(
-			elastic.NewBulkIndexRequest().
-				Index(e.indexStr.Get(message.Lock(msg, i))).
-				Pipeline(e.pipelineStr.Get(message.Lock(msg, i))).
-				Type(e.conf.Type).
-				Id(e.idStr.Get(message.Lock(msg, i))).
-				Doc(jObj),
-		)
+		reqIndexes = append(reqIndexes, i)
+		reqs = append(reqs, req)
 		return nil
 	})
 
-	for b.NumberOfActions() != 0 {
+	for len(reqs) > 0 {
 		wait := e.backoff.NextBackOff()
 
-		result, err := b.Do(context.Background())
-		if err != nil {
-			return err
+		b := e.client.Bulk()
+		for _, req := range reqs {
+			b.Add(req)
 		}
 
-		failed := result.Failed()
-		if len(failed) == 0 {
-			return nil
+		result, err := b.Do(context.Background())
+		if err == nil && len(result.Items) != len(reqs) {
+			err = fmt.Errorf("bulk response item count does not match request: %v != %v", len(result.Items), len(reqs))
+		}
+		if err != nil {
+			// The outcome of each item is unknown, so all of them are retried.
+			if wait == backoff.Stop {
+				e.log.Errorf("Bulk request failed: %v\n", err)
+				for _, index := range reqIndexes {
+					failedParts[index] = err
+				}
+				break
+			}
+			e.eRetried.Incr(1)
+			e.log.Warnf("Bulk request failed: %v\n", err)
+			time.Sleep(wait)
+			continue
 		}
 
-		for i := 0; i < len(failed); i++ {
-			if !shouldRetry(failed[i].Status) {
-				e.log.Errorf("elasticsearch message rejected with code [%s]: %v\n", failed[i].Status, failed[i].Error.Reason)
-				return fmt.Errorf("failed to send %v parts from message: %v", len(failed), failed[0].Error.Reason)
+		var retryIndexes []int
+		var retryReqs []elastic.BulkableRequest
+		for i, item := range result.Items {
+			for op, resp := range item {
+				if resp.Status >= 200 && resp.Status <= 299 {
+					continue
+				}
+				if op == "create" && resp.Status == http.StatusConflict {
+					e.log.Debugf("Elasticsearch message part %v already exists\n", reqIndexes[i])
+					continue
+				}
+				reason := fmt.Sprintf("status %v", resp.Status)
+				if resp.Error != nil {
+					reason = resp.Error.Reason
+				}
+				if shouldRetry(resp.Status) && wait != backoff.Stop {
+					e.eRetried.Incr(1)
+					e.log.Warnf("Elasticsearch message part %v failed with code [%v]: %v\n", reqIndexes[i], resp.Status, reason)
+					retryIndexes = append(retryIndexes, reqIndexes[i])
+					retryReqs = append(retryReqs, reqs[i])
+				} else {
+					e.eRejected.Incr(1)
+					e.log.Errorf("Elasticsearch message part %v rejected with code [%v]: %v\n", reqIndexes[i], resp.Status, reason)
+					failedParts[reqIndexes[i]] = errors.New(reason)
+				}
 			}
-			e.log.Errorf("elasticsearch message failed with code [%s]: %v\n", failed[i].Status, failed[i].Error.Reason)
 		}
-		if wait == backoff.Stop {
-			return fmt.Errorf("failed to send %v parts from message: %v", len(failed), failed[0].Error.Reason)
+
+		reqIndexes, reqs = retryIndexes, retryReqs
+		if len(reqs) > 0 {
+			time.Sleep(wait)
 		}
-		time.Sleep(wait)
 	}
 
+	if len(failedParts) > 0 {
+		indexes := make([]int, 0, len(failedParts))
+		for i := range failedParts {
+			indexes = append(indexes, i)
+		}
+		sort.Ints(indexes)
+		return &types.ErrPartsFailed{
+			Indexes: indexes,
+			Err:     failedParts[indexes[0]],
+		}
+	}
 	return nil
 }
 
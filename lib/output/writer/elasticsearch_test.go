@@ -21,20 +21,421 @@
 package writer
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
 	"github.com/olivere/elastic"
 	"github.com/ory/dockertest"
 )
 
+type fakeBulkItem struct {
+	Action string
+	Meta   map[string]interface{}
+	Doc    map[string]interface{}
+}
+
+func fakeElasticServer(t *testing.T, version string, status func(attempt int, item fakeBulkItem) int) (*httptest.Server, func() [][]fakeBulkItem) {
+	var mut sync.Mutex
+	var requests [][]fakeBulkItem
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/_bulk") {
+			w.Header().Set("Content-Type", "application/json")
+			if r.URL.Path == "/" {
+				fmt.Fprintf(w, `{"version":{"number":"%v"}}`, version)
+				return
+			}
+			w.Write([]byte(`{}`))
+			return
+		}
+
+		var items []fakeBulkItem
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var action map[string]map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+				t.Errorf("Failed to parse bulk action: %v", err)
+				return
+			}
+			for k, v := range action {
+				item := fakeBulkItem{Action: k, Meta: v}
+				if k != "delete" && scanner.Scan() {
+					if err := json.Unmarshal(scanner.Bytes(), &item.Doc); err != nil {
+						t.Errorf("Failed to parse bulk doc: %v", err)
+						return
+					}
+				}
+				items = append(items, item)
+			}
+		}
+
+		mut.Lock()
+		requests = append(requests, items)
+		attempt := len(requests)
+		mut.Unlock()
+
+		resItems := []map[string]interface{}{}
+		for _, item := range items {
+			code := status(attempt, item)
+			resItem := map[string]interface{}{
+				"_id":    item.Meta["_id"],
+				"status": code,
+			}
+			if code >= 300 {
+				resItem["error"] = map[string]interface{}{
+					"type":   "test_error",
+					"reason": fmt.Sprintf("test failure %v", item.Meta["_id"]),
+				}
+			}
+			resItems = append(resItems, map[string]interface{}{
+				item.Action: resItem,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"took":   1,
+			"errors": false,
+			"items":  resItems,
+		})
+	}))
+
+	return ts, func() [][]fakeBulkItem {
+		mut.Lock()
+		defer mut.Unlock()
+		return requests
+	}
+}
+
+func TestElasticBulkActions(t *testing.T) {
+	ts, getRequests := fakeElasticServer(t, "6.4.0", func(int, fakeBulkItem) int {
+		return 200
+	})
+	defer ts.Close()
+
+	conf := NewElasticsearchConfig()
+	conf.URLs = []string{ts.URL}
+	conf.Sniff = false
+	conf.ID = "${!json_field:id}"
+	conf.Action = "${!metadata:action}"
+	conf.Routing = "${!json_field:user}"
+
+	m, err := NewElasticsearch(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{
+		[]byte(`{"id":"foo1","user":"1"}`),
+		[]byte(`{"id":"foo2","user":"2"}`),
+		[]byte(`{"id":"foo3","user":"3"}`),
+		[]byte(`{"id":"foo4","user":"4"}`),
+		[]byte(`{"id":"foo5","user":"5"}`),
+	})
+	for i, action := range []string{"index", "create", "update", "upsert", "delete"} {
+		msg.Get(i).Metadata().Set("action", action)
+	}
+
+	if err = m.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := getRequests()
+	if len(requests) != 1 {
+		t.Fatalf("Wrong count of bulk requests: %v != 1", len(requests))
+	}
+
+	type summary struct {
+		Action  string
+		ID      interface{}
+		Routing interface{}
+		Doc     map[string]interface{}
+	}
+	exp := []summary{
+		{Action: "index", ID: "foo1", Routing: "1", Doc: map[string]interface{}{"id": "foo1", "user": "1"}},
+		{Action: "create", ID: "foo2", Routing: "2", Doc: map[string]interface{}{"id": "foo2", "user": "2"}},
+		{Action: "update", ID: "foo3", Routing: "3", Doc: map[string]interface{}{
+			"doc":           map[string]interface{}{"id": "foo3", "user": "3"},
+			"doc_as_upsert": false,
+		}},
+		{Action: "update", ID: "foo4", Routing: "4", Doc: map[string]interface{}{
+			"doc":           map[string]interface{}{"id": "foo4", "user": "4"},
+			"doc_as_upsert": true,
+		}},
+		{Action: "delete", ID: "foo5", Routing: "5"},
+	}
+	act := []summary{}
+	for _, item := range requests[0] {
+		act = append(act, summary{
+			Action:  item.Action,
+			ID:      item.Meta["_id"],
+			Routing: item.Meta["routing"],
+			Doc:     item.Doc,
+		})
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong bulk items: %v != %v", act, exp)
+	}
+}
+
+func TestElasticBulkBadAction(t *testing.T) {
+	conf := NewElasticsearchConfig()
+	conf.Action = "nope"
+	if _, err := NewElasticsearch(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad action")
+	}
+}
+
+func TestElasticBulkPartialFailure(t *testing.T) {
+	ts, getRequests := fakeElasticServer(t, "6.4.0", func(attempt int, item fakeBulkItem) int {
+		switch item.Meta["_id"] {
+		case "retry":
+			if attempt == 1 {
+				return 503
+			}
+		case "reject":
+			return 400
+		}
+		return 201
+	})
+	defer ts.Close()
+
+	conf := NewElasticsearchConfig()
+	conf.URLs = []string{ts.URL}
+	conf.Sniff = false
+	conf.ID = "${!json_field:id}"
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+
+	m, err := NewElasticsearch(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Write(message.New([][]byte{
+		[]byte(`{"id":"ok"}`),
+		[]byte(`{"id":"retry"}`),
+		[]byte(`{"id":"reject"}`),
+		[]byte(`not json`),
+	}))
+	if err == nil {
+		t.Fatal("Expected error from rejected parts")
+	}
+	if exp, act := "failed to send 2 parts [2 3]", err.Error(); !strings.HasPrefix(act, exp) {
+		t.Errorf("Wrong error: %v != %v", act, exp)
+	}
+
+	requests := getRequests()
+	if len(requests) != 2 {
+		t.Fatalf("Wrong count of bulk requests: %v != 2", len(requests))
+	}
+	if exp, act := 3, len(requests[0]); exp != act {
+		t.Errorf("Wrong count of items in first request: %v != %v", act, exp)
+	}
+	if exp, act := 1, len(requests[1]); exp != act {
+		t.Fatalf("Wrong count of items in retry request: %v != %v", act, exp)
+	}
+	if exp, act := "retry", requests[1][0].Meta["_id"]; exp != act {
+		t.Errorf("Wrong item retried: %v != %v", act, exp)
+	}
+}
+
+func TestElasticBulkCreateConflict(t *testing.T) {
+	ts, getRequests := fakeElasticServer(t, "6.4.0", func(attempt int, item fakeBulkItem) int {
+		if item.Meta["_id"] == "exists" {
+			return 409
+		}
+		return 201
+	})
+	defer ts.Close()
+
+	conf := NewElasticsearchConfig()
+	conf.URLs = []string{ts.URL}
+	conf.Sniff = false
+	conf.ID = "${!json_field:id}"
+	conf.Action = "create"
+
+	m, err := NewElasticsearch(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.Write(message.New([][]byte{
+		[]byte(`{"id":"new"}`),
+		[]byte(`{"id":"exists"}`),
+	})); err != nil {
+		t.Error(err)
+	}
+	if exp, act := 1, len(getRequests()); exp != act {
+		t.Errorf("Wrong count of bulk requests: %v != %v", act, exp)
+	}
+}
+
+func TestElasticBulkRequestFailure(t *testing.T) {
+	var calls int
+	var mut sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if !strings.HasSuffix(r.URL.Path, "/_bulk") {
+			w.Write([]byte(`{"version":{"number":"6.4.0"}}`))
+			return
+		}
+		mut.Lock()
+		calls++
+		mut.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	conf := NewElasticsearchConfig()
+	conf.URLs = []string{ts.URL}
+	conf.Sniff = false
+	conf.MaxRetries = 2
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+
+	m, err := NewElasticsearch(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Write(message.New([][]byte{
+		[]byte(`{"id":"foo"}`),
+		[]byte(`{"id":"bar"}`),
+	}))
+	pErr, ok := err.(*types.ErrPartsFailed)
+	if !ok {
+		t.Fatalf("Wrong error type: %T: %v", err, err)
+	}
+	if exp, act := []int{0, 1}, pErr.Indexes; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong failed indexes: %v != %v", act, exp)
+	}
+
+	mut.Lock()
+	if exp, act := 3, calls; exp != act {
+		t.Errorf("Wrong count of bulk requests: %v != %v", act, exp)
+	}
+	mut.Unlock()
+}
+
+func TestElasticVersionTypes(t *testing.T) {
+	tests := []struct {
+		version string
+		docType string
+		expType interface{}
+	}{
+		{version: "6.4.0", docType: "doc", expType: "doc"},
+		{version: "7.10.2", docType: "_doc", expType: "_doc"},
+		{version: "7.10.2", docType: "", expType: nil},
+		{version: "8.1.0", docType: "doc", expType: nil},
+	}
+
+	for _, test := range tests {
+		ts, getRequests := fakeElasticServer(t, test.version, func(int, fakeBulkItem) int {
+			return 200
+		})
+
+		conf := NewElasticsearchConfig()
+		conf.URLs = []string{ts.URL}
+		conf.Sniff = false
+		conf.Type = test.docType
+
+		m, err := NewElasticsearch(conf, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = m.Connect(); err != nil {
+			t.Fatal(err)
+		}
+		if err = m.Write(message.New([][]byte{[]byte(`{"foo":"bar"}`)})); err != nil {
+			t.Fatal(err)
+		}
+
+		requests := getRequests()
+		if len(requests) != 1 || len(requests[0]) != 1 {
+			t.Fatalf("Wrong bulk requests for version %v: %v", test.version, requests)
+		}
+		if act := requests[0][0].Meta["_type"]; act != test.expType {
+			t.Errorf("Wrong type for version %v: %v != %v", test.version, act, test.expType)
+		}
+		ts.Close()
+	}
+}
+
+func TestElasticDataStream(t *testing.T) {
+	ts, getRequests := fakeElasticServer(t, "7.10.2", func(int, fakeBulkItem) int {
+		return 201
+	})
+	defer ts.Close()
+
+	conf := NewElasticsearchConfig()
+	conf.URLs = []string{ts.URL}
+	conf.Sniff = false
+	conf.DataStream = true
+	conf.Action = "index"
+	conf.Index = "logs-${!json_field_timestamp:@timestamp,2006.01.02}"
+
+	m, err := NewElasticsearch(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Write(message.New([][]byte{
+		[]byte(`{"@timestamp":"2018-11-12T14:05:00Z","message":"foo"}`),
+		[]byte(`{"message":"bar"}`),
+	}))
+	if err == nil {
+		t.Error("Expected error from missing timestamp")
+	} else if exp, act := "failed to send 1 parts [1]", err.Error(); !strings.HasPrefix(act, exp) {
+		t.Errorf("Wrong error: %v != %v", act, exp)
+	}
+
+	requests := getRequests()
+	if len(requests) != 1 || len(requests[0]) != 1 {
+		t.Fatalf("Wrong bulk requests: %v", requests)
+	}
+	item := requests[0][0]
+	if exp, act := "create", item.Action; exp != act {
+		t.Errorf("Wrong action: %v != %v", act, exp)
+	}
+	if exp, act := "logs-2018.11.12", item.Meta["_index"]; exp != act {
+		t.Errorf("Wrong index: %v != %v", act, exp)
+	}
+	if act, exists := item.Meta["_type"]; exists {
+		t.Errorf("Unexpected type: %v", act)
+	}
+}
+
 func TestElasticIntegration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping integration test in short mode")
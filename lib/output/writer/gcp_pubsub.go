@@ -30,14 +30,16 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	bmeta "github.com/Jeffail/benthos/lib/util/metadata"
 )
 
 //------------------------------------------------------------------------------
 
 // GCPPubSubConfig contains configuration fields for the output GCPPubSub type.
 type GCPPubSubConfig struct {
-	ProjectID string `json:"project" yaml:"project"`
-	TopicID   string `json:"topic" yaml:"topic"`
+	ProjectID string       `json:"project" yaml:"project"`
+	TopicID   string       `json:"topic" yaml:"topic"`
+	Metadata  bmeta.Config `json:"metadata" yaml:"metadata"`
 }
 
 // NewGCPPubSubConfig creates a new Config with default values.
@@ -45,6 +47,7 @@ func NewGCPPubSubConfig() GCPPubSubConfig {
 	return GCPPubSubConfig{
 		ProjectID: "",
 		TopicID:   "",
+		Metadata:  bmeta.NewConfig(),
 	}
 }
 
@@ -54,6 +57,7 @@ func NewGCPPubSubConfig() GCPPubSubConfig {
 // GCP Pub/Sub topic.
 type GCPPubSub struct {
 	conf GCPPubSubConfig
+	meta *bmeta.Filter
 
 	client   *pubsub.Client
 	topic    *pubsub.Topic
@@ -71,12 +75,17 @@ func NewGCPPubSub(
 ) (*GCPPubSub, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
+	meta, err := conf.Metadata.Get()
+	if err != nil {
+		return nil, err
+	}
 	client, err := pubsub.NewClient(ctx, conf.ProjectID)
 	if err != nil {
 		return nil, err
 	}
 	return &GCPPubSub{
 		conf:   conf,
+		meta:   meta,
 		log:    log.NewModule(".output.gcp_pubsub"),
 		client: client,
 		stats:  stats,
@@ -123,7 +132,7 @@ func (c *GCPPubSub) Write(msg types.Message) error {
 
 	msg.Iter(func(i int, part types.Part) error {
 		attr := map[string]string{}
-		part.Metadata().Iter(func(k, v string) error {
+		c.meta.Iter(part.Metadata(), func(k, v string) error {
 			attr[k] = v
 			return nil
 		})
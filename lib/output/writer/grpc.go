@@ -0,0 +1,236 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	bgrpc "github.com/Jeffail/benthos/lib/util/grpc"
+	btls "github.com/Jeffail/benthos/lib/util/tls"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+//------------------------------------------------------------------------------
+
+// GRPCConfig contains configuration fields for the GRPC output type.
+type GRPCConfig struct {
+	Address            string      `json:"address" yaml:"address"`
+	Method             string      `json:"method" yaml:"method"`
+	ProtoDescriptorSet string      `json:"proto_descriptor_set" yaml:"proto_descriptor_set"`
+	TimeoutMS          int64       `json:"timeout_ms" yaml:"timeout_ms"`
+	TLS                btls.Config `json:"tls" yaml:"tls"`
+}
+
+// NewGRPCConfig creates a new GRPCConfig with default values.
+func NewGRPCConfig() GRPCConfig {
+	return GRPCConfig{
+		Address:            "localhost:50051",
+		Method:             "",
+		ProtoDescriptorSet: "",
+		TimeoutMS:          5000,
+		TLS:                btls.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// GRPC is an output type that calls a gRPC method with the contents of
+// messages.
+type GRPC struct {
+	log   log.Modular
+	stats metrics.Type
+
+	conf    GRPCConfig
+	method  bgrpc.Method
+	tlsConf *tls.Config
+	timeout time.Duration
+
+	conn    *grpc.ClientConn
+	connMut sync.RWMutex
+}
+
+// NewGRPC creates a new GRPC output type.
+func NewGRPC(conf GRPCConfig, log log.Modular, stats metrics.Type) (*GRPC, error) {
+	if len(conf.Method) == 0 {
+		return nil, errors.New("a method must be specified")
+	}
+	g := GRPC{
+		log:     log.NewModule(".output.grpc"),
+		stats:   stats,
+		conf:    conf,
+		timeout: time.Duration(conf.TimeoutMS) * time.Millisecond,
+	}
+
+	var err error
+	if g.method, err = bgrpc.ResolveMethod(conf.ProtoDescriptorSet, conf.Method); err != nil {
+		return nil, err
+	}
+	if conf.TLS.Enabled {
+		if g.tlsConf, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+	return &g, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to establish a connection to the target gRPC server.
+func (g *GRPC) Connect() error {
+	g.connMut.Lock()
+	defer g.connMut.Unlock()
+
+	if g.conn != nil {
+		return nil
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithDefaultCallOptions(grpc.CallCustomCodec(bgrpc.Codec{})),
+	}
+	if g.tlsConf != nil {
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(g.tlsConf)))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+
+	var err error
+	if g.conn, err = grpc.Dial(g.conf.Address, opts...); err != nil {
+		return err
+	}
+
+	g.log.Infof("Sending gRPC messages to %v at: %v\n", g.method.FullName, g.conf.Address)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// callStream opens a stream for the target method, sends each provided part
+// as a message and then reads all responses until the stream is closed.
+func (g *GRPC) callStream(conn *grpc.ClientConn, parts ...types.Part) error {
+	ctx, done := g.callContext(parts[0])
+	defer done()
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{
+		ClientStreams: g.method.ClientStreaming,
+		ServerStreams: g.method.ServerStreaming,
+	}, g.method.FullName)
+	if err != nil {
+		return err
+	}
+	for _, p := range parts {
+		b := p.Get()
+		if err = stream.SendMsg(&b); err != nil {
+			if err != io.EOF {
+				return err
+			}
+			// The server ended the stream early, the reason is given by
+			// RecvMsg.
+			break
+		}
+	}
+	if err = stream.CloseSend(); err != nil {
+		return err
+	}
+	for {
+		var resBytes []byte
+		if err = stream.RecvMsg(&resBytes); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if !g.method.ServerStreaming {
+			return nil
+		}
+	}
+}
+
+// callContext returns a context carrying the deadline of a call and the
+// metadata of a part as outgoing gRPC metadata.
+func (g *GRPC) callContext(p types.Part) (context.Context, func()) {
+	ctx := metadata.NewOutgoingContext(
+		context.Background(), bgrpc.MetadataToGRPC(p.Metadata()),
+	)
+	if g.timeout > 0 {
+		return context.WithTimeout(ctx, g.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// Write attempts to write a message by calling the target method. Client
+// streaming methods are called once per message with each part sent over the
+// stream, otherwise the method is called once for each message part.
+func (g *GRPC) Write(msg types.Message) error {
+	g.connMut.RLock()
+	conn := g.conn
+	g.connMut.RUnlock()
+
+	if conn == nil {
+		return types.ErrNotConnected
+	}
+
+	if g.method.ClientStreaming {
+		parts := make([]types.Part, msg.Len())
+		msg.Iter(func(i int, p types.Part) error {
+			parts[i] = p
+			return nil
+		})
+		return g.callStream(conn, parts...)
+	}
+
+	return msg.Iter(func(i int, p types.Part) error {
+		if g.method.ServerStreaming {
+			return g.callStream(conn, p)
+		}
+		ctx, done := g.callContext(p)
+		defer done()
+
+		reqBytes, resBytes := p.Get(), []byte{}
+		return conn.Invoke(ctx, g.method.FullName, &reqBytes, &resBytes)
+	})
+}
+
+// CloseAsync shuts down the GRPC output and stops processing messages.
+func (g *GRPC) CloseAsync() {
+	g.connMut.Lock()
+	if g.conn != nil {
+		g.conn.Close()
+		g.conn = nil
+	}
+	g.connMut.Unlock()
+}
+
+// WaitForClose blocks until the GRPC output has closed down.
+func (g *GRPC) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
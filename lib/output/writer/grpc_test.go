@@ -0,0 +1,185 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"io"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	bgrpc "github.com/Jeffail/benthos/lib/util/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+//------------------------------------------------------------------------------
+
+type fakeGRPCCall struct {
+	method   string
+	meta     string
+	payloads []string
+}
+
+type fakeGRPCServer struct {
+	sync.Mutex
+	calls []fakeGRPCCall
+}
+
+func (f *fakeGRPCServer) handler(srv interface{}, stream grpc.ServerStream) error {
+	call := fakeGRPCCall{}
+	call.method, _ = grpc.MethodFromServerStream(stream)
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
+		if v := md.Get("foo"); len(v) > 0 {
+			call.meta = v[0]
+		}
+	}
+	for {
+		var b []byte
+		if err := stream.RecvMsg(&b); err != nil {
+			if err != io.EOF {
+				return err
+			}
+			break
+		}
+		call.payloads = append(call.payloads, string(b))
+	}
+	f.Lock()
+	f.calls = append(f.calls, call)
+	f.Unlock()
+	return stream.SendMsg(&[]byte{})
+}
+
+func startFakeGRPCServer(t *testing.T) (*fakeGRPCServer, string, func()) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeGRPCServer{}
+	s := grpc.NewServer(
+		grpc.CustomCodec(bgrpc.Codec{}),
+		grpc.UnknownServiceHandler(f.handler),
+	)
+	go s.Serve(lis)
+	return f, lis.Addr().String(), s.Stop
+}
+
+func TestGRPCUnary(t *testing.T) {
+	f, addr, stop := startFakeGRPCServer(t)
+	defer stop()
+
+	conf := NewGRPCConfig()
+	conf.Address = addr
+	conf.Method = "foo.Bar.Baz"
+
+	w, err := NewGRPC(conf, log.Noop(), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.CloseAsync()
+
+	msg := message.New([][]byte{[]byte("first"), []byte("second")})
+	msg.Get(0).Metadata().Set("foo", "bar1")
+	msg.Get(1).Metadata().Set("foo", "bar2")
+	if err = w.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []fakeGRPCCall{
+		{method: "/foo.Bar/Baz", meta: "bar1", payloads: []string{"first"}},
+		{method: "/foo.Bar/Baz", meta: "bar2", payloads: []string{"second"}},
+	}
+	f.Lock()
+	defer f.Unlock()
+	if !reflect.DeepEqual(exp, f.calls) {
+		t.Errorf("Wrong calls: %+v != %+v", f.calls, exp)
+	}
+}
+
+func TestGRPCClientStreaming(t *testing.T) {
+	f, addr, stop := startFakeGRPCServer(t)
+	defer stop()
+
+	conf := NewGRPCConfig()
+	conf.Address = addr
+	conf.Method = "foo.Bar/Baz"
+
+	w, err := NewGRPC(conf, log.Noop(), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.method.ClientStreaming = true
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.CloseAsync()
+
+	msg := message.New([][]byte{[]byte("first"), []byte("second")})
+	msg.Get(0).Metadata().Set("foo", "bar1")
+	if err = w.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []fakeGRPCCall{
+		{method: "/foo.Bar/Baz", meta: "bar1", payloads: []string{"first", "second"}},
+	}
+	f.Lock()
+	defer f.Unlock()
+	if !reflect.DeepEqual(exp, f.calls) {
+		t.Errorf("Wrong calls: %+v != %+v", f.calls, exp)
+	}
+}
+
+func TestGRPCBadConfig(t *testing.T) {
+	conf := NewGRPCConfig()
+	if _, err := NewGRPC(conf, log.Noop(), metrics.DudType{}); err == nil {
+		t.Error("Expected error from empty method")
+	}
+
+	conf.Method = "nope"
+	if _, err := NewGRPC(conf, log.Noop(), metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad method")
+	}
+}
+
+func TestGRPCNotConnected(t *testing.T) {
+	conf := NewGRPCConfig()
+	conf.Method = "foo.Bar/Baz"
+
+	w, err := NewGRPC(conf, log.Noop(), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Write(message.New([][]byte{[]byte("foo")})); err == nil {
+		t.Error("Expected error when not connected")
+	}
+}
+
+//------------------------------------------------------------------------------
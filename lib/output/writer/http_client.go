@@ -21,9 +21,12 @@
 package writer
 
 import (
+	"bytes"
+	"fmt"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/http/client"
@@ -35,17 +38,41 @@ import (
 // type.
 type HTTPClientConfig struct {
 	client.Config `json:",inline" yaml:",inline"`
+	BatchAs       string `json:"batch_as" yaml:"batch_as"`
 }
 
 // NewHTTPClientConfig creates a new HTTPClientConfig with default values.
 func NewHTTPClientConfig() HTTPClientConfig {
 	return HTTPClientConfig{
-		Config: client.NewConfig(),
+		Config:  client.NewConfig(),
+		BatchAs: "multipart",
 	}
 }
 
 //------------------------------------------------------------------------------
 
+type httpBatchMode int
+
+const (
+	httpBatchNone httpBatchMode = iota
+	httpBatchMultipart
+	httpBatchNDJSON
+)
+
+func strToHTTPBatchMode(str string) (httpBatchMode, error) {
+	switch str {
+	case "none":
+		return httpBatchNone, nil
+	case "multipart":
+		return httpBatchMultipart, nil
+	case "ndjson":
+		return httpBatchNDJSON, nil
+	}
+	return httpBatchNone, fmt.Errorf("batch_as value not recognised: %v", str)
+}
+
+//------------------------------------------------------------------------------
+
 // HTTPClient is an output type that sends messages as HTTP requests to a target
 // server endpoint.
 type HTTPClient struct {
@@ -55,6 +82,7 @@ type HTTPClient struct {
 	log   log.Modular
 
 	conf      HTTPClientConfig
+	batchMode httpBatchMode
 	closeChan chan struct{}
 }
 
@@ -72,8 +100,22 @@ func NewHTTPClient(
 		closeChan: make(chan struct{}),
 	}
 	var err error
+	if h.batchMode, err = strToHTTPBatchMode(conf.BatchAs); err != nil {
+		return nil, err
+	}
+
+	clientConf := conf.Config
+	if h.batchMode == httpBatchNDJSON && clientConf.Headers["Content-Type"] == "application/octet-stream" {
+		headers := make(map[string]string, len(clientConf.Headers))
+		for k, v := range clientConf.Headers {
+			headers[k] = v
+		}
+		headers["Content-Type"] = "application/x-ndjson"
+		clientConf.Headers = headers
+	}
+
 	if h.client, err = client.New(
-		conf.Config,
+		clientConf,
 		client.OptSetCloseChan(h.closeChan),
 		client.OptSetLogger(h.log),
 		client.OptSetManager(mgr),
@@ -95,6 +137,28 @@ func (h *HTTPClient) Connect() error {
 // Write attempts to send a message to an HTTP server, this attempt may include
 // retries, and if all retries fail an error is returned.
 func (h *HTTPClient) Write(msg types.Message) error {
+	switch h.batchMode {
+	case httpBatchNone:
+		return msg.Iter(func(i int, p types.Part) error {
+			partMsg := message.New(nil)
+			partMsg.Append(p.Copy())
+			_, err := h.client.Send(partMsg)
+			return err
+		})
+	case httpBatchNDJSON:
+		var body bytes.Buffer
+		msg.Iter(func(i int, p types.Part) error {
+			body.Write(bytes.TrimRight(p.Get(), "\n"))
+			body.WriteByte('\n')
+			return nil
+		})
+		ndMsg := message.New([][]byte{body.Bytes()})
+		if msg.Len() > 0 {
+			ndMsg.Get(0).SetMetadata(msg.Get(0).Metadata().Copy())
+		}
+		_, err := h.client.Send(ndMsg)
+		return err
+	}
 	_, err := h.client.Send(msg)
 	return err
 }
@@ -222,3 +222,86 @@ func TestHTTPClientMultipart(t *testing.T) {
 }
 
 //------------------------------------------------------------------------------
+
+func TestHTTPClientBatchAs(t *testing.T) {
+	type request struct {
+		contentType string
+		body        string
+	}
+	resultChan := make(chan request, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		resultChan <- request{
+			contentType: r.Header.Get("Content-Type"),
+			body:        string(b),
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		batchAs string
+		exp     []request
+	}{
+		{
+			batchAs: "none",
+			exp: []request{
+				{contentType: "application/octet-stream", body: `{"id":1}`},
+				{contentType: "application/octet-stream", body: `{"id":2}`},
+				{contentType: "application/octet-stream", body: "{\"id\":3}\n"},
+			},
+		},
+		{
+			batchAs: "ndjson",
+			exp: []request{
+				{contentType: "application/x-ndjson", body: "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewHTTPClientConfig()
+		conf.URL = ts.URL + "/testpost"
+		conf.BatchAs = test.batchAs
+
+		h, err := NewHTTPClient(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = h.Write(message.New([][]byte{
+			[]byte(`{"id":1}`),
+			[]byte(`{"id":2}`),
+			[]byte("{\"id\":3}\n"),
+		})); err != nil {
+			t.Error(err)
+		}
+
+		for _, exp := range test.exp {
+			select {
+			case act := <-resultChan:
+				if act != exp {
+					t.Errorf("Wrong request for %v: %v != %v", test.batchAs, act, exp)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("Action timed out for %v", test.batchAs)
+			}
+		}
+		select {
+		case act := <-resultChan:
+			t.Errorf("Unexpected request for %v: %v", test.batchAs, act)
+		default:
+		}
+	}
+}
+
+func TestHTTPClientBadBatchAs(t *testing.T) {
+	conf := NewHTTPClientConfig()
+	conf.BatchAs = "nope"
+
+	if _, err := NewHTTPClient(conf, types.NoopMgr(), log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad batch_as")
+	}
+}
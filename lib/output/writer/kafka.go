@@ -31,8 +31,10 @@ import (
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/kafka/sasl"
 	"github.com/Jeffail/benthos/lib/util/text"
 	btls "github.com/Jeffail/benthos/lib/util/tls"
+	"github.com/Jeffail/benthos/lib/util/tracecontext"
 	"github.com/Shopify/sarama"
 )
 
@@ -40,33 +42,41 @@ import (
 
 // KafkaConfig contains configuration fields for the Kafka output type.
 type KafkaConfig struct {
-	Addresses            []string    `json:"addresses" yaml:"addresses"`
-	ClientID             string      `json:"client_id" yaml:"client_id"`
-	Key                  string      `json:"key" yaml:"key"`
-	RoundRobinPartitions bool        `json:"round_robin_partitions" yaml:"round_robin_partitions"`
-	Topic                string      `json:"topic" yaml:"topic"`
-	Compression          string      `json:"compression" yaml:"compression"`
-	MaxMsgBytes          int         `json:"max_msg_bytes" yaml:"max_msg_bytes"`
-	TimeoutMS            int         `json:"timeout_ms" yaml:"timeout_ms"`
-	AckReplicas          bool        `json:"ack_replicas" yaml:"ack_replicas"`
-	TargetVersion        string      `json:"target_version" yaml:"target_version"`
-	TLS                  btls.Config `json:"tls" yaml:"tls"`
+	Addresses             []string    `json:"addresses" yaml:"addresses"`
+	ClientID              string      `json:"client_id" yaml:"client_id"`
+	Key                   string      `json:"key" yaml:"key"`
+	RoundRobinPartitions  bool        `json:"round_robin_partitions" yaml:"round_robin_partitions"`
+	Topic                 string      `json:"topic" yaml:"topic"`
+	Compression           string      `json:"compression" yaml:"compression"`
+	MaxMsgBytes           int         `json:"max_msg_bytes" yaml:"max_msg_bytes"`
+	TimeoutMS             int         `json:"timeout_ms" yaml:"timeout_ms"`
+	AckReplicas           bool        `json:"ack_replicas" yaml:"ack_replicas"`
+	IdempotentWrite       bool        `json:"idempotent_write" yaml:"idempotent_write"`
+	TransactionalID       string      `json:"transactional_id" yaml:"transactional_id"`
+	TargetVersion         string      `json:"target_version" yaml:"target_version"`
+	TLS                   btls.Config `json:"tls" yaml:"tls"`
+	SASL                  sasl.Config `json:"sasl" yaml:"sasl"`
+	PropagateTraceContext bool        `json:"propagate_trace_context" yaml:"propagate_trace_context"`
 }
 
 // NewKafkaConfig creates a new KafkaConfig with default values.
 func NewKafkaConfig() KafkaConfig {
 	return KafkaConfig{
-		Addresses:            []string{"localhost:9092"},
-		ClientID:             "benthos_kafka_output",
-		Key:                  "",
-		RoundRobinPartitions: false,
-		Topic:                "benthos_stream",
-		Compression:          "none",
-		MaxMsgBytes:          1000000,
-		TimeoutMS:            5000,
-		AckReplicas:          false,
-		TargetVersion:        sarama.V1_0_0_0.String(),
-		TLS:                  btls.NewConfig(),
+		Addresses:             []string{"localhost:9092"},
+		ClientID:              "benthos_kafka_output",
+		Key:                   "",
+		RoundRobinPartitions:  false,
+		Topic:                 "benthos_stream",
+		Compression:           "none",
+		MaxMsgBytes:           1000000,
+		TimeoutMS:             5000,
+		AckReplicas:           false,
+		IdempotentWrite:       false,
+		TransactionalID:       "",
+		TargetVersion:         sarama.V1_0_0_0.String(),
+		TLS:                   btls.NewConfig(),
+		SASL:                  sasl.NewConfig(),
+		PropagateTraceContext: false,
 	}
 }
 
@@ -84,6 +94,7 @@ type Kafka struct {
 	conf      KafkaConfig
 
 	mDroppedMaxBytes metrics.StatCounter
+	mTxnAborted      metrics.StatCounter
 
 	key   *text.InterpolatedBytes
 	topic *text.InterpolatedString
@@ -92,6 +103,9 @@ type Kafka struct {
 	compression sarama.CompressionCodec
 
 	connMut sync.RWMutex
+
+	// txnMut ensures only one batch is sent per transaction.
+	txnMut sync.Mutex
 }
 
 // NewKafka creates a new Kafka writer type.
@@ -105,6 +119,7 @@ func NewKafka(conf KafkaConfig, log log.Modular, stats metrics.Type) (*Kafka, er
 		log:              log.NewModule(".output.kafka"),
 		stats:            stats,
 		mDroppedMaxBytes: stats.GetCounter("output.kafka.send.dropped.max_msg_bytes"),
+		mTxnAborted:      stats.GetCounter("output.kafka.transaction.aborted"),
 
 		conf:        conf,
 		key:         text.NewInterpolatedBytes([]byte(conf.Key)),
@@ -118,10 +133,31 @@ func NewKafka(conf KafkaConfig, log log.Modular, stats metrics.Type) (*Kafka, er
 			return nil, err
 		}
 	}
+	if err := conf.SASL.Validate(); err != nil {
+		return nil, err
+	}
 
 	if k.version, err = sarama.ParseKafkaVersion(conf.TargetVersion); err != nil {
 		return nil, err
 	}
+	if conf.PropagateTraceContext && !k.version.IsAtLeast(sarama.V0_11_0_0) {
+		return nil, fmt.Errorf(
+			"propagating trace context requires record headers, which are not supported by target version %v",
+			conf.TargetVersion,
+		)
+	}
+	if conf.IdempotentWrite && !k.version.IsAtLeast(sarama.V0_11_0_0) {
+		return nil, fmt.Errorf(
+			"idempotent writes are not supported by target version %v",
+			conf.TargetVersion,
+		)
+	}
+	if len(conf.TransactionalID) > 0 && !k.version.IsAtLeast(sarama.V0_11_0_0) {
+		return nil, fmt.Errorf(
+			"transactions are not supported by target version %v",
+			conf.TargetVersion,
+		)
+	}
 
 	for _, addr := range conf.Addresses {
 		for _, splitAddr := range strings.Split(addr, ",") {
@@ -175,6 +211,9 @@ func (k *Kafka) Connect() error {
 	if k.conf.TLS.Enabled {
 		config.Net.TLS.Config = k.tlsConf
 	}
+	if err := k.conf.SASL.Apply(config); err != nil {
+		return err
+	}
 
 	if k.conf.RoundRobinPartitions {
 		config.Producer.Partitioner = sarama.NewRoundRobinPartitioner
@@ -186,13 +225,23 @@ func (k *Kafka) Connect() error {
 		config.Producer.RequiredAcks = sarama.WaitForLocal
 	}
 
+	// Idempotent writes require acknowledgement from all replicas and a
+	// single in flight request per broker in order to preserve ordering.
+	// Transactional writes are always idempotent.
+	if k.conf.IdempotentWrite || len(k.conf.TransactionalID) > 0 {
+		config.Producer.Idempotent = true
+		config.Producer.RequiredAcks = sarama.WaitForAll
+		config.Net.MaxOpenRequests = 1
+	}
+	config.Producer.Transaction.ID = k.conf.TransactionalID
+
 	var err error
 	k.producer, err = sarama.NewSyncProducer(k.addresses, config)
 
 	if err == nil {
 		k.log.Infof("Sending Kafka messages to addresses: %s\n", k.addresses)
 	}
-	return err
+	return k.conf.SASL.ConnectErr(err)
 }
 
 // Write will attempt to write a message to Kafka, wait for acknowledgement, and
@@ -223,17 +272,72 @@ func (k *Kafka) Write(msg types.Message) error {
 		if len(key) > 0 {
 			nextMsg.Key = sarama.ByteEncoder(key)
 		}
+		if k.conf.PropagateTraceContext {
+			tracecontext.Inject(p.Metadata(), func(name, value string) {
+				nextMsg.Headers = append(nextMsg.Headers, sarama.RecordHeader{
+					Key:   []byte(name),
+					Value: []byte(value),
+				})
+			})
+		}
 		msgs = append(msgs, nextMsg)
 		return nil
 	})
 
+	if producer.IsTransactional() {
+		return k.sendTxn(producer, msgs)
+	}
+	return sendMessages(producer, msgs)
+}
+
+func sendMessages(producer sarama.SyncProducer, msgs []*sarama.ProducerMessage) error {
 	err := producer.SendMessages(msgs)
 	if err != nil {
 		if pErr, ok := err.(sarama.ProducerErrors); ok && len(pErr) > 0 {
 			err = fmt.Errorf("failed to send %v parts from message: %v", len(pErr), pErr[0].Err)
 		}
 	}
+	return err
+}
 
+// sendTxn sends messages within a transaction, which is committed once all
+// messages are acknowledged and is otherwise aborted. Transactions are sent one
+// at a time.
+func (k *Kafka) sendTxn(producer sarama.SyncProducer, msgs []*sarama.ProducerMessage) error {
+	k.txnMut.Lock()
+	defer k.txnMut.Unlock()
+
+	err := producer.BeginTxn()
+	if err == nil {
+		if err = sendMessages(producer, msgs); err == nil {
+			if err = producer.CommitTxn(); err == nil {
+				return nil
+			}
+			err = fmt.Errorf("failed to commit transaction: %v", err)
+		}
+	} else {
+		err = fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	if producer.TxnStatus()&sarama.ProducerTxnFlagInTransaction != 0 ||
+		producer.TxnStatus()&sarama.ProducerTxnFlagAbortableError != 0 {
+		k.mTxnAborted.Incr(1)
+		if aErr := producer.AbortTxn(); aErr != nil {
+			k.log.Errorf("Failed to abort transaction: %v\n", aErr)
+		}
+	}
+
+	// A producer in a fatal state must be recreated.
+	if producer.TxnStatus()&sarama.ProducerTxnFlagFatalError != 0 {
+		k.log.Errorf("Closing producer after fatal transaction error: %v\n", err)
+		k.connMut.Lock()
+		if k.producer == producer {
+			k.producer.Close()
+			k.producer = nil
+		}
+		k.connMut.Unlock()
+		return types.ErrNotConnected
+	}
 	return err
 }
 
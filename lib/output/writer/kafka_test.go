@@ -0,0 +1,410 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Shopify/sarama"
+)
+
+//------------------------------------------------------------------------------
+
+func TestKafkaIdempotentBadVersion(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.IdempotentWrite = true
+	conf.TargetVersion = "0.10.2.0"
+
+	if _, err := NewKafka(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from unsupported target version")
+	}
+}
+
+type producedBatch struct {
+	producerID int64
+	sequence   int32
+}
+
+// kafkaTap is a proxy between a Kafka client and a mock broker that records
+// the producer id and first sequence number of each record batch within produce
+// requests, as seen on the wire by the broker.
+type kafkaTap struct {
+	t        *testing.T
+	ln       net.Listener
+	upstream string
+
+	mut     sync.Mutex
+	batches []producedBatch
+}
+
+func newKafkaTap(t *testing.T, upstream string) *kafkaTap {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tap := &kafkaTap{t: t, ln: ln, upstream: upstream}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go tap.serve(conn)
+		}
+	}()
+	return tap
+}
+
+func (k *kafkaTap) Addr() string {
+	return k.ln.Addr().String()
+}
+
+func (k *kafkaTap) Close() {
+	k.ln.Close()
+}
+
+func (k *kafkaTap) Batches() []producedBatch {
+	k.mut.Lock()
+	defer k.mut.Unlock()
+	return append([]producedBatch(nil), k.batches...)
+}
+
+func (k *kafkaTap) serve(conn net.Conn) {
+	defer conn.Close()
+
+	upConn, err := net.Dial("tcp", k.upstream)
+	if err != nil {
+		k.t.Error(err)
+		return
+	}
+	defer upConn.Close()
+	go io.Copy(conn, upConn)
+
+	sizeBytes := make([]byte, 4)
+	for {
+		if _, err = io.ReadFull(conn, sizeBytes); err != nil {
+			return
+		}
+		body := make([]byte, binary.BigEndian.Uint32(sizeBytes))
+		if _, err = io.ReadFull(conn, body); err != nil {
+			return
+		}
+		if binary.BigEndian.Uint16(body) == 0 {
+			batches, err := parseProduceBatches(body)
+			if err != nil {
+				k.t.Errorf("Failed to parse produce request: %v", err)
+			}
+			k.mut.Lock()
+			k.batches = append(k.batches, batches...)
+			k.mut.Unlock()
+		}
+		if _, err = upConn.Write(append(sizeBytes, body...)); err != nil {
+			return
+		}
+	}
+}
+
+// parseProduceBatches reads the record batch headers of a produce request (v3 to
+// v8).
+func parseProduceBatches(b []byte) ([]producedBatch, error) {
+	errShort := errors.New("request too short")
+	skip := func(n int) bool {
+		if n < 0 || len(b) < n {
+			return false
+		}
+		b = b[n:]
+		return true
+	}
+	readInt16 := func() (int16, bool) {
+		if len(b) < 2 {
+			return 0, false
+		}
+		v := int16(binary.BigEndian.Uint16(b))
+		b = b[2:]
+		return v, true
+	}
+	readInt32 := func() (int32, bool) {
+		if len(b) < 4 {
+			return 0, false
+		}
+		v := int32(binary.BigEndian.Uint32(b))
+		b = b[4:]
+		return v, true
+	}
+	skipString := func() bool {
+		l, ok := readInt16()
+		if !ok {
+			return false
+		}
+		if l < 0 {
+			return true
+		}
+		return skip(int(l))
+	}
+
+	// Header: api key, api version, correlation id and client id, followed by
+	// transactional id, required acks and timeout.
+	if !skip(8) || !skipString() || !skipString() || !skip(6) {
+		return nil, errShort
+	}
+
+	var batches []producedBatch
+	nTopics, ok := readInt32()
+	if !ok {
+		return nil, errShort
+	}
+	for i := int32(0); i < nTopics; i++ {
+		if !skipString() {
+			return nil, errShort
+		}
+		nPartitions, ok := readInt32()
+		if !ok {
+			return nil, errShort
+		}
+		for j := int32(0); j < nPartitions; j++ {
+			if !skip(4) {
+				return nil, errShort
+			}
+			size, ok := readInt32()
+			if !ok || len(b) < int(size) {
+				return nil, errShort
+			}
+			records := b[:size]
+			b = b[size:]
+
+			// Producer id and base sequence sit at fixed offsets within a
+			// record batch header.
+			if len(records) < 57 {
+				return nil, errShort
+			}
+			if magic := records[16]; magic != 2 {
+				return nil, fmt.Errorf("unexpected record batch magic: %v", magic)
+			}
+			batches = append(batches, producedBatch{
+				producerID: int64(binary.BigEndian.Uint64(records[43:])),
+				sequence:   int32(binary.BigEndian.Uint32(records[53:])),
+			})
+		}
+	}
+	return batches, nil
+}
+
+func TestKafkaIdempotentRetry(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+
+	tap := newKafkaTap(t, broker.Addr())
+	defer tap.Close()
+
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(tap.Addr(), broker.BrokerID()).
+			SetLeader("foo", 0, broker.BrokerID()),
+		"InitProducerIDRequest": sarama.NewMockWrapper(&sarama.InitProducerIDResponse{
+			ProducerID:    1000,
+			ProducerEpoch: 1,
+		}),
+		"ProduceRequest": sarama.NewMockSequence(
+			sarama.NewMockProduceResponse(t).
+				SetVersion(3).
+				SetError("foo", 0, sarama.ErrNotEnoughReplicasAfterAppend),
+			sarama.NewMockProduceResponse(t).SetVersion(3),
+		),
+	})
+
+	conf := NewKafkaConfig()
+	conf.Addresses = []string{tap.Addr()}
+	conf.Topic = "foo"
+	conf.IdempotentWrite = true
+
+	w, err := NewKafka(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.CloseAsync()
+
+	if err = w.Write(message.New([][]byte{[]byte("hello world")})); err != nil {
+		t.Fatal(err)
+	}
+
+	batches := tap.Batches()
+	if exp, act := 2, len(batches); exp != act {
+		t.Fatalf("Wrong count of produce requests: %v != %v", act, exp)
+	}
+
+	// The retried batch must carry the same producer id and sequence as the
+	// original in order for the broker to discard it as a duplicate.
+	seen := map[producedBatch]struct{}{}
+	for _, b := range batches {
+		if exp, act := int64(1000), b.producerID; exp != act {
+			t.Errorf("Wrong producer id: %v != %v", act, exp)
+		}
+		seen[b] = struct{}{}
+	}
+	if exp, act := 1, len(seen); exp != act {
+		t.Errorf("Wrong count of unique batches: %v != %v", act, exp)
+	}
+}
+
+func TestKafkaTransactionBadVersion(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.TransactionalID = "foo_txn"
+	conf.TargetVersion = "0.10.2.0"
+
+	if _, err := NewKafka(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from unsupported target version")
+	}
+}
+
+func newKafkaTxnBroker(t *testing.T, produce sarama.MockResponse) *sarama.MockBroker {
+	t.Helper()
+
+	broker := sarama.NewMockBroker(t, 1)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("foo", 0, broker.BrokerID()),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorTransaction, "foo_txn", broker),
+		"InitProducerIDRequest": sarama.NewMockWrapper(&sarama.InitProducerIDResponse{
+			ProducerID:    1000,
+			ProducerEpoch: 1,
+		}),
+		"AddPartitionsToTxnRequest": sarama.NewMockWrapper(&sarama.AddPartitionsToTxnResponse{
+			Errors: map[string][]*sarama.PartitionError{
+				"foo": {{Partition: 0, Err: sarama.ErrNoError}},
+			},
+		}),
+		"ProduceRequest": produce,
+		"EndTxnRequest":  sarama.NewMockWrapper(&sarama.EndTxnResponse{}),
+	})
+	return broker
+}
+
+func newKafkaTxnWriter(t *testing.T, broker *sarama.MockBroker) *Kafka {
+	t.Helper()
+
+	conf := NewKafkaConfig()
+	conf.Addresses = []string{broker.Addr()}
+	conf.Topic = "foo"
+	conf.TransactionalID = "foo_txn"
+
+	w, err := NewKafka(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+// txnResults returns the outcome of each transaction ended against a mock
+// broker, where true is a commit and false an abort.
+func txnResults(broker *sarama.MockBroker) []bool {
+	var results []bool
+	for _, rr := range broker.History() {
+		if req, ok := interface{}(rr.Request).(*sarama.EndTxnRequest); ok {
+			results = append(results, req.TransactionResult)
+		}
+	}
+	return results
+}
+
+func TestKafkaTransactionCommitAbort(t *testing.T) {
+	broker := newKafkaTxnBroker(t, sarama.NewMockSequence(
+		sarama.NewMockProduceResponse(t).
+			SetVersion(3).
+			SetError("foo", 0, sarama.ErrMessageSizeTooLarge),
+		sarama.NewMockProduceResponse(t).SetVersion(3),
+	))
+	defer broker.Close()
+
+	w := newKafkaTxnWriter(t, broker)
+	defer w.CloseAsync()
+
+	if err := w.Write(message.New([][]byte{[]byte("foo")})); err == nil {
+		t.Error("Expected error from failed send")
+	}
+	if err := w.Write(message.New([][]byte{[]byte("bar")})); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := []bool{false, true}, txnResults(broker); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong transaction results: %v != %v", act, exp)
+	}
+	for _, rr := range broker.History() {
+		req, ok := interface{}(rr.Request).(*sarama.ProduceRequest)
+		if !ok {
+			continue
+		}
+		if req.TransactionalID == nil || *req.TransactionalID != "foo_txn" {
+			t.Errorf("Wrong transactional id in produce request: %v", req.TransactionalID)
+		}
+	}
+}
+
+func TestKafkaTransactionConcurrentWrites(t *testing.T) {
+	broker := newKafkaTxnBroker(t, sarama.NewMockProduceResponse(t).SetVersion(3))
+	defer broker.Close()
+
+	w := newKafkaTxnWriter(t, broker)
+	defer w.CloseAsync()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := w.Write(message.New([][]byte{[]byte("foo")})); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	results := txnResults(broker)
+	if exp, act := 10, len(results); exp != act {
+		t.Fatalf("Wrong count of transactions: %v != %v", act, exp)
+	}
+	for _, committed := range results {
+		if !committed {
+			t.Error("Expected transaction to be committed")
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
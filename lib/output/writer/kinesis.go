@@ -235,6 +235,7 @@ func (a *Kinesis) Write(msg types.Message) error {
 			if wait == backoff.Stop {
 				return err
 			}
+			time.Sleep(wait)
 			continue
 		}
 
@@ -0,0 +1,257 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	sess "github.com/Jeffail/benthos/lib/util/aws/session"
+	"github.com/Jeffail/benthos/lib/util/retries"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/firehose"
+	"github.com/aws/aws-sdk-go/service/firehose/firehoseiface"
+	"github.com/cenkalti/backoff"
+)
+
+//------------------------------------------------------------------------------
+
+const (
+	firehoseMaxRecordsCount = 500
+	firehoseMaxBatchBytes   = 4 * mebibyte
+	firehoseMaxRecordBytes  = 1000 * 1024
+)
+
+// KinesisFirehoseConfig contains configuration fields for the Kinesis Firehose
+// output type.
+type KinesisFirehoseConfig struct {
+	sessionConfig  `json:",inline" yaml:",inline"`
+	Stream         string `json:"stream" yaml:"stream"`
+	retries.Config `json:",inline" yaml:",inline"`
+}
+
+// NewKinesisFirehoseConfig creates a new Config with default values.
+func NewKinesisFirehoseConfig() KinesisFirehoseConfig {
+	rConf := retries.NewConfig()
+	rConf.MaxRetries = 3
+	rConf.Backoff.InitialInterval = "1s"
+	rConf.Backoff.MaxInterval = "5s"
+	rConf.Backoff.MaxElapsedTime = "30s"
+	return KinesisFirehoseConfig{
+		sessionConfig: sessionConfig{
+			Config: sess.NewConfig(),
+		},
+		Stream: "",
+		Config: rConf,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// KinesisFirehose is a benthos writer.Type implementation that writes messages
+// to an Amazon Kinesis Firehose delivery stream.
+type KinesisFirehose struct {
+	conf KinesisFirehoseConfig
+
+	session  *session.Session
+	firehose firehoseiface.FirehoseAPI
+
+	backoff    backoff.BackOff
+	streamName *text.InterpolatedString
+
+	log   log.Modular
+	stats metrics.Type
+
+	mRetried      metrics.StatCounter
+	mPartsRetried metrics.StatCounter
+}
+
+// NewKinesisFirehose creates a new Amazon Kinesis Firehose writer.Type.
+func NewKinesisFirehose(
+	conf KinesisFirehoseConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*KinesisFirehose, error) {
+	if len(conf.Stream) == 0 {
+		return nil, errors.New("stream must not be empty")
+	}
+
+	k := KinesisFirehose{
+		conf:          conf,
+		log:           log.NewModule(".output.kinesis_firehose"),
+		stats:         stats,
+		mRetried:      stats.GetCounter("output.kinesis_firehose.send.retried"),
+		mPartsRetried: stats.GetCounter("output.kinesis_firehose.parts.send.retried"),
+		streamName:    text.NewInterpolatedString(conf.Stream),
+	}
+
+	var err error
+	if k.backoff, err = conf.Config.Get(); err != nil {
+		return nil, fmt.Errorf("failed to parse retry fields: %v", err)
+	}
+	return &k, nil
+}
+
+//------------------------------------------------------------------------------
+
+// firehoseBatch is a group of records destined for the same delivery stream
+// that fits within the limits of a single PutRecordBatch call.
+type firehoseBatch struct {
+	stream  string
+	records []*firehose.Record
+	size    int
+}
+
+// toBatches converts a benthos message into batches of records. Each message
+// part is resolved to a delivery stream individually and parts targeting the
+// same stream are grouped in order.
+func (a *KinesisFirehose) toBatches(msg types.Message) ([]*firehoseBatch, error) {
+	var batches []*firehoseBatch
+	current := map[string]*firehoseBatch{}
+
+	err := msg.Iter(func(i int, p types.Part) error {
+		data := p.Get()
+		if len(data) > firehoseMaxRecordBytes {
+			a.log.Errorf("part %d exceeds the maximum Kinesis Firehose record limit of 1000 KiB\n", i)
+			return types.ErrMessageTooLarge
+		}
+
+		stream := a.streamName.Get(message.Lock(msg, i))
+		b, exists := current[stream]
+		if !exists || len(b.records) >= firehoseMaxRecordsCount || b.size+len(data) > firehoseMaxBatchBytes {
+			b = &firehoseBatch{stream: stream}
+			current[stream] = b
+			batches = append(batches, b)
+		}
+		b.records = append(b.records, &firehose.Record{Data: data})
+		b.size += len(data)
+		return nil
+	})
+	return batches, err
+}
+
+//------------------------------------------------------------------------------
+
+// Connect creates a new Kinesis Firehose client and, when the stream name is
+// static, ensures that the target delivery stream exists.
+func (a *KinesisFirehose) Connect() error {
+	if a.session != nil {
+		return nil
+	}
+
+	sess, err := a.conf.GetSession()
+	if err != nil {
+		return err
+	}
+
+	client := firehose.New(sess)
+	if !text.ContainsFunctionVariables([]byte(a.conf.Stream)) {
+		if _, err = client.DescribeDeliveryStream(&firehose.DescribeDeliveryStreamInput{
+			DeliveryStreamName: aws.String(a.conf.Stream),
+		}); err != nil {
+			return err
+		}
+	}
+
+	a.session = sess
+	a.firehose = client
+
+	a.log.Infof("Sending messages to Kinesis Firehose delivery stream: %v\n", a.conf.Stream)
+	return nil
+}
+
+// writeBatch attempts to write a batch of records to a delivery stream,
+// retrying any records that fail according to the configurable backoff
+// settings. An error is returned if records remain undelivered once the
+// retries are exhausted.
+func (a *KinesisFirehose) writeBatch(b *firehoseBatch) error {
+	input := &firehose.PutRecordBatchInput{
+		DeliveryStreamName: aws.String(b.stream),
+		Records:            b.records,
+	}
+
+	a.backoff.Reset()
+	for {
+		output, err := a.firehose.PutRecordBatch(input)
+		if err != nil {
+			a.log.Warnf("kinesis firehose error: %v\n", err)
+		} else if output.FailedPutCount != nil && *output.FailedPutCount > 0 {
+			var failed []*firehose.Record
+			for i, entry := range output.RequestResponses {
+				if entry.ErrorCode != nil {
+					failed = append(failed, input.Records[i])
+					err = fmt.Errorf("record failed with code [%s] %s", *entry.ErrorCode, aws.StringValue(entry.ErrorMessage))
+				}
+			}
+			input.Records = failed
+			a.mPartsRetried.Incr(int64(len(failed)))
+			a.log.Warnf("scheduling retry of failed records (%d): %v\n", len(failed), err)
+		} else {
+			return nil
+		}
+
+		wait := a.backoff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		a.mRetried.Incr(1)
+		time.Sleep(wait)
+	}
+}
+
+// Write attempts to write message contents to target Kinesis Firehose
+// delivery streams in batches of up to 500 records or 4 MiB.
+func (a *KinesisFirehose) Write(msg types.Message) error {
+	if a.session == nil {
+		return types.ErrNotConnected
+	}
+
+	batches, err := a.toBatches(msg)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range batches {
+		if err = a.writeBatch(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (a *KinesisFirehose) CloseAsync() {
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (a *KinesisFirehose) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,244 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/firehose"
+	"github.com/aws/aws-sdk-go/service/firehose/firehoseiface"
+	"github.com/cenkalti/backoff"
+)
+
+//------------------------------------------------------------------------------
+
+type mockFirehose struct {
+	firehoseiface.FirehoseAPI
+	fn func(input *firehose.PutRecordBatchInput) (*firehose.PutRecordBatchOutput, error)
+}
+
+func (m *mockFirehose) PutRecordBatch(input *firehose.PutRecordBatchInput) (*firehose.PutRecordBatchOutput, error) {
+	return m.fn(input)
+}
+
+func newMockKinesisFirehose(
+	t *testing.T,
+	stream string,
+	boff backoff.BackOff,
+	fn func(input *firehose.PutRecordBatchInput) (*firehose.PutRecordBatchOutput, error),
+) *KinesisFirehose {
+	t.Helper()
+
+	conf := NewKinesisFirehoseConfig()
+	conf.Stream = stream
+
+	k, err := NewKinesisFirehose(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.backoff = boff
+	k.session = session.Must(session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials("xxxxx", "xxxxx", "xxxxx"),
+	}))
+	k.firehose = &mockFirehose{fn: fn}
+	return k
+}
+
+func TestKinesisFirehoseBadConfig(t *testing.T) {
+	if _, err := NewKinesisFirehose(NewKinesisFirehoseConfig(), log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from empty stream")
+	}
+}
+
+func TestKinesisFirehoseWriteChunk(t *testing.T) {
+	type call struct {
+		stream string
+		count  int
+	}
+	var calls []call
+
+	k := newMockKinesisFirehose(t, "${!metadata:stream}", backoff.NewExponentialBackOff(),
+		func(input *firehose.PutRecordBatchInput) (*firehose.PutRecordBatchOutput, error) {
+			size := 0
+			for _, r := range input.Records {
+				size += len(r.Data)
+			}
+			if size > firehoseMaxBatchBytes {
+				return nil, fmt.Errorf("batch size %v exceeds limit", size)
+			}
+			calls = append(calls, call{
+				stream: *input.DeliveryStreamName,
+				count:  len(input.Records),
+			})
+			return &firehose.PutRecordBatchOutput{FailedPutCount: aws.Int64(0)}, nil
+		},
+	)
+
+	msg := message.New(nil)
+	for i := 0; i < 1200; i++ {
+		part := message.NewPart([]byte("foo"))
+		if i%2 == 0 {
+			part.Metadata().Set("stream", "even")
+		} else {
+			part.Metadata().Set("stream", "odd")
+		}
+		msg.Append(part)
+	}
+	for i := 0; i < 6; i++ {
+		part := message.NewPart(bytes.Repeat([]byte("x"), firehoseMaxRecordBytes))
+		part.Metadata().Set("stream", "big")
+		msg.Append(part)
+	}
+
+	if err := k.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []call{
+		{"even", 500},
+		{"odd", 500},
+		{"even", 100},
+		{"odd", 100},
+		{"big", 4},
+		{"big", 2},
+	}
+	if len(calls) != len(exp) {
+		t.Fatalf("Wrong calls: %v != %v", calls, exp)
+	}
+	for i, e := range exp {
+		if calls[i] != e {
+			t.Errorf("Wrong call %v: %v != %v", i, calls[i], e)
+		}
+	}
+}
+
+func TestKinesisFirehoseRetryFailedRecords(t *testing.T) {
+	var calls [][]string
+	k := newMockKinesisFirehose(t, "foo", backoff.NewConstantBackOff(0),
+		func(input *firehose.PutRecordBatchInput) (*firehose.PutRecordBatchOutput, error) {
+			var contents []string
+			output := &firehose.PutRecordBatchOutput{FailedPutCount: aws.Int64(0)}
+			for _, r := range input.Records {
+				contents = append(contents, string(r.Data))
+				entry := &firehose.PutRecordBatchResponseEntry{RecordId: aws.String("id")}
+				if len(calls) == 0 && string(r.Data) != "second" {
+					entry = &firehose.PutRecordBatchResponseEntry{
+						ErrorCode:    aws.String("ServiceUnavailableException"),
+						ErrorMessage: aws.String("slow down"),
+					}
+					*output.FailedPutCount++
+				}
+				output.RequestResponses = append(output.RequestResponses, entry)
+			}
+			calls = append(calls, contents)
+			return output, nil
+		},
+	)
+
+	msg := message.New([][]byte{[]byte("first"), []byte("second"), []byte("third")})
+	if err := k.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]string{
+		{"first", "second", "third"},
+		{"first", "third"},
+	}
+	if fmt.Sprintf("%v", calls) != fmt.Sprintf("%v", exp) {
+		t.Errorf("Wrong calls: %v != %v", calls, exp)
+	}
+}
+
+func TestKinesisFirehoseRetriesExhausted(t *testing.T) {
+	calls := 0
+	k := newMockKinesisFirehose(t, "foo", backoff.WithMaxRetries(backoff.NewConstantBackOff(0), 2),
+		func(input *firehose.PutRecordBatchInput) (*firehose.PutRecordBatchOutput, error) {
+			calls++
+			return &firehose.PutRecordBatchOutput{
+				FailedPutCount: aws.Int64(1),
+				RequestResponses: []*firehose.PutRecordBatchResponseEntry{
+					{
+						ErrorCode:    aws.String("ServiceUnavailableException"),
+						ErrorMessage: aws.String("slow down"),
+					},
+				},
+			}, nil
+		},
+	)
+
+	if err := k.Write(message.New([][]byte{[]byte("foo")})); err == nil {
+		t.Error("Expected error from undelivered records")
+	}
+	if exp, act := 3, calls; exp != act {
+		t.Errorf("Wrong count of calls: %v != %v", act, exp)
+	}
+
+	calls = 0
+	k.firehose = &mockFirehose{
+		fn: func(input *firehose.PutRecordBatchInput) (*firehose.PutRecordBatchOutput, error) {
+			calls++
+			return nil, errors.New("nope")
+		},
+	}
+	if err := k.Write(message.New([][]byte{[]byte("foo")})); err == nil || err.Error() != "nope" {
+		t.Errorf("Wrong error: %v", err)
+	}
+	if exp, act := 3, calls; exp != act {
+		t.Errorf("Wrong count of calls: %v != %v", act, exp)
+	}
+}
+
+func TestKinesisFirehoseRecordTooLarge(t *testing.T) {
+	k := newMockKinesisFirehose(t, "foo", backoff.NewExponentialBackOff(),
+		func(input *firehose.PutRecordBatchInput) (*firehose.PutRecordBatchOutput, error) {
+			return nil, errors.New("should not be called")
+		},
+	)
+
+	msg := message.New([][]byte{bytes.Repeat([]byte("x"), firehoseMaxRecordBytes+1)})
+	if err := k.Write(msg); err != types.ErrMessageTooLarge {
+		t.Errorf("Wrong error: %v", err)
+	}
+}
+
+func TestKinesisFirehoseNotConnected(t *testing.T) {
+	conf := NewKinesisFirehoseConfig()
+	conf.Stream = "foo"
+	k, err := NewKinesisFirehose(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = k.Write(message.New([][]byte{[]byte("foo")})); err != types.ErrNotConnected {
+		t.Errorf("Wrong error: %v", err)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -10,6 +10,7 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
 	sess "github.com/Jeffail/benthos/lib/util/aws/session"
 	"github.com/Jeffail/benthos/lib/util/text"
 	"github.com/aws/aws-sdk-go/aws"
@@ -341,6 +342,103 @@ func TestKinesisWriteBackoffMaxRetriesExceeded(t *testing.T) {
 	}
 }
 
+func newMockKinesisWriter(
+	bOff backoff.BackOff,
+	fn func(input *kinesis.PutRecordsInput) (*kinesis.PutRecordsOutput, error),
+) *Kinesis {
+	return &Kinesis{
+		backoff: bOff,
+		session: session.Must(session.NewSession(&aws.Config{
+			Credentials: credentials.NewStaticCredentials("xxxxx", "xxxxx", "xxxxx"),
+		})),
+		kinesis:          &mockKinesis{fn: fn},
+		mThrottled:       mThrottled,
+		mThrottledF:      mThrottledF,
+		mPartsThrottled:  mPartsThrottled,
+		mPartsThrottledF: mPartsThrottledF,
+		log:              log.Noop(),
+		partitionKey:     text.NewInterpolatedString("${!json_field:id}"),
+		hashKey:          text.NewInterpolatedString(""),
+	}
+}
+
+func TestKinesisWritePersistentThrottling(t *testing.T) {
+	t.Parallel()
+	var calls int
+	k := newMockKinesisWriter(
+		backoff.WithMaxRetries(backoff.NewConstantBackOff(time.Millisecond*20), 2),
+		func(input *kinesis.PutRecordsInput) (*kinesis.PutRecordsOutput, error) {
+			calls++
+			var output kinesis.PutRecordsOutput
+			output.FailedRecordCount = aws.Int64(int64(len(input.Records)))
+			for range input.Records {
+				output.Records = append(output.Records, &kinesis.PutRecordsResultEntry{
+					ErrorCode: aws.String(kinesis.ErrCodeKMSThrottlingException),
+				})
+			}
+			return &output, nil
+		},
+	)
+
+	msg := message.New([][]byte{
+		[]byte(`{"foo":"bar","id":123}`),
+		[]byte(`{"foo":"baz","id":456}`),
+	})
+
+	start := time.Now()
+	if err := k.Write(msg); err != types.ErrTimeout {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrTimeout)
+	}
+	if exp, act := 3, calls; exp != act {
+		t.Errorf("Wrong count of calls: %v != %v", act, exp)
+	}
+	if elapsed := time.Since(start); elapsed < time.Millisecond*40 {
+		t.Errorf("Expected backoff between retries, write took %v", elapsed)
+	}
+
+	// The backoff is reset between writes.
+	calls = 0
+	if err := k.Write(msg); err != types.ErrTimeout {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrTimeout)
+	}
+	if exp, act := 3, calls; exp != act {
+		t.Errorf("Wrong count of calls: %v != %v", act, exp)
+	}
+}
+
+func TestKinesisWritePersistentError(t *testing.T) {
+	t.Parallel()
+	var calls int
+	k := newMockKinesisWriter(
+		backoff.WithMaxRetries(backoff.NewConstantBackOff(time.Millisecond*20), 2),
+		func(input *kinesis.PutRecordsInput) (*kinesis.PutRecordsOutput, error) {
+			calls++
+			return nil, errors.New(kinesis.ErrCodeProvisionedThroughputExceededException)
+		},
+	)
+
+	msg := message.New([][]byte{[]byte(`{"foo":"bar","id":123}`)})
+
+	start := time.Now()
+	if err := k.Write(msg); err == nil || err.Error() != kinesis.ErrCodeProvisionedThroughputExceededException {
+		t.Errorf("Wrong error: %v", err)
+	}
+	if exp, act := 3, calls; exp != act {
+		t.Errorf("Wrong count of calls: %v != %v", act, exp)
+	}
+	if elapsed := time.Since(start); elapsed < time.Millisecond*40 {
+		t.Errorf("Expected backoff between retries, write took %v", elapsed)
+	}
+
+	calls = 0
+	if err := k.Write(msg); err == nil {
+		t.Error("Expected error from persistent throttling")
+	}
+	if exp, act := 3, calls; exp != act {
+		t.Errorf("Wrong count of calls: %v != %v", act, exp)
+	}
+}
+
 func TestKinesisIntegration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping integration test in short mode")
@@ -0,0 +1,265 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/go-redis/redis"
+)
+
+//------------------------------------------------------------------------------
+
+// RedisConfig contains configuration fields for the Redis output type.
+type RedisConfig struct {
+	URL       string `json:"url" yaml:"url"`
+	Operation string `json:"operation" yaml:"operation"`
+	Key       string `json:"key" yaml:"key"`
+	Field     string `json:"field" yaml:"field"`
+	Value     string `json:"value" yaml:"value"`
+	Score     string `json:"score" yaml:"score"`
+}
+
+// NewRedisConfig creates a new RedisConfig with default values.
+func NewRedisConfig() RedisConfig {
+	return RedisConfig{
+		URL:       "tcp://localhost:6379",
+		Operation: "hset",
+		Key:       "benthos_key",
+		Field:     "${!count:items}-${!timestamp_unix_nano}",
+		Value:     "${!content}",
+		Score:     "${!timestamp_unix}",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type redisOperator func(client *redis.Client, r *Redis, msg types.Message) error
+
+func redisHSetOperator(client *redis.Client, r *Redis, msg types.Message) error {
+	return client.HSet(
+		r.key.Get(msg), r.field.Get(msg), r.value.Get(msg),
+	).Err()
+}
+
+func redisSAddOperator(client *redis.Client, r *Redis, msg types.Message) error {
+	return client.SAdd(r.key.Get(msg), r.value.Get(msg)).Err()
+}
+
+func redisZAddOperator(client *redis.Client, r *Redis, msg types.Message) error {
+	score, err := r.scoreOf(msg)
+	if err != nil {
+		return err
+	}
+	return client.ZAdd(r.key.Get(msg), redis.Z{
+		Score:  score,
+		Member: r.value.Get(msg),
+	}).Err()
+}
+
+func strToRedisOperator(str string) (redisOperator, error) {
+	switch str {
+	case "hset":
+		return redisHSetOperator, nil
+	case "sadd":
+		return redisSAddOperator, nil
+	case "zadd":
+		return redisZAddOperator, nil
+	}
+	return nil, fmt.Errorf("redis operation not recognised: %v", str)
+}
+
+//------------------------------------------------------------------------------
+
+// Redis is an output type that writes messages to Redis hashes, sets and
+// sorted sets.
+type Redis struct {
+	log   log.Modular
+	stats metrics.Type
+
+	url  *url.URL
+	conf RedisConfig
+
+	operator redisOperator
+	key      *text.InterpolatedString
+	field    *text.InterpolatedString
+	value    *text.InterpolatedString
+	score    *text.InterpolatedString
+
+	mErrScore metrics.StatCounter
+
+	client  *redis.Client
+	connMut sync.RWMutex
+}
+
+// NewRedis creates a new Redis output type.
+func NewRedis(
+	conf RedisConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*Redis, error) {
+	r := &Redis{
+		log:   log.NewModule(".output.redis"),
+		stats: stats,
+		conf:  conf,
+
+		key:   text.NewInterpolatedString(conf.Key),
+		field: text.NewInterpolatedString(conf.Field),
+		value: text.NewInterpolatedString(conf.Value),
+		score: text.NewInterpolatedString(conf.Score),
+
+		mErrScore: stats.GetCounter("output.redis.score.error"),
+	}
+
+	var err error
+	if r.operator, err = strToRedisOperator(conf.Operation); err != nil {
+		return nil, err
+	}
+	if r.url, err = url.Parse(conf.URL); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect establishes a connection to a Redis server.
+func (r *Redis) Connect() error {
+	r.connMut.Lock()
+	defer r.connMut.Unlock()
+
+	var pass string
+	if r.url.User != nil {
+		pass, _ = r.url.User.Password()
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     r.url.Host,
+		Network:  r.url.Scheme,
+		Password: pass,
+	})
+
+	if _, err := client.Ping().Result(); err != nil {
+		return err
+	}
+
+	r.log.Infof("Writing messages to Redis with operation: %v\n", r.conf.Operation)
+
+	r.client = client
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// Write attempts to write each part of a message with the configured Redis
+// operation.
+func (r *Redis) Write(msg types.Message) error {
+	r.connMut.RLock()
+	client := r.client
+	r.connMut.RUnlock()
+
+	if client == nil {
+		return types.ErrNotConnected
+	}
+
+	var scoreErr error
+	var failed []int
+	if err := msg.Iter(func(i int, p types.Part) error {
+		if err := r.operator(client, r, message.Lock(msg, i)); err != nil {
+			if _, isScoreErr := err.(redisScoreError); isScoreErr {
+				scoreErr = err
+				failed = append(failed, i)
+				return nil
+			}
+			r.disconnect()
+			r.log.Errorf("Error from redis: %v\n", err)
+			return types.ErrNotConnected
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		return &types.ErrPartsFailed{
+			Indexes: failed,
+			Err:     scoreErr,
+		}
+	}
+	return nil
+}
+
+// redisScoreError is returned when the score of a sorted set member cannot be
+// parsed.
+type redisScoreError struct {
+	err error
+}
+
+func (e redisScoreError) Error() string {
+	return e.err.Error()
+}
+
+// scoreOf resolves the score of a sorted set member from a message.
+func (r *Redis) scoreOf(msg types.Message) (float64, error) {
+	scoreStr := r.score.Get(msg)
+	score, err := strconv.ParseFloat(scoreStr, 64)
+	if err != nil {
+		r.mErrScore.Incr(1)
+		r.log.Errorf("Failed to parse score '%v': %v\n", scoreStr, err)
+		return 0, redisScoreError{
+			err: fmt.Errorf("failed to parse score '%v': %v", scoreStr, err),
+		}
+	}
+	return score, nil
+}
+
+// disconnect safely closes a connection to a Redis server.
+func (r *Redis) disconnect() error {
+	r.connMut.Lock()
+	defer r.connMut.Unlock()
+	if r.client != nil {
+		err := r.client.Close()
+		r.client = nil
+		return err
+	}
+	return nil
+}
+
+// CloseAsync shuts down the Redis output and stops processing messages.
+func (r *Redis) CloseAsync() {
+	r.disconnect()
+}
+
+// WaitForClose blocks until the Redis output has closed down.
+func (r *Redis) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
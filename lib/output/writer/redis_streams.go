@@ -28,6 +28,7 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	bmeta "github.com/Jeffail/benthos/lib/util/metadata"
 	"github.com/go-redis/redis"
 )
 
@@ -35,10 +36,11 @@ import (
 
 // RedisStreamsConfig contains configuration fields for the RedisStreams output type.
 type RedisStreamsConfig struct {
-	URL          string `json:"url" yaml:"url"`
-	Stream       string `json:"stream" yaml:"stream"`
-	BodyKey      string `json:"body_key" yaml:"body_key"`
-	MaxLenApprox int64  `json:"max_length" yaml:"max_length"`
+	URL          string       `json:"url" yaml:"url"`
+	Stream       string       `json:"stream" yaml:"stream"`
+	BodyKey      string       `json:"body_key" yaml:"body_key"`
+	MaxLenApprox int64        `json:"max_length" yaml:"max_length"`
+	Metadata     bmeta.Config `json:"metadata" yaml:"metadata"`
 }
 
 // NewRedisStreamsConfig creates a new RedisStreamsConfig with default values.
@@ -48,6 +50,7 @@ func NewRedisStreamsConfig() RedisStreamsConfig {
 		Stream:       "benthos_stream",
 		BodyKey:      "body",
 		MaxLenApprox: 0,
+		Metadata:     bmeta.NewConfig(),
 	}
 }
 
@@ -60,6 +63,7 @@ type RedisStreams struct {
 
 	url  *url.URL
 	conf RedisStreamsConfig
+	meta *bmeta.Filter
 
 	client  *redis.Client
 	connMut sync.RWMutex
@@ -83,6 +87,9 @@ func NewRedisStreams(
 	if err != nil {
 		return nil, err
 	}
+	if r.meta, err = conf.Metadata.Get(); err != nil {
+		return nil, err
+	}
 
 	return r, nil
 }
@@ -128,7 +135,7 @@ func (r *RedisStreams) Write(msg types.Message) error {
 
 	return msg.Iter(func(i int, p types.Part) error {
 		values := map[string]interface{}{}
-		p.Metadata().Iter(func(k, v string) error {
+		r.meta.Iter(p.Metadata(), func(k, v string) error {
 			values[k] = v
 			return nil
 		})
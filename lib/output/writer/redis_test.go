@@ -0,0 +1,78 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/go-redis/redis"
+)
+
+//------------------------------------------------------------------------------
+
+func TestRedisBadScore(t *testing.T) {
+	conf := NewRedisConfig()
+	conf.Operation = "zadd"
+	conf.Score = "${!json_field:score}"
+
+	r, err := NewRedis(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{
+		[]byte(`{"score":5.5}`),
+		[]byte(`{"score":"nope"}`),
+	})
+
+	if score, err := r.scoreOf(message.Lock(msg, 0)); err != nil {
+		t.Error(err)
+	} else if exp, act := 5.5, score; exp != act {
+		t.Errorf("Wrong score: %v != %v", act, exp)
+	}
+	if _, err = r.scoreOf(message.Lock(msg, 1)); err == nil {
+		t.Error("Expected error from bad score")
+	}
+
+	// Parts with bad scores are rejected before reaching Redis, so the client
+	// does not need a server.
+	r.client = redis.NewClient(&redis.Options{Addr: "localhost:1"})
+	defer r.client.Close()
+
+	err = r.Write(message.New([][]byte{
+		[]byte(`{"score":"nope"}`),
+		[]byte(`{}`),
+	}))
+	pErr, ok := err.(*types.ErrPartsFailed)
+	if !ok {
+		t.Fatalf("Wrong error type returned: %T", err)
+	}
+	if exp, act := []int{0, 1}, pErr.Indexes; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong failed indexes: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,188 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/codec"
+	btls "github.com/Jeffail/benthos/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+// SocketConfig contains configuration fields for the Socket output type.
+type SocketConfig struct {
+	Network   string      `json:"network" yaml:"network"`
+	Address   string      `json:"address" yaml:"address"`
+	Codec     string      `json:"codec" yaml:"codec"`
+	Delimiter string      `json:"delimiter" yaml:"delimiter"`
+	TimeoutMS int64       `json:"timeout_ms" yaml:"timeout_ms"`
+	TLS       btls.Config `json:"tls" yaml:"tls"`
+}
+
+// NewSocketConfig creates a new SocketConfig with default values.
+func NewSocketConfig() SocketConfig {
+	return SocketConfig{
+		Network:   "tcp",
+		Address:   "",
+		Codec:     "lines",
+		Delimiter: "",
+		TimeoutMS: 5000,
+		TLS:       btls.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Socket is an output type that writes framed messages to a TCP or Unix
+// socket connection.
+type Socket struct {
+	log   log.Modular
+	stats metrics.Type
+
+	conf    SocketConfig
+	frame   codec.FrameFunc
+	tlsConf *tls.Config
+	timeout time.Duration
+
+	conn    net.Conn
+	connMut sync.Mutex
+
+	mWriteErr metrics.StatCounter
+}
+
+// NewSocket creates a new Socket output type.
+func NewSocket(conf SocketConfig, log log.Modular, stats metrics.Type) (*Socket, error) {
+	s := Socket{
+		log:     log.NewModule(".output.socket"),
+		stats:   stats,
+		conf:    conf,
+		timeout: time.Duration(conf.TimeoutMS) * time.Millisecond,
+
+		mWriteErr: stats.GetCounter("output.socket.write.error"),
+	}
+
+	if len(conf.Address) == 0 {
+		return nil, errors.New("an address must be specified")
+	}
+
+	switch conf.Network {
+	case "tcp", "unix":
+	default:
+		return nil, fmt.Errorf("network not recognised: %v", conf.Network)
+	}
+
+	var err error
+	if s.frame, err = codec.NewFrameFunc(conf.Codec, conf.Delimiter); err != nil {
+		return nil, err
+	}
+	if conf.TLS.Enabled {
+		if s.tlsConf, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+	return &s, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to establish a connection to the target socket.
+func (s *Socket) Connect() error {
+	s.connMut.Lock()
+	defer s.connMut.Unlock()
+
+	if s.conn != nil {
+		return nil
+	}
+
+	dialer := &net.Dialer{Timeout: s.timeout}
+
+	var err error
+	if s.tlsConf != nil {
+		s.conn, err = tls.DialWithDialer(dialer, s.conf.Network, s.conf.Address, s.tlsConf)
+	} else {
+		s.conn, err = dialer.Dial(s.conf.Network, s.conf.Address)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.log.Infof("Sending messages over %v socket to: %v\n", s.conf.Network, s.conf.Address)
+	return nil
+}
+
+// Write attempts to write a message to the socket, where each part is framed
+// with the configured codec. If the write fails the connection is dropped and
+// types.ErrNotConnected is returned so that it can be reestablished.
+func (s *Socket) Write(msg types.Message) error {
+	buf := bytes.Buffer{}
+	if err := msg.Iter(func(i int, p types.Part) error {
+		return s.frame(&buf, p.Get())
+	}); err != nil {
+		return err
+	}
+
+	s.connMut.Lock()
+	defer s.connMut.Unlock()
+
+	if s.conn == nil {
+		return types.ErrNotConnected
+	}
+
+	if s.timeout > 0 {
+		s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+	}
+	if _, err := s.conn.Write(buf.Bytes()); err != nil {
+		s.mWriteErr.Incr(1)
+		s.log.Errorf("Failed to write to socket: %v\n", err)
+		s.conn.Close()
+		s.conn = nil
+		return types.ErrNotConnected
+	}
+	return nil
+}
+
+// CloseAsync shuts down the Socket output and stops processing messages.
+func (s *Socket) CloseAsync() {
+	s.connMut.Lock()
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	s.connMut.Unlock()
+}
+
+// WaitForClose blocks until the Socket output has closed down.
+func (s *Socket) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,207 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// acceptSocketData accepts connections on a listener and sends all data read
+// from each connection once it is closed.
+func acceptSocketData(t *testing.T, ln net.Listener) <-chan string {
+	t.Helper()
+
+	dataChan := make(chan string)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				data, _ := ioutil.ReadAll(conn)
+				conn.Close()
+				dataChan <- string(data)
+			}()
+		}
+	}()
+	return dataChan
+}
+
+func readSocketData(t *testing.T, dataChan <-chan string) string {
+	t.Helper()
+
+	select {
+	case data := <-dataChan:
+		return data
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for data")
+	}
+	return ""
+}
+
+func TestSocketTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	dataChan := acceptSocketData(t, ln)
+
+	conf := NewSocketConfig()
+	conf.Address = ln.Addr().String()
+
+	w, err := NewSocket(conf, log.Noop(), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = w.Write(message.New([][]byte{[]byte("foo"), []byte("bar")})); err != nil {
+		t.Error(err)
+	}
+	if err = w.Write(message.New([][]byte{[]byte("baz")})); err != nil {
+		t.Error(err)
+	}
+	w.CloseAsync()
+
+	if exp, act := "foo\nbar\nbaz\n", readSocketData(t, dataChan); exp != act {
+		t.Errorf("Wrong data: %q != %q", act, exp)
+	}
+}
+
+func TestSocketUnixLengthPrefixed(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_socket_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	conf := NewSocketConfig()
+	conf.Network = "unix"
+	conf.Address = filepath.Join(tmpDir, "benthos.sock")
+	conf.Codec = "length_prefixed"
+
+	ln, err := net.Listen("unix", conf.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	dataChan := acceptSocketData(t, ln)
+
+	w, err := NewSocket(conf, log.Noop(), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = w.Write(message.New([][]byte{[]byte("foo"), []byte("bar\nbaz")})); err != nil {
+		t.Error(err)
+	}
+	w.CloseAsync()
+
+	if exp, act := "\x00\x00\x00\x03foo\x00\x00\x00\x07bar\nbaz", readSocketData(t, dataChan); exp != act {
+		t.Errorf("Wrong data: %q != %q", act, exp)
+	}
+}
+
+func TestSocketReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	dataChan := acceptSocketData(t, ln)
+
+	conf := NewSocketConfig()
+	conf.Address = ln.Addr().String()
+
+	w, err := NewSocket(conf, log.Noop(), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.CloseAsync()
+
+	if err = w.Write(message.New([][]byte{[]byte("foo")})); err != types.ErrNotConnected {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrNotConnected)
+	}
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Break the connection without the writer knowing.
+	w.conn.Close()
+	if err = w.Write(message.New([][]byte{[]byte("foo")})); err != types.ErrNotConnected {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrNotConnected)
+	}
+	if exp, act := "", readSocketData(t, dataChan); exp != act {
+		t.Errorf("Wrong data: %q != %q", act, exp)
+	}
+
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Write(message.New([][]byte{[]byte("foo")})); err != nil {
+		t.Error(err)
+	}
+	w.CloseAsync()
+
+	if exp, act := "foo\n", readSocketData(t, dataChan); exp != act {
+		t.Errorf("Wrong data: %q != %q", act, exp)
+	}
+}
+
+func TestSocketBadConfig(t *testing.T) {
+	conf := NewSocketConfig()
+	if _, err := NewSocket(conf, log.Noop(), metrics.DudType{}); err == nil {
+		t.Error("Expected error from empty address")
+	}
+
+	conf.Address = "localhost:4195"
+	conf.Network = "udp"
+	if _, err := NewSocket(conf, log.Noop(), metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad network")
+	}
+
+	conf.Network = "tcp"
+	conf.Codec = "nope"
+	if _, err := NewSocket(conf, log.Noop(), metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad codec")
+	}
+}
+
+//------------------------------------------------------------------------------
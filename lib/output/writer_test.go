@@ -452,9 +452,10 @@ func TestWriterHappyPath(t *testing.T) {
 
 	writerImpl.resToSnd = expErr
 
+	stats := metrics.NewLocal()
 	w, err := NewWriter(
 		"foo", writerImpl,
-		log.New(os.Stdout, logConfig), metrics.DudType{},
+		log.New(os.Stdout, logConfig), stats,
 	)
 	if err != nil {
 		t.Error(err)
@@ -508,6 +509,14 @@ func TestWriterHappyPath(t *testing.T) {
 	if act := message.GetAllBytes(writerImpl.msgRcvd); !reflect.DeepEqual(exp, act) {
 		t.Errorf("Wrong message sent: %v != %v", act, exp)
 	}
+
+	timings := stats.GetTimings()
+	if exp, act := int64(6), timings["output.foo.size"]; exp != act {
+		t.Errorf("Wrong size metric: %v != %v", act, exp)
+	}
+	if act := timings["output.foo.latency"]; act <= 0 {
+		t.Errorf("Expected positive latency metric: %v", act)
+	}
 }
 
 func TestWriterSadPath(t *testing.T) {
@@ -578,4 +587,122 @@ func TestWriterSadPath(t *testing.T) {
 	}
 }
 
+// mockPartialWriter passes each written message to the test before returning
+// the error it is given.
+type mockPartialWriter struct {
+	msgChan chan types.Message
+	errChan chan error
+}
+
+func (w *mockPartialWriter) Connect() error {
+	return nil
+}
+func (w *mockPartialWriter) Write(msg types.Message) error {
+	w.msgChan <- msg
+	return <-w.errChan
+}
+func (w *mockPartialWriter) CloseAsync() {}
+func (w *mockPartialWriter) WaitForClose(time.Duration) error {
+	return nil
+}
+
+func TestWriterPartialFailure(t *testing.T) {
+	t.Parallel()
+
+	writerImpl := &mockPartialWriter{
+		msgChan: make(chan types.Message),
+		errChan: make(chan error),
+	}
+
+	w, err := NewWriter(
+		"foo", writerImpl,
+		log.Noop(), metrics.DudType{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	if err = w.Consume(msgChan); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{
+		[]byte("foo"), []byte("bar"), []byte("baz"), []byte("qux"),
+	})
+
+	type write struct {
+		exp [][]byte
+		err error
+	}
+
+	// Sends a single transaction and expects a sequence of writes from it,
+	// followed by a response.
+	sendAndExpect := func(m types.Message, writes []write, resErr string) {
+		t.Helper()
+		go func() {
+			select {
+			case msgChan <- types.NewTransaction(m, resChan):
+			case <-time.After(time.Second):
+				t.Error("Timed out")
+			}
+		}()
+		for _, wr := range writes {
+			select {
+			case m := <-writerImpl.msgChan:
+				if act := message.GetAllBytes(m); !reflect.DeepEqual(wr.exp, act) {
+					t.Errorf("Wrong message sent: %s != %s", act, wr.exp)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Timed out")
+			}
+			select {
+			case writerImpl.errChan <- wr.err:
+			case <-time.After(time.Second):
+				t.Fatal("Timed out")
+			}
+		}
+		select {
+		case res := <-resChan:
+			var act string
+			if err := res.Error(); err != nil {
+				act = err.Error()
+			}
+			if act != resErr {
+				t.Errorf("Wrong response: %v != %v", act, resErr)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	all := [][]byte{[]byte("foo"), []byte("bar"), []byte("baz"), []byte("qux")}
+
+	// Failed parts are retried within the transaction.
+	sendAndExpect(msg, []write{
+		{exp: all, err: &types.ErrPartsFailed{Indexes: []int{1, 3}, Err: errors.New("nope")}},
+		{exp: [][]byte{[]byte("bar"), []byte("qux")}, err: nil},
+	}, "")
+
+	// Retries stop once an attempt makes no progress.
+	sendAndExpect(msg, []write{
+		{exp: all, err: &types.ErrPartsFailed{Indexes: []int{1, 3}, Err: errors.New("nope")}},
+		{exp: [][]byte{[]byte("bar"), []byte("qux")}, err: &types.ErrPartsFailed{Indexes: []int{1}, Err: errors.New("nope")}},
+		{exp: [][]byte{[]byte("qux")}, err: &types.ErrPartsFailed{Indexes: []int{0}, Err: errors.New("nope")}},
+	}, "failed to send 1 parts [3] from message: nope")
+
+	// A resend of the same message is a new transaction and is written in
+	// full.
+	sendAndExpect(msg, []write{
+		{exp: all, err: nil},
+	}, "")
+
+	w.CloseAsync()
+	if err = w.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
 //------------------------------------------------------------------------------
@@ -98,6 +98,7 @@ func New(
 			if err != nil {
 				return nil, fmt.Errorf("failed to create processor '%v': %v", procConf.Type, err)
 			}
+			processors[i] = processor.WrapTimed("pipeline", i, procConf, processors[i], stats)
 		}
 		for j, procCtor := range processorCtors {
 			var err error
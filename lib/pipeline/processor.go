@@ -44,6 +44,7 @@ type Processor struct {
 	stats metrics.Type
 
 	msgProcessors []types.Processor
+	flushPeriod   time.Duration
 
 	messagesOut chan types.Transaction
 	responsesIn chan types.Response
@@ -66,6 +67,7 @@ func NewProcessor(
 	return &Processor{
 		running:       1,
 		msgProcessors: msgProcessors,
+		flushPeriod:   time.Second,
 		log:           log.NewModule(".pipeline.processor"),
 		stats:         stats,
 		mSndSucc:      stats.GetCounter("pipeline.processor.send.success"),
@@ -91,6 +93,18 @@ func (p *Processor) loop() {
 		mProcDropped = p.stats.GetCounter("pipeline.processor.dropped")
 	)
 
+	// Processors that can produce messages without input are flushed
+	// periodically.
+	var flushChan <-chan time.Time
+	for _, proc := range p.msgProcessors {
+		if _, ok := proc.(types.Flusher); ok {
+			ticker := time.NewTicker(p.flushPeriod)
+			defer ticker.Stop()
+			flushChan = ticker.C
+			break
+		}
+	}
+
 	var open bool
 	for atomic.LoadInt32(&p.running) == 1 {
 		var tran types.Transaction
@@ -99,22 +113,15 @@ func (p *Processor) loop() {
 			if !open {
 				return
 			}
+		case <-flushChan:
+			p.flush()
+			continue
 		case <-p.closeChan:
 			return
 		}
 		mProcCount.Incr(1)
 
-		resultMsgs := []types.Message{tran.Payload}
-		var resultRes types.Response
-		for i := 0; len(resultMsgs) > 0 && i < len(p.msgProcessors); i++ {
-			var nextResultMsgs []types.Message
-			for _, m := range resultMsgs {
-				var rMsgs []types.Message
-				rMsgs, resultRes = p.msgProcessors[i].ProcessMessage(m)
-				nextResultMsgs = append(nextResultMsgs, rMsgs...)
-			}
-			resultMsgs = nextResultMsgs
-		}
+		resultMsgs, resultRes := p.processMessages(0, []types.Message{tran.Payload})
 
 		if len(resultMsgs) == 0 {
 			mProcDropped.Incr(1)
@@ -138,6 +145,39 @@ func (p *Processor) loop() {
 	}
 }
 
+// processMessages applies the processors from an index onwards to a slice of
+// messages.
+func (p *Processor) processMessages(from int, msgs []types.Message) ([]types.Message, types.Response) {
+	var res types.Response
+	for i := from; len(msgs) > 0 && i < len(p.msgProcessors); i++ {
+		var nextMsgs []types.Message
+		for _, m := range msgs {
+			var rMsgs []types.Message
+			rMsgs, res = p.msgProcessors[i].ProcessMessage(m)
+			nextMsgs = append(nextMsgs, rMsgs...)
+		}
+		msgs = nextMsgs
+	}
+	return msgs, res
+}
+
+// flush collects the messages of any processors that are flushers, applies the
+// processors that follow them, and sends the results onwards. Flushed messages
+// have no origin and therefore the resulting response is discarded.
+func (p *Processor) flush() {
+	for i, proc := range p.msgProcessors {
+		f, ok := proc.(types.Flusher)
+		if !ok {
+			continue
+		}
+		msgs, _ := p.processMessages(i+1, f.Flush())
+		if len(msgs) == 0 {
+			continue
+		}
+		p.dispatchMessages(msgs, make(chan types.Response, 1))
+	}
+}
+
 // dispatchMessages attempts to send a multiple messages results of processors
 // over the shared messages channel. This send is retried until success.
 func (p *Processor) dispatchMessages(msgs []types.Message, ogResChan chan<- types.Response) {
@@ -225,20 +265,35 @@ func (p *Processor) TransactionChan() <-chan types.Transaction {
 	return p.messagesOut
 }
 
-// CloseAsync shuts down the pipeline and stops processing messages.
+// CloseAsync shuts down the pipeline and stops processing messages. Any
+// processors that are also closable are closed in order to interrupt blocking
+// calls.
 func (p *Processor) CloseAsync() {
 	if atomic.CompareAndSwapInt32(&p.running, 1, 0) {
 		close(p.closeChan)
+		for _, proc := range p.msgProcessors {
+			if c, ok := proc.(types.Closable); ok {
+				c.CloseAsync()
+			}
+		}
 	}
 }
 
 // WaitForClose blocks until the StackBuffer output has closed down.
 func (p *Processor) WaitForClose(timeout time.Duration) error {
+	stopBy := time.Now().Add(timeout)
 	select {
 	case <-p.closed:
 	case <-time.After(timeout):
 		return types.ErrTimeout
 	}
+	for _, proc := range p.msgProcessors {
+		if c, ok := proc.(types.Closable); ok {
+			if err := c.WaitForClose(time.Until(stopBy)); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -53,6 +53,50 @@ func (m *mockMsgProcessor) ProcessMessage(msg types.Message) ([]types.Message, t
 	return msgs[:], nil
 }
 
+type mockClosableProcessor struct {
+	closeChan chan struct{}
+}
+
+func (m *mockClosableProcessor) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	<-m.closeChan
+	msgs := [1]types.Message{msg}
+	return msgs[:], nil
+}
+
+func (m *mockClosableProcessor) CloseAsync() {
+	close(m.closeChan)
+}
+
+func (m *mockClosableProcessor) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+func TestProcessorClosesProcessors(t *testing.T) {
+	mockProc := &mockClosableProcessor{closeChan: make(chan struct{})}
+
+	proc := NewProcessor(
+		log.New(os.Stdout, log.Config{LogLevel: "NONE"}),
+		metrics.DudType{},
+		mockProc,
+	)
+
+	tChan, resChan := make(chan types.Transaction), make(chan types.Response)
+	if err := proc.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	proc.CloseAsync()
+	if err := proc.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestProcessorPipeline(t *testing.T) {
 	mockProc := &mockMsgProcessor{dropChan: make(chan bool)}
 
@@ -369,3 +413,76 @@ func TestProcessorMultiMsgsOddSync(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+type mockFlushProcessor struct {
+	flushChan chan []types.Message
+}
+
+func (m *mockFlushProcessor) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	return nil, response.NewAck()
+}
+
+func (m *mockFlushProcessor) Flush() []types.Message {
+	select {
+	case msgs := <-m.flushChan:
+		return msgs
+	default:
+	}
+	return nil
+}
+
+func TestProcessorFlush(t *testing.T) {
+	flushProc := &mockFlushProcessor{flushChan: make(chan []types.Message)}
+
+	proc := NewProcessor(
+		log.New(os.Stdout, log.Config{LogLevel: "NONE"}),
+		metrics.DudType{},
+		flushProc,
+		&mockMultiMsgProcessor{N: 2},
+	)
+	proc.flushPeriod = time.Millisecond
+
+	// No messages are sent to the pipeline.
+	if err := proc.Consume(make(chan types.Transaction)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case flushProc.flushChan <- []types.Message{message.New([][]byte{[]byte("foo")})}:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	expMsgs := map[string]struct{}{
+		"test0": {},
+		"test1": {},
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case procT, open := <-proc.TransactionChan():
+			if !open {
+				t.Fatal("Closed early")
+			}
+			act := string(procT.Payload.Get(0).Get())
+			if _, exists := expMsgs[act]; !exists {
+				t.Errorf("Unexpected message: %v", act)
+			}
+			delete(expMsgs, act)
+			select {
+			case procT.ResponseChan <- response.NewAck():
+			case <-time.After(time.Second):
+				t.Fatal("Timed out")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+	if len(expMsgs) != 0 {
+		t.Errorf("Expected messages were not received: %v", expMsgs)
+	}
+
+	proc.CloseAsync()
+	if err := proc.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
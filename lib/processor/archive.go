@@ -43,16 +43,26 @@ func init() {
 		constructor: NewArchive,
 		description: `
 Archives all the parts of a message into a single part according to the selected
-archive type. Supported archive types are: tar, zip, binary, lines.
+archive type. Supported archive types are: tar, zip, binary, lines, json_array
+and concatenate.
 
 Some archive types (such as tar, zip) treat each archive item (message part) as a
 file with a path. Since message parts only contain raw data a unique path must
 be generated for each part. This can be done by using function interpolations on
-the 'path' field as described [here](../config_interpolation.md#functions). For
-types that aren't file based (such as binary) the file field is ignored.
+the 'path' field as described [here](../config_interpolation.md#functions),
+which are resolved for each message part individually. For types that aren't
+file based (such as binary) the path field is ignored.
 
-The resulting archived message adopts the metadata of the _first_ message part
-of the batch.`,
+The json_array format parses each message part as JSON and combines them into a
+single array, and the concatenate format joins the raw contents of parts without
+a delimiter.
+
+The resulting archived message adopts the metadata of all message parts of the
+batch merged together. When parts share a metadata key the value of the
+_earliest_ part takes precedence. Therefore, if any part of the batch has been
+flagged as having failed a processing step then so is the archived message.
+
+Messages with zero parts are passed through unchanged.`,
 	}
 }
 
@@ -76,7 +86,7 @@ func NewArchiveConfig() ArchiveConfig {
 
 type archiveFunc func(hFunc headerFunc, msg types.Message) (types.Part, error)
 
-type headerFunc func(body types.Part) os.FileInfo
+type headerFunc func(index int, body types.Part) os.FileInfo
 
 func tarArchive(hFunc headerFunc, msg types.Message) (types.Part, error) {
 	buf := &bytes.Buffer{}
@@ -84,7 +94,7 @@ func tarArchive(hFunc headerFunc, msg types.Message) (types.Part, error) {
 
 	// Iterate through the parts of the message.
 	err := msg.Iter(func(i int, part types.Part) error {
-		hdr, err := tar.FileInfoHeader(hFunc(part), "")
+		hdr, err := tar.FileInfoHeader(hFunc(i, part), "")
 		if err != nil {
 			return err
 		}
@@ -101,8 +111,7 @@ func tarArchive(hFunc headerFunc, msg types.Message) (types.Part, error) {
 	if err != nil {
 		return nil, err
 	}
-	return message.NewPart(buf.Bytes()).
-		SetMetadata(msg.Get(0).Metadata().Copy()), nil
+	return message.NewPart(buf.Bytes()), nil
 }
 
 func zipArchive(hFunc headerFunc, msg types.Message) (types.Part, error) {
@@ -111,7 +120,7 @@ func zipArchive(hFunc headerFunc, msg types.Message) (types.Part, error) {
 
 	// Iterate through the parts of the message.
 	err := msg.Iter(func(i int, part types.Part) error {
-		h, err := zip.FileInfoHeader(hFunc(part))
+		h, err := zip.FileInfoHeader(hFunc(i, part))
 		if err != nil {
 			return err
 		}
@@ -131,13 +140,11 @@ func zipArchive(hFunc headerFunc, msg types.Message) (types.Part, error) {
 	if err != nil {
 		return nil, err
 	}
-	return message.NewPart(buf.Bytes()).
-		SetMetadata(msg.Get(0).Metadata().Copy()), nil
+	return message.NewPart(buf.Bytes()), nil
 }
 
 func binaryArchive(hFunc headerFunc, msg types.Message) (types.Part, error) {
-	return message.NewPart(message.ToBytes(msg)).
-		SetMetadata(msg.Get(0).Metadata().Copy()), nil
+	return message.NewPart(message.ToBytes(msg)), nil
 }
 
 func linesArchive(hFunc headerFunc, msg types.Message) (types.Part, error) {
@@ -146,8 +153,36 @@ func linesArchive(hFunc headerFunc, msg types.Message) (types.Part, error) {
 		tmpParts[i] = part.Get()
 		return nil
 	})
-	return message.NewPart(bytes.Join(tmpParts, []byte("\n"))).
-		SetMetadata(msg.Get(0).Metadata().Copy()), nil
+	return message.NewPart(bytes.Join(tmpParts, []byte("\n"))), nil
+}
+
+func concatenateArchive(hFunc headerFunc, msg types.Message) (types.Part, error) {
+	tmpParts := make([][]byte, msg.Len())
+	msg.Iter(func(i int, part types.Part) error {
+		tmpParts[i] = part.Get()
+		return nil
+	})
+	return message.NewPart(bytes.Join(tmpParts, nil)), nil
+}
+
+func jsonArrayArchive(hFunc headerFunc, msg types.Message) (types.Part, error) {
+	jArray := make([]interface{}, msg.Len())
+	if err := msg.Iter(func(i int, part types.Part) error {
+		jDoc, err := part.JSON()
+		if err != nil {
+			return fmt.Errorf("failed to parse message part %v as JSON: %v", i, err)
+		}
+		jArray[i] = jDoc
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	newPart := message.NewPart(nil)
+	if err := newPart.SetJSON(jArray); err != nil {
+		return nil, err
+	}
+	return newPart, nil
 }
 
 func strToArchiver(str string) (archiveFunc, error) {
@@ -160,6 +195,10 @@ func strToArchiver(str string) (archiveFunc, error) {
 		return binaryArchive, nil
 	case "lines":
 		return linesArchive, nil
+	case "json_array":
+		return jsonArrayArchive, nil
+	case "concatenate":
+		return concatenateArchive, nil
 	}
 	return nil, fmt.Errorf("archive format not recognised: %v", str)
 }
@@ -240,11 +279,11 @@ func (f fakeInfo) Sys() interface{} {
 	return nil
 }
 
-func (d *Archive) createHeaderFunc(msg types.Message) func(types.Part) os.FileInfo {
-	return func(body types.Part) os.FileInfo {
+func (d *Archive) createHeaderFunc(msg types.Message) headerFunc {
+	return func(index int, body types.Part) os.FileInfo {
 		path := d.conf.Path
 		if d.interpolatePath {
-			path = string(text.ReplaceFunctionVariables(msg, d.pathBytes))
+			path = string(text.ReplaceFunctionVariables(message.Lock(msg, index), d.pathBytes))
 		}
 		return fakeInfo{
 			name: path,
@@ -254,6 +293,27 @@ func (d *Archive) createHeaderFunc(msg types.Message) func(types.Part) os.FileIn
 	}
 }
 
+// mergeMetadata combines the metadata of all parts of a message, where values
+// of earlier parts take precedence over later parts.
+func mergeMetadata(msg types.Message) types.Metadata {
+	merged := msg.Get(0).Metadata().Copy()
+	keys := map[string]struct{}{}
+	merged.Iter(func(k, v string) error {
+		keys[k] = struct{}{}
+		return nil
+	})
+	for i := 1; i < msg.Len(); i++ {
+		msg.Get(i).Metadata().Iter(func(k, v string) error {
+			if _, exists := keys[k]; !exists {
+				keys[k] = struct{}{}
+				merged.Set(k, v)
+			}
+			return nil
+		})
+	}
+	return merged
+}
+
 //------------------------------------------------------------------------------
 
 // ProcessMessage applies the processor to a message, either creating >0
@@ -263,7 +323,8 @@ func (d *Archive) ProcessMessage(msg types.Message) ([]types.Message, types.Resp
 
 	if msg.Len() == 0 {
 		d.mSkipped.Incr(1)
-		return nil, response.NewAck()
+		msgs := [1]types.Message{msg}
+		return msgs[:], nil
 	}
 
 	newPart, err := d.archive(d.createHeaderFunc(msg), msg)
@@ -277,7 +338,7 @@ func (d *Archive) ProcessMessage(msg types.Message) ([]types.Message, types.Resp
 	d.mSent.Incr(1)
 
 	newMsg := message.New(nil)
-	newMsg.Append(newPart)
+	newMsg.Append(newPart.SetMetadata(mergeMetadata(msg)))
 
 	msgs := [1]types.Message{newMsg}
 	return msgs[:], nil
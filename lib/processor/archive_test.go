@@ -232,8 +232,136 @@ func TestArchiveEmpty(t *testing.T) {
 		return
 	}
 
-	msgs, _ := proc.ProcessMessage(message.New([][]byte{}))
+	msgs, res := proc.ProcessMessage(message.New([][]byte{}))
+	if len(msgs) != 1 {
+		t.Fatalf("Expected zero part message to pass through: %v", res)
+	}
+	if msgs[0].Len() != 0 {
+		t.Errorf("Expected zero part message, got %v parts", msgs[0].Len())
+	}
+}
+
+func TestArchiveTarPathPerPart(t *testing.T) {
+	conf := NewConfig()
+	conf.Archive.Format = "tar"
+	conf.Archive.Path = "${!metadata:name}.txt"
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	proc, err := NewArchive(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	input.Get(0).Metadata().Set("name", "first")
+	input.Get(1).Metadata().Set("name", "second")
+
+	msgs, res := proc.ProcessMessage(input)
+	if len(msgs) != 1 {
+		t.Fatalf("Archive failed: %v", res)
+	}
+
+	names := []string{}
+	tr := tar.NewReader(bytes.NewReader(msgs[0].Get(0).Get()))
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, h.Name)
+	}
+	if exp := []string{"first.txt", "second.txt"}; !reflect.DeepEqual(exp, names) {
+		t.Errorf("Unexpected names: %v != %v", names, exp)
+	}
+}
+
+func TestArchiveJSONArray(t *testing.T) {
+	conf := NewConfig()
+	conf.Archive.Format = "json_array"
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	proc, err := NewArchive(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"foo":"bar"}`),
+		[]byte(`5`),
+		[]byte(`"baz"`),
+	}))
+	if len(msgs) != 1 {
+		t.Fatalf("Archive failed: %v", res)
+	}
+	if exp, act := `[{"foo":"bar"},5,"baz"]`, string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Unexpected output: %v != %v", act, exp)
+	}
+
+	msgs, _ = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"foo":"bar"}`),
+		[]byte(`not json`),
+	}))
 	if len(msgs) != 0 {
-		t.Error("Expected failure with zero part message")
+		t.Error("Expected failure with invalid JSON")
+	}
+}
+
+func TestArchiveConcatenate(t *testing.T) {
+	conf := NewConfig()
+	conf.Archive.Format = "concatenate"
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	proc, err := NewArchive(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte("foo"), []byte("bar"), []byte("baz"),
+	}))
+	if len(msgs) != 1 {
+		t.Fatalf("Archive failed: %v", res)
+	}
+	if exp, act := "foobarbaz", string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Unexpected output: %v != %v", act, exp)
+	}
+}
+
+func TestArchiveMetadataMerge(t *testing.T) {
+	conf := NewConfig()
+	conf.Archive.Format = "lines"
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	proc, err := NewArchive(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{[]byte("foo"), []byte("bar"), []byte("baz")})
+	input.Get(0).Metadata().Set("a", "first").Set("b", "")
+	input.Get(1).Metadata().Set("a", "second").Set("b", "second").Set("c", "second")
+	input.Get(2).Metadata().Set("c", "third").Set("d", "third")
+
+	msgs, res := proc.ProcessMessage(input)
+	if len(msgs) != 1 {
+		t.Fatalf("Archive failed: %v", res)
+	}
+
+	exp := map[string]string{
+		"a": "first",
+		"b": "",
+		"c": "second",
+		"d": "third",
+	}
+	act := map[string]string{}
+	msgs[0].Get(0).Metadata().Iter(func(k, v string) error {
+		act[k] = v
+		return nil
+	})
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Unexpected metadata: %v != %v", act, exp)
 	}
 }
@@ -0,0 +1,214 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"fmt"
+
+	"github.com/Jeffail/benthos/lib/bloblang"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeBloblang] = TypeSpec{
+		constructor: NewBloblang,
+		description: `
+Executes a mapping written in Bloblang, a small language for restructuring,
+filtering and enriching documents, against each message part. The mapping is a
+list of assignments, each setting a field of the new document (` + "`root`" + `)
+or a metadata key (` + "`meta`" + `) to the result of a query. Queries read
+from the original part with ` + "`this`" + `, and can call functions and
+methods:
+
+` + "``` yaml" + `
+bloblang:
+  mapping: |
+    root.id = this.user.id
+    root.name = this.user.first + " " + this.user.last
+    root.tags = this.tags.map_each(this.lowercase())
+    root.topic = meta("kafka_topic").or("unknown")
+    root.password = deleted()
+    meta source = "bloblang"
+` + "```" + `
+
+Fields can be removed from the new document by assigning ` + "`deleted()`" + `
+to them, and assigning ` + "`deleted()`" + ` to ` + "`root`" + ` removes the
+part from the batch entirely. If ` + "`root`" + ` is never assigned the contents
+of the part are left unchanged. When the resulting document is a string it is
+written raw, otherwise it is serialised as JSON.
+
+Conditional values are expressed with ` + "`if`" + ` blocks, where an
+` + "`if`" + ` without a matching branch leaves the target unchanged:
+
+` + "``` coffee" + `
+root = this
+root.size = if this.count > 100 { "large" } else { "small" }
+root = if this.type == "noise" { deleted() }
+` + "```" + `
+
+The input part is only parsed as JSON when ` + "`this`" + ` is referenced, so
+mappings that only use functions such as ` + "`content()`" + ` and
+` + "`meta()`" + ` work on any content. If a mapping fails, for example because
+a method receives the wrong type, the part is left unchanged and flagged as
+failed so that it can be routed with the
+` + "[`processor_failed`](../conditions/README.md#processor_failed)" + `
+condition. Errors can also be recovered from inline with the
+` + "`catch`" + ` method.
+
+### Functions
+
+` + bloblang.FunctionsDocs() + `
+
+### Methods
+
+` + bloblang.MethodsDocs(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// BloblangConfig contains configuration fields for the Bloblang processor.
+type BloblangConfig struct {
+	Parts   []int  `json:"parts" yaml:"parts"`
+	Mapping string `json:"mapping" yaml:"mapping"`
+}
+
+// NewBloblangConfig returns a BloblangConfig with default values.
+func NewBloblangConfig() BloblangConfig {
+	return BloblangConfig{
+		Parts:   []int{},
+		Mapping: "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Bloblang is a processor that executes a Bloblang mapping on message parts.
+type Bloblang struct {
+	parts   []int
+	mapping *bloblang.Mapping
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mDeleted   metrics.StatCounter
+	mDropped   metrics.StatCounter
+	mSent      metrics.StatCounter
+	mSentParts metrics.StatCounter
+}
+
+// NewBloblang returns a Bloblang processor.
+func NewBloblang(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	mapping, err := bloblang.NewMapping(conf.Bloblang.Mapping)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mapping: %v", err)
+	}
+	return &Bloblang{
+		parts:   conf.Bloblang.Parts,
+		mapping: mapping,
+
+		log:   log.NewModule(".processor.bloblang"),
+		stats: stats,
+
+		mCount:     stats.GetCounter("processor.bloblang.count"),
+		mErr:       stats.GetCounter("processor.bloblang.error"),
+		mDeleted:   stats.GetCounter("processor.bloblang.parts.deleted"),
+		mDropped:   stats.GetCounter("processor.bloblang.dropped"),
+		mSent:      stats.GetCounter("processor.bloblang.sent"),
+		mSentParts: stats.GetCounter("processor.bloblang.parts.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (b *Bloblang) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	b.mCount.Incr(1)
+
+	lParts := msg.Len()
+	parts := make([]types.Part, lParts)
+	msg.Iter(func(i int, p types.Part) error {
+		parts[i] = p.Copy()
+		return nil
+	})
+
+	targetParts := b.parts
+	if len(targetParts) == 0 {
+		targetParts = make([]int, lParts)
+		for i := range targetParts {
+			targetParts[i] = i
+		}
+	}
+
+	deleted := map[int]struct{}{}
+	for _, index := range targetParts {
+		if index < 0 {
+			index = lParts + index
+		}
+		if index < 0 || index >= lParts {
+			continue
+		}
+
+		newPart, err := b.mapping.MapPart(index, msg)
+		if err != nil {
+			b.mErr.Incr(1)
+			b.log.Debugf("Failed to apply mapping: %v\n", err)
+			FlagErr(parts[index], err)
+			continue
+		}
+		if newPart == nil {
+			b.mDeleted.Incr(1)
+			deleted[index] = struct{}{}
+			continue
+		}
+		parts[index] = newPart
+	}
+
+	newMsg := message.New(nil)
+	for i, p := range parts {
+		if _, exists := deleted[i]; !exists {
+			newMsg.Append(p)
+		}
+	}
+
+	if newMsg.Len() == 0 {
+		b.mDropped.Incr(1)
+		return nil, response.NewAck()
+	}
+
+	b.mSent.Incr(1)
+	b.mSentParts.Incr(int64(newMsg.Len()))
+	msgs := [1]types.Message{newMsg}
+	return msgs[:], nil
+}
+
+//------------------------------------------------------------------------------
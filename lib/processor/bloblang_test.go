@@ -0,0 +1,188 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestBloblangAllParts(t *testing.T) {
+	conf := NewConfig()
+	conf.Bloblang.Mapping = `root.id = this.id
+root.name = this.name.uppercase()
+meta index = batch_index()`
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	proc, err := NewBloblang(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgIn := message.New([][]byte{
+		[]byte(`{"id":1,"name":"foo","other":true}`),
+		[]byte(`{"id":2,"name":"bar"}`),
+	})
+	msgs, res := proc.ProcessMessage(msgIn)
+	if len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+	if res != nil {
+		t.Fatal("Non-nil result")
+	}
+
+	exp := [][]byte{
+		[]byte(`{"id":1,"name":"FOO"}`),
+		[]byte(`{"id":2,"name":"BAR"}`),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	for i, exp := range []string{"0", "1"} {
+		if act := msgs[0].Get(i).Metadata().Get("index"); exp != act {
+			t.Errorf("Wrong metadata: %v != %v", act, exp)
+		}
+	}
+	if exp, act := `{"id":1,"name":"foo","other":true}`, string(msgIn.Get(0).Get()); exp != act {
+		t.Errorf("Input message was modified: %v != %v", act, exp)
+	}
+}
+
+func TestBloblangTargetParts(t *testing.T) {
+	conf := NewConfig()
+	conf.Bloblang.Parts = []int{-1}
+	conf.Bloblang.Mapping = `root = content().uppercase()`
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	proc, err := NewBloblang(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`foo`), []byte(`bar`),
+	}))
+	if len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+	if res != nil {
+		t.Fatal("Non-nil result")
+	}
+
+	exp := [][]byte{[]byte(`foo`), []byte(`BAR`)}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+}
+
+func TestBloblangDelete(t *testing.T) {
+	conf := NewConfig()
+	conf.Bloblang.Mapping = `root = if this.keep { this } else { deleted() }`
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	proc, err := NewBloblang(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"keep":false,"id":0}`),
+		[]byte(`{"keep":true,"id":1}`),
+		[]byte(`{"keep":false,"id":2}`),
+	}))
+	if len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+	if res != nil {
+		t.Fatal("Non-nil result")
+	}
+	exp := [][]byte{[]byte(`{"id":1,"keep":true}`)}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+
+	msgs, res = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"keep":false}`),
+	}))
+	if len(msgs) != 0 {
+		t.Error("Expected message to be dropped")
+	}
+	if res == nil || res.Error() != nil {
+		t.Errorf("Expected ack response: %v", res)
+	}
+}
+
+func TestBloblangErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Bloblang.Mapping = `root.name = this.name.uppercase()`
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	proc, err := NewBloblang(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"name":5}`),
+		[]byte(`not json`),
+		[]byte(`{"name":"foo"}`),
+	}))
+	if len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+	if res != nil {
+		t.Fatal("Non-nil result")
+	}
+
+	exp := [][]byte{
+		[]byte(`{"name":5}`),
+		[]byte(`not json`),
+		[]byte(`{"name":"FOO"}`),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	for i, exp := range []bool{true, true, false} {
+		if act := HasFailed(msgs[0].Get(i)); exp != act {
+			t.Errorf("Wrong fail flag for part %v: %v != %v", i, act, exp)
+		}
+	}
+}
+
+func TestBloblangBadMapping(t *testing.T) {
+	conf := NewConfig()
+	conf.Bloblang.Mapping = `root.foo = this.bar.nope()`
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	if _, err := NewBloblang(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad mapping")
+	}
+}
@@ -22,6 +22,7 @@ package processor
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
@@ -37,7 +38,18 @@ func init() {
 		description: `
 Checks whether each message fits within certain boundaries, and drops messages
 that do not. A metric is incremented for each dropped message and debug logs
-are also provided if enabled.`,
+are also provided if enabled.
+
+The field ` + "`action`" + ` determines what happens to messages that are out of
+bounds. When set to ` + "`drop`" + ` (the default) the message is dropped and
+acknowledged. When set to ` + "`flag`" + ` the message continues through the
+pipeline with all of its parts flagged as failed, allowing it to be routed
+elsewhere using the error handling patterns of the pipeline. Empty messages
+have no parts to flag and are therefore always dropped.
+
+Each violation increments a metric specific to the limit that was exceeded,
+` + "`processor.bounds_check.violation.<limit>`" + `, where ` + "`<limit>`" + ` is
+one of ` + "`max_parts`, `min_parts`, `max_part_size` or `min_part_size`" + `.`,
 	}
 }
 
@@ -46,10 +58,11 @@ are also provided if enabled.`,
 // BoundsCheckConfig contains configuration fields for the BoundsCheck
 // processor.
 type BoundsCheckConfig struct {
-	MaxParts    int `json:"max_parts" yaml:"max_parts"`
-	MinParts    int `json:"min_parts" yaml:"min_parts"`
-	MaxPartSize int `json:"max_part_size" yaml:"max_part_size"`
-	MinPartSize int `json:"min_part_size" yaml:"min_part_size"`
+	MaxParts    int    `json:"max_parts" yaml:"max_parts"`
+	MinParts    int    `json:"min_parts" yaml:"min_parts"`
+	MaxPartSize int    `json:"max_part_size" yaml:"max_part_size"`
+	MinPartSize int    `json:"min_part_size" yaml:"min_part_size"`
+	Action      string `json:"action" yaml:"action"`
 }
 
 // NewBoundsCheckConfig returns a BoundsCheckConfig with default values.
@@ -59,6 +72,7 @@ func NewBoundsCheckConfig() BoundsCheckConfig {
 		MinParts:    1,
 		MaxPartSize: 1 * 1024 * 1024 * 1024, // 1GB
 		MinPartSize: 1,
+		Action:      "drop",
 	}
 }
 
@@ -68,6 +82,7 @@ func NewBoundsCheckConfig() BoundsCheckConfig {
 // and rejects messages if they aren't within them.
 type BoundsCheck struct {
 	conf  Config
+	flag  bool
 	log   log.Modular
 	stats metrics.Type
 
@@ -76,6 +91,8 @@ type BoundsCheck struct {
 	mDroppedEmpty    metrics.StatCounter
 	mDroppedNumParts metrics.StatCounter
 	mDroppedPartSize metrics.StatCounter
+	mFlagged         metrics.StatCounter
+	mViolations      map[string]metrics.StatCounter
 	mSent            metrics.StatCounter
 	mSentParts       metrics.StatCounter
 }
@@ -84,8 +101,25 @@ type BoundsCheck struct {
 func NewBoundsCheck(
 	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
 ) (Type, error) {
+	var flag bool
+	switch conf.BoundsCheck.Action {
+	case "drop", "":
+	case "flag":
+		flag = true
+	default:
+		return nil, fmt.Errorf("bounds_check action not recognised: %v", conf.BoundsCheck.Action)
+	}
+
+	mViolations := map[string]metrics.StatCounter{}
+	for _, limit := range []string{
+		"max_parts", "min_parts", "max_part_size", "min_part_size",
+	} {
+		mViolations[limit] = stats.GetCounter("processor.bounds_check.violation." + limit)
+	}
+
 	return &BoundsCheck{
 		conf:  conf,
+		flag:  flag,
 		log:   log.NewModule(".processor.bounds_check"),
 		stats: stats,
 
@@ -94,6 +128,8 @@ func NewBoundsCheck(
 		mDroppedEmpty:    stats.GetCounter("processor.bounds_check.dropped_empty"),
 		mDroppedNumParts: stats.GetCounter("processor.bounds_check.dropped_num_parts"),
 		mDroppedPartSize: stats.GetCounter("processor.bounds_check.dropped_part_size"),
+		mFlagged:         stats.GetCounter("processor.bounds_check.flagged"),
+		mViolations:      mViolations,
 		mSent:            stats.GetCounter("processor.bounds_check.sent"),
 		mSentParts:       stats.GetCounter("processor.bounds_check.parts.sent"),
 	}, nil
@@ -101,49 +137,74 @@ func NewBoundsCheck(
 
 //------------------------------------------------------------------------------
 
-// ProcessMessage applies the processor to a message, either creating >0
-// resulting messages or a response to be sent back to the message source.
-func (m *BoundsCheck) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
-	m.mCount.Incr(1)
-
+// violation returns the name of the first limit that a message violates, or
+// an empty string if it is within bounds.
+func (m *BoundsCheck) violation(msg types.Message) string {
 	lParts := msg.Len()
 	if lParts < m.conf.BoundsCheck.MinParts {
 		m.log.Debugf(
 			"Rejecting message due to message parts below minimum (%v): %v\n",
 			m.conf.BoundsCheck.MinParts, lParts,
 		)
-		m.mDropped.Incr(1)
-		m.mDroppedEmpty.Incr(1)
-		return nil, response.NewAck()
+		return "min_parts"
 	} else if lParts > m.conf.BoundsCheck.MaxParts {
 		m.log.Debugf(
 			"Rejecting message due to message parts exceeding limit (%v): %v\n",
 			m.conf.BoundsCheck.MaxParts, lParts,
 		)
-		m.mDropped.Incr(1)
-		m.mDroppedNumParts.Incr(1)
-		return nil, response.NewAck()
+		return "max_parts"
 	}
 
-	var reject bool
+	var limit string
 	msg.Iter(func(i int, p types.Part) error {
-		if size := len(p.Get()); size > m.conf.BoundsCheck.MaxPartSize ||
-			size < m.conf.BoundsCheck.MinPartSize {
-			m.log.Debugf(
-				"Rejecting message due to message part size (%v -> %v): %v\n",
-				m.conf.BoundsCheck.MinPartSize,
-				m.conf.BoundsCheck.MaxPartSize,
-				size,
-			)
-			reject = true
-			return errors.New("exit")
+		size := len(p.Get())
+		if size > m.conf.BoundsCheck.MaxPartSize {
+			limit = "max_part_size"
+		} else if size < m.conf.BoundsCheck.MinPartSize {
+			limit = "min_part_size"
+		} else {
+			return nil
 		}
-		return nil
+		m.log.Debugf(
+			"Rejecting message due to message part size (%v -> %v): %v\n",
+			m.conf.BoundsCheck.MinPartSize,
+			m.conf.BoundsCheck.MaxPartSize,
+			size,
+		)
+		return errors.New("exit")
 	})
+	return limit
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (m *BoundsCheck) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	m.mCount.Incr(1)
+
+	if limit := m.violation(msg); len(limit) > 0 {
+		m.mViolations[limit].Incr(1)
+		if m.flag && msg.Len() > 0 {
+			m.mFlagged.Incr(1)
+			newMsg := msg.Copy()
+			newMsg.Iter(func(i int, p types.Part) error {
+				FlagFail(p)
+				return nil
+			})
+			m.mSent.Incr(1)
+			m.mSentParts.Incr(int64(newMsg.Len()))
+			msgs := [1]types.Message{newMsg}
+			return msgs[:], nil
+		}
 
-	if reject {
 		m.mDropped.Incr(1)
-		m.mDroppedPartSize.Incr(1)
+		switch limit {
+		case "min_parts":
+			m.mDroppedEmpty.Incr(1)
+		case "max_parts":
+			m.mDroppedNumParts.Incr(1)
+		default:
+			m.mDroppedPartSize.Incr(1)
+		}
 		return nil, response.NewAck()
 	}
 
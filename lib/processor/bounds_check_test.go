@@ -106,3 +106,99 @@ func TestBoundsCheck(t *testing.T) {
 		}
 	}
 }
+
+func TestBoundsCheckFlag(t *testing.T) {
+	conf := NewConfig()
+	conf.BoundsCheck.MinParts = 2
+	conf.BoundsCheck.MaxParts = 3
+	conf.BoundsCheck.MaxPartSize = 10
+	conf.BoundsCheck.MinPartSize = 1
+	conf.BoundsCheck.Action = "flag"
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	stats := metrics.NewLocal()
+	proc, err := NewBoundsCheck(conf, nil, testLog, stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		parts [][]byte
+		limit string
+	}
+
+	tests := []testCase{
+		{
+			parts: [][]byte{[]byte("hello world")},
+			limit: "min_parts",
+		},
+		{
+			parts: [][]byte{
+				[]byte("a"), []byte("b"), []byte("c"), []byte("d"),
+			},
+			limit: "max_parts",
+		},
+		{
+			parts: [][]byte{
+				[]byte("hello"), []byte("hello world this exceeds max part size"),
+			},
+			limit: "max_part_size",
+		},
+		{
+			parts: [][]byte{[]byte("hello"), []byte("")},
+			limit: "min_part_size",
+		},
+	}
+
+	for _, test := range tests {
+		msgs, res := proc.ProcessMessage(message.New(test.parts))
+		if len(msgs) != 1 || res != nil {
+			t.Fatalf("Expected flagged message for %v: %v", test.limit, res)
+		}
+		if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(test.parts, act) {
+			t.Errorf("Wrong message contents for %v: %s != %s", test.limit, act, test.parts)
+		}
+		for i := 0; i < msgs[0].Len(); i++ {
+			if !HasFailed(msgs[0].Get(i)) {
+				t.Errorf("Part %v not flagged as failed for %v", i, test.limit)
+			}
+		}
+		if exp, act := int64(1), stats.GetCounters()["processor.bounds_check.violation."+test.limit]; exp != act {
+			t.Errorf("Wrong violation count for %v: %v != %v", test.limit, act, exp)
+		}
+	}
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{[]byte("hello"), []byte("world")}))
+	if len(msgs) != 1 {
+		t.Fatal("Expected message to pass")
+	}
+	for i := 0; i < msgs[0].Len(); i++ {
+		if HasFailed(msgs[0].Get(i)) {
+			t.Errorf("Part %v of good message flagged as failed", i)
+		}
+	}
+
+	if msgs, res := proc.ProcessMessage(message.New(nil)); len(msgs) > 0 {
+		t.Error("Expected empty message to be dropped")
+	} else if _, ok := res.(response.Ack); !ok {
+		t.Error("Expected simple response from empty message")
+	}
+
+	counters := stats.GetCounters()
+	if exp, act := int64(4), counters["processor.bounds_check.flagged"]; exp != act {
+		t.Errorf("Wrong flagged count: %v != %v", act, exp)
+	}
+	if exp, act := int64(1), counters["processor.bounds_check.dropped"]; exp != act {
+		t.Errorf("Wrong dropped count: %v != %v", act, exp)
+	}
+}
+
+func TestBoundsCheckBadAction(t *testing.T) {
+	conf := NewConfig()
+	conf.BoundsCheck.Action = "nope"
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	if _, err := NewBoundsCheck(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad action")
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeCache] = TypeSpec{
+		constructor: NewCache,
+		description: `
+Performs operations against a [cache resource](../caches) for each message part,
+allowing you to store or retrieve data within message payloads.
+
+This processor will interpolate functions within the ` + "`key`" + `,
+` + "`value`" + ` and ` + "`ttl`" + ` fields individually for each message part.
+This allows you to specify dynamic keys and values based on the contents of the
+message payloads and metadata. You can find a list of functions
+[here](../config_interpolation.md#functions).
+
+### Operators
+
+#### ` + "`set`" + `
+
+Set a key in the cache to a value. If the key already exists the contents are
+overridden.
+
+#### ` + "`add`" + `
+
+Set a key in the cache to a value. If the key already exists the action fails
+with a 'key already exists' error, which means the message part is flagged as
+failed.
+
+#### ` + "`get`" + `
+
+Retrieve the contents of a cached key and replace the original message payload
+with the result. If the key does not exist the action fails with an error and
+the message part is flagged as failed.
+
+#### ` + "`delete`" + `
+
+Delete a key and its contents from the cache. If the key does not exist the
+action is a no-op and will not fail with an error.
+
+### TTL
+
+The ` + "`ttl`" + ` field, when not empty, sets an expiration for keys written
+with the ` + "`set`" + ` and ` + "`add`" + ` operators that overrides the
+default expiration of the cache. Only caches that support per key expirations
+(currently ` + "`redis`" + `) can be used with this field.
+
+For example, the following config would deduplicate messages by an ID field
+atomically across any number of Benthos instances, where each ID is remembered
+for a duration specified within the message metadata:
+
+` + "``` yaml" + `
+processors:
+- cache:
+    cache: foocache
+    operator: add
+    key: ${!json_field:id}
+    value: t
+    ttl: ${!metadata:dedupe_ttl}
+- filter_parts:
+    type: not
+    not:
+      type: metadata
+      metadata:
+        operator: exists
+        key: benthos_processing_failed
+` + "```" + `
+
+Message parts that fail an operation are not dropped, they are flagged as failed
+and continue through the pipeline.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CacheConfig contains configuration fields for the Cache processor.
+type CacheConfig struct {
+	Cache    string `json:"cache" yaml:"cache"`
+	Operator string `json:"operator" yaml:"operator"`
+	Key      string `json:"key" yaml:"key"`
+	Value    string `json:"value" yaml:"value"`
+	TTL      string `json:"ttl" yaml:"ttl"`
+}
+
+// NewCacheConfig returns a CacheConfig with default values.
+func NewCacheConfig() CacheConfig {
+	return CacheConfig{
+		Cache:    "",
+		Operator: "set",
+		Key:      "",
+		Value:    "",
+		TTL:      "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type cacheOperator func(key string, value []byte, ttl *time.Duration) ([]byte, bool, error)
+
+func newCacheSetOperator(cache types.Cache) cacheOperator {
+	return func(key string, value []byte, ttl *time.Duration) ([]byte, bool, error) {
+		if ttl != nil {
+			return nil, false, cache.(types.CacheWithTTL).SetWithTTL(key, value, ttl)
+		}
+		return nil, false, cache.Set(key, value)
+	}
+}
+
+func newCacheAddOperator(cache types.Cache) cacheOperator {
+	return func(key string, value []byte, ttl *time.Duration) ([]byte, bool, error) {
+		if ttl != nil {
+			return nil, false, cache.(types.CacheWithTTL).AddWithTTL(key, value, ttl)
+		}
+		return nil, false, cache.Add(key, value)
+	}
+}
+
+func newCacheGetOperator(cache types.Cache) cacheOperator {
+	return func(key string, _ []byte, _ *time.Duration) ([]byte, bool, error) {
+		result, err := cache.Get(key)
+		return result, true, err
+	}
+}
+
+func newCacheDeleteOperator(cache types.Cache) cacheOperator {
+	return func(key string, _ []byte, _ *time.Duration) ([]byte, bool, error) {
+		return nil, false, cache.Delete(key)
+	}
+}
+
+func cacheOperatorFromString(operator string, cache types.Cache) (cacheOperator, error) {
+	switch operator {
+	case "set":
+		return newCacheSetOperator(cache), nil
+	case "add":
+		return newCacheAddOperator(cache), nil
+	case "get":
+		return newCacheGetOperator(cache), nil
+	case "delete":
+		return newCacheDeleteOperator(cache), nil
+	}
+	return nil, fmt.Errorf("operator not recognised: %v", operator)
+}
+
+//------------------------------------------------------------------------------
+
+// Cache is a processor that stores or retrieves data from a cache for each
+// message part.
+type Cache struct {
+	conf  Config
+	log   log.Modular
+	stats metrics.Type
+
+	key      *text.InterpolatedString
+	value    *text.InterpolatedString
+	ttl      *text.InterpolatedString
+	cache    types.Cache
+	operator cacheOperator
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mErrTTL    metrics.StatCounter
+	mSent      metrics.StatCounter
+	mSentParts metrics.StatCounter
+}
+
+// NewCache returns a Cache processor.
+func NewCache(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	c, err := mgr.GetCache(conf.Cache.Cache)
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := cacheOperatorFromString(conf.Cache.Operator, c)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(conf.Cache.TTL) > 0 {
+		if _, ok := c.(types.CacheWithTTL); !ok {
+			return nil, fmt.Errorf("cache '%v' does not support per key TTLs", conf.Cache.Cache)
+		}
+	}
+
+	return &Cache{
+		conf:  conf,
+		log:   log.NewModule(".processor.cache"),
+		stats: stats,
+
+		key:      text.NewInterpolatedString(conf.Cache.Key),
+		value:    text.NewInterpolatedString(conf.Cache.Value),
+		ttl:      text.NewInterpolatedString(conf.Cache.TTL),
+		cache:    c,
+		operator: op,
+
+		mCount:     stats.GetCounter("processor.cache.count"),
+		mErr:       stats.GetCounter("processor.cache.error"),
+		mErrTTL:    stats.GetCounter("processor.cache.error.ttl"),
+		mSent:      stats.GetCounter("processor.cache.sent"),
+		mSentParts: stats.GetCounter("processor.cache.parts.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (c *Cache) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	c.mCount.Incr(1)
+
+	newMsg := msg.Copy()
+
+	for i := 0; i < newMsg.Len(); i++ {
+		lMsg := message.Lock(newMsg, i)
+		key := c.key.Get(lMsg)
+		value := c.value.Get(lMsg)
+
+		var ttl *time.Duration
+		if ttlStr := c.ttl.Get(lMsg); len(ttlStr) > 0 {
+			td, err := time.ParseDuration(ttlStr)
+			if err != nil {
+				c.mErrTTL.Incr(1)
+				c.log.Errorf("Failed to parse ttl '%v': %v\n", ttlStr, err)
+				FlagFail(newMsg.Get(i))
+				continue
+			}
+			ttl = &td
+		}
+
+		result, useResult, err := c.operator(key, []byte(value), ttl)
+		if err != nil {
+			if err != types.ErrKeyAlreadyExists {
+				c.mErr.Incr(1)
+				c.log.Debugf("Operator failed for key '%s': %v\n", key, err)
+			}
+			FlagFail(newMsg.Get(i))
+			continue
+		}
+
+		if useResult {
+			newMsg.Get(i).Set(result)
+		}
+	}
+
+	c.mSent.Incr(1)
+	c.mSentParts.Incr(int64(newMsg.Len()))
+	msgs := [1]types.Message{newMsg}
+	return msgs[:], nil
+}
+
+//------------------------------------------------------------------------------
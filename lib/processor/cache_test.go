@@ -0,0 +1,267 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/cache"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+type fakeTTLCache struct {
+	types.Cache
+	ttls map[string]time.Duration
+}
+
+func (f *fakeTTLCache) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
+	f.ttls[key] = *ttl
+	return f.Set(key, value)
+}
+
+func (f *fakeTTLCache) AddWithTTL(key string, value []byte, ttl *time.Duration) error {
+	if err := f.Add(key, value); err != nil {
+		return err
+	}
+	f.ttls[key] = *ttl
+	return nil
+}
+
+func newCacheTestMgr(t *testing.T) (*fakeMgr, types.Cache) {
+	t.Helper()
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, testLog, metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}, memCache
+}
+
+//------------------------------------------------------------------------------
+
+func TestCacheSetGet(t *testing.T) {
+	mgr, memCache := newCacheTestMgr(t)
+
+	conf := NewConfig()
+	conf.Cache.Cache = "foocache"
+	conf.Cache.Operator = "set"
+	conf.Cache.Key = "${!json_field:key}"
+	conf.Cache.Value = "${!json_field:value}"
+
+	proc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte(`{"key":"1","value":"foo 1"}`),
+		[]byte(`{"key":"2","value":"foo 2"}`),
+	})
+	output, res := proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(output) != 1 {
+		t.Fatalf("Wrong count of result msgs: %v", len(output))
+	}
+	if exp, act := message.GetAllBytes(input), message.GetAllBytes(output[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+
+	if act, err := memCache.Get("1"); err != nil {
+		t.Error(err)
+	} else if exp := "foo 1"; string(act) != exp {
+		t.Errorf("Wrong cached value: %s != %s", act, exp)
+	}
+
+	conf.Cache.Operator = "get"
+	if proc, err = NewCache(conf, mgr, log.Noop(), metrics.Noop()); err != nil {
+		t.Fatal(err)
+	}
+
+	input = message.New([][]byte{
+		[]byte(`{"key":"2"}`),
+		[]byte(`{"key":"3"}`),
+	})
+	if output, res = proc.ProcessMessage(input); res != nil {
+		t.Fatal(res.Error())
+	}
+	exp := [][]byte{
+		[]byte(`foo 2`),
+		[]byte(`{"key":"3"}`),
+	}
+	if act := message.GetAllBytes(output[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if HasFailed(output[0].Get(0)) {
+		t.Error("Expected part 0 not to be flagged")
+	}
+	if !HasFailed(output[0].Get(1)) {
+		t.Error("Expected part 1 to be flagged")
+	}
+}
+
+func TestCacheAdd(t *testing.T) {
+	mgr, _ := newCacheTestMgr(t)
+
+	conf := NewConfig()
+	conf.Cache.Cache = "foocache"
+	conf.Cache.Operator = "add"
+	conf.Cache.Key = "${!json_field:key}"
+	conf.Cache.Value = "${!json_field:value}"
+
+	proc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte(`{"key":"1","value":"foo 1"}`),
+		[]byte(`{"key":"2","value":"foo 2"}`),
+		[]byte(`{"key":"1","value":"foo 3"}`),
+	})
+	output, res := proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	for i, exp := range []bool{false, false, true} {
+		if act := HasFailed(output[0].Get(i)); act != exp {
+			t.Errorf("Wrong fail flag for part %v: %v != %v", i, act, exp)
+		}
+	}
+}
+
+func TestCacheDelete(t *testing.T) {
+	mgr, memCache := newCacheTestMgr(t)
+	memCache.Set("1", []byte("foo"))
+
+	conf := NewConfig()
+	conf.Cache.Cache = "foocache"
+	conf.Cache.Operator = "delete"
+	conf.Cache.Key = "${!json_field:key}"
+
+	proc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte(`{"key":"1"}`),
+		[]byte(`{"key":"2"}`),
+	})
+	output, res := proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	for i := 0; i < output[0].Len(); i++ {
+		if HasFailed(output[0].Get(i)) {
+			t.Errorf("Expected part %v not to be flagged", i)
+		}
+	}
+	if _, err = memCache.Get("1"); err != types.ErrKeyNotFound {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyNotFound)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	mgr, memCache := newCacheTestMgr(t)
+
+	conf := NewConfig()
+	conf.Cache.Cache = "foocache"
+	conf.Cache.Operator = "set"
+	conf.Cache.Key = "${!json_field:key}"
+	conf.Cache.Value = "bar"
+	conf.Cache.TTL = "${!metadata:ttl}"
+
+	if _, err := NewCache(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from cache without TTL support")
+	}
+
+	ttlCache := &fakeTTLCache{
+		Cache: memCache,
+		ttls:  map[string]time.Duration{},
+	}
+	mgr.caches["foocache"] = ttlCache
+
+	proc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte(`{"key":"1"}`),
+		[]byte(`{"key":"2"}`),
+		[]byte(`{"key":"3"}`),
+	})
+	input.Get(0).Metadata().Set("ttl", "1m")
+	input.Get(1).Metadata().Set("ttl", "5s")
+	input.Get(2).Metadata().Set("ttl", "nope")
+
+	output, res := proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp := map[string]time.Duration{
+		"1": time.Minute,
+		"2": time.Second * 5,
+	}
+	if !reflect.DeepEqual(exp, ttlCache.ttls) {
+		t.Errorf("Wrong TTLs: %v != %v", ttlCache.ttls, exp)
+	}
+	for i, exp := range []bool{false, false, true} {
+		if act := HasFailed(output[0].Get(i)); act != exp {
+			t.Errorf("Wrong fail flag for part %v: %v != %v", i, act, exp)
+		}
+	}
+}
+
+func TestCacheBadOperator(t *testing.T) {
+	mgr, _ := newCacheTestMgr(t)
+
+	conf := NewConfig()
+	conf.Cache.Cache = "foocache"
+	conf.Cache.Operator = "nope"
+
+	if _, err := NewCache(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad operator")
+	}
+
+	conf.Cache.Operator = "set"
+	conf.Cache.Cache = "barcache"
+	if _, err := NewCache(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing cache")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,161 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package condition
+
+import (
+	"encoding/json"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeAll] = TypeSpec{
+		constructor: NewAll,
+		description: `
+Applies a child condition to each part of a message batch individually and
+returns true if the child resolves to true for every part. A batch with zero
+parts results in true.
+
+The child condition is given each part as a single part message, and should
+therefore target part 0 (the default) or -1. For example, in order to only
+archive a batch when every part is valid JSON you could use:
+
+` + "``` yaml" + `
+type: conditional
+conditional:
+  condition:
+    type: all
+    all:
+      type: jmespath
+      jmespath:
+        query: "` + "`true`" + `"
+  processors:
+  - type: archive
+    archive:
+      format: json_array
+` + "```" + ``,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			if conf.All.Config == nil {
+				return struct{}{}, nil
+			}
+			return SanitiseConfig(*conf.All.Config)
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// AllConfig is a configuration struct containing fields for the All condition.
+type AllConfig struct {
+	*Config
+}
+
+// NewAllConfig returns a AllConfig with default values.
+func NewAllConfig() AllConfig {
+	return AllConfig{
+		Config: nil,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// MarshalJSON prints an empty object instead of nil.
+func (m AllConfig) MarshalJSON() ([]byte, error) {
+	if m.Config != nil {
+		return json.Marshal(m.Config)
+	}
+	return json.Marshal(struct{}{})
+}
+
+// MarshalYAML prints an empty object instead of nil.
+func (m AllConfig) MarshalYAML() (interface{}, error) {
+	if m.Config != nil {
+		return *m.Config, nil
+	}
+	return struct{}{}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// UnmarshalJSON ensures that when parsing child config it is initialised.
+func (m *AllConfig) UnmarshalJSON(bytes []byte) error {
+	if m.Config == nil {
+		nConf := NewConfig()
+		m.Config = &nConf
+	}
+
+	return json.Unmarshal(bytes, m.Config)
+}
+
+// UnmarshalYAML ensures that when parsing child config it is initialised.
+func (m *AllConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if m.Config == nil {
+		nConf := NewConfig()
+		m.Config = &nConf
+	}
+
+	return unmarshal(m.Config)
+}
+
+//------------------------------------------------------------------------------
+
+// All is a condition that returns true if a child condition resolves to true
+// for every part of a message.
+type All struct {
+	child Type
+}
+
+// NewAll returns an All condition.
+func NewAll(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	childConf := conf.All.Config
+	if childConf == nil {
+		newConf := NewConfig()
+		childConf = &newConf
+	}
+	child, err := New(*childConf, mgr, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return &All{
+		child: child,
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Check attempts to check a message part against a configured condition.
+func (c *All) Check(msg types.Message) bool {
+	for i := 0; i < msg.Len(); i++ {
+		if !c.child.Check(message.Lock(msg, i)) {
+			return false
+		}
+	}
+	return true
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,88 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package condition
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestAllCheck(t *testing.T) {
+	conf := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+type: all
+all:
+  type: jmespath
+  jmespath:
+    query: "foo == 'bar'"
+`), &conf); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		input []string
+		want  bool
+	}{
+		{"empty batch", []string{}, true},
+		{"single match", []string{`{"foo":"bar"}`}, true},
+		{"all match", []string{`{"foo":"bar"}`, `{"foo":"bar","baz":1}`}, true},
+		{"last fails", []string{`{"foo":"bar"}`, `{"foo":"baz"}`}, false},
+		{"first fails", []string{`not json`, `{"foo":"bar"}`}, false},
+	}
+
+	for _, test := range tests {
+		parts := make([][]byte, len(test.input))
+		for i, p := range test.input {
+			parts[i] = []byte(p)
+		}
+		if act := c.Check(message.New(parts)); act != test.want {
+			t.Errorf("%v: wrong result: %v != %v", test.name, act, test.want)
+		}
+	}
+}
+
+func TestAllConfigMarshal(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeAll
+
+	act, err := yaml.Marshal(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nConf := NewConfig()
+	if err = yaml.Unmarshal(act, &nConf); err != nil {
+		t.Fatal(err)
+	}
+	if nConf.All.Config == nil || nConf.All.Config.Type != "text" {
+		t.Errorf("Child config not initialised: %+v", nConf.All.Config)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package condition
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestAnyCheck(t *testing.T) {
+	conf := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+type: any
+any:
+  type: text
+  text:
+    operator: equals
+    arg: ""
+`), &conf); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		input []string
+		want  bool
+	}{
+		{"empty batch", []string{}, false},
+		{"no empty parts", []string{"foo", "bar"}, false},
+		{"first empty", []string{"", "bar"}, true},
+		{"last empty", []string{"foo", "bar", ""}, true},
+	}
+
+	for _, test := range tests {
+		parts := make([][]byte, len(test.input))
+		for i, p := range test.input {
+			parts[i] = []byte(p)
+		}
+		if act := c.Check(message.New(parts)); act != test.want {
+			t.Errorf("%v: wrong result: %v != %v", test.name, act, test.want)
+		}
+	}
+}
+
+func TestAnyChildPart(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeAny
+
+	childConf := NewConfig()
+	childConf.Type = TypeText
+	childConf.Text.Operator = "equals"
+	childConf.Text.Arg = "bar"
+	childConf.Text.Part = -1
+	conf.Any.Config = &childConf
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar"), []byte("baz")})
+	if !c.Check(msg) {
+		t.Error("Expected child to be applied to each part")
+	}
+}
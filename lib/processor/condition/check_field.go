@@ -21,6 +21,7 @@
 package condition
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"strings"
@@ -38,7 +39,33 @@ func init() {
 		constructor: NewCheckField,
 		description: `
 Extracts the value of a field within messages (currently only JSON format is
-supported) and then tests the extracted value against a child condition.`,
+supported) and then tests the extracted value against a child condition. The
+extracted value replaces the contents of each message part before it is checked,
+where string values are extracted without quotes.
+
+For example, the following condition passes when the field ` + "`event.type`" + `
+of a JSON document is ` + "`purchase`" + `:
+
+` + "``` yaml" + `
+check_field:
+  path: event.type
+  condition:
+    type: text
+    text:
+      operator: equals
+      arg: purchase
+` + "```" + `
+
+If the path does not exist within any of the checked parts then the condition
+does not pass, regardless of the child condition.
+
+Alternatively, a value can be extracted from the metadata of message parts by
+setting ` + "`metadata_key`" + ` instead of ` + "`path`" + `, in which case
+parts without the key, or with an empty value, do not pass.
+
+The field ` + "`parts`" + ` selects the message parts to extract values from,
+where negative indexes count backwards from the last part. When empty all parts
+are extracted.`,
 		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
 			var condConf interface{} = struct{}{}
 			if conf.CheckField.Condition != nil {
@@ -47,11 +74,16 @@ supported) and then tests the extracted value against a child condition.`,
 					return nil, err
 				}
 			}
-			return map[string]interface{}{
+			sanit := map[string]interface{}{
 				"parts":     conf.CheckField.Parts,
 				"path":      conf.CheckField.Path,
 				"condition": condConf,
-			}, nil
+			}
+			if len(conf.CheckField.MetadataKey) > 0 {
+				delete(sanit, "path")
+				sanit["metadata_key"] = conf.CheckField.MetadataKey
+			}
+			return sanit, nil
 		},
 	}
 }
@@ -60,34 +92,38 @@ supported) and then tests the extracted value against a child condition.`,
 
 // CheckFieldConfig contains configuration fields for the CheckField condition.
 type CheckFieldConfig struct {
-	Parts     []int   `json:"parts" yaml:"parts"`
-	Path      string  `json:"path" yaml:"path"`
-	Condition *Config `json:"condition" yaml:"condition"`
+	Parts       []int   `json:"parts" yaml:"parts"`
+	Path        string  `json:"path" yaml:"path"`
+	MetadataKey string  `json:"metadata_key" yaml:"metadata_key"`
+	Condition   *Config `json:"condition" yaml:"condition"`
 }
 
 // NewCheckFieldConfig returns a CheckFieldConfig with default values.
 func NewCheckFieldConfig() CheckFieldConfig {
 	return CheckFieldConfig{
-		Parts:     []int{},
-		Path:      "",
-		Condition: nil,
+		Parts:       []int{},
+		Path:        "",
+		MetadataKey: "",
+		Condition:   nil,
 	}
 }
 
 //------------------------------------------------------------------------------
 
 type dummyCheckFieldConfig struct {
-	Parts     []int       `json:"parts" yaml:"parts"`
-	Path      string      `json:"path" yaml:"path"`
-	Condition interface{} `json:"condition" yaml:"condition"`
+	Parts       []int       `json:"parts" yaml:"parts"`
+	Path        string      `json:"path" yaml:"path"`
+	MetadataKey string      `json:"metadata_key" yaml:"metadata_key"`
+	Condition   interface{} `json:"condition" yaml:"condition"`
 }
 
 // MarshalJSON prints an empty object instead of nil.
 func (c CheckFieldConfig) MarshalJSON() ([]byte, error) {
 	dummy := dummyCheckFieldConfig{
-		Parts:     c.Parts,
-		Path:      c.Path,
-		Condition: c.Condition,
+		Parts:       c.Parts,
+		Path:        c.Path,
+		MetadataKey: c.MetadataKey,
+		Condition:   c.Condition,
 	}
 	if c.Condition == nil {
 		dummy.Condition = struct{}{}
@@ -98,9 +134,10 @@ func (c CheckFieldConfig) MarshalJSON() ([]byte, error) {
 // MarshalYAML prints an empty object instead of nil.
 func (c CheckFieldConfig) MarshalYAML() (interface{}, error) {
 	dummy := dummyCheckFieldConfig{
-		Parts:     c.Parts,
-		Path:      c.Path,
-		Condition: c.Condition,
+		Parts:       c.Parts,
+		Path:        c.Path,
+		MetadataKey: c.MetadataKey,
+		Condition:   c.Condition,
 	}
 	if c.Condition == nil {
 		dummy.Condition = struct{}{}
@@ -121,6 +158,7 @@ type CheckField struct {
 	path  []string
 
 	mApplied metrics.StatCounter
+	mMissing metrics.StatCounter
 	mErrJSON metrics.StatCounter
 	mErr     metrics.StatCounter
 }
@@ -135,6 +173,9 @@ func NewCheckField(
 	if conf.CheckField.Condition == nil {
 		return nil, errors.New("cannot create check_field condition without a child")
 	}
+	if len(conf.CheckField.Path) > 0 && len(conf.CheckField.MetadataKey) > 0 {
+		return nil, errors.New("cannot specify both a path and a metadata_key")
+	}
 
 	child, err := New(*conf.CheckField.Condition, mgr, nsLog, nsStats)
 	if err != nil {
@@ -148,6 +189,7 @@ func NewCheckField(
 		child:    child,
 		path:     strings.Split(conf.CheckField.Path, "."),
 		mApplied: stats.GetCounter("condition.check_field.applied"),
+		mMissing: stats.GetCounter("condition.check_field.missing"),
 		mErr:     stats.GetCounter("condition.check_field.error"),
 		mErrJSON: stats.GetCounter("condition.check_field.error.json_parse"),
 	}, nil
@@ -158,11 +200,34 @@ func NewCheckField(
 // Check attempts to check a message part against a configured condition
 func (c *CheckField) Check(msg types.Message) bool {
 	payload := msg.Copy()
+	missing := false
 
 	proc := func(index int) {
+		if index < 0 {
+			index = payload.Len() + index
+		}
+		if index < 0 || index >= payload.Len() {
+			return
+		}
+
 		payload.Get(index).Set([]byte(""))
 
-		jpart, err := msg.Get(index).JSON()
+		if len(c.conf.MetadataKey) > 0 {
+			v := msg.Get(index).Metadata().Get(c.conf.MetadataKey)
+			if len(v) == 0 {
+				missing = true
+				return
+			}
+			payload.Get(index).Set([]byte(v))
+			return
+		}
+
+		// Numbers are decoded without conversion into floats so that large
+		// integers are extracted precisely.
+		dec := json.NewDecoder(bytes.NewReader(msg.Get(index).Get()))
+		dec.UseNumber()
+
+		gpart, err := gabs.ParseJSONDecoder(dec)
 		if err != nil {
 			c.log.Debugf("Failed to parse message as JSON: %v\n", err)
 			c.mErrJSON.Incr(1)
@@ -170,11 +235,8 @@ func (c *CheckField) Check(msg types.Message) bool {
 			return
 		}
 
-		var gpart *gabs.Container
-		if gpart, err = gabs.Consume(jpart); err != nil {
-			c.log.Debugf("Failed to parse message as JSON: %v\n", err)
-			c.mErrJSON.Incr(1)
-			c.mErr.Incr(1)
+		if !gpart.Exists(c.path...) {
+			missing = true
 			return
 		}
 
@@ -197,6 +259,11 @@ func (c *CheckField) Check(msg types.Message) bool {
 		}
 	}
 
+	if missing {
+		c.mMissing.Incr(1)
+		return false
+	}
+
 	c.mApplied.Incr(1)
 	return c.child.Check(payload)
 }
@@ -213,3 +213,107 @@ func TestCheckFieldBadChild(t *testing.T) {
 		t.Error("expected error from bad child")
 	}
 }
+
+func TestCheckFieldMissingPath(t *testing.T) {
+	tConf := NewConfig()
+	tConf.Type = "text"
+	tConf.Text.Operator = "equals"
+	tConf.Text.Arg = "purchase"
+
+	notConf := NewConfig()
+	notConf.Type = "not"
+	notConf.Not.Config = &tConf
+
+	conf := NewConfig()
+	conf.Type = "check_field"
+	conf.CheckField.Path = "event.type"
+	conf.CheckField.Condition = &notConf
+
+	stats := metrics.NewLocal()
+	c, err := NewCheckField(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !c.Check(message.New([][]byte{[]byte(`{"event":{"type":"refund"}}`)})) {
+		t.Error("Expected condition to pass for different value")
+	}
+	if c.Check(message.New([][]byte{[]byte(`{"event":{"type":"purchase"}}`)})) {
+		t.Error("Expected condition to fail for matching value")
+	}
+	if c.Check(message.New([][]byte{[]byte(`{"event":{"id":"foo"}}`)})) {
+		t.Error("Expected condition to fail for missing path")
+	}
+	if exp, act := int64(1), stats.GetCounters()["condition.check_field.missing"]; exp != act {
+		t.Errorf("Wrong count of missing paths: %v != %v", act, exp)
+	}
+}
+
+func TestCheckFieldMetadata(t *testing.T) {
+	tConf := NewConfig()
+	tConf.Type = "text"
+	tConf.Text.Operator = "contains"
+	tConf.Text.Part = -1
+	tConf.Text.Arg = "json"
+
+	conf := NewConfig()
+	conf.Type = "check_field"
+	conf.CheckField.MetadataKey = "content_type"
+	conf.CheckField.Parts = []int{-1}
+	conf.CheckField.Condition = &tConf
+
+	c, err := NewCheckField(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{[]byte(`foo`), []byte(`bar`)})
+	msg.Get(0).Metadata().Set("content_type", "text/plain")
+	msg.Get(1).Metadata().Set("content_type", "application/json")
+	if !c.Check(msg) {
+		t.Error("Expected condition to pass for last part")
+	}
+
+	msg = message.New([][]byte{[]byte(`foo`), []byte(`bar`)})
+	msg.Get(0).Metadata().Set("content_type", "application/json")
+	msg.Get(1).Metadata().Set("content_type", "text/plain")
+	if c.Check(msg) {
+		t.Error("Expected condition to fail for last part")
+	}
+
+	msg = message.New([][]byte{[]byte(`foo`)})
+	if c.Check(msg) {
+		t.Error("Expected condition to fail for missing key")
+	}
+
+	conf.CheckField.Path = "foo"
+	if _, err = NewCheckField(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from both path and metadata_key")
+	}
+}
+
+func TestCheckFieldNegativeParts(t *testing.T) {
+	tConf := NewConfig()
+	tConf.Type = "text"
+	tConf.Text.Operator = "equals"
+	tConf.Text.Part = -1
+	tConf.Text.Arg = "purchase"
+
+	conf := NewConfig()
+	conf.Type = "check_field"
+	conf.CheckField.Path = "type"
+	conf.CheckField.Parts = []int{-1, 5}
+	conf.CheckField.Condition = &tConf
+
+	c, err := NewCheckField(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !c.Check(message.New([][]byte{
+		[]byte(`{"type":"refund"}`),
+		[]byte(`{"type":"purchase"}`),
+	})) {
+		t.Error("Expected condition to pass")
+	}
+}
@@ -55,57 +55,72 @@ var Constructors = map[string]TypeSpec{}
 
 // String constants representing each condition type.
 var (
-	TypeAnd         = "and"
-	TypeBoundsCheck = "bounds_check"
-	TypeCheckField  = "check_field"
-	TypeCount       = "count"
-	TypeJMESPath    = "jmespath"
-	TypeNot         = "not"
-	TypeMetadata    = "metadata"
-	TypeOr          = "or"
-	TypeResource    = "resource"
-	TypeStatic      = "static"
-	TypeText        = "text"
-	TypeXor         = "xor"
+	TypeAll             = "all"
+	TypeAnd             = "and"
+	TypeAny             = "any"
+	TypeBoundsCheck     = "bounds_check"
+	TypeCheckField      = "check_field"
+	TypeCount           = "count"
+	TypeCountParts      = "count_parts"
+	TypeJMESPath        = "jmespath"
+	TypeNot             = "not"
+	TypeMetadata        = "metadata"
+	TypeNumber          = "number"
+	TypeOr              = "or"
+	TypeProcessorFailed = "processor_failed"
+	TypeResource        = "resource"
+	TypeStatic          = "static"
+	TypeText            = "text"
+	TypeXor             = "xor"
 )
 
 //------------------------------------------------------------------------------
 
 // Config is the all encompassing configuration struct for all condition types.
 type Config struct {
-	Type        string            `json:"type" yaml:"type"`
-	And         AndConfig         `json:"and" yaml:"and"`
-	BoundsCheck BoundsCheckConfig `json:"bounds_check" yaml:"bounds_check"`
-	CheckField  CheckFieldConfig  `json:"check_field" yaml:"check_field"`
-	Count       CountConfig       `json:"count" yaml:"count"`
-	JMESPath    JMESPathConfig    `json:"jmespath" yaml:"jmespath"`
-	Not         NotConfig         `json:"not" yaml:"not"`
-	Metadata    MetadataConfig    `json:"metadata" yaml:"metadata"`
-	Or          OrConfig          `json:"or" yaml:"or"`
-	Plugin      interface{}       `json:"plugin,omitempty" yaml:"plugin,omitempty"`
-	Resource    string            `json:"resource" yaml:"resource"`
-	Static      bool              `json:"static" yaml:"static"`
-	Text        TextConfig        `json:"text" yaml:"text"`
-	Xor         XorConfig         `json:"xor" yaml:"xor"`
+	Type            string                `json:"type" yaml:"type"`
+	All             AllConfig             `json:"all" yaml:"all"`
+	And             AndConfig             `json:"and" yaml:"and"`
+	Any             AnyConfig             `json:"any" yaml:"any"`
+	BoundsCheck     BoundsCheckConfig     `json:"bounds_check" yaml:"bounds_check"`
+	CheckField      CheckFieldConfig      `json:"check_field" yaml:"check_field"`
+	Count           CountConfig           `json:"count" yaml:"count"`
+	CountParts      CountPartsConfig      `json:"count_parts" yaml:"count_parts"`
+	JMESPath        JMESPathConfig        `json:"jmespath" yaml:"jmespath"`
+	Not             NotConfig             `json:"not" yaml:"not"`
+	Metadata        MetadataConfig        `json:"metadata" yaml:"metadata"`
+	Number          NumberConfig          `json:"number" yaml:"number"`
+	Or              OrConfig              `json:"or" yaml:"or"`
+	Plugin          interface{}           `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	ProcessorFailed ProcessorFailedConfig `json:"processor_failed" yaml:"processor_failed"`
+	Resource        string                `json:"resource" yaml:"resource"`
+	Static          bool                  `json:"static" yaml:"static"`
+	Text            TextConfig            `json:"text" yaml:"text"`
+	Xor             XorConfig             `json:"xor" yaml:"xor"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Type:        "text",
-		And:         NewAndConfig(),
-		BoundsCheck: NewBoundsCheckConfig(),
-		CheckField:  NewCheckFieldConfig(),
-		Count:       NewCountConfig(),
-		JMESPath:    NewJMESPathConfig(),
-		Not:         NewNotConfig(),
-		Metadata:    NewMetadataConfig(),
-		Or:          NewOrConfig(),
-		Plugin:      nil,
-		Resource:    "",
-		Static:      true,
-		Text:        NewTextConfig(),
-		Xor:         NewXorConfig(),
+		Type:            "text",
+		All:             NewAllConfig(),
+		And:             NewAndConfig(),
+		Any:             NewAnyConfig(),
+		BoundsCheck:     NewBoundsCheckConfig(),
+		CheckField:      NewCheckFieldConfig(),
+		Count:           NewCountConfig(),
+		CountParts:      NewCountPartsConfig(),
+		JMESPath:        NewJMESPathConfig(),
+		Not:             NewNotConfig(),
+		Metadata:        NewMetadataConfig(),
+		Number:          NewNumberConfig(),
+		Or:              NewOrConfig(),
+		Plugin:          nil,
+		ProcessorFailed: NewProcessorFailedConfig(),
+		Resource:        "",
+		Static:          true,
+		Text:            NewTextConfig(),
+		Xor:             NewXorConfig(),
 	}
 }
 
@@ -271,6 +286,9 @@ the whole batch. Whereas other processors such as
 of a batch individually, in which case the condition acts as if it were
 referencing a single message batch.
 
+In order to check a condition against every part of a batch as a whole you can
+wrap it in an ` + "[`any`](#any)" + ` or ` + "[`all`](#all)" + ` condition.
+
 Part indexes can be negative, and if so the part will be selected from the end
 counting backwards starting from -1. E.g. if part = -1 then the selected part
 will be the last part of the message, if part = -2 then the part before the last
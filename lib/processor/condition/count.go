@@ -38,6 +38,18 @@ condition is useful when paired with the ` + "`read_until`" + ` input, as it can
 be used to cut the input stream off once a certain number of messages have been
 read.
 
+Since the condition resolves to false once in every ` + "`arg`" + ` messages it
+can also be wrapped in a ` + "[`not`](#not)" + ` condition in order to match
+every Nth message, which is useful for sampling or tapping a stream:
+
+` + "``` yaml" + `
+type: not
+not:
+  type: count
+  count:
+    arg: 10
+` + "```" + `
+
 It is worth noting that each discrete count condition will have its own counter.
 Parallel processors containing a count condition will therefore count
 independently. It is, however, possible to share the counter across processor
@@ -0,0 +1,234 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package condition
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeCountParts] = TypeSpec{
+		constructor: NewCountParts,
+		description: `
+Checks each part of a message batch individually against a child condition and
+passes when the number of parts that match satisfies the ` + "`operator`" + `
+and ` + "`arg`" + `. Available operators are ` + "`at_least`" + `,
+` + "`at_most`" + ` and ` + "`exactly`" + `.
+
+For example, the following condition passes when at least three parts of a
+batch contain the word "error":
+
+` + "``` yaml" + `
+count_parts:
+  operator: at_least
+  arg: 3
+  condition:
+    type: text
+    text:
+      operator: contains
+      arg: error
+` + "```" + `
+
+Parts stop being checked as soon as the result is known, therefore the child
+condition might not be evaluated against every part of a batch.`,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			var condConf interface{} = struct{}{}
+			if conf.CountParts.Condition != nil {
+				var err error
+				if condConf, err = SanitiseConfig(*conf.CountParts.Condition); err != nil {
+					return nil, err
+				}
+			}
+			return map[string]interface{}{
+				"operator":  conf.CountParts.Operator,
+				"arg":       conf.CountParts.Arg,
+				"condition": condConf,
+			}, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CountPartsConfig contains configuration fields for the CountParts condition.
+type CountPartsConfig struct {
+	Operator  string  `json:"operator" yaml:"operator"`
+	Arg       int     `json:"arg" yaml:"arg"`
+	Condition *Config `json:"condition" yaml:"condition"`
+}
+
+// NewCountPartsConfig returns a CountPartsConfig with default values.
+func NewCountPartsConfig() CountPartsConfig {
+	return CountPartsConfig{
+		Operator:  "at_least",
+		Arg:       1,
+		Condition: nil,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dummyCountPartsConfig struct {
+	Operator  string      `json:"operator" yaml:"operator"`
+	Arg       int         `json:"arg" yaml:"arg"`
+	Condition interface{} `json:"condition" yaml:"condition"`
+}
+
+// MarshalJSON prints an empty object instead of nil.
+func (c CountPartsConfig) MarshalJSON() ([]byte, error) {
+	dummy := dummyCountPartsConfig{
+		Operator:  c.Operator,
+		Arg:       c.Arg,
+		Condition: c.Condition,
+	}
+	if c.Condition == nil {
+		dummy.Condition = struct{}{}
+	}
+	return json.Marshal(dummy)
+}
+
+// MarshalYAML prints an empty object instead of nil.
+func (c CountPartsConfig) MarshalYAML() (interface{}, error) {
+	dummy := dummyCountPartsConfig{
+		Operator:  c.Operator,
+		Arg:       c.Arg,
+		Condition: c.Condition,
+	}
+	if c.Condition == nil {
+		dummy.Condition = struct{}{}
+	}
+	return dummy, nil
+}
+
+//------------------------------------------------------------------------------
+
+// countPartsOperator returns whether a count of matched parts satisfies the
+// operator, and whether the result is final regardless of the parts that
+// remain to be checked.
+type countPartsOperator func(matched, remaining int) (result, final bool)
+
+func newCountPartsOperator(op string, arg int) (countPartsOperator, error) {
+	switch op {
+	case "at_least":
+		return func(matched, remaining int) (bool, bool) {
+			if matched >= arg {
+				return true, true
+			}
+			return false, matched+remaining < arg
+		}, nil
+	case "at_most":
+		return func(matched, remaining int) (bool, bool) {
+			if matched > arg {
+				return false, true
+			}
+			return true, matched+remaining <= arg
+		}, nil
+	case "exactly":
+		return func(matched, remaining int) (bool, bool) {
+			if matched > arg || matched+remaining < arg {
+				return false, true
+			}
+			return matched == arg, matched == arg && remaining == 0
+		}, nil
+	}
+	return nil, fmt.Errorf("operator not recognised: %v", op)
+}
+
+//------------------------------------------------------------------------------
+
+// CountParts is a condition that checks whether the number of message parts
+// that match a child condition satisfies an operator.
+type CountParts struct {
+	child    Type
+	operator countPartsOperator
+
+	mApplied metrics.StatCounter
+	mTrue    metrics.StatCounter
+	mFalse   metrics.StatCounter
+}
+
+// NewCountParts returns a CountParts condition.
+func NewCountParts(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	if conf.CountParts.Condition == nil {
+		return nil, errors.New("cannot create count_parts condition without a child")
+	}
+	if conf.CountParts.Arg < 0 {
+		return nil, errors.New("arg must not be negative")
+	}
+
+	operator, err := newCountPartsOperator(conf.CountParts.Operator, conf.CountParts.Arg)
+	if err != nil {
+		return nil, err
+	}
+
+	nsStats := metrics.Namespaced(stats, "condition.count_parts")
+	nsLog := log.NewModule(".condition.count_parts")
+
+	child, err := New(*conf.CountParts.Condition, mgr, nsLog, nsStats)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CountParts{
+		child:    child,
+		operator: operator,
+
+		mApplied: stats.GetCounter("condition.count_parts.applied"),
+		mTrue:    stats.GetCounter("condition.count_parts.true"),
+		mFalse:   stats.GetCounter("condition.count_parts.false"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Check attempts to check a message part against a configured condition.
+func (c *CountParts) Check(msg types.Message) bool {
+	c.mApplied.Incr(1)
+
+	matched := 0
+	result, final := c.operator(matched, msg.Len())
+	for i := 0; i < msg.Len() && !final; i++ {
+		if c.child.Check(message.Lock(msg, i)) {
+			matched++
+		}
+		result, final = c.operator(matched, msg.Len()-i-1)
+	}
+
+	if result {
+		c.mTrue.Incr(1)
+	} else {
+		c.mFalse.Incr(1)
+	}
+	return result
+}
+
+//------------------------------------------------------------------------------
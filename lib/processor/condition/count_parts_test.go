@@ -0,0 +1,153 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package condition
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	yaml "gopkg.in/yaml.v2"
+)
+
+//------------------------------------------------------------------------------
+
+type countingCondition struct {
+	child  Type
+	checks int
+}
+
+func (c *countingCondition) Check(msg types.Message) bool {
+	c.checks++
+	return c.child.Check(msg)
+}
+
+func TestCountParts(t *testing.T) {
+	type testCase struct {
+		name     string
+		operator string
+		arg      int
+		input    []string
+		want     bool
+		checks   int
+	}
+
+	tests := []testCase{
+		{"at least pass", "at_least", 2, []string{"foo", "bar", "foo", "foo"}, true, 3},
+		{"at least fail", "at_least", 2, []string{"bar", "foo", "bar"}, false, 3},
+		{"at least fail early", "at_least", 3, []string{"bar", "bar", "foo", "foo"}, false, 2},
+		{"at least zero", "at_least", 0, []string{"bar"}, true, 0},
+		{"at least empty", "at_least", 1, []string{}, false, 0},
+		{"at most pass", "at_most", 1, []string{"bar", "foo", "bar"}, true, 3},
+		{"at most fail early", "at_most", 1, []string{"foo", "foo", "bar"}, false, 2},
+		{"at most pass early", "at_most", 3, []string{"foo", "bar"}, true, 0},
+		{"exactly pass", "exactly", 2, []string{"foo", "bar", "foo"}, true, 3},
+		{"exactly fail over", "exactly", 1, []string{"foo", "foo", "bar"}, false, 2},
+		{"exactly fail under", "exactly", 2, []string{"foo", "bar", "bar"}, false, 3},
+		{"exactly fail early", "exactly", 2, []string{"bar", "bar", "foo"}, false, 2},
+		{"exactly zero", "exactly", 0, []string{"bar", "bar"}, true, 2},
+	}
+
+	for _, test := range tests {
+		childConf := NewConfig()
+		childConf.Type = TypeText
+		childConf.Text.Operator = "equals"
+		childConf.Text.Arg = "foo"
+
+		conf := NewConfig()
+		conf.Type = TypeCountParts
+		conf.CountParts.Operator = test.operator
+		conf.CountParts.Arg = test.arg
+		conf.CountParts.Condition = &childConf
+
+		c, err := New(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		counter := &countingCondition{child: c.(*CountParts).child}
+		c.(*CountParts).child = counter
+
+		parts := make([][]byte, len(test.input))
+		for i, p := range test.input {
+			parts[i] = []byte(p)
+		}
+		if act := c.Check(message.New(parts)); act != test.want {
+			t.Errorf("%v: wrong result: %v != %v", test.name, act, test.want)
+		}
+		if counter.checks != test.checks {
+			t.Errorf("%v: wrong count of child checks: %v != %v", test.name, counter.checks, test.checks)
+		}
+	}
+}
+
+func TestCountPartsChildSeesSinglePart(t *testing.T) {
+	conf := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+type: count_parts
+count_parts:
+  arg: 2
+  condition:
+    type: text
+    text:
+      operator: equals
+      part: -1
+      arg: foo
+`), &conf); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "at_least", conf.CountParts.Operator; exp != act {
+		t.Errorf("Wrong default operator: %v != %v", act, exp)
+	}
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Check(message.New([][]byte{[]byte("foo"), []byte("foo"), []byte("bar")})) {
+		t.Error("Expected condition to pass")
+	}
+}
+
+func TestCountPartsBadConfig(t *testing.T) {
+	childConf := NewConfig()
+
+	conf := NewConfig()
+	conf.Type = TypeCountParts
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing child")
+	}
+
+	conf.CountParts.Condition = &childConf
+	conf.CountParts.Operator = "nope"
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad operator")
+	}
+
+	conf.CountParts.Operator = "exactly"
+	conf.CountParts.Arg = -1
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from negative arg")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -53,3 +53,25 @@ func TestCountCheck(t *testing.T) {
 		}
 	}
 }
+
+func TestCountEveryNth(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeNot
+
+	childConf := NewConfig()
+	childConf.Type = TypeCount
+	childConf.Count.Arg = 3
+	conf.Not.Config = &childConf
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{[]byte("foo")})
+	for i := 1; i <= 9; i++ {
+		if exp, act := i%3 == 0, c.Check(msg); exp != act {
+			t.Errorf("Wrong result for message %v: %v != %v", i, act, exp)
+		}
+	}
+}
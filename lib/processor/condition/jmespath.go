@@ -36,10 +36,13 @@ func init() {
 		constructor: NewJMESPath,
 		description: `
 Parses a message part as a JSON blob and attempts to apply a JMESPath expression
-to it, expecting a boolean response. If the response is true the condition
-passes, otherwise it does not. Please refer to the
-[JMESPath website](http://jmespath.org/) for information and tutorials regarding
-the syntax of expressions.
+to it. If the result is truthy the condition passes, otherwise it does not.
+Please refer to the [JMESPath website](http://jmespath.org/) for information and
+tutorials regarding the syntax of expressions.
+
+A result is truthy unless it is ` + "`false`" + `, ` + "`null`" + `, zero, an
+empty string, an empty array or an empty object. This allows a query such as
+` + "`tags[?@ == 'urgent']`" + ` to pass when any element matches.
 
 For example, with the following config:
 
@@ -59,6 +62,11 @@ If the initial jmespaths of part 0 were:
 
 Then the condition would pass.
 
+Message parts that cannot be parsed as JSON, or where the query fails, do not
+pass the condition. When ` + "`on_invalid_json`" + ` is ` + "`skip`" + ` these
+failures are logged at the debug level, and when it is ` + "`error`" + ` they
+are logged as errors.
+
 JMESPath is traditionally used for mutating JSON, in order to do this please
 instead use the ` + "[`jmespath`](../processors/README.md#jmespath)" + `
 processor.`,
@@ -70,15 +78,17 @@ processor.`,
 // JMESPathConfig is a configuration struct containing fields for the jmespath
 // condition.
 type JMESPathConfig struct {
-	Part  int    `json:"part" yaml:"part"`
-	Query string `json:"query" yaml:"query"`
+	Part          int    `json:"part" yaml:"part"`
+	Query         string `json:"query" yaml:"query"`
+	OnInvalidJSON string `json:"on_invalid_json" yaml:"on_invalid_json"`
 }
 
 // NewJMESPathConfig returns a JMESPathConfig with default values.
 func NewJMESPathConfig() JMESPathConfig {
 	return JMESPathConfig{
-		Part:  0,
-		Query: "",
+		Part:          0,
+		Query:         "",
+		OnInvalidJSON: "skip",
 	}
 }
 
@@ -90,6 +100,7 @@ type JMESPath struct {
 	log   log.Modular
 	part  int
 	query *jmespath.JMESPath
+	logf  func(format string, v ...interface{})
 
 	mSkipped  metrics.StatCounter
 	mErrJSONP metrics.StatCounter
@@ -107,7 +118,7 @@ func NewJMESPath(
 		return nil, fmt.Errorf("failed to compile JMESPath query: %v", err)
 	}
 
-	return &JMESPath{
+	c := &JMESPath{
 		stats: stats,
 		log:   log,
 		part:  conf.JMESPath.Part,
@@ -118,7 +129,17 @@ func NewJMESPath(
 		mDropped:  stats.GetCounter("condition.jmespath.dropped"),
 		mErrJMES:  stats.GetCounter("condition.jmespath.error.jmespath_search"),
 		mApplied:  stats.GetCounter("condition.jmespath.applied"),
-	}, nil
+	}
+
+	switch conf.JMESPath.OnInvalidJSON {
+	case "skip":
+		c.logf = c.log.Debugf
+	case "error":
+		c.logf = c.log.Errorf
+	default:
+		return nil, fmt.Errorf("on_invalid_json not recognised: %v", conf.JMESPath.OnInvalidJSON)
+	}
+	return c, nil
 }
 
 //------------------------------------------------------------------------------
@@ -132,6 +153,27 @@ func safeSearch(part interface{}, j *jmespath.JMESPath) (res interface{}, err er
 	return j.Search(part)
 }
 
+// jmespathTruthy returns whether a JMESPath result is truthy.
+func jmespathTruthy(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case string:
+		return len(t) > 0
+	case float64:
+		return t != 0
+	case int:
+		return t != 0
+	case []interface{}:
+		return len(t) > 0
+	case map[string]interface{}:
+		return len(t) > 0
+	}
+	return true
+}
+
 // Check attempts to check a message part against a configured condition.
 func (c *JMESPath) Check(msg types.Message) bool {
 	index := c.part
@@ -148,7 +190,7 @@ func (c *JMESPath) Check(msg types.Message) bool {
 	if err != nil {
 		c.mErrJSONP.Incr(1)
 		c.mDropped.Incr(1)
-		c.log.Debugf("Failed to parse part into json: %v\n", err)
+		c.logf("Failed to parse part into json: %v\n", err)
 		return false
 	}
 
@@ -156,13 +198,12 @@ func (c *JMESPath) Check(msg types.Message) bool {
 	if result, err = safeSearch(jsonPart, c.query); err != nil {
 		c.mErrJMES.Incr(1)
 		c.mDropped.Incr(1)
-		c.log.Debugf("Failed to search json: %v\n", err)
+		c.logf("Failed to search json: %v\n", err)
 		return false
 	}
 	c.mApplied.Incr(1)
 
-	resultBool, _ := result.(bool)
-	return resultBool
+	return jmespathTruthy(result)
 }
 
 //------------------------------------------------------------------------------
@@ -66,7 +66,7 @@ func TestJMESPathCheck(t *testing.T) {
 			want: false,
 		},
 		{
-			name: "str result neg",
+			name: "str result pos",
 			fields: fields{
 				query: "foo",
 				part:  0,
@@ -74,6 +74,116 @@ func TestJMESPathCheck(t *testing.T) {
 			arg: [][]byte{
 				[]byte(`{"foo":"baz"}`),
 			},
+			want: true,
+		},
+		{
+			name: "empty str result neg",
+			fields: fields{
+				query: "foo",
+				part:  0,
+			},
+			arg: [][]byte{
+				[]byte(`{"foo":""}`),
+			},
+			want: false,
+		},
+		{
+			name: "number result pos",
+			fields: fields{
+				query: "foo",
+				part:  0,
+			},
+			arg: [][]byte{
+				[]byte(`{"foo":5}`),
+			},
+			want: true,
+		},
+		{
+			name: "zero result neg",
+			fields: fields{
+				query: "foo",
+				part:  0,
+			},
+			arg: [][]byte{
+				[]byte(`{"foo":0}`),
+			},
+			want: false,
+		},
+		{
+			name: "null result neg",
+			fields: fields{
+				query: "foo",
+				part:  0,
+			},
+			arg: [][]byte{
+				[]byte(`{"bar":"baz"}`),
+			},
+			want: false,
+		},
+		{
+			name: "array result pos",
+			fields: fields{
+				query: "foo[?@ == 'b']",
+				part:  0,
+			},
+			arg: [][]byte{
+				[]byte(`{"foo":["a","b"]}`),
+			},
+			want: true,
+		},
+		{
+			name: "empty array result neg",
+			fields: fields{
+				query: "foo[?@ == 'c']",
+				part:  0,
+			},
+			arg: [][]byte{
+				[]byte(`{"foo":["a","b"]}`),
+			},
+			want: false,
+		},
+		{
+			name: "object result pos",
+			fields: fields{
+				query: "foo",
+				part:  0,
+			},
+			arg: [][]byte{
+				[]byte(`{"foo":{"a":"b"}}`),
+			},
+			want: true,
+		},
+		{
+			name: "empty object result neg",
+			fields: fields{
+				query: "foo",
+				part:  0,
+			},
+			arg: [][]byte{
+				[]byte(`{"foo":{}}`),
+			},
+			want: false,
+		},
+		{
+			name: "non json neg",
+			fields: fields{
+				query: "foo",
+				part:  0,
+			},
+			arg: [][]byte{
+				[]byte(`not json`),
+			},
+			want: false,
+		},
+		{
+			name: "out of bounds neg",
+			fields: fields{
+				query: "foo",
+				part:  1,
+			},
+			arg: [][]byte{
+				[]byte(`{"foo":"bar"}`),
+			},
 			want: false,
 		},
 	}
@@ -109,3 +219,29 @@ func TestJMESPathBadOperator(t *testing.T) {
 		t.Error("expected error from bad query")
 	}
 }
+
+func TestJMESPathOnInvalidJSON(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	conf.Type = "jmespath"
+	conf.JMESPath.Query = "foo"
+	conf.JMESPath.OnInvalidJSON = "error"
+
+	stats := metrics.NewLocal()
+	c, err := NewJMESPath(conf, nil, testLog, stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Check(message.New([][]byte{[]byte(`not json`)})) {
+		t.Error("Expected non json part not to pass")
+	}
+	if exp, act := int64(1), stats.GetCounters()["condition.jmespath.error.json_parse"]; exp != act {
+		t.Errorf("Wrong count of json errors: %v != %v", act, exp)
+	}
+
+	conf.JMESPath.OnInvalidJSON = "explode"
+	if _, err = NewJMESPath(conf, nil, testLog, stats); err == nil {
+		t.Error("expected error from bad on_invalid_json")
+	}
+}
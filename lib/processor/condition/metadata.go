@@ -41,7 +41,23 @@ func init() {
 		constructor: NewMetadata,
 		description: `
 Metadata is a condition that checks metadata keys of a message part against an
-operator from the following list:
+operator from the following list. If the key does not exist, or has an empty
+value, then all operators other than ` + "`exists`" + ` and
+` + "`not_exists`" + ` resolve to false.
+
+### ` + "`contains`" + `
+
+Checks whether the contents of a metadata key contains an argument. This
+operator is case sensitive.
+
+` + "```yaml" + `
+type: metadata
+metadata:
+  operator: contains
+  part: 0
+  key: foo
+  arg: bar
+` + "```" + `
 
 ### ` + "`enum`" + `
 
@@ -119,7 +135,8 @@ metadata:
 ### ` + "`has_prefix`" + `
 
 Checks whether the contents of a metadata key match one of the provided prefixes.
-The arg field can either be a singular prefix string or a list of prefixes.
+The arg field can either be a singular prefix string or a list of prefixes. This
+operator can also be referred to as ` + "`prefix`" + `.
 
 ` + "```yaml" + `
 type: metadata
@@ -148,6 +165,18 @@ metadata:
   arg: 3
 ` + "```" + `
 
+### ` + "`not_exists`" + `
+
+Checks whether a metadata key does not exist, or has an empty value.
+
+` + "```yaml" + `
+type: metadata
+metadata:
+  operator: not_exists
+  part: 0
+  key: foo
+` + "```" + `
+
 ### ` + "`regexp_partial`" + `
 
 Checks whether any section of the contents of a metadata key matches a regular
@@ -209,6 +238,16 @@ func NewMetadataConfig() MetadataConfig {
 
 type metadataOperator func(md types.Metadata) bool
 
+func metadataContainsOperator(key string, arg interface{}) (metadataOperator, error) {
+	argStr, err := cast.ToStringE(arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse argument as string: %v", err)
+	}
+	return func(md types.Metadata) bool {
+		return strings.Contains(md.Get(key), argStr)
+	}, nil
+}
+
 func metadataEnumOperator(key string, arg interface{}) (metadataOperator, error) {
 	entries, err := cast.ToStringSliceE(arg)
 	if err != nil {
@@ -250,6 +289,12 @@ func metadataExistsOperator(key string) metadataOperator {
 	}
 }
 
+func metadataNotExistsOperator(key string) metadataOperator {
+	return func(md types.Metadata) bool {
+		return len(md.Get(key)) == 0
+	}
+}
+
 func metadataGreaterThanOperator(key string, arg interface{}) (metadataOperator, error) {
 	v, err := cast.ToFloat64E(arg)
 	if err != nil {
@@ -327,19 +372,44 @@ func metadataRegexpExactOperator(key string, arg interface{}) (metadataOperator,
 	}, nil
 }
 
+// metadataRequireKey wraps an operator so that it resolves to false when the
+// key does not exist.
+func metadataRequireKey(key string, op metadataOperator) metadataOperator {
+	return func(md types.Metadata) bool {
+		if len(md.Get(key)) == 0 {
+			return false
+		}
+		return op(md)
+	}
+}
+
 func strToMetadataOperator(str, key string, arg interface{}) (metadataOperator, error) {
 	switch str {
+	case "exists":
+		return metadataExistsOperator(key), nil
+	case "not_exists":
+		return metadataNotExistsOperator(key), nil
+	}
+	op, err := strToMetadataValueOperator(str, key, arg)
+	if err != nil {
+		return nil, err
+	}
+	return metadataRequireKey(key, op), nil
+}
+
+func strToMetadataValueOperator(str, key string, arg interface{}) (metadataOperator, error) {
+	switch str {
+	case "contains":
+		return metadataContainsOperator(key, arg)
 	case "enum":
 		return metadataEnumOperator(key, arg)
 	case "equals":
 		return metadataEqualsOperator(key, arg)
 	case "equals_cs":
 		return metadataEqualsCSOperator(key, arg)
-	case "exists":
-		return metadataExistsOperator(key), nil
 	case "greater_than":
 		return metadataGreaterThanOperator(key, arg)
-	case "has_prefix":
+	case "has_prefix", "prefix":
 		return metadataHasPrefixOperator(key, arg)
 	case "less_than":
 		return metadataLessThanOperator(key, arg)
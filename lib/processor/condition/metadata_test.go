@@ -392,6 +392,160 @@ func TestMetadataCheck(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "contains pos",
+			fields: fields{
+				operator: "contains",
+				key:      "foo",
+				part:     0,
+				arg:      "bar",
+			},
+			arg: map[string]string{
+				"foo": "foobarbaz",
+			},
+			want: true,
+		},
+		{
+			name: "contains neg",
+			fields: fields{
+				operator: "contains",
+				key:      "foo",
+				part:     0,
+				arg:      "BAR",
+			},
+			arg: map[string]string{
+				"foo": "foobarbaz",
+			},
+			want: false,
+		},
+		{
+			name: "prefix pos",
+			fields: fields{
+				operator: "prefix",
+				key:      "foo",
+				part:     0,
+				arg:      "foo",
+			},
+			arg: map[string]string{
+				"foo": "foobar",
+			},
+			want: true,
+		},
+		{
+			name: "prefix neg",
+			fields: fields{
+				operator: "prefix",
+				key:      "foo",
+				part:     0,
+				arg:      "bar",
+			},
+			arg: map[string]string{
+				"foo": "foobar",
+			},
+			want: false,
+		},
+		{
+			name: "not_exists pos",
+			fields: fields{
+				operator: "not_exists",
+				key:      "foo",
+				part:     0,
+			},
+			arg: map[string]string{
+				"bar": "baz",
+			},
+			want: true,
+		},
+		{
+			name: "not_exists neg",
+			fields: fields{
+				operator: "not_exists",
+				key:      "foo",
+				part:     0,
+			},
+			arg: map[string]string{
+				"foo": "baz",
+			},
+			want: false,
+		},
+		{
+			name: "missing equals empty",
+			fields: fields{
+				operator: "equals_cs",
+				key:      "foo",
+				part:     0,
+				arg:      "",
+			},
+			arg: map[string]string{
+				"bar": "baz",
+			},
+			want: false,
+		},
+		{
+			name: "missing contains empty",
+			fields: fields{
+				operator: "contains",
+				key:      "foo",
+				part:     0,
+				arg:      "",
+			},
+			arg: map[string]string{
+				"bar": "baz",
+			},
+			want: false,
+		},
+		{
+			name: "missing regexp_partial",
+			fields: fields{
+				operator: "regexp_partial",
+				key:      "foo",
+				part:     0,
+				arg:      ".*",
+			},
+			arg: map[string]string{
+				"bar": "baz",
+			},
+			want: false,
+		},
+		{
+			name: "missing prefix empty",
+			fields: fields{
+				operator: "prefix",
+				key:      "foo",
+				part:     0,
+				arg:      "",
+			},
+			arg: map[string]string{
+				"bar": "baz",
+			},
+			want: false,
+		},
+		{
+			name: "missing less_than",
+			fields: fields{
+				operator: "less_than",
+				key:      "foo",
+				part:     0,
+				arg:      5,
+			},
+			arg: map[string]string{
+				"bar": "baz",
+			},
+			want: false,
+		},
+		{
+			name: "missing enum",
+			fields: fields{
+				operator: "enum",
+				key:      "foo",
+				part:     0,
+				arg:      []interface{}{""},
+			},
+			arg: map[string]string{
+				"bar": "baz",
+			},
+			want: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
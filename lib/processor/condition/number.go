@@ -0,0 +1,166 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package condition
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeNumber] = TypeSpec{
+		constructor: NewNumber,
+		description: `
+Parses the contents of a message part as a number and compares it against an
+argument with one of the operators ` + "`equals`" + `, ` + "`greater_than`" + `
+or ` + "`less_than`" + `.
+
+Numbers can be integers, decimals or use exponent notation, and are compared
+exactly. Therefore integers that are too large to be represented precisely as a
+floating point number are not rounded before they are compared. Contents that
+cannot be parsed as a number do not pass the condition, and increment the
+counter ` + "`condition.number.error.parse`" + `.
+
+In order to compare a field of a JSON document use this condition as the child
+of a ` + "[`check_field`](#check_field)" + ` condition, and to check a range
+combine it with the ` + "[`and`](#and)" + `, ` + "[`or`](#or)" + ` and
+` + "[`not`](#not)" + ` conditions. For example, the following condition passes
+when the field ` + "`retry_count`" + ` is greater than or equal to three:
+
+` + "``` yaml" + `
+check_field:
+  path: retry_count
+  condition:
+    not:
+      number:
+        operator: less_than
+        arg: 3
+` + "```" + ``,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// numberPattern matches decimal numbers with an optional exponent, and is used
+// to reject the other notations accepted by big.Rat such as fractions and hex.
+var numberPattern = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+
+// NumberConfig is a configuration struct containing fields for the number
+// condition.
+type NumberConfig struct {
+	Operator string  `json:"operator" yaml:"operator"`
+	Part     int     `json:"part" yaml:"part"`
+	Arg      float64 `json:"arg" yaml:"arg"`
+}
+
+// NewNumberConfig returns a NumberConfig with default values.
+func NewNumberConfig() NumberConfig {
+	return NumberConfig{
+		Operator: "equals",
+		Part:     0,
+		Arg:      0,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Number is a condition that compares the contents of a message part as a
+// number against an argument.
+type Number struct {
+	part     int
+	arg      *big.Rat
+	operator func(cmp int) bool
+
+	mSkipped  metrics.StatCounter
+	mErrParse metrics.StatCounter
+	mApplied  metrics.StatCounter
+}
+
+// NewNumber returns a Number condition.
+func NewNumber(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	if math.IsNaN(conf.Number.Arg) || math.IsInf(conf.Number.Arg, 0) {
+		return nil, errors.New("arg must be a finite number")
+	}
+
+	var operator func(cmp int) bool
+	switch conf.Number.Operator {
+	case "equals":
+		operator = func(cmp int) bool { return cmp == 0 }
+	case "greater_than":
+		operator = func(cmp int) bool { return cmp > 0 }
+	case "less_than":
+		operator = func(cmp int) bool { return cmp < 0 }
+	default:
+		return nil, fmt.Errorf("operator not recognised: %v", conf.Number.Operator)
+	}
+
+	return &Number{
+		part:     conf.Number.Part,
+		arg:      new(big.Rat).SetFloat64(conf.Number.Arg),
+		operator: operator,
+
+		mSkipped:  stats.GetCounter("condition.number.skipped"),
+		mErrParse: stats.GetCounter("condition.number.error.parse"),
+		mApplied:  stats.GetCounter("condition.number.applied"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Check attempts to check a message part against a configured condition.
+func (c *Number) Check(msg types.Message) bool {
+	index := c.part
+	if index < 0 {
+		index = msg.Len() + index
+	}
+	if index < 0 || index >= msg.Len() {
+		c.mSkipped.Incr(1)
+		return false
+	}
+
+	str := strings.TrimSpace(string(msg.Get(index).Get()))
+	if !numberPattern.MatchString(str) {
+		c.mErrParse.Incr(1)
+		return false
+	}
+	v, ok := new(big.Rat).SetString(str)
+	if !ok {
+		c.mErrParse.Incr(1)
+		return false
+	}
+
+	c.mApplied.Incr(1)
+	return c.operator(v.Cmp(c.arg))
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,155 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package condition
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	yaml "gopkg.in/yaml.v2"
+)
+
+//------------------------------------------------------------------------------
+
+func TestNumberCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		operator string
+		arg      float64
+		part     int
+		input    []string
+		want     bool
+	}{
+		{"equals int", "equals", 10, 0, []string{"10"}, true},
+		{"equals decimal", "equals", 10, 0, []string{"10.0"}, true},
+		{"equals exponent", "equals", 1000, 0, []string{"1e3"}, true},
+		{"equals whitespace", "equals", 10, 0, []string{" 10\n"}, true},
+		{"equals neg", "equals", 10, 0, []string{"10.0001"}, false},
+		{"greater than pos", "greater_than", 10000, 0, []string{"10000.5"}, true},
+		{"greater than equal", "greater_than", 10000, 0, []string{"10000"}, false},
+		{"greater than negative", "greater_than", -5, 0, []string{"-4"}, true},
+		{"less than pos", "less_than", 3, 0, []string{"2"}, true},
+		{"less than neg", "less_than", 3, 0, []string{"3"}, false},
+		{"large int equals", "equals", 9007199254740992, 0, []string{"9007199254740993"}, false},
+		{"large int greater", "greater_than", 9007199254740992, 0, []string{"9007199254740993"}, true},
+		{"last part", "equals", 2, -1, []string{"1", "2"}, true},
+		{"out of bounds", "equals", 0, 2, []string{"0"}, false},
+		{"not a number", "equals", 0, 0, []string{"zero"}, false},
+		{"empty", "equals", 0, 0, []string{""}, false},
+		{"fraction", "equals", 0.5, 0, []string{"1/2"}, false},
+		{"hex", "equals", 16, 0, []string{"0x10"}, false},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.Type = TypeNumber
+		conf.Number.Operator = test.operator
+		conf.Number.Arg = test.arg
+		conf.Number.Part = test.part
+
+		c, err := New(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+
+		parts := make([][]byte, len(test.input))
+		for i, p := range test.input {
+			parts[i] = []byte(p)
+		}
+		if act := c.Check(message.New(parts)); act != test.want {
+			t.Errorf("%v: wrong result: %v != %v", test.name, act, test.want)
+		}
+	}
+}
+
+func TestNumberParseErrorMetric(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeNumber
+
+	stats := metrics.NewLocal()
+	c, err := New(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Check(message.New([][]byte{[]byte("nope")}))
+	c.Check(message.New([][]byte{[]byte("0")}))
+
+	if exp, act := int64(1), stats.GetCounters()["condition.number.error.parse"]; exp != act {
+		t.Errorf("Wrong count of parse errors: %v != %v", act, exp)
+	}
+}
+
+func TestNumberCheckFieldRange(t *testing.T) {
+	conf := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+type: check_field
+check_field:
+  path: amount
+  condition:
+    type: and
+    and:
+    - type: not
+      not:
+        type: number
+        number:
+          operator: less_than
+          arg: 100
+    - type: number
+      number:
+        operator: less_than
+        arg: 9007199254740992
+`), &conf); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		`{"amount":99}`:               false,
+		`{"amount":100}`:              true,
+		`{"amount":"250.5"}`:          true,
+		`{"amount":9007199254740991}`: true,
+		`{"amount":9007199254740993}`: false,
+		`{"amount":"lots"}`:           false,
+		`{"other":150}`:               false,
+	}
+	for input, exp := range tests {
+		if act := c.Check(message.New([][]byte{[]byte(input)})); act != exp {
+			t.Errorf("Wrong result for '%v': %v != %v", input, act, exp)
+		}
+	}
+}
+
+func TestNumberBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeNumber
+	conf.Number.Operator = "nope"
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad operator")
+	}
+}
+
+//------------------------------------------------------------------------------
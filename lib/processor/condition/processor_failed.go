@@ -0,0 +1,142 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package condition
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeProcessorFailed] = TypeSpec{
+		constructor: NewProcessorFailed,
+		description: `
+Returns true if a message part has been flagged as having failed a processing
+step. By default the condition resolves to true when any part of the message is
+flagged, and a specific part can be checked by setting the field ` + "`part`" + `,
+where negative indexes count backwards from the end of the message.
+
+Processors flag parts by setting the metadata key
+` + "`benthos_processing_failed`" + `, which is preserved when parts are
+grouped, split or archived. This condition can therefore be used at the end of a
+pipeline in order to route failed messages to a dead letter queue:
+
+` + "``` yaml" + `
+output:
+  type: switch
+  switch:
+    outputs:
+    - output:
+        type: kafka
+        kafka:
+          topic: dead_letters
+      condition:
+        type: processor_failed
+    - output:
+        type: kafka
+        kafka:
+          topic: processed
+` + "```" + ``,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessorFailedConfig is a configuration struct containing fields for the
+// processor_failed condition.
+type ProcessorFailedConfig struct {
+	Part *int `json:"part,omitempty" yaml:"part,omitempty"`
+}
+
+// NewProcessorFailedConfig returns a ProcessorFailedConfig with default values.
+func NewProcessorFailedConfig() ProcessorFailedConfig {
+	return ProcessorFailedConfig{
+		Part: nil,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessorFailed is a condition that checks whether message parts have been
+// flagged as having failed a processing step.
+type ProcessorFailed struct {
+	part *int
+
+	mApplied metrics.StatCounter
+	mTrue    metrics.StatCounter
+	mFalse   metrics.StatCounter
+}
+
+// NewProcessorFailed returns a ProcessorFailed condition.
+func NewProcessorFailed(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	return &ProcessorFailed{
+		part: conf.ProcessorFailed.Part,
+
+		mApplied: stats.GetCounter("condition.processor_failed.applied"),
+		mTrue:    stats.GetCounter("condition.processor_failed.true"),
+		mFalse:   stats.GetCounter("condition.processor_failed.false"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func partFailed(part types.Part) bool {
+	return len(part.Metadata().Get(types.FailFlagKey)) > 0
+}
+
+func (p *ProcessorFailed) check(msg types.Message) bool {
+	if p.part == nil {
+		for i := 0; i < msg.Len(); i++ {
+			if partFailed(msg.Get(i)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	index := *p.part
+	lParts := msg.Len()
+	if index < 0 {
+		index = lParts + index
+	}
+	if index < 0 || index >= lParts {
+		return false
+	}
+	return partFailed(msg.Get(index))
+}
+
+// Check attempts to check a message part against a configured condition.
+func (p *ProcessorFailed) Check(msg types.Message) bool {
+	p.mApplied.Incr(1)
+	if p.check(msg) {
+		p.mTrue.Incr(1)
+		return true
+	}
+	p.mFalse.Incr(1)
+	return false
+}
+
+//------------------------------------------------------------------------------
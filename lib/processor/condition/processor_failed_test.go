@@ -0,0 +1,77 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package condition
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func TestProcessorFailedCheck(t *testing.T) {
+	intPtr := func(i int) *int {
+		return &i
+	}
+
+	tests := []struct {
+		name   string
+		part   *int
+		failed []bool
+		want   bool
+	}{
+		{"any no parts", nil, []bool{}, false},
+		{"any none failed", nil, []bool{false, false}, false},
+		{"any one failed", nil, []bool{false, true}, true},
+		{"first failed", intPtr(0), []bool{true, false}, true},
+		{"first not failed", intPtr(0), []bool{false, true}, false},
+		{"last failed", intPtr(-1), []bool{false, true}, true},
+		{"last not failed", intPtr(-1), []bool{true, false}, false},
+		{"out of bounds", intPtr(2), []bool{true, true}, false},
+		{"negative out of bounds", intPtr(-3), []bool{true, true}, false},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.Type = TypeProcessorFailed
+		conf.ProcessorFailed.Part = test.part
+
+		c, err := New(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+
+		msg := message.New(nil)
+		for _, f := range test.failed {
+			part := message.NewPart([]byte("foo"))
+			if f {
+				part.Metadata().Set(types.FailFlagKey, "true")
+			}
+			msg.Append(part)
+		}
+
+		if act := c.Check(msg); act != test.want {
+			t.Errorf("%v: wrong result: %v != %v", test.name, act, test.want)
+		}
+	}
+}
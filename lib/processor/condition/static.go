@@ -32,7 +32,25 @@ func init() {
 	Constructors[TypeStatic] = TypeSpec{
 		constructor: NewStatic,
 		description: `
-Static is a condition that always resolves to the same static boolean value.`,
+Static is a condition that always resolves to the same static boolean value.
+This is useful as an explicit catch-all case at the end of a
+` + "[`switch`](../processors/README.md#switch)" + ` processor, or for
+temporarily enabling or disabling a branch of a config:
+
+` + "``` yaml" + `
+type: switch
+switch:
+- condition:
+    type: resource
+    resource: is_foo
+  processors:
+  - type: noop
+- condition:
+    type: static
+    static: true
+  processors:
+  - type: noop
+` + "```",
 	}
 }
 
@@ -57,21 +57,28 @@ var Constructors = map[string]TypeSpec{}
 const (
 	TypeArchive      = "archive"
 	TypeBatch        = "batch"
+	TypeBloblang     = "bloblang"
 	TypeBoundsCheck  = "bounds_check"
+	TypeCache        = "cache"
 	TypeCombine      = "combine"
 	TypeCompress     = "compress"
 	TypeConditional  = "conditional"
 	TypeDecode       = "decode"
 	TypeDecompress   = "decompress"
 	TypeDedupe       = "dedupe"
+	TypeDelay        = "delay"
 	TypeEncode       = "encode"
 	TypeFilter       = "filter"
 	TypeFilterParts  = "filter_parts"
+	TypeForEach      = "for_each"
+	TypeGeoIP        = "geoip"
 	TypeGrok         = "grok"
 	TypeGroupBy      = "group_by"
+	TypeGroupByValue = "group_by_value"
 	TypeHash         = "hash"
 	TypeHashSample   = "hash_sample"
 	TypeHTTP         = "http"
+	TypeIf           = "if"
 	TypeInsertPart   = "insert_part"
 	TypeJMESPath     = "jmespath"
 	TypeJSON         = "json"
@@ -81,16 +88,26 @@ const (
 	TypeMetadata     = "metadata"
 	TypeMetric       = "metric"
 	TypeNoop         = "noop"
+	TypeParallel     = "parallel"
+	TypeParseURL     = "parse_url"
+	TypeParseUA      = "parse_user_agent"
 	TypeProcessBatch = "process_batch"
 	TypeProcessDAG   = "process_dag"
 	TypeProcessField = "process_field"
 	TypeProcessMap   = "process_map"
+	TypeRateLimit    = "rate_limit"
+	TypeRedact       = "redact"
 	TypeSample       = "sample"
 	TypeSelectParts  = "select_parts"
+	TypeSleep        = "sleep"
 	TypeSplit        = "split"
+	TypeSwitch       = "switch"
 	TypeText         = "text"
 	TypeThrottle     = "throttle"
+	TypeTimestamp    = "timestamp"
 	TypeUnarchive    = "unarchive"
+	TypeWhile        = "while"
+	TypeWindow       = "window"
 )
 
 //------------------------------------------------------------------------------
@@ -100,21 +117,28 @@ type Config struct {
 	Type         string             `json:"type" yaml:"type"`
 	Archive      ArchiveConfig      `json:"archive" yaml:"archive"`
 	Batch        BatchConfig        `json:"batch" yaml:"batch"`
+	Bloblang     BloblangConfig     `json:"bloblang" yaml:"bloblang"`
 	BoundsCheck  BoundsCheckConfig  `json:"bounds_check" yaml:"bounds_check"`
+	Cache        CacheConfig        `json:"cache" yaml:"cache"`
 	Combine      CombineConfig      `json:"combine" yaml:"combine"`
 	Compress     CompressConfig     `json:"compress" yaml:"compress"`
 	Conditional  ConditionalConfig  `json:"conditional" yaml:"conditional"`
 	Decode       DecodeConfig       `json:"decode" yaml:"decode"`
 	Decompress   DecompressConfig   `json:"decompress" yaml:"decompress"`
 	Dedupe       DedupeConfig       `json:"dedupe" yaml:"dedupe"`
+	Delay        DelayConfig        `json:"delay" yaml:"delay"`
 	Encode       EncodeConfig       `json:"encode" yaml:"encode"`
 	Filter       FilterConfig       `json:"filter" yaml:"filter"`
 	FilterParts  FilterPartsConfig  `json:"filter_parts" yaml:"filter_parts"`
+	ForEach      ForEachConfig      `json:"for_each" yaml:"for_each"`
+	GeoIP        GeoIPConfig        `json:"geoip" yaml:"geoip"`
 	Grok         GrokConfig         `json:"grok" yaml:"grok"`
 	GroupBy      GroupByConfig      `json:"group_by" yaml:"group_by"`
+	GroupByValue GroupByValueConfig `json:"group_by_value" yaml:"group_by_value"`
 	Hash         HashConfig         `json:"hash" yaml:"hash"`
 	HashSample   HashSampleConfig   `json:"hash_sample" yaml:"hash_sample"`
 	HTTP         HTTPConfig         `json:"http" yaml:"http"`
+	If           IfConfig           `json:"if" yaml:"if"`
 	InsertPart   InsertPartConfig   `json:"insert_part" yaml:"insert_part"`
 	JMESPath     JMESPathConfig     `json:"jmespath" yaml:"jmespath"`
 	JSON         JSONConfig         `json:"json" yaml:"json"`
@@ -123,17 +147,29 @@ type Config struct {
 	MergeJSON    MergeJSONConfig    `json:"merge_json" yaml:"merge_json"`
 	Metadata     MetadataConfig     `json:"metadata" yaml:"metadata"`
 	Metric       MetricConfig       `json:"metric" yaml:"metric"`
+	Parallel     ParallelConfig     `json:"parallel" yaml:"parallel"`
+	ParseURL     ParseURLConfig     `json:"parse_url" yaml:"parse_url"`
+	ParseUA      ParseUAConfig      `json:"parse_user_agent" yaml:"parse_user_agent"`
 	Plugin       interface{}        `json:"plugin,omitempty" yaml:"plugin,omitempty"`
 	ProcessBatch ProcessBatchConfig `json:"process_batch" yaml:"process_batch"`
 	ProcessDAG   ProcessDAGConfig   `json:"process_dag" yaml:"process_dag"`
 	ProcessField ProcessFieldConfig `json:"process_field" yaml:"process_field"`
 	ProcessMap   ProcessMapConfig   `json:"process_map" yaml:"process_map"`
+	RateLimit    RateLimitConfig    `json:"rate_limit" yaml:"rate_limit"`
+	Redact       RedactConfig       `json:"redact" yaml:"redact"`
 	Sample       SampleConfig       `json:"sample" yaml:"sample"`
 	SelectParts  SelectPartsConfig  `json:"select_parts" yaml:"select_parts"`
+	Sleep        SleepConfig        `json:"sleep" yaml:"sleep"`
 	Split        SplitConfig        `json:"split" yaml:"split"`
+	Switch       SwitchConfig       `json:"switch" yaml:"switch"`
 	Text         TextConfig         `json:"text" yaml:"text"`
 	Throttle     ThrottleConfig     `json:"throttle" yaml:"throttle"`
+	Timestamp    TimestampConfig    `json:"timestamp" yaml:"timestamp"`
 	Unarchive    UnarchiveConfig    `json:"unarchive" yaml:"unarchive"`
+	While        WhileConfig        `json:"while" yaml:"while"`
+	Window       WindowConfig       `json:"window" yaml:"window"`
+
+	TrackLatency bool `json:"track_latency" yaml:"track_latency"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
@@ -142,21 +178,28 @@ func NewConfig() Config {
 		Type:         "bounds_check",
 		Archive:      NewArchiveConfig(),
 		Batch:        NewBatchConfig(),
+		Bloblang:     NewBloblangConfig(),
 		BoundsCheck:  NewBoundsCheckConfig(),
+		Cache:        NewCacheConfig(),
 		Combine:      NewCombineConfig(),
 		Compress:     NewCompressConfig(),
 		Conditional:  NewConditionalConfig(),
 		Decode:       NewDecodeConfig(),
 		Decompress:   NewDecompressConfig(),
 		Dedupe:       NewDedupeConfig(),
+		Delay:        NewDelayConfig(),
 		Encode:       NewEncodeConfig(),
 		Filter:       NewFilterConfig(),
 		FilterParts:  NewFilterPartsConfig(),
+		ForEach:      NewForEachConfig(),
+		GeoIP:        NewGeoIPConfig(),
 		Grok:         NewGrokConfig(),
 		GroupBy:      NewGroupByConfig(),
+		GroupByValue: NewGroupByValueConfig(),
 		Hash:         NewHashConfig(),
 		HashSample:   NewHashSampleConfig(),
 		HTTP:         NewHTTPConfig(),
+		If:           NewIfConfig(),
 		InsertPart:   NewInsertPartConfig(),
 		JMESPath:     NewJMESPathConfig(),
 		JSON:         NewJSONConfig(),
@@ -165,17 +208,29 @@ func NewConfig() Config {
 		MergeJSON:    NewMergeJSONConfig(),
 		Metadata:     NewMetadataConfig(),
 		Metric:       NewMetricConfig(),
+		Parallel:     NewParallelConfig(),
+		ParseURL:     NewParseURLConfig(),
+		ParseUA:      NewParseUAConfig(),
 		Plugin:       nil,
 		ProcessBatch: NewProcessBatchConfig(),
 		ProcessDAG:   NewProcessDAGConfig(),
 		ProcessField: NewProcessFieldConfig(),
 		ProcessMap:   NewProcessMapConfig(),
+		RateLimit:    NewRateLimitConfig(),
+		Redact:       NewRedactConfig(),
 		Sample:       NewSampleConfig(),
 		SelectParts:  NewSelectPartsConfig(),
+		Sleep:        NewSleepConfig(),
 		Split:        NewSplitConfig(),
+		Switch:       NewSwitchConfig(),
 		Text:         NewTextConfig(),
 		Throttle:     NewThrottleConfig(),
+		Timestamp:    NewTimestampConfig(),
 		Unarchive:    NewUnarchiveConfig(),
+		While:        NewWhileConfig(),
+		Window:       NewWindowConfig(),
+
+		TrackLatency: false,
 	}
 }
 
@@ -194,6 +249,9 @@ func SanitiseConfig(conf Config) (interface{}, error) {
 
 	outputMap := config.Sanitised{}
 	outputMap["type"] = conf.Type
+	if conf.TrackLatency {
+		outputMap["track_latency"] = true
+	}
 	if sfunc := Constructors[conf.Type].sanitiseConfigFunc; sfunc != nil {
 		if outputMap[conf.Type], err = sfunc(conf); err != nil {
 			return nil, err
@@ -307,8 +365,22 @@ element will be selected, and so on.
 
 Some processors such as ` + "`filter` and `dedupe`" + ` act across an entire
 batch, when instead we'd like to perform them on individual messages of a batch.
-In this case the ` + "[`process_batch`](#process_batch)" + ` processor can be
-used.`
+In this case the ` + "[`for_each`](#for_each)" + ` processor can be used.
+
+### Latency
+
+The time taken by each processor listed within an input, pipeline or output to
+process a message batch is recorded as the timing metric
+` + "`<layer>.processors.<index>.<type>.latency`" + ` in nanoseconds, e.g.
+` + "`pipeline.processors.2.jmespath.latency`" + ` for the third processor of
+the pipeline section. The time taken by processors that are nested within other
+processors is included in the time of their parent.
+
+When the field ` + "`track_latency`" + ` of a processor is set to
+` + "`true`" + ` the time taken is also written to the metadata of each
+resulting message part with the key
+` + "`benthos_proc_<layer>_<index>_<type>_ms`" + `, in milliseconds. This has no
+effect on processors that are nested within other processors.`
 
 var footer = `
 [0]: ./examples.md`
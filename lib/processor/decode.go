@@ -22,7 +22,9 @@ package processor
 
 import (
 	"bytes"
+	"encoding/ascii85"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 
@@ -39,7 +41,16 @@ func init() {
 		constructor: NewDecode,
 		description: `
 Decodes parts of a message according to the selected scheme. Supported available
-schemes are: base64.`,
+schemes are: base64, base64url, hex, ascii85.
+
+The ` + "`base64`" + ` scheme uses the standard encoding and ` + "`base64url`" + `
+uses the URL and filename safe alternative encoding, both are expected to be
+padded.
+
+### Error Handling
+
+Message parts that fail to be decoded are left unchanged and are flagged as
+failed.`,
 	}
 }
 
@@ -68,10 +79,35 @@ func base64Decode(b []byte) ([]byte, error) {
 	return ioutil.ReadAll(e)
 }
 
+func base64URLDecode(b []byte) ([]byte, error) {
+	e := base64.NewDecoder(base64.URLEncoding, bytes.NewReader(b))
+	return ioutil.ReadAll(e)
+}
+
+func hexDecode(b []byte) ([]byte, error) {
+	d := make([]byte, hex.DecodedLen(len(b)))
+	n, err := hex.Decode(d, b)
+	if err != nil {
+		return nil, err
+	}
+	return d[:n], nil
+}
+
+func ascii85Decode(b []byte) ([]byte, error) {
+	d := ascii85.NewDecoder(bytes.NewReader(b))
+	return ioutil.ReadAll(d)
+}
+
 func strToDecoder(str string) (decodeFunc, error) {
 	switch str {
 	case "base64":
 		return base64Decode, nil
+	case "base64url":
+		return base64URLDecode, nil
+	case "hex":
+		return hexDecode, nil
+	case "ascii85":
+		return ascii85Decode, nil
 	}
 	return nil, fmt.Errorf("decode scheme not recognised: %v", str)
 }
@@ -142,8 +178,9 @@ func (c *Decode) ProcessMessage(msg types.Message) ([]types.Message, types.Respo
 			c.mSucc.Incr(1)
 			newMsg.Get(index).Set(newPart)
 		} else {
-			c.log.Errorf("Failed to decode message part: %v\n", err)
+			c.log.Debugf("Failed to decode message part: %v\n", err)
 			c.mErr.Incr(1)
+			FlagFail(newMsg.Get(index))
 		}
 	}
 
@@ -166,3 +166,92 @@ func TestDecodeEmpty(t *testing.T) {
 		t.Error("Expected failure with zero part message")
 	}
 }
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	binary := make([]byte, 256)
+	for i := range binary {
+		binary[i] = byte(i)
+	}
+
+	input := [][]byte{
+		[]byte("hello world"),
+		[]byte(`{"foo":"bar"}`),
+		binary,
+		{0xff, 0xfe, 0x00, 0x01},
+		[]byte("a"),
+	}
+
+	for _, scheme := range []string{"base64", "base64url", "hex", "ascii85"} {
+		encConf := NewConfig()
+		encConf.Encode.Scheme = scheme
+		enc, err := NewEncode(encConf, nil, testLog, metrics.DudType{})
+		if err != nil {
+			t.Fatalf("Failed to create encoder for '%v': %v", scheme, err)
+		}
+
+		decConf := NewConfig()
+		decConf.Decode.Scheme = scheme
+		dec, err := NewDecode(decConf, nil, testLog, metrics.DudType{})
+		if err != nil {
+			t.Fatalf("Failed to create decoder for '%v': %v", scheme, err)
+		}
+
+		msgs, res := enc.ProcessMessage(message.New(input))
+		if len(msgs) != 1 || res != nil {
+			t.Fatalf("Encode failed for '%v': %v", scheme, res)
+		}
+		encoded := message.GetAllBytes(msgs[0])
+		if reflect.DeepEqual(input, encoded) {
+			t.Errorf("Input and encoded output are the same for '%v'", scheme)
+		}
+
+		if msgs, res = dec.ProcessMessage(msgs[0]); len(msgs) != 1 || res != nil {
+			t.Fatalf("Decode failed for '%v': %v", scheme, res)
+		}
+		if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(input, act) {
+			t.Errorf("Wrong round trip result for '%v': %v != %v", scheme, act, input)
+		}
+		for i := 0; i < msgs[0].Len(); i++ {
+			if HasFailed(msgs[0].Get(i)) {
+				t.Errorf("Part %v flagged as failed for '%v'", i, scheme)
+			}
+		}
+	}
+}
+
+func TestDecodeFailures(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	tests := map[string][]byte{
+		"base64":    []byte("not%base64"),
+		"base64url": []byte("not+base64/"),
+		"hex":       []byte("not hex"),
+		"ascii85":   []byte("not ascii85 ~~"),
+	}
+
+	for scheme, bad := range tests {
+		conf := NewConfig()
+		conf.Decode.Scheme = scheme
+		proc, err := NewDecode(conf, nil, testLog, metrics.DudType{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		input := [][]byte{bad, []byte("")}
+		msgs, res := proc.ProcessMessage(message.New(input))
+		if len(msgs) != 1 || res != nil {
+			t.Fatalf("Decode failed for '%v': %v", scheme, res)
+		}
+		if exp, act := bad, msgs[0].Get(0).Get(); !reflect.DeepEqual(exp, act) {
+			t.Errorf("Failed part was modified for '%v': %s != %s", scheme, act, exp)
+		}
+		if !HasFailed(msgs[0].Get(0)) {
+			t.Errorf("Expected part to be flagged as failed for '%v'", scheme)
+		}
+		if HasFailed(msgs[0].Get(1)) {
+			t.Errorf("Expected empty part not to be flagged as failed for '%v'", scheme)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeDelay] = TypeSpec{
+		constructor: NewDelay,
+		description: `
+Holds a message batch until a point in time resolved from each message part as
+a unix timestamp in milliseconds. The ` + "`until`" + ` field supports
+[function interpolation](../config_interpolation.md#functions) and defaults to
+the ` + "`dead_letter_retry_at`" + ` metadata field set by the
+` + "[`dead_letter`](../outputs/README.md#dead_letter)" + ` output, which allows
+you to build an exponential retry loop by consuming the dead letter queue:
+
+` + "``` yaml" + `
+input:
+  type: kafka
+  kafka:
+    topic: dead_letters
+  processors:
+  - type: delay
+output:
+  type: broker
+  broker:
+    pattern: try
+    outputs:
+    - type: http_client
+      http_client:
+        url: http://localhost:8080/post
+    - type: dead_letter
+      dead_letter:
+        max_attempts: 10
+        output:
+          type: kafka
+          kafka:
+            topic: dead_letters
+` + "```" + `
+
+The batch is held until the latest time resolved from any of its parts. Parts
+where the time is empty, cannot be parsed or has already passed do not cause a
+delay. The period of any single hold is capped at ` + "`max_delay`" + `, and any
+hold is interrupted when the pipeline is closed.
+
+Holding a message blocks the pipeline thread it occupies and delays the
+acknowledgement of the message at the input, which means messages are never lost
+when Benthos is restarted during a hold. However, inputs with acknowledgement
+deadlines (such as SQS visibility timeouts) may redeliver held messages, in
+which case ` + "`max_delay`" + ` should be set below that deadline.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// DelayConfig contains configuration fields for the Delay processor.
+type DelayConfig struct {
+	Until    string `json:"until" yaml:"until"`
+	MaxDelay string `json:"max_delay" yaml:"max_delay"`
+}
+
+// NewDelayConfig returns a DelayConfig with default values.
+func NewDelayConfig() DelayConfig {
+	return DelayConfig{
+		Until:    "${!metadata:dead_letter_retry_at}",
+		MaxDelay: "1h",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Delay is a processor that holds message batches until a time resolved from
+// their contents.
+type Delay struct {
+	closed int32
+
+	log   log.Modular
+	stats metrics.Type
+
+	until    *text.InterpolatedString
+	maxDelay time.Duration
+	nowFn    func() time.Time
+
+	closeChan chan struct{}
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mDelayed   metrics.StatCounter
+	mSent      metrics.StatCounter
+	mSentParts metrics.StatCounter
+}
+
+// NewDelay returns a Delay processor.
+func NewDelay(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	d := &Delay{
+		log:   log.NewModule(".processor.delay"),
+		stats: stats,
+
+		until:     text.NewInterpolatedString(conf.Delay.Until),
+		nowFn:     time.Now,
+		closeChan: make(chan struct{}),
+
+		mCount:     stats.GetCounter("processor.delay.count"),
+		mErr:       stats.GetCounter("processor.delay.error"),
+		mDelayed:   stats.GetCounter("processor.delay.delayed"),
+		mSent:      stats.GetCounter("processor.delay.sent"),
+		mSentParts: stats.GetCounter("processor.delay.parts.sent"),
+	}
+	if len(conf.Delay.MaxDelay) > 0 {
+		var err error
+		if d.maxDelay, err = time.ParseDuration(conf.Delay.MaxDelay); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+//------------------------------------------------------------------------------
+
+// holdPeriod returns the period that a message batch should be held for.
+func (d *Delay) holdPeriod(msg types.Message) time.Duration {
+	var latest int64
+	for i := 0; i < msg.Len(); i++ {
+		untilStr := d.until.Get(message.Lock(msg, i))
+		if len(untilStr) == 0 {
+			continue
+		}
+		until, err := strconv.ParseInt(untilStr, 10, 64)
+		if err != nil {
+			d.mErr.Incr(1)
+			d.log.Errorf("Failed to parse timestamp '%v': %v\n", untilStr, err)
+			continue
+		}
+		if until > latest {
+			latest = until
+		}
+	}
+	if latest == 0 {
+		return 0
+	}
+
+	period := time.Unix(0, latest*int64(time.Millisecond)).Sub(d.nowFn())
+	if d.maxDelay > 0 && period > d.maxDelay {
+		period = d.maxDelay
+	}
+	return period
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (d *Delay) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	d.mCount.Incr(1)
+
+	if period := d.holdPeriod(msg); period > 0 {
+		d.mDelayed.Incr(1)
+		select {
+		case <-time.After(period):
+		case <-d.closeChan:
+		}
+	}
+
+	d.mSent.Incr(1)
+	d.mSentParts.Incr(int64(msg.Len()))
+	msgs := [1]types.Message{msg}
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and interrupts any ongoing hold.
+func (d *Delay) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&d.closed, 0, 1) {
+		close(d.closeChan)
+	}
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (d *Delay) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
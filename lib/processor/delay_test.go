@@ -0,0 +1,139 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestDelayHoldPeriod(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeDelay
+	conf.Delay.MaxDelay = "10s"
+
+	stats := metrics.NewLocal()
+	proc, err := New(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := proc.(*Delay)
+	d.nowFn = func() time.Time {
+		return time.Unix(1000, 0)
+	}
+
+	tests := []struct {
+		name   string
+		values []string
+		exp    time.Duration
+	}{
+		{"no metadata", []string{""}, 0},
+		{"passed", []string{"999000"}, -time.Second},
+		{"single", []string{"1002000"}, time.Second * 2},
+		{"latest part", []string{"1001000", "1003500", ""}, time.Millisecond * 3500},
+		{"capped", []string{"2000000"}, time.Second * 10},
+		{"invalid", []string{"nope"}, 0},
+	}
+
+	for _, test := range tests {
+		msg := message.New(nil)
+		for _, v := range test.values {
+			part := message.NewPart([]byte("foo"))
+			if len(v) > 0 {
+				part.Metadata().Set("dead_letter_retry_at", v)
+			}
+			msg.Append(part)
+		}
+		if act := d.holdPeriod(msg); act != test.exp {
+			t.Errorf("%v: wrong period: %v != %v", test.name, act, test.exp)
+		}
+	}
+
+	if exp, act := int64(1), stats.GetCounters()["processor.delay.error"]; exp != act {
+		t.Errorf("Wrong count of errors: %v != %v", act, exp)
+	}
+}
+
+func TestDelayHolds(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeDelay
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	until := time.Now().Add(time.Millisecond * 200)
+
+	msg := message.New([][]byte{[]byte("foo")})
+	msg.Get(0).Metadata().Set(
+		"dead_letter_retry_at",
+		strconv.FormatInt(until.UnixNano()/int64(time.Millisecond), 10),
+	)
+
+	msgsOut, res := proc.ProcessMessage(msg)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := msg, msgsOut[0]; exp != act {
+		t.Errorf("Wrong message returned: %v != %v", act, exp)
+	}
+	if time.Now().Before(until.Add(-time.Millisecond)) {
+		t.Error("Message was released too early")
+	}
+}
+
+func TestDelayClose(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeDelay
+	conf.Delay.Until = "${!metadata:retry_at}"
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{[]byte("foo")})
+	msg.Get(0).Metadata().Set(
+		"retry_at",
+		strconv.FormatInt(time.Now().Add(time.Minute).UnixNano()/int64(time.Millisecond), 10),
+	)
+
+	go func() {
+		<-time.After(time.Millisecond * 50)
+		proc.(*Delay).CloseAsync()
+	}()
+
+	tBefore := time.Now()
+	proc.ProcessMessage(msg)
+	if dur := time.Since(tBefore); dur > time.Second*10 {
+		t.Errorf("Hold was not interrupted: %v", dur)
+	}
+	if err = proc.(*Delay).WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
@@ -22,7 +22,9 @@ package processor
 
 import (
 	"bytes"
+	"encoding/ascii85"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -38,7 +40,15 @@ func init() {
 		constructor: NewEncode,
 		description: `
 Encodes parts of a message according to the selected scheme. Supported schemes
-are: base64.`,
+are: base64, base64url, hex, ascii85.
+
+The ` + "`base64`" + ` scheme uses the standard encoding and ` + "`base64url`" + `
+uses the URL and filename safe alternative encoding, both are padded.
+
+### Error Handling
+
+Message parts that fail to be encoded are left unchanged and are flagged as
+failed.`,
 	}
 }
 
@@ -72,10 +82,33 @@ func base64Encode(b []byte) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+func base64URLEncode(b []byte) ([]byte, error) {
+	e := make([]byte, base64.URLEncoding.EncodedLen(len(b)))
+	base64.URLEncoding.Encode(e, b)
+	return e, nil
+}
+
+func hexEncode(b []byte) ([]byte, error) {
+	e := make([]byte, hex.EncodedLen(len(b)))
+	hex.Encode(e, b)
+	return e, nil
+}
+
+func ascii85Encode(b []byte) ([]byte, error) {
+	e := make([]byte, ascii85.MaxEncodedLen(len(b)))
+	return e[:ascii85.Encode(e, b)], nil
+}
+
 func strToEncoder(str string) (encodeFunc, error) {
 	switch str {
 	case "base64":
 		return base64Encode, nil
+	case "base64url":
+		return base64URLEncode, nil
+	case "hex":
+		return hexEncode, nil
+	case "ascii85":
+		return ascii85Encode, nil
 	}
 	return nil, fmt.Errorf("encode scheme not recognised: %v", str)
 }
@@ -148,6 +181,7 @@ func (c *Encode) ProcessMessage(msg types.Message) ([]types.Message, types.Respo
 		} else {
 			c.log.Debugf("Failed to encode message part: %v\n", err)
 			c.mErr.Incr(1)
+			FlagFail(newMsg.Get(index))
 		}
 	}
 
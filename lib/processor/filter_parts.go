@@ -44,7 +44,14 @@ in this case each condition will be applied to a part as if it were a single
 part message.
 
 This processor is useful if you are combining messages into batches using the
-` + "[`batch`](#batch)" + ` processor and wish to remove specific parts.`,
+` + "[`batch`](#batch)" + ` processor and wish to remove specific parts. Unlike
+the ` + "[`filter`](#filter)" + ` processor, which checks a condition against a
+batch as a whole and keeps or drops the entire batch, this processor keeps the
+parts that pass and preserves their order and metadata.
+
+Dropped parts are still acknowledged at the source once the remaining parts of
+the batch have been successfully sent, and if all parts are dropped then the
+batch is acknowledged immediately.`,
 		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
 			return condition.SanitiseConfig(conf.FilterParts.Config)
 		},
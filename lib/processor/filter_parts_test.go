@@ -105,3 +105,42 @@ func TestFilterPartsTextCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterPartsMetadataAndMetrics(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "filter_parts"
+	conf.FilterParts.Type = "metadata"
+	conf.FilterParts.Metadata.Operator = "equals_cs"
+	conf.FilterParts.Metadata.Key = "keep"
+	conf.FilterParts.Metadata.Arg = "true"
+
+	stats := metrics.NewLocal()
+	c, err := New(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte("foo"), []byte("bar"), []byte("baz"),
+	})
+	input.Get(0).Metadata().Set("keep", "true").Set("id", "1")
+	input.Get(1).Metadata().Set("keep", "false")
+	input.Get(2).Metadata().Set("keep", "true").Set("id", "3")
+
+	msgs, res := c.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := [][]byte{[]byte("foo"), []byte("baz")}, message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if exp, act := "3", msgs[0].Get(1).Metadata().Get("id"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+	if exp, act := 3, input.Len(); exp != act {
+		t.Errorf("Input message was modified: %v != %v", act, exp)
+	}
+	if exp, act := int64(1), stats.GetCounters()["processor.filter_parts.part.dropped"]; exp != act {
+		t.Errorf("Wrong count of dropped parts: %v != %v", act, exp)
+	}
+}
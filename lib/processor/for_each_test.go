@@ -0,0 +1,185 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package processor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/processor/condition"
+)
+
+//------------------------------------------------------------------------------
+
+func newForEachFilterConf(arg string) Config {
+	cond := condition.NewConfig()
+	cond.Type = "text"
+	cond.Text.Arg = arg
+	cond.Text.Operator = "contains"
+
+	filterConf := NewConfig()
+	filterConf.Type = "filter"
+	filterConf.Filter.Config = cond
+	return filterConf
+}
+
+func newForEachUnarchiveConf() Config {
+	unarchiveConf := NewConfig()
+	unarchiveConf.Type = "unarchive"
+	unarchiveConf.Unarchive.Format = "lines"
+	return unarchiveConf
+}
+
+func TestForEachFilter(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "for_each"
+	conf.ForEach = append(conf.ForEach, newForEachFilterConf("foo"))
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := [][]byte{
+		[]byte("1 2 3 4"),
+		[]byte("foo bar baz"),
+		[]byte("5 6 7 8"),
+		[]byte("hello foo world"),
+	}
+	exp := [][]byte{
+		[]byte("foo bar baz"),
+		[]byte("hello foo world"),
+	}
+	msgs, res := proc.ProcessMessage(message.New(parts))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of result msgs: %v", len(msgs))
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong results: %s != %s", act, exp)
+	}
+}
+
+func TestForEachUnarchive(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "for_each"
+	conf.ForEach = append(conf.ForEach, newForEachUnarchiveConf())
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := [][]byte{
+		[]byte("foo\nbar"),
+		[]byte("baz"),
+		[]byte("qux\nquz\nquv"),
+	}
+	exp := [][]byte{
+		[]byte("foo"),
+		[]byte("bar"),
+		[]byte("baz"),
+		[]byte("qux"),
+		[]byte("quz"),
+		[]byte("quv"),
+	}
+	msgs, res := proc.ProcessMessage(message.New(parts))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of result msgs: %v", len(msgs))
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong results: %s != %s", act, exp)
+	}
+}
+
+func TestForEachFilterThenUnarchive(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "for_each"
+	conf.ForEach = append(
+		conf.ForEach,
+		newForEachFilterConf("keep"),
+		newForEachUnarchiveConf(),
+	)
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := [][]byte{
+		[]byte("keep a1\nkeep b1\nkeep a2"),
+		[]byte("drop a3\ndrop a4"),
+		[]byte("keep b2"),
+		[]byte("keep a5"),
+	}
+	exp := [][]byte{
+		[]byte("keep a1"),
+		[]byte("keep b1"),
+		[]byte("keep a2"),
+		[]byte("keep b2"),
+		[]byte("keep a5"),
+	}
+	msgs, res := proc.ProcessMessage(message.New(parts))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of result msgs: %v", len(msgs))
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong results: %s != %s", act, exp)
+	}
+}
+
+func TestForEachFilterAll(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "for_each"
+	conf.ForEach = append(conf.ForEach, newForEachFilterConf("foo"))
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := [][]byte{
+		[]byte("bar baz"),
+		[]byte("hello world"),
+	}
+	msgs, res := proc.ProcessMessage(message.New(parts))
+	if res == nil {
+		t.Fatal("expected empty response")
+	}
+	if err = res.Error(); err != nil {
+		t.Error(err)
+	}
+	if len(msgs) != 0 {
+		t.Errorf("Wrong count of result msgs: %v", len(msgs))
+	}
+}
+
+//------------------------------------------------------------------------------
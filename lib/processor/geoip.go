@@ -0,0 +1,491 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/mmdb"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/Jeffail/gabs"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeGeoIP] = TypeSpec{
+		constructor: NewGeoIP,
+		description: `
+Looks up an IP address within a [MaxMind DB](https://maxmind.github.io/MaxMind-DB/)
+file, such as the GeoIP2 and GeoLite2 databases, and writes the results as an
+object at a ` + "`target`" + ` path of the JSON message part.
+
+The IP address is taken from the ` + "`source`" + ` field, which supports
+[interpolation functions](../config_interpolation.md#functions) resolved
+individually for each message part. This allows you to read the address from
+metadata (` + "`${!metadata:ip}`" + `) or from a field of the JSON document
+(` + "`${!json_field:client.ip}`" + `).
+
+The ` + "`lookups`" + ` field selects the results to add, where each is written
+as an object under its own key:
+
+- ` + "`country`" + `: ` + "`iso_code`, `name` and `continent_code`" + `.
+- ` + "`city`" + `: ` + "`name`, `subdivision`, `postal_code`, `latitude`, `longitude` and `time_zone`" + `.
+- ` + "`asn`" + `: ` + "`number` and `organization`" + `.
+
+Fields that are not present in the database are omitted. For example, with the
+following config:
+
+` + "``` yaml" + `
+geoip:
+  file: /var/lib/GeoLite2-City.mmdb
+  source: ${!json_field:client_ip}
+  target: geo
+  lookups:
+  - country
+  - city
+` + "```" + `
+
+The document ` + "`{\"client_ip\":\"81.2.69.160\"}`" + ` might become:
+
+` + "``` json" + `
+{
+  "client_ip": "81.2.69.160",
+  "geo": {
+    "city": {"latitude":51.5142,"longitude":-0.0931,"name":"London","time_zone":"Europe/London"},
+    "country": {"continent_code":"EU","iso_code":"GB","name":"United Kingdom"}
+  }
+}
+` + "```" + `
+
+If the IP address is invalid or is not found within the database an empty
+object is written to the target and the counters
+` + "`processor.geoip.error.ip`" + ` and ` + "`processor.geoip.miss`" + `
+are incremented respectively. Message parts that cannot be parsed as JSON, or
+where the lookup fails due to a corrupt database, are flagged as failed.
+
+The database file is memory mapped. When ` + "`reload_interval`" + ` is not
+empty the file is checked periodically and reloaded when its modification time
+or size changes. In order to avoid reading a partially written database it
+should be replaced atomically, e.g. by writing a new file and renaming it over
+the old one.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// GeoIPConfig contains configuration fields for the GeoIP processor.
+type GeoIPConfig struct {
+	Parts          []int    `json:"parts" yaml:"parts"`
+	File           string   `json:"file" yaml:"file"`
+	Source         string   `json:"source" yaml:"source"`
+	Target         string   `json:"target" yaml:"target"`
+	Lookups        []string `json:"lookups" yaml:"lookups"`
+	ReloadInterval string   `json:"reload_interval" yaml:"reload_interval"`
+}
+
+// NewGeoIPConfig returns a GeoIPConfig with default values.
+func NewGeoIPConfig() GeoIPConfig {
+	return GeoIPConfig{
+		Parts:          []int{},
+		File:           "",
+		Source:         "${!metadata:ip}",
+		Target:         "geoip",
+		Lookups:        []string{"country", "city"},
+		ReloadInterval: "1m",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type geoIPLookup func(record map[string]interface{}) map[string]interface{}
+
+func geoIPValue(v interface{}, path ...string) (interface{}, bool) {
+	for _, key := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+func geoIPFields(v interface{}, fields map[string][]string) map[string]interface{} {
+	result := map[string]interface{}{}
+	for k, path := range fields {
+		if value, exists := geoIPValue(v, path...); exists {
+			result[k] = value
+		}
+	}
+	return result
+}
+
+func geoIPCountry(record map[string]interface{}) map[string]interface{} {
+	return geoIPFields(record, map[string][]string{
+		"iso_code":       {"country", "iso_code"},
+		"name":           {"country", "names", "en"},
+		"continent_code": {"continent", "code"},
+	})
+}
+
+func geoIPCity(record map[string]interface{}) map[string]interface{} {
+	result := geoIPFields(record, map[string][]string{
+		"name":        {"city", "names", "en"},
+		"postal_code": {"postal", "code"},
+		"latitude":    {"location", "latitude"},
+		"longitude":   {"location", "longitude"},
+		"time_zone":   {"location", "time_zone"},
+	})
+	if subs, ok := record["subdivisions"].([]interface{}); ok && len(subs) > 0 {
+		if name, exists := geoIPValue(subs[0], "names", "en"); exists {
+			result["subdivision"] = name
+		}
+	}
+	return result
+}
+
+func geoIPASN(record map[string]interface{}) map[string]interface{} {
+	return geoIPFields(record, map[string][]string{
+		"number":       {"autonomous_system_number"},
+		"organization": {"autonomous_system_organization"},
+	})
+}
+
+func geoIPLookupFromString(str string) (geoIPLookup, error) {
+	switch str {
+	case "country":
+		return geoIPCountry, nil
+	case "city":
+		return geoIPCity, nil
+	case "asn":
+		return geoIPASN, nil
+	}
+	return nil, fmt.Errorf("lookup not recognised: %v", str)
+}
+
+//------------------------------------------------------------------------------
+
+// GeoIP is a processor that enriches JSON message parts with the results of
+// looking up an IP address within a MaxMind DB file.
+type GeoIP struct {
+	parts      []int
+	path       string
+	source     *text.InterpolatedString
+	targetPath []string
+	lookups    map[string]geoIPLookup
+
+	dbMut   sync.RWMutex
+	db      *mmdb.Reader
+	modTime time.Time
+	size    int64
+
+	running    int32
+	closeChan  chan struct{}
+	closedChan chan struct{}
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mMiss      metrics.StatCounter
+	mErrIP     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mErrJSONP  metrics.StatCounter
+	mErrJSONS  metrics.StatCounter
+	mReload    metrics.StatCounter
+	mErrReload metrics.StatCounter
+	mSucc      metrics.StatCounter
+	mSent      metrics.StatCounter
+	mSentParts metrics.StatCounter
+}
+
+// NewGeoIP returns a GeoIP processor.
+func NewGeoIP(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	if len(conf.GeoIP.File) == 0 {
+		return nil, fmt.Errorf("a database file must be specified")
+	}
+	if len(conf.GeoIP.Lookups) == 0 {
+		return nil, fmt.Errorf("at least one lookup must be specified")
+	}
+
+	lookups := map[string]geoIPLookup{}
+	for _, l := range conf.GeoIP.Lookups {
+		fn, err := geoIPLookupFromString(l)
+		if err != nil {
+			return nil, err
+		}
+		lookups[l] = fn
+	}
+
+	var interval time.Duration
+	if len(conf.GeoIP.ReloadInterval) > 0 {
+		var err error
+		if interval, err = time.ParseDuration(conf.GeoIP.ReloadInterval); err != nil {
+			return nil, fmt.Errorf("failed to parse reload_interval: %v", err)
+		}
+	}
+
+	info, err := os.Stat(conf.GeoIP.File)
+	if err != nil {
+		return nil, err
+	}
+	db, err := mmdb.Open(conf.GeoIP.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+
+	g := &GeoIP{
+		parts:      conf.GeoIP.Parts,
+		path:       conf.GeoIP.File,
+		source:     text.NewInterpolatedString(conf.GeoIP.Source),
+		targetPath: splitJSONPath(conf.GeoIP.Target),
+		lookups:    lookups,
+
+		db:      db,
+		modTime: info.ModTime(),
+		size:    info.Size(),
+
+		running:    1,
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+
+		log:   log.NewModule(".processor.geoip"),
+		stats: stats,
+
+		mCount:     stats.GetCounter("processor.geoip.count"),
+		mMiss:      stats.GetCounter("processor.geoip.miss"),
+		mErrIP:     stats.GetCounter("processor.geoip.error.ip"),
+		mErr:       stats.GetCounter("processor.geoip.error"),
+		mErrJSONP:  stats.GetCounter("processor.geoip.error.json_parse"),
+		mErrJSONS:  stats.GetCounter("processor.geoip.error.json_set"),
+		mReload:    stats.GetCounter("processor.geoip.reload"),
+		mErrReload: stats.GetCounter("processor.geoip.error.reload"),
+		mSucc:      stats.GetCounter("processor.geoip.success"),
+		mSent:      stats.GetCounter("processor.geoip.sent"),
+		mSentParts: stats.GetCounter("processor.geoip.parts.sent"),
+	}
+
+	if interval > 0 {
+		go g.reloadLoop(interval)
+	} else {
+		close(g.closedChan)
+	}
+	return g, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (g *GeoIP) reloadLoop(interval time.Duration) {
+	defer close(g.closedChan)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := g.checkReload(); err != nil {
+				g.mErrReload.Incr(1)
+				g.log.Errorf("Failed to reload database: %v\n", err)
+			}
+		case <-g.closeChan:
+			return
+		}
+	}
+}
+
+// checkReload replaces the database when the modification time or size of its
+// file has changed, returns true if the database was reloaded.
+func (g *GeoIP) checkReload() (bool, error) {
+	info, err := os.Stat(g.path)
+	if err != nil {
+		return false, err
+	}
+	if info.ModTime().Equal(g.modTime) && info.Size() == g.size {
+		return false, nil
+	}
+
+	db, err := mmdb.Open(g.path)
+	if err != nil {
+		return false, err
+	}
+
+	g.dbMut.Lock()
+	if g.db == nil {
+		g.dbMut.Unlock()
+		db.Close()
+		return false, types.ErrTypeClosed
+	}
+	oldDB := g.db
+	g.db = db
+	g.dbMut.Unlock()
+
+	g.modTime, g.size = info.ModTime(), info.Size()
+	oldDB.Close()
+
+	g.mReload.Incr(1)
+	g.log.Infof("Reloaded database from: %v\n", g.path)
+	return true, nil
+}
+
+// lookup returns the results of all configured lookups for an IP address,
+// where the result is empty if the address is invalid or not found.
+func (g *GeoIP) lookup(ipStr string) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+
+	ip := net.ParseIP(strings.TrimSpace(ipStr))
+	if ip == nil {
+		g.mErrIP.Incr(1)
+		g.log.Debugf("Invalid IP address: %v\n", ipStr)
+		return result, nil
+	}
+
+	g.dbMut.RLock()
+	if g.db == nil {
+		g.dbMut.RUnlock()
+		return nil, types.ErrTypeClosed
+	}
+	v, found, err := g.db.Lookup(ip)
+	g.dbMut.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	record, _ := v.(map[string]interface{})
+	if !found || record == nil {
+		g.mMiss.Incr(1)
+		return result, nil
+	}
+
+	for k, fn := range g.lookups {
+		if r := fn(record); len(r) > 0 {
+			result[k] = r
+		}
+	}
+	return result, nil
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (g *GeoIP) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	g.mCount.Incr(1)
+
+	newMsg := msg.Copy()
+
+	targetParts := g.parts
+	if len(targetParts) == 0 {
+		targetParts = make([]int, newMsg.Len())
+		for i := range targetParts {
+			targetParts[i] = i
+		}
+	}
+
+	for _, index := range targetParts {
+		if index < 0 {
+			index = newMsg.Len() + index
+		}
+		if index < 0 || index >= newMsg.Len() {
+			continue
+		}
+
+		var gPart *gabs.Container
+		if len(g.targetPath) > 0 {
+			jsonPart, err := newMsg.Get(index).JSON()
+			if err == nil {
+				gPart, err = gabs.Consume(jsonPart)
+			}
+			if err != nil {
+				g.mErrJSONP.Incr(1)
+				g.log.Debugf("Failed to parse part into json: %v\n", err)
+				FlagFail(newMsg.Get(index))
+				continue
+			}
+		}
+
+		result, err := g.lookup(g.source.Get(message.Lock(newMsg, index)))
+		if err != nil {
+			g.mErr.Incr(1)
+			g.log.Debugf("Failed to look up IP address: %v\n", err)
+			FlagFail(newMsg.Get(index))
+			continue
+		}
+
+		var data interface{} = result
+		if gPart != nil {
+			gPart.Set(result, g.targetPath...)
+			data = gPart.Data()
+		}
+
+		if err = newMsg.Get(index).SetJSON(data); err != nil {
+			g.mErrJSONS.Incr(1)
+			g.log.Debugf("Failed to convert json into part: %v\n", err)
+			FlagFail(newMsg.Get(index))
+			continue
+		}
+		g.mSucc.Incr(1)
+	}
+
+	g.mSent.Incr(1)
+	g.mSentParts.Incr(int64(newMsg.Len()))
+	msgs := [1]types.Message{newMsg}
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and releases the database.
+func (g *GeoIP) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&g.running, 1, 0) {
+		close(g.closeChan)
+
+		g.dbMut.Lock()
+		if g.db != nil {
+			g.db.Close()
+			g.db = nil
+		}
+		g.dbMut.Unlock()
+	}
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (g *GeoIP) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-g.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
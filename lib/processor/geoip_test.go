@@ -0,0 +1,308 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/util/mmdb"
+)
+
+//------------------------------------------------------------------------------
+
+func writeGeoIPTestDB(t *testing.T, path string, records map[string]interface{}) {
+	t.Helper()
+
+	w, err := mmdb.NewWriter("GeoIP2-Test", 28)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for network, record := range records {
+		if err = w.Insert(network, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := w.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replace the file atomically as the old version may still be mapped.
+	tmpPath := path + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var geoIPTestRecords = map[string]interface{}{
+	"81.2.69.0/24": map[string]interface{}{
+		"city": map[string]interface{}{
+			"names": map[string]interface{}{"en": "London"},
+		},
+		"continent": map[string]interface{}{"code": "EU"},
+		"country": map[string]interface{}{
+			"iso_code": "GB",
+			"names":    map[string]interface{}{"en": "United Kingdom"},
+		},
+		"location": map[string]interface{}{
+			"latitude":  51.5142,
+			"longitude": -0.0931,
+			"time_zone": "Europe/London",
+		},
+		"subdivisions": []interface{}{
+			map[string]interface{}{
+				"names": map[string]interface{}{"en": "England"},
+			},
+		},
+		"autonomous_system_number":       uint32(20712),
+		"autonomous_system_organization": "Andrews and Arnold Ltd",
+	},
+	"2001:218::/32": map[string]interface{}{
+		"country": map[string]interface{}{"iso_code": "JP"},
+	},
+}
+
+func newGeoIPTestDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "benthos_geoip_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestGeoIPLookups(t *testing.T) {
+	dir := newGeoIPTestDir(t)
+	defer os.RemoveAll(dir)
+
+	dbPath := filepath.Join(dir, "test.mmdb")
+	writeGeoIPTestDB(t, dbPath, geoIPTestRecords)
+
+	conf := NewConfig()
+	conf.Type = TypeGeoIP
+	conf.GeoIP.File = dbPath
+	conf.GeoIP.Source = "${!json_field:ip}"
+	conf.GeoIP.Target = "geo"
+	conf.GeoIP.Lookups = []string{"city", "country", "asn"}
+	conf.GeoIP.ReloadInterval = ""
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	stats := metrics.NewLocal()
+
+	proc, err := New(conf, nil, testLog, stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		proc.(*GeoIP).CloseAsync()
+		if err = proc.(*GeoIP).WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	input := message.New([][]byte{
+		[]byte(`{"ip":"81.2.69.160"}`),
+		[]byte(`{"ip":"2001:218:1::1"}`),
+		[]byte(`{"ip":"127.0.0.1"}`),
+		[]byte(`{"ip":"not an ip"}`),
+		[]byte(`not json`),
+	})
+	exp := [][]byte{
+		[]byte(`{"geo":{"asn":{"number":20712,"organization":"Andrews and Arnold Ltd"},"city":{"latitude":51.5142,"longitude":-0.0931,"name":"London","subdivision":"England","time_zone":"Europe/London"},"country":{"continent_code":"EU","iso_code":"GB","name":"United Kingdom"}},"ip":"81.2.69.160"}`),
+		[]byte(`{"geo":{"country":{"iso_code":"JP"}},"ip":"2001:218:1::1"}`),
+		[]byte(`{"geo":{},"ip":"127.0.0.1"}`),
+		[]byte(`{"geo":{},"ip":"not an ip"}`),
+		[]byte(`not json`),
+	}
+
+	msgs, res := proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", len(msgs))
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	for i := 0; i < 4; i++ {
+		if HasFailed(msgs[0].Get(i)) {
+			t.Errorf("Part %v flagged as failed", i)
+		}
+	}
+	if !HasFailed(msgs[0].Get(4)) {
+		t.Error("Expected non-JSON part to be flagged as failed")
+	}
+
+	counters := stats.GetCounters()
+	for k, v := range map[string]int64{
+		"processor.geoip.miss":             1,
+		"processor.geoip.error.ip":         1,
+		"processor.geoip.error.json_parse": 1,
+		"processor.geoip.success":          4,
+	} {
+		if act := counters[k]; act != v {
+			t.Errorf("Wrong counter %v: %v != %v", k, act, v)
+		}
+	}
+}
+
+func TestGeoIPMetadataSource(t *testing.T) {
+	dir := newGeoIPTestDir(t)
+	defer os.RemoveAll(dir)
+
+	dbPath := filepath.Join(dir, "test.mmdb")
+	writeGeoIPTestDB(t, dbPath, geoIPTestRecords)
+
+	conf := NewConfig()
+	conf.Type = TypeGeoIP
+	conf.GeoIP.File = dbPath
+	conf.GeoIP.Lookups = []string{"asn"}
+	conf.GeoIP.ReloadInterval = ""
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proc.(*GeoIP).CloseAsync()
+
+	input := message.New([][]byte{[]byte(`{"foo":"bar"}`)})
+	input.Get(0).Metadata().Set("ip", "81.2.69.1")
+
+	msgs, res := proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	exp := `{"foo":"bar","geoip":{"asn":{"number":20712,"organization":"Andrews and Arnold Ltd"}}}`
+	if act := string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func TestGeoIPReload(t *testing.T) {
+	dir := newGeoIPTestDir(t)
+	defer os.RemoveAll(dir)
+
+	dbPath := filepath.Join(dir, "test.mmdb")
+	writeGeoIPTestDB(t, dbPath, geoIPTestRecords)
+
+	conf := NewConfig()
+	conf.Type = TypeGeoIP
+	conf.GeoIP.File = dbPath
+	conf.GeoIP.Source = "${!json_field:ip}"
+	conf.GeoIP.Lookups = []string{"country"}
+	conf.GeoIP.ReloadInterval = "1h"
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := proc.(*GeoIP)
+	defer func() {
+		g.CloseAsync()
+		if err = g.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	lookup := func() string {
+		t.Helper()
+		msgs, res := g.ProcessMessage(message.New([][]byte{[]byte(`{"ip":"81.2.69.1"}`)}))
+		if res != nil {
+			t.Fatal(res.Error())
+		}
+		return string(msgs[0].Get(0).Get())
+	}
+
+	if exp, act := `{"geoip":{"country":{"continent_code":"EU","iso_code":"GB","name":"United Kingdom"}},"ip":"81.2.69.1"}`, lookup(); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	reloaded, err := g.checkReload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded {
+		t.Error("Expected unchanged database not to be reloaded")
+	}
+
+	writeGeoIPTestDB(t, dbPath, map[string]interface{}{
+		"81.2.0.0/16": map[string]interface{}{
+			"country": map[string]interface{}{"iso_code": "FR"},
+		},
+	})
+
+	if reloaded, err = g.checkReload(); err != nil {
+		t.Fatal(err)
+	}
+	if !reloaded {
+		t.Error("Expected changed database to be reloaded")
+	}
+
+	if exp, act := `{"geoip":{"country":{"iso_code":"FR"}},"ip":"81.2.69.1"}`, lookup(); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func TestGeoIPBadConfig(t *testing.T) {
+	dir := newGeoIPTestDir(t)
+	defer os.RemoveAll(dir)
+
+	dbPath := filepath.Join(dir, "test.mmdb")
+	writeGeoIPTestDB(t, dbPath, geoIPTestRecords)
+
+	badPath := filepath.Join(dir, "bad.mmdb")
+	if err := ioutil.WriteFile(badPath, []byte("not a database"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]func(c *GeoIPConfig){
+		"no file":      func(c *GeoIPConfig) { c.File = "" },
+		"missing file": func(c *GeoIPConfig) { c.File = filepath.Join(dir, "nope.mmdb") },
+		"invalid file": func(c *GeoIPConfig) { c.File = badPath },
+		"no lookups":   func(c *GeoIPConfig) { c.Lookups = nil },
+		"bad lookup":   func(c *GeoIPConfig) { c.Lookups = []string{"planet"} },
+		"bad interval": func(c *GeoIPConfig) { c.ReloadInterval = "nope" },
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		conf.Type = TypeGeoIP
+		conf.GeoIP.File = dbPath
+		fn(&conf.GeoIP)
+		if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+			t.Errorf("%v: Expected error", name)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
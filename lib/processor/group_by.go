@@ -59,6 +59,10 @@ Messages are added to the first group that passes and can only belong to a
 single group. Messages that do not pass the conditions of any group are placed
 in a final batch with no processors applied.
 
+The resulting batches are sent onwards independently, and the original batch is
+only acknowledged at the source once all of the resulting batches have been
+acknowledged.
+
 For example, imagine we have a batch of messages that we wish to split into two
 groups - the foos and the bars - which should be sent to different output
 destinations based on those groupings. We also need to send the foos as a tar
@@ -119,4 +119,52 @@ func TestGroupByWithDefaults(t *testing.T) {
 	}
 }
 
+func TestGroupByJMESPath(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeGroupBy
+
+	condConf := condition.NewConfig()
+	condConf.Type = condition.TypeJMESPath
+	condConf.JMESPath.Query = "tags[?@ == 'urgent']"
+
+	conf.GroupBy = append(conf.GroupBy, GroupByElement{
+		Condition: condConf,
+	})
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][][]byte{
+		{
+			[]byte(`{"id":1,"tags":["urgent","bug"]}`),
+			[]byte(`{"id":3,"tags":["urgent"]}`),
+		},
+		{
+			[]byte(`{"id":2,"tags":["bug"]}`),
+			[]byte(`not json`),
+			[]byte(`{"id":4,"tags":[]}`),
+		},
+	}
+	act := [][][]byte{}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":1,"tags":["urgent","bug"]}`),
+		[]byte(`{"id":2,"tags":["bug"]}`),
+		[]byte(`not json`),
+		[]byte(`{"id":3,"tags":["urgent"]}`),
+		[]byte(`{"id":4,"tags":[]}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	for _, msg := range msgs {
+		act = append(act, message.GetAllBytes(msg))
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+}
+
 //------------------------------------------------------------------------------
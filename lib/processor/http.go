@@ -22,6 +22,8 @@ package processor
 
 import (
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
@@ -54,7 +56,9 @@ from the batch.
 
 The ` + "`rate_limit`" + ` field can be used to specify a rate limit
 [resource](../rate_limits/README.md) to cap the rate of requests across all
-parallel components service wide.
+parallel components service wide. Requests block until the rate limit permits
+access, and the metric ` + "`processor.http.client.http.rate_limit.total_ms`" + `
+counts the total time in milliseconds spent throttled.
 
 The URL and header values of this type can be dynamically set using function
 interpolations described [here](../config_interpolation.md#functions).
@@ -89,6 +93,8 @@ func NewHTTPConfig() HTTPConfig {
 // HTTP is a processor that performs an HTTP request using the message as the
 // request body, and returns the response.
 type HTTP struct {
+	closed int32
+
 	client *client.Type
 
 	parallel bool
@@ -98,6 +104,8 @@ type HTTP struct {
 	log   log.Modular
 	stats metrics.Type
 
+	closeChan chan struct{}
+
 	mCount     metrics.StatCounter
 	mErrHTTP   metrics.StatCounter
 	mErr       metrics.StatCounter
@@ -118,6 +126,8 @@ func NewHTTP(
 		parallel: conf.HTTP.Parallel,
 		max:      conf.HTTP.MaxParallel,
 
+		closeChan: make(chan struct{}),
+
 		mCount:     stats.GetCounter("processor.http.count"),
 		mSucc:      stats.GetCounter("processor.http.success"),
 		mErr:       stats.GetCounter("processor.http.error"),
@@ -131,6 +141,7 @@ func NewHTTP(
 		client.OptSetLogger(g.log),
 		client.OptSetStats(metrics.Namespaced(g.stats, "processor.http")),
 		client.OptSetManager(mgr),
+		client.OptSetCloseChan(g.closeChan),
 	); err != nil {
 		return nil, err
 	}
@@ -217,3 +228,18 @@ func (h *HTTP) ProcessMessage(msg types.Message) ([]types.Message, types.Respons
 }
 
 //------------------------------------------------------------------------------
+
+// CloseAsync shuts down the processor and interrupts any ongoing wait for
+// access to a rate limit or retry backoff.
+func (h *HTTP) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&h.closed, 0, 1) {
+		close(h.closeChan)
+	}
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (h *HTTP) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -32,6 +32,7 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
 )
 
 func TestHTTPClientRetries(t *testing.T) {
@@ -237,3 +238,42 @@ func TestHTTPClientParallelCapped(t *testing.T) {
 		t.Errorf("Wrong result: %v != %v", act, exp)
 	}
 }
+
+func TestHTTPClientRateLimitClose(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&reqCount, 1)
+		w.Write([]byte("foobar"))
+	}))
+	defer ts.Close()
+
+	mgr := &fakeMgr{
+		ratelimits: map[string]types.RateLimit{
+			"foo": &fakeRateLimit{period: time.Hour},
+		},
+	}
+
+	conf := NewConfig()
+	conf.HTTP.Client.URL = ts.URL + "/testpost"
+	conf.HTTP.Client.RateLimit = "foo"
+
+	h, err := NewHTTP(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		<-time.After(time.Millisecond * 100)
+		h.(types.Closable).CloseAsync()
+	}()
+
+	if _, res := h.ProcessMessage(message.New([][]byte{[]byte("foo")})); res == nil || res.Error() == nil {
+		t.Error("Expected error from closed processor")
+	}
+	if exp, act := uint32(0), atomic.LoadUint32(&reqCount); exp != act {
+		t.Errorf("Wrong count of HTTP attempts: %v != %v", act, exp)
+	}
+	if err = h.(types.Closable).WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/processor/condition"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeIf] = TypeSpec{
+		constructor: NewIf,
+		description: `
+If is a processor that has a condition, a list of child ` + "`processors`" + `
+and an optional list of ` + "`else_processors`" + `. The condition is checked
+against each message of a batch individually, and messages that pass have the
+child ` + "`processors`" + ` applied, whereas messages that fail have the
+` + "`else_processors`" + ` applied.
+
+Consecutive messages of a batch that take the same branch are processed
+together as a batch, and when a batch contains messages that take different
+branches the results are merged back into a single batch in their original
+order.
+
+When ` + "`batch_mode`" + ` is set to ` + "`true`" + ` the condition is instead
+checked once against the batch as a whole, and the entire batch is processed by
+the branch that it selects, which is equivalent to the
+` + "[`conditional`](#conditional)" + ` processor.
+
+` + "``` yaml" + `
+type: if
+if:
+  condition:
+    type: text
+    text:
+      operator: contains
+      arg: foo
+  processors:
+  - type: foo_processor
+  else_processors:
+  - type: bar_processor
+` + "```" + `
+
+You can find a [full list of conditions here](../conditions).`,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			condSanit, err := condition.SanitiseConfig(conf.If.Condition)
+			if err != nil {
+				return nil, err
+			}
+			procConfs := make([]interface{}, len(conf.If.Processors))
+			for i, pConf := range conf.If.Processors {
+				if procConfs[i], err = SanitiseConfig(pConf); err != nil {
+					return nil, err
+				}
+			}
+			elseProcConfs := make([]interface{}, len(conf.If.ElseProcessors))
+			for i, pConf := range conf.If.ElseProcessors {
+				if elseProcConfs[i], err = SanitiseConfig(pConf); err != nil {
+					return nil, err
+				}
+			}
+			return map[string]interface{}{
+				"condition":       condSanit,
+				"batch_mode":      conf.If.BatchMode,
+				"processors":      procConfs,
+				"else_processors": elseProcConfs,
+			}, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// IfConfig is a config struct containing fields for the If processor.
+type IfConfig struct {
+	Condition      condition.Config `json:"condition" yaml:"condition"`
+	BatchMode      bool             `json:"batch_mode" yaml:"batch_mode"`
+	Processors     []Config         `json:"processors" yaml:"processors"`
+	ElseProcessors []Config         `json:"else_processors" yaml:"else_processors"`
+}
+
+// NewIfConfig returns a default IfConfig.
+func NewIfConfig() IfConfig {
+	return IfConfig{
+		Condition:      condition.NewConfig(),
+		BatchMode:      false,
+		Processors:     []Config{},
+		ElseProcessors: []Config{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// If is a processor that applies one of two lists of child processors to each
+// message depending on a condition.
+type If struct {
+	cond         condition.Type
+	batchMode    bool
+	children     []Type
+	elseChildren []Type
+
+	log log.Modular
+
+	mCount      metrics.StatCounter
+	mCondPassed metrics.StatCounter
+	mCondFailed metrics.StatCounter
+	mSent       metrics.StatCounter
+	mSentParts  metrics.StatCounter
+	mDropped    metrics.StatCounter
+}
+
+// NewIf returns an If processor.
+func NewIf(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	nsStats := metrics.Namespaced(stats, "processor.if")
+	nsLog := log.NewModule(".processor.if")
+	cond, err := condition.New(conf.If.Condition, mgr, nsLog, nsStats)
+	if err != nil {
+		return nil, err
+	}
+
+	nsStats = metrics.Namespaced(stats, "processor.if.then")
+	nsLog = log.NewModule(".processor.if.then")
+	var children []Type
+	for _, pconf := range conf.If.Processors {
+		var proc Type
+		if proc, err = New(pconf, mgr, nsLog, nsStats); err != nil {
+			return nil, err
+		}
+		children = append(children, proc)
+	}
+
+	nsStats = metrics.Namespaced(stats, "processor.if.else")
+	nsLog = log.NewModule(".processor.if.else")
+	var elseChildren []Type
+	for _, pconf := range conf.If.ElseProcessors {
+		var proc Type
+		if proc, err = New(pconf, mgr, nsLog, nsStats); err != nil {
+			return nil, err
+		}
+		elseChildren = append(elseChildren, proc)
+	}
+
+	return &If{
+		cond:         cond,
+		batchMode:    conf.If.BatchMode,
+		children:     children,
+		elseChildren: elseChildren,
+
+		log: log.NewModule(".processor.if"),
+
+		mCount:      stats.GetCounter("processor.if.count"),
+		mCondPassed: stats.GetCounter("processor.if.passed"),
+		mCondFailed: stats.GetCounter("processor.if.failed"),
+		mSent:       stats.GetCounter("processor.if.sent"),
+		mSentParts:  stats.GetCounter("processor.if.parts.sent"),
+		mDropped:    stats.GetCounter("processor.if.dropped"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// check returns the result of the condition against a message and records it.
+func (c *If) check(msg types.Message) bool {
+	if c.cond.Check(msg) {
+		c.mCondPassed.Incr(1)
+		return true
+	}
+	c.mCondFailed.Incr(1)
+	return false
+}
+
+// branch applies the processors of a branch to a message.
+func (c *If) branch(passed bool, msg types.Message) ([]types.Message, types.Response) {
+	procs := c.elseChildren
+	if passed {
+		procs = c.children
+	}
+
+	resultMsgs := []types.Message{msg}
+	var resultRes types.Response
+
+	for i := 0; len(resultMsgs) > 0 && i < len(procs); i++ {
+		var nextResultMsgs []types.Message
+		for _, m := range resultMsgs {
+			var rMsgs []types.Message
+			rMsgs, resultRes = procs[i].ProcessMessage(m)
+			nextResultMsgs = append(nextResultMsgs, rMsgs...)
+		}
+		resultMsgs = nextResultMsgs
+	}
+	return resultMsgs, resultRes
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (c *If) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	c.mCount.Incr(1)
+
+	var resultMsgs []types.Message
+	var resultRes types.Response
+
+	if c.batchMode || msg.Len() <= 1 {
+		resultMsgs, resultRes = c.branch(c.check(msg), msg)
+	} else {
+		// Group consecutive messages that take the same branch.
+		var runs []types.Message
+		var runPassed []bool
+		msg.Iter(func(i int, p types.Part) error {
+			passed := c.check(message.Lock(msg, i))
+			if len(runs) == 0 || runPassed[len(runs)-1] != passed {
+				runs = append(runs, message.New(nil))
+				runPassed = append(runPassed, passed)
+			}
+			runs[len(runs)-1].Append(p.Copy())
+			return nil
+		})
+
+		if len(runs) == 1 {
+			resultMsgs, resultRes = c.branch(runPassed[0], msg)
+		} else {
+			merged := message.New(nil)
+			for i, run := range runs {
+				var rMsgs []types.Message
+				rMsgs, resultRes = c.branch(runPassed[i], run)
+				for _, m := range rMsgs {
+					m.Iter(func(_ int, p types.Part) error {
+						merged.Append(p)
+						return nil
+					})
+				}
+			}
+			if merged.Len() > 0 {
+				resultMsgs = []types.Message{merged}
+			}
+		}
+	}
+
+	if len(resultMsgs) == 0 {
+		c.mDropped.Incr(1)
+		if resultRes == nil {
+			resultRes = response.NewAck()
+		}
+		return nil, resultRes
+	}
+
+	c.mSent.Incr(int64(len(resultMsgs)))
+	totalParts := 0
+	for _, m := range resultMsgs {
+		totalParts += m.Len()
+	}
+	c.mSentParts.Incr(int64(totalParts))
+	return resultMsgs, nil
+}
+
+//------------------------------------------------------------------------------
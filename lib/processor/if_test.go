@@ -0,0 +1,157 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func newIfTestConf(batchMode bool) Config {
+	conf := NewConfig()
+	conf.Type = TypeIf
+	conf.If.BatchMode = batchMode
+	conf.If.Condition.Type = "text"
+	conf.If.Condition.Text.Operator = "contains"
+	conf.If.Condition.Text.Arg = "foo"
+
+	procConf := NewConfig()
+	procConf.Type = TypeText
+	procConf.Text.Operator = "to_upper"
+
+	elseProcConf := NewConfig()
+	elseProcConf.Type = TypeText
+	elseProcConf.Text.Operator = "prepend"
+	elseProcConf.Text.Value = "else "
+
+	conf.If.Processors = append(conf.If.Processors, procConf)
+	conf.If.ElseProcessors = append(conf.If.ElseProcessors, elseProcConf)
+	return conf
+}
+
+func TestIfPerMessage(t *testing.T) {
+	c, err := New(newIfTestConf(false), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		input  [][]byte
+		output [][]byte
+	}
+	tests := []testCase{
+		{
+			input:  [][]byte{[]byte("foo")},
+			output: [][]byte{[]byte("FOO")},
+		},
+		{
+			input:  [][]byte{[]byte("bar")},
+			output: [][]byte{[]byte("else bar")},
+		},
+		{
+			input: [][]byte{
+				[]byte("foo 1"), []byte("bar 2"), []byte("bar 3"), []byte("foo 4"),
+			},
+			output: [][]byte{
+				[]byte("FOO 1"), []byte("else bar 2"), []byte("else bar 3"), []byte("FOO 4"),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		msgs, res := c.ProcessMessage(message.New(test.input))
+		if res != nil {
+			t.Fatal(res.Error())
+		}
+		if exp, act := 1, len(msgs); exp != act {
+			t.Fatalf("Wrong count of messages: %v != %v", act, exp)
+		}
+		if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(act, test.output) {
+			t.Errorf("Wrong result: %s != %s", act, test.output)
+		}
+	}
+}
+
+func TestIfBatchMode(t *testing.T) {
+	c, err := New(newIfTestConf(true), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := c.ProcessMessage(message.New([][]byte{
+		[]byte("foo 1"), []byte("bar 2"),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	exp := [][]byte{[]byte("FOO 1"), []byte("BAR 2")}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+
+	msgs, res = c.ProcessMessage(message.New([][]byte{
+		[]byte("bar 1"), []byte("foo 2"),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	exp = [][]byte{[]byte("else bar 1"), []byte("else foo 2")}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+}
+
+func TestIfDropped(t *testing.T) {
+	conf := newIfTestConf(false)
+	filterConf := NewConfig()
+	filterConf.Type = TypeFilter
+	filterConf.Filter.Type = "static"
+	filterConf.Filter.Static = false
+	conf.If.ElseProcessors = []Config{filterConf}
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := c.ProcessMessage(message.New([][]byte{
+		[]byte("foo 1"), []byte("bar 2"), []byte("foo 3"),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	exp := [][]byte{[]byte("FOO 1"), []byte("FOO 3")}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+
+	msgs, res = c.ProcessMessage(message.New([][]byte{[]byte("bar")}))
+	if len(msgs) != 0 {
+		t.Errorf("Expected message to be dropped: %s", message.GetAllBytes(msgs[0]))
+	}
+	if res == nil || res.Error() != nil {
+		t.Errorf("Expected ack response, got: %v", res)
+	}
+}
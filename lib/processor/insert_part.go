@@ -42,6 +42,12 @@ counting backwards starting from -1. E.g. if index = -1 then the new part will
 become the last part of the message, if index = -2 then the new part will be
 inserted before the last element, and so on. If the negative index is greater
 than the length of the existing parts it will be inserted at the beginning.
+Indexes that are out of range in either direction are counted by the metric
+` + "`processor.insert_part.out_of_range`" + `.
+
+The new part inherits the metadata of the first part of the message, which is
+how metadata of a batch is represented, such that it is preserved when inserting
+a part at the beginning.
 
 This processor will interpolate functions within the 'content' field, you can
 find a list of functions [here](../config_interpolation.md#functions).`,
@@ -76,9 +82,10 @@ type InsertPart struct {
 	log   log.Modular
 	stats metrics.Type
 
-	mCount     metrics.StatCounter
-	mSent      metrics.StatCounter
-	mSentParts metrics.StatCounter
+	mCount      metrics.StatCounter
+	mOutOfRange metrics.StatCounter
+	mSent       metrics.StatCounter
+	mSentParts  metrics.StatCounter
 }
 
 // NewInsertPart returns a InsertPart processor.
@@ -94,9 +101,10 @@ func NewInsertPart(
 		log:         log.NewModule(".processor.insert_part"),
 		stats:       stats,
 
-		mCount:     stats.GetCounter("processor.insert_part.count"),
-		mSent:      stats.GetCounter("processor.insert_part.sent"),
-		mSentParts: stats.GetCounter("processor.insert_part.parts.sent"),
+		mCount:      stats.GetCounter("processor.insert_part.count"),
+		mOutOfRange: stats.GetCounter("processor.insert_part.out_of_range"),
+		mSent:       stats.GetCounter("processor.insert_part.sent"),
+		mSentParts:  stats.GetCounter("processor.insert_part.parts.sent"),
 	}, nil
 }
 
@@ -107,11 +115,15 @@ func NewInsertPart(
 func (p *InsertPart) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
 	p.mCount.Incr(1)
 
-	var newPart []byte
+	var newPartBytes []byte
 	if p.interpolate {
-		newPart = text.ReplaceFunctionVariables(msg, p.part)
+		newPartBytes = text.ReplaceFunctionVariables(msg, p.part)
 	} else {
-		newPart = p.part
+		newPartBytes = p.part
+	}
+	newPart := message.NewPart(newPartBytes)
+	if msg.Len() > 0 {
+		newPart.SetMetadata(msg.Get(0).Metadata().Copy())
 	}
 
 	index := p.conf.InsertPart.Index
@@ -119,22 +131,24 @@ func (p *InsertPart) ProcessMessage(msg types.Message) ([]types.Message, types.R
 	if index < 0 {
 		index = msgLen + index + 1
 		if index < 0 {
+			p.mOutOfRange.Incr(1)
 			index = 0
 		}
 	} else if index > msgLen {
+		p.mOutOfRange.Incr(1)
 		index = msgLen
 	}
 
 	newMsg := message.New(nil)
 	msg.Iter(func(i int, p types.Part) error {
 		if i == index {
-			newMsg.Append(message.NewPart(newPart))
+			newMsg.Append(newPart)
 		}
 		newMsg.Append(p.Copy())
 		return nil
 	})
 	if index == msg.Len() {
-		newMsg.Append(message.NewPart(newPart))
+		newMsg.Append(newPart)
 	}
 
 	p.mSent.Incr(1)
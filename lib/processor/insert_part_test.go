@@ -252,3 +252,62 @@ func TestInsertPartInterpolation(t *testing.T) {
 		}
 	}
 }
+
+func TestInsertPartMetadata(t *testing.T) {
+	conf := NewConfig()
+	conf.InsertPart.Content = `{"index":{}}`
+	conf.InsertPart.Index = 0
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	proc, err := NewInsertPart(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	input.Get(0).Metadata().Set("batch", "first")
+	input.Get(1).Metadata().Set("batch", "second")
+
+	msgs, res := proc.ProcessMessage(input)
+	if len(msgs) != 1 {
+		t.Fatalf("Insert Part failed: %v", res)
+	}
+
+	exp := []string{"first", "first", "second"}
+	for i, e := range exp {
+		if act := msgs[0].Get(i).Metadata().Get("batch"); e != act {
+			t.Errorf("Wrong metadata for part %v: %v != %v", i, act, e)
+		}
+	}
+
+	// Ensure the original message is not modified.
+	msgs[0].Get(0).Metadata().Set("batch", "changed")
+	if exp, act := "first", input.Get(0).Metadata().Get("batch"); exp != act {
+		t.Errorf("Original metadata was changed: %v != %v", act, exp)
+	}
+}
+
+func TestInsertPartOutOfRange(t *testing.T) {
+	conf := NewConfig()
+	conf.InsertPart.Content = "hello world"
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	stats := metrics.NewLocal()
+
+	for _, index := range []int{-1, 0, 2, -3, 3, -4} {
+		conf.InsertPart.Index = index
+		proc, err := NewInsertPart(conf, nil, testLog, stats)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msgs, res := proc.ProcessMessage(message.New([][]byte{
+			[]byte("foo"), []byte("bar"),
+		})); len(msgs) != 1 {
+			t.Fatalf("Insert Part failed: %v", res)
+		}
+	}
+
+	if exp, act := int64(2), stats.GetCounters()["processor.insert_part.out_of_range"]; exp != act {
+		t.Errorf("Wrong count of out of range indexes: %v != %v", act, exp)
+	}
+}
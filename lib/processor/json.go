@@ -21,13 +21,18 @@
 package processor
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/text"
 	"github.com/Jeffail/gabs"
@@ -42,7 +47,12 @@ func init() {
 Parses a message part as a JSON document, performs a mutation on the data, and
 then overwrites the previous contents with the new value.
 
-If the path is empty or "." the root of the data will be targeted.
+If the path is empty or "." the root of the data will be targeted. Keys that
+contain dots can be referenced by escaping the dots as ` + "`~1`" + `, and a
+tilde can be referenced with ` + "`~0`" + `, e.g. the path
+` + "`foo.bar~1baz`" + ` targets the key ` + "`bar.baz`" + ` within the object
+` + "`foo`" + `. This applies to both the ` + "`path`" + ` field and destination
+paths specified in the ` + "`value`" + ` field.
 
 This processor will interpolate functions within the 'value' field, you can find
 a list of functions [here](../config_interpolation.md#functions).
@@ -83,6 +93,41 @@ collision).
 Removes a key identified by the dot path. If the path does not exist this is a
 no-op.
 
+#### ` + "`explode`" + `
+
+Explodes an array or object found at a dot path into multiple message parts,
+which replace the original part within the batch. If the path targets the root
+of the document then each element of the array (or each value of the object)
+becomes a message part. Otherwise, each resulting message part is a copy of the
+original document where the value at the path is replaced with an individual
+element. The values of objects are exploded in the order of their keys, and all
+resulting message parts inherit the metadata of the original part. An empty
+array or object results in the original part being removed.
+
+For example, with the path ` + "`foo`" + ` the document
+` + "`{\"id\":1,\"foo\":[\"a\",\"b\"]}`" + ` would become the two parts
+` + "`{\"id\":1,\"foo\":\"a\"}`" + ` and ` + "`{\"id\":1,\"foo\":\"b\"}`" + `.
+
+If the value at the path is not an array or object the message part is left
+unchanged and is flagged as failed.
+
+#### ` + "`flatten`" + `
+
+Flattens an array or object found at a dot path. An array is flattened by one
+level, e.g. the array ` + "`[[1,2],[3],4]`" + ` would become
+` + "`[1,2,3,4]`" + `.
+
+An object is flattened into a single level object where the keys are the paths
+of each nested value joined by ` + "`delimiter`" + `, e.g. the object
+` + "`{\"foo\":{\"bar\":1,\"baz\":[2,3]}}`" + ` would become
+` + "`{\"foo.bar\":1,\"foo.baz.0\":2,\"foo.baz.1\":3}`" + `. When
+` + "`index_arrays`" + ` is set to ` + "`false`" + ` arrays are not flattened and
+are instead kept as values, e.g. ` + "`{\"foo.bar\":1,\"foo.baz\":[2,3]}`" + `.
+Empty objects and arrays are also kept as values.
+
+If the value at the path is not an array or object the message part is flagged
+as failed.
+
 #### ` + "`move`" + `
 
 Moves the value of a target dot path (if it exists) to a new location. The
@@ -95,6 +140,18 @@ collision).
 Reads the value found at a dot path and replaced the original contents entirely
 by the new value.
 
+#### ` + "`unflatten`" + `
+
+Reverses the ` + "`flatten`" + ` operator for an object found at a dot path by
+splitting each key by ` + "`delimiter`" + ` and creating the nested objects of
+the resulting path. When ` + "`index_arrays`" + ` is set to ` + "`true`" + `
+objects whose keys are exactly the sequence of indexes from zero are converted
+into arrays.
+
+If the value at the path is not an object, or two keys collide (e.g.
+` + "`foo`" + ` and ` + "`foo.bar`" + ` both with non-object values), the
+message part is flagged as failed.
+
 #### ` + "`set`" + `
 
 Sets the value of a field at a dot path. If the path does not exist all objects
@@ -115,7 +172,21 @@ json:
 ` + "```" + `
 
 The value will be converted into '{"foo":{"bar":5}}'. If the YAML object
-contains keys that aren't strings those fields will be ignored.`,
+contains keys that aren't strings those fields will be ignored.
+
+### Streaming
+
+When ` + "`streaming`" + ` is set to ` + "`true`" + ` the ` + "`set`" + `, ` + "`select`" + ` and
+` + "`delete`" + ` operators edit the raw bytes of a document rather than
+unmarshalling and re-marshalling it in full, which is significantly cheaper for
+large documents. Fields outside of the target path are left exactly as they
+were, including their order and whitespace. Paths that cross an array, a null
+value or duplicate keys fall back to a full parse, as do all other operators.
+
+### Error Handling
+
+Message parts that cannot be parsed as JSON, or where the operator fails to be
+applied, are left unchanged and are flagged as failed.`,
 	}
 }
 
@@ -201,19 +272,27 @@ func (r rawJSONValue) MarshalYAML() (interface{}, error) {
 
 // JSONConfig contains configuration fields for the JSON processor.
 type JSONConfig struct {
-	Parts    []int        `json:"parts" yaml:"parts"`
-	Operator string       `json:"operator" yaml:"operator"`
-	Path     string       `json:"path" yaml:"path"`
-	Value    rawJSONValue `json:"value" yaml:"value"`
+	Parts     []int        `json:"parts" yaml:"parts"`
+	Operator  string       `json:"operator" yaml:"operator"`
+	Path      string       `json:"path" yaml:"path"`
+	Value     rawJSONValue `json:"value" yaml:"value"`
+	Streaming bool         `json:"streaming" yaml:"streaming"`
+
+	Delimiter   string `json:"delimiter" yaml:"delimiter"`
+	IndexArrays bool   `json:"index_arrays" yaml:"index_arrays"`
 }
 
 // NewJSONConfig returns a JSONConfig with default values.
 func NewJSONConfig() JSONConfig {
 	return JSONConfig{
-		Parts:    []int{},
-		Operator: "get",
-		Path:     "",
-		Value:    rawJSONValue(`""`),
+		Parts:     []int{},
+		Operator:  "get",
+		Path:      "",
+		Value:     rawJSONValue(`""`),
+		Streaming: false,
+
+		Delimiter:   ".",
+		IndexArrays: true,
 	}
 }
 
@@ -441,7 +520,219 @@ func newAppendOperator(path []string) jsonOperator {
 	}
 }
 
-func getOperator(opStr string, path []string, value json.RawMessage) (jsonOperator, error) {
+// jsonExploded is the result of an operator that splits a document into
+// multiple documents.
+type jsonExploded []interface{}
+
+func newExplodeOperator(path []string) jsonOperator {
+	return func(body interface{}, value json.RawMessage) (interface{}, error) {
+		gPart, err := gabs.Consume(body)
+		if err != nil {
+			return nil, err
+		}
+
+		var values []interface{}
+		switch t := gPart.S(path...).Data().(type) {
+		case []interface{}:
+			values = t
+		case map[string]interface{}:
+			keys := make([]string, 0, len(t))
+			for k := range t {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				values = append(values, t[k])
+			}
+		default:
+			return nil, fmt.Errorf("expected array or object at path '%v', found: %T", strings.Join(path, "."), t)
+		}
+
+		if len(path) == 0 {
+			return jsonExploded(values), nil
+		}
+
+		results := make(jsonExploded, 0, len(values))
+		for _, v := range values {
+			var gCopy *gabs.Container
+			if gCopy, err = gabs.ParseJSON(gPart.Bytes()); err != nil {
+				return nil, err
+			}
+			gCopy.Set(v, path...)
+			results = append(results, gCopy.Data())
+		}
+		return results, nil
+	}
+}
+
+// flattenJSONValue writes each nested value of v to result with keys made of
+// the path of the value joined by a delimiter.
+func flattenJSONValue(prefix string, v interface{}, delim string, indexArrays bool, result map[string]interface{}) {
+	join := func(key string) string {
+		if len(prefix) == 0 {
+			return key
+		}
+		return prefix + delim + key
+	}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if len(t) > 0 {
+			for k, child := range t {
+				flattenJSONValue(join(k), child, delim, indexArrays, result)
+			}
+			return
+		}
+	case []interface{}:
+		if indexArrays && len(t) > 0 {
+			for i, child := range t {
+				flattenJSONValue(join(strconv.Itoa(i)), child, delim, indexArrays, result)
+			}
+			return
+		}
+	}
+	result[prefix] = v
+}
+
+// unflattenJSONArrays walks a value and converts each object with keys that
+// are exactly the sequence of indexes from zero into an array.
+func unflattenJSONArrays(v interface{}) interface{} {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	for k, child := range obj {
+		obj[k] = unflattenJSONArrays(child)
+	}
+	if len(obj) == 0 {
+		return obj
+	}
+	array := make([]interface{}, len(obj))
+	for k, child := range obj {
+		i, err := strconv.Atoi(k)
+		if err != nil || i < 0 || i >= len(obj) || strconv.Itoa(i) != k {
+			return obj
+		}
+		array[i] = child
+	}
+	return array
+}
+
+func newFlattenOperator(path []string, delim string, indexArrays bool) jsonOperator {
+	return func(body interface{}, value json.RawMessage) (interface{}, error) {
+		gPart, err := gabs.Consume(body)
+		if err != nil {
+			return nil, err
+		}
+
+		var flattenedV interface{}
+		switch t := gPart.S(path...).Data().(type) {
+		case map[string]interface{}:
+			flattenedObj := map[string]interface{}{}
+			for k, child := range t {
+				flattenJSONValue(k, child, delim, indexArrays, flattenedObj)
+			}
+			flattenedV = flattenedObj
+		case []interface{}:
+			flattened := []interface{}{}
+			for _, v := range t {
+				if inner, isArray := v.([]interface{}); isArray {
+					flattened = append(flattened, inner...)
+				} else {
+					flattened = append(flattened, v)
+				}
+			}
+			flattenedV = flattened
+		default:
+			return nil, fmt.Errorf("expected array or object at path '%v', found: %T", strings.Join(path, "."), t)
+		}
+
+		if len(path) == 0 {
+			return flattenedV, nil
+		}
+		gPart.Set(flattenedV, path...)
+		return gPart.Data(), nil
+	}
+}
+
+func newUnflattenOperator(path []string, delim string, indexArrays bool) jsonOperator {
+	return func(body interface{}, value json.RawMessage) (interface{}, error) {
+		gPart, err := gabs.Consume(body)
+		if err != nil {
+			return nil, err
+		}
+
+		obj, ok := gPart.S(path...).Data().(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected object at path '%v', found: %T", strings.Join(path, "."), gPart.S(path...).Data())
+		}
+
+		// Keys are sorted so that a parent is always set before its children.
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		unflattened := map[string]interface{}{}
+		for _, k := range keys {
+			segments := strings.Split(k, delim)
+			node := unflattened
+			for _, seg := range segments[:len(segments)-1] {
+				child, exists := node[seg]
+				if !exists {
+					child = map[string]interface{}{}
+					node[seg] = child
+				}
+				if node, ok = child.(map[string]interface{}); !ok {
+					return nil, fmt.Errorf("key '%v' collides with a non-object value", k)
+				}
+			}
+			last := segments[len(segments)-1]
+			if existing, exists := node[last]; exists {
+				if _, isObj := existing.(map[string]interface{}); !isObj {
+					return nil, fmt.Errorf("key '%v' collides with an existing value", k)
+				}
+				if newObj, isObj := obj[k].(map[string]interface{}); !isObj || len(newObj) > 0 {
+					return nil, fmt.Errorf("key '%v' collides with an existing value", k)
+				}
+				continue
+			}
+			node[last] = obj[k]
+		}
+
+		var unflattenedV interface{} = unflattened
+		if indexArrays {
+			unflattenedV = unflattenJSONArrays(unflattenedV)
+		}
+
+		if len(path) == 0 {
+			return unflattenedV, nil
+		}
+		gPart.Set(unflattenedV, path...)
+		return gPart.Data(), nil
+	}
+}
+
+// splitJSONPath splits a dot path into its segments, where the escape sequences
+// ~1 and ~0 within segments are replaced with a dot and a tilde respectively.
+func splitJSONPath(path string) []string {
+	if len(path) == 0 || path == "." {
+		return []string{}
+	}
+	segments := strings.Split(path, ".")
+	for i, s := range segments {
+		s = strings.Replace(s, "~1", ".", -1)
+		segments[i] = strings.Replace(s, "~0", "~", -1)
+	}
+	return segments
+}
+
+func getOperator(conf JSONConfig, path []string, value json.RawMessage) (jsonOperator, error) {
+	opStr := conf.Operator
+	if (opStr == "flatten" || opStr == "unflatten") && len(conf.Delimiter) == 0 {
+		return nil, errors.New("delimiter must not be empty")
+	}
+
 	var destPath []string
 	if opStr == "move" || opStr == "copy" {
 		var destDotPath string
@@ -449,7 +740,7 @@ func getOperator(opStr string, path []string, value json.RawMessage) (jsonOperat
 			return nil, fmt.Errorf("failed to parse destination path from value: %v", err)
 		}
 		if len(destDotPath) > 0 {
-			destPath = strings.Split(destDotPath, ".")
+			destPath = splitJSONPath(destDotPath)
 		}
 	}
 	switch opStr {
@@ -467,18 +758,311 @@ func getOperator(opStr string, path []string, value json.RawMessage) (jsonOperat
 		return newAppendOperator(path), nil
 	case "clean":
 		return newCleanOperator(path), nil
+	case "explode":
+		return newExplodeOperator(path), nil
+	case "flatten":
+		return newFlattenOperator(path, conf.Delimiter, conf.IndexArrays), nil
+	case "unflatten":
+		return newUnflattenOperator(path, conf.Delimiter, conf.IndexArrays), nil
 	}
 	return nil, fmt.Errorf("operator not recognised: %v", opStr)
 }
 
 //------------------------------------------------------------------------------
 
+// errRawJSONUnsupported is returned by raw operators when a document or path
+// cannot be edited without a full parse.
+var errRawJSONUnsupported = errors.New("path cannot be resolved without a full parse")
+
+// jsonRawOperator performs an operation directly on the serialised bytes of a
+// JSON document. An error indicates that the operation should instead be
+// attempted against the fully parsed document.
+type jsonRawOperator func(body []byte, value json.RawMessage) ([]byte, error)
+
+// jsonRawMember is the location of a key/value pair within an object, where
+// start is the index of the opening quote of the key and valStart and valEnd
+// bound the value.
+type jsonRawMember struct {
+	start, valStart, valEnd int
+}
+
+// jsonRawTarget is the result of walking a path through a raw document.
+type jsonRawTarget struct {
+	found  bool
+	member jsonRawMember
+
+	// The deepest object reached, the number of path segments leading to it
+	// and the index of its closing brace.
+	depth    int
+	objEmpty bool
+	objEnd   int
+}
+
+func jsonSkipSpace(b []byte, i int) int {
+	for i < len(b) {
+		switch b[i] {
+		case ' ', '\t', '\n', '\r':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// jsonSkipString returns the index immediately after the string beginning at
+// b[i].
+func jsonSkipString(b []byte, i int) int {
+	for i++; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return i
+}
+
+// jsonSkipValue returns the index immediately after the value beginning at
+// b[i]. The document is assumed to be valid.
+func jsonSkipValue(b []byte, i int) int {
+	switch b[i] {
+	case '"':
+		return jsonSkipString(b, i)
+	case '{', '[':
+		depth := 0
+		for i < len(b) {
+			switch b[i] {
+			case '"':
+				i = jsonSkipString(b, i)
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				if depth--; depth == 0 {
+					return i + 1
+				}
+			}
+			i++
+		}
+		return i
+	}
+	for i < len(b) {
+		switch b[i] {
+		case ',', '}', ']', ' ', '\t', '\n', '\r':
+			return i
+		}
+		i++
+	}
+	return i
+}
+
+// jsonFindMember scans the object beginning at b[i] for members matching key,
+// returning the last match, the number of matches, whether the object is empty
+// and the index of the closing brace.
+func jsonFindMember(b []byte, i int, key string) (m jsonRawMember, matches int, empty bool, end int) {
+	empty = true
+	for i = jsonSkipSpace(b, i+1); i < len(b) && b[i] != '}'; {
+		empty = false
+		keyStart := i
+		keyEnd := jsonSkipString(b, i)
+
+		i = jsonSkipSpace(b, keyEnd)
+		valStart := jsonSkipSpace(b, i+1)
+		valEnd := jsonSkipValue(b, valStart)
+
+		rawKey := b[keyStart+1 : keyEnd-1]
+		match := false
+		if bytes.IndexByte(rawKey, '\\') == -1 {
+			match = string(rawKey) == key
+		} else {
+			var k string
+			match = json.Unmarshal(b[keyStart:keyEnd], &k) == nil && k == key
+		}
+		if match {
+			m = jsonRawMember{start: keyStart, valStart: valStart, valEnd: valEnd}
+			matches++
+		}
+
+		if i = jsonSkipSpace(b, valEnd); i < len(b) && b[i] == ',' {
+			i = jsonSkipSpace(b, i+1)
+		}
+	}
+	return m, matches, empty, i
+}
+
+// jsonRawLookup walks a path through the nested objects of a valid document.
+// Paths that cross anything other than an object, or that contain duplicate
+// keys, are not supported.
+func jsonRawLookup(b []byte, path []string) (jsonRawTarget, error) {
+	i := jsonSkipSpace(b, 0)
+	for d, seg := range path {
+		if i >= len(b) || b[i] != '{' {
+			return jsonRawTarget{}, errRawJSONUnsupported
+		}
+		m, matches, empty, end := jsonFindMember(b, i, seg)
+		if matches > 1 {
+			return jsonRawTarget{}, errRawJSONUnsupported
+		}
+		if matches == 0 {
+			return jsonRawTarget{depth: d, objEmpty: empty, objEnd: end}, nil
+		}
+		if d == len(path)-1 {
+			return jsonRawTarget{found: true, member: m, depth: d}, nil
+		}
+		i = m.valStart
+	}
+	return jsonRawTarget{}, errRawJSONUnsupported
+}
+
+func newRawSetOperator(path []string) jsonRawOperator {
+	return func(body []byte, value json.RawMessage) ([]byte, error) {
+		if !json.Valid(body) {
+			return nil, errors.New("failed to parse message body")
+		}
+		var compacted bytes.Buffer
+		if err := json.Compact(&compacted, value); err != nil {
+			return nil, fmt.Errorf("failed to parse value: %v", err)
+		}
+		if len(path) == 0 {
+			return compacted.Bytes(), nil
+		}
+
+		target, err := jsonRawLookup(body, path)
+		if err != nil {
+			return nil, err
+		}
+
+		var result []byte
+		if target.found {
+			result = make([]byte, 0, len(body)-(target.member.valEnd-target.member.valStart)+compacted.Len())
+			result = append(result, body[:target.member.valStart]...)
+			result = append(result, compacted.Bytes()...)
+			return append(result, body[target.member.valEnd:]...), nil
+		}
+
+		// Build the missing objects of the path and insert them at the end of
+		// the deepest object that exists.
+		var inserted bytes.Buffer
+		if !target.objEmpty {
+			inserted.WriteByte(',')
+		}
+		for i, seg := range path[target.depth:] {
+			if i > 0 {
+				inserted.WriteByte('{')
+			}
+			keyBytes, _ := json.Marshal(seg)
+			inserted.Write(keyBytes)
+			inserted.WriteByte(':')
+		}
+		inserted.Write(compacted.Bytes())
+		for i := target.depth + 1; i < len(path); i++ {
+			inserted.WriteByte('}')
+		}
+
+		result = make([]byte, 0, len(body)+inserted.Len())
+		result = append(result, body[:target.objEnd]...)
+		result = append(result, inserted.Bytes()...)
+		return append(result, body[target.objEnd:]...), nil
+	}
+}
+
+func newRawSelectOperator(path []string) jsonRawOperator {
+	return func(body []byte, value json.RawMessage) ([]byte, error) {
+		if len(path) == 0 {
+			return nil, errRawJSONUnsupported
+		}
+		if !json.Valid(body) {
+			return nil, errors.New("failed to parse message body")
+		}
+
+		target, err := jsonRawLookup(body, path)
+		if err != nil {
+			return nil, err
+		}
+		if !target.found {
+			return []byte("null"), nil
+		}
+
+		v := body[target.member.valStart:target.member.valEnd]
+		if v[0] == '"' {
+			var str string
+			if err = json.Unmarshal(v, &str); err != nil {
+				return nil, err
+			}
+			return []byte(str), nil
+		}
+
+		var compacted bytes.Buffer
+		if err = json.Compact(&compacted, v); err != nil {
+			return nil, err
+		}
+		return compacted.Bytes(), nil
+	}
+}
+
+func newRawDeleteOperator(path []string) jsonRawOperator {
+	return func(body []byte, value json.RawMessage) ([]byte, error) {
+		if !json.Valid(body) {
+			return nil, errors.New("failed to parse message body")
+		}
+		if len(path) == 0 {
+			return []byte("null"), nil
+		}
+
+		target, err := jsonRawLookup(body, path)
+		if err != nil {
+			return nil, err
+		}
+		if !target.found {
+			return nil, errRawJSONUnsupported
+		}
+
+		// Remove the member along with the comma that separates it from its
+		// neighbour, if it has one.
+		start, end := target.member.start, target.member.valEnd
+		if i := jsonSkipSpace(body, end); body[i] == ',' {
+			end = jsonSkipSpace(body, i+1)
+		} else {
+			i = start - 1
+			for i >= 0 && (body[i] == ' ' || body[i] == '\t' || body[i] == '\n' || body[i] == '\r') {
+				i--
+			}
+			if body[i] == ',' {
+				start = i
+			}
+		}
+
+		result := make([]byte, 0, len(body)-(end-start))
+		result = append(result, body[:start]...)
+		return append(result, body[end:]...), nil
+	}
+}
+
+// getRawOperator returns a raw variant of an operator, or nil if the operator
+// requires a full parse.
+func getRawOperator(opStr string, path []string) jsonRawOperator {
+	switch opStr {
+	case "set":
+		return newRawSetOperator(path)
+	case "select":
+		return newRawSelectOperator(path)
+	case "delete":
+		return newRawDeleteOperator(path)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
 // JSON is a processor that performs an operation on a JSON payload.
 type JSON struct {
 	parts       []int
 	interpolate bool
 	valueBytes  rawJSONValue
 	operator    jsonOperator
+	rawOperator jsonRawOperator
 
 	conf  Config
 	log   log.Modular
@@ -488,6 +1072,7 @@ type JSON struct {
 	mErrJSONP  metrics.StatCounter
 	mErrJSONS  metrics.StatCounter
 	mErr       metrics.StatCounter
+	mFallback  metrics.StatCounter
 	mSucc      metrics.StatCounter
 	mSent      metrics.StatCounter
 	mSentParts metrics.StatCounter
@@ -509,6 +1094,7 @@ func NewJSON(
 		mErrJSONP:  stats.GetCounter("processor.json.error.json_parse"),
 		mErrJSONS:  stats.GetCounter("processor.json.error.json_set"),
 		mErr:       stats.GetCounter("processor.json.error"),
+		mFallback:  stats.GetCounter("processor.json.streaming.fallback"),
 		mSucc:      stats.GetCounter("processor.json.success"),
 		mSent:      stats.GetCounter("processor.json.sent"),
 		mSentParts: stats.GetCounter("processor.json.parts.sent"),
@@ -516,15 +1102,15 @@ func NewJSON(
 
 	j.interpolate = text.ContainsFunctionVariables(j.valueBytes)
 
-	splitPath := strings.Split(conf.JSON.Path, ".")
-	if len(conf.JSON.Path) == 0 || conf.JSON.Path == "." {
-		splitPath = []string{}
-	}
+	splitPath := splitJSONPath(conf.JSON.Path)
 
 	var err error
-	if j.operator, err = getOperator(conf.JSON.Operator, splitPath, json.RawMessage(j.valueBytes)); err != nil {
+	if j.operator, err = getOperator(conf.JSON, splitPath, json.RawMessage(j.valueBytes)); err != nil {
 		return nil, err
 	}
+	if conf.JSON.Streaming {
+		j.rawOperator = getRawOperator(conf.JSON.Operator, splitPath)
+	}
 	return j, nil
 }
 
@@ -550,11 +1136,25 @@ func (p *JSON) ProcessMessage(msg types.Message) ([]types.Message, types.Respons
 		}
 	}
 
+	var exploded map[int]jsonExploded
+
 	for _, index := range targetParts {
+		if p.rawOperator != nil {
+			data, err := p.rawOperator(newMsg.Get(index).Get(), json.RawMessage(valueBytes))
+			if err == nil {
+				newMsg.Get(index).Set(data)
+				p.mSucc.Incr(1)
+				continue
+			}
+			p.mFallback.Incr(1)
+			p.log.Tracef("Falling back to full parse: %v\n", err)
+		}
+
 		jsonPart, err := newMsg.Get(index).JSON()
 		if err != nil {
 			p.mErrJSONP.Incr(1)
 			p.log.Debugf("Failed to parse part into json: %v\n", err)
+			FlagFail(newMsg.Get(index))
 			continue
 		}
 
@@ -562,10 +1162,19 @@ func (p *JSON) ProcessMessage(msg types.Message) ([]types.Message, types.Respons
 		if data, err = p.operator(jsonPart, json.RawMessage(valueBytes)); err != nil {
 			p.mErr.Incr(1)
 			p.log.Debugf("Failed to apply operator: %v\n", err)
+			FlagFail(newMsg.Get(index))
 			continue
 		}
 
 		switch t := data.(type) {
+		case jsonExploded:
+			if index < 0 {
+				index = newMsg.Len() + index
+			}
+			if exploded == nil {
+				exploded = map[int]jsonExploded{}
+			}
+			exploded[index] = t
 		case rawJSONValue:
 			newMsg.Get(index).Set([]byte(t))
 		case []byte:
@@ -582,6 +1191,32 @@ func (p *JSON) ProcessMessage(msg types.Message) ([]types.Message, types.Respons
 		}
 	}
 
+	if len(exploded) > 0 {
+		parts := []types.Part{}
+		newMsg.Iter(func(i int, part types.Part) error {
+			values, exists := exploded[i]
+			if !exists {
+				parts = append(parts, part)
+				return nil
+			}
+			for _, v := range values {
+				newPart := message.NewPart(nil)
+				if err := newPart.SetJSON(v); err != nil {
+					p.mErrJSONS.Incr(1)
+					p.log.Debugf("Failed to convert json into part: %v\n", err)
+					continue
+				}
+				newPart.SetMetadata(part.Metadata().Copy())
+				parts = append(parts, newPart)
+			}
+			return nil
+		})
+		newMsg.SetAll(parts)
+		if newMsg.Len() == 0 {
+			return nil, response.NewAck()
+		}
+	}
+
 	msgs := [1]types.Message{newMsg}
 
 	p.mSent.Incr(1)
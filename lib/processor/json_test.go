@@ -21,7 +21,10 @@
 package processor
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -723,6 +726,9 @@ value:
   baz:
     deeper: look at me
   here: 11
+streaming: false
+delimiter: .
+index_arrays: true
 `,
 		`parts:
 - 0
@@ -735,18 +741,27 @@ value:
     - second
     - third
   here: 11
+streaming: false
+delimiter: .
+index_arrays: true
 `,
 		`parts:
 - 5
 operator: set
 path: foo.bar.baz
 value: 5
+streaming: false
+delimiter: .
+index_arrays: true
 `,
 		`parts:
 - 0
 operator: set
 path: foo.bar
 value: hello world
+streaming: false
+delimiter: .
+index_arrays: true
 `,
 		`parts:
 - 0
@@ -757,6 +772,9 @@ value:
   - values:
     - nested: true
     with: array
+streaming: false
+delimiter: .
+index_arrays: true
 `,
 		`parts:
 - 0
@@ -767,6 +785,9 @@ value:
     bar:
       baz:
         value: true
+streaming: false
+delimiter: .
+index_arrays: true
 `,
 	}
 
@@ -974,3 +995,650 @@ func TestJSONDelete(t *testing.T) {
 		}
 	}
 }
+
+func TestJSONExplode(t *testing.T) {
+	type jTest struct {
+		name   string
+		path   string
+		input  string
+		output []string
+		failed bool
+	}
+
+	tests := []jTest{
+		{
+			name:   "explode array",
+			path:   "foo",
+			input:  `{"id":1,"foo":["a","b"]}`,
+			output: []string{`{"foo":"a","id":1}`, `{"foo":"b","id":1}`},
+		},
+		{
+			name:   "explode object",
+			path:   "foo",
+			input:  `{"id":1,"foo":{"b":2,"a":1}}`,
+			output: []string{`{"foo":1,"id":1}`, `{"foo":2,"id":1}`},
+		},
+		{
+			name:   "explode root",
+			path:   ".",
+			input:  `[{"a":1},{"b":2},3]`,
+			output: []string{`{"a":1}`, `{"b":2}`, `3`},
+		},
+		{
+			name:   "explode escaped path",
+			path:   "foo~1bar",
+			input:  `{"foo.bar":[1,2]}`,
+			output: []string{`{"foo.bar":1}`, `{"foo.bar":2}`},
+		},
+		{
+			name:   "explode empty",
+			path:   "foo",
+			input:  `{"foo":[]}`,
+			output: []string{},
+		},
+		{
+			name:   "explode non array",
+			path:   "foo",
+			input:  `{"foo":"bar"}`,
+			output: []string{`{"foo":"bar"}`},
+			failed: true,
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.JSON.Operator = "explode"
+		conf.JSON.Parts = []int{-1}
+		conf.JSON.Path = test.path
+
+		jProc, err := NewJSON(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatalf("Error for test '%v': %v", test.name, err)
+		}
+
+		inMsg := message.New([][]byte{[]byte(`{"before":true}`), []byte(test.input)})
+		inMsg.Get(1).Metadata().Set("foo", "bar")
+
+		msgs, res := jProc.ProcessMessage(inMsg)
+		if len(msgs) != 1 {
+			t.Fatalf("Test '%v' did not succeed: %v", test.name, res)
+		}
+
+		exp := append([]string{`{"before":true}`}, test.output...)
+		act := []string{}
+		for _, b := range message.GetAllBytes(msgs[0]) {
+			act = append(act, string(b))
+		}
+		if !reflect.DeepEqual(exp, act) {
+			t.Errorf("Wrong result '%v': %v != %v", test.name, act, exp)
+		}
+		for i := 1; i < msgs[0].Len(); i++ {
+			if exp, act := "bar", msgs[0].Get(i).Metadata().Get("foo"); exp != act {
+				t.Errorf("Wrong metadata '%v': %v != %v", test.name, act, exp)
+			}
+			if exp, act := test.failed, HasFailed(msgs[0].Get(i)); exp != act {
+				t.Errorf("Wrong fail flag '%v': %v != %v", test.name, act, exp)
+			}
+		}
+	}
+}
+
+func TestJSONExplodeAll(t *testing.T) {
+	conf := NewConfig()
+	conf.JSON.Operator = "explode"
+	conf.JSON.Path = "foo"
+
+	jProc, err := NewJSON(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := jProc.ProcessMessage(message.New([][]byte{[]byte(`{"foo":[]}`)}))
+	if len(msgs) != 0 {
+		t.Errorf("Expected no messages, received: %v", len(msgs))
+	}
+	if res == nil || res.Error() != nil {
+		t.Errorf("Expected ack response: %v", res)
+	}
+}
+
+func TestJSONFlatten(t *testing.T) {
+	type jTest struct {
+		name   string
+		path   string
+		input  string
+		output string
+		failed bool
+	}
+
+	tests := []jTest{
+		{
+			name:   "flatten nested",
+			path:   "foo",
+			input:  `{"foo":[[1,2],[3],4,[[5]]]}`,
+			output: `{"foo":[1,2,3,4,[5]]}`,
+		},
+		{
+			name:   "flatten root",
+			path:   "",
+			input:  `[["a"],["b","c"]]`,
+			output: `["a","b","c"]`,
+		},
+		{
+			name:   "flatten object",
+			path:   "foo",
+			input:  `{"foo":{"bar":{"baz":1},"qux":[2,{"quz":3}]}}`,
+			output: `{"foo":{"bar.baz":1,"qux.0":2,"qux.1.quz":3}}`,
+		},
+		{
+			name:   "flatten object root",
+			path:   "",
+			input:  `{"foo":{"bar":1},"baz":{},"qux":[]}`,
+			output: `{"baz":{},"foo.bar":1,"qux":[]}`,
+		},
+		{
+			name:   "flatten non array",
+			path:   "foo",
+			input:  `{"foo":"bar"}`,
+			output: `{"foo":"bar"}`,
+			failed: true,
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.JSON.Operator = "flatten"
+		conf.JSON.Path = test.path
+
+		jProc, err := NewJSON(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatalf("Error for test '%v': %v", test.name, err)
+		}
+
+		msgs, _ := jProc.ProcessMessage(message.New([][]byte{[]byte(test.input)}))
+		if len(msgs) != 1 {
+			t.Fatalf("Test '%v' did not succeed", test.name)
+		}
+		if exp, act := test.output, string(msgs[0].Get(0).Get()); exp != act {
+			t.Errorf("Wrong result '%v': %v != %v", test.name, act, exp)
+		}
+		if exp, act := test.failed, HasFailed(msgs[0].Get(0)); exp != act {
+			t.Errorf("Wrong fail flag '%v': %v != %v", test.name, act, exp)
+		}
+	}
+}
+
+func TestJSONFlattenOptions(t *testing.T) {
+	type jTest struct {
+		name        string
+		operator    string
+		delim       string
+		indexArrays bool
+		input       string
+		output      string
+	}
+
+	tests := []jTest{
+		{
+			name:        "flatten no index arrays",
+			operator:    "flatten",
+			delim:       ".",
+			indexArrays: false,
+			input:       `{"foo":{"bar":[1,{"baz":2}]}}`,
+			output:      `{"foo.bar":[1,{"baz":2}]}`,
+		},
+		{
+			name:        "flatten custom delimiter",
+			operator:    "flatten",
+			delim:       "_",
+			indexArrays: true,
+			input:       `{"foo":{"bar":[1,{"baz":2}]}}`,
+			output:      `{"foo_bar_0":1,"foo_bar_1_baz":2}`,
+		},
+		{
+			name:        "unflatten custom delimiter",
+			operator:    "unflatten",
+			delim:       "_",
+			indexArrays: true,
+			input:       `{"foo_bar_0":1,"foo_bar_1_baz":2}`,
+			output:      `{"foo":{"bar":[1,{"baz":2}]}}`,
+		},
+		{
+			name:        "unflatten no index arrays",
+			operator:    "unflatten",
+			delim:       ".",
+			indexArrays: false,
+			input:       `{"foo.0":1,"foo.1":2}`,
+			output:      `{"foo":{"0":1,"1":2}}`,
+		},
+		{
+			name:        "unflatten non sequential indexes",
+			operator:    "unflatten",
+			delim:       ".",
+			indexArrays: true,
+			input:       `{"foo.0":1,"foo.2":2}`,
+			output:      `{"foo":{"0":1,"2":2}}`,
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.JSON.Operator = test.operator
+		conf.JSON.Delimiter = test.delim
+		conf.JSON.IndexArrays = test.indexArrays
+
+		jProc, err := NewJSON(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatalf("Error for test '%v': %v", test.name, err)
+		}
+
+		msgs, _ := jProc.ProcessMessage(message.New([][]byte{[]byte(test.input)}))
+		if len(msgs) != 1 {
+			t.Fatalf("Test '%v' did not succeed", test.name)
+		}
+		if exp, act := test.output, string(msgs[0].Get(0).Get()); exp != act {
+			t.Errorf("Wrong result '%v': %v != %v", test.name, act, exp)
+		}
+		if HasFailed(msgs[0].Get(0)) {
+			t.Errorf("Test '%v' failed", test.name)
+		}
+	}
+}
+
+func TestJSONUnflattenErrors(t *testing.T) {
+	tests := map[string]string{
+		"non object":                  `{"foo":[1,2]}`,
+		"collision":                   `{"foo":{"a":1,"a.b":2}}`,
+		"collision with empty object": `{"foo":{"a":{"b":1},"a.b":{}}}`,
+	}
+
+	for name, input := range tests {
+		conf := NewConfig()
+		conf.JSON.Operator = "unflatten"
+		conf.JSON.Path = "foo"
+
+		jProc, err := NewJSON(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msgs, _ := jProc.ProcessMessage(message.New([][]byte{[]byte(input)}))
+		if len(msgs) != 1 {
+			t.Fatalf("Test '%v' did not succeed", name)
+		}
+		if exp, act := input, string(msgs[0].Get(0).Get()); exp != act {
+			t.Errorf("Wrong result '%v': %v != %v", name, act, exp)
+		}
+		if !HasFailed(msgs[0].Get(0)) {
+			t.Errorf("Expected test '%v' to fail", name)
+		}
+	}
+
+	conf := NewConfig()
+	conf.JSON.Operator = "unflatten"
+	conf.JSON.Delimiter = ""
+	if _, err := NewJSON(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from empty delimiter")
+	}
+}
+
+func TestJSONFlattenRoundTrip(t *testing.T) {
+	inputs := []string{
+		`{"a":1,"b":{"c":"two","d":[3,{"e":[4,5]}]}}`,
+		`{"a":[],"b":{},"c":{"d":{}},"e":[[1,2],[3]],"f":null}`,
+		`{"a":[{"b":[{"c":true}]}]}`,
+		`{"a.b":{"c":1}}`,
+	}
+
+	for _, indexArrays := range []bool{true, false} {
+		for _, delim := range []string{"/", "__"} {
+			flatConf := NewConfig()
+			flatConf.JSON.Operator = "flatten"
+			flatConf.JSON.Delimiter = delim
+			flatConf.JSON.IndexArrays = indexArrays
+
+			unflatConf := NewConfig()
+			unflatConf.JSON.Operator = "unflatten"
+			unflatConf.JSON.Delimiter = delim
+			unflatConf.JSON.IndexArrays = indexArrays
+
+			flatProc, err := NewJSON(flatConf, nil, log.Noop(), metrics.Noop())
+			if err != nil {
+				t.Fatal(err)
+			}
+			unflatProc, err := NewJSON(unflatConf, nil, log.Noop(), metrics.Noop())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, input := range inputs {
+				msgs, _ := flatProc.ProcessMessage(message.New([][]byte{[]byte(input)}))
+				if HasFailed(msgs[0].Get(0)) {
+					t.Fatalf("Failed to flatten '%v'", input)
+				}
+				msgs, _ = unflatProc.ProcessMessage(msgs[0])
+				if HasFailed(msgs[0].Get(0)) {
+					t.Fatalf("Failed to unflatten '%s'", msgs[0].Get(0).Get())
+				}
+
+				var exp, act interface{}
+				if err = json.Unmarshal([]byte(input), &exp); err != nil {
+					t.Fatal(err)
+				}
+				if err = json.Unmarshal(msgs[0].Get(0).Get(), &act); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(exp, act) {
+					t.Errorf("Wrong round trip result with delimiter '%v' and index arrays %v: %s != %s", delim, indexArrays, msgs[0].Get(0).Get(), input)
+				}
+			}
+		}
+	}
+}
+
+func TestJSONEscapedPaths(t *testing.T) {
+	conf := NewConfig()
+	conf.JSON.Operator = "move"
+	conf.JSON.Path = "foo~1bar.baz~0qux"
+	conf.JSON.Value = []byte(`"a~1b"`)
+
+	jProc, err := NewJSON(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, _ := jProc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"foo.bar":{"baz~qux":5}}`),
+	}))
+	if len(msgs) != 1 {
+		t.Fatal("Expected one message")
+	}
+	if exp, act := `{"a.b":5,"foo.bar":{}}`, string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func TestJSONStreaming(t *testing.T) {
+	type jTest struct {
+		name     string
+		operator string
+		path     string
+		value    string
+		input    string
+		output   string
+	}
+
+	tests := []jTest{
+		{
+			name:     "set existing",
+			operator: "set",
+			path:     "foo.bar",
+			value:    `{ "baz": 1 }`,
+			input:    `{"foo": {"qux":"a", "bar": 5}, "abc": [1,2]}`,
+			output:   `{"foo": {"qux":"a", "bar": {"baz":1}}, "abc": [1,2]}`,
+		},
+		{
+			name:     "set missing leaf",
+			operator: "set",
+			path:     "foo.bar",
+			value:    `"hello"`,
+			input:    `{"foo":{"qux":"a"},"abc":true}`,
+			output:   `{"foo":{"qux":"a","bar":"hello"},"abc":true}`,
+		},
+		{
+			name:     "set missing objects",
+			operator: "set",
+			path:     "foo.bar.baz",
+			value:    `5`,
+			input:    `{"abc":"}{"}`,
+			output:   `{"abc":"}{","foo":{"bar":{"baz":5}}}`,
+		},
+		{
+			name:     "set empty object",
+			operator: "set",
+			path:     "foo",
+			value:    `5`,
+			input:    ` { } `,
+			output:   ` { "foo":5} `,
+		},
+		{
+			name:     "set escaped key",
+			operator: "set",
+			path:     "foo\"bar",
+			value:    `5`,
+			input:    `{"foo\u0022bar":1,"foo":2}`,
+			output:   `{"foo\u0022bar":5,"foo":2}`,
+		},
+		{
+			name:     "set through array falls back",
+			operator: "set",
+			path:     "foo.bar",
+			value:    `5`,
+			input:    `{"foo":[{"bar":1}],"baz":2}`,
+			output:   `{"baz":2,"foo":[{"bar":1}]}`,
+		},
+		{
+			name:     "set through null falls back",
+			operator: "set",
+			path:     "foo.bar",
+			value:    `5`,
+			input:    `{"foo":null,"baz":2}`,
+			output:   `{"baz":2,"foo":{"bar":5}}`,
+		},
+		{
+			name:     "set root",
+			operator: "set",
+			path:     "",
+			value:    `{ "baz": 1 }`,
+			input:    `{"foo":2}`,
+			output:   `{"baz":1}`,
+		},
+		{
+			name:     "select nested",
+			operator: "select",
+			path:     "foo.bar",
+			input:    `{"foo":{"a":[1,{"bar":2}],"bar":{ "baz" : [1, 2] }}}`,
+			output:   `{"baz":[1,2]}`,
+		},
+		{
+			name:     "select str",
+			operator: "select",
+			path:     "foo.bar",
+			input:    `{"foo":{"bar":"hello \"world\""}}`,
+			output:   `hello "world"`,
+		},
+		{
+			name:     "select number",
+			operator: "select",
+			path:     "foo",
+			input:    `{"foo":1.50e3}`,
+			output:   `1.50e3`,
+		},
+		{
+			name:     "select missing",
+			operator: "select",
+			path:     "foo.bar",
+			input:    `{"foo":{"baz":1}}`,
+			output:   `null`,
+		},
+		{
+			name:     "select through array falls back",
+			operator: "select",
+			path:     "foo.bar",
+			input:    `{"foo":[{"bar":1},{"bar":2}]}`,
+			output:   `[1,2]`,
+		},
+		{
+			name:     "select duplicate keys falls back",
+			operator: "select",
+			path:     "foo",
+			input:    `{"foo":1,"foo":2}`,
+			output:   `2`,
+		},
+		{
+			name:     "delete first",
+			operator: "delete",
+			path:     "foo.a",
+			input:    `{"foo":{ "a": 1, "b": 2, "c": 3 }}`,
+			output:   `{"foo":{ "b": 2, "c": 3 }}`,
+		},
+		{
+			name:     "delete middle",
+			operator: "delete",
+			path:     "foo.b",
+			input:    `{"foo":{ "a": 1, "b": 2, "c": 3 }}`,
+			output:   `{"foo":{ "a": 1, "c": 3 }}`,
+		},
+		{
+			name:     "delete last",
+			operator: "delete",
+			path:     "foo.c",
+			input:    `{"foo":{ "a": 1, "b": 2, "c": {"d":[3]} }}`,
+			output:   `{"foo":{ "a": 1, "b": 2 }}`,
+		},
+		{
+			name:     "delete only",
+			operator: "delete",
+			path:     "foo.a",
+			input:    `{"foo":{"a":1},"bar":2}`,
+			output:   `{"foo":{},"bar":2}`,
+		},
+		{
+			name:     "delete duplicate keys falls back",
+			operator: "delete",
+			path:     "foo",
+			input:    `{"foo":1,"bar":2,"foo":3}`,
+			output:   `{"bar":2}`,
+		},
+		{
+			name:     "delete missing falls back",
+			operator: "delete",
+			path:     "foo.baz",
+			input:    `{"foo":{"bar":1}}`,
+			output:   `{"foo":{"bar":1}}`,
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.JSON.Operator = test.operator
+		conf.JSON.Path = test.path
+		conf.JSON.Value = []byte(test.value)
+		conf.JSON.Streaming = true
+
+		proc, err := NewJSON(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatalf("Error for test '%v': %v", test.name, err)
+		}
+
+		msgs, _ := proc.ProcessMessage(message.New([][]byte{[]byte(test.input)}))
+		if len(msgs) != 1 {
+			t.Fatalf("Test '%v' did not succeed", test.name)
+		}
+		if exp, act := test.output, string(msgs[0].Get(0).Get()); exp != act {
+			t.Errorf("Wrong result '%v': %v != %v", test.name, act, exp)
+		}
+	}
+}
+
+func TestJSONStreamingMatchesFullParse(t *testing.T) {
+	inputs := []string{
+		`{"foo":{"bar":{"baz":1},"qux":[1,2,{"bar":3}]},"quz":"a\"b"}`,
+		`{"foo":{"bar":"}"},"bar":{"foo":{"bar":null}}}`,
+		`{}`,
+		`{"foo":5}`,
+		`[{"foo":{"bar":1}}]`,
+		`"foo"`,
+		`{"foo":{"bar":5}`,
+	}
+	paths := []string{"foo", "foo.bar", "foo.bar.baz", "bar.foo.bar", "quz", "nope.nope"}
+
+	for _, op := range []string{"set", "select", "delete"} {
+		for _, path := range paths {
+			conf := NewConfig()
+			conf.JSON.Operator = op
+			conf.JSON.Path = path
+			conf.JSON.Value = []byte(`{"new":["value"]}`)
+
+			fullProc, err := NewJSON(conf, nil, log.Noop(), metrics.Noop())
+			if err != nil {
+				t.Fatal(err)
+			}
+			conf.JSON.Streaming = true
+			rawProc, err := NewJSON(conf, nil, log.Noop(), metrics.Noop())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, input := range inputs {
+				fullMsgs, _ := fullProc.ProcessMessage(message.New([][]byte{[]byte(input)}))
+				rawMsgs, _ := rawProc.ProcessMessage(message.New([][]byte{[]byte(input)}))
+
+				fullPart, rawPart := fullMsgs[0].Get(0), rawMsgs[0].Get(0)
+				if exp, act := HasFailed(fullPart), HasFailed(rawPart); exp != act {
+					t.Errorf("Wrong failed flag for %v '%v' on %v: %v != %v", op, path, input, act, exp)
+				}
+
+				var exp, act interface{}
+				if err = json.Unmarshal(fullPart.Get(), &exp); err != nil {
+					exp = string(fullPart.Get())
+				}
+				if err = json.Unmarshal(rawPart.Get(), &act); err != nil {
+					act = string(rawPart.Get())
+				}
+				if !reflect.DeepEqual(exp, act) {
+					t.Errorf("Wrong result for %v '%v' on %v: %s != %s", op, path, input, rawPart.Get(), fullPart.Get())
+				}
+			}
+		}
+	}
+}
+
+func benchmarkJSONDoc() []byte {
+	doc := map[string]interface{}{}
+	for i := 0; i < 1000; i++ {
+		doc[fmt.Sprintf("field%v", i)] = map[string]interface{}{
+			"id":    i,
+			"name":  "a reasonably long string value to pad the document out",
+			"tags":  []interface{}{"foo", "bar", "baz"},
+			"inner": map[string]interface{}{"a": 1.5, "b": true},
+		}
+	}
+	doc["target"] = map[string]interface{}{"value": 1}
+	b, _ := json.Marshal(doc)
+	return b
+}
+
+func benchmarkJSONOperator(b *testing.B, operator string, streaming bool) {
+	conf := NewConfig()
+	conf.JSON.Operator = operator
+	conf.JSON.Path = "target.value"
+	conf.JSON.Value = []byte(`{"foo":"bar"}`)
+	conf.JSON.Streaming = streaming
+
+	proc, err := NewJSON(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	doc := benchmarkJSONDoc()
+	b.SetBytes(int64(len(doc)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		msgs, _ := proc.ProcessMessage(message.New([][]byte{doc}))
+		if len(msgs) != 1 || HasFailed(msgs[0].Get(0)) {
+			b.Fatal("processing failed")
+		}
+	}
+}
+
+func BenchmarkJSONSetFull(b *testing.B)      { benchmarkJSONOperator(b, "set", false) }
+func BenchmarkJSONSetStreaming(b *testing.B) { benchmarkJSONOperator(b, "set", true) }
+
+func BenchmarkJSONSelectFull(b *testing.B)      { benchmarkJSONOperator(b, "select", false) }
+func BenchmarkJSONSelectStreaming(b *testing.B) { benchmarkJSONOperator(b, "select", true) }
+
+func BenchmarkJSONDeleteFull(b *testing.B)      { benchmarkJSONOperator(b, "delete", false) }
+func BenchmarkJSONDeleteStreaming(b *testing.B) { benchmarkJSONOperator(b, "delete", true) }
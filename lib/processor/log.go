@@ -53,7 +53,28 @@ log:
 ` + "```" + `
 
 The ` + "`level`" + ` field determines the log level of the printed events and
-can be any of the following values: TRACE, DEBUG, INFO, WARN, ERROR.`,
+can be any of the following values: TRACE, DEBUG, INFO, WARN, ERROR.
+
+### Structured Fields
+
+The ` + "`fields`" + ` field is a map of keys to values that are added to each
+log event as structured fields. Each value supports
+[function interpolations](../config_interpolation.md#functions), which means
+you can expose message contents and metadata as fields rather than within the
+message itself:
+
+` + "``` yaml" + `
+type: log
+log:
+  level: DEBUG
+  message: "processed order ${!json_field:id}"
+  fields:
+    partition: ${!metadata:kafka_partition}
+    topic: ${!metadata:kafka_topic}
+` + "```" + `
+
+Fields are only added to log events when the logger is configured with
+` + "`json_format`" + ` enabled, which is the default.`,
 	}
 }
 
@@ -61,14 +82,16 @@ can be any of the following values: TRACE, DEBUG, INFO, WARN, ERROR.`,
 
 // LogConfig contains configuration fields for the Log processor.
 type LogConfig struct {
-	Level   string `json:"level" yaml:"level"`
-	Message string `json:"message" yaml:"message"`
+	Level   string            `json:"level" yaml:"level"`
+	Fields  map[string]string `json:"fields" yaml:"fields"`
+	Message string            `json:"message" yaml:"message"`
 }
 
 // NewLogConfig returns a LogConfig with default values.
 func NewLogConfig() LogConfig {
 	return LogConfig{
 		Level:   "INFO",
+		Fields:  map[string]string{},
 		Message: "",
 	}
 }
@@ -79,6 +102,7 @@ func NewLogConfig() LogConfig {
 type Log struct {
 	log     log.Modular
 	level   string
+	fields  map[string]*text.InterpolatedString
 	message *text.InterpolatedString
 	printFn func(msg string)
 }
@@ -90,10 +114,14 @@ func NewLog(
 	l := &Log{
 		log:     log,
 		level:   conf.Log.Level,
+		fields:  map[string]*text.InterpolatedString{},
 		message: text.NewInterpolatedString(conf.Log.Message),
 	}
+	for k, v := range conf.Log.Fields {
+		l.fields[k] = text.NewInterpolatedString(v)
+	}
 	var err error
-	if l.printFn, err = l.levelToLogFn(l.level); err != nil {
+	if l.printFn, err = levelToLogFn(l.level, l.log); err != nil {
 		return nil, err
 	}
 	return l, nil
@@ -101,18 +129,18 @@ func NewLog(
 
 //------------------------------------------------------------------------------
 
-func (l *Log) levelToLogFn(level string) (func(msg string), error) {
+func levelToLogFn(level string, logger log.Modular) (func(msg string), error) {
 	switch level {
 	case "TRACE":
-		return l.log.Traceln, nil
+		return logger.Traceln, nil
 	case "DEBUG":
-		return l.log.Debugln, nil
+		return logger.Debugln, nil
 	case "INFO":
-		return l.log.Infoln, nil
+		return logger.Infoln, nil
 	case "WARN":
-		return l.log.Warnln, nil
+		return logger.Warnln, nil
 	case "ERROR":
-		return l.log.Errorln, nil
+		return logger.Errorln, nil
 	}
 	return nil, fmt.Errorf("log level not recognised: %v", level)
 }
@@ -122,7 +150,19 @@ func (l *Log) levelToLogFn(level string) (func(msg string), error) {
 // ProcessMessage logs an event and returns the message unchanged.
 func (l *Log) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
 	msgs := [1]types.Message{msg}
-	l.printFn(l.message.Get(msg))
+
+	fLogger, ok := l.log.(log.ModularWithFields)
+	if len(l.fields) == 0 || !ok {
+		l.printFn(l.message.Get(msg))
+		return msgs[:], nil
+	}
+
+	fields := make(map[string]string, len(l.fields))
+	for k, v := range l.fields {
+		fields[k] = v.Get(msg)
+	}
+	printFn, _ := levelToLogFn(l.level, fLogger.WithFields(fields))
+	printFn(l.message.Get(msg))
 	return msgs[:], nil
 }
 
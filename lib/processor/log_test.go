@@ -21,6 +21,7 @@
 package processor
 
 import (
+	"bytes"
 	"fmt"
 	"reflect"
 	"testing"
@@ -122,4 +123,41 @@ func TestLogLevelTrace(t *testing.T) {
 	}
 }
 
+func TestLogWithFields(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeLog
+	conf.Log.Level = "INFO"
+	conf.Log.Message = "processed ${!json_field:id}"
+	conf.Log.Fields = map[string]string{
+		"static":    "foo",
+		"partition": "${!metadata:partition}",
+	}
+
+	logConf := log.NewConfig()
+	logConf.AddTimeStamp = false
+	logConf.Prefix = "benthos"
+	logConf.StaticFields = map[string]string{}
+
+	buf := &bytes.Buffer{}
+	l, err := New(conf, nil, log.New(buf, logConf).NewModule(".processor"), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{[]byte(`{"id":"bar"}`)})
+	input.Get(0).Metadata().Set("partition", "3")
+	actMsgs, res := l.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := []types.Message{input}, actMsgs; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong message passthrough: %s != %s", act, exp)
+	}
+
+	exp := `{"partition":"3","static":"foo","level":"INFO","component":"benthos.processor","message":"processed bar"}` + "\n"
+	if act := buf.String(); exp != act {
+		t.Errorf("Wrong log output: %v != %v", act, exp)
+	}
+}
+
 //------------------------------------------------------------------------------
@@ -38,7 +38,17 @@ Parses selected message parts as JSON documents, attempts to merge them into one
 single JSON document and then writes it to a new message part at the end of the
 message. Merged parts are removed unless ` + "`retain_parts`" + ` is set to
 true. The new merged message part will contain the metadata of the first part to
-be merged.`,
+be merged.
+
+Objects are merged recursively and arrays are concatenated. By default values
+that conflict are collected into an array, if ` + "`overwrite_conflicts`" + `
+is set to true then the value of the later part is kept instead.
+
+### Error Handling
+
+Message parts that cannot be parsed as JSON are excluded from the merge, left
+unchanged and are flagged as failed. These parts are kept in the resulting
+message regardless of ` + "`retain_parts`" + `.`,
 	}
 }
 
@@ -46,15 +56,17 @@ be merged.`,
 
 // MergeJSONConfig contains configuration fields for the MergeJSON processor.
 type MergeJSONConfig struct {
-	Parts       []int `json:"parts" yaml:"parts"`
-	RetainParts bool  `json:"retain_parts" yaml:"retain_parts"`
+	Parts              []int `json:"parts" yaml:"parts"`
+	RetainParts        bool  `json:"retain_parts" yaml:"retain_parts"`
+	OverwriteConflicts bool  `json:"overwrite_conflicts" yaml:"overwrite_conflicts"`
 }
 
 // NewMergeJSONConfig returns a MergeJSONConfig with default values.
 func NewMergeJSONConfig() MergeJSONConfig {
 	return MergeJSONConfig{
-		Parts:       []int{},
-		RetainParts: false,
+		Parts:              []int{},
+		RetainParts:        false,
+		OverwriteConflicts: false,
 	}
 }
 
@@ -63,8 +75,9 @@ func NewMergeJSONConfig() MergeJSONConfig {
 // MergeJSON is a processor that merges JSON parsed message parts into a single
 // value.
 type MergeJSON struct {
-	parts  []int
-	retain bool
+	parts     []int
+	retain    bool
+	overwrite bool
 
 	log   log.Modular
 	stats metrics.Type
@@ -82,10 +95,11 @@ func NewMergeJSON(
 	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
 ) (Type, error) {
 	j := &MergeJSON{
-		parts:  conf.MergeJSON.Parts,
-		retain: conf.MergeJSON.RetainParts,
-		log:    log.NewModule(".processor.merge_json"),
-		stats:  stats,
+		parts:     conf.MergeJSON.Parts,
+		retain:    conf.MergeJSON.RetainParts,
+		overwrite: conf.MergeJSON.OverwriteConflicts,
+		log:       log.NewModule(".processor.merge_json"),
+		stats:     stats,
 
 		mCount:     stats.GetCounter("processor.merge_json.count"),
 		mErrJSONP:  stats.GetCounter("processor.merge_json.error.json_parse"),
@@ -99,28 +113,65 @@ func NewMergeJSON(
 
 //------------------------------------------------------------------------------
 
+// mergeJSONOverwrite merges src into dst, where objects are merged recursively,
+// arrays are concatenated and any other conflict is resolved by keeping src.
+// Neither dst nor src are mutated.
+func mergeJSONOverwrite(dst, src interface{}) interface{} {
+	switch s := src.(type) {
+	case map[string]interface{}:
+		if d, ok := dst.(map[string]interface{}); ok {
+			merged := make(map[string]interface{}, len(d)+len(s))
+			for k, v := range d {
+				merged[k] = v
+			}
+			for k, v := range s {
+				if existing, exists := merged[k]; exists {
+					merged[k] = mergeJSONOverwrite(existing, v)
+				} else {
+					merged[k] = v
+				}
+			}
+			return merged
+		}
+	case []interface{}:
+		if d, ok := dst.([]interface{}); ok {
+			merged := make([]interface{}, 0, len(d)+len(s))
+			merged = append(merged, d...)
+			return append(merged, s...)
+		}
+	}
+	return src
+}
+
 // ProcessMessage applies the processor to a message, either creating >0
 // resulting messages or a response to be sent back to the message source.
 func (p *MergeJSON) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
 	p.mCount.Incr(1)
 
 	newPart := gabs.New()
-	mergeFunc := func(index int) {
+	var overwritten interface{}
+	mergeFunc := func(index int) error {
 		jsonPart, err := msg.Get(index).JSON()
 		if err != nil {
 			p.mErrJSONP.Incr(1)
 			p.log.Debugf("Failed to parse part into json: %v\n", err)
-			return
+			return err
+		}
+
+		if p.overwrite {
+			overwritten = mergeJSONOverwrite(overwritten, jsonPart)
+			return nil
 		}
 
 		var gPart *gabs.Container
 		if gPart, err = gabs.Consume(jsonPart); err != nil {
 			p.mErrJSONP.Incr(1)
 			p.log.Debugf("Failed to parse part into json: %v\n", err)
-			return
+			return err
 		}
 
 		newPart.Merge(gPart)
+		return nil
 	}
 
 	var newMsg types.Message
@@ -131,13 +182,13 @@ func (p *MergeJSON) ProcessMessage(msg types.Message) ([]types.Message, types.Re
 	}
 
 	var firstMetadata types.Metadata
+	targetParts := make(map[int]struct{}, len(p.parts))
 	if len(p.parts) == 0 {
 		for i := 0; i < msg.Len(); i++ {
-			mergeFunc(i)
+			targetParts[i] = struct{}{}
 		}
 		firstMetadata = msg.Get(0).Metadata().Copy()
 	} else {
-		targetParts := make(map[int]struct{}, len(p.parts))
 		for _, part := range p.parts {
 			if part < 0 {
 				part = msg.Len() + part
@@ -147,19 +198,34 @@ func (p *MergeJSON) ProcessMessage(msg types.Message) ([]types.Message, types.Re
 			}
 			targetParts[part] = struct{}{}
 		}
-		msg.Iter(func(i int, b types.Part) error {
-			if _, isTarget := targetParts[i]; isTarget {
-				mergeFunc(i)
-			} else if !p.retain {
-				newMsg.Append(b.Copy())
+		firstMetadata = msg.Get(p.parts[0]).Metadata().Copy()
+	}
+
+	msg.Iter(func(i int, b types.Part) error {
+		_, isTarget := targetParts[i]
+		if isTarget && mergeFunc(i) == nil {
+			return nil
+		}
+		if p.retain {
+			if isTarget {
+				FlagFail(newMsg.Get(i))
 			}
 			return nil
-		})
-		firstMetadata = msg.Get(p.parts[0]).Metadata().Copy()
+		}
+		j := newMsg.Append(b.Copy())
+		if isTarget {
+			FlagFail(newMsg.Get(j))
+		}
+		return nil
+	})
+
+	var mergedData interface{} = newPart.Data()
+	if p.overwrite && overwritten != nil {
+		mergedData = overwritten
 	}
 
 	i := newMsg.Append(message.NewPart(nil))
-	if err := newMsg.Get(i).SetJSON(newPart.Data()); err != nil {
+	if err := newMsg.Get(i).SetJSON(mergedData); err != nil {
 		p.mErrJSONS.Incr(1)
 		p.log.Debugf("Failed to marshal merged part into json: %v\n", err)
 	} else {
@@ -28,6 +28,7 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
 )
 
 func TestMergeJSON(t *testing.T) {
@@ -230,3 +231,140 @@ func TestMergeJSONNoRetention(t *testing.T) {
 		t.Errorf("Wrong metadata: %v != %v", act, exp)
 	}
 }
+
+func TestMergeJSONOverwriteConflicts(t *testing.T) {
+	tLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	tStats := metrics.DudType{}
+
+	type jTest struct {
+		name   string
+		input  []string
+		output string
+	}
+
+	tests := []jTest{
+		{
+			name:   "object 1",
+			input:  []string{`{"baz":{"foo":1}}`, `{"baz":{"bar":5}}`},
+			output: `{"baz":{"bar":5,"foo":1}}`,
+		},
+		{
+			name:   "scalar overwrite 1",
+			input:  []string{`{"baz":{"foo":3}}`, `{"baz":{"foo":5}}`},
+			output: `{"baz":{"foo":5}}`,
+		},
+		{
+			name:   "scalar overwrite 2",
+			input:  []string{`{"a":1,"b":"x"}`, `{"a":2}`, `{"a":3,"b":null}`},
+			output: `{"a":3,"b":null}`,
+		},
+		{
+			name:   "array concatenate 1",
+			input:  []string{`{"foo":[1,2]}`, `{"foo":[3]}`, `{"foo":[4,5]}`},
+			output: `{"foo":[1,2,3,4,5]}`,
+		},
+		{
+			name:   "array overwritten by scalar 1",
+			input:  []string{`{"foo":[1,2,3]}`, `{"foo":5}`},
+			output: `{"foo":5}`,
+		},
+		{
+			name:   "top level arrays 1",
+			input:  []string{`[1,2]`, `[3]`},
+			output: `[1,2,3]`,
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.MergeJSON.OverwriteConflicts = true
+
+		jMrg, err := NewMergeJSON(conf, nil, tLog, tStats)
+		if err != nil {
+			t.Fatalf("Error for test '%v': %v", test.name, err)
+		}
+
+		inParts := [][]byte{}
+		for _, p := range test.input {
+			inParts = append(inParts, []byte(p))
+		}
+		inMsg := message.New(inParts)
+		msgs, _ := jMrg.ProcessMessage(inMsg)
+		if len(msgs) != 1 {
+			t.Fatalf("Test '%v' did not succeed", test.name)
+		}
+
+		if exp, act := test.output, string(message.GetAllBytes(msgs[0])[0]); exp != act {
+			t.Errorf("Wrong result '%v': %v != %v", test.name, act, exp)
+		}
+
+		// Source parts must not be mutated by the merge.
+		for i, p := range test.input {
+			if exp, act := p, string(inMsg.Get(i).Get()); exp != act {
+				t.Errorf("Input part modified '%v': %v != %v", test.name, act, exp)
+			}
+		}
+	}
+}
+
+func TestMergeJSONFailedParts(t *testing.T) {
+	tLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	tStats := metrics.DudType{}
+
+	input := func() types.Message {
+		return message.New([][]byte{
+			[]byte(`{"foo":1}`),
+			[]byte(`not json`),
+			[]byte(`{"bar":2}`),
+		})
+	}
+
+	conf := NewConfig()
+	jMrg, err := NewMergeJSON(conf, nil, tLog, tStats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, _ := jMrg.ProcessMessage(input())
+	if len(msgs) != 1 {
+		t.Fatal("Wrong output count")
+	}
+	expParts := [][]byte{
+		[]byte(`not json`),
+		[]byte(`{"bar":2,"foo":1}`),
+	}
+	if act, exp := message.GetAllBytes(msgs[0]), expParts; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if !HasFailed(msgs[0].Get(0)) {
+		t.Error("Expected non-JSON part to be flagged as failed")
+	}
+	if HasFailed(msgs[0].Get(1)) {
+		t.Error("Expected merged part not to be flagged as failed")
+	}
+
+	conf = NewConfig()
+	conf.MergeJSON.RetainParts = true
+	if jMrg, err = NewMergeJSON(conf, nil, tLog, tStats); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, _ = jMrg.ProcessMessage(input())
+	if len(msgs) != 1 {
+		t.Fatal("Wrong output count")
+	}
+	expParts = [][]byte{
+		[]byte(`{"foo":1}`),
+		[]byte(`not json`),
+		[]byte(`{"bar":2}`),
+		[]byte(`{"bar":2,"foo":1}`),
+	}
+	if act, exp := message.GetAllBytes(msgs[0]), expParts; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	for i, exp := range []bool{false, true, false, false} {
+		if act := HasFailed(msgs[0].Get(i)); exp != act {
+			t.Errorf("Wrong fail flag for part %v: %v != %v", i, act, exp)
+		}
+	}
+}
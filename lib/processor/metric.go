@@ -98,10 +98,19 @@ Equivalent to ` + "`gauge`" + ` where instead the metric is a timing.
 
 ### Labels
 
-Some metrics aggregators, such as Prometheus, support arbitrary labels, in which
-case the ` + "`labels`" + ` field can be used in order to create them. Label
+Some metrics aggregators, such as Prometheus or Statsd with a
+` + "`tag_format`" + ` other than ` + "`none`" + `, support arbitrary labels, in
+which case the ` + "`labels`" + ` field can be used in order to create them. Label
 values can also be set using function interpolations in order to dynamically
-populate them with context about the message.`,
+populate them with context about the message.
+
+### Errors
+
+When the contents of ` + "`value`" + ` cannot be parsed for the types
+` + "`counter_by`" + `, ` + "`gauge`" + ` and ` + "`timing`" + ` the metric is
+not updated and the counters ` + "`processor.metric.error`" + ` and
+` + "`processor.metric.error.parse`" + ` are incremented instead. Messages are
+never modified by this processor.`,
 	}
 }
 
@@ -140,9 +149,10 @@ type Metric struct {
 	mGauge   metrics.StatGaugeVec
 	mTimer   metrics.StatTimerVec
 
-	mCount metrics.StatCounter
-	mSucc  metrics.StatCounter
-	mErr   metrics.StatCounter
+	mCount    metrics.StatCounter
+	mSucc     metrics.StatCounter
+	mErr      metrics.StatCounter
+	mErrParse metrics.StatCounter
 
 	handler func(string, types.Message) error
 }
@@ -188,6 +198,7 @@ func NewMetric(
 		mCount:           stats.GetCounter("processor.metric.count"),
 		mSucc:            stats.GetCounter("processor.metric.success"),
 		mErr:             stats.GetCounter("processor.metric.error"),
+		mErrParse:        stats.GetCounter("processor.metric.error.parse"),
 		interpolateValue: text.ContainsFunctionVariables([]byte(conf.Metric.Value)),
 	}
 
@@ -226,6 +237,17 @@ func NewMetric(
 	return m, nil
 }
 
+// parseValue attempts to parse an interpolated value as an integer, counting
+// the failure when it cannot be parsed.
+func (m *Metric) parseValue(val string) (int64, error) {
+	i, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		m.mErrParse.Incr(1)
+		m.log.Debugf("Failed to parse metric value '%v': %v\n", val, err)
+	}
+	return i, err
+}
+
 func (m *Metric) handleCounter(val string, msg types.Message) error {
 	m.mCounter.With(m.labels.values(msg)...).Incr(1)
 	return nil
@@ -240,7 +262,7 @@ func (m *Metric) handleCounterParts(val string, msg types.Message) error {
 }
 
 func (m *Metric) handleCounterBy(val string, msg types.Message) error {
-	i, err := strconv.ParseInt(val, 10, 64)
+	i, err := m.parseValue(val)
 	if err != nil {
 		return err
 	}
@@ -252,7 +274,7 @@ func (m *Metric) handleCounterBy(val string, msg types.Message) error {
 }
 
 func (m *Metric) handleGauge(val string, msg types.Message) error {
-	i, err := strconv.ParseInt(val, 10, 64)
+	i, err := m.parseValue(val)
 	if err != nil {
 		return err
 	}
@@ -264,7 +286,7 @@ func (m *Metric) handleGauge(val string, msg types.Message) error {
 }
 
 func (m *Metric) handleTimer(val string, msg types.Message) error {
-	i, err := strconv.ParseInt(val, 10, 64)
+	i, err := m.parseValue(val)
 	if err != nil {
 		return err
 	}
@@ -229,10 +229,11 @@ func TestMetricCounterBy(t *testing.T) {
 	}
 
 	expMetrics := map[string]int64{
-		"processor.metric.count":   7,
-		"processor.metric.success": 2,
-		"processor.metric.error":   5,
-		"foo.bar":                  5,
+		"processor.metric.count":       7,
+		"processor.metric.success":     2,
+		"processor.metric.error":       5,
+		"processor.metric.error.parse": 4,
+		"foo.bar":                      5,
 	}
 
 	for _, i := range inputs {
@@ -288,10 +289,11 @@ func TestMetricGauge(t *testing.T) {
 	}
 
 	expMetrics := map[string]int64{
-		"processor.metric.count":   7,
-		"processor.metric.success": 1,
-		"processor.metric.error":   6,
-		"foo.bar":                  5,
+		"processor.metric.count":       7,
+		"processor.metric.success":     1,
+		"processor.metric.error":       6,
+		"processor.metric.error.parse": 5,
+		"foo.bar":                      5,
 	}
 
 	for _, i := range inputs {
@@ -299,6 +301,9 @@ func TestMetricGauge(t *testing.T) {
 		if exp, act := 1, len(msg); exp != act {
 			t.Errorf("Wrong count of resulting messages: %v != %v", act, exp)
 		}
+		if exp, act := i, message.GetAllBytes(msg[0]); len(exp) > 0 && !reflect.DeepEqual(exp, act) {
+			t.Errorf("Message was modified: %s != %s", act, exp)
+		}
 		if res != nil {
 			t.Error(res.Error())
 		}
@@ -347,10 +352,11 @@ func TestMetricTiming(t *testing.T) {
 	}
 
 	expMetrics := map[string]int64{
-		"processor.metric.count":   7,
-		"processor.metric.success": 1,
-		"processor.metric.error":   6,
-		"foo.bar":                  5,
+		"processor.metric.count":       7,
+		"processor.metric.success":     1,
+		"processor.metric.error":       6,
+		"processor.metric.error.parse": 5,
+		"foo.bar":                      5,
 	}
 
 	for _, i := range inputs {
@@ -0,0 +1,213 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"sync"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeParallel] = TypeSpec{
+		constructor: NewParallel,
+		description: `
+A processor that applies a list of child processors to messages of a batch as
+though they were each a batch of one message (similar to the
+` + "[`for_each`](#for_each)" + ` processor), but where each message
+is processed in parallel.
+
+The field ` + "`cap`" + `, if greater than zero, caps the maximum number of
+parallel processing threads.
+
+The resulting parts are reassembled into a single batch in the order of the
+original parts. If the child processors of a part result in more than one part
+(or message batch) then those parts are flattened in order into the position of
+the original part, and if they result in zero parts then the original part is
+removed from the batch. Parts that are flagged as failed by a child processor
+are kept in the batch along with their flag.
+
+Child processors are shared between threads, and therefore processors that
+carry state between messages (such as ` + "`dedupe` or `throttle`" + `) might
+behave differently to when executed serially.`,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			var err error
+			procConfs := make([]interface{}, len(conf.Parallel.Processors))
+			for i, pConf := range conf.Parallel.Processors {
+				if procConfs[i], err = SanitiseConfig(pConf); err != nil {
+					return nil, err
+				}
+			}
+			return map[string]interface{}{
+				"cap":        conf.Parallel.Cap,
+				"processors": procConfs,
+			}, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ParallelConfig is a config struct containing fields for the Parallel
+// processor.
+type ParallelConfig struct {
+	Cap        int      `json:"cap" yaml:"cap"`
+	Processors []Config `json:"processors" yaml:"processors"`
+}
+
+// NewParallelConfig returns a default ParallelConfig.
+func NewParallelConfig() ParallelConfig {
+	return ParallelConfig{
+		Cap:        0,
+		Processors: []Config{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Parallel is a processor that applies a list of child processors to each
+// message of a batch individually, where messages are processed in parallel.
+type Parallel struct {
+	children []Type
+	cap      int
+
+	log log.Modular
+
+	mCount     metrics.StatCounter
+	mSent      metrics.StatCounter
+	mSentParts metrics.StatCounter
+	mDropped   metrics.StatCounter
+}
+
+// NewParallel returns a Parallel processor.
+func NewParallel(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	nsStats := metrics.Namespaced(stats, "processor.parallel")
+	nsLog := log.NewModule(".processor.parallel")
+
+	var children []Type
+	for _, pconf := range conf.Parallel.Processors {
+		proc, err := New(pconf, mgr, nsLog, nsStats)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, proc)
+	}
+	return &Parallel{
+		children: children,
+		cap:      conf.Parallel.Cap,
+		log:      nsLog,
+
+		mCount:     stats.GetCounter("processor.parallel.count"),
+		mSent:      stats.GetCounter("processor.parallel.sent"),
+		mSentParts: stats.GetCounter("processor.parallel.parts.sent"),
+		mDropped:   stats.GetCounter("processor.parallel.dropped"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// processPart applies the child processors to a single message part and
+// returns the resulting parts in order.
+func (p *Parallel) processPart(part types.Part) ([]types.Part, types.Response) {
+	tmpMsg := message.New(nil)
+	tmpMsg.SetAll([]types.Part{part})
+
+	resultMsgs := []types.Message{tmpMsg}
+	var res types.Response
+	for i := 0; len(resultMsgs) > 0 && i < len(p.children); i++ {
+		var nextResultMsgs []types.Message
+		for _, m := range resultMsgs {
+			var rMsgs []types.Message
+			rMsgs, res = p.children[i].ProcessMessage(m)
+			nextResultMsgs = append(nextResultMsgs, rMsgs...)
+		}
+		resultMsgs = nextResultMsgs
+	}
+
+	var parts []types.Part
+	for _, m := range resultMsgs {
+		m.Iter(func(i int, p types.Part) error {
+			parts = append(parts, p)
+			return nil
+		})
+	}
+	return parts, res
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *Parallel) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+
+	resultParts := make([][]types.Part, msg.Len())
+	resultRes := make([]types.Response, msg.Len())
+
+	max := p.cap
+	if max <= 0 || max > msg.Len() {
+		max = msg.Len()
+	}
+
+	reqChan := make(chan int)
+	wg := sync.WaitGroup{}
+	wg.Add(max)
+
+	for i := 0; i < max; i++ {
+		go func() {
+			for index := range reqChan {
+				resultParts[index], resultRes[index] = p.processPart(msg.Get(index).Copy())
+			}
+			wg.Done()
+		}()
+	}
+	for i := 0; i < msg.Len(); i++ {
+		reqChan <- i
+	}
+	close(reqChan)
+	wg.Wait()
+
+	resMsg := message.New(nil)
+	var res types.Response
+	for i, parts := range resultParts {
+		resMsg.Append(parts...)
+		if resultRes[i] != nil {
+			res = resultRes[i]
+		}
+	}
+	if resMsg.Len() == 0 {
+		p.mDropped.Incr(1)
+		return nil, res
+	}
+
+	p.mSent.Incr(1)
+	p.mSentParts.Incr(int64(resMsg.Len()))
+
+	resMsgs := [1]types.Message{resMsg}
+	return resMsgs[:], nil
+}
+
+//------------------------------------------------------------------------------
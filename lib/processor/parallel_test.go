@@ -0,0 +1,154 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestParallelOrdering(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeParallel
+	conf.Parallel.Cap = 5
+
+	procConf := NewConfig()
+	procConf.Type = TypeSleep
+	procConf.Sleep.Duration = "${!metadata:delay}ms"
+	conf.Parallel.Processors = append(conf.Parallel.Processors, procConf)
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]byte{}
+	msg := message.New(nil)
+	for i := 0; i < 50; i++ {
+		content := []byte(fmt.Sprintf("part %v", i))
+		exp = append(exp, content)
+
+		part := message.NewPart(content)
+		part.Metadata().Set("delay", strconv.Itoa(rand.Intn(20)))
+		if i%10 == 0 {
+			FlagFail(part)
+		}
+		msg.Append(part)
+	}
+
+	msgs, res := proc.ProcessMessage(msg)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", len(msgs))
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	for i := 0; i < msgs[0].Len(); i++ {
+		if exp, act := i%10 == 0, HasFailed(msgs[0].Get(i)); exp != act {
+			t.Errorf("Wrong failed flag for part %v: %v != %v", i, act, exp)
+		}
+	}
+}
+
+func TestParallelExpand(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeParallel
+
+	procConf := NewConfig()
+	procConf.Type = TypeInsertPart
+	procConf.InsertPart.Content = "${!content}_copy"
+	procConf.InsertPart.Index = -1
+	conf.Parallel.Processors = append(conf.Parallel.Processors, procConf)
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]byte{
+		[]byte("foo"),
+		[]byte("foo_copy"),
+		[]byte("bar"),
+		[]byte("bar_copy"),
+		[]byte("baz"),
+		[]byte("baz_copy"),
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte("foo"), []byte("bar"), []byte("baz"),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+}
+
+func TestParallelDropped(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeParallel
+
+	procConf := NewConfig()
+	procConf.Type = TypeFilterParts
+	procConf.FilterParts.Type = "text"
+	procConf.FilterParts.Text.Operator = "equals"
+	procConf.FilterParts.Text.Arg = "bar"
+	conf.Parallel.Processors = append(conf.Parallel.Processors, procConf)
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]byte{
+		[]byte("bar"),
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte("foo"), []byte("bar"), []byte("baz"),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+
+	if msgs, res = proc.ProcessMessage(message.New([][]byte{
+		[]byte("foo"), []byte("baz"),
+	})); len(msgs) > 0 {
+		t.Error("Expected message to be dropped")
+	}
+	if res == nil {
+		t.Error("Expected response from dropped message")
+	}
+}
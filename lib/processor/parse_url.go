@@ -0,0 +1,283 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/Jeffail/gabs"
+	"golang.org/x/net/publicsuffix"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeParseURL] = TypeSpec{
+		constructor: NewParseURL,
+		description: `
+Parses a URL into its components and writes the result as an object at a
+` + "`target`" + ` path of the JSON message part.
+
+The URL is taken from the ` + "`source`" + ` field, which supports
+[interpolation functions](../config_interpolation.md#functions) resolved
+individually for each message part. For example, with the default config the
+document ` + "`{\"url\":\"https://www.example.co.uk:8080/a/b?id=1&tag=x&tag=y#top\"}`" + `
+would have the following object added:
+
+` + "``` json" + `
+{
+  "scheme": "https",
+  "host": "www.example.co.uk",
+  "port": "8080",
+  "path": "/a/b",
+  "query": {"id":"1","tag":["x","y"]},
+  "fragment": "top"
+}
+` + "```" + `
+
+Query parameters are decoded, and parameters that are repeated become arrays.
+If a query key or value cannot be percent-decoded then its raw form is kept and
+the counter ` + "`processor.parse_url.warning.decode`" + ` is incremented.
+
+When ` + "`registered_domain`" + ` is ` + "`true`" + ` the registered domain of
+the host, also known as eTLD+1, is added under the key
+` + "`registered_domain`" + `. For the example above this would be
+` + "`example.co.uk`" + `. Hosts without a registered domain, such as IP
+addresses, result in an empty string.
+
+Message parts that cannot be parsed as JSON, or where the URL is invalid or not
+absolute, are flagged as failed.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ParseURLConfig contains configuration fields for the ParseURL processor.
+type ParseURLConfig struct {
+	Parts            []int  `json:"parts" yaml:"parts"`
+	Source           string `json:"source" yaml:"source"`
+	Target           string `json:"target" yaml:"target"`
+	RegisteredDomain bool   `json:"registered_domain" yaml:"registered_domain"`
+}
+
+// NewParseURLConfig returns a ParseURLConfig with default values.
+func NewParseURLConfig() ParseURLConfig {
+	return ParseURLConfig{
+		Parts:            []int{},
+		Source:           "${!json_field:url}",
+		Target:           "url_parsed",
+		RegisteredDomain: false,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ParseURL is a processor that parses URLs into structured objects.
+type ParseURL struct {
+	parts            []int
+	source           *text.InterpolatedString
+	targetPath       []string
+	registeredDomain bool
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErrURL    metrics.StatCounter
+	mWarnDec   metrics.StatCounter
+	mErrJSONP  metrics.StatCounter
+	mErrJSONS  metrics.StatCounter
+	mSucc      metrics.StatCounter
+	mSent      metrics.StatCounter
+	mSentParts metrics.StatCounter
+}
+
+// NewParseURL returns a ParseURL processor.
+func NewParseURL(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	return &ParseURL{
+		parts:            conf.ParseURL.Parts,
+		source:           text.NewInterpolatedString(conf.ParseURL.Source),
+		targetPath:       splitJSONPath(conf.ParseURL.Target),
+		registeredDomain: conf.ParseURL.RegisteredDomain,
+
+		log:   log.NewModule(".processor.parse_url"),
+		stats: stats,
+
+		mCount:     stats.GetCounter("processor.parse_url.count"),
+		mErrURL:    stats.GetCounter("processor.parse_url.error.url"),
+		mWarnDec:   stats.GetCounter("processor.parse_url.warning.decode"),
+		mErrJSONP:  stats.GetCounter("processor.parse_url.error.json_parse"),
+		mErrJSONS:  stats.GetCounter("processor.parse_url.error.json_set"),
+		mSucc:      stats.GetCounter("processor.parse_url.success"),
+		mSent:      stats.GetCounter("processor.parse_url.sent"),
+		mSentParts: stats.GetCounter("processor.parse_url.parts.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// unescapeQueryComponent decodes a query key or value, keeping the raw form
+// when it cannot be decoded.
+func (p *ParseURL) unescapeQueryComponent(raw string) string {
+	v, err := url.QueryUnescape(raw)
+	if err != nil {
+		p.mWarnDec.Incr(1)
+		p.log.Debugf("Failed to decode query component '%v': %v\n", raw, err)
+		return raw
+	}
+	return v
+}
+
+func (p *ParseURL) parseQuery(rawQuery string) map[string]interface{} {
+	query := map[string]interface{}{}
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if len(pair) == 0 {
+			continue
+		}
+		var value string
+		key := pair
+		if i := strings.Index(pair, "="); i >= 0 {
+			key, value = pair[:i], pair[i+1:]
+		}
+		key = p.unescapeQueryComponent(key)
+		value = p.unescapeQueryComponent(value)
+
+		switch existing := query[key].(type) {
+		case nil:
+			query[key] = value
+		case string:
+			query[key] = []interface{}{existing, value}
+		case []interface{}:
+			query[key] = append(existing, value)
+		}
+	}
+	return query
+}
+
+func (p *ParseURL) parse(rawURL string) (map[string]interface{}, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, err
+	}
+	if len(u.Scheme) == 0 || len(u.Host) == 0 {
+		return nil, errors.New("not an absolute URL")
+	}
+
+	result := map[string]interface{}{
+		"scheme":   u.Scheme,
+		"host":     u.Hostname(),
+		"port":     u.Port(),
+		"path":     u.Path,
+		"query":    p.parseQuery(u.RawQuery),
+		"fragment": u.Fragment,
+	}
+	if p.registeredDomain {
+		var domain string
+		if net.ParseIP(u.Hostname()) == nil {
+			domain, _ = publicsuffix.EffectiveTLDPlusOne(u.Hostname())
+		}
+		result["registered_domain"] = domain
+	}
+	return result, nil
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *ParseURL) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+
+	newMsg := msg.Copy()
+
+	targetParts := p.parts
+	if len(targetParts) == 0 {
+		targetParts = make([]int, newMsg.Len())
+		for i := range targetParts {
+			targetParts[i] = i
+		}
+	}
+
+	for _, index := range targetParts {
+		if index < 0 {
+			index = newMsg.Len() + index
+		}
+		if index < 0 || index >= newMsg.Len() {
+			continue
+		}
+
+		part := newMsg.Get(index)
+
+		var gPart *gabs.Container
+		if len(p.targetPath) > 0 {
+			jsonPart, err := part.JSON()
+			if err == nil {
+				gPart, err = gabs.Consume(jsonPart)
+			}
+			if err != nil {
+				p.mErrJSONP.Incr(1)
+				p.log.Debugf("Failed to parse part into json: %v\n", err)
+				FlagErr(part, err)
+				continue
+			}
+		}
+
+		rawURL := p.source.Get(message.Lock(newMsg, index))
+		result, err := p.parse(rawURL)
+		if err != nil {
+			p.mErrURL.Incr(1)
+			err = fmt.Errorf("failed to parse URL '%v': %v", rawURL, err)
+			p.log.Debugf("%v\n", err)
+			FlagErr(part, err)
+			continue
+		}
+
+		var data interface{} = result
+		if gPart != nil {
+			gPart.Set(result, p.targetPath...)
+			data = gPart.Data()
+		}
+
+		if err = part.SetJSON(data); err != nil {
+			p.mErrJSONS.Incr(1)
+			p.log.Debugf("Failed to convert json into part: %v\n", err)
+			FlagErr(part, err)
+			continue
+		}
+		p.mSucc.Incr(1)
+	}
+
+	p.mSent.Incr(1)
+	p.mSentParts.Incr(int64(newMsg.Len()))
+	msgs := [1]types.Message{newMsg}
+	return msgs[:], nil
+}
+
+//------------------------------------------------------------------------------
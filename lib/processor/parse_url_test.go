@@ -0,0 +1,143 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func TestParseURL(t *testing.T) {
+	conf := NewConfig()
+	conf.ParseURL.Source = "${!metadata:url}"
+	conf.ParseURL.Target = "parsed"
+	conf.ParseURL.RegisteredDomain = true
+
+	proc, err := NewParseURL(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{
+		[]byte(`{"id":"foo"}`),
+		[]byte(`{"id":"bar"}`),
+	})
+	msg.Get(0).Metadata().Set("url", "https://www.example.co.uk:8080/a%20b/c?id=1&tag=x&tag=y&tag=z&q=hello+world#top")
+	msg.Get(1).Metadata().Set("url", "http://10.0.0.1/")
+
+	msgs, res := proc.ProcessMessage(msg)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp := [][]byte{
+		[]byte(`{"id":"foo","parsed":{"fragment":"top","host":"www.example.co.uk","path":"/a b/c","port":"8080","query":{"id":"1","q":"hello world","tag":["x","y","z"]},"registered_domain":"example.co.uk","scheme":"https"}}`),
+		[]byte(`{"id":"bar","parsed":{"fragment":"","host":"10.0.0.1","path":"/","port":"","query":{},"registered_domain":"","scheme":"http"}}`),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+}
+
+func TestParseURLDecodeWarning(t *testing.T) {
+	conf := NewConfig()
+	conf.ParseURL.Source = "${!content}"
+	conf.ParseURL.Target = ""
+
+	stats := metrics.NewLocal()
+	proc, err := NewParseURL(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`http://example.com/?ok=%41&bad=%zz&%zz=1`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if HasFailed(msgs[0].Get(0)) {
+		t.Error("Unexpected failure")
+	}
+
+	exp := `{"fragment":"","host":"example.com","path":"/","port":"","query":{"%zz":"1","bad":"%zz","ok":"A"},"scheme":"http"}`
+	if act := string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if exp, act := int64(2), stats.GetCounters()["processor.parse_url.warning.decode"]; exp != act {
+		t.Errorf("Wrong count of decode warnings: %v != %v", act, exp)
+	}
+}
+
+func TestParseURLErrors(t *testing.T) {
+	conf := NewConfig()
+
+	stats := metrics.NewLocal()
+	proc, err := NewParseURL(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := [][]byte{
+		[]byte(`{"url":"/just/a/path"}`),
+		[]byte(`{"url":"http://[::1"}`),
+		[]byte(`not json`),
+	}
+	msgs, res := proc.ProcessMessage(message.New(input))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(input, act) {
+		t.Errorf("Failed parts were modified: %s != %s", act, input)
+	}
+
+	expErrs := []string{
+		"failed to parse URL '/just/a/path': not an absolute URL",
+		`failed to parse URL 'http://[::1': parse "http://[::1": missing ']' in host`,
+	}
+	for i, exp := range expErrs {
+		if act := msgs[0].Get(i).Metadata().Get(types.FailErrorKey); exp != act {
+			t.Errorf("Wrong error for part %v: %v != %v", i, act, exp)
+		}
+	}
+	for i := range input {
+		if !HasFailed(msgs[0].Get(i)) {
+			t.Errorf("Expected part %v to be flagged", i)
+		}
+	}
+
+	counters := stats.GetCounters()
+	if exp, act := int64(2), counters["processor.parse_url.error.url"]; exp != act {
+		t.Errorf("Wrong count of url errors: %v != %v", act, exp)
+	}
+	if exp, act := int64(1), counters["processor.parse_url.error.json_parse"]; exp != act {
+		t.Errorf("Wrong count of json errors: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
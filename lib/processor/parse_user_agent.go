@@ -0,0 +1,334 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"container/list"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/Jeffail/benthos/lib/util/useragent"
+	"github.com/Jeffail/gabs"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeParseUA] = TypeSpec{
+		constructor: NewParseUA,
+		description: `
+Parses a User-Agent string into browser, operating system and device fields and
+writes the result as an object at a ` + "`target`" + ` path of the JSON message
+part.
+
+The User-Agent is taken from the ` + "`source`" + ` field, which supports
+[interpolation functions](../config_interpolation.md#functions) resolved
+individually for each message part, e.g. ` + "`${!json_field:request.user_agent}`" + `.
+The result has the following structure:
+
+` + "``` json" + `
+{
+  "family": "Chrome",
+  "major": "70",
+  "minor": "0",
+  "patch": "3538",
+  "os": {"family":"Mac OS X","major":"10","minor":"14","patch":"1"},
+  "device": {"family":"Mac","brand":"Apple","model":"Mac"},
+  "is_bot": false
+}
+` + "```" + `
+
+Fields that cannot be determined are left empty and families that cannot be
+determined are set to ` + "`Other`" + `. Crawlers are identified by a device
+family of ` + "`Spider`" + `, which also sets ` + "`is_bot`" + ` to true.
+
+### Regexes
+
+An embedded set of patterns covering common browsers, HTTP clients, crawlers,
+operating systems and devices is used by default. For more thorough coverage
+set ` + "`regexes_file`" + ` to the path of a ` + "`regexes.yaml`" + ` file
+from the [ua-parser project](https://github.com/ua-parser/uap-core). Patterns
+using features that are not supported by Go regular expressions, such as
+lookarounds, are skipped and the number skipped is logged.
+
+### Caching
+
+Parsing is relatively expensive, and therefore the results of the most recently
+seen User-Agent strings are cached, where ` + "`cache_size`" + ` sets the
+maximum number of entries. Setting it to zero disables the cache.
+
+Message parts that cannot be parsed as JSON are flagged as failed.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ParseUAConfig contains configuration fields for the ParseUA processor.
+type ParseUAConfig struct {
+	Parts       []int  `json:"parts" yaml:"parts"`
+	Source      string `json:"source" yaml:"source"`
+	Target      string `json:"target" yaml:"target"`
+	RegexesFile string `json:"regexes_file" yaml:"regexes_file"`
+	CacheSize   int    `json:"cache_size" yaml:"cache_size"`
+}
+
+// NewParseUAConfig returns a ParseUAConfig with default values.
+func NewParseUAConfig() ParseUAConfig {
+	return ParseUAConfig{
+		Parts:       []int{},
+		Source:      "${!json_field:user_agent}",
+		Target:      "user_agent_parsed",
+		RegexesFile: "",
+		CacheSize:   1000,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// uaCache is a least recently used cache of parsed User-Agent strings.
+type uaCache struct {
+	mut     sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type uaCacheEntry struct {
+	ua     string
+	result useragent.Result
+}
+
+func newUACache(size int) *uaCache {
+	return &uaCache{
+		size:    size,
+		entries: make(map[string]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+func (c *uaCache) get(ua string) (useragent.Result, bool) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	e, exists := c.entries[ua]
+	if !exists {
+		return useragent.Result{}, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*uaCacheEntry).result, true
+}
+
+func (c *uaCache) add(ua string, result useragent.Result) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if e, exists := c.entries[ua]; exists {
+		c.order.MoveToFront(e)
+		e.Value.(*uaCacheEntry).result = result
+		return
+	}
+	c.entries[ua] = c.order.PushFront(&uaCacheEntry{ua: ua, result: result})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*uaCacheEntry).ua)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ParseUA is a processor that parses User-Agent strings into structured
+// objects.
+type ParseUA struct {
+	parts      []int
+	source     *text.InterpolatedString
+	targetPath []string
+	parser     *useragent.Parser
+	cache      *uaCache
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mCacheHit  metrics.StatCounter
+	mCacheMiss metrics.StatCounter
+	mErrJSONP  metrics.StatCounter
+	mErrJSONS  metrics.StatCounter
+	mSucc      metrics.StatCounter
+	mSent      metrics.StatCounter
+	mSentParts metrics.StatCounter
+}
+
+// NewParseUA returns a ParseUA processor.
+func NewParseUA(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	var parser *useragent.Parser
+	var err error
+	if len(conf.ParseUA.RegexesFile) > 0 {
+		var regexes []byte
+		if regexes, err = ioutil.ReadFile(conf.ParseUA.RegexesFile); err != nil {
+			return nil, fmt.Errorf("failed to read regexes file: %v", err)
+		}
+		parser, err = useragent.New(regexes)
+	} else {
+		parser, err = useragent.NewDefault()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p := &ParseUA{
+		parts:      conf.ParseUA.Parts,
+		source:     text.NewInterpolatedString(conf.ParseUA.Source),
+		targetPath: splitJSONPath(conf.ParseUA.Target),
+		parser:     parser,
+
+		log:   log.NewModule(".processor.parse_user_agent"),
+		stats: stats,
+
+		mCount:     stats.GetCounter("processor.parse_user_agent.count"),
+		mCacheHit:  stats.GetCounter("processor.parse_user_agent.cache.hit"),
+		mCacheMiss: stats.GetCounter("processor.parse_user_agent.cache.miss"),
+		mErrJSONP:  stats.GetCounter("processor.parse_user_agent.error.json_parse"),
+		mErrJSONS:  stats.GetCounter("processor.parse_user_agent.error.json_set"),
+		mSucc:      stats.GetCounter("processor.parse_user_agent.success"),
+		mSent:      stats.GetCounter("processor.parse_user_agent.sent"),
+		mSentParts: stats.GetCounter("processor.parse_user_agent.parts.sent"),
+	}
+	if conf.ParseUA.CacheSize > 0 {
+		p.cache = newUACache(conf.ParseUA.CacheSize)
+	}
+	if parser.InvalidPatterns > 0 {
+		p.log.Warnf(
+			"Skipped %v patterns that are not supported by Go regular expressions\n",
+			parser.InvalidPatterns,
+		)
+	}
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (p *ParseUA) parse(ua string) useragent.Result {
+	if p.cache == nil {
+		return p.parser.Parse(ua)
+	}
+	if res, ok := p.cache.get(ua); ok {
+		p.mCacheHit.Incr(1)
+		return res
+	}
+	p.mCacheMiss.Incr(1)
+	res := p.parser.Parse(ua)
+	p.cache.add(ua, res)
+	return res
+}
+
+// uaResultToMap converts a parsed result into a fresh generic structure that
+// is safe to be mutated by subsequent processors.
+func uaResultToMap(r useragent.Result) map[string]interface{} {
+	return map[string]interface{}{
+		"family": r.Agent.Family,
+		"major":  r.Agent.Major,
+		"minor":  r.Agent.Minor,
+		"patch":  r.Agent.Patch,
+		"os": map[string]interface{}{
+			"family": r.OS.Family,
+			"major":  r.OS.Major,
+			"minor":  r.OS.Minor,
+			"patch":  r.OS.Patch,
+		},
+		"device": map[string]interface{}{
+			"family": r.Device.Family,
+			"brand":  r.Device.Brand,
+			"model":  r.Device.Model,
+		},
+		"is_bot": r.IsBot,
+	}
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *ParseUA) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+
+	newMsg := msg.Copy()
+
+	targetParts := p.parts
+	if len(targetParts) == 0 {
+		targetParts = make([]int, newMsg.Len())
+		for i := range targetParts {
+			targetParts[i] = i
+		}
+	}
+
+	for _, index := range targetParts {
+		if index < 0 {
+			index = newMsg.Len() + index
+		}
+		if index < 0 || index >= newMsg.Len() {
+			continue
+		}
+
+		var gPart *gabs.Container
+		if len(p.targetPath) > 0 {
+			jsonPart, err := newMsg.Get(index).JSON()
+			if err == nil {
+				gPart, err = gabs.Consume(jsonPart)
+			}
+			if err != nil {
+				p.mErrJSONP.Incr(1)
+				p.log.Debugf("Failed to parse part into json: %v\n", err)
+				FlagFail(newMsg.Get(index))
+				continue
+			}
+		}
+
+		result := uaResultToMap(p.parse(p.source.Get(message.Lock(newMsg, index))))
+
+		var data interface{} = result
+		if gPart != nil {
+			gPart.Set(result, p.targetPath...)
+			data = gPart.Data()
+		}
+
+		if err := newMsg.Get(index).SetJSON(data); err != nil {
+			p.mErrJSONS.Incr(1)
+			p.log.Debugf("Failed to convert json into part: %v\n", err)
+			FlagFail(newMsg.Get(index))
+			continue
+		}
+		p.mSucc.Incr(1)
+	}
+
+	p.mSent.Incr(1)
+	p.mSentParts.Incr(int64(newMsg.Len()))
+	msgs := [1]types.Message{newMsg}
+	return msgs[:], nil
+}
+
+//------------------------------------------------------------------------------
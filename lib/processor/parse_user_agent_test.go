@@ -0,0 +1,212 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+const testChromeUA = `Mozilla/5.0 (Macintosh; Intel Mac OS X 10_14_1) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/70.0.3538.102 Safari/537.36`
+
+func TestParseUA(t *testing.T) {
+	conf := NewConfig()
+	conf.ParseUA.Target = "ua"
+
+	proc, err := NewParseUA(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"user_agent":"` + testChromeUA + `"}`),
+		[]byte(`{"user_agent":"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"}`),
+		[]byte(`not json`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", len(msgs))
+	}
+
+	exp := [][]byte{
+		[]byte(`{"ua":{"device":{"brand":"Apple","family":"Mac","model":"Mac"},"family":"Chrome","is_bot":false,"major":"70","minor":"0","os":{"family":"Mac OS X","major":"10","minor":"14","patch":"1"},"patch":"3538"},"user_agent":"` + testChromeUA + `"}`),
+		[]byte(`{"ua":{"device":{"brand":"Spider","family":"Spider","model":"Desktop"},"family":"Googlebot","is_bot":true,"major":"2","minor":"1","os":{"family":"Other","major":"","minor":"","patch":""},"patch":""},"user_agent":"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"}`),
+		[]byte(`not json`),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if HasFailed(msgs[0].Get(0)) || HasFailed(msgs[0].Get(1)) {
+		t.Error("Expected parsed parts not to be flagged")
+	}
+	if !HasFailed(msgs[0].Get(2)) {
+		t.Error("Expected non-json part to be flagged")
+	}
+}
+
+func TestParseUAMetadataSource(t *testing.T) {
+	conf := NewConfig()
+	conf.ParseUA.Source = "${!metadata:user_agent}"
+	conf.ParseUA.Target = ""
+
+	proc, err := NewParseUA(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{[]byte(`not json`)})
+	msg.Get(0).Metadata().Set("user_agent", "curl/7.58.0")
+
+	msgs, res := proc.ProcessMessage(msg)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp := `{"device":{"brand":"","family":"Other","model":""},"family":"curl","is_bot":false,"major":"7","minor":"58","os":{"family":"Other","major":"","minor":"","patch":""},"patch":"0"}`
+	if act := string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func TestParseUARegexesFile(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_parse_ua_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	regexesPath := filepath.Join(tmpDir, "regexes.yaml")
+	if err = ioutil.WriteFile(regexesPath, []byte(`
+user_agent_parsers:
+  - regex: '(FooBrowser)/(\d+)'
+os_parsers: []
+device_parsers: []
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewConfig()
+	conf.ParseUA.Target = "request.ua"
+	conf.ParseUA.RegexesFile = regexesPath
+
+	proc, err := NewParseUA(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"user_agent":"FooBrowser/3"}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	var act interface{}
+	if act, err = msgs[0].Get(0).JSON(); err != nil {
+		t.Fatal(err)
+	}
+	ua := act.(map[string]interface{})["request"].(map[string]interface{})["ua"]
+	if exp, act := "FooBrowser", ua.(map[string]interface{})["family"]; exp != act {
+		t.Errorf("Wrong family: %v != %v", act, exp)
+	}
+
+	conf.ParseUA.RegexesFile = filepath.Join(tmpDir, "does_not_exist.yaml")
+	if _, err = NewParseUA(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing regexes file")
+	}
+}
+
+func TestParseUACache(t *testing.T) {
+	conf := NewConfig()
+	conf.ParseUA.CacheSize = 2
+
+	stats := metrics.NewLocal()
+	proc, err := NewParseUA(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ua := range []string{"curl/1", "curl/2", "curl/1", "curl/3", "curl/2", "curl/3"} {
+		msgs, res := proc.ProcessMessage(message.New([][]byte{
+			[]byte(`{"user_agent":"` + ua + `"}`),
+		}))
+		if res != nil {
+			t.Fatal(res.Error())
+		}
+		if HasFailed(msgs[0].Get(0)) {
+			t.Fatalf("Unexpected failure for '%v'", ua)
+		}
+	}
+
+	counters := stats.GetCounters()
+	if exp, act := int64(2), counters["processor.parse_user_agent.cache.hit"]; exp != act {
+		t.Errorf("Wrong count of cache hits: %v != %v", act, exp)
+	}
+	if exp, act := int64(4), counters["processor.parse_user_agent.cache.miss"]; exp != act {
+		t.Errorf("Wrong count of cache misses: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func benchmarkParseUA(b *testing.B, cacheSize int) {
+	conf := NewConfig()
+	conf.ParseUA.CacheSize = cacheSize
+
+	proc, err := NewParseUA(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	msg := message.New([][]byte{
+		[]byte(`{"user_agent":"` + testChromeUA + `"}`),
+	})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, res := proc.ProcessMessage(msg); res != nil {
+			b.Fatal(res.Error())
+		}
+	}
+}
+
+func BenchmarkParseUANoCache(b *testing.B) {
+	benchmarkParseUA(b, 0)
+}
+
+func BenchmarkParseUACache(b *testing.B) {
+	benchmarkParseUA(b, 1000)
+}
+
+//------------------------------------------------------------------------------
@@ -22,6 +22,7 @@ package processor
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 
@@ -94,7 +95,32 @@ process_dag:
 ` + "```" + `
 
 With this config the DAG would determine that the children foo and bar can be
-executed in parallel, and once they are both finished we may proceed onto baz.`,
+executed in parallel, and once they are both finished we may proceed onto baz.
+
+If the dependencies of the children contain a cycle then the processor fails to
+be created.
+
+### Stage Results
+
+After processing, the names of the children that succeeded for each message
+part are written as a comma separated list to the metadata key
+` + "`benthos_dag_succeeded`" + `, and the names of the children that failed
+are written to the key ` + "`benthos_dag_failed`" + `, both in the order that
+they were executed. Children that skipped a message part, due to its premap
+targets not being found or its conditions not passing, are not listed. Message
+parts that fail at least one child are also flagged as failed.
+
+These keys can be used downstream in order to handle partial results, for
+example, the following condition would only pass for message parts where the
+child baz succeeded:
+
+` + "``` yaml" + `
+type: metadata
+metadata:
+  operator: regexp_partial
+  key: benthos_dag_succeeded
+  arg: (^|,)baz(,|$)
+` + "```" + ``,
 		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
 			sanitChildren := map[string]interface{}{}
 			for k, v := range conf.ProcessDAG {
@@ -112,6 +138,18 @@ executed in parallel, and once they are both finished we may proceed onto baz.`,
 
 //------------------------------------------------------------------------------
 
+const (
+	// DAGSucceededKey is a metadata key listing the children of a process_dag
+	// processor that succeeded for a message part.
+	DAGSucceededKey = "benthos_dag_succeeded"
+
+	// DAGFailedKey is a metadata key listing the children of a process_dag
+	// processor that failed for a message part.
+	DAGFailedKey = "benthos_dag_failed"
+)
+
+//------------------------------------------------------------------------------
+
 // DepProcessMapConfig contains a superset of a ProcessMap config and some DAG
 // specific fields.
 type DepProcessMapConfig struct {
@@ -199,6 +237,9 @@ func (p *ProcessDAG) ProcessMessage(msg types.Message) ([]types.Message, types.R
 	p.mCountParts.Incr(int64(msg.Len()))
 
 	result := msg.Copy()
+	succeeded := make([][]string, result.Len())
+	failed := make([][]string, result.Len())
+
 	for _, layer := range p.dag {
 		results := make([]types.Message, len(layer))
 		errors := make([]error, len(layer))
@@ -215,16 +256,59 @@ func (p *ProcessDAG) ProcessMessage(msg types.Message) ([]types.Message, types.R
 
 		for i, id := range layer {
 			if err := errors[i]; err != nil {
+				p.mErr.Incr(1)
 				p.log.Errorf("Failed to perform child '%v': %v\n", id, err)
+				for j := range failed {
+					failed[j] = append(failed[j], id)
+					FlagFail(result.Get(j))
+				}
 				continue
 			}
+
+			// Fail flags from earlier stages are cleared from premapped parts
+			// by CreateResult, therefore any flag seen here was set by this
+			// stage.
+			stageSucceeded := make([]bool, result.Len())
+			stageFailed := make([]bool, result.Len())
+			results[i].Iter(func(j int, part types.Part) error {
+				if j >= len(stageFailed) || part.Get() == nil {
+					return nil
+				}
+				if HasFailed(part) {
+					stageFailed[j] = true
+				} else {
+					stageSucceeded[j] = true
+				}
+				return nil
+			})
+
 			if err := p.children[id].OverlayResult(result, results[i]); err != nil {
+				p.mErr.Incr(1)
 				p.log.Errorf("Failed to overlay child '%v': %v\n", id, err)
-				continue
+				for j := range stageSucceeded {
+					if stageSucceeded[j] || stageFailed[j] {
+						stageSucceeded[j], stageFailed[j] = false, true
+						FlagFail(result.Get(j))
+					}
+				}
+			}
+
+			for j := range stageSucceeded {
+				if stageSucceeded[j] {
+					succeeded[j] = append(succeeded[j], id)
+				} else if stageFailed[j] {
+					failed[j] = append(failed[j], id)
+				}
 			}
 		}
 	}
 
+	result.Iter(func(i int, part types.Part) error {
+		setDAGResultMeta(part.Metadata(), DAGSucceededKey, succeeded[i])
+		setDAGResultMeta(part.Metadata(), DAGFailedKey, failed[i])
+		return nil
+	})
+
 	p.mSent.Incr(1)
 	p.mSentParts.Incr(int64(result.Len()))
 
@@ -234,6 +318,14 @@ func (p *ProcessDAG) ProcessMessage(msg types.Message) ([]types.Message, types.R
 
 //------------------------------------------------------------------------------
 
+func setDAGResultMeta(meta types.Metadata, key string, ids []string) {
+	if len(ids) == 0 {
+		meta.Delete(key)
+		return
+	}
+	meta.Set(key, strings.Join(ids, ","))
+}
+
 func getDeps(id string, wanted []string, procs map[string]*ProcessMap) []string {
 	dependencies := []string{}
 	targetsNeeded := wanted
@@ -274,6 +366,7 @@ func resolveDAG(explicitDeps map[string][]string, procs map[string]*ProcessMap)
 	}
 	layers := dependencysolver.LayeredTopologicalSort(entries)
 	for _, l := range layers {
+		sort.Strings(l)
 		for _, id := range l {
 			delete(targetProcs, id)
 		}
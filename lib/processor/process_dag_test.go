@@ -142,3 +142,116 @@ func TestProcessDAGDiamond(t *testing.T) {
 		t.Errorf("Wrong result: %s != %s", act, exp)
 	}
 }
+
+func TestProcessDAGStageResults(t *testing.T) {
+	mgr, memCache := newCacheTestMgr(t)
+	memCache.Set("foo", []byte(`"cached foo"`))
+
+	cacheConf := NewConfig()
+	cacheConf.Type = "cache"
+	cacheConf.Cache.Cache = "foocache"
+	cacheConf.Cache.Operator = "get"
+	cacheConf.Cache.Key = "${!json_field:key}"
+
+	barConf := NewProcessMapConfig()
+	barConf.Premap["key"] = "tmp.foo"
+	barConf.Postmap["tmp.bar"] = "."
+	barConf.Processors = append(barConf.Processors, cacheConf)
+
+	conf := NewConfig()
+	conf.Type = "process_dag"
+	conf.ProcessDAG["foo"] = createProcMapConf("root", "tmp.foo")
+	conf.ProcessDAG["bar"] = DepProcessMapConfig{ProcessMapConfig: barConf}
+	conf.ProcessDAG["baz"] = createProcMapConf("tmp.bar", "tmp.baz")
+
+	c, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := [][]byte{
+		[]byte(`{"oops":"no root"}`),
+		[]byte(`{"root":"foo","tmp":{"bar":"cached foo","baz":"cached foo","foo":"foo"}}`),
+		[]byte(`{"root":"bar","tmp":{"foo":"bar"}}`),
+	}
+
+	input := message.New([][]byte{
+		[]byte(`{"oops":"no root"}`),
+		[]byte(`{"root":"foo"}`),
+		[]byte(`{"root":"bar"}`),
+	})
+	input.Get(0).Metadata().Set(DAGFailedKey, "stale")
+
+	msg, res := c.ProcessMessage(input)
+	if res != nil {
+		t.Error(res.Error())
+	}
+	if act := message.GetAllBytes(msg[0]); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+
+	expSucceeded := []string{"", "foo,bar,baz", "foo"}
+	expFailed := []string{"", "", "bar"}
+	expFlagged := []bool{false, false, true}
+	for i := 0; i < msg[0].Len(); i++ {
+		meta := msg[0].Get(i).Metadata()
+		if exp, act := expSucceeded[i], meta.Get(DAGSucceededKey); exp != act {
+			t.Errorf("Wrong succeeded stages for part %v: %v != %v", i, act, exp)
+		}
+		if exp, act := expFailed[i], meta.Get(DAGFailedKey); exp != act {
+			t.Errorf("Wrong failed stages for part %v: %v != %v", i, act, exp)
+		}
+		if exp, act := expFlagged[i], HasFailed(msg[0].Get(i)); exp != act {
+			t.Errorf("Wrong fail flag for part %v: %v != %v", i, act, exp)
+		}
+	}
+}
+
+func TestProcessDAGFailureNoCascade(t *testing.T) {
+	mgr, _ := newCacheTestMgr(t)
+
+	cacheConf := NewConfig()
+	cacheConf.Type = "cache"
+	cacheConf.Cache.Cache = "foocache"
+	cacheConf.Cache.Operator = "get"
+	cacheConf.Cache.Key = "${!json_field:key}"
+
+	aConf := NewProcessMapConfig()
+	aConf.Premap["key"] = "root"
+	aConf.Postmap["tmp.a"] = "."
+	aConf.Processors = append(aConf.Processors, cacheConf)
+
+	conf := NewConfig()
+	conf.Type = "process_dag"
+	conf.ProcessDAG["x"] = createProcMapConf("root", "tmp.x")
+	conf.ProcessDAG["a"] = DepProcessMapConfig{ProcessMapConfig: aConf}
+	conf.ProcessDAG["b"] = createProcMapConf("tmp.x", "tmp.b")
+
+	c, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := [][]byte{
+		[]byte(`{"root":"foo","tmp":{"b":"foo","x":"foo"}}`),
+	}
+
+	msg, res := c.ProcessMessage(message.New([][]byte{
+		[]byte(`{"root":"foo"}`),
+	}))
+	if res != nil {
+		t.Error(res.Error())
+	}
+	if act := message.GetAllBytes(msg[0]); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+
+	meta := msg[0].Get(0).Metadata()
+	if exp, act := "a", meta.Get(DAGFailedKey); exp != act {
+		t.Errorf("Wrong failed stages: %v != %v", act, exp)
+	}
+	if exp, act := "x,b", meta.Get(DAGSucceededKey); exp != act {
+		t.Errorf("Wrong succeeded stages: %v != %v", act, exp)
+	}
+	if !HasFailed(msg[0].Get(0)) {
+		t.Error("Expected part to be flagged")
+	}
+}
@@ -21,6 +21,9 @@
 package processor
 
 import (
+	"encoding/json"
+	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -41,9 +44,32 @@ JSON format is supported) then applies a list of processors to the extracted
 value, and finally sets the field within the original payloads to the processed
 result.
 
+The result can be coerced into a specific type with the field
+` + "`result_type`" + `, which can be one of ` + "`string`, `int`, `float` or `object`" + `.
+The type ` + "`object`" + ` parses the result as a JSON document of any kind.
+
+For example, the following config lowercases the email address of each
+document:
+
+` + "``` yaml" + `
+process_field:
+  path: user.email
+  result_type: string
+  processors:
+  - text:
+      operator: to_lower
+` + "```" + `
+
 If the number of messages resulting from the processing steps does not match the
 original count then this processor fails and the messages continue unchanged.
-Therefore, you should avoid using batch and filter type processors in this list.`,
+Therefore, you should avoid using batch and filter type processors in this list.
+
+### Error Handling
+
+Message parts that cannot be parsed as JSON, that are flagged as failed by a
+child processor, or where the result cannot be coerced into the
+` + "`result_type`" + `, are left unchanged and are flagged as failed. If the
+processing steps fail entirely then all targeted parts are flagged as failed.`,
 		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
 			var err error
 			procConfs := make([]interface{}, len(conf.ProcessField.Processors))
@@ -53,9 +79,10 @@ Therefore, you should avoid using batch and filter type processors in this list.
 				}
 			}
 			return map[string]interface{}{
-				"parts":      conf.ProcessField.Parts,
-				"path":       conf.ProcessField.Path,
-				"processors": procConfs,
+				"parts":       conf.ProcessField.Parts,
+				"path":        conf.ProcessField.Path,
+				"result_type": conf.ProcessField.ResultType,
+				"processors":  procConfs,
 			}, nil
 		},
 	}
@@ -68,6 +95,7 @@ Therefore, you should avoid using batch and filter type processors in this list.
 type ProcessFieldConfig struct {
 	Parts      []int    `json:"parts" yaml:"parts"`
 	Path       string   `json:"path" yaml:"path"`
+	ResultType string   `json:"result_type" yaml:"result_type"`
 	Processors []Config `json:"processors" yaml:"processors"`
 }
 
@@ -76,17 +104,47 @@ func NewProcessFieldConfig() ProcessFieldConfig {
 	return ProcessFieldConfig{
 		Parts:      []int{},
 		Path:       "",
+		ResultType: "string",
 		Processors: []Config{},
 	}
 }
 
 //------------------------------------------------------------------------------
 
+type processFieldCodec func(part []byte) (interface{}, error)
+
+func stringToProcessFieldCodec(t string) (processFieldCodec, error) {
+	switch t {
+	case "string":
+		return func(part []byte) (interface{}, error) {
+			return string(part), nil
+		}, nil
+	case "int":
+		return func(part []byte) (interface{}, error) {
+			return strconv.ParseInt(strings.TrimSpace(string(part)), 10, 64)
+		}, nil
+	case "float":
+		return func(part []byte) (interface{}, error) {
+			return strconv.ParseFloat(strings.TrimSpace(string(part)), 64)
+		}, nil
+	case "object":
+		return func(part []byte) (interface{}, error) {
+			var jObj interface{}
+			err := json.Unmarshal(part, &jObj)
+			return jObj, err
+		}, nil
+	}
+	return nil, fmt.Errorf("result type not recognised: %v", t)
+}
+
+//------------------------------------------------------------------------------
+
 // ProcessField is a processor that applies a list of child processors to a
 // field extracted from the original payload.
 type ProcessField struct {
 	parts    []int
 	path     []string
+	codec    processFieldCodec
 	children []Type
 
 	log log.Modular
@@ -94,6 +152,8 @@ type ProcessField struct {
 	mCount              metrics.StatCounter
 	mErr                metrics.StatCounter
 	mErrJSONParse       metrics.StatCounter
+	mErrCoerce          metrics.StatCounter
+	mErrProc            metrics.StatCounter
 	mErrMisaligned      metrics.StatCounter
 	mErrMisalignedBatch metrics.StatCounter
 	mSent               metrics.StatCounter
@@ -108,6 +168,11 @@ func NewProcessField(
 	nsStats := metrics.Namespaced(stats, "processor.process_field")
 	nsLog := log.NewModule(".processor.process_field")
 
+	codec, err := stringToProcessFieldCodec(conf.ProcessField.ResultType)
+	if err != nil {
+		return nil, err
+	}
+
 	var children []Type
 	for _, pconf := range conf.ProcessField.Processors {
 		proc, err := New(pconf, mgr, nsLog, nsStats)
@@ -119,6 +184,7 @@ func NewProcessField(
 	return &ProcessField{
 		parts:    conf.ProcessField.Parts,
 		path:     strings.Split(conf.ProcessField.Path, "."),
+		codec:    codec,
 		children: children,
 
 		log: nsLog,
@@ -126,6 +192,8 @@ func NewProcessField(
 		mCount:              stats.GetCounter("processor.process_field.count"),
 		mErr:                stats.GetCounter("processor.process_field.error"),
 		mErrJSONParse:       stats.GetCounter("processor.process_field.error.json_parse"),
+		mErrCoerce:          stats.GetCounter("processor.process_field.error.coerce"),
+		mErrProc:            stats.GetCounter("processor.process_field.error.processors"),
 		mErrMisaligned:      stats.GetCounter("processor.process_field.error.misaligned"),
 		mErrMisalignedBatch: stats.GetCounter("processor.process_field.error.misaligned_messages"),
 		mSent:               stats.GetCounter("processor.process_field.sent"),
@@ -157,15 +225,16 @@ func (p *ProcessField) ProcessMessage(msg types.Message) (msgs []types.Message,
 
 	for i, index := range targetParts {
 		reqMsg.Get(i).Set([]byte(""))
-		var err error
-		var jObj interface{}
-		if jObj, err = payload.Get(index).JSON(); err != nil {
-			p.mErrJSONParse.Incr(1)
-			p.log.Errorf("Failed to decode part: %v\n", err)
+		jObj, err := payload.Get(index).JSON()
+		if err == nil {
+			gParts[i], err = gabs.Consume(jObj)
 		}
-		if gParts[i], err = gabs.Consume(jObj); err != nil {
+		if err != nil {
+			p.mErr.Incr(1)
 			p.mErrJSONParse.Incr(1)
 			p.log.Errorf("Failed to decode part: %v\n", err)
+			gParts[i] = nil
+			continue
 		}
 		gTarget := gParts[i].S(p.path...)
 		switch t := gTarget.Data().(type) {
@@ -201,11 +270,32 @@ func (p *ProcessField) ProcessMessage(msg types.Message) (msgs []types.Message,
 		p.mErr.Incr(1)
 		p.mErrMisalignedBatch.Incr(1)
 		p.log.Errorf("Misaligned processor result batch. Expected %v messages, received %v\n", exp, act)
+		for _, index := range targetParts {
+			FlagFail(payload.Get(index))
+		}
 		return
 	}
 
 	for i, index := range targetParts {
-		gParts[i].Set(string(resMsg.Get(i).Get()), p.path...)
+		if gParts[i] == nil {
+			FlagFail(payload.Get(index))
+			continue
+		}
+		if HasFailed(resMsg.Get(i)) {
+			p.mErr.Incr(1)
+			p.mErrProc.Incr(1)
+			FlagFail(payload.Get(index))
+			continue
+		}
+		v, err := p.codec(resMsg.Get(i).Get())
+		if err != nil {
+			p.mErr.Incr(1)
+			p.mErrCoerce.Incr(1)
+			p.log.Errorf("Failed to coerce processed result: %v\n", err)
+			FlagFail(payload.Get(index))
+			continue
+		}
+		gParts[i].Set(v, p.path...)
 		payload.Get(index).SetJSON(gParts[i].Data())
 	}
 
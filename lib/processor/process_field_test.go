@@ -166,6 +166,11 @@ func TestProcessFieldBadProc(t *testing.T) {
 	if act := message.GetAllBytes(msg[0]); !reflect.DeepEqual(act, exp) {
 		t.Errorf("Wrong result: %s != %s", act, exp)
 	}
+	for i := 0; i < msg[0].Len(); i++ {
+		if !HasFailed(msg[0].Get(i)) {
+			t.Errorf("Expected part %v to be flagged", i)
+		}
+	}
 }
 
 func TestProcessFieldBadProcTwo(t *testing.T) {
@@ -199,4 +204,119 @@ func TestProcessFieldBadProcTwo(t *testing.T) {
 	if act := message.GetAllBytes(msg[0]); !reflect.DeepEqual(act, exp) {
 		t.Errorf("Wrong result: %s != %s", act, exp)
 	}
+	for i := 0; i < msg[0].Len(); i++ {
+		if !HasFailed(msg[0].Get(i)) {
+			t.Errorf("Expected part %v to be flagged", i)
+		}
+	}
+}
+
+func TestProcessFieldResultTypes(t *testing.T) {
+	tests := []struct {
+		name       string
+		resultType string
+		input      [][]byte
+		output     [][]byte
+		failed     []bool
+	}{
+		{
+			name:       "string",
+			resultType: "string",
+			input: [][]byte{
+				[]byte(`{"foo":{"bar":"Foo@Example.COM"}}`),
+				[]byte(`{"foo":{"bar":5}}`),
+			},
+			output: [][]byte{
+				[]byte(`{"foo":{"bar":"foo@example.com"}}`),
+				[]byte(`{"foo":{"bar":"5"}}`),
+			},
+			failed: []bool{false, false},
+		},
+		{
+			name:       "int",
+			resultType: "int",
+			input: [][]byte{
+				[]byte(`{"foo":{"bar":"5"}}`),
+				[]byte(`{"foo":{"bar":"5.5"}}`),
+				[]byte(`{"foo":{"bar":"NOPE"}}`),
+			},
+			output: [][]byte{
+				[]byte(`{"foo":{"bar":5}}`),
+				[]byte(`{"foo":{"bar":"5.5"}}`),
+				[]byte(`{"foo":{"bar":"NOPE"}}`),
+			},
+			failed: []bool{false, true, true},
+		},
+		{
+			name:       "float",
+			resultType: "float",
+			input: [][]byte{
+				[]byte(`{"foo":{"bar":"5"}}`),
+				[]byte(`{"foo":{"bar":"5.5"}}`),
+				[]byte(`{"foo":{"bar":"NOPE"}}`),
+			},
+			output: [][]byte{
+				[]byte(`{"foo":{"bar":5}}`),
+				[]byte(`{"foo":{"bar":5.5}}`),
+				[]byte(`{"foo":{"bar":"NOPE"}}`),
+			},
+			failed: []bool{false, false, true},
+		},
+		{
+			name:       "object",
+			resultType: "object",
+			input: [][]byte{
+				[]byte(`{"foo":{"bar":{"baz":"QUX"}}}`),
+				[]byte(`{"foo":{"bar":"NOT JSON"}}`),
+				[]byte(`not json at all`),
+			},
+			output: [][]byte{
+				[]byte(`{"foo":{"bar":{"baz":"qux"}}}`),
+				[]byte(`{"foo":{"bar":"NOT JSON"}}`),
+				[]byte(`not json at all`),
+			},
+			failed: []bool{false, true, true},
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.Type = "process_field"
+		conf.ProcessField.Path = "foo.bar"
+		conf.ProcessField.ResultType = test.resultType
+
+		procConf := NewConfig()
+		procConf.Type = "text"
+		procConf.Text.Operator = "to_lower"
+
+		conf.ProcessField.Processors = append(conf.ProcessField.Processors, procConf)
+
+		c, err := New(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msg, res := c.ProcessMessage(message.New(test.input))
+		if res != nil {
+			t.Fatal(res.Error())
+		}
+		if act := message.GetAllBytes(msg[0]); !reflect.DeepEqual(act, test.output) {
+			t.Errorf("Wrong result for %v: %s != %s", test.name, act, test.output)
+		}
+		for i, exp := range test.failed {
+			if act := HasFailed(msg[0].Get(i)); act != exp {
+				t.Errorf("Wrong fail flag for %v part %v: %v != %v", test.name, i, act, exp)
+			}
+		}
+	}
+}
+
+func TestProcessFieldBadResultType(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "process_field"
+	conf.ProcessField.ResultType = "nope"
+
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad result type")
+	}
 }
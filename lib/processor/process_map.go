@@ -98,6 +98,15 @@ not match the original count then this processor fails and the messages continue
 unchanged. Therefore, you should avoid using batch and filter type processors in
 this list.
 
+### Error Handling
+
+When the child processors fail, or their results cannot be aligned with the
+original batch, the original message parts are left unchanged and are flagged as
+failed. Similarly, if a child processor
+flags an individual part as failed then the result of that part is not mapped
+back, and the original part is flagged instead. Message parts that are skipped
+due to missing premap targets or conditions are not flagged.
+
 ### Batch Ordering
 
 This processor supports batch messages. When message parts are post-mapped after
@@ -284,15 +293,19 @@ func NewProcessMap(
 // ProcessMessage applies the processor to a message, either creating >0
 // resulting messages or a response to be sent back to the message source.
 func (p *ProcessMap) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	result := msg.Copy()
+
 	alignedResult, err := p.CreateResult(msg)
 	if err != nil {
-		msgs := [1]types.Message{msg}
+		p.flagParts(result)
+		msgs := [1]types.Message{result}
 		return msgs[:], nil
 	}
 
-	result := msg.Copy()
 	if err = p.OverlayResult(result, alignedResult); err != nil {
-		msgs := [1]types.Message{msg}
+		result = msg.Copy()
+		p.flagParts(result)
+		msgs := [1]types.Message{result}
 		return msgs[:], nil
 	}
 
@@ -336,6 +349,13 @@ func (p *ProcessMap) CreateResult(msg types.Message) (types.Message, error) {
 		return nil, err
 	}
 
+	// Parts flagged as failed before reaching this processor would otherwise
+	// be indistinguishable from those flagged by our children.
+	mappedMsg.Iter(func(_ int, part types.Part) error {
+		ClearFail(part)
+		return nil
+	})
+
 	if mappedMsg.Len() == 0 {
 		p.mSkipped.Incr(1)
 		p.mSkippedParts.Incr(int64(msg.Len()))
@@ -372,9 +392,38 @@ func (p *ProcessMap) CreateResult(msg types.Message) (types.Message, error) {
 	return alignedResult, nil
 }
 
+// flagParts marks each message part targeted by this processor as failed.
+func (p *ProcessMap) flagParts(msg types.Message) {
+	if len(p.parts) == 0 {
+		msg.Iter(func(_ int, part types.Part) error {
+			FlagFail(part)
+			return nil
+		})
+		return
+	}
+	for _, index := range p.parts {
+		FlagFail(msg.Get(index))
+	}
+}
+
 // OverlayResult attempts to merge the result of a process_map with the original
 //  payload as per the map specified in the postmap and postmap_optional fields.
+//
+// Response parts that were flagged as failed by the child processors are not
+// mapped, instead the corresponding payload part is left unchanged and flagged
+// as failed.
 func (p *ProcessMap) OverlayResult(payload, response types.Message) error {
+	if payload.Len() == response.Len() {
+		response.Iter(func(i int, part types.Part) error {
+			if part.Get() != nil && HasFailed(part) {
+				p.mErrProc.Incr(1)
+				FlagFail(payload.Get(i))
+				part.Set(nil)
+			}
+			return nil
+		})
+	}
+
 	if err := p.mapper.MapResponses(payload, response); err != nil {
 		p.mErrPost.Incr(1)
 		p.mErr.Incr(1)
@@ -188,6 +188,11 @@ func TestProcessMapStrict(t *testing.T) {
 	if act := message.GetAllBytes(msg[0]); !reflect.DeepEqual(act, exp) {
 		t.Errorf("Wrong result: %s != %s", act, exp)
 	}
+	for i := 0; i < msg[0].Len(); i++ {
+		if HasFailed(msg[0].Get(i)) {
+			t.Errorf("Expected part %v not to be flagged", i)
+		}
+	}
 }
 
 func TestProcessMapOptional(t *testing.T) {
@@ -259,6 +264,11 @@ func TestProcessMapBadProc(t *testing.T) {
 	if act := message.GetAllBytes(msg[0]); !reflect.DeepEqual(act, exp) {
 		t.Errorf("Wrong result: %s != %s", act, exp)
 	}
+	for i := 0; i < msg[0].Len(); i++ {
+		if !HasFailed(msg[0].Get(i)) {
+			t.Errorf("Expected part %v to be flagged", i)
+		}
+	}
 }
 
 func TestProcessMapBadProcTwo(t *testing.T) {
@@ -293,4 +303,93 @@ func TestProcessMapBadProcTwo(t *testing.T) {
 	if act := message.GetAllBytes(msg[0]); !reflect.DeepEqual(act, exp) {
 		t.Errorf("Wrong result: %s != %s", act, exp)
 	}
+	for i := 0; i < msg[0].Len(); i++ {
+		if !HasFailed(msg[0].Get(i)) {
+			t.Errorf("Expected part %v to be flagged", i)
+		}
+	}
+}
+
+func TestProcessMapChildFlagged(t *testing.T) {
+	mgr, memCache := newCacheTestMgr(t)
+	memCache.Set("foo", []byte(`"cached foo"`))
+
+	conf := NewConfig()
+	conf.Type = "process_map"
+	conf.ProcessMap.Premap["key"] = "key"
+	conf.ProcessMap.Postmap["value"] = "."
+
+	procConf := NewConfig()
+	procConf.Type = "cache"
+	procConf.Cache.Cache = "foocache"
+	procConf.Cache.Operator = "get"
+	procConf.Cache.Key = "${!json_field:key}"
+
+	conf.ProcessMap.Processors = append(conf.ProcessMap.Processors, procConf)
+
+	c, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]byte{
+		[]byte(`{"key":"foo","value":"cached foo"}`),
+		[]byte(`{"key":"bar"}`),
+		[]byte(`{"nokey":"baz"}`),
+	}
+
+	msg, res := c.ProcessMessage(message.New([][]byte{
+		[]byte(`{"key":"foo"}`),
+		[]byte(`{"key":"bar"}`),
+		[]byte(`{"nokey":"baz"}`),
+	}))
+	if res != nil {
+		t.Error(res.Error())
+	}
+	if act := message.GetAllBytes(msg[0]); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	for i, exp := range []bool{false, true, false} {
+		if act := HasFailed(msg[0].Get(i)); act != exp {
+			t.Errorf("Wrong fail flag for part %v: %v != %v", i, act, exp)
+		}
+	}
+}
+
+func TestProcessMapPreFlagged(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "process_map"
+	conf.ProcessMap.Premap["."] = "foo"
+	conf.ProcessMap.Postmap["bar"] = "."
+
+	procConf := NewConfig()
+	procConf.Type = "text"
+	procConf.Text.Operator = "to_upper"
+
+	conf.ProcessMap.Processors = append(conf.ProcessMap.Processors, procConf)
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]byte{
+		[]byte(`{"bar":"HELLO","foo":"hello"}`),
+	}
+
+	input := message.New([][]byte{
+		[]byte(`{"foo":"hello"}`),
+	})
+	FlagFail(input.Get(0))
+
+	msg, res := c.ProcessMessage(input)
+	if res != nil {
+		t.Error(res.Error())
+	}
+	if act := message.GetAllBytes(msg[0]); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if !HasFailed(msg[0].Get(0)) {
+		t.Error("Expected upstream fail flag to be preserved")
+	}
 }
@@ -0,0 +1,191 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/clock"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeRateLimit] = TypeSpec{
+		constructor: NewRateLimit,
+		description: `
+Throttles the throughput of a pipeline to a maximum of message batches per
+period defined by a [rate limit resource](../rate_limits/README.md). Each
+message batch that passes through the processor consumes a single access of the
+rate limit, and when the limit is reached the pipeline is blocked until access
+is permitted again.
+
+For example, to cap the throughput of a pipeline to 100 batches per second:
+
+` + "``` yaml" + `
+pipeline:
+  processors:
+  - type: rate_limit
+    rate_limit:
+      resource: foo_limit
+resources:
+  rate_limits:
+    foo_limit:
+      type: local
+      local:
+        count: 100
+        interval: 1s
+` + "```" + `
+
+Since rate limits are resources they can be shared with other components, such
+as the ` + "`http`" + ` processor, in order to cap their combined rate. If the
+pipeline is closed whilst blocked then the message batch is rejected.
+
+The metric ` + "`processor.rate_limit.total_ms`" + ` counts the total time in
+milliseconds spent throttled.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// RateLimitConfig contains configuration fields for the RateLimit processor.
+type RateLimitConfig struct {
+	Resource string `json:"resource" yaml:"resource"`
+}
+
+// NewRateLimitConfig returns a RateLimitConfig with default values.
+func NewRateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		Resource: "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// RateLimit is a processor that blocks message batches until access to a rate
+// limit resource is permitted.
+type RateLimit struct {
+	closed int32
+
+	rl types.RateLimit
+
+	log   log.Modular
+	stats metrics.Type
+
+	closeChan chan struct{}
+	clock     clock.Clock
+
+	mCount     metrics.StatCounter
+	mLimited   metrics.StatCounter
+	mLimitFor  metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mSentParts metrics.StatCounter
+}
+
+// NewRateLimit returns a RateLimit processor.
+func NewRateLimit(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	rl, err := mgr.GetRateLimit(conf.RateLimit.Resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain rate limit resource '%v': %v", conf.RateLimit.Resource, err)
+	}
+	return &RateLimit{
+		rl: rl,
+
+		log:   log.NewModule(".processor.rate_limit"),
+		stats: stats,
+
+		closeChan: make(chan struct{}),
+		clock:     clock.Real(),
+
+		mCount:     stats.GetCounter("processor.rate_limit.count"),
+		mLimited:   stats.GetCounter("processor.rate_limit.limited"),
+		mLimitFor:  stats.GetCounter("processor.rate_limit.total_ms"),
+		mErr:       stats.GetCounter("processor.rate_limit.error"),
+		mSent:      stats.GetCounter("processor.rate_limit.sent"),
+		mSentParts: stats.GetCounter("processor.rate_limit.parts.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// waitForAccess blocks until the rate limit permits access, returns false if
+// the processor was closed during the wait.
+func (r *RateLimit) waitForAccess() bool {
+	for {
+		period, err := r.rl.Access()
+		if err != nil {
+			r.log.Errorf("Rate limit error: %v\n", err)
+			r.mErr.Incr(1)
+			period = time.Second
+		}
+		if period <= 0 {
+			return true
+		}
+		if err == nil {
+			r.mLimited.Incr(1)
+			r.mLimitFor.Incr(period.Nanoseconds() / 1000000)
+		}
+		select {
+		case <-r.clock.After(period):
+		case <-r.closeChan:
+			return false
+		}
+	}
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (r *RateLimit) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	r.mCount.Incr(1)
+
+	if !r.waitForAccess() {
+		return nil, response.NewError(types.ErrTypeClosed)
+	}
+
+	r.mSent.Incr(1)
+	r.mSentParts.Incr(int64(msg.Len()))
+	msgs := [1]types.Message{msg}
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and interrupts any ongoing wait for
+// access to the rate limit.
+func (r *RateLimit) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&r.closed, 0, 1) {
+		close(r.closeChan)
+	}
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (r *RateLimit) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
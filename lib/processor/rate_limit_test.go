@@ -0,0 +1,207 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/ratelimit"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/clock"
+)
+
+//------------------------------------------------------------------------------
+
+type fakeRateLimit struct {
+	period time.Duration
+	err    error
+	calls  int32
+}
+
+func (f *fakeRateLimit) Access() (time.Duration, error) {
+	if atomic.AddInt32(&f.calls, 1) > 1 {
+		return 0, nil
+	}
+	return f.period, f.err
+}
+
+//------------------------------------------------------------------------------
+
+func TestRateLimitBasic(t *testing.T) {
+	rlConf := ratelimit.NewConfig()
+	rlConf.Local.Count = 2
+	rlConf.Local.Interval = "100ms"
+
+	rl, err := ratelimit.New(rlConf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := &fakeMgr{
+		ratelimits: map[string]types.RateLimit{
+			"foo": rl,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Type = TypeRateLimit
+	conf.RateLimit.Resource = "foo"
+
+	proc, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tBefore := time.Now()
+	for i := 0; i < 3; i++ {
+		msgIn := message.New([][]byte{[]byte("foo")})
+		msgsOut, res := proc.ProcessMessage(msgIn)
+		if res != nil {
+			t.Fatal(res.Error())
+		}
+		if exp, act := msgIn, msgsOut[0]; exp != act {
+			t.Errorf("Wrong message returned: %v != %v", act, exp)
+		}
+	}
+	if dur := time.Since(tBefore); dur < (time.Millisecond * 50) {
+		t.Errorf("Messages weren't throttled: %v", dur)
+	}
+}
+
+func TestRateLimitFakeClock(t *testing.T) {
+	mgr := &fakeMgr{
+		ratelimits: map[string]types.RateLimit{
+			"foo": &fakeRateLimit{period: time.Minute},
+		},
+	}
+
+	conf := NewConfig()
+	conf.Type = TypeRateLimit
+	conf.RateLimit.Resource = "foo"
+
+	proc, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeClock := clock.NewFake(time.Unix(0, 0))
+	proc.(*RateLimit).clock = fakeClock
+
+	resChan := make(chan types.Response)
+	go func() {
+		_, res := proc.ProcessMessage(message.New([][]byte{[]byte("foo")}))
+		resChan <- res
+	}()
+
+	fakeClock.BlockUntil(1)
+	fakeClock.Add(time.Second * 59)
+	select {
+	case <-resChan:
+		t.Fatal("Message was not limited")
+	default:
+	}
+
+	fakeClock.Add(time.Second)
+	select {
+	case res := <-resChan:
+		if res != nil {
+			t.Error(res.Error())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for rate limit")
+	}
+}
+
+func TestRateLimitError(t *testing.T) {
+	mgr := &fakeMgr{
+		ratelimits: map[string]types.RateLimit{
+			"foo": &fakeRateLimit{err: errors.New("nope")},
+		},
+	}
+
+	conf := NewConfig()
+	conf.Type = TypeRateLimit
+	conf.RateLimit.Resource = "foo"
+
+	proc, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		<-time.After(time.Millisecond * 100)
+		proc.(types.Closable).CloseAsync()
+	}()
+
+	if _, res := proc.ProcessMessage(message.New([][]byte{[]byte("foo")})); res == nil || res.Error() != types.ErrTypeClosed {
+		t.Errorf("Expected closed error: %v", res)
+	}
+}
+
+func TestRateLimitClose(t *testing.T) {
+	mgr := &fakeMgr{
+		ratelimits: map[string]types.RateLimit{
+			"foo": &fakeRateLimit{period: time.Hour},
+		},
+	}
+
+	conf := NewConfig()
+	conf.Type = TypeRateLimit
+	conf.RateLimit.Resource = "foo"
+
+	proc, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		<-time.After(time.Millisecond * 100)
+		proc.(types.Closable).CloseAsync()
+	}()
+
+	tBefore := time.Now()
+	if _, res := proc.ProcessMessage(message.New([][]byte{[]byte("foo")})); res == nil || res.Error() != types.ErrTypeClosed {
+		t.Errorf("Expected closed error: %v", res)
+	}
+	if dur := time.Since(tBefore); dur > time.Second {
+		t.Errorf("Took too long to close: %v", dur)
+	}
+	if err = proc.(types.Closable).WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRateLimitMissingResource(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeRateLimit
+	conf.RateLimit.Resource = "foo"
+
+	if _, err := New(conf, &fakeMgr{}, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing resource")
+	}
+}
+
+//------------------------------------------------------------------------------
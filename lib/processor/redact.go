@@ -0,0 +1,389 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/Jeffail/gabs"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeRedact] = TypeSpec{
+		constructor: NewRedact,
+		description: `
+Redacts sensitive data from message parts according to a list of rules, which
+are applied in order. Each rule targets either a field of a JSON document with
+a ` + "`path`" + `, or sections of the raw contents of a part that match a
+` + "`regexp`" + `, and performs one of the following actions on them:
+
+- ` + "`delete`" + `: Removes the field, or the matched contents.
+- ` + "`replace`" + `: Replaces the value with ` + "`value`" + `, which supports
+  [interpolation functions](../config_interpolation.md#functions).
+- ` + "`hash`" + `: Replaces the value with the hex encoded SHA-256 hash of the
+  ` + "`salt`" + ` followed by the value.
+- ` + "`partial_mask`" + `: Replaces all but the first ` + "`keep_first`" + `
+  and last ` + "`keep_last`" + ` characters of the value with
+  ` + "`mask_char`" + `. Values too short to keep any characters hidden are
+  masked entirely.
+
+JSON fields that are not strings are converted into a string before they are
+hashed or masked. For example, the following config masks card numbers within a
+JSON field and hashes email addresses found anywhere within a part:
+
+` + "``` yaml" + `
+redact:
+  rules:
+  - path: payment.card_number
+    action: partial_mask
+    keep_last: 4
+  - regexp: '[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}'
+    action: hash
+    salt: ${SALT}
+` + "```" + `
+
+The number of values redacted by each rule is counted by the metric
+` + "`processor.redact.rule.<index>.hit`" + `, where the index is the position
+of the rule in the list.
+
+When ` + "`dry_run`" + ` is ` + "`true`" + ` the number of values each rule
+would redact is logged and counted, but message parts are not modified. The
+values themselves are never logged.
+
+Rules with a ` + "`path`" + ` are skipped for message parts that cannot be
+parsed as JSON, which are flagged as failed. Rules with a ` + "`regexp`" + ` are
+still applied to these parts.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// RedactRuleConfig contains configuration fields for a single rule of the
+// Redact processor.
+type RedactRuleConfig struct {
+	Path      string `json:"path" yaml:"path"`
+	Regexp    string `json:"regexp" yaml:"regexp"`
+	Action    string `json:"action" yaml:"action"`
+	Value     string `json:"value" yaml:"value"`
+	Salt      string `json:"salt" yaml:"salt"`
+	KeepFirst int    `json:"keep_first" yaml:"keep_first"`
+	KeepLast  int    `json:"keep_last" yaml:"keep_last"`
+	MaskChar  string `json:"mask_char" yaml:"mask_char"`
+}
+
+// NewRedactRuleConfig returns a RedactRuleConfig with default values.
+func NewRedactRuleConfig() RedactRuleConfig {
+	return RedactRuleConfig{
+		Path:      "",
+		Regexp:    "",
+		Action:    "replace",
+		Value:     "REDACTED",
+		Salt:      "",
+		KeepFirst: 0,
+		KeepLast:  0,
+		MaskChar:  "*",
+	}
+}
+
+// UnmarshalJSON ensures that when parsing configs that are in a map or slice
+// the default values are still applied.
+func (r *RedactRuleConfig) UnmarshalJSON(bytes []byte) error {
+	type confAlias RedactRuleConfig
+	aliased := confAlias(NewRedactRuleConfig())
+
+	if err := json.Unmarshal(bytes, &aliased); err != nil {
+		return err
+	}
+
+	*r = RedactRuleConfig(aliased)
+	return nil
+}
+
+// UnmarshalYAML ensures that when parsing configs that are in a map or slice
+// the default values are still applied.
+func (r *RedactRuleConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type confAlias RedactRuleConfig
+	aliased := confAlias(NewRedactRuleConfig())
+
+	if err := unmarshal(&aliased); err != nil {
+		return err
+	}
+
+	*r = RedactRuleConfig(aliased)
+	return nil
+}
+
+// RedactConfig contains configuration fields for the Redact processor.
+type RedactConfig struct {
+	Parts  []int              `json:"parts" yaml:"parts"`
+	Rules  []RedactRuleConfig `json:"rules" yaml:"rules"`
+	DryRun bool               `json:"dry_run" yaml:"dry_run"`
+}
+
+// NewRedactConfig returns a RedactConfig with default values.
+func NewRedactConfig() RedactConfig {
+	return RedactConfig{
+		Parts:  []int{},
+		Rules:  []RedactRuleConfig{},
+		DryRun: false,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// redactAction returns the redacted form of a value, where the bool return
+// value is false if the value should be deleted.
+type redactAction func(value string, lockedMsg text.Message) (string, bool)
+
+func newRedactAction(conf RedactRuleConfig) (redactAction, error) {
+	switch conf.Action {
+	case "delete":
+		return func(string, text.Message) (string, bool) {
+			return "", false
+		}, nil
+	case "replace":
+		value := text.NewInterpolatedString(conf.Value)
+		return func(_ string, lockedMsg text.Message) (string, bool) {
+			return value.Get(lockedMsg), true
+		}, nil
+	case "hash":
+		salt := conf.Salt
+		return func(v string, _ text.Message) (string, bool) {
+			sum := sha256.Sum256([]byte(salt + v))
+			return hex.EncodeToString(sum[:]), true
+		}, nil
+	case "partial_mask":
+		if conf.KeepFirst < 0 || conf.KeepLast < 0 {
+			return nil, errors.New("keep_first and keep_last must not be negative")
+		}
+		if utf8.RuneCountInString(conf.MaskChar) != 1 {
+			return nil, errors.New("mask_char must be a single character")
+		}
+		keepFirst, keepLast, maskChar := conf.KeepFirst, conf.KeepLast, conf.MaskChar
+		return func(v string, _ text.Message) (string, bool) {
+			runes := []rune(v)
+			if len(runes) <= keepFirst+keepLast {
+				return strings.Repeat(maskChar, len(runes)), true
+			}
+			return string(runes[:keepFirst]) +
+				strings.Repeat(maskChar, len(runes)-keepFirst-keepLast) +
+				string(runes[len(runes)-keepLast:]), true
+		}, nil
+	}
+	return nil, fmt.Errorf("action not recognised: %v", conf.Action)
+}
+
+type redactRule struct {
+	path   []string
+	re     *regexp.Regexp
+	action redactAction
+
+	mHit metrics.StatCounter
+}
+
+// redactValueString converts a JSON value into a string to be redacted.
+func redactValueString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case json.Number:
+		return t.String()
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+//------------------------------------------------------------------------------
+
+// Redact is a processor that redacts sensitive data from message parts.
+type Redact struct {
+	parts  []int
+	rules  []redactRule
+	dryRun bool
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErrJSONP  metrics.StatCounter
+	mErrJSONS  metrics.StatCounter
+	mSent      metrics.StatCounter
+	mSentParts metrics.StatCounter
+}
+
+// NewRedact returns a Redact processor.
+func NewRedact(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	var rules []redactRule
+	for i, ruleConf := range conf.Redact.Rules {
+		var rule redactRule
+		var err error
+		if len(ruleConf.Path) > 0 && len(ruleConf.Regexp) > 0 {
+			return nil, fmt.Errorf("rule %v: only one of path or regexp can be specified", i)
+		}
+		if len(ruleConf.Path) > 0 {
+			rule.path = splitJSONPath(ruleConf.Path)
+		} else if len(ruleConf.Regexp) > 0 {
+			if rule.re, err = regexp.Compile(ruleConf.Regexp); err != nil {
+				return nil, fmt.Errorf("rule %v: failed to compile regexp: %v", i, err)
+			}
+		} else {
+			return nil, fmt.Errorf("rule %v: a path or regexp must be specified", i)
+		}
+		if rule.action, err = newRedactAction(ruleConf); err != nil {
+			return nil, fmt.Errorf("rule %v: %v", i, err)
+		}
+		rule.mHit = stats.GetCounter(fmt.Sprintf("processor.redact.rule.%v.hit", i))
+		rules = append(rules, rule)
+	}
+
+	return &Redact{
+		parts:  conf.Redact.Parts,
+		rules:  rules,
+		dryRun: conf.Redact.DryRun,
+
+		log:   log.NewModule(".processor.redact"),
+		stats: stats,
+
+		mCount:     stats.GetCounter("processor.redact.count"),
+		mErrJSONP:  stats.GetCounter("processor.redact.error.json_parse"),
+		mErrJSONS:  stats.GetCounter("processor.redact.error.json_set"),
+		mSent:      stats.GetCounter("processor.redact.sent"),
+		mSentParts: stats.GetCounter("processor.redact.parts.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (r *Redact) applyPathRule(i int, rule redactRule, part types.Part, lockedMsg text.Message) error {
+	jsonPart, err := part.JSON()
+	var gPart *gabs.Container
+	if err == nil {
+		gPart, err = gabs.Consume(jsonPart)
+	}
+	if err != nil {
+		r.mErrJSONP.Incr(1)
+		return fmt.Errorf("failed to parse part into json: %v", err)
+	}
+
+	if !gPart.Exists(rule.path...) {
+		return nil
+	}
+	rule.mHit.Incr(1)
+	if r.dryRun {
+		r.log.Infof("Rule %v would redact field '%v'\n", i, strings.Join(rule.path, "."))
+		return nil
+	}
+
+	if newValue, keep := rule.action(redactValueString(gPart.S(rule.path...).Data()), lockedMsg); keep {
+		gPart.Set(newValue, rule.path...)
+	} else if len(rule.path) == 0 {
+		gPart = gabs.New()
+	} else {
+		gPart.Delete(rule.path...)
+	}
+
+	if err = part.SetJSON(gPart.Data()); err != nil {
+		r.mErrJSONS.Incr(1)
+		return fmt.Errorf("failed to convert json into part: %v", err)
+	}
+	return nil
+}
+
+func (r *Redact) applyRegexpRule(i int, rule redactRule, part types.Part, lockedMsg text.Message) {
+	matches := len(rule.re.FindAllIndex(part.Get(), -1))
+	if matches == 0 {
+		return
+	}
+	rule.mHit.Incr(int64(matches))
+	if r.dryRun {
+		r.log.Infof("Rule %v would redact %v matches of regexp\n", i, matches)
+		return
+	}
+
+	part.Set(rule.re.ReplaceAllFunc(part.Get(), func(match []byte) []byte {
+		newValue, _ := rule.action(string(match), lockedMsg)
+		return []byte(newValue)
+	}))
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (r *Redact) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	r.mCount.Incr(1)
+
+	newMsg := msg.Copy()
+
+	targetParts := r.parts
+	if len(targetParts) == 0 {
+		targetParts = make([]int, newMsg.Len())
+		for i := range targetParts {
+			targetParts[i] = i
+		}
+	}
+
+	for _, index := range targetParts {
+		if index < 0 {
+			index = newMsg.Len() + index
+		}
+		if index < 0 || index >= newMsg.Len() {
+			continue
+		}
+
+		part := newMsg.Get(index)
+		lockedMsg := message.Lock(newMsg, index)
+		for i, rule := range r.rules {
+			if rule.re != nil {
+				r.applyRegexpRule(i, rule, part, lockedMsg)
+				continue
+			}
+			if err := r.applyPathRule(i, rule, part, lockedMsg); err != nil {
+				r.log.Debugf("Rule %v: %v\n", i, err)
+				FlagErr(part, err)
+			}
+		}
+	}
+
+	r.mSent.Incr(1)
+	r.mSentParts.Incr(int64(newMsg.Len()))
+	msgs := [1]types.Message{newMsg}
+	return msgs[:], nil
+}
+
+//------------------------------------------------------------------------------
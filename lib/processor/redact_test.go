@@ -0,0 +1,309 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	yaml "gopkg.in/yaml.v2"
+)
+
+//------------------------------------------------------------------------------
+
+func TestRedactActions(t *testing.T) {
+	saltedHash := sha256.Sum256([]byte("saltfoo@example.com"))
+
+	type testCase struct {
+		name   string
+		rule   RedactRuleConfig
+		input  string
+		output string
+	}
+
+	newRule := func(f func(r *RedactRuleConfig)) RedactRuleConfig {
+		r := NewRedactRuleConfig()
+		f(&r)
+		return r
+	}
+
+	tests := []testCase{
+		{
+			name: "path delete",
+			rule: newRule(func(r *RedactRuleConfig) {
+				r.Path = "user.email"
+				r.Action = "delete"
+			}),
+			input:  `{"user":{"email":"foo@example.com","name":"foo"}}`,
+			output: `{"user":{"name":"foo"}}`,
+		},
+		{
+			name: "path replace default",
+			rule: newRule(func(r *RedactRuleConfig) {
+				r.Path = "user.email"
+			}),
+			input:  `{"user":{"email":"foo@example.com"}}`,
+			output: `{"user":{"email":"REDACTED"}}`,
+		},
+		{
+			name: "path replace interpolated",
+			rule: newRule(func(r *RedactRuleConfig) {
+				r.Path = "user.email"
+				r.Value = "${!json_field:user.name}@redacted"
+			}),
+			input:  `{"user":{"email":"foo@example.com","name":"foo"}}`,
+			output: `{"user":{"email":"foo@redacted","name":"foo"}}`,
+		},
+		{
+			name: "path hash salted",
+			rule: newRule(func(r *RedactRuleConfig) {
+				r.Path = "email"
+				r.Action = "hash"
+				r.Salt = "salt"
+			}),
+			input:  `{"email":"foo@example.com"}`,
+			output: `{"email":"` + hex.EncodeToString(saltedHash[:]) + `"}`,
+		},
+		{
+			name: "path partial mask number",
+			rule: newRule(func(r *RedactRuleConfig) {
+				r.Path = "card"
+				r.Action = "partial_mask"
+				r.KeepLast = 4
+			}),
+			input:  `{"card":4111111111111111}`,
+			output: `{"card":"************1111"}`,
+		},
+		{
+			name: "path partial mask too short",
+			rule: newRule(func(r *RedactRuleConfig) {
+				r.Path = "pin"
+				r.Action = "partial_mask"
+				r.KeepFirst = 2
+				r.KeepLast = 2
+				r.MaskChar = "#"
+			}),
+			input:  `{"pin":"1234"}`,
+			output: `{"pin":"####"}`,
+		},
+		{
+			name: "path missing",
+			rule: newRule(func(r *RedactRuleConfig) {
+				r.Path = "user.email"
+				r.Action = "delete"
+			}),
+			input:  `{"user":{"name":"foo"}}`,
+			output: `{"user":{"name":"foo"}}`,
+		},
+		{
+			name: "regexp delete",
+			rule: newRule(func(r *RedactRuleConfig) {
+				r.Regexp = `\s*secret=\w+`
+				r.Action = "delete"
+			}),
+			input:  `user=foo secret=bar ok=true`,
+			output: `user=foo ok=true`,
+		},
+		{
+			name: "regexp partial mask",
+			rule: newRule(func(r *RedactRuleConfig) {
+				r.Regexp = `\d{4}-\d{4}-\d{4}-\d{4}`
+				r.Action = "partial_mask"
+				r.KeepFirst = 4
+				r.KeepLast = 4
+			}),
+			input:  `cards: 4111-1111-1111-1111, 5500-0000-0000-0004`,
+			output: `cards: 4111***********1111, 5500***********0004`,
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.Redact.Rules = []RedactRuleConfig{test.rule}
+
+		proc, err := NewRedact(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+
+		msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(test.input)}))
+		if res != nil {
+			t.Fatalf("%v: %v", test.name, res.Error())
+		}
+		if HasFailed(msgs[0].Get(0)) {
+			t.Errorf("%v: unexpected failure", test.name)
+		}
+		if act := string(msgs[0].Get(0).Get()); act != test.output {
+			t.Errorf("%v: wrong result: %v != %v", test.name, act, test.output)
+		}
+	}
+}
+
+func TestRedactRulesInOrder(t *testing.T) {
+	conf := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+type: redact
+redact:
+  rules:
+  - regexp: '[a-z]+@example\.com'
+    action: hash
+  - path: email
+    action: partial_mask
+    keep_first: 3
+  - path: phone
+`), &conf); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "*", conf.Redact.Rules[1].MaskChar; exp != act {
+		t.Errorf("Wrong default mask_char: %v != %v", act, exp)
+	}
+
+	stats := metrics.NewLocal()
+	proc, err := NewRedact(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"email":"foo@example.com","phone":"555 1234"}`),
+		[]byte(`{"email":"bar@example.com","note":"cc baz@example.com"}`),
+		[]byte(`not json but foo@example.com`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	hashed := func(v string) string {
+		sum := sha256.Sum256([]byte(v))
+		return hex.EncodeToString(sum[:])
+	}
+	exp := [][]byte{
+		[]byte(`{"email":"` + hashed("foo@example.com")[:3] + strings.Repeat("*", 61) + `","phone":"REDACTED"}`),
+		[]byte(`{"email":"` + hashed("bar@example.com")[:3] + strings.Repeat("*", 61) + `","note":"cc ` + hashed("baz@example.com") + `"}`),
+		[]byte(`not json but ` + hashed("foo@example.com")),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if HasFailed(msgs[0].Get(0)) || HasFailed(msgs[0].Get(1)) {
+		t.Error("Expected JSON parts not to be flagged")
+	}
+	if !HasFailed(msgs[0].Get(2)) {
+		t.Error("Expected non JSON part to be flagged")
+	}
+
+	counters := stats.GetCounters()
+	for k, exp := range map[string]int64{
+		"processor.redact.rule.0.hit":       4,
+		"processor.redact.rule.1.hit":       2,
+		"processor.redact.rule.2.hit":       1,
+		"processor.redact.error.json_parse": 2,
+	} {
+		if act := counters[k]; exp != act {
+			t.Errorf("Wrong count for %v: %v != %v", k, act, exp)
+		}
+	}
+}
+
+func TestRedactDryRun(t *testing.T) {
+	conf := NewConfig()
+	conf.Redact.DryRun = true
+
+	rule := NewRedactRuleConfig()
+	rule.Path = "email"
+	rule.Action = "delete"
+	conf.Redact.Rules = append(conf.Redact.Rules, rule)
+
+	rule = NewRedactRuleConfig()
+	rule.Regexp = `\d+`
+	conf.Redact.Rules = append(conf.Redact.Rules, rule)
+
+	stats := metrics.NewLocal()
+	proc, err := NewRedact(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := [][]byte{
+		[]byte(`{"email":"foo@example.com","id":123,"age":45}`),
+	}
+	msgs, res := proc.ProcessMessage(message.New(input))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(input, act) {
+		t.Errorf("Dry run modified message: %s != %s", act, input)
+	}
+
+	counters := stats.GetCounters()
+	if exp, act := int64(1), counters["processor.redact.rule.0.hit"]; exp != act {
+		t.Errorf("Wrong count of rule 0 hits: %v != %v", act, exp)
+	}
+	if exp, act := int64(2), counters["processor.redact.rule.1.hit"]; exp != act {
+		t.Errorf("Wrong count of rule 1 hits: %v != %v", act, exp)
+	}
+}
+
+func TestRedactBadConfig(t *testing.T) {
+	tests := map[string]func(r *RedactRuleConfig){
+		"no target": func(r *RedactRuleConfig) {},
+		"both targets": func(r *RedactRuleConfig) {
+			r.Path = "foo"
+			r.Regexp = "foo"
+		},
+		"bad regexp": func(r *RedactRuleConfig) {
+			r.Regexp = "(foo"
+		},
+		"bad action": func(r *RedactRuleConfig) {
+			r.Path = "foo"
+			r.Action = "shred"
+		},
+		"bad mask char": func(r *RedactRuleConfig) {
+			r.Path = "foo"
+			r.Action = "partial_mask"
+			r.MaskChar = "**"
+		},
+		"negative keep": func(r *RedactRuleConfig) {
+			r.Path = "foo"
+			r.Action = "partial_mask"
+			r.KeepLast = -1
+		},
+	}
+
+	for name, f := range tests {
+		rule := NewRedactRuleConfig()
+		f(&rule)
+
+		conf := NewConfig()
+		conf.Redact.Rules = []RedactRuleConfig{rule}
+		if _, err := NewRedact(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+			t.Errorf("%v: expected error", name)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
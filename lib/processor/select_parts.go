@@ -35,7 +35,9 @@ func init() {
 		constructor: NewSelectParts,
 		description: `
 Cherry pick a set of parts from messages by their index. Indexes larger than the
-number of parts are simply ignored.
+number of parts are simply ignored, and are counted by the metric
+` + "`processor.select_parts.skipped`" + `. The metadata of selected parts is
+preserved.
 
 The selected parts are added to the new message in the same order as the
 selection array. E.g. with 'parts' set to [ 2, 0, 1 ] and the message parts
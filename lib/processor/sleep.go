@@ -0,0 +1,173 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSleep] = TypeSpec{
+		constructor: NewSleep,
+		description: `
+Sleep for a period of time specified as a duration string. This field supports
+[function interpolation](../config_interpolation.md#functions), which allows you
+to derive the period from the contents or metadata of a message. For example,
+to honour a retry period captured in the metadata field ` + "`retry_after`" + `
+you could use the following config:
+
+` + "``` yaml" + `
+type: sleep
+sleep:
+  duration: ${!metadata:retry_after}ms
+` + "```" + `
+
+By default the duration is resolved once per message batch, using the first
+part for interpolations. If ` + "`per_part`" + ` is set to ` + "`true`" + ` the
+duration is instead resolved and slept for each message part in turn.
+
+If the duration cannot be parsed the message is passed on immediately and an
+error is logged. Any sleep is interrupted when the pipeline is closed.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SleepConfig contains configuration fields for the Sleep processor.
+type SleepConfig struct {
+	Duration string `json:"duration" yaml:"duration"`
+	PerPart  bool   `json:"per_part" yaml:"per_part"`
+}
+
+// NewSleepConfig returns a SleepConfig with default values.
+func NewSleepConfig() SleepConfig {
+	return SleepConfig{
+		Duration: "100us",
+		PerPart:  false,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Sleep is a processor that pauses the pipeline for a period of time on each
+// message batch or part.
+type Sleep struct {
+	closed int32
+
+	conf  Config
+	log   log.Modular
+	stats metrics.Type
+
+	duration *text.InterpolatedString
+
+	closeChan chan struct{}
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mSentParts metrics.StatCounter
+}
+
+// NewSleep returns a Sleep processor.
+func NewSleep(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	return &Sleep{
+		conf:  conf,
+		log:   log.NewModule(".processor.sleep"),
+		stats: stats,
+
+		duration:  text.NewInterpolatedString(conf.Sleep.Duration),
+		closeChan: make(chan struct{}),
+
+		mCount:     stats.GetCounter("processor.sleep.count"),
+		mErr:       stats.GetCounter("processor.sleep.error"),
+		mSent:      stats.GetCounter("processor.sleep.sent"),
+		mSentParts: stats.GetCounter("processor.sleep.parts.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// sleepFor blocks for the duration resolved from a message, returns false if
+// the processor was closed during the sleep.
+func (s *Sleep) sleepFor(msg types.Message) bool {
+	durStr := s.duration.Get(msg)
+	period, err := time.ParseDuration(durStr)
+	if err != nil {
+		s.mErr.Incr(1)
+		s.log.Errorf("Failed to parse duration '%v': %v\n", durStr, err)
+		return true
+	}
+	if period <= 0 {
+		return true
+	}
+	select {
+	case <-time.After(period):
+	case <-s.closeChan:
+		return false
+	}
+	return true
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (s *Sleep) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	s.mCount.Incr(1)
+
+	if s.conf.Sleep.PerPart {
+		for i := 0; i < msg.Len(); i++ {
+			if !s.sleepFor(message.Lock(msg, i)) {
+				break
+			}
+		}
+	} else {
+		s.sleepFor(msg)
+	}
+
+	s.mSent.Incr(1)
+	s.mSentParts.Incr(int64(msg.Len()))
+	msgs := [1]types.Message{msg}
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and interrupts any ongoing sleep.
+func (s *Sleep) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&s.closed, 0, 1) {
+		close(s.closeChan)
+	}
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (s *Sleep) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
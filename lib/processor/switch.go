@@ -0,0 +1,291 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/processor/condition"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSwitch] = TypeSpec{
+		constructor: NewSwitch,
+		description: `
+Switch is a processor that lists child cases, where each case consists of a
+condition, a list of processors and a boolean field ` + "`fallthrough`" + `.
+Cases are evaluated in order against each message, and the processors of the
+first case with a passing condition are applied. Conditions are checked against
+the message batch as a whole, and therefore conditions that target a single
+message part will check the first part unless configured otherwise.
+
+If a case has ` + "`fallthrough`" + ` set to ` + "`true`" + ` then after its
+processors are applied the resulting messages continue to be evaluated against
+the cases that follow it. If no condition is defined for a case then it behaves
+like a static ` + "`true`" + ` condition, and can therefore be used as a default
+case at the end of the list. Messages that do not match any case continue
+unchanged.
+
+In the following example messages containing "foo" have the first processor
+applied, messages containing "bar" have the second processor applied, and all
+other messages have the third processor applied:
+
+` + "``` yaml" + `
+type: switch
+switch:
+- condition:
+    type: text
+    text:
+      operator: contains
+      arg: foo
+  processors:
+  - type: foo_processor
+- condition:
+    type: text
+    text:
+      operator: contains
+      arg: bar
+  processors:
+  - type: bar_processor
+- processors:
+  - type: baz_processor
+` + "```" + `
+
+The number of messages that match each case is exported as the counter
+` + "`processor.switch.case.<index>.hit`" + `, where the index is the position
+of the case starting from zero.`,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			cases := []interface{}{}
+			for _, c := range conf.Switch {
+				sanCond, err := condition.SanitiseConfig(c.Condition)
+				if err != nil {
+					return nil, err
+				}
+				procConfs := make([]interface{}, len(c.Processors))
+				for i, pConf := range c.Processors {
+					if procConfs[i], err = SanitiseConfig(pConf); err != nil {
+						return nil, err
+					}
+				}
+				cases = append(cases, map[string]interface{}{
+					"condition":   sanCond,
+					"processors":  procConfs,
+					"fallthrough": c.Fallthrough,
+				})
+			}
+			return cases, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SwitchCaseConfig contains a condition, processors and other fields for an
+// individual case in the Switch processor.
+type SwitchCaseConfig struct {
+	Condition   condition.Config `json:"condition" yaml:"condition"`
+	Processors  []Config         `json:"processors" yaml:"processors"`
+	Fallthrough bool             `json:"fallthrough" yaml:"fallthrough"`
+}
+
+// NewSwitchCaseConfig returns a new SwitchCaseConfig with default values.
+func NewSwitchCaseConfig() SwitchCaseConfig {
+	cond := condition.NewConfig()
+	cond.Type = condition.TypeStatic
+	cond.Static = true
+
+	return SwitchCaseConfig{
+		Condition:   cond,
+		Processors:  []Config{},
+		Fallthrough: false,
+	}
+}
+
+// UnmarshalJSON ensures that when parsing configs that are in a map or slice
+// the default values are still applied.
+func (s *SwitchCaseConfig) UnmarshalJSON(bytes []byte) error {
+	type confAlias SwitchCaseConfig
+	aliased := confAlias(NewSwitchCaseConfig())
+
+	if err := json.Unmarshal(bytes, &aliased); err != nil {
+		return err
+	}
+
+	*s = SwitchCaseConfig(aliased)
+	return nil
+}
+
+// UnmarshalYAML ensures that when parsing configs that are in a map or slice
+// the default values are still applied.
+func (s *SwitchCaseConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type confAlias SwitchCaseConfig
+	aliased := confAlias(NewSwitchCaseConfig())
+
+	if err := unmarshal(&aliased); err != nil {
+		return err
+	}
+
+	*s = SwitchCaseConfig(aliased)
+	return nil
+}
+
+// SwitchConfig is a config struct containing fields for the Switch processor.
+type SwitchConfig []SwitchCaseConfig
+
+// NewSwitchConfig returns a default SwitchConfig.
+func NewSwitchConfig() SwitchConfig {
+	return []SwitchCaseConfig{}
+}
+
+//------------------------------------------------------------------------------
+
+// switchCase contains a condition, processors and other fields for an
+// individual case in the Switch processor.
+type switchCase struct {
+	cond        condition.Type
+	procs       []Type
+	fallThrough bool
+
+	mHit metrics.StatCounter
+}
+
+// Switch is a processor that applies the child processors of the first case
+// with a passing condition.
+type Switch struct {
+	cases []switchCase
+
+	log log.Modular
+
+	mCount     metrics.StatCounter
+	mSent      metrics.StatCounter
+	mSentParts metrics.StatCounter
+	mDropped   metrics.StatCounter
+}
+
+// NewSwitch returns a Switch processor.
+func NewSwitch(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	var cases []switchCase
+	for i, caseConf := range conf.Switch {
+		prefix := fmt.Sprintf("processor.switch.case.%v", i)
+		nsStats := metrics.Namespaced(stats, prefix)
+		nsLog := log.NewModule(fmt.Sprintf(".processor.switch.case.%v", i))
+
+		cond, err := condition.New(caseConf.Condition, mgr, nsLog, nsStats)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create case %v condition: %v", i, err)
+		}
+
+		var procs []Type
+		for j, procConf := range caseConf.Processors {
+			var proc Type
+			if proc, err = New(procConf, mgr, nsLog, nsStats); err != nil {
+				return nil, fmt.Errorf("failed to create case %v processor %v: %v", i, j, err)
+			}
+			procs = append(procs, proc)
+		}
+
+		cases = append(cases, switchCase{
+			cond:        cond,
+			procs:       procs,
+			fallThrough: caseConf.Fallthrough,
+			mHit:        stats.GetCounter(prefix + ".hit"),
+		})
+	}
+
+	return &Switch{
+		cases: cases,
+		log:   log.NewModule(".processor.switch"),
+
+		mCount:     stats.GetCounter("processor.switch.count"),
+		mSent:      stats.GetCounter("processor.switch.sent"),
+		mSentParts: stats.GetCounter("processor.switch.parts.sent"),
+		mDropped:   stats.GetCounter("processor.switch.dropped"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (s *Switch) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	s.mCount.Incr(1)
+
+	var resultMsgs []types.Message
+	var resultRes types.Response
+
+	pending := []types.Message{msg}
+	for i := 0; len(pending) > 0 && i < len(s.cases); i++ {
+		var nextPending []types.Message
+		for _, m := range pending {
+			if !s.cases[i].cond.Check(m) {
+				nextPending = append(nextPending, m)
+				continue
+			}
+			s.cases[i].mHit.Incr(1)
+			s.log.Tracef("Case %v matched\n", i)
+
+			caseMsgs := []types.Message{m}
+			for j := 0; len(caseMsgs) > 0 && j < len(s.cases[i].procs); j++ {
+				var nextCaseMsgs []types.Message
+				for _, cm := range caseMsgs {
+					var rMsgs []types.Message
+					rMsgs, resultRes = s.cases[i].procs[j].ProcessMessage(cm)
+					nextCaseMsgs = append(nextCaseMsgs, rMsgs...)
+				}
+				caseMsgs = nextCaseMsgs
+			}
+
+			if s.cases[i].fallThrough {
+				nextPending = append(nextPending, caseMsgs...)
+			} else {
+				resultMsgs = append(resultMsgs, caseMsgs...)
+			}
+		}
+		pending = nextPending
+	}
+	resultMsgs = append(resultMsgs, pending...)
+
+	if len(resultMsgs) == 0 {
+		s.mDropped.Incr(1)
+		if resultRes == nil {
+			resultRes = response.NewAck()
+		}
+		return nil, resultRes
+	}
+
+	s.mSent.Incr(int64(len(resultMsgs)))
+	totalParts := 0
+	for _, m := range resultMsgs {
+		totalParts += m.Len()
+	}
+	s.mSentParts.Incr(int64(totalParts))
+	return resultMsgs, nil
+}
+
+//------------------------------------------------------------------------------
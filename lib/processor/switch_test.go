@@ -0,0 +1,206 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package processor
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/processor/condition"
+)
+
+//------------------------------------------------------------------------------
+
+func newSwitchTestCase(contains, insert string, fallThrough bool) SwitchCaseConfig {
+	caseConf := NewSwitchCaseConfig()
+	if len(contains) > 0 {
+		caseConf.Condition.Type = condition.TypeText
+		caseConf.Condition.Text.Operator = "contains"
+		caseConf.Condition.Text.Arg = contains
+	}
+	caseConf.Fallthrough = fallThrough
+
+	procConf := NewConfig()
+	procConf.Type = TypeInsertPart
+	procConf.InsertPart.Content = insert
+	procConf.InsertPart.Index = -1
+	caseConf.Processors = append(caseConf.Processors, procConf)
+	return caseConf
+}
+
+func TestSwitchCases(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeSwitch
+	conf.Switch = append(
+		conf.Switch,
+		newSwitchTestCase("foo", "case 0", false),
+		newSwitchTestCase("bar", "case 1", true),
+		newSwitchTestCase("baz", "case 2", false),
+		newSwitchTestCase("", "default", false),
+	)
+
+	stats := metrics.NewLocal()
+	proc, err := New(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    []string
+		expected []string
+	}{
+		{
+			input:    []string{"foo bar baz"},
+			expected: []string{"foo bar baz", "case 0"},
+		},
+		{
+			input:    []string{"bar"},
+			expected: []string{"bar", "case 1", "default"},
+		},
+		{
+			input:    []string{"bar baz"},
+			expected: []string{"bar baz", "case 1", "case 2"},
+		},
+		{
+			input:    []string{"baz"},
+			expected: []string{"baz", "case 2"},
+		},
+		{
+			input:    []string{"qux", "foo"},
+			expected: []string{"qux", "foo", "default"},
+		},
+	}
+
+	for i, test := range tests {
+		input := [][]byte{}
+		for _, p := range test.input {
+			input = append(input, []byte(p))
+		}
+		msgs, res := proc.ProcessMessage(message.New(input))
+		if res != nil {
+			t.Fatal(res.Error())
+		}
+		if len(msgs) != 1 {
+			t.Fatalf("Test %v: wrong count of messages: %v", i, len(msgs))
+		}
+		act := []string{}
+		for _, p := range message.GetAllBytes(msgs[0]) {
+			act = append(act, string(p))
+		}
+		if !reflect.DeepEqual(test.expected, act) {
+			t.Errorf("Test %v: wrong result: %v != %v", i, act, test.expected)
+		}
+	}
+
+	counters := stats.GetCounters()
+	for k, exp := range map[string]int64{
+		"processor.switch.case.0.hit": 1,
+		"processor.switch.case.1.hit": 2,
+		"processor.switch.case.2.hit": 2,
+		"processor.switch.case.3.hit": 2,
+	} {
+		if act := counters[k]; exp != act {
+			t.Errorf("Wrong count for %v: %v != %v", k, act, exp)
+		}
+	}
+}
+
+func TestSwitchNoMatch(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeSwitch
+	conf.Switch = append(conf.Switch, newSwitchTestCase("foo", "case 0", false))
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]byte{[]byte("bar")}
+	msgs, res := proc.ProcessMessage(message.New(exp))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", len(msgs))
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+}
+
+func TestSwitchFiltered(t *testing.T) {
+	filterConf := NewConfig()
+	filterConf.Type = TypeFilter
+	filterConf.Filter.Type = condition.TypeStatic
+	filterConf.Filter.Static = false
+
+	caseConf := NewSwitchCaseConfig()
+	caseConf.Processors = append(caseConf.Processors, filterConf)
+
+	conf := NewConfig()
+	conf.Type = TypeSwitch
+	conf.Switch = append(conf.Switch, caseConf)
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte("foo")}))
+	if len(msgs) != 0 {
+		t.Errorf("Wrong count of messages: %v", len(msgs))
+	}
+	if res == nil {
+		t.Fatal("Expected a response")
+	}
+	if err = res.Error(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSwitchCaseDefaults(t *testing.T) {
+	conf := NewConfig()
+	if err := json.Unmarshal([]byte(`{
+	"type": "switch",
+	"switch": [
+		{
+			"processors": [{"type": "noop"}]
+		}
+	]
+}`), &conf); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := 1, len(conf.Switch); exp != act {
+		t.Fatalf("Wrong count of cases: %v != %v", act, exp)
+	}
+	if exp, act := condition.TypeStatic, conf.Switch[0].Condition.Type; exp != act {
+		t.Errorf("Wrong default condition type: %v != %v", act, exp)
+	}
+	if !conf.Switch[0].Condition.Static {
+		t.Error("Expected default condition to pass")
+	}
+}
+
+//------------------------------------------------------------------------------
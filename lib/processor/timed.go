@@ -0,0 +1,102 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// Timed is a processor that wraps another processor and records the time taken
+// by each call to it.
+type Timed struct {
+	proc    Type
+	metaKey string
+
+	mLatency metrics.StatTimer
+}
+
+// WrapTimed wraps a processor constructed from a config so that the time taken
+// to process each message batch is recorded as the timing metric
+// `<layer>.processors.<index>.<type>.latency`, in nanoseconds. If the config
+// has track_latency enabled then the time taken is also written to the
+// metadata key `benthos_proc_<layer>_<index>_<type>_ms` of each resulting
+// message part, in milliseconds.
+func WrapTimed(layer string, index int, conf Config, proc Type, stats metrics.Type) Type {
+	t := &Timed{
+		proc:     proc,
+		mLatency: stats.GetTimer(fmt.Sprintf("%v.processors.%v.%v.latency", layer, index, conf.Type)),
+	}
+	if conf.TrackLatency {
+		t.metaKey = fmt.Sprintf("benthos_proc_%v_%v_%v_ms", layer, index, conf.Type)
+	}
+	return t
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the wrapped processor to a message and records the
+// time taken.
+func (t *Timed) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	started := time.Now()
+	msgs, res := t.proc.ProcessMessage(msg)
+	taken := time.Since(started)
+	t.mLatency.Timing(taken.Nanoseconds())
+
+	if len(t.metaKey) == 0 || len(msgs) == 0 {
+		return msgs, res
+	}
+
+	takenStr := strconv.FormatFloat(float64(taken)/float64(time.Millisecond), 'f', -1, 64)
+	newMsgs := make([]types.Message, len(msgs))
+	for i, m := range msgs {
+		newMsgs[i] = m.Copy()
+		newMsgs[i].Iter(func(_ int, p types.Part) error {
+			p.Metadata().Set(t.metaKey, takenStr)
+			return nil
+		})
+	}
+	return newMsgs, res
+}
+
+// CloseAsync shuts down the wrapped processor if it is closable.
+func (t *Timed) CloseAsync() {
+	if c, ok := t.proc.(types.Closable); ok {
+		c.CloseAsync()
+	}
+}
+
+// WaitForClose blocks until the wrapped processor has closed down if it is
+// closable.
+func (t *Timed) WaitForClose(timeout time.Duration) error {
+	if c, ok := t.proc.(types.Closable); ok {
+		return c.WaitForClose(timeout)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,112 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/util/config"
+)
+
+func TestTimed(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeNoop
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats := metrics.NewLocal()
+	timed := WrapTimed("pipeline", 1, conf, proc, stats)
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	msgs, res := timed.ProcessMessage(msg)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := 1, len(msgs); exp != act {
+		t.Fatalf("Wrong count of messages: %v != %v", act, exp)
+	}
+	if v := msgs[0].Get(0).Metadata().Get("benthos_proc_pipeline_1_noop_ms"); v != "" {
+		t.Errorf("Unexpected latency metadata: %v", v)
+	}
+
+	if _, exists := stats.GetTimings()["pipeline.processors.1.noop.latency"]; !exists {
+		t.Errorf("Expected latency timing, found: %v", stats.GetTimings())
+	}
+}
+
+func TestTimedTrackLatency(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeNoop
+	conf.TrackLatency = true
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timed := WrapTimed("input", 0, conf, proc, metrics.Noop())
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	msgs, res := timed.ProcessMessage(msg)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := 1, len(msgs); exp != act {
+		t.Fatalf("Wrong count of messages: %v != %v", act, exp)
+	}
+	for i := 0; i < msgs[0].Len(); i++ {
+		v := msgs[0].Get(i).Metadata().Get("benthos_proc_input_0_noop_ms")
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			t.Errorf("Wrong latency metadata for part %v: %v", i, v)
+		}
+	}
+	if v := msg.Get(0).Metadata().Get("benthos_proc_input_0_noop_ms"); v != "" {
+		t.Errorf("Original message was modified: %v", v)
+	}
+}
+
+func TestTimedSanitise(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeNoop
+
+	sanit, err := SanitiseConfig(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := sanit.(config.Sanitised)["track_latency"]; exists {
+		t.Error("Expected track_latency to be omitted")
+	}
+
+	conf.TrackLatency = true
+	if sanit, err = SanitiseConfig(conf); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := sanit.(config.Sanitised)["track_latency"].(bool); !v {
+		t.Error("Expected track_latency to be true")
+	}
+}
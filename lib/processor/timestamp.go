@@ -0,0 +1,370 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/Jeffail/gabs"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeTimestamp] = TypeSpec{
+		constructor: NewTimestamp,
+		description: `
+Parses a timestamp found at the ` + "`source_path`" + ` of a JSON message part
+and writes it in a new format to the ` + "`target_path`" + `. When
+` + "`target_path`" + ` is empty the source value is replaced. Both paths
+support [interpolation functions](../config_interpolation.md#functions)
+resolved individually for each message part.
+
+Formats are either a Go time layout, as described in the
+[time package](https://golang.org/pkg/time/#pkg-constants), or one of
+` + "`unix`" + ` and ` + "`unix_ms`" + `, which are the number of seconds and
+milliseconds since the Unix epoch respectively. Unix timestamps are written as
+numbers but can be parsed from either numbers or strings.
+
+The field ` + "`parse_format`" + ` can be a single format or a list of
+candidates, which are tried in order until one succeeds. Timestamps without a
+zone offset are interpreted in the ` + "`input_timezone`" + `, and the result
+is converted into ` + "`output_timezone`" + ` before it is formatted. Time
+zones are names from the IANA Time Zone database, such as
+` + "`America/New_York`" + `, or ` + "`Local`" + ` for the local time zone of
+the host.
+
+For example, the following config converts timestamps of a few formats into
+RFC3339 UTC:
+
+` + "``` yaml" + `
+timestamp:
+  source_path: event.time
+  parse_format:
+  - 2006-01-02 15:04:05
+  - 02/Jan/2006:15:04:05 -0700
+  - unix_ms
+  input_timezone: Europe/Berlin
+  output_format: 2006-01-02T15:04:05Z07:00
+  output_timezone: UTC
+` + "```" + `
+
+Message parts that cannot be parsed as JSON, where the source path does not
+exist or where the value cannot be parsed with any of the formats are flagged
+as failed, and the error, including the offending value, is added to the
+metadata key ` + "`benthos_processing_error`" + `.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// timestampFormats is a list of timestamp formats that can be parsed from
+// either a single string or a list of strings.
+type timestampFormats []string
+
+func (t *timestampFormats) UnmarshalJSON(bytes []byte) error {
+	var single string
+	if err := json.Unmarshal(bytes, &single); err == nil {
+		*t = timestampFormats{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(bytes, &multiple); err != nil {
+		return err
+	}
+	*t = multiple
+	return nil
+}
+
+func (t *timestampFormats) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var single string
+	if err := unmarshal(&single); err == nil {
+		*t = timestampFormats{single}
+		return nil
+	}
+	var multiple []string
+	if err := unmarshal(&multiple); err != nil {
+		return err
+	}
+	*t = multiple
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// TimestampConfig contains configuration fields for the Timestamp processor.
+type TimestampConfig struct {
+	Parts          []int            `json:"parts" yaml:"parts"`
+	SourcePath     string           `json:"source_path" yaml:"source_path"`
+	TargetPath     string           `json:"target_path" yaml:"target_path"`
+	ParseFormat    timestampFormats `json:"parse_format" yaml:"parse_format"`
+	InputTimezone  string           `json:"input_timezone" yaml:"input_timezone"`
+	OutputFormat   string           `json:"output_format" yaml:"output_format"`
+	OutputTimezone string           `json:"output_timezone" yaml:"output_timezone"`
+}
+
+// NewTimestampConfig returns a TimestampConfig with default values.
+func NewTimestampConfig() TimestampConfig {
+	return TimestampConfig{
+		Parts:          []int{},
+		SourcePath:     "timestamp",
+		TargetPath:     "",
+		ParseFormat:    timestampFormats{time.RFC3339Nano},
+		InputTimezone:  "UTC",
+		OutputFormat:   time.RFC3339,
+		OutputTimezone: "UTC",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	timestampFormatUnix   = "unix"
+	timestampFormatUnixMS = "unix_ms"
+)
+
+// unixToTime converts a number of units since the Unix epoch into a time.
+func unixToTime(v float64, unit time.Duration) (time.Time, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return time.Time{}, errors.New("not a finite number")
+	}
+	whole, frac := math.Modf(v)
+	return time.Unix(0, 0).Add(
+		time.Duration(whole)*unit + time.Duration(frac*float64(unit)),
+	), nil
+}
+
+func parseTimestamp(v interface{}, format string, loc *time.Location) (time.Time, error) {
+	var unit time.Duration
+	switch format {
+	case timestampFormatUnix:
+		unit = time.Second
+	case timestampFormatUnixMS:
+		unit = time.Millisecond
+	default:
+		str, ok := v.(string)
+		if !ok {
+			return time.Time{}, fmt.Errorf("expected string value, found %T", v)
+		}
+		return time.ParseInLocation(format, str, loc)
+	}
+
+	switch t := v.(type) {
+	case float64:
+		return unixToTime(t, unit)
+	case json.Number:
+		f, err := t.Float64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return unixToTime(f, unit)
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return unixToTime(f, unit)
+	}
+	return time.Time{}, fmt.Errorf("expected number or string value, found %T", v)
+}
+
+func formatTimestamp(t time.Time, format string) interface{} {
+	switch format {
+	case timestampFormatUnix:
+		return t.Unix()
+	case timestampFormatUnixMS:
+		return t.UnixNano() / int64(time.Millisecond)
+	}
+	return t.Format(format)
+}
+
+//------------------------------------------------------------------------------
+
+// Timestamp is a processor that parses timestamps from a JSON field and writes
+// them in a new format.
+type Timestamp struct {
+	parts        []int
+	sourcePath   *text.InterpolatedString
+	targetPath   *text.InterpolatedString
+	parseFormats []string
+	inputLoc     *time.Location
+	outputFormat string
+	outputLoc    *time.Location
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErrJSONP  metrics.StatCounter
+	mErrMiss   metrics.StatCounter
+	mErrParse  metrics.StatCounter
+	mErrJSONS  metrics.StatCounter
+	mSucc      metrics.StatCounter
+	mSent      metrics.StatCounter
+	mSentParts metrics.StatCounter
+}
+
+// NewTimestamp returns a Timestamp processor.
+func NewTimestamp(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	if len(conf.Timestamp.ParseFormat) == 0 {
+		return nil, errors.New("at least one parse_format must be specified")
+	}
+	if len(conf.Timestamp.OutputFormat) == 0 {
+		return nil, errors.New("an output_format must be specified")
+	}
+	inputLoc, err := time.LoadLocation(conf.Timestamp.InputTimezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load input_timezone: %v", err)
+	}
+	outputLoc, err := time.LoadLocation(conf.Timestamp.OutputTimezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load output_timezone: %v", err)
+	}
+
+	targetPath := conf.Timestamp.TargetPath
+	if len(targetPath) == 0 {
+		targetPath = conf.Timestamp.SourcePath
+	}
+
+	return &Timestamp{
+		parts:        conf.Timestamp.Parts,
+		sourcePath:   text.NewInterpolatedString(conf.Timestamp.SourcePath),
+		targetPath:   text.NewInterpolatedString(targetPath),
+		parseFormats: conf.Timestamp.ParseFormat,
+		inputLoc:     inputLoc,
+		outputFormat: conf.Timestamp.OutputFormat,
+		outputLoc:    outputLoc,
+
+		log:   log.NewModule(".processor.timestamp"),
+		stats: stats,
+
+		mCount:     stats.GetCounter("processor.timestamp.count"),
+		mErrJSONP:  stats.GetCounter("processor.timestamp.error.json_parse"),
+		mErrMiss:   stats.GetCounter("processor.timestamp.error.missing"),
+		mErrParse:  stats.GetCounter("processor.timestamp.error.parse"),
+		mErrJSONS:  stats.GetCounter("processor.timestamp.error.json_set"),
+		mSucc:      stats.GetCounter("processor.timestamp.success"),
+		mSent:      stats.GetCounter("processor.timestamp.sent"),
+		mSentParts: stats.GetCounter("processor.timestamp.parts.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (t *Timestamp) parse(v interface{}) (time.Time, error) {
+	for _, format := range t.parseFormats {
+		if ts, err := parseTimestamp(v, format, t.inputLoc); err == nil {
+			return ts, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf(
+		"failed to parse timestamp '%v' with formats: %v", v,
+		strings.Join(t.parseFormats, ", "),
+	)
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (t *Timestamp) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	t.mCount.Incr(1)
+
+	newMsg := msg.Copy()
+
+	targetParts := t.parts
+	if len(targetParts) == 0 {
+		targetParts = make([]int, newMsg.Len())
+		for i := range targetParts {
+			targetParts[i] = i
+		}
+	}
+
+	for _, index := range targetParts {
+		if index < 0 {
+			index = newMsg.Len() + index
+		}
+		if index < 0 || index >= newMsg.Len() {
+			continue
+		}
+
+		part := newMsg.Get(index)
+		lockedMsg := message.Lock(newMsg, index)
+		sourcePath := t.sourcePath.Get(lockedMsg)
+		targetPath := t.targetPath.Get(lockedMsg)
+
+		jsonPart, err := part.JSON()
+		var gPart *gabs.Container
+		if err == nil {
+			gPart, err = gabs.Consume(jsonPart)
+		}
+		if err != nil {
+			t.mErrJSONP.Incr(1)
+			t.log.Debugf("Failed to parse part into json: %v\n", err)
+			FlagErr(part, err)
+			continue
+		}
+
+		gSource := gPart.S(splitJSONPath(sourcePath)...)
+		if gSource == nil || gSource.Data() == nil {
+			t.mErrMiss.Incr(1)
+			err = fmt.Errorf("timestamp not found at path '%v'", sourcePath)
+			t.log.Debugf("%v\n", err)
+			FlagErr(part, err)
+			continue
+		}
+
+		ts, err := t.parse(gSource.Data())
+		if err != nil {
+			t.mErrParse.Incr(1)
+			t.log.Debugf("%v\n", err)
+			FlagErr(part, err)
+			continue
+		}
+
+		gPart.Set(formatTimestamp(ts.In(t.outputLoc), t.outputFormat), splitJSONPath(targetPath)...)
+		if err = part.SetJSON(gPart.Data()); err != nil {
+			t.mErrJSONS.Incr(1)
+			t.log.Debugf("Failed to convert json into part: %v\n", err)
+			FlagErr(part, err)
+			continue
+		}
+		t.mSucc.Incr(1)
+	}
+
+	t.mSent.Incr(1)
+	t.mSentParts.Incr(int64(newMsg.Len()))
+	msgs := [1]types.Message{newMsg}
+	return msgs[:], nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,269 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	yaml "gopkg.in/yaml.v2"
+)
+
+//------------------------------------------------------------------------------
+
+func TestTimestampFormats(t *testing.T) {
+	type testCase struct {
+		name      string
+		input     string
+		formats   []string
+		inputTZ   string
+		outFormat string
+		outputTZ  string
+		output    string
+	}
+
+	tests := []testCase{
+		{
+			name:      "rfc3339 to rfc3339 utc",
+			input:     `{"timestamp":"2018-11-26T10:04:05+01:00"}`,
+			formats:   []string{"2006-01-02T15:04:05Z07:00"},
+			outFormat: "2006-01-02T15:04:05Z07:00",
+			output:    `{"timestamp":"2018-11-26T09:04:05Z"}`,
+		},
+		{
+			name:      "naive with input timezone",
+			input:     `{"timestamp":"2018-11-26 10:04:05"}`,
+			formats:   []string{"2006-01-02 15:04:05"},
+			inputTZ:   "America/New_York",
+			outFormat: "2006-01-02T15:04:05Z07:00",
+			output:    `{"timestamp":"2018-11-26T15:04:05Z"}`,
+		},
+		{
+			name:      "output timezone",
+			input:     `{"timestamp":"2018-11-26T15:04:05Z"}`,
+			formats:   []string{"2006-01-02T15:04:05Z07:00"},
+			outFormat: "2006-01-02T15:04:05Z07:00",
+			outputTZ:  "America/New_York",
+			output:    `{"timestamp":"2018-11-26T10:04:05-05:00"}`,
+		},
+		{
+			name:      "unix number to rfc3339",
+			input:     `{"timestamp":1543226645}`,
+			formats:   []string{"unix"},
+			outFormat: "2006-01-02T15:04:05Z07:00",
+			output:    `{"timestamp":"2018-11-26T10:04:05Z"}`,
+		},
+		{
+			name:      "fractional unix string to rfc3339 nano",
+			input:     `{"timestamp":"1543226645.25"}`,
+			formats:   []string{"unix"},
+			outFormat: "2006-01-02T15:04:05.999999999Z07:00",
+			output:    `{"timestamp":"2018-11-26T10:04:05.25Z"}`,
+		},
+		{
+			name:      "unix_ms to unix",
+			input:     `{"timestamp":1543226645123}`,
+			formats:   []string{"unix_ms"},
+			outFormat: "unix",
+			output:    `{"timestamp":1543226645}`,
+		},
+		{
+			name:      "rfc3339 to unix_ms",
+			input:     `{"timestamp":"2018-11-26T10:04:05.123Z"}`,
+			formats:   []string{"2006-01-02T15:04:05Z07:00"},
+			outFormat: "unix_ms",
+			output:    `{"timestamp":1543226645123}`,
+		},
+		{
+			name:      "second candidate",
+			input:     `{"timestamp":"26/Nov/2018:10:04:05 +0000"}`,
+			formats:   []string{"2006-01-02T15:04:05Z07:00", "unix", "02/Jan/2006:15:04:05 -0700"},
+			outFormat: "2006-01-02",
+			output:    `{"timestamp":"2018-11-26"}`,
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.Timestamp.ParseFormat = test.formats
+		conf.Timestamp.OutputFormat = test.outFormat
+		if len(test.inputTZ) > 0 {
+			conf.Timestamp.InputTimezone = test.inputTZ
+		}
+		if len(test.outputTZ) > 0 {
+			conf.Timestamp.OutputTimezone = test.outputTZ
+		}
+
+		proc, err := NewTimestamp(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+
+		msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(test.input)}))
+		if res != nil {
+			t.Fatalf("%v: %v", test.name, res.Error())
+		}
+		if HasFailed(msgs[0].Get(0)) {
+			t.Errorf("%v: unexpected failure: %v", test.name, msgs[0].Get(0).Metadata().Get(types.FailErrorKey))
+		}
+		if act := string(msgs[0].Get(0).Get()); act != test.output {
+			t.Errorf("%v: wrong result: %v != %v", test.name, act, test.output)
+		}
+	}
+}
+
+func TestTimestampPaths(t *testing.T) {
+	conf := NewConfig()
+	conf.Timestamp.SourcePath = "${!metadata:field}.raw"
+	conf.Timestamp.TargetPath = "${!metadata:field}.parsed"
+	conf.Timestamp.ParseFormat = []string{"unix"}
+
+	proc, err := NewTimestamp(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{
+		[]byte(`{"created":{"raw":0}}`),
+		[]byte(`{"updated":{"raw":60}}`),
+	})
+	msg.Get(0).Metadata().Set("field", "created")
+	msg.Get(1).Metadata().Set("field", "updated")
+
+	msgs, res := proc.ProcessMessage(msg)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp := [][]byte{
+		[]byte(`{"created":{"parsed":"1970-01-01T00:00:00Z","raw":0}}`),
+		[]byte(`{"updated":{"parsed":"1970-01-01T00:01:00Z","raw":60}}`),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+}
+
+func TestTimestampErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Timestamp.ParseFormat = []string{"unix", "2006-01-02"}
+
+	stats := metrics.NewLocal()
+	proc, err := NewTimestamp(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := [][]byte{
+		[]byte(`{"timestamp":"yesterday"}`),
+		[]byte(`{"other":"2018-11-26"}`),
+		[]byte(`not json`),
+		[]byte(`{"timestamp":"2018-11-26"}`),
+	}
+	msgs, res := proc.ProcessMessage(message.New(input))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	expErrs := []string{
+		"failed to parse timestamp 'yesterday' with formats: unix, 2006-01-02",
+		"timestamp not found at path 'timestamp'",
+		"invalid character 'o' in literal null (expecting 'u')",
+		"",
+	}
+	for i, exp := range expErrs {
+		part := msgs[0].Get(i)
+		if act := part.Metadata().Get(types.FailErrorKey); exp != act {
+			t.Errorf("Wrong error for part %v: %v != %v", i, act, exp)
+		}
+		if exp, act := len(exp) > 0, HasFailed(part); exp != act {
+			t.Errorf("Wrong failed flag for part %v: %v != %v", i, act, exp)
+		}
+	}
+	for i, exp := range input[:3] {
+		if act := msgs[0].Get(i).Get(); !reflect.DeepEqual(exp, act) {
+			t.Errorf("Part %v was modified: %s != %s", i, act, exp)
+		}
+	}
+
+	counters := stats.GetCounters()
+	for k, exp := range map[string]int64{
+		"processor.timestamp.error.parse":      1,
+		"processor.timestamp.error.missing":    1,
+		"processor.timestamp.error.json_parse": 1,
+		"processor.timestamp.success":          1,
+	} {
+		if act := counters[k]; exp != act {
+			t.Errorf("Wrong count for %v: %v != %v", k, act, exp)
+		}
+	}
+}
+
+func TestTimestampBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Timestamp.InputTimezone = "Not/AZone"
+	if _, err := NewTimestamp(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad input timezone")
+	}
+
+	conf = NewConfig()
+	conf.Timestamp.OutputTimezone = "Not/AZone"
+	if _, err := NewTimestamp(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad output timezone")
+	}
+
+	conf = NewConfig()
+	conf.Timestamp.ParseFormat = nil
+	if _, err := NewTimestamp(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from empty parse formats")
+	}
+}
+
+func TestTimestampParseFormatUnmarshal(t *testing.T) {
+	conf := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+type: timestamp
+timestamp:
+  parse_format: unix`), &conf); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := []string{"unix"}, []string(conf.Timestamp.ParseFormat); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong formats: %v != %v", act, exp)
+	}
+
+	conf = NewConfig()
+	if err := json.Unmarshal([]byte(`{
+	"type": "timestamp",
+	"timestamp": {"parse_format": ["unix", "unix_ms"]}
+}`), &conf); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := []string{"unix", "unix_ms"}, []string(conf.Timestamp.ParseFormat); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong formats: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
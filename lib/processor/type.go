@@ -38,3 +38,28 @@ type Type interface {
 }
 
 //------------------------------------------------------------------------------
+
+// FlagFail marks a message part as having failed at a processing step.
+func FlagFail(part types.Part) {
+	part.Metadata().Set(types.FailFlagKey, "true")
+}
+
+// FlagErr marks a message part as having failed at a processing step and adds
+// the error to its metadata.
+func FlagErr(part types.Part, err error) {
+	FlagFail(part)
+	part.Metadata().Set(types.FailErrorKey, err.Error())
+}
+
+// ClearFail removes any failure flag and error from a message part.
+func ClearFail(part types.Part) {
+	part.Metadata().Delete(types.FailFlagKey)
+	part.Metadata().Delete(types.FailErrorKey)
+}
+
+// HasFailed checks whether a message part has failed a processing step.
+func HasFailed(part types.Part) bool {
+	return len(part.Metadata().Get(types.FailFlagKey)) > 0
+}
+
+//------------------------------------------------------------------------------
@@ -24,8 +24,10 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"bytes"
+	"encoding/csv"
 	"fmt"
 	"io"
+	"sort"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
@@ -41,17 +43,28 @@ func init() {
 		constructor: NewUnarchive,
 		description: `
 Unarchives parts of a message according to the selected archive type into
-multiple parts. Supported archive types are: tar, zip, binary, lines.
+multiple parts. Supported archive types are: tar, zip, binary, lines,
+json_array, json_map and csv.
 
 When a part is unarchived it is split into more message parts that replace the
 original part. If you wish to split the archive into one message per file then
-follow this with the 'split' processor.
-
-Parts that are selected but fail to unarchive (invalid format) will be removed
-from the message. If the message results in zero parts it is skipped entirely.
+follow this with the 'split' processor. Each extracted part inherits the
+metadata of the original part.
 
 For the unarchivers that contain file information (tar, zip), a metadata field
-is added to each part called ` + "`archive_filename`" + ` with the extracted filename.`,
+is added to each part called ` + "`archive_filename`" + ` with the extracted filename.
+
+The json_array format creates a part for each element of a JSON array, and the
+json_map format creates a part for each value of a JSON object, ordered by key,
+with the key added as the metadata field ` + "`archive_key`" + `.
+
+The csv format treats the first row as a header and creates a part for each
+subsequent row as a JSON object, mapping each header to the value of the row.
+
+### Error Handling
+
+Parts that are selected but fail to unarchive (invalid format) are left
+unchanged and are flagged as failed, sibling parts are unaffected.`,
 	}
 }
 
@@ -157,6 +170,81 @@ func linesUnarchive(part types.Part) ([]types.Part, error) {
 	return parts, nil
 }
 
+func jsonArrayUnarchive(part types.Part) ([]types.Part, error) {
+	jDoc, err := part.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message into JSON array: %v", err)
+	}
+
+	jArray, ok := jDoc.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("failed to parse message into JSON array: invalid type '%T'", jDoc)
+	}
+
+	parts := make([]types.Part, len(jArray))
+	for i, ele := range jArray {
+		newPart := message.NewPart(nil)
+		if err = newPart.SetJSON(ele); err != nil {
+			return nil, fmt.Errorf("failed marshal JSON element: %v", err)
+		}
+		parts[i] = newPart.SetMetadata(part.Metadata().Copy())
+	}
+	return parts, nil
+}
+
+func jsonMapUnarchive(part types.Part) ([]types.Part, error) {
+	jDoc, err := part.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message into JSON map: %v", err)
+	}
+
+	jMap, ok := jDoc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("failed to parse message into JSON map: invalid type '%T'", jDoc)
+	}
+
+	keys := make([]string, 0, len(jMap))
+	for k := range jMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]types.Part, len(keys))
+	for i, k := range keys {
+		newPart := message.NewPart(nil)
+		if err = newPart.SetJSON(jMap[k]); err != nil {
+			return nil, fmt.Errorf("failed marshal JSON value: %v", err)
+		}
+		parts[i] = newPart.SetMetadata(part.Metadata().Copy().Set("archive_key", k))
+	}
+	return parts, nil
+}
+
+func csvUnarchive(part types.Part) ([]types.Part, error) {
+	records, err := csv.NewReader(bytes.NewReader(part.Get())).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as csv: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	headers := records[0]
+	parts := make([]types.Part, 0, len(records)-1)
+	for _, record := range records[1:] {
+		obj := make(map[string]interface{}, len(headers))
+		for i, h := range headers {
+			obj[h] = record[i]
+		}
+		newPart := message.NewPart(nil)
+		if err = newPart.SetJSON(obj); err != nil {
+			return nil, fmt.Errorf("failed marshal csv row: %v", err)
+		}
+		parts = append(parts, newPart.SetMetadata(part.Metadata().Copy()))
+	}
+	return parts, nil
+}
+
 func strToUnarchiver(str string) (unarchiveFunc, error) {
 	switch str {
 	case "tar":
@@ -167,6 +255,12 @@ func strToUnarchiver(str string) (unarchiveFunc, error) {
 		return binaryUnarchive, nil
 	case "lines":
 		return linesUnarchive, nil
+	case "json_array":
+		return jsonArrayUnarchive, nil
+	case "json_map":
+		return jsonMapUnarchive, nil
+	case "csv":
+		return csvUnarchive, nil
 	}
 	return nil, fmt.Errorf("archive format not recognised: %v", str)
 }
@@ -247,6 +341,10 @@ func (d *Unarchive) ProcessMessage(msg types.Message) ([]types.Message, types.Re
 			newMsg.Append(newParts...)
 		} else {
 			d.mErr.Incr(1)
+			d.log.Debugf("Failed to unarchive message part: %v\n", err)
+			newPart := part.Copy()
+			newMsg.Append(newPart)
+			FlagFail(newPart)
 		}
 		return nil
 	})
@@ -234,8 +234,10 @@ func TestUnarchiveBinary(t *testing.T) {
 	}
 	if msgs, _ := proc.ProcessMessage(
 		message.New([][]byte{[]byte("wat this isnt good")}),
-	); len(msgs) > 0 {
-		t.Error("Expected fail on bad message")
+	); len(msgs) != 1 {
+		t.Error("Expected bad message to be kept")
+	} else if !HasFailed(msgs[0].Get(0)) {
+		t.Error("Expected bad message to be flagged")
 	}
 
 	testMsg := message.New([][]byte{[]byte("hello"), []byte("world")})
@@ -377,7 +379,125 @@ func TestUnarchiveEmpty(t *testing.T) {
 	msgs, _ = proc.ProcessMessage(message.New(
 		[][]byte{[]byte("first"), []byte("second")},
 	))
-	if len(msgs) != 0 {
-		t.Error("Expected failure with bad data")
+	if len(msgs) != 1 {
+		t.Fatal("Expected bad data to be kept")
+	}
+	exp := [][]byte{[]byte("first"), []byte("second")}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Unexpected output: %s != %s", act, exp)
+	}
+	for i := 0; i < 2; i++ {
+		if !HasFailed(msgs[0].Get(i)) {
+			t.Errorf("Expected part %v to be flagged", i)
+		}
+	}
+}
+
+func TestUnarchiveJSONArray(t *testing.T) {
+	conf := NewConfig()
+	conf.Unarchive.Format = "json_array"
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	proc, err := NewUnarchive(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte(`[{"foo":"bar"},"baz",5]`),
+		[]byte(`{"not":"an array"}`),
+	})
+	input.Get(0).Metadata().Set("foo", "bar")
+
+	msgs, res := proc.ProcessMessage(input)
+	if len(msgs) != 1 {
+		t.Fatalf("Unarchive failed: %v", res)
+	}
+
+	exp := [][]byte{
+		[]byte(`{"foo":"bar"}`),
+		[]byte(`"baz"`),
+		[]byte(`5`),
+		[]byte(`{"not":"an array"}`),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Unexpected output: %s != %s", act, exp)
+	}
+	for i := 0; i < 3; i++ {
+		if exp, act := "bar", msgs[0].Get(i).Metadata().Get("foo"); exp != act {
+			t.Errorf("Wrong metadata on part %v: %v != %v", i, act, exp)
+		}
+		if HasFailed(msgs[0].Get(i)) {
+			t.Errorf("Unexpected flag on part %v", i)
+		}
+	}
+	if !HasFailed(msgs[0].Get(3)) {
+		t.Error("Expected bad part to be flagged")
+	}
+}
+
+func TestUnarchiveJSONMap(t *testing.T) {
+	conf := NewConfig()
+	conf.Unarchive.Format = "json_map"
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	proc, err := NewUnarchive(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"b":{"foo":"bar"},"a":"baz","c":[1,2]}`),
+	}))
+	if len(msgs) != 1 {
+		t.Fatalf("Unarchive failed: %v", res)
+	}
+
+	exp := [][]byte{
+		[]byte(`"baz"`),
+		[]byte(`{"foo":"bar"}`),
+		[]byte(`[1,2]`),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Unexpected output: %s != %s", act, exp)
+	}
+	for i, exp := range []string{"a", "b", "c"} {
+		if act := msgs[0].Get(i).Metadata().Get("archive_key"); exp != act {
+			t.Errorf("Wrong archive_key on part %v: %v != %v", i, act, exp)
+		}
+	}
+}
+
+func TestUnarchiveCSV(t *testing.T) {
+	conf := NewConfig()
+	conf.Unarchive.Format = "csv"
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	proc, err := NewUnarchive(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte("id,name\n1,foo\n2,\"bar, baz\"\n"),
+		[]byte("id,name\n1,foo,extra\n"),
+	}))
+	if len(msgs) != 1 {
+		t.Fatalf("Unarchive failed: %v", res)
+	}
+
+	exp := [][]byte{
+		[]byte(`{"id":"1","name":"foo"}`),
+		[]byte(`{"id":"2","name":"bar, baz"}`),
+		[]byte("id,name\n1,foo,extra\n"),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Unexpected output: %s != %s", act, exp)
+	}
+	if HasFailed(msgs[0].Get(0)) || HasFailed(msgs[0].Get(1)) {
+		t.Error("Unexpected flag on good parts")
+	}
+	if !HasFailed(msgs[0].Get(2)) {
+		t.Error("Expected bad csv to be flagged")
 	}
 }
@@ -0,0 +1,236 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/processor/condition"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeWhile] = TypeSpec{
+		constructor: NewWhile,
+		description: `
+While is a processor that has a condition and a list of child processors. The
+child processors are executed continously on a message batch for as long as the
+child condition resolves to true.
+
+The field ` + "`at_least_once`" + `, if true, ensures that the child processors
+are always executed at least one time (like a do .. while loop.)
+
+The field ` + "`max_loops`" + `, if greater than zero, caps the number of loops
+for a message batch to this value. If the cap is reached whilst the condition
+still resolves to true then each part of the batch is flagged as having failed
+processing. It is strongly recommended to set this field in order to protect
+against infinite loops.
+
+If the child processors produce more than one message batch then the condition
+is checked against all resulting parts combined as a single batch.
+
+The number of loops performed for each message batch is recorded with the
+metric ` + "`processor.while.loops`" + `, which is exposed as a timing
+distribution.
+
+You can find a [full list of conditions here](../conditions).`,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			condSanit, err := condition.SanitiseConfig(conf.While.Condition)
+			if err != nil {
+				return nil, err
+			}
+			procConfs := make([]interface{}, len(conf.While.Processors))
+			for i, pConf := range conf.While.Processors {
+				if procConfs[i], err = SanitiseConfig(pConf); err != nil {
+					return nil, err
+				}
+			}
+			return map[string]interface{}{
+				"at_least_once": conf.While.AtLeastOnce,
+				"max_loops":     conf.While.MaxLoops,
+				"condition":     condSanit,
+				"processors":    procConfs,
+			}, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// WhileConfig is a config struct containing fields for the While
+// processor.
+type WhileConfig struct {
+	AtLeastOnce bool             `json:"at_least_once" yaml:"at_least_once"`
+	MaxLoops    int              `json:"max_loops" yaml:"max_loops"`
+	Condition   condition.Config `json:"condition" yaml:"condition"`
+	Processors  []Config         `json:"processors" yaml:"processors"`
+}
+
+// NewWhileConfig returns a default WhileConfig.
+func NewWhileConfig() WhileConfig {
+	return WhileConfig{
+		AtLeastOnce: false,
+		MaxLoops:    0,
+		Condition:   condition.NewConfig(),
+		Processors:  []Config{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// While is a processor that applies child processors for as long as a child
+// condition resolves true.
+type While struct {
+	cond        condition.Type
+	children    []Type
+	maxLoops    int
+	atLeastOnce bool
+
+	log log.Modular
+
+	mCount     metrics.StatCounter
+	mLoop      metrics.StatCounter
+	mLoops     metrics.StatTimer
+	mMaxLoops  metrics.StatCounter
+	mSent      metrics.StatCounter
+	mSentParts metrics.StatCounter
+	mDropped   metrics.StatCounter
+}
+
+// NewWhile returns a While processor.
+func NewWhile(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	nsStats := metrics.Namespaced(stats, "processor.while")
+	nsLog := log.NewModule(".processor.while")
+	cond, err := condition.New(conf.While.Condition, mgr, nsLog, nsStats)
+	if err != nil {
+		return nil, err
+	}
+
+	var children []Type
+	for _, pconf := range conf.While.Processors {
+		var proc Type
+		if proc, err = New(pconf, mgr, nsLog, nsStats); err != nil {
+			return nil, err
+		}
+		children = append(children, proc)
+	}
+
+	return &While{
+		cond:        cond,
+		children:    children,
+		maxLoops:    conf.While.MaxLoops,
+		atLeastOnce: conf.While.AtLeastOnce,
+
+		log: nsLog,
+
+		mCount:     stats.GetCounter("processor.while.count"),
+		mLoop:      stats.GetCounter("processor.while.loop"),
+		mLoops:     stats.GetTimer("processor.while.loops"),
+		mMaxLoops:  stats.GetCounter("processor.while.max_loops_reached"),
+		mSent:      stats.GetCounter("processor.while.sent"),
+		mSentParts: stats.GetCounter("processor.while.parts.sent"),
+		mDropped:   stats.GetCounter("processor.while.dropped"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// checkMsgs evaluates the condition against all parts of a slice of message
+// batches combined.
+func (w *While) checkMsgs(msgs []types.Message) bool {
+	if len(msgs) == 1 {
+		return w.cond.Check(msgs[0])
+	}
+	combined := message.New(nil)
+	for _, m := range msgs {
+		m.Iter(func(i int, p types.Part) error {
+			combined.Append(p)
+			return nil
+		})
+	}
+	return w.cond.Check(combined)
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (w *While) ProcessMessage(msg types.Message) (msgs []types.Message, res types.Response) {
+	w.mCount.Incr(1)
+
+	resultMsgs := []types.Message{msg}
+	var resultRes types.Response
+
+	loops := 0
+	condResult := w.atLeastOnce || w.checkMsgs(resultMsgs)
+	for condResult {
+		if w.maxLoops > 0 && loops >= w.maxLoops {
+			w.log.Debugln("Reached max loops count")
+			w.mMaxLoops.Incr(1)
+			for _, m := range resultMsgs {
+				m.Iter(func(i int, p types.Part) error {
+					FlagFail(p)
+					return nil
+				})
+			}
+			break
+		}
+
+		w.mLoop.Incr(1)
+		w.log.Traceln("Looped")
+		for i := 0; len(resultMsgs) > 0 && i < len(w.children); i++ {
+			var nextResultMsgs []types.Message
+			for _, m := range resultMsgs {
+				var rMsgs []types.Message
+				rMsgs, resultRes = w.children[i].ProcessMessage(m)
+				nextResultMsgs = append(nextResultMsgs, rMsgs...)
+			}
+			resultMsgs = nextResultMsgs
+		}
+		loops++
+
+		if len(resultMsgs) == 0 {
+			break
+		}
+		condResult = w.checkMsgs(resultMsgs)
+	}
+	w.mLoops.Timing(int64(loops))
+
+	if len(resultMsgs) == 0 {
+		w.mDropped.Incr(1)
+		res = resultRes
+	} else {
+		w.mSent.Incr(int64(len(resultMsgs)))
+		totalParts := 0
+		for _, msg := range resultMsgs {
+			totalParts += msg.Len()
+		}
+		w.mSentParts.Incr(int64(totalParts))
+		msgs = resultMsgs
+	}
+
+	return
+}
+
+//------------------------------------------------------------------------------
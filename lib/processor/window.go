@@ -0,0 +1,642 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/clock"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/Jeffail/gabs"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeWindow] = TypeSpec{
+		constructor: NewWindow,
+		description: `
+Aggregates message parts over windows of time, where each window is grouped by
+a key, and emits one aggregate message per key each time a window closes.
+Windows are of a fixed ` + "`size`" + ` and a new window starts every
+` + "`slide`" + `, aligned to the unix epoch. When ` + "`slide`" + ` is empty it
+defaults to the ` + "`size`" + `, which results in tumbling windows, so a size
+of ` + "`1m`" + ` produces windows that start at the beginning of each minute.
+
+When ` + "`slide`" + ` is smaller than ` + "`size`" + ` the windows overlap, and
+each message part contributes to every window that contains its timestamp. For
+example, a ` + "`size`" + ` of ` + "`5m`" + ` and a ` + "`slide`" + ` of
+` + "`1m`" + ` results in aggregates of the last five minutes being emitted
+every minute, which is useful for calculating moving averages. The number of
+windows open at any given time, and therefore the state held, is bounded by
+` + "`size`" + ` divided by ` + "`slide`" + ` rounded up.
+
+The ` + "`key`" + ` and ` + "`timestamp`" + ` fields support
+[function interpolations](../config_interpolation.md#functions), which are
+resolved individually for each message part.
+
+The state of the open windows is stored in a [cache resource](../caches) under
+the key ` + "`state_key`" + `, and is loaded from the cache for each message
+received. This means that when a persistent cache such as ` + "`redis`" + ` is
+used the open windows survive restarts. Window processors of the same
+` + "`state_key`" + ` within a Benthos instance, such as those of each
+pipeline thread, take turns to update the state and therefore share windows.
+Window processors of separate Benthos instances must be given their own
+` + "`state_key`" + `.
+
+Message parts are acknowledged once they have been added to the stored window
+state. A window is closed when a message is received with a timestamp at or
+beyond the end of the window, in which case the aggregate messages are sent
+onwards in place of the message that closed them. Windows are also checked
+every second by the pipeline running the processor, and are closed once the
+time elapsed since the latest timestamp was received reaches the end of the
+window, which means windows close on time when no new messages arrive. When the
+` + "`timestamp`" + ` field is set this assumes that message timestamps keep
+pace with processing time. Closed windows are removed from the state.
+
+### Aggregations
+
+The ` + "`aggregate`" + ` field determines what is recorded for each key, and
+can be one of the following:
+
+#### ` + "`count`" + `
+
+Counts the number of message parts within the window.
+
+#### ` + "`sum:<path>`" + `
+
+Sums the numerical value found at a JSON dot path of each message part, e.g.
+` + "`sum:order.total`" + `. Message parts that are not JSON or do not contain a
+number at the path are counted but do not contribute to the sum.
+
+#### ` + "`collect`" + `
+
+Collects the contents of each message part into an array. Parts that are valid
+JSON are added as JSON values, other parts are added as strings.
+
+Each aggregate message is a JSON object of the following form, where the field
+` + "`sum`" + ` or ` + "`values`" + ` is present depending on the aggregation:
+
+` + "``` json" + `
+{
+  "key": "foo",
+  "window_start": "2018-11-26T10:00:00Z",
+  "window_end": "2018-11-26T10:01:00Z",
+  "count": 10,
+  "sum": 24.5
+}
+` + "```" + `
+
+### Late Arrivals
+
+When the ` + "`timestamp`" + ` field is empty the processing time of each
+message part is used, otherwise the field is resolved for each part and parsed
+as an RFC 3339 timestamp. Parts that have a timestamp that cannot be parsed are
+dropped.
+
+Message parts with a timestamp that belongs to a window that has already closed
+are late arrivals, and the field ` + "`late_arrivals`" + ` determines how they
+are handled. When set to ` + "`drop`" + ` late parts are only added to the
+windows containing their timestamp that are still open, and are dropped if there
+are none. When set to ` + "`current`" + ` late parts are instead added to the
+windows containing the latest timestamp received.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// WindowConfig contains configuration fields for the Window processor.
+type WindowConfig struct {
+	Cache        string `json:"cache" yaml:"cache"`
+	StateKey     string `json:"state_key" yaml:"state_key"`
+	Size         string `json:"size" yaml:"size"`
+	Slide        string `json:"slide" yaml:"slide"`
+	Key          string `json:"key" yaml:"key"`
+	Timestamp    string `json:"timestamp" yaml:"timestamp"`
+	Aggregate    string `json:"aggregate" yaml:"aggregate"`
+	LateArrivals string `json:"late_arrivals" yaml:"late_arrivals"`
+}
+
+// NewWindowConfig returns a WindowConfig with default values.
+func NewWindowConfig() WindowConfig {
+	return WindowConfig{
+		Cache:        "",
+		StateKey:     "benthos_window",
+		Size:         "1m",
+		Slide:        "",
+		Key:          "",
+		Timestamp:    "",
+		Aggregate:    "count",
+		LateArrivals: "drop",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// windowAggregate is the aggregated state of a single key within a window.
+type windowAggregate struct {
+	Count  int64         `json:"count"`
+	Sum    float64       `json:"sum,omitempty"`
+	Values []interface{} `json:"values,omitempty"`
+}
+
+// windowBucket is the aggregated state of all keys within a single window.
+type windowBucket struct {
+	Start      time.Time                   `json:"start"`
+	Keys       []string                    `json:"keys"`
+	Aggregates map[string]*windowAggregate `json:"aggregates"`
+}
+
+func newWindowBucket(start time.Time) *windowBucket {
+	return &windowBucket{
+		Start:      start,
+		Keys:       []string{},
+		Aggregates: map[string]*windowAggregate{},
+	}
+}
+
+// windowState is the state of all open windows, which is stored within a cache
+// as a single JSON document.
+type windowState struct {
+	// Latest is the latest timestamp received.
+	Latest time.Time `json:"latest"`
+
+	// Received is the processing time at which the latest timestamp was
+	// received.
+	Received time.Time `json:"received"`
+
+	// Closed is the time at which all windows that end at or before it are
+	// closed.
+	Closed time.Time `json:"closed"`
+
+	// Windows are the open windows ordered by their start time.
+	Windows []*windowBucket `json:"windows"`
+}
+
+func newWindowState() *windowState {
+	return &windowState{
+		Windows: []*windowBucket{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type windowAggregator func(agg *windowAggregate, part types.Part)
+
+func newWindowCountAggregator() windowAggregator {
+	return func(agg *windowAggregate, part types.Part) {}
+}
+
+func newWindowSumAggregator(path []string) windowAggregator {
+	return func(agg *windowAggregate, part types.Part) {
+		jObj, err := part.JSON()
+		if err != nil {
+			return
+		}
+		gObj, err := gabs.Consume(jObj)
+		if err != nil {
+			return
+		}
+		switch t := gObj.S(path...).Data().(type) {
+		case float64:
+			agg.Sum += t
+		case json.Number:
+			if f, err := t.Float64(); err == nil {
+				agg.Sum += f
+			}
+		}
+	}
+}
+
+func newWindowCollectAggregator() windowAggregator {
+	return func(agg *windowAggregate, part types.Part) {
+		if jObj, err := part.JSON(); err == nil {
+			agg.Values = append(agg.Values, jObj)
+		} else {
+			agg.Values = append(agg.Values, string(part.Get()))
+		}
+	}
+}
+
+func windowAggregatorFromString(aggregate string) (windowAggregator, error) {
+	switch {
+	case aggregate == "count":
+		return newWindowCountAggregator(), nil
+	case aggregate == "collect":
+		return newWindowCollectAggregator(), nil
+	case strings.HasPrefix(aggregate, "sum:"):
+		path := strings.TrimPrefix(aggregate, "sum:")
+		if len(path) == 0 {
+			return nil, errors.New("sum aggregate requires a path")
+		}
+		return newWindowSumAggregator(splitJSONPath(path)), nil
+	}
+	return nil, fmt.Errorf("aggregate not recognised: %v", aggregate)
+}
+
+//------------------------------------------------------------------------------
+
+// Window is a processor that aggregates message parts over tumbling windows of
+// time grouped by a key, and emits an aggregate message per key each time a
+// window closes.
+type Window struct {
+	log   log.Modular
+	stats metrics.Type
+
+	cache       types.Cache
+	stateKey    string
+	size        time.Duration
+	slide       time.Duration
+	key         *text.InterpolatedString
+	timestamp   *text.InterpolatedString
+	aggregate   windowAggregator
+	isSum       bool
+	useTS       bool
+	lateCurrent bool
+
+	state    *windowState
+	stateMut *sync.Mutex
+	clock    clock.Clock
+
+	mCount       metrics.StatCounter
+	mLate        metrics.StatCounter
+	mErr         metrics.StatCounter
+	mErrCache    metrics.StatCounter
+	mErrTS       metrics.StatCounter
+	mClosed      metrics.StatCounter
+	mDropped     metrics.StatCounter
+	mSent        metrics.StatCounter
+	mSentParts   metrics.StatCounter
+	mWindowParts metrics.StatGauge
+	mWindowsOpen metrics.StatGauge
+}
+
+// NewWindow returns a Window processor.
+func NewWindow(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	c, err := mgr.GetCache(conf.Window.Cache)
+	if err != nil {
+		return nil, err
+	}
+	if len(conf.Window.StateKey) == 0 {
+		return nil, errors.New("a state_key must be specified")
+	}
+
+	size, err := time.ParseDuration(conf.Window.Size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse size: %v", err)
+	}
+	if size <= 0 {
+		return nil, errors.New("size must be greater than zero")
+	}
+
+	slide := size
+	if len(conf.Window.Slide) > 0 {
+		if slide, err = time.ParseDuration(conf.Window.Slide); err != nil {
+			return nil, fmt.Errorf("failed to parse slide: %v", err)
+		}
+		if slide <= 0 || slide > size {
+			return nil, errors.New("slide must be greater than zero and no greater than size")
+		}
+	}
+
+	agg, err := windowAggregatorFromString(conf.Window.Aggregate)
+	if err != nil {
+		return nil, err
+	}
+
+	var lateCurrent bool
+	switch conf.Window.LateArrivals {
+	case "drop":
+	case "current":
+		lateCurrent = true
+	default:
+		return nil, fmt.Errorf("late_arrivals option not recognised: %v", conf.Window.LateArrivals)
+	}
+
+	return &Window{
+		log:   log.NewModule(".processor.window"),
+		stats: stats,
+
+		cache:       c,
+		stateKey:    conf.Window.StateKey,
+		size:        size,
+		slide:       slide,
+		key:         text.NewInterpolatedString(conf.Window.Key),
+		timestamp:   text.NewInterpolatedString(conf.Window.Timestamp),
+		aggregate:   agg,
+		isSum:       strings.HasPrefix(conf.Window.Aggregate, "sum:"),
+		useTS:       len(conf.Window.Timestamp) > 0,
+		lateCurrent: lateCurrent,
+
+		stateMut: windowStateMut(conf.Window.Cache, conf.Window.StateKey),
+		clock:    clock.Real(),
+
+		mCount:       stats.GetCounter("processor.window.count"),
+		mLate:        stats.GetCounter("processor.window.late"),
+		mErr:         stats.GetCounter("processor.window.error"),
+		mErrCache:    stats.GetCounter("processor.window.error.cache"),
+		mErrTS:       stats.GetCounter("processor.window.error.timestamp"),
+		mClosed:      stats.GetCounter("processor.window.closed"),
+		mDropped:     stats.GetCounter("processor.window.dropped"),
+		mSent:        stats.GetCounter("processor.window.sent"),
+		mSentParts:   stats.GetCounter("processor.window.parts.sent"),
+		mWindowParts: stats.GetGauge("processor.window.window_parts"),
+		mWindowsOpen: stats.GetGauge("processor.window.windows_open"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// windowStateMuts guards each window state, since the processors of multiple
+// pipeline threads may share a state_key.
+var (
+	windowStateMutsMut sync.Mutex
+	windowStateMuts    = map[string]*sync.Mutex{}
+)
+
+func windowStateMut(cache, stateKey string) *sync.Mutex {
+	windowStateMutsMut.Lock()
+	defer windowStateMutsMut.Unlock()
+	id := cache + "/" + stateKey
+	mut, exists := windowStateMuts[id]
+	if !exists {
+		mut = &sync.Mutex{}
+		windowStateMuts[id] = mut
+	}
+	return mut
+}
+
+// loadState reads the current window state from the cache, if the state does
+// not yet exist an empty state is created.
+func (w *Window) loadState() error {
+	stateBytes, err := w.cache.Get(w.stateKey)
+	if err == types.ErrKeyNotFound {
+		w.state = newWindowState()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	state := newWindowState()
+	if err = json.Unmarshal(stateBytes, state); err != nil {
+		return fmt.Errorf("failed to parse window state: %v", err)
+	}
+	w.state = state
+	return nil
+}
+
+// storeState writes the current window state to the cache.
+func (w *Window) storeState() error {
+	stateBytes, err := json.Marshal(w.state)
+	if err != nil {
+		return err
+	}
+	return w.cache.Set(w.stateKey, stateBytes)
+}
+
+// latestStart returns the start time of the latest window that a timestamp
+// belongs to.
+func (w *Window) latestStart(t time.Time) time.Time {
+	nanos := t.UnixNano()
+	return time.Unix(0, nanos-nanos%int64(w.slide)).UTC()
+}
+
+// closeWindows closes all windows that end at or before a timestamp, and
+// returns an aggregate message for each key of the closed windows.
+func (w *Window) closeWindows(t time.Time) []types.Message {
+	if closed := w.latestStart(t.Add(-w.size)).Add(w.size); closed.After(w.state.Closed) {
+		w.state.Closed = closed
+	}
+
+	var msgs []types.Message
+	i := 0
+	for ; i < len(w.state.Windows); i++ {
+		bucket := w.state.Windows[i]
+		if bucket.Start.Add(w.size).After(w.state.Closed) {
+			break
+		}
+		msgs = append(msgs, w.aggregateMessages(bucket)...)
+		w.mClosed.Incr(1)
+	}
+	w.state.Windows = w.state.Windows[i:]
+	return msgs
+}
+
+// aggregateMessages creates an aggregate message for each key of a window.
+func (w *Window) aggregateMessages(bucket *windowBucket) []types.Message {
+	msgs := make([]types.Message, 0, len(bucket.Keys))
+	start := bucket.Start.Format(time.RFC3339Nano)
+	end := bucket.Start.Add(w.size).Format(time.RFC3339Nano)
+
+	for _, k := range bucket.Keys {
+		agg := bucket.Aggregates[k]
+		result := map[string]interface{}{
+			"key":          k,
+			"window_start": start,
+			"window_end":   end,
+			"count":        agg.Count,
+		}
+		if w.isSum {
+			result["sum"] = agg.Sum
+		} else if agg.Values != nil {
+			result["values"] = agg.Values
+		}
+
+		part := message.NewPart(nil)
+		if err := part.SetJSON(result); err != nil {
+			w.mErr.Incr(1)
+			w.log.Errorf("Failed to serialise aggregate for key '%v': %v\n", k, err)
+			continue
+		}
+		newMsg := message.New(nil)
+		newMsg.Append(part)
+		msgs = append(msgs, newMsg)
+	}
+	return msgs
+}
+
+// openWindows returns all windows containing a timestamp that are still open,
+// creating them when they do not yet exist.
+func (w *Window) openWindows(t time.Time) []*windowBucket {
+	var buckets []*windowBucket
+	for start := w.latestStart(t); start.Add(w.size).After(t); start = start.Add(-w.slide) {
+		if !start.Add(w.size).After(w.state.Closed) {
+			break
+		}
+
+		i := 0
+		for ; i < len(w.state.Windows); i++ {
+			if !w.state.Windows[i].Start.Before(start) {
+				break
+			}
+		}
+		if i == len(w.state.Windows) || !w.state.Windows[i].Start.Equal(start) {
+			w.state.Windows = append(w.state.Windows, nil)
+			copy(w.state.Windows[i+1:], w.state.Windows[i:])
+			w.state.Windows[i] = newWindowBucket(start)
+		}
+		buckets = append(buckets, w.state.Windows[i])
+	}
+	return buckets
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (w *Window) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	w.mCount.Incr(1)
+
+	w.stateMut.Lock()
+	defer w.stateMut.Unlock()
+
+	// The state is loaded for every message as it may have been modified by
+	// another processor sharing the same state_key.
+	if err := w.loadState(); err != nil {
+		w.mErrCache.Incr(1)
+		w.log.Errorf("Failed to load window state: %v\n", err)
+		return nil, response.NewError(err)
+	}
+
+	var msgs []types.Message
+	msg.Iter(func(i int, part types.Part) error {
+		lMsg := message.Lock(msg, i)
+
+		now := w.clock.Now()
+		ts := now
+		if w.useTS {
+			tsStr := w.timestamp.Get(lMsg)
+			var err error
+			if ts, err = time.Parse(time.RFC3339Nano, tsStr); err != nil {
+				w.mErrTS.Incr(1)
+				w.log.Debugf("Failed to parse timestamp '%v': %v\n", tsStr, err)
+				return nil
+			}
+		}
+
+		if ts.Before(w.state.Closed) {
+			w.mLate.Incr(1)
+			if w.lateCurrent {
+				ts = w.state.Latest
+			}
+		} else {
+			if ts.After(w.state.Latest) {
+				w.state.Latest = ts
+				w.state.Received = now
+			}
+			msgs = append(msgs, w.closeWindows(ts)...)
+		}
+
+		key := w.key.Get(lMsg)
+		for _, bucket := range w.openWindows(ts) {
+			agg, exists := bucket.Aggregates[key]
+			if !exists {
+				agg = &windowAggregate{}
+				bucket.Aggregates[key] = agg
+				bucket.Keys = append(bucket.Keys, key)
+			}
+			agg.Count++
+			w.aggregate(agg, part)
+		}
+		return nil
+	})
+
+	if err := w.storeState(); err != nil {
+		w.mErrCache.Incr(1)
+		w.log.Errorf("Failed to store window state: %v\n", err)
+		return nil, response.NewError(err)
+	}
+	w.updateGauges()
+
+	if len(msgs) == 0 {
+		w.mDropped.Incr(1)
+		return nil, response.NewAck()
+	}
+
+	w.mSent.Incr(int64(len(msgs)))
+	w.mSentParts.Incr(int64(len(msgs)))
+	return msgs, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Flush closes all windows that have ended according to the time elapsed since
+// the latest timestamp was received, and returns an aggregate message for each
+// key of the closed windows. This allows windows to close when no new messages
+// arrive.
+func (w *Window) Flush() []types.Message {
+	w.stateMut.Lock()
+	defer w.stateMut.Unlock()
+
+	if err := w.loadState(); err != nil {
+		w.mErrCache.Incr(1)
+		w.log.Errorf("Failed to load window state: %v\n", err)
+		return nil
+	}
+	if w.state.Latest.IsZero() {
+		return nil
+	}
+
+	t := w.state.Latest.Add(w.clock.Now().Sub(w.state.Received))
+	msgs := w.closeWindows(t)
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	// If the state cannot be stored the closed windows remain open in the
+	// cache, and therefore the aggregates are not sent in order to avoid
+	// sending them twice.
+	if err := w.storeState(); err != nil {
+		w.mErrCache.Incr(1)
+		w.log.Errorf("Failed to store window state: %v\n", err)
+		return nil
+	}
+	w.updateGauges()
+
+	w.mSent.Incr(int64(len(msgs)))
+	w.mSentParts.Incr(int64(len(msgs)))
+	return msgs
+}
+
+// updateGauges sets the gauges of the currently open windows.
+func (w *Window) updateGauges() {
+	var windowParts int64
+	for _, bucket := range w.state.Windows {
+		for _, agg := range bucket.Aggregates {
+			windowParts += agg.Count
+		}
+	}
+	w.mWindowParts.Set(windowParts)
+	w.mWindowsOpen.Set(int64(len(w.state.Windows)))
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,529 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package processor
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/cache"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/clock"
+)
+
+//------------------------------------------------------------------------------
+
+func newWindowTestMgr(t *testing.T) *fakeMgr {
+	t.Helper()
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+}
+
+func newWindowTestProc(t *testing.T, conf Config, mgr types.Manager, fakeClock *clock.Fake) *Window {
+	t.Helper()
+	proc, err := New(conf, mgr, log.New(os.Stdout, log.Config{LogLevel: "NONE"}), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := proc.(*Window)
+	if fakeClock != nil {
+		w.clock = fakeClock
+	}
+	return w
+}
+
+func windowResults(t *testing.T, msgs []types.Message) []string {
+	t.Helper()
+	results := []string{}
+	for _, m := range msgs {
+		if m.Len() != 1 {
+			t.Fatalf("Wrong count of aggregate parts: %v", m.Len())
+		}
+		results = append(results, string(m.Get(0).Get()))
+	}
+	return results
+}
+
+func TestWindowBadConfig(t *testing.T) {
+	mgr := newWindowTestMgr(t)
+
+	tests := map[string]func(c *WindowConfig){
+		"missing cache": func(c *WindowConfig) {
+			c.Cache = "doesnotexist"
+		},
+		"bad size": func(c *WindowConfig) {
+			c.Size = "nope"
+		},
+		"zero size": func(c *WindowConfig) {
+			c.Size = "0s"
+		},
+		"bad aggregate": func(c *WindowConfig) {
+			c.Aggregate = "average"
+		},
+		"empty sum path": func(c *WindowConfig) {
+			c.Aggregate = "sum:"
+		},
+		"bad late arrivals": func(c *WindowConfig) {
+			c.LateArrivals = "later"
+		},
+		"empty state key": func(c *WindowConfig) {
+			c.StateKey = ""
+		},
+		"bad slide": func(c *WindowConfig) {
+			c.Slide = "nope"
+		},
+		"zero slide": func(c *WindowConfig) {
+			c.Slide = "0s"
+		},
+		"slide exceeds size": func(c *WindowConfig) {
+			c.Slide = "2m"
+		},
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		conf.Type = TypeWindow
+		conf.Window.Cache = "foocache"
+		fn(&conf.Window)
+		if _, err := New(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+			t.Errorf("%v: expected error", name)
+		}
+	}
+}
+
+func TestWindowCount(t *testing.T) {
+	fakeClock := clock.NewFake(time.Date(2018, 11, 26, 10, 0, 5, 0, time.UTC))
+
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Cache = "foocache"
+	conf.Window.Key = "${!json_field:user}"
+
+	proc := newWindowTestProc(t, conf, newWindowTestMgr(t), fakeClock)
+
+	for _, doc := range []string{`{"user":"b"}`, `{"user":"a"}`, `{"user":"b"}`} {
+		msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(doc)}))
+		if len(msgs) > 0 {
+			t.Fatalf("Unexpected aggregates: %s", msgs)
+		}
+		if res == nil || res.Error() != nil || res.SkipAck() {
+			t.Fatalf("Expected ack response: %v", res)
+		}
+	}
+
+	fakeClock.Add(time.Minute)
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`{"user":"c"}`)}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp := []string{
+		`{"count":2,"key":"b","window_end":"2018-11-26T10:01:00Z","window_start":"2018-11-26T10:00:00Z"}`,
+		`{"count":1,"key":"a","window_end":"2018-11-26T10:01:00Z","window_start":"2018-11-26T10:00:00Z"}`,
+	}
+	if act := windowResults(t, msgs); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong aggregates: %v != %v", act, exp)
+	}
+
+	fakeClock.Add(time.Minute)
+	msgs, res = proc.ProcessMessage(message.New([][]byte{[]byte(`{"user":"a"}`)}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp = []string{
+		`{"count":1,"key":"c","window_end":"2018-11-26T10:02:00Z","window_start":"2018-11-26T10:01:00Z"}`,
+	}
+	if act := windowResults(t, msgs); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong aggregates: %v != %v", act, exp)
+	}
+}
+
+func TestWindowFlush(t *testing.T) {
+	fakeClock := clock.NewFake(time.Date(2018, 11, 26, 10, 0, 5, 0, time.UTC))
+
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Cache = "foocache"
+	conf.Window.Key = "${!json_field:user}"
+
+	proc := newWindowTestProc(t, conf, newWindowTestMgr(t), fakeClock)
+
+	if msgs := proc.Flush(); len(msgs) > 0 {
+		t.Fatalf("Unexpected aggregates: %s", msgs)
+	}
+
+	for _, doc := range []string{`{"user":"b"}`, `{"user":"a"}`, `{"user":"b"}`} {
+		if msgs, _ := proc.ProcessMessage(message.New([][]byte{[]byte(doc)})); len(msgs) > 0 {
+			t.Fatalf("Unexpected aggregates: %s", msgs)
+		}
+	}
+
+	fakeClock.Add(time.Second * 54)
+	if msgs := proc.Flush(); len(msgs) > 0 {
+		t.Fatalf("Unexpected aggregates: %s", msgs)
+	}
+
+	// No further messages arrive after the end of the window.
+	fakeClock.Add(time.Second)
+	exp := []string{
+		`{"count":2,"key":"b","window_end":"2018-11-26T10:01:00Z","window_start":"2018-11-26T10:00:00Z"}`,
+		`{"count":1,"key":"a","window_end":"2018-11-26T10:01:00Z","window_start":"2018-11-26T10:00:00Z"}`,
+	}
+	if act := windowResults(t, proc.Flush()); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong aggregates: %v != %v", act, exp)
+	}
+
+	fakeClock.Add(time.Minute)
+	if msgs := proc.Flush(); len(msgs) > 0 {
+		t.Fatalf("Unexpected aggregates: %s", msgs)
+	}
+
+	// The flushed window is closed and is not emitted again.
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`{"user":"c"}`)}))
+	if res == nil || res.Error() != nil {
+		t.Fatalf("Expected ack response: %v", res)
+	}
+	if len(msgs) > 0 {
+		t.Fatalf("Unexpected aggregates: %s", msgs)
+	}
+}
+
+func TestWindowFlushTimestamp(t *testing.T) {
+	fakeClock := clock.NewFake(time.Date(2018, 11, 27, 0, 0, 0, 0, time.UTC))
+
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Cache = "foocache"
+	conf.Window.Size = "10s"
+	conf.Window.Timestamp = "${!json_field:ts}"
+
+	proc := newWindowTestProc(t, conf, newWindowTestMgr(t), fakeClock)
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"ts":"2018-11-26T10:00:01Z"}`),
+		[]byte(`{"ts":"2018-11-26T10:00:07Z"}`),
+	}))
+	if len(msgs) > 0 {
+		t.Fatalf("Unexpected aggregates: %s", msgs)
+	}
+
+	fakeClock.Add(time.Second * 2)
+	if msgs = proc.Flush(); len(msgs) > 0 {
+		t.Fatalf("Unexpected aggregates: %s", msgs)
+	}
+
+	fakeClock.Add(time.Second)
+	exp := []string{
+		`{"count":2,"key":"","window_end":"2018-11-26T10:00:10Z","window_start":"2018-11-26T10:00:00Z"}`,
+	}
+	if act := windowResults(t, proc.Flush()); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong aggregates: %v != %v", act, exp)
+	}
+}
+
+func TestWindowSumLateDrop(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Cache = "foocache"
+	conf.Window.Size = "10s"
+	conf.Window.Timestamp = "${!json_field:ts}"
+	conf.Window.Aggregate = "sum:order.total"
+
+	proc := newWindowTestProc(t, conf, newWindowTestMgr(t), nil)
+
+	input := message.New([][]byte{
+		[]byte(`{"ts":"2018-11-26T10:00:01Z","order":{"total":1.5}}`),
+		[]byte(`{"ts":"2018-11-26T10:00:09Z","order":{"total":2}}`),
+		[]byte(`{"ts":"2018-11-26T10:00:03Z","order":{"total":"nope"}}`),
+		[]byte(`{"ts":"not a timestamp","order":{"total":100}}`),
+		[]byte(`{"ts":"2018-11-26T10:00:12Z","order":{"total":3}}`),
+		[]byte(`{"ts":"2018-11-26T10:00:05Z","order":{"total":50}}`),
+	})
+	msgs, res := proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp := []string{
+		`{"count":3,"key":"","sum":3.5,"window_end":"2018-11-26T10:00:10Z","window_start":"2018-11-26T10:00:00Z"}`,
+	}
+	if act := windowResults(t, msgs); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong aggregates: %v != %v", act, exp)
+	}
+
+	msgs, res = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"ts":"2018-11-26T10:00:20Z","order":{"total":1}}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp = []string{
+		`{"count":1,"key":"","sum":3,"window_end":"2018-11-26T10:00:20Z","window_start":"2018-11-26T10:00:10Z"}`,
+	}
+	if act := windowResults(t, msgs); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong aggregates: %v != %v", act, exp)
+	}
+}
+
+func TestWindowCollectLateCurrent(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Cache = "foocache"
+	conf.Window.Size = "10s"
+	conf.Window.Timestamp = "${!metadata:ts}"
+	conf.Window.Aggregate = "collect"
+	conf.Window.LateArrivals = "current"
+
+	proc := newWindowTestProc(t, conf, newWindowTestMgr(t), nil)
+
+	newPart := func(content, ts string) types.Part {
+		p := message.NewPart([]byte(content))
+		p.Metadata().Set("ts", ts)
+		return p
+	}
+
+	input := message.New(nil)
+	input.Append(
+		newPart(`{"id":1}`, "2018-11-26T10:00:01Z"),
+		newPart(`{"id":2}`, "2018-11-26T10:00:11Z"),
+		newPart(`not json`, "2018-11-26T10:00:02Z"),
+	)
+	msgs, res := proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp := []string{
+		`{"count":1,"key":"","values":[{"id":1}],"window_end":"2018-11-26T10:00:10Z","window_start":"2018-11-26T10:00:00Z"}`,
+	}
+	if act := windowResults(t, msgs); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong aggregates: %v != %v", act, exp)
+	}
+
+	input = message.New(nil)
+	input.Append(newPart(`{"id":3}`, "2018-11-26T10:00:25Z"))
+	if msgs, res = proc.ProcessMessage(input); res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp = []string{
+		`{"count":2,"key":"","values":[{"id":2},"not json"],"window_end":"2018-11-26T10:00:20Z","window_start":"2018-11-26T10:00:10Z"}`,
+	}
+	if act := windowResults(t, msgs); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong aggregates: %v != %v", act, exp)
+	}
+}
+
+func TestWindowStateRestored(t *testing.T) {
+	fakeClock := clock.NewFake(time.Date(2018, 11, 26, 10, 0, 5, 0, time.UTC))
+
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Cache = "foocache"
+	conf.Window.Key = "${!json_field:user}"
+
+	mgr := newWindowTestMgr(t)
+
+	proc := newWindowTestProc(t, conf, mgr, fakeClock)
+	for _, doc := range []string{`{"user":"a"}`, `{"user":"a"}`} {
+		if _, res := proc.ProcessMessage(message.New([][]byte{[]byte(doc)})); res.Error() != nil {
+			t.Fatal(res.Error())
+		}
+	}
+
+	fakeClock.Add(time.Second * 10)
+	proc = newWindowTestProc(t, conf, mgr, fakeClock)
+	if _, res := proc.ProcessMessage(message.New([][]byte{[]byte(`{"user":"a"}`)})); res.Error() != nil {
+		t.Fatal(res.Error())
+	}
+
+	fakeClock.Add(time.Minute)
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`{"user":"b"}`)}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp := []string{
+		`{"count":3,"key":"a","window_end":"2018-11-26T10:01:00Z","window_start":"2018-11-26T10:00:00Z"}`,
+	}
+	if act := windowResults(t, msgs); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong aggregates: %v != %v", act, exp)
+	}
+}
+
+func TestWindowSharedState(t *testing.T) {
+	fakeClock := clock.NewFake(time.Date(2018, 11, 26, 10, 0, 5, 0, time.UTC))
+
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Cache = "foocache"
+	conf.Window.Key = "${!json_field:user}"
+
+	mgr := newWindowTestMgr(t)
+
+	// Emulates a processor per pipeline thread sharing a state_key.
+	procs := []*Window{
+		newWindowTestProc(t, conf, mgr, fakeClock),
+		newWindowTestProc(t, conf, mgr, fakeClock),
+	}
+
+	for i := 0; i < 50; i++ {
+		for _, proc := range procs {
+			if _, res := proc.ProcessMessage(message.New([][]byte{[]byte(`{"user":"a"}`)})); res.Error() != nil {
+				t.Fatal(res.Error())
+			}
+		}
+	}
+
+	fakeClock.Add(time.Minute)
+	msgs, res := procs[0].ProcessMessage(message.New([][]byte{[]byte(`{"user":"b"}`)}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp := []string{
+		`{"count":100,"key":"a","window_end":"2018-11-26T10:01:00Z","window_start":"2018-11-26T10:00:00Z"}`,
+	}
+	if act := windowResults(t, msgs); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong aggregates: %v != %v", act, exp)
+	}
+}
+
+func TestWindowSliding(t *testing.T) {
+	fakeClock := clock.NewFake(time.Date(2018, 11, 26, 10, 0, 5, 0, time.UTC))
+
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Cache = "foocache"
+	conf.Window.Size = "30s"
+	conf.Window.Slide = "10s"
+
+	proc := newWindowTestProc(t, conf, newWindowTestMgr(t), fakeClock)
+
+	type step struct {
+		advance time.Duration
+		exp     []string
+	}
+	steps := []step{
+		{
+			advance: 0,
+			exp:     []string{},
+		},
+		{
+			advance: time.Second * 10,
+			exp: []string{
+				`{"count":1,"key":"","window_end":"2018-11-26T10:00:10Z","window_start":"2018-11-26T09:59:40Z"}`,
+			},
+		},
+		{
+			advance: time.Second * 10,
+			exp: []string{
+				`{"count":2,"key":"","window_end":"2018-11-26T10:00:20Z","window_start":"2018-11-26T09:59:50Z"}`,
+			},
+		},
+		{
+			advance: time.Second * 35,
+			exp: []string{
+				`{"count":3,"key":"","window_end":"2018-11-26T10:00:30Z","window_start":"2018-11-26T10:00:00Z"}`,
+				`{"count":2,"key":"","window_end":"2018-11-26T10:00:40Z","window_start":"2018-11-26T10:00:10Z"}`,
+				`{"count":1,"key":"","window_end":"2018-11-26T10:00:50Z","window_start":"2018-11-26T10:00:20Z"}`,
+			},
+		},
+	}
+
+	for i, s := range steps {
+		fakeClock.Add(s.advance)
+		msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`{}`)}))
+		if len(msgs) == 0 {
+			if res == nil || res.Error() != nil {
+				t.Fatalf("Step %v: expected ack response: %v", i, res)
+			}
+		} else if res != nil {
+			t.Fatalf("Step %v: unexpected response: %v", i, res)
+		}
+		if act := windowResults(t, msgs); !reflect.DeepEqual(s.exp, act) {
+			t.Errorf("Step %v: wrong aggregates: %v != %v", i, act, s.exp)
+		}
+		if exp, act := 3, len(proc.state.Windows); exp != act {
+			t.Errorf("Step %v: wrong count of open windows: %v != %v", i, act, exp)
+		}
+	}
+}
+
+func TestWindowSlidingLateDrop(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Cache = "foocache"
+	conf.Window.Size = "20s"
+	conf.Window.Slide = "10s"
+	conf.Window.Timestamp = "${!json_field:ts}"
+
+	proc := newWindowTestProc(t, conf, newWindowTestMgr(t), nil)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"ts":"2018-11-26T10:00:05Z"}`),
+		[]byte(`{"ts":"2018-11-26T10:00:12Z"}`),
+		[]byte(`{"ts":"2018-11-26T10:00:07Z"}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp := []string{
+		`{"count":1,"key":"","window_end":"2018-11-26T10:00:10Z","window_start":"2018-11-26T09:59:50Z"}`,
+	}
+	if act := windowResults(t, msgs); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong aggregates: %v != %v", act, exp)
+	}
+
+	msgs, res = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"ts":"2018-11-26T10:00:30Z"}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp = []string{
+		`{"count":3,"key":"","window_end":"2018-11-26T10:00:20Z","window_start":"2018-11-26T10:00:00Z"}`,
+		`{"count":1,"key":"","window_end":"2018-11-26T10:00:30Z","window_start":"2018-11-26T10:00:10Z"}`,
+	}
+	if act := windowResults(t, msgs); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong aggregates: %v != %v", act, exp)
+	}
+	if exp, act := 2, len(proc.state.Windows); exp != act {
+		t.Errorf("Wrong count of open windows: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -50,6 +50,7 @@ var Constructors = map[string]TypeSpec{}
 // String constants representing each ratelimit type.
 const (
 	TypeLocal = "local"
+	TypeRedis = "redis"
 )
 
 //------------------------------------------------------------------------------
@@ -58,6 +59,7 @@ const (
 type Config struct {
 	Type  string      `json:"type" yaml:"type"`
 	Local LocalConfig `json:"local" yaml:"local"`
+	Redis RedisConfig `json:"redis" yaml:"redis"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
@@ -65,6 +67,7 @@ func NewConfig() Config {
 	return Config{
 		Type:  "local",
 		Local: NewLocalConfig(),
+		Redis: NewRedisConfig(),
 	}
 }
 
@@ -158,7 +161,13 @@ we want, but could potentially stress our HTTP server if a batch is large.
 
 However, by using a rate limit we can guarantee that even across parallel
 processing pipelines and variable sized batches we wont hit the service more
-than 500 times per second.`
+than 500 times per second.
+
+Rate limits can be used by the ` + "`http`" + ` processor and the
+` + "`http_client`" + ` output via the ` + "`rate_limit`" + ` field of their
+requests, and the ` + "[`rate_limit`](../processors/README.md#rate_limit)" + `
+processor can be used to cap the throughput of a pipeline regardless of what it
+does.`
 
 // Descriptions returns a formatted string of descriptions for each type.
 func Descriptions() string {
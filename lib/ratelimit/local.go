@@ -29,6 +29,7 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/clock"
 )
 
 //------------------------------------------------------------------------------
@@ -72,6 +73,7 @@ type Local struct {
 
 	size   int
 	period time.Duration
+	clock  clock.Clock
 }
 
 // NewLocal creates a local rate limit from a configuration struct. This type is
@@ -89,12 +91,17 @@ func NewLocal(
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse interval: %v", err)
 	}
+	return newLocal(conf.Local.Count, period, clock.Real()), nil
+}
+
+func newLocal(count int, period time.Duration, c clock.Clock) *Local {
 	return &Local{
-		bucket:      conf.Local.Count,
-		lastRefresh: time.Now(),
-		size:        conf.Local.Count,
+		bucket:      count,
+		lastRefresh: c.Now(),
+		size:        count,
 		period:      period,
-	}, nil
+		clock:       c,
+	}
 }
 
 //------------------------------------------------------------------------------
@@ -109,14 +116,14 @@ func (r *Local) Access() (time.Duration, error) {
 
 	if r.bucket < 0 {
 		r.bucket = 0
-		remaining := r.period - time.Since(r.lastRefresh)
+		remaining := r.period - r.clock.Now().Sub(r.lastRefresh)
 
 		if remaining > 0 {
 			r.mut.Unlock()
 			return remaining, nil
 		}
 		r.bucket = r.size - 1
-		r.lastRefresh = time.Now()
+		r.lastRefresh = r.clock.Now()
 	}
 	r.mut.Unlock()
 	return 0, nil
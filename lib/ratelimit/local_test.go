@@ -27,6 +27,7 @@ import (
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/util/clock"
 )
 
 //------------------------------------------------------------------------------
@@ -108,6 +109,45 @@ func TestLocalRateLimitRefresh(t *testing.T) {
 	}
 }
 
+func TestLocalRateLimitFakeClock(t *testing.T) {
+	fakeClock := clock.NewFake(time.Unix(0, 0))
+	rl := newLocal(2, time.Second, fakeClock)
+
+	for i := 0; i < 2; i++ {
+		if period, _ := rl.Access(); period != 0 {
+			t.Errorf("Rate limited on get %v", i)
+		}
+	}
+
+	if exp, act := time.Second, mustAccess(t, rl); exp != act {
+		t.Errorf("Wrong period: %v != %v", act, exp)
+	}
+
+	fakeClock.Add(time.Millisecond * 400)
+	if exp, act := time.Millisecond*600, mustAccess(t, rl); exp != act {
+		t.Errorf("Wrong period: %v != %v", act, exp)
+	}
+
+	fakeClock.Add(time.Millisecond * 600)
+	for i := 0; i < 2; i++ {
+		if period, _ := rl.Access(); period != 0 {
+			t.Errorf("Rate limited on get %v after refresh", i)
+		}
+	}
+	if exp, act := time.Second, mustAccess(t, rl); exp != act {
+		t.Errorf("Wrong period: %v != %v", act, exp)
+	}
+}
+
+func mustAccess(t *testing.T, rl *Local) time.Duration {
+	t.Helper()
+	period, err := rl.Access()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return period
+}
+
 //------------------------------------------------------------------------------
 
 func BenchmarkRateLimit(b *testing.B) {
@@ -0,0 +1,201 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/go-redis/redis"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeRedis] = TypeSpec{
+		constructor: NewRedis,
+		description: `
+The redis rate limit is an X every Y type rate limit where the state is held
+within a Redis server, allowing the limit to be shared across multiple Benthos
+instances. All instances configured with the same ` + "`key`" + ` share a
+limit of ` + "`count`" + ` accesses per ` + "`interval`" + `.
+
+Accesses are counted within fixed windows that begin with the first access
+after the previous window has expired, and are tracked atomically with a Lua
+script. When the limit is reached the time remaining until the window expires
+is returned as the period to wait.
+
+When ` + "`cluster`" + ` is set to ` + "`true`" + ` the ` + "`url`" + ` is
+used as a seed node of a Redis Cluster.
+
+If the Redis server cannot be reached the rate limit fails closed by default,
+returning an error which results in a retry after a short wait. When
+` + "`fail_open`" + ` is set to ` + "`true`" + ` access is instead granted.
+Either way the metric ` + "`rate_limit.redis.error`" + ` is incremented.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// RedisConfig is a config struct containing fields for a Redis rate limit.
+type RedisConfig struct {
+	URL      string `json:"url" yaml:"url"`
+	Cluster  bool   `json:"cluster" yaml:"cluster"`
+	Key      string `json:"key" yaml:"key"`
+	Count    int    `json:"count" yaml:"count"`
+	Interval string `json:"interval" yaml:"interval"`
+	FailOpen bool   `json:"fail_open" yaml:"fail_open"`
+}
+
+// NewRedisConfig returns a Redis rate limit configuration struct with default
+// values.
+func NewRedisConfig() RedisConfig {
+	return RedisConfig{
+		URL:      "tcp://localhost:6379",
+		Cluster:  false,
+		Key:      "benthos_rate_limit",
+		Count:    1000,
+		Interval: "1s",
+		FailOpen: false,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// redisRateLimitScript increments the count of the current window, starting a
+// new window if one does not exist, and returns the number of milliseconds
+// remaining in the window if the count exceeds the limit, or zero otherwise.
+var redisRateLimitScript = redis.NewScript(`
+local current = redis.call("INCR", KEYS[1])
+if current == 1 then
+  redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+if current <= tonumber(ARGV[1]) then
+  return 0
+end
+local remaining = redis.call("PTTL", KEYS[1])
+if remaining < 0 then
+  redis.call("PEXPIRE", KEYS[1], ARGV[2])
+  return tonumber(ARGV[2])
+end
+if remaining == 0 then
+  return 1
+end
+return remaining
+`)
+
+// Redis is a rate limit where the state is held within a Redis server, which
+// allows it to be shared across multiple instances of a service.
+type Redis struct {
+	client   redis.UniversalClient
+	key      string
+	count    int
+	period   time.Duration
+	failOpen bool
+
+	log  log.Modular
+	mErr metrics.StatCounter
+}
+
+// NewRedis creates a Redis rate limit from a configuration struct. This type is
+// safe to share and call from parallel goroutines.
+func NewRedis(
+	conf Config,
+	mgr types.Manager,
+	logger log.Modular,
+	stats metrics.Type,
+) (types.RateLimit, error) {
+	if conf.Redis.Count <= 0 {
+		return nil, errors.New("count must be larger than zero")
+	}
+	if len(conf.Redis.Key) == 0 {
+		return nil, errors.New("a key must be specified")
+	}
+	period, err := time.ParseDuration(conf.Redis.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse interval: %v", err)
+	}
+	if period < time.Millisecond {
+		return nil, errors.New("interval must be at least one millisecond")
+	}
+
+	url, err := url.Parse(conf.Redis.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	var pass string
+	if url.User != nil {
+		pass, _ = url.User.Password()
+	}
+
+	var client redis.UniversalClient
+	if conf.Redis.Cluster {
+		client = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:    []string{url.Host},
+			Password: pass,
+		})
+	} else {
+		client = redis.NewClient(&redis.Options{
+			Addr:     url.Host,
+			Network:  url.Scheme,
+			Password: pass,
+		})
+	}
+
+	return &Redis{
+		client:   client,
+		key:      conf.Redis.Key,
+		count:    conf.Redis.Count,
+		period:   period,
+		failOpen: conf.Redis.FailOpen,
+		log:      logger.NewModule(".rate_limit.redis"),
+		mErr:     stats.GetCounter("rate_limit.redis.error"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Access the rate limited resource. Returns a duration or an error if the rate
+// limit check fails. The returned duration is either zero (meaning the resource
+// can be accessed) or a reasonable length of time to wait before requesting
+// again.
+func (r *Redis) Access() (time.Duration, error) {
+	remaining, err := redisRateLimitScript.Run(
+		r.client, []string{r.key}, r.count, int64(r.period/time.Millisecond),
+	).Int64()
+	if err != nil {
+		r.mErr.Incr(1)
+		if r.failOpen {
+			r.log.Debugf("Failed to access rate limit, failing open: %v\n", err)
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to access rate limit: %v", err)
+	}
+	return time.Duration(remaining) * time.Millisecond, nil
+}
+
+//------------------------------------------------------------------------------
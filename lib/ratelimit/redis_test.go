@@ -0,0 +1,234 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ratelimit
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/ory/dockertest"
+)
+
+//------------------------------------------------------------------------------
+
+func TestRedisRateLimitConfErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeRedis
+	conf.Redis.Count = -1
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("expected error from bad count")
+	}
+
+	conf = NewConfig()
+	conf.Type = TypeRedis
+	conf.Redis.Interval = "nope"
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("expected error from bad interval")
+	}
+
+	conf = NewConfig()
+	conf.Type = TypeRedis
+	conf.Redis.Key = ""
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("expected error from empty key")
+	}
+}
+
+func TestRedisRateLimitConnectionErrors(t *testing.T) {
+	for _, failOpen := range []bool{false, true} {
+		conf := NewConfig()
+		conf.Type = TypeRedis
+		conf.Redis.URL = "tcp://localhost:1"
+		conf.Redis.FailOpen = failOpen
+
+		stats := metrics.NewLocal()
+		rl, err := New(conf, nil, log.Noop(), stats)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		period, err := rl.Access()
+		if failOpen && err != nil {
+			t.Errorf("Expected no error when failing open: %v", err)
+		}
+		if !failOpen && err == nil {
+			t.Error("Expected error when failing closed")
+		}
+		if period != 0 {
+			t.Errorf("Unexpected period: %v", period)
+		}
+		if exp, act := int64(1), stats.GetCounters()["rate_limit.redis.error"]; exp != act {
+			t.Errorf("Wrong error count: %v != %v", act, exp)
+		}
+	}
+}
+
+func TestRedisRateLimitIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Skipf("Could not connect to docker: %s", err)
+	}
+
+	resource, err := pool.Run("redis", "latest", nil)
+	if err != nil {
+		t.Fatalf("Could not start resource: %s", err)
+	}
+
+	url := fmt.Sprintf("tcp://localhost:%v", resource.GetPort("6379/tcp"))
+
+	if err = pool.Retry(func() error {
+		conf := NewConfig()
+		conf.Redis.URL = url
+
+		r, cErr := NewRedis(conf, nil, log.Noop(), metrics.Noop())
+		if cErr != nil {
+			return cErr
+		}
+		_, cErr = r.Access()
+		return cErr
+	}); err != nil {
+		t.Fatalf("Could not connect to docker resource: %s", err)
+	}
+
+	defer func() {
+		if err = pool.Purge(resource); err != nil {
+			t.Logf("Failed to clean up docker resource: %v", err)
+		}
+	}()
+
+	t.Run("TestRedisRateLimitBasic", func(te *testing.T) {
+		testRedisRateLimitBasic(url, te)
+	})
+	t.Run("TestRedisRateLimitRefresh", func(te *testing.T) {
+		testRedisRateLimitRefresh(url, te)
+	})
+	t.Run("TestRedisRateLimitShared", func(te *testing.T) {
+		testRedisRateLimitShared(url, te)
+	})
+}
+
+func testRedisRateLimitBasic(url string, t *testing.T) {
+	conf := NewConfig()
+	conf.Redis.URL = url
+	conf.Redis.Key = "benthos_test_basic"
+	conf.Redis.Count = 10
+	conf.Redis.Interval = "1s"
+
+	rl, err := NewRedis(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < conf.Redis.Count; i++ {
+		period, err := rl.Access()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if period > 0 {
+			t.Errorf("Period above zero: %v", period)
+		}
+	}
+
+	if period, err := rl.Access(); err != nil {
+		t.Fatal(err)
+	} else if period == 0 {
+		t.Error("Expected limit on final request")
+	} else if period > time.Second {
+		t.Errorf("Period beyond interval: %v", period)
+	}
+}
+
+func testRedisRateLimitRefresh(url string, t *testing.T) {
+	conf := NewConfig()
+	conf.Redis.URL = url
+	conf.Redis.Key = "benthos_test_refresh"
+	conf.Redis.Count = 10
+	conf.Redis.Interval = "50ms"
+
+	rl, err := NewRedis(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < conf.Redis.Count; i++ {
+		if period, _ := rl.Access(); period != 0 {
+			t.Errorf("Rate limited on get %v", i)
+		}
+	}
+
+	period, _ := rl.Access()
+	if period == 0 {
+		t.Fatal("Expected limit on final request")
+	}
+
+	<-time.After(period)
+
+	for i := 0; i < conf.Redis.Count; i++ {
+		if period, _ := rl.Access(); period != 0 {
+			t.Errorf("Rate limited on get %v", i)
+		}
+	}
+}
+
+func testRedisRateLimitShared(url string, t *testing.T) {
+	conf := NewConfig()
+	conf.Redis.URL = url
+	conf.Redis.Key = "benthos_test_shared"
+	conf.Redis.Count = 100
+	conf.Redis.Interval = "1h"
+
+	// Multiple rate limits sharing a key must share the limit.
+	var accessed int
+	var mut sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		rl, err := NewRedis(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if period, err := rl.Access(); err == nil && period == 0 {
+					mut.Lock()
+					accessed++
+					mut.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if exp, act := conf.Redis.Count, accessed; exp != act {
+		t.Errorf("Wrong count of accesses: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
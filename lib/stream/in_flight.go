@@ -0,0 +1,302 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package stream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// inFlightTracker sits between the input layer of a stream and the remaining
+// layers, counting transactions that have been dispatched but not yet
+// acknowledged. When draining it stops consuming new transactions from the
+// input layer whilst continuing to propagate responses of transactions that
+// are already in flight, allowing inputs to acknowledge them before closing.
+//
+// The tracker can also be paused, during which it stops consuming new
+// transactions from the input layer until it is resumed.
+type inFlightTracker struct {
+	mut      sync.Mutex
+	count    int
+	stopped  bool
+	drained  bool
+	draining bool
+	closed   bool
+	paused   bool
+
+	transactionsOut chan types.Transaction
+
+	// Closed and replaced each time the tracker is paused or resumed.
+	pauseChangedChan chan struct{}
+
+	drainChan   chan struct{}
+	drainedChan chan struct{}
+	closeChan   chan struct{}
+}
+
+// newInFlightTracker creates an inFlightTracker that consumes transactions
+// from a channel and forwards them to its own transaction channel.
+func newInFlightTracker(transactionsIn <-chan types.Transaction) *inFlightTracker {
+	t := &inFlightTracker{
+		transactionsOut:  make(chan types.Transaction),
+		pauseChangedChan: make(chan struct{}),
+		drainChan:        make(chan struct{}),
+		drainedChan:      make(chan struct{}),
+		closeChan:        make(chan struct{}),
+	}
+	go t.loop(transactionsIn)
+	return t
+}
+
+//------------------------------------------------------------------------------
+
+func (t *inFlightTracker) loop(transactionsIn <-chan types.Transaction) {
+	defer func() {
+		t.stop()
+		close(t.transactionsOut)
+	}()
+
+	for {
+		t.mut.Lock()
+		inChan, pauseChangedChan := transactionsIn, t.pauseChangedChan
+		if t.paused {
+			inChan = nil
+		}
+		t.mut.Unlock()
+
+		var tran types.Transaction
+		var open bool
+		select {
+		case tran, open = <-inChan:
+			if !open {
+				return
+			}
+		case <-pauseChangedChan:
+			continue
+		case <-t.drainChan:
+			// Stop consuming but hold the downstream layers open until we are
+			// told to close.
+			t.stop()
+			<-t.closeChan
+			return
+		case <-t.closeChan:
+			return
+		}
+
+		// Buffered so that downstream layers are never blocked by a response
+		// that has been abandoned.
+		resChan := make(chan types.Response, 1)
+
+		t.mut.Lock()
+		t.count++
+		t.mut.Unlock()
+
+		select {
+		case t.transactionsOut <- types.NewTransaction(tran.Payload, resChan):
+		case <-t.drainChan:
+			// The transaction was never dispatched and will therefore never be
+			// acknowledged by the input.
+			t.done()
+			t.stop()
+			<-t.closeChan
+			return
+		case <-t.closeChan:
+			t.done()
+			return
+		}
+
+		go t.relay(tran.ResponseChan, resChan)
+	}
+}
+
+// relay forwards the response of a dispatched transaction back to the input
+// that sent it.
+func (t *inFlightTracker) relay(resChanOut chan<- types.Response, resChanIn <-chan types.Response) {
+	defer t.done()
+
+	var res types.Response
+	select {
+	case res = <-resChanIn:
+	case <-t.closeChan:
+		return
+	}
+	select {
+	case resChanOut <- res:
+	case <-t.closeChan:
+	}
+}
+
+// stop marks the tracker as no longer consuming transactions.
+func (t *inFlightTracker) stop() {
+	t.mut.Lock()
+	t.stopped = true
+	t.checkDrained()
+	t.mut.Unlock()
+}
+
+// done marks an in-flight transaction as resolved.
+func (t *inFlightTracker) done() {
+	t.mut.Lock()
+	t.count--
+	t.checkDrained()
+	t.mut.Unlock()
+}
+
+// checkDrained must be called whilst holding the mutex.
+func (t *inFlightTracker) checkDrained() {
+	if t.stopped && t.count == 0 && !t.drained {
+		t.drained = true
+		close(t.drainedChan)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// TransactionChan returns the channel used for consuming tracked
+// transactions.
+func (t *inFlightTracker) TransactionChan() <-chan types.Transaction {
+	return t.transactionsOut
+}
+
+// Pause instructs the tracker to stop consuming new transactions from the
+// input layer until Resume is called. Transactions already in flight continue
+// to be resolved.
+func (t *inFlightTracker) Pause() {
+	t.setPaused(true)
+}
+
+// Resume instructs a paused tracker to continue consuming transactions from
+// the input layer.
+func (t *inFlightTracker) Resume() {
+	t.setPaused(false)
+}
+
+func (t *inFlightTracker) setPaused(paused bool) {
+	t.mut.Lock()
+	if t.paused != paused {
+		t.paused = paused
+		close(t.pauseChangedChan)
+		t.pauseChangedChan = make(chan struct{})
+	}
+	t.mut.Unlock()
+}
+
+// Paused returns whether the tracker is currently paused.
+func (t *inFlightTracker) Paused() bool {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	return t.paused
+}
+
+// Count returns the number of transactions currently in flight.
+func (t *inFlightTracker) Count() int {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	return t.count
+}
+
+// StopConsuming instructs the tracker to stop consuming new transactions from
+// the input layer. Transactions already in flight continue to be resolved and
+// the downstream layers remain open until CloseAsync is called.
+func (t *inFlightTracker) StopConsuming() {
+	t.mut.Lock()
+	if !t.draining {
+		t.draining = true
+		close(t.drainChan)
+	}
+	t.mut.Unlock()
+}
+
+// WaitForDrained blocks until the tracker has stopped consuming and all
+// in-flight transactions have been resolved, or the timeout occurs.
+func (t *inFlightTracker) WaitForDrained(timeout time.Duration) error {
+	select {
+	case <-t.drainedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+// CloseAsync closes the transaction channel of the tracker, which shuts down
+// the downstream layers by proxy, and abandons any pending responses.
+func (t *inFlightTracker) CloseAsync() {
+	t.mut.Lock()
+	if !t.closed {
+		t.closed = true
+		close(t.closeChan)
+	}
+	t.mut.Unlock()
+}
+
+//------------------------------------------------------------------------------
+
+// disconnectCheckInterval is the interval at which the connection state of an
+// output is checked when pausing on output disconnect.
+const disconnectCheckInterval = time.Millisecond * 100
+
+// pauseOnDisconnect pauses the tracker once the output has been disconnected
+// for longer than a grace period, and resumes it once the output reconnects.
+// Blocks until the tracker is closed.
+func (t *inFlightTracker) pauseOnDisconnect(
+	output interface{}, grace time.Duration, log log.Modular, stats metrics.Type,
+) {
+	mPaused := stats.GetGauge("input.paused")
+
+	ticker := time.NewTicker(disconnectCheckInterval)
+	defer ticker.Stop()
+
+	var disconnectedSince time.Time
+	for {
+		select {
+		case <-ticker.C:
+		case <-t.closeChan:
+			return
+		}
+
+		if types.IsConnected(output) {
+			disconnectedSince = time.Time{}
+			if t.Paused() {
+				log.Infoln("Output has reconnected, resuming input")
+				t.Resume()
+				mPaused.Set(0)
+			}
+			continue
+		}
+
+		if disconnectedSince.IsZero() {
+			disconnectedSince = time.Now()
+		}
+		if !t.Paused() && time.Since(disconnectedSince) >= grace {
+			log.Warnf("Output has been disconnected for longer than %v, pausing input\n", grace)
+			t.Pause()
+			mPaused.Set(1)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
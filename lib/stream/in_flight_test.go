@@ -0,0 +1,232 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package stream
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func TestInFlightTrackerDrain(t *testing.T) {
+	tranChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	tracker := newInFlightTracker(tranChan)
+
+	select {
+	case tranChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	var tran types.Transaction
+	select {
+	case tran = <-tracker.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	if exp, act := "foo", string(tran.Payload.Get(0).Get()); exp != act {
+		t.Errorf("Wrong payload: %v != %v", act, exp)
+	}
+	if exp, act := 1, tracker.Count(); exp != act {
+		t.Errorf("Wrong in-flight count: %v != %v", act, exp)
+	}
+
+	tracker.StopConsuming()
+	if err := tracker.WaitForDrained(time.Millisecond * 50); err != types.ErrTimeout {
+		t.Errorf("Expected timeout whilst in flight, received: %v", err)
+	}
+
+	// New transactions should not be consumed whilst draining.
+	select {
+	case tranChan <- types.NewTransaction(message.New([][]byte{[]byte("bar")}), resChan):
+		t.Error("Transaction consumed whilst draining")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	select {
+	case tran.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	select {
+	case res := <-resChan:
+		if res.Error() != nil {
+			t.Error(res.Error())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	if err := tracker.WaitForDrained(time.Second); err != nil {
+		t.Error(err)
+	}
+	if exp, act := 0, tracker.Count(); exp != act {
+		t.Errorf("Wrong in-flight count: %v != %v", act, exp)
+	}
+
+	// Downstream layers remain open until the tracker is closed.
+	select {
+	case <-tracker.TransactionChan():
+		t.Error("Transaction channel closed before tracker")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	tracker.CloseAsync()
+	select {
+	case _, open := <-tracker.TransactionChan():
+		if open {
+			t.Error("Expected transaction channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func TestInFlightTrackerInputClosed(t *testing.T) {
+	tranChan := make(chan types.Transaction)
+	tracker := newInFlightTracker(tranChan)
+
+	close(tranChan)
+	select {
+	case _, open := <-tracker.TransactionChan():
+		if open {
+			t.Error("Expected transaction channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	if err := tracker.WaitForDrained(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type fakeConnector struct {
+	connected int32
+}
+
+func (f *fakeConnector) Connected() bool {
+	return atomic.LoadInt32(&f.connected) == 1
+}
+
+func (f *fakeConnector) set(connected bool) {
+	if connected {
+		atomic.StoreInt32(&f.connected, 1)
+	} else {
+		atomic.StoreInt32(&f.connected, 0)
+	}
+}
+
+func TestInFlightTrackerPause(t *testing.T) {
+	tranChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	tracker := newInFlightTracker(tranChan)
+	defer tracker.CloseAsync()
+
+	tracker.Pause()
+	select {
+	case tranChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+		t.Error("Transaction consumed whilst paused")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	tracker.Resume()
+	select {
+	case tranChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	select {
+	case tran := <-tracker.TransactionChan():
+		if exp, act := "foo", string(tran.Payload.Get(0).Get()); exp != act {
+			t.Errorf("Wrong payload: %v != %v", act, exp)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func TestInFlightTrackerPauseOnDisconnect(t *testing.T) {
+	tranChan := make(chan types.Transaction)
+	tracker := newInFlightTracker(tranChan)
+
+	output := &fakeConnector{}
+	output.set(true)
+
+	stats := metrics.NewLocal()
+	done := make(chan struct{})
+	go func() {
+		tracker.pauseOnDisconnect(output, time.Millisecond*200, log.Noop(), stats)
+		close(done)
+	}()
+
+	waitFor := func(paused bool) {
+		t.Helper()
+		for start := time.Now(); tracker.Paused() != paused; {
+			if time.Since(start) > time.Second*5 {
+				t.Fatalf("Timed out waiting for paused state: %v", paused)
+			}
+			<-time.After(time.Millisecond * 10)
+		}
+	}
+
+	// A brief disconnection within the grace period does not pause.
+	output.set(false)
+	<-time.After(time.Millisecond * 50)
+	output.set(true)
+	<-time.After(disconnectCheckInterval * 3)
+	if tracker.Paused() {
+		t.Error("Paused within grace period")
+	}
+
+	output.set(false)
+	waitFor(true)
+	if exp, act := int64(1), stats.GetCounters()["input.paused"]; exp != act {
+		t.Errorf("Wrong paused gauge: %v != %v", act, exp)
+	}
+
+	output.set(true)
+	waitFor(false)
+	if exp, act := int64(0), stats.GetCounters()["input.paused"]; exp != act {
+		t.Errorf("Wrong paused gauge: %v != %v", act, exp)
+	}
+
+	tracker.CloseAsync()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Monitor did not exit after close")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -32,6 +32,7 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/stream"
+	"github.com/Jeffail/benthos/lib/tracer"
 	"github.com/Jeffail/benthos/lib/types"
 )
 
@@ -118,6 +119,7 @@ type Type struct {
 	manager    types.Manager
 	stats      metrics.Type
 	logger     log.Modular
+	tracer     tracer.Type
 	apiTimeout time.Duration
 
 	inputPipeCtors    []StreamPipeConstructorFunc
@@ -133,6 +135,7 @@ func New(opts ...func(*Type)) *Type {
 		streams:    map[string]*StreamStatus{},
 		manager:    types.DudMgr{},
 		stats:      metrics.DudType{},
+		tracer:     tracer.Noop{},
 		apiTimeout: time.Second * 5,
 		logger:     log.New(os.Stdout, log.Config{LogLevel: "NONE"}),
 	}
@@ -161,6 +164,13 @@ func OptSetLogger(log log.Modular) func(*Type) {
 	}
 }
 
+// OptSetTracer sets the tracer to be used by all child streams.
+func OptSetTracer(tr tracer.Type) func(*Type) {
+	return func(t *Type) {
+		t.tracer = tr
+	}
+}
+
 // OptSetManager sets the service manager to be used by the stream manager and
 // all child streams.
 func OptSetManager(mgr types.Manager) func(*Type) {
@@ -265,6 +275,7 @@ func (m *Type) Create(id string, conf stream.Config) error {
 		stream.OptSetLogger(strmLogger),
 		stream.OptSetStats(metrics.Combine(metrics.Namespaced(m.stats, id), strmFlatMetrics)),
 		stream.OptSetManager(namespacedMgr(id, m.manager)),
+		stream.OptSetTracer(m.tracer),
 		stream.OptOnClose(func() {
 			wrapper.setClosed()
 		}),
@@ -350,6 +361,24 @@ func (m *Type) Delete(id string, timeout time.Duration) error {
 
 //------------------------------------------------------------------------------
 
+// Connected returns booleans indicating whether the inputs and outputs of all
+// running streams are currently connected to their targets.
+func (m *Type) Connected() (inputs, outputs bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	inputs, outputs = true, true
+	for _, strm := range m.streams {
+		if !strm.IsRunning() {
+			continue
+		}
+		in, out := strm.strm.Connected()
+		inputs = inputs && in
+		outputs = outputs && out
+	}
+	return
+}
+
 // Stop attempts to gracefully shut down all active streams and close the
 // stream manager.
 func (m *Type) Stop(timeout time.Duration) error {
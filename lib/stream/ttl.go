@@ -0,0 +1,124 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package stream
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/pipeline"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// ingestedAtKey is the metadata key used to carry the time at which a message
+// part was read by an input, in nanoseconds since the Unix epoch.
+const ingestedAtKey = "benthos_ingested_at"
+
+// ttlStamper is a processor that stamps each message part with the time at
+// which it was ingested. Parts that have already been stamped are left
+// untouched.
+type ttlStamper struct{}
+
+// ProcessMessage stamps a message with its ingestion time.
+func (ttlStamper) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	now := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	newMsg := msg.Copy()
+	newMsg.Iter(func(i int, p types.Part) error {
+		if p.Metadata().Get(ingestedAtKey) == "" {
+			p.Metadata().Set(ingestedAtKey, now)
+		}
+		return nil
+	})
+	return []types.Message{newMsg}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ttlFilter is a processor that removes message parts that were ingested
+// longer ago than a TTL, and strips the ingestion time from the parts that
+// remain. Messages where every part is removed are dropped and acknowledged.
+type ttlFilter struct {
+	ttl time.Duration
+	log log.Modular
+
+	mDropped metrics.StatCounter
+}
+
+func newTTLFilter(ttl time.Duration, log log.Modular, stats metrics.Type) *ttlFilter {
+	return &ttlFilter{
+		ttl:      ttl,
+		log:      log,
+		mDropped: stats.GetCounter("output.dropped_stale"),
+	}
+}
+
+// ProcessMessage removes stale parts from a message.
+func (f *ttlFilter) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	now := time.Now()
+
+	newMsg := message.New(nil)
+	msg.Iter(func(i int, p types.Part) error {
+		if tStr := p.Metadata().Get(ingestedAtKey); tStr != "" {
+			nanos, err := strconv.ParseInt(tStr, 10, 64)
+			if err != nil {
+				f.log.Debugf("Failed to parse ingestion time of message: %v\n", err)
+			} else if now.Sub(time.Unix(0, nanos)) > f.ttl {
+				f.mDropped.Incr(1)
+				return nil
+			}
+			p = p.Copy()
+			p.Metadata().Delete(ingestedAtKey)
+		}
+		newMsg.Append(p)
+		return nil
+	})
+
+	if newMsg.Len() == 0 {
+		return nil, response.NewAck()
+	}
+	return []types.Message{newMsg}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ttlPipelines returns pipeline constructors for stamping messages with their
+// ingestion time as they leave an input, and for removing stale messages
+// before they reach an output.
+func ttlPipelines(
+	ttl time.Duration, log log.Modular, stats metrics.Type,
+) (inputPipe, outputPipe types.PipelineConstructorFunc) {
+	inputPipe = func() (types.Pipeline, error) {
+		return pipeline.NewProcessor(log, stats, ttlStamper{}), nil
+	}
+	outputPipe = func() (types.Pipeline, error) {
+		return pipeline.NewProcessor(log, stats, newTTLFilter(ttl, log, stats)), nil
+	}
+	return
+}
+
+//------------------------------------------------------------------------------
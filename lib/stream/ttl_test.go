@@ -0,0 +1,109 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package stream
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func TestTTLStamper(t *testing.T) {
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	msg.Get(1).Metadata().Set(ingestedAtKey, "10")
+
+	msgs, res := ttlStamper{}.ProcessMessage(msg)
+	if res != nil {
+		t.Fatalf("Unexpected response: %v", res.Error())
+	}
+	if exp, act := 1, len(msgs); exp != act {
+		t.Fatalf("Wrong count of messages: %v != %v", act, exp)
+	}
+
+	if v := msgs[0].Get(0).Metadata().Get(ingestedAtKey); v == "" || v == "10" {
+		t.Errorf("Unexpected stamp on first part: %v", v)
+	}
+	if exp, act := "10", msgs[0].Get(1).Metadata().Get(ingestedAtKey); exp != act {
+		t.Errorf("Existing stamp was modified: %v != %v", act, exp)
+	}
+	if v := msg.Get(0).Metadata().Get(ingestedAtKey); v != "" {
+		t.Errorf("Original message was modified: %v", v)
+	}
+}
+
+func TestTTLFilter(t *testing.T) {
+	stats := metrics.NewLocal()
+	filter := newTTLFilter(time.Minute, log.Noop(), stats)
+
+	fresh := strconv.FormatInt(time.Now().UnixNano(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).UnixNano(), 10)
+
+	msg := message.New([][]byte{
+		[]byte("foo"), []byte("bar"), []byte("baz"),
+	})
+	msg.Get(0).Metadata().Set(ingestedAtKey, fresh)
+	msg.Get(1).Metadata().Set(ingestedAtKey, stale)
+
+	msgs, res := filter.ProcessMessage(msg)
+	if res != nil {
+		t.Fatalf("Unexpected response: %v", res.Error())
+	}
+	if exp, act := 1, len(msgs); exp != act {
+		t.Fatalf("Wrong count of messages: %v != %v", act, exp)
+	}
+	if exp, act := [][]byte{[]byte("foo"), []byte("baz")}, message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	msgs[0].Iter(func(i int, p types.Part) error {
+		if v := p.Metadata().Get(ingestedAtKey); v != "" {
+			t.Errorf("Stamp was not removed from part %v: %v", i, v)
+		}
+		return nil
+	})
+	if exp, act := fresh, msg.Get(0).Metadata().Get(ingestedAtKey); exp != act {
+		t.Errorf("Original message was modified: %v != %v", act, exp)
+	}
+
+	msg = message.New([][]byte{[]byte("foo")})
+	msg.Get(0).Metadata().Set(ingestedAtKey, stale)
+
+	msgs, res = filter.ProcessMessage(msg)
+	if len(msgs) != 0 {
+		t.Errorf("Expected stale message to be dropped: %s", message.GetAllBytes(msgs[0]))
+	}
+	if res == nil || res.Error() != nil {
+		t.Errorf("Expected ack response, got: %v", res)
+	}
+
+	if exp, act := int64(2), stats.GetCounters()["output.dropped_stale"]; exp != act {
+		t.Errorf("Wrong count of dropped parts: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
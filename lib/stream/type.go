@@ -22,6 +22,7 @@ package stream
 
 import (
 	"bytes"
+	"fmt"
 	"runtime/pprof"
 	"time"
 
@@ -31,6 +32,8 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/output"
 	"github.com/Jeffail/benthos/lib/pipeline"
+	"github.com/Jeffail/benthos/lib/processor"
+	"github.com/Jeffail/benthos/lib/tracer"
 	"github.com/Jeffail/benthos/lib/types"
 )
 
@@ -41,6 +44,7 @@ type Type struct {
 	conf Config
 
 	inputLayer    input.Type
+	inFlight      *inFlightTracker
 	bufferLayer   buffer.Type
 	pipelineLayer pipeline.Type
 	outputLayer   output.Type
@@ -52,6 +56,7 @@ type Type struct {
 	manager types.Manager
 	stats   metrics.Type
 	logger  log.Modular
+	tracer  tracer.Type
 
 	onClose func()
 }
@@ -63,6 +68,7 @@ func New(conf Config, opts ...func(*Type)) (*Type, error) {
 		stats:   metrics.Noop(),
 		logger:  log.Noop(),
 		manager: types.NoopMgr(),
+		tracer:  tracer.Noop{},
 		onClose: func() {},
 	}
 	for _, opt := range opts {
@@ -133,6 +139,14 @@ func OptSetManager(mgr types.Manager) func(*Type) {
 	}
 }
 
+// OptSetTracer sets the tracer to be used for recording segments of the work
+// performed on each message by the stream.
+func OptSetTracer(tr tracer.Type) func(*Type) {
+	return func(t *Type) {
+		t.tracer = tr
+	}
+}
+
 // OptOnClose sets a closure to be called when the stream closes.
 func OptOnClose(onClose func()) func(*Type) {
 	return func(t *Type) {
@@ -142,10 +156,66 @@ func OptOnClose(onClose func()) func(*Type) {
 
 //------------------------------------------------------------------------------
 
+// tracedLayers returns the pipeline config and processor constructors of the
+// stream with each processor wrapped in order to record subsegments, and the
+// input pipeline constructors with a pipeline that starts a segment for each
+// message.
+func (t *Type) tracedLayers() (
+	pipeline.Config, []types.ProcessorConstructorFunc, []types.PipelineConstructorFunc,
+) {
+	inputPipes := append([]types.PipelineConstructorFunc{}, t.complementaryInputPipes...)
+	inputPipes = append(inputPipes, func() (types.Pipeline, error) {
+		return tracer.NewInputPipeline(t.conf.Input.Type, t.tracer), nil
+	})
+
+	var procs []types.ProcessorConstructorFunc
+	for i, procConf := range t.conf.Pipeline.Processors {
+		index, pConf := i, procConf
+		procs = append(procs, func() (types.Processor, error) {
+			proc, err := processor.New(pConf, t.manager, t.logger, t.stats)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", pConf.Type, err)
+			}
+			proc = processor.WrapTimed("pipeline", index, pConf, proc, t.stats)
+			return tracer.WrapProcessor(pConf.Type, proc, t.tracer), nil
+		})
+	}
+	procs = append(procs, t.complementaryProcs...)
+
+	pipeConf := t.conf.Pipeline
+	pipeConf.Processors = nil
+	return pipeConf, procs, inputPipes
+}
+
 func (t *Type) start() (err error) {
+	var pauseGrace time.Duration
+	if t.conf.Input.PauseOnOutputDisconnect {
+		if pauseGrace, err = time.ParseDuration(t.conf.Input.PauseGracePeriod); err != nil {
+			return fmt.Errorf("failed to parse pause_grace_period: %v", err)
+		}
+	}
+
+	pipeConf := t.conf.Pipeline
+	procs := t.complementaryProcs
+	inputPipes := t.complementaryInputPipes
+	outputPipes := t.complementaryOutputPipes
+	if _, isNoop := t.tracer.(tracer.Noop); !isNoop {
+		pipeConf, procs, inputPipes = t.tracedLayers()
+	}
+
+	if len(t.conf.Input.MessageTTL) > 0 {
+		var ttl time.Duration
+		if ttl, err = time.ParseDuration(t.conf.Input.MessageTTL); err != nil {
+			return fmt.Errorf("failed to parse message_ttl: %v", err)
+		}
+		inputPipe, outputPipe := ttlPipelines(ttl, t.logger, t.stats)
+		inputPipes = append(append([]types.PipelineConstructorFunc{}, inputPipes...), inputPipe)
+		outputPipes = append(append([]types.PipelineConstructorFunc{}, outputPipes...), outputPipe)
+	}
+
 	// Constructors
 	if t.inputLayer, err = input.New(
-		t.conf.Input, t.manager, t.logger, t.stats, t.complementaryInputPipes...,
+		t.conf.Input, t.manager, t.logger, t.stats, inputPipes...,
 	); err != nil {
 		return
 	}
@@ -156,15 +226,15 @@ func (t *Type) start() (err error) {
 			return
 		}
 	}
-	if tLen := len(t.complementaryProcs) + len(t.conf.Pipeline.Processors); tLen > 0 {
+	if tLen := len(procs) + len(pipeConf.Processors); tLen > 0 {
 		if t.pipelineLayer, err = pipeline.New(
-			t.conf.Pipeline, t.manager, t.logger, t.stats, t.complementaryProcs...,
+			pipeConf, t.manager, t.logger, t.stats, procs...,
 		); err != nil {
 			return
 		}
 	}
 	if t.outputLayer, err = output.New(
-		t.conf.Output, t.manager, t.logger, t.stats, t.complementaryOutputPipes...,
+		t.conf.Output, t.manager, t.logger, t.stats, outputPipes...,
 	); err != nil {
 		return
 	}
@@ -172,7 +242,11 @@ func (t *Type) start() (err error) {
 	// Start chaining components
 	var nextTranChan <-chan types.Transaction
 
-	nextTranChan = t.inputLayer.TransactionChan()
+	t.inFlight = newInFlightTracker(t.inputLayer.TransactionChan())
+	nextTranChan = t.inFlight.TransactionChan()
+	if t.conf.Input.PauseOnOutputDisconnect {
+		go t.inFlight.pauseOnDisconnect(t.outputLayer, pauseGrace, t.logger, t.stats)
+	}
 	if t.bufferLayer != nil {
 		if err = t.bufferLayer.Consume(nextTranChan); err != nil {
 			return
@@ -201,18 +275,33 @@ func (t *Type) start() (err error) {
 	return nil
 }
 
-// stopGracefully attempts to close the stream in the most graceful way by only
-// closing the input layer and waiting for all other layers to terminate by
-// proxy. This should guarantee that all in-flight and buffered data is resolved
-// before shutting down.
+// Connected returns booleans indicating whether the inputs and outputs of the
+// stream are currently connected to their targets.
+func (t *Type) Connected() (inputs, outputs bool) {
+	return types.IsConnected(t.inputLayer), types.IsConnected(t.outputLayer)
+}
+
+// stopGracefully attempts to close the stream in the most graceful way by
+// first preventing inputs from dispatching new messages and waiting for any
+// in-flight messages to be acknowledged, then closing the input layer and
+// waiting for all other layers to terminate by proxy. This should guarantee
+// that all in-flight and buffered data is resolved before shutting down.
 func (t *Type) stopGracefully(timeout time.Duration) (err error) {
-	t.inputLayer.CloseAsync()
 	started := time.Now()
-	if err = t.inputLayer.WaitForClose(timeout); err != nil {
+	t.inFlight.StopConsuming()
+	if err = t.inFlight.WaitForDrained(timeout); err != nil {
 		return
 	}
 
-	var remaining time.Duration
+	t.inputLayer.CloseAsync()
+	remaining := timeout - time.Since(started)
+	if remaining < 0 {
+		return types.ErrTimeout
+	}
+	if err = t.inputLayer.WaitForClose(remaining); err != nil {
+		return
+	}
+	t.inFlight.CloseAsync()
 
 	// If we have a buffer then wait right here. We want to try and allow the
 	// buffer to empty out before prompting the other layers to shut down.
@@ -261,6 +350,7 @@ func (t *Type) stopOrdered(timeout time.Duration) (err error) {
 	if err = t.inputLayer.WaitForClose(timeout); err != nil {
 		return
 	}
+	t.inFlight.CloseAsync()
 
 	var remaining time.Duration
 
@@ -303,6 +393,7 @@ func (t *Type) stopOrdered(timeout time.Duration) (err error) {
 // should only be attempted if both stopGracefully and stopOrdered failed.
 func (t *Type) stopUnordered(timeout time.Duration) (err error) {
 	t.inputLayer.CloseAsync()
+	t.inFlight.CloseAsync()
 	if t.bufferLayer != nil {
 		t.bufferLayer.CloseAsync()
 	}
@@ -361,6 +452,9 @@ func (t *Type) Stop(timeout time.Duration) error {
 		return nil
 	}
 	if err == types.ErrTimeout {
+		if n := t.inFlight.Count(); n > 0 {
+			t.logger.Warnf("Shutdown timeout reached with %v messages still in flight.\n", n)
+		}
 		t.logger.Infoln("Unable to fully drain buffered messages within target time.")
 	} else {
 		t.logger.Errorf("Encountered error whilst shutting down: %v\n", err)
@@ -86,6 +86,29 @@ func TestTypeConstruction(t *testing.T) {
 	}
 }
 
+func TestTypeBadPauseGracePeriod(t *testing.T) {
+	conf := NewConfig()
+	conf.Input.Type = input.TypeNanomsg
+	conf.Input.PauseOnOutputDisconnect = true
+	conf.Input.PauseGracePeriod = "nope"
+	conf.Output.Type = output.TypeNanomsg
+
+	if _, err := New(conf); err == nil {
+		t.Error("Expected error from bad pause grace period")
+	}
+}
+
+func TestTypeBadMessageTTL(t *testing.T) {
+	conf := NewConfig()
+	conf.Input.Type = input.TypeNanomsg
+	conf.Input.MessageTTL = "nope"
+	conf.Output.Type = output.TypeNanomsg
+
+	if _, err := New(conf); err == nil {
+		t.Error("Expected error from bad message ttl")
+	}
+}
+
 func TestTypeCloseGracefully(t *testing.T) {
 	conf := NewConfig()
 	conf.Input.Type = input.TypeNanomsg
@@ -0,0 +1,215 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package integration
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/go-redis/redis"
+	"github.com/ory/dockertest"
+)
+
+func TestRedisIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Skipf("Could not connect to docker: %s", err)
+	}
+	pool.MaxWait = time.Second * 30
+
+	resource, err := pool.Run("redis", "latest", nil)
+	if err != nil {
+		t.Fatalf("Could not start resource: %s", err)
+	}
+
+	urlStr := fmt.Sprintf("tcp://localhost:%v", resource.GetPort("6379/tcp"))
+
+	if err = pool.Retry(func() error {
+		conf := writer.NewRedisConfig()
+		conf.URL = urlStr
+
+		w, cErr := writer.NewRedis(conf, log.Noop(), metrics.Noop())
+		if cErr != nil {
+			return cErr
+		}
+		cErr = w.Connect()
+
+		w.CloseAsync()
+		return cErr
+	}); err != nil {
+		t.Fatalf("Could not connect to docker resource: %s", err)
+	}
+
+	defer func() {
+		if err = pool.Purge(resource); err != nil {
+			t.Logf("Failed to clean up docker resource: %v", err)
+		}
+	}()
+
+	t.Run("TestRedisHSet", func(te *testing.T) {
+		testRedisHSet(urlStr, te)
+	})
+	t.Run("TestRedisSAdd", func(te *testing.T) {
+		testRedisSAdd(urlStr, te)
+	})
+	t.Run("TestRedisZAdd", func(te *testing.T) {
+		testRedisZAdd(urlStr, te)
+	})
+}
+
+func createRedisOutputAndClient(
+	conf writer.RedisConfig,
+) (mOutput *writer.Redis, client *redis.Client, err error) {
+	if mOutput, err = writer.NewRedis(conf, log.Noop(), metrics.Noop()); err != nil {
+		return
+	}
+	if err = mOutput.Connect(); err != nil {
+		return
+	}
+	var u *url.URL
+	if u, err = url.Parse(conf.URL); err != nil {
+		return
+	}
+	client = redis.NewClient(&redis.Options{
+		Addr:    u.Host,
+		Network: u.Scheme,
+	})
+	return
+}
+
+func testRedisHSet(urlStr string, t *testing.T) {
+	conf := writer.NewRedisConfig()
+	conf.URL = urlStr
+	conf.Operation = "hset"
+	conf.Key = "benthos_test_hash"
+	conf.Field = "${!json_field:id}"
+	conf.Value = "${!json_field:name}"
+
+	mOutput, client, err := createRedisOutputAndClient(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		client.Close()
+		mOutput.CloseAsync()
+		if cErr := mOutput.WaitForClose(time.Second); cErr != nil {
+			t.Error(cErr)
+		}
+	}()
+
+	if err = mOutput.Write(message.New([][]byte{
+		[]byte(`{"id":"1","name":"foo"}`),
+		[]byte(`{"id":"2","name":"bar"}`),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string]string{"1": "foo", "2": "bar"}
+	act, err := client.HGetAll("benthos_test_hash").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func testRedisSAdd(urlStr string, t *testing.T) {
+	conf := writer.NewRedisConfig()
+	conf.URL = urlStr
+	conf.Operation = "sadd"
+	conf.Key = "benthos_test_set"
+
+	mOutput, client, err := createRedisOutputAndClient(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		client.Close()
+		mOutput.CloseAsync()
+		if cErr := mOutput.WaitForClose(time.Second); cErr != nil {
+			t.Error(cErr)
+		}
+	}()
+
+	if err = mOutput.Write(message.New([][]byte{
+		[]byte("foo"), []byte("bar"), []byte("foo"),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	act, err := client.SCard("benthos_test_set").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := int64(2); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func testRedisZAdd(urlStr string, t *testing.T) {
+	conf := writer.NewRedisConfig()
+	conf.URL = urlStr
+	conf.Operation = "zadd"
+	conf.Key = "benthos_test_sorted_set"
+	conf.Value = "${!json_field:player}"
+	conf.Score = "${!json_field:points}"
+
+	mOutput, client, err := createRedisOutputAndClient(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		client.Close()
+		mOutput.CloseAsync()
+		if cErr := mOutput.WaitForClose(time.Second); cErr != nil {
+			t.Error(cErr)
+		}
+	}()
+
+	if err = mOutput.Write(message.New([][]byte{
+		[]byte(`{"player":"foo","points":10}`),
+		[]byte(`{"player":"bar","points":30}`),
+		[]byte(`{"player":"baz","points":20}`),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{"bar", "baz", "foo"}
+	act, err := client.ZRevRange("benthos_test_sorted_set", 0, -1).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
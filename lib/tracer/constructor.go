@@ -0,0 +1,105 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracer
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/Jeffail/benthos/lib/log"
+)
+
+//------------------------------------------------------------------------------
+
+// Errors for the tracer package.
+var (
+	ErrInvalidTracerType = errors.New("invalid tracer type")
+)
+
+//------------------------------------------------------------------------------
+
+// String constants representing each tracer type.
+const (
+	TypeNone = "none"
+	TypeXRay = "xray"
+)
+
+//------------------------------------------------------------------------------
+
+// Config is the all encompassing configuration struct for all tracer types.
+type Config struct {
+	Type string     `json:"type" yaml:"type"`
+	XRay XRayConfig `json:"xray" yaml:"xray"`
+}
+
+// NewConfig returns a configuration struct fully populated with default values.
+func NewConfig() Config {
+	return Config{
+		Type: TypeNone,
+		XRay: NewXRayConfig(),
+	}
+}
+
+// SanitiseConfig returns a sanitised version of the Config, meaning sections
+// that aren't relevant to behaviour are removed.
+func SanitiseConfig(conf Config) (interface{}, error) {
+	cBytes, err := json.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	hashMap := map[string]interface{}{}
+	if err = json.Unmarshal(cBytes, &hashMap); err != nil {
+		return nil, err
+	}
+
+	outputMap := map[string]interface{}{}
+	outputMap["type"] = hashMap["type"]
+	if conf.Type != TypeNone {
+		outputMap[conf.Type] = hashMap[conf.Type]
+	}
+
+	return outputMap, nil
+}
+
+//------------------------------------------------------------------------------
+
+// OptSetLogger sets the logging output to be used by the tracer.
+func OptSetLogger(log log.Modular) func(Type) {
+	return func(t Type) {
+		t.SetLogger(log)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// New creates a tracer type based on a configuration.
+func New(conf Config, opts ...func(Type)) (Type, error) {
+	switch conf.Type {
+	case TypeNone, "":
+		return Noop{}, nil
+	case TypeXRay:
+		return NewXRay(conf.XRay, opts...)
+	}
+	return nil, ErrInvalidTracerType
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,84 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracer
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// Segment is a unit of work performed on a message that has been started and
+// must be ended.
+type Segment interface {
+	// End finishes the segment, where a non-nil error indicates that the work
+	// failed.
+	End(err error)
+}
+
+// Type is a tracer that records segments of work performed on messages.
+type Type interface {
+	// StartSegment begins a new segment for a message entering the stream,
+	// continuing any trace referenced within the metadata of the message. The
+	// trace context of the new segment is written to the metadata of all parts
+	// of the message so that subsegments can be attached to it.
+	StartSegment(name string, msg types.Message) Segment
+
+	// StartSubsegment begins a subsegment of the segment referenced within the
+	// metadata of a message.
+	StartSubsegment(name string, msg types.Message) Segment
+
+	// SetLogger sets the logging mechanism of the tracer.
+	SetLogger(log log.Modular)
+
+	// Close stops the tracer and cleans up resources.
+	Close() error
+}
+
+//------------------------------------------------------------------------------
+
+type noopSegment struct{}
+
+func (n noopSegment) End(err error) {}
+
+// Noop is a tracer implementation that does nothing.
+type Noop struct{}
+
+// StartSegment returns a segment that does nothing.
+func (n Noop) StartSegment(name string, msg types.Message) Segment {
+	return noopSegment{}
+}
+
+// StartSubsegment returns a segment that does nothing.
+func (n Noop) StartSubsegment(name string, msg types.Message) Segment {
+	return noopSegment{}
+}
+
+// SetLogger does nothing.
+func (n Noop) SetLogger(log log.Modular) {}
+
+// Close does nothing.
+func (n Noop) Close() error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
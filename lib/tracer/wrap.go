@@ -0,0 +1,188 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracer
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+type tracedProcessor struct {
+	name   string
+	proc   types.Processor
+	tracer Type
+}
+
+// WrapProcessor returns a processor that records a subsegment for each message
+// that it processes.
+func WrapProcessor(name string, proc types.Processor, t Type) types.Processor {
+	return &tracedProcessor{
+		name:   name,
+		proc:   proc,
+		tracer: t,
+	}
+}
+
+// ProcessMessage processes a message with the wrapped processor within a
+// subsegment.
+func (t *tracedProcessor) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	seg := t.tracer.StartSubsegment(t.name, msg)
+	msgs, res := t.proc.ProcessMessage(msg)
+	var err error
+	if res != nil {
+		err = res.Error()
+	}
+	seg.End(err)
+	return msgs, res
+}
+
+// CloseAsync shuts down the wrapped processor if it is closable.
+func (t *tracedProcessor) CloseAsync() {
+	if c, ok := t.proc.(types.Closable); ok {
+		c.CloseAsync()
+	}
+}
+
+// WaitForClose blocks until the wrapped processor has closed down if it is
+// closable.
+func (t *tracedProcessor) WaitForClose(timeout time.Duration) error {
+	if c, ok := t.proc.(types.Closable); ok {
+		return c.WaitForClose(timeout)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// InputPipeline is a pipeline that starts a segment for each message received
+// from an input, and ends it once the message has been acknowledged.
+type InputPipeline struct {
+	running int32
+
+	name   string
+	tracer Type
+
+	messagesIn  <-chan types.Transaction
+	messagesOut chan types.Transaction
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewInputPipeline creates a pipeline that starts a segment for each message
+// received from an input.
+func NewInputPipeline(name string, t Type) *InputPipeline {
+	return &InputPipeline{
+		running:     1,
+		name:        name,
+		tracer:      t,
+		messagesOut: make(chan types.Transaction),
+		closeChan:   make(chan struct{}),
+		closedChan:  make(chan struct{}),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (p *InputPipeline) loop() {
+	defer func() {
+		close(p.messagesOut)
+		close(p.closedChan)
+	}()
+
+	for atomic.LoadInt32(&p.running) == 1 {
+		var tran types.Transaction
+		var open bool
+		select {
+		case tran, open = <-p.messagesIn:
+			if !open {
+				return
+			}
+		case <-p.closeChan:
+			return
+		}
+
+		msg := tran.Payload.Copy()
+		seg := p.tracer.StartSegment(p.name, msg)
+
+		resChan := make(chan types.Response)
+		select {
+		case p.messagesOut <- types.NewTransaction(msg, resChan):
+		case <-p.closeChan:
+			return
+		}
+
+		go func(seg Segment, ogResChan chan<- types.Response) {
+			var res types.Response
+			select {
+			case res = <-resChan:
+			case <-p.closeChan:
+				return
+			}
+			seg.End(res.Error())
+			select {
+			case ogResChan <- res:
+			case <-p.closeChan:
+			}
+		}(seg, tran.ResponseChan)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Consume assigns a messages channel for the pipeline to read.
+func (p *InputPipeline) Consume(msgs <-chan types.Transaction) error {
+	if p.messagesIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	p.messagesIn = msgs
+	go p.loop()
+	return nil
+}
+
+// TransactionChan returns the channel used for consuming messages from this
+// pipeline.
+func (p *InputPipeline) TransactionChan() <-chan types.Transaction {
+	return p.messagesOut
+}
+
+// CloseAsync shuts down the pipeline.
+func (p *InputPipeline) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&p.running, 1, 0) {
+		close(p.closeChan)
+	}
+}
+
+// WaitForClose blocks until the pipeline has closed down.
+func (p *InputPipeline) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-p.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
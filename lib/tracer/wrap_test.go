@@ -0,0 +1,170 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracer
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+type mockSegment struct {
+	name   string
+	tracer *mockTracer
+}
+
+func (m mockSegment) End(err error) {
+	m.tracer.Lock()
+	m.tracer.ended = append(m.tracer.ended, m.name)
+	m.tracer.errs = append(m.tracer.errs, err)
+	m.tracer.Unlock()
+}
+
+type mockTracer struct {
+	sync.Mutex
+	ended []string
+	errs  []error
+}
+
+func (m *mockTracer) StartSegment(name string, msg types.Message) Segment {
+	return mockSegment{name: "segment:" + name, tracer: m}
+}
+
+func (m *mockTracer) StartSubsegment(name string, msg types.Message) Segment {
+	return mockSegment{name: "subsegment:" + name, tracer: m}
+}
+
+func (m *mockTracer) SetLogger(log log.Modular) {}
+
+func (m *mockTracer) Close() error {
+	return nil
+}
+
+type mockProc struct {
+	err error
+}
+
+func (m mockProc) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	if m.err != nil {
+		return nil, response.NewError(m.err)
+	}
+	return []types.Message{msg}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func TestWrapProcessor(t *testing.T) {
+	tr := &mockTracer{}
+
+	errProc := errors.New("nope")
+	procA := WrapProcessor("foo", mockProc{}, tr)
+	procB := WrapProcessor("bar", mockProc{err: errProc}, tr)
+
+	msgs, res := procA.ProcessMessage(message.New([][]byte{[]byte("foo")}))
+	if len(msgs) != 1 || res != nil {
+		t.Errorf("Unexpected result: %v, %v", msgs, res)
+	}
+	msgs, res = procB.ProcessMessage(message.New([][]byte{[]byte("foo")}))
+	if len(msgs) != 0 || res == nil || res.Error() != errProc {
+		t.Errorf("Unexpected result: %v, %v", msgs, res)
+	}
+
+	if exp, act := []string{"subsegment:foo", "subsegment:bar"}, tr.ended; len(exp) != len(act) || exp[0] != act[0] || exp[1] != act[1] {
+		t.Errorf("Wrong segments ended: %v != %v", act, exp)
+	}
+	if exp, act := []error{nil, errProc}, tr.errs; len(exp) != len(act) || exp[0] != act[0] || exp[1] != act[1] {
+		t.Errorf("Wrong segment errors: %v != %v", act, exp)
+	}
+}
+
+func TestInputPipeline(t *testing.T) {
+	tr := &mockTracer{}
+
+	pipe := NewInputPipeline("foo", tr)
+
+	tChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	if err := pipe.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	errRes := errors.New("nope")
+	for _, resErr := range []error{nil, errRes} {
+		select {
+		case tChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+
+		var tran types.Transaction
+		select {
+		case tran = <-pipe.TransactionChan():
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		if exp, act := "foo", string(tran.Payload.Get(0).Get()); exp != act {
+			t.Errorf("Wrong payload: %v != %v", act, exp)
+		}
+
+		var res types.Response = response.NewAck()
+		if resErr != nil {
+			res = response.NewError(resErr)
+		}
+		select {
+		case tran.ResponseChan <- res:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+
+		select {
+		case res = <-resChan:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		if exp, act := resErr, res.Error(); exp != act {
+			t.Errorf("Wrong response: %v != %v", act, exp)
+		}
+	}
+
+	pipe.CloseAsync()
+	if err := pipe.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+
+	tr.Lock()
+	defer tr.Unlock()
+	if exp, act := []string{"segment:foo", "segment:foo"}, tr.ended; len(exp) != len(act) || exp[0] != act[0] || exp[1] != act[1] {
+		t.Errorf("Wrong segments ended: %v != %v", act, exp)
+	}
+	if exp, act := []error{nil, errRes}, tr.errs; len(exp) != len(act) || exp[0] != act[0] || exp[1] != act[1] {
+		t.Errorf("Wrong segment errors: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
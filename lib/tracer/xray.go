@@ -0,0 +1,347 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/tracecontext"
+)
+
+//------------------------------------------------------------------------------
+
+// XRayTraceHeaderKey is the metadata key of an AWS X-Ray trace header, which
+// has the same format as the X-Amzn-Trace-Id HTTP header.
+const XRayTraceHeaderKey = "xray_trace_header"
+
+// XRaySamplingConfig contains fields for deciding which new traces are sampled,
+// following the semantics of an X-Ray sampling rule.
+type XRaySamplingConfig struct {
+	Reservoir int     `json:"reservoir" yaml:"reservoir"`
+	FixedRate float64 `json:"fixed_rate" yaml:"fixed_rate"`
+}
+
+// XRayConfig contains configuration fields for the X-Ray tracer.
+type XRayConfig struct {
+	Address     string             `json:"address" yaml:"address"`
+	ServiceName string             `json:"service_name" yaml:"service_name"`
+	Sampling    XRaySamplingConfig `json:"sampling" yaml:"sampling"`
+}
+
+// NewXRayConfig creates an XRayConfig populated with default values.
+func NewXRayConfig() XRayConfig {
+	return XRayConfig{
+		Address:     "127.0.0.1:2000",
+		ServiceName: "benthos",
+		Sampling: XRaySamplingConfig{
+			Reservoir: 1,
+			FixedRate: 0.05,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// XRayHeader is a parsed AWS X-Ray trace header.
+type XRayHeader struct {
+	TraceID  string
+	ParentID string
+
+	// Sampled is nil when the sampling decision has not yet been made.
+	Sampled *bool
+}
+
+// ParseXRayHeader parses an AWS X-Ray trace header of the form
+// `Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1`,
+// returns false if the header does not contain a trace ID.
+func ParseXRayHeader(v string) (XRayHeader, bool) {
+	var h XRayHeader
+	for _, field := range strings.Split(v, ";") {
+		kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "Root":
+			h.TraceID = kv[1]
+		case "Parent":
+			h.ParentID = kv[1]
+		case "Sampled":
+			switch kv[1] {
+			case "1":
+				sampled := true
+				h.Sampled = &sampled
+			case "0":
+				sampled := false
+				h.Sampled = &sampled
+			}
+		}
+	}
+	return h, len(h.TraceID) > 0
+}
+
+// String returns the header in the format of an X-Amzn-Trace-Id header.
+func (h XRayHeader) String() string {
+	s := "Root=" + h.TraceID
+	if len(h.ParentID) > 0 {
+		s += ";Parent=" + h.ParentID
+	}
+	if h.Sampled != nil {
+		if *h.Sampled {
+			s += ";Sampled=1"
+		} else {
+			s += ";Sampled=0"
+		}
+	}
+	return s
+}
+
+// xrayHeaderFromTraceParent converts a W3C traceparent into an X-Ray trace
+// header, where the first eight hex digits of the trace ID are the epoch
+// segment of the X-Ray trace ID.
+func xrayHeaderFromTraceParent(v string) (XRayHeader, bool) {
+	if !tracecontext.ValidTraceParent(v) {
+		return XRayHeader{}, false
+	}
+	parts := strings.Split(v, "-")
+	sampled := parts[3] == "01"
+	return XRayHeader{
+		TraceID:  "1-" + parts[1][:8] + "-" + parts[1][8:],
+		ParentID: parts[2],
+		Sampled:  &sampled,
+	}, true
+}
+
+//------------------------------------------------------------------------------
+
+type xrayDocument struct {
+	Name        string            `json:"name"`
+	ID          string            `json:"id"`
+	TraceID     string            `json:"trace_id"`
+	ParentID    string            `json:"parent_id,omitempty"`
+	Type        string            `json:"type,omitempty"`
+	StartTime   float64           `json:"start_time"`
+	EndTime     float64           `json:"end_time"`
+	Error       bool              `json:"error,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type xraySegment struct {
+	x   *XRay
+	doc xrayDocument
+}
+
+func epochSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+// End finishes the segment and sends it to the X-Ray daemon.
+func (s *xraySegment) End(err error) {
+	s.doc.EndTime = epochSeconds(time.Now())
+	s.doc.Error = err != nil
+	s.x.send(s.doc)
+}
+
+//------------------------------------------------------------------------------
+
+// XRay is a tracer that sends segments to an AWS X-Ray daemon.
+type XRay struct {
+	serviceName string
+	reservoir   int
+	fixedRate   float64
+
+	conn net.Conn
+	log  log.Modular
+
+	randMut     sync.Mutex
+	rand        *rand.Rand
+	reservoirTS int64
+	reservoirN  int
+}
+
+// NewXRay creates a new X-Ray tracer.
+func NewXRay(conf XRayConfig, opts ...func(Type)) (Type, error) {
+	if conf.Sampling.FixedRate < 0 || conf.Sampling.FixedRate > 1 {
+		return nil, fmt.Errorf("sampling fixed rate must be between 0 and 1: %v", conf.Sampling.FixedRate)
+	}
+	conn, err := net.Dial("udp", conf.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to X-Ray daemon: %v", err)
+	}
+	x := &XRay{
+		serviceName: conf.ServiceName,
+		reservoir:   conf.Sampling.Reservoir,
+		fixedRate:   conf.Sampling.FixedRate,
+		conn:        conn,
+		log:         log.New(ioutil.Discard, log.Config{LogLevel: "OFF"}),
+		rand:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, opt := range opts {
+		opt(x)
+	}
+	return x, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (x *XRay) randomHex(bytes int) string {
+	b := make([]byte, bytes)
+	x.randMut.Lock()
+	x.rand.Read(b)
+	x.randMut.Unlock()
+	return fmt.Sprintf("%x", b)
+}
+
+func (x *XRay) newTraceID() string {
+	return fmt.Sprintf("1-%08x-%v", time.Now().Unix(), x.randomHex(12))
+}
+
+// sample decides whether a new trace is sampled, allowing up to the reservoir
+// of traces each second and a fixed rate of traces beyond that.
+func (x *XRay) sample() bool {
+	x.randMut.Lock()
+	defer x.randMut.Unlock()
+
+	if now := time.Now().Unix(); now != x.reservoirTS {
+		x.reservoirTS = now
+		x.reservoirN = 0
+	}
+	if x.reservoirN < x.reservoir {
+		x.reservoirN++
+		return true
+	}
+	return x.rand.Float64() < x.fixedRate
+}
+
+func (x *XRay) send(doc xrayDocument) {
+	docBytes, err := json.Marshal(doc)
+	if err != nil {
+		x.log.Errorf("Failed to marshal segment: %v\n", err)
+		return
+	}
+	packet := append([]byte(`{"format": "json", "version": 1}`+"\n"), docBytes...)
+	if _, err = x.conn.Write(packet); err != nil {
+		x.log.Debugf("Failed to send segment to X-Ray daemon: %v\n", err)
+	}
+}
+
+// headerFromMessage extracts a trace header from the metadata of the first
+// part of a message, either from an X-Ray trace header or a W3C traceparent.
+func headerFromMessage(msg types.Message) (XRayHeader, bool) {
+	if msg.Len() == 0 {
+		return XRayHeader{}, false
+	}
+	meta := msg.Get(0).Metadata()
+	if h, ok := ParseXRayHeader(meta.Get(XRayTraceHeaderKey)); ok {
+		return h, true
+	}
+	return xrayHeaderFromTraceParent(meta.Get(tracecontext.TraceParentKey))
+}
+
+//------------------------------------------------------------------------------
+
+// StartSegment begins a new segment for a message entering the stream,
+// continuing any X-Ray or W3C trace referenced within the metadata of the
+// message.
+func (x *XRay) StartSegment(name string, msg types.Message) Segment {
+	if msg.Len() == 0 {
+		return noopSegment{}
+	}
+
+	h, exists := headerFromMessage(msg)
+	if !exists {
+		h = XRayHeader{TraceID: x.newTraceID()}
+	}
+	if h.Sampled == nil {
+		sampled := x.sample()
+		h.Sampled = &sampled
+	}
+
+	seg := &xraySegment{
+		x: x,
+		doc: xrayDocument{
+			Name:      x.serviceName,
+			ID:        x.randomHex(8),
+			TraceID:   h.TraceID,
+			ParentID:  h.ParentID,
+			StartTime: epochSeconds(time.Now()),
+			Annotations: map[string]string{
+				"input": name,
+			},
+		},
+	}
+
+	h.ParentID = seg.doc.ID
+	header := h.String()
+	msg.Iter(func(i int, p types.Part) error {
+		p.Metadata().Set(XRayTraceHeaderKey, header)
+		return nil
+	})
+
+	if !*h.Sampled {
+		return noopSegment{}
+	}
+	return seg
+}
+
+// StartSubsegment begins a subsegment of the segment referenced within the
+// metadata of a message.
+func (x *XRay) StartSubsegment(name string, msg types.Message) Segment {
+	if msg.Len() == 0 {
+		return noopSegment{}
+	}
+	h, exists := ParseXRayHeader(msg.Get(0).Metadata().Get(XRayTraceHeaderKey))
+	if !exists || len(h.ParentID) == 0 || h.Sampled == nil || !*h.Sampled {
+		return noopSegment{}
+	}
+	return &xraySegment{
+		x: x,
+		doc: xrayDocument{
+			Name:      name,
+			ID:        x.randomHex(8),
+			TraceID:   h.TraceID,
+			ParentID:  h.ParentID,
+			Type:      "subsegment",
+			StartTime: epochSeconds(time.Now()),
+		},
+	}
+}
+
+// SetLogger sets the logger used to print errors.
+func (x *XRay) SetLogger(log log.Modular) {
+	x.log = log
+}
+
+// Close stops the tracer and closes the connection to the daemon.
+func (x *XRay) Close() error {
+	return x.conn.Close()
+}
+
+//------------------------------------------------------------------------------
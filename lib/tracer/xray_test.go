@@ -0,0 +1,310 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/message"
+)
+
+//------------------------------------------------------------------------------
+
+func TestParseXRayHeader(t *testing.T) {
+	tests := []struct {
+		input   string
+		exists  bool
+		trace   string
+		parent  string
+		sampled string
+	}{
+		{
+			input:   "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+			exists:  true,
+			trace:   "1-5759e988-bd862e3fe1be46a994272793",
+			parent:  "53995c3f42cd8ad8",
+			sampled: "1",
+		},
+		{
+			input:   "Root=1-5759e988-bd862e3fe1be46a994272793; Sampled=0",
+			exists:  true,
+			trace:   "1-5759e988-bd862e3fe1be46a994272793",
+			sampled: "0",
+		},
+		{
+			input:  "Root=1-5759e988-bd862e3fe1be46a994272793",
+			exists: true,
+			trace:  "1-5759e988-bd862e3fe1be46a994272793",
+		},
+		{
+			input: "Parent=53995c3f42cd8ad8;Sampled=1",
+		},
+		{
+			input: "",
+		},
+	}
+
+	for _, test := range tests {
+		h, exists := ParseXRayHeader(test.input)
+		if exp, act := test.exists, exists; exp != act {
+			t.Errorf("Wrong exists result for '%v': %v != %v", test.input, act, exp)
+		}
+		if !exists {
+			continue
+		}
+		if exp, act := test.trace, h.TraceID; exp != act {
+			t.Errorf("Wrong trace ID for '%v': %v != %v", test.input, act, exp)
+		}
+		if exp, act := test.parent, h.ParentID; exp != act {
+			t.Errorf("Wrong parent ID for '%v': %v != %v", test.input, act, exp)
+		}
+		sampled := ""
+		if h.Sampled != nil {
+			if *h.Sampled {
+				sampled = "1"
+			} else {
+				sampled = "0"
+			}
+		}
+		if exp, act := test.sampled, sampled; exp != act {
+			t.Errorf("Wrong sampled for '%v': %v != %v", test.input, act, exp)
+		}
+	}
+}
+
+func TestXRayHeaderFromTraceParent(t *testing.T) {
+	h, exists := xrayHeaderFromTraceParent("00-5759e988bd862e3fe1be46a994272793-53995c3f42cd8ad8-01")
+	if !exists {
+		t.Fatal("Expected header to exist")
+	}
+	if exp, act := "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1", h.String(); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if _, exists = xrayHeaderFromTraceParent("not a traceparent"); exists {
+		t.Error("Expected invalid traceparent to be rejected")
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type xrayListener struct {
+	conn  net.PacketConn
+	docsC chan map[string]interface{}
+}
+
+func newXRayListener(t *testing.T) *xrayListener {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &xrayListener{
+		conn:  conn,
+		docsC: make(chan map[string]interface{}, 10),
+	}
+	go func() {
+		buf := make([]byte, 65536)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			parts := bytes.SplitN(buf[:n], []byte("\n"), 2)
+			if len(parts) != 2 {
+				t.Errorf("Missing packet header: %s", buf[:n])
+				continue
+			}
+			if exp, act := `{"format": "json", "version": 1}`, string(parts[0]); exp != act {
+				t.Errorf("Wrong packet header: %v != %v", act, exp)
+			}
+			doc := map[string]interface{}{}
+			if err = json.Unmarshal(parts[1], &doc); err != nil {
+				t.Error(err)
+				continue
+			}
+			l.docsC <- doc
+		}
+	}()
+	return l
+}
+
+func (l *xrayListener) next(t *testing.T) map[string]interface{} {
+	select {
+	case doc := <-l.docsC:
+		return doc
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for segment")
+	}
+	return nil
+}
+
+func newTestXRay(t *testing.T, l *xrayListener, reservoir int) Type {
+	conf := NewXRayConfig()
+	conf.Address = l.conn.LocalAddr().String()
+	conf.ServiceName = "foo"
+	conf.Sampling.Reservoir = reservoir
+	conf.Sampling.FixedRate = 0
+
+	x, err := NewXRay(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return x
+}
+
+//------------------------------------------------------------------------------
+
+func TestXRayContinueTrace(t *testing.T) {
+	l := newXRayListener(t)
+	defer l.conn.Close()
+
+	x := newTestXRay(t, l, 0)
+	defer x.Close()
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	msg.Get(0).Metadata().Set(
+		XRayTraceHeaderKey,
+		"Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+	)
+
+	seg := x.StartSegment("sqs", msg)
+
+	header := msg.Get(0).Metadata().Get(XRayTraceHeaderKey)
+	if exp, act := header, msg.Get(1).Metadata().Get(XRayTraceHeaderKey); exp != act {
+		t.Errorf("Mismatched part headers: %v != %v", act, exp)
+	}
+	h, exists := ParseXRayHeader(header)
+	if !exists {
+		t.Fatalf("Header not written: %v", header)
+	}
+	if exp, act := "1-5759e988-bd862e3fe1be46a994272793", h.TraceID; exp != act {
+		t.Errorf("Wrong trace ID: %v != %v", act, exp)
+	}
+
+	subSeg := x.StartSubsegment("jmespath", msg)
+	subSeg.End(errors.New("nope"))
+	seg.End(nil)
+
+	doc := l.next(t)
+	if exp, act := "jmespath", doc["name"]; exp != act {
+		t.Errorf("Wrong subsegment name: %v != %v", act, exp)
+	}
+	if exp, act := "subsegment", doc["type"]; exp != act {
+		t.Errorf("Wrong subsegment type: %v != %v", act, exp)
+	}
+	if exp, act := h.ParentID, doc["parent_id"]; exp != act {
+		t.Errorf("Wrong subsegment parent: %v != %v", act, exp)
+	}
+	if exp, act := true, doc["error"]; exp != act {
+		t.Errorf("Wrong subsegment error: %v != %v", act, exp)
+	}
+
+	doc = l.next(t)
+	if exp, act := "foo", doc["name"]; exp != act {
+		t.Errorf("Wrong segment name: %v != %v", act, exp)
+	}
+	if exp, act := h.ParentID, doc["id"]; exp != act {
+		t.Errorf("Wrong segment ID: %v != %v", act, exp)
+	}
+	if exp, act := "53995c3f42cd8ad8", doc["parent_id"]; exp != act {
+		t.Errorf("Wrong segment parent: %v != %v", act, exp)
+	}
+	if exp, act := "1-5759e988-bd862e3fe1be46a994272793", doc["trace_id"]; exp != act {
+		t.Errorf("Wrong segment trace ID: %v != %v", act, exp)
+	}
+	if _, exists := doc["error"]; exists {
+		t.Error("Unexpected segment error")
+	}
+	annotations, _ := doc["annotations"].(map[string]interface{})
+	if exp, act := "sqs", annotations["input"]; exp != act {
+		t.Errorf("Wrong input annotation: %v != %v", act, exp)
+	}
+}
+
+func TestXRayNewTrace(t *testing.T) {
+	l := newXRayListener(t)
+	defer l.conn.Close()
+
+	x := newTestXRay(t, l, 1)
+	defer x.Close()
+
+	msg := message.New([][]byte{[]byte("foo")})
+	x.StartSegment("stdin", msg).End(nil)
+
+	h, exists := ParseXRayHeader(msg.Get(0).Metadata().Get(XRayTraceHeaderKey))
+	if !exists {
+		t.Fatal("Header not written")
+	}
+	if !strings.HasPrefix(h.TraceID, "1-") || len(h.TraceID) != 35 {
+		t.Errorf("Wrong trace ID format: %v", h.TraceID)
+	}
+	if h.Sampled == nil || !*h.Sampled {
+		t.Error("Expected trace to be sampled")
+	}
+
+	doc := l.next(t)
+	if exp, act := h.TraceID, doc["trace_id"]; exp != act {
+		t.Errorf("Wrong segment trace ID: %v != %v", act, exp)
+	}
+	if _, exists := doc["parent_id"]; exists {
+		t.Error("Unexpected segment parent")
+	}
+}
+
+func TestXRayNotSampled(t *testing.T) {
+	l := newXRayListener(t)
+	defer l.conn.Close()
+
+	x := newTestXRay(t, l, 0)
+	defer x.Close()
+
+	msg := message.New([][]byte{[]byte("foo")})
+	msg.Get(0).Metadata().Set(XRayTraceHeaderKey, "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=0")
+
+	x.StartSegment("stdin", msg).End(nil)
+	x.StartSubsegment("noop", msg).End(nil)
+
+	h, _ := ParseXRayHeader(msg.Get(0).Metadata().Get(XRayTraceHeaderKey))
+	if h.Sampled == nil || *h.Sampled {
+		t.Error("Expected sampling decision to be propagated")
+	}
+
+	msg = message.New([][]byte{[]byte("foo")})
+	x.StartSegment("stdin", msg).End(nil)
+
+	h, _ = ParseXRayHeader(msg.Get(0).Metadata().Get(XRayTraceHeaderKey))
+	if h.Sampled == nil || *h.Sampled {
+		t.Error("Expected new trace not to be sampled")
+	}
+
+	select {
+	case doc := <-l.docsC:
+		t.Errorf("Unexpected segment: %v", doc)
+	case <-time.After(time.Millisecond * 100):
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -44,6 +44,7 @@ var (
 	ErrInvalidOutputType    = errors.New("output type was not recognised")
 
 	ErrInvalidZMQType        = errors.New("invalid ZMQ socket type")
+	ErrZMQ4NotCompiled       = errors.New("zmq4 support was not compiled in, build with the tag ZMQ4 to enable it")
 	ErrInvalidScaleProtoType = errors.New("invalid Scalability Protocols socket type")
 
 	// ErrAlreadyStarted is returned when an input or output type gets started a
@@ -91,3 +92,18 @@ func (e ErrUnexpectedHTTPRes) Error() string {
 }
 
 //------------------------------------------------------------------------------
+
+// ErrPartsFailed is an error returned (as a pointer) by writers when only a subset of the
+// parts of a message failed to send. Indexes contains the sorted indexes of the
+// failed parts and Err describes the failure of the first of them.
+type ErrPartsFailed struct {
+	Indexes []int
+	Err     error
+}
+
+// Error returns the Error string.
+func (e *ErrPartsFailed) Error() string {
+	return fmt.Sprintf("failed to send %v parts %v from message: %v", len(e.Indexes), e.Indexes, e.Err)
+}
+
+//------------------------------------------------------------------------------
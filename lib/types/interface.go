@@ -51,6 +51,21 @@ type Cache interface {
 	Delete(key string) error
 }
 
+// CacheWithTTL is a Cache that supports setting a TTL per key, overriding any
+// expiration configured for the cache as a whole.
+type CacheWithTTL interface {
+	Cache
+
+	// SetWithTTL attempts to set the value of a key with an expiration. A nil
+	// TTL results in the default expiration of the cache being used.
+	SetWithTTL(key string, value []byte, ttl *time.Duration) error
+
+	// AddWithTTL attempts to set the value of a key with an expiration only
+	// if the key does not already exist. A nil TTL results in the default
+	// expiration of the cache being used.
+	AddWithTTL(key string, value []byte, ttl *time.Duration) error
+}
+
 //------------------------------------------------------------------------------
 
 // RateLimit is a strategy for limiting access to a shared resource, this
@@ -84,6 +99,15 @@ type Processor interface {
 	ProcessMessage(Message) ([]Message, Response)
 }
 
+// Flusher is implemented by processors that hold state which can result in
+// messages without receiving new input, such as windows that close after a
+// period of time.
+type Flusher interface {
+	// Flush returns any messages that are ready to be sent onwards. Flush is
+	// called periodically by the pipeline running the processor.
+	Flush() []Message
+}
+
 //------------------------------------------------------------------------------
 
 // Manager is an interface expected by Benthos components that allows them to
@@ -127,6 +151,25 @@ type Closable interface {
 
 //------------------------------------------------------------------------------
 
+// Connector is implemented by components that connect to an external target and
+// are able to report the state of that connection.
+type Connector interface {
+	// Connected returns a boolean indicating whether the component is
+	// currently connected to its target.
+	Connected() bool
+}
+
+// IsConnected returns whether a component is connected to its target.
+// Components that do not implement Connector are always considered connected.
+func IsConnected(c interface{}) bool {
+	if conn, ok := c.(Connector); ok {
+		return conn.Connected()
+	}
+	return true
+}
+
+//------------------------------------------------------------------------------
+
 // Producer is a type that sends messages as transactions and waits for a
 // response back, the response indicates whether the message was successfully
 // propagated to a new destination (and can be discarded from the source.)
@@ -48,6 +48,16 @@ type Metadata interface {
 
 //------------------------------------------------------------------------------
 
+// FailFlagKey is a metadata key used for flagging message parts that have
+// failed a processing step.
+const FailFlagKey = "benthos_processing_failed"
+
+// FailErrorKey is a metadata key containing a description of the error that
+// caused a message part to be flagged as failed, when one is available.
+const FailErrorKey = "benthos_processing_error"
+
+//------------------------------------------------------------------------------
+
 // Part is an interface representing a message part. It contains a byte array
 // of raw data, metadata, and lazily parsed formats of the payload such as JSON.
 type Part interface {
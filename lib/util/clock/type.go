@@ -0,0 +1,152 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+// Clock provides the current time and the ability to wait for durations of
+// time to pass.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After waits for a duration to elapse and then sends the current time on
+	// the returned channel.
+	After(d time.Duration) <-chan time.Time
+
+	// Sleep blocks until a duration has elapsed.
+	Sleep(d time.Duration)
+}
+
+//------------------------------------------------------------------------------
+
+type realClock struct{}
+
+// Real returns a Clock backed by the time package.
+func Real() Clock {
+	return realClock{}
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+//------------------------------------------------------------------------------
+
+type fakeWaiter struct {
+	until time.Time
+	c     chan time.Time
+}
+
+// Fake is a Clock where time only passes when it is explicitly advanced, which
+// is useful for testing time dependent components deterministically.
+type Fake struct {
+	mut     sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+// NewFake returns a Fake clock set to a specific time.
+func NewFake(t time.Time) *Fake {
+	f := &Fake{now: t}
+	f.cond = sync.NewCond(&f.mut)
+	return f
+}
+
+// Now returns the current time of the fake clock.
+func (f *Fake) Now() time.Time {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	return f.now
+}
+
+// After returns a channel that receives the current time of the fake clock once
+// it has been advanced by at least the duration.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	c := make(chan time.Time, 1)
+	if d <= 0 {
+		c <- f.now
+		return c
+	}
+	f.waiters = append(f.waiters, fakeWaiter{
+		until: f.now.Add(d),
+		c:     c,
+	})
+	f.cond.Broadcast()
+	return c
+}
+
+// Sleep blocks until the fake clock has been advanced by at least the
+// duration.
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+// Add advances the fake clock by a duration, waking any waiters whose duration
+// has elapsed.
+func (f *Fake) Add(d time.Duration) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	f.now = f.now.Add(d)
+
+	sort.SliceStable(f.waiters, func(i, j int) bool {
+		return f.waiters[i].until.Before(f.waiters[j].until)
+	})
+	remaining := f.waiters[:0]
+	for _, w := range f.waiters {
+		if w.until.After(f.now) {
+			remaining = append(remaining, w)
+			continue
+		}
+		w.c <- f.now
+	}
+	f.waiters = remaining
+}
+
+// BlockUntil blocks until at least n calls to After or Sleep are waiting on
+// the fake clock to be advanced.
+func (f *Fake) BlockUntil(n int) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	for len(f.waiters) < n {
+		f.cond.Wait()
+	}
+}
+
+//------------------------------------------------------------------------------
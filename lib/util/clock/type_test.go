@@ -0,0 +1,126 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+func TestRealClock(t *testing.T) {
+	c := Real()
+
+	before := time.Now()
+	if now := c.Now(); now.Before(before) {
+		t.Errorf("Real clock is behind: %v < %v", now, before)
+	}
+
+	select {
+	case <-c.After(time.Millisecond):
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for real clock")
+	}
+}
+
+func TestFakeClockNow(t *testing.T) {
+	start := time.Date(2018, 11, 26, 10, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+
+	if exp, act := start, c.Now(); !exp.Equal(act) {
+		t.Errorf("Wrong time: %v != %v", act, exp)
+	}
+	c.Add(time.Minute)
+	if exp, act := start.Add(time.Minute), c.Now(); !exp.Equal(act) {
+		t.Errorf("Wrong time: %v != %v", act, exp)
+	}
+}
+
+func TestFakeClockAfter(t *testing.T) {
+	start := time.Date(2018, 11, 26, 10, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+
+	select {
+	case <-c.After(0):
+	default:
+		t.Error("Expected zero duration to fire immediately")
+	}
+
+	shortChan := c.After(time.Second)
+	longChan := c.After(time.Second * 10)
+
+	c.Add(time.Millisecond * 500)
+	select {
+	case <-shortChan:
+		t.Error("Short waiter fired early")
+	case <-longChan:
+		t.Error("Long waiter fired early")
+	default:
+	}
+
+	c.Add(time.Millisecond * 500)
+	select {
+	case ts := <-shortChan:
+		if exp := start.Add(time.Second); !exp.Equal(ts) {
+			t.Errorf("Wrong time: %v != %v", ts, exp)
+		}
+	default:
+		t.Error("Short waiter did not fire")
+	}
+	select {
+	case <-longChan:
+		t.Error("Long waiter fired early")
+	default:
+	}
+
+	c.Add(time.Minute)
+	select {
+	case <-longChan:
+	default:
+		t.Error("Long waiter did not fire")
+	}
+}
+
+func TestFakeClockSleep(t *testing.T) {
+	c := NewFake(time.Unix(0, 0))
+
+	done := make(chan struct{})
+	go func() {
+		c.Sleep(time.Second)
+		close(done)
+	}()
+
+	c.BlockUntil(1)
+	select {
+	case <-done:
+		t.Fatal("Sleep returned early")
+	default:
+	}
+
+	c.Add(time.Second)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for sleep to return")
+	}
+}
+
+//------------------------------------------------------------------------------
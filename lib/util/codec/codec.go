@@ -0,0 +1,147 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+//------------------------------------------------------------------------------
+
+// Documentation is a markdown description of the available codecs.
+const Documentation = `### Codecs
+
+The ` + "`codec`" + ` field determines how messages are framed within a stream:
+
+- ` + "`lines`" + `: Messages are delimited by a line feed, with any trailing
+  carriage return removed.
+- ` + "`delim`" + `: Messages are delimited by the string specified in the
+  ` + "`delimiter`" + ` field.
+- ` + "`length_prefixed`" + `: Each message is preceded by its length in bytes
+  as a four byte big endian unsigned integer.`
+
+//------------------------------------------------------------------------------
+
+// ErrEmptyDelimiter is returned when the delim codec is used without a
+// delimiter.
+var ErrEmptyDelimiter = errors.New("the delim codec requires a delimiter")
+
+// NewSplitFunc returns a bufio.SplitFunc that extracts messages from a stream
+// framed with a codec. The delimiter is only used by the delim codec.
+func NewSplitFunc(codec, delimiter string) (bufio.SplitFunc, error) {
+	switch codec {
+	case "lines":
+		return bufio.ScanLines, nil
+	case "delim":
+		if len(delimiter) == 0 {
+			return nil, ErrEmptyDelimiter
+		}
+		return splitDelim([]byte(delimiter)), nil
+	case "length_prefixed":
+		return splitLengthPrefixed, nil
+	}
+	return nil, fmt.Errorf("codec not recognised: %v", codec)
+}
+
+func splitDelim(delim []byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		if i := bytes.Index(data, delim); i >= 0 {
+			return i + len(delim), data[:i], nil
+		}
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// ErrTruncatedFrame is returned when a stream ends part way through a length
+// prefixed message.
+var ErrTruncatedFrame = errors.New("stream ended within a length prefixed message")
+
+func splitLengthPrefixed(data []byte, atEOF bool) (int, []byte, error) {
+	if len(data) < 4 {
+		if atEOF && len(data) > 0 {
+			return 0, nil, ErrTruncatedFrame
+		}
+		return 0, nil, nil
+	}
+	size := int(binary.BigEndian.Uint32(data))
+	if len(data) < size+4 {
+		if atEOF {
+			return 0, nil, ErrTruncatedFrame
+		}
+		return 0, nil, nil
+	}
+	return size + 4, data[4 : size+4], nil
+}
+
+//------------------------------------------------------------------------------
+
+// FrameFunc appends a framed message to a buffer.
+type FrameFunc func(buf *bytes.Buffer, msg []byte) error
+
+// NewFrameFunc returns a FrameFunc that frames messages with a codec such that
+// they can be extracted by the SplitFunc of the same codec. The delimiter is
+// only used by the delim codec.
+func NewFrameFunc(codec, delimiter string) (FrameFunc, error) {
+	switch codec {
+	case "lines":
+		return frameDelim([]byte("\n")), nil
+	case "delim":
+		if len(delimiter) == 0 {
+			return nil, ErrEmptyDelimiter
+		}
+		return frameDelim([]byte(delimiter)), nil
+	case "length_prefixed":
+		return frameLengthPrefixed, nil
+	}
+	return nil, fmt.Errorf("codec not recognised: %v", codec)
+}
+
+func frameDelim(delim []byte) FrameFunc {
+	return func(buf *bytes.Buffer, msg []byte) error {
+		buf.Write(msg)
+		buf.Write(delim)
+		return nil
+	}
+}
+
+func frameLengthPrefixed(buf *bytes.Buffer, msg []byte) error {
+	if uint64(len(msg)) > math.MaxUint32 {
+		return fmt.Errorf("message of size %v exceeds length prefix", len(msg))
+	}
+	var prefix [4]byte
+	binary.BigEndian.PutUint32(prefix[:], uint32(len(msg)))
+	buf.Write(prefix[:])
+	buf.Write(msg)
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,173 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+//------------------------------------------------------------------------------
+
+func scanAll(t *testing.T, codec, delim string, input []byte) ([]string, error) {
+	t.Helper()
+
+	split, err := NewSplitFunc(codec, delim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(input))
+	scanner.Split(split)
+
+	var res []string
+	for scanner.Scan() {
+		res = append(res, scanner.Text())
+	}
+	return res, scanner.Err()
+}
+
+func TestSplitFuncs(t *testing.T) {
+	type testCase struct {
+		codec  string
+		delim  string
+		input  []byte
+		output []string
+	}
+
+	tests := map[string]testCase{
+		"lines": {
+			codec:  "lines",
+			input:  []byte("foo\nbar\r\nbaz"),
+			output: []string{"foo", "bar", "baz"},
+		},
+		"delim": {
+			codec:  "delim",
+			delim:  "||",
+			input:  []byte("foo||bar||baz||"),
+			output: []string{"foo", "bar", "baz"},
+		},
+		"length prefixed": {
+			codec: "length_prefixed",
+			input: []byte(
+				"\x00\x00\x00\x03foo\x00\x00\x00\x00\x00\x00\x00\x07bar\nbaz",
+			),
+			output: []string{"foo", "", "bar\nbaz"},
+		},
+	}
+
+	for name, test := range tests {
+		res, err := scanAll(t, test.codec, test.delim, test.input)
+		if err != nil {
+			t.Errorf("%v: %v", name, err)
+		}
+		if !reflect.DeepEqual(test.output, res) {
+			t.Errorf("%v: wrong result: %q != %q", name, res, test.output)
+		}
+	}
+}
+
+func TestSplitLengthPrefixedTruncated(t *testing.T) {
+	res, err := scanAll(t, "length_prefixed", "", []byte("\x00\x00\x00\x03foo\x00\x00\x00\x05ba"))
+	if err != ErrTruncatedFrame {
+		t.Errorf("Wrong error: %v != %v", err, ErrTruncatedFrame)
+	}
+	if exp := []string{"foo"}; !reflect.DeepEqual(exp, res) {
+		t.Errorf("Wrong result: %q != %q", res, exp)
+	}
+}
+
+func TestSplitFuncErrors(t *testing.T) {
+	if _, err := NewSplitFunc("delim", ""); err != ErrEmptyDelimiter {
+		t.Errorf("Wrong error: %v != %v", err, ErrEmptyDelimiter)
+	}
+	if _, err := NewSplitFunc("nope", ""); err == nil {
+		t.Error("Expected error from unrecognised codec")
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func TestFrameFuncs(t *testing.T) {
+	msgs := []string{"foo", "bar\nbaz", "qux"}
+	tests := map[string]struct {
+		codec string
+		delim string
+		exp   string
+	}{
+		"lines": {
+			codec: "lines",
+			exp:   "foo\nbar\nbaz\nqux\n",
+		},
+		"delim": {
+			codec: "delim",
+			delim: "||",
+			exp:   "foo||bar\nbaz||qux||",
+		},
+		"length prefixed": {
+			codec: "length_prefixed",
+			exp:   "\x00\x00\x00\x03foo\x00\x00\x00\x07bar\nbaz\x00\x00\x00\x03qux",
+		},
+	}
+
+	for name, test := range tests {
+		frame, err := NewFrameFunc(test.codec, test.delim)
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		buf := bytes.Buffer{}
+		for _, msg := range msgs {
+			if err = frame(&buf, []byte(msg)); err != nil {
+				t.Fatalf("%v: %v", name, err)
+			}
+		}
+		if act := buf.String(); act != test.exp {
+			t.Errorf("%v: wrong result: %q != %q", name, act, test.exp)
+		}
+	}
+
+	// Messages without delimiters should survive a round trip through the
+	// matching split func.
+	for _, codec := range []string{"delim", "length_prefixed"} {
+		frame, _ := NewFrameFunc(codec, "||")
+		buf := bytes.Buffer{}
+		for _, msg := range msgs {
+			frame(&buf, []byte(msg))
+		}
+		res, err := scanAll(t, codec, "||", buf.Bytes())
+		if err != nil {
+			t.Error(err)
+		}
+		if !reflect.DeepEqual(msgs, res) {
+			t.Errorf("%v: wrong round trip result: %q != %q", codec, res, msgs)
+		}
+	}
+
+	if _, err := NewFrameFunc("delim", ""); err != ErrEmptyDelimiter {
+		t.Errorf("Wrong error: %v != %v", err, ErrEmptyDelimiter)
+	}
+	if _, err := NewFrameFunc("nope", ""); err == nil {
+		t.Error("Expected error from unrecognised codec")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,65 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"fmt"
+)
+
+//------------------------------------------------------------------------------
+
+// Codec is a gRPC codec that passes serialized messages through untouched,
+// allowing payloads to be forwarded without knowledge of their proto types.
+// Values marshalled and unmarshalled must be of type *[]byte.
+type Codec struct{}
+
+// Marshal returns the raw bytes of a message.
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	switch t := v.(type) {
+	case *[]byte:
+		return *t, nil
+	case []byte:
+		return t, nil
+	}
+	return nil, fmt.Errorf("unable to marshal type %T as raw bytes", v)
+}
+
+// Unmarshal writes the raw bytes of a message into v.
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	t, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unable to unmarshal raw bytes into type %T", v)
+	}
+	*t = append((*t)[:0], data...)
+	return nil
+}
+
+// Name returns the name of the codec.
+func (Codec) Name() string {
+	return "raw"
+}
+
+// String returns the name of the codec.
+func (c Codec) String() string {
+	return c.Name()
+}
+
+//------------------------------------------------------------------------------
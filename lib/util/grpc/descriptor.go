@@ -0,0 +1,120 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+//------------------------------------------------------------------------------
+
+// Method describes a gRPC method that can be invoked or served.
+type Method struct {
+	// FullName is the method path used on the wire in the form
+	// /package.Service/Method.
+	FullName string
+
+	// InputType is the fully qualified name of the request message type,
+	// which is empty when the method was not resolved from a descriptor set.
+	InputType string
+
+	// OutputType is the fully qualified name of the response message type,
+	// which is empty when the method was not resolved from a descriptor set.
+	OutputType string
+
+	ClientStreaming bool
+	ServerStreaming bool
+}
+
+// MethodPath normalises a method name into the wire form
+// /package.Service/Method. Names can be given as package.Service/Method,
+// /package.Service/Method or package.Service.Method.
+func MethodPath(name string) (string, error) {
+	name = strings.TrimPrefix(name, "/")
+	if i := strings.LastIndex(name, "/"); i == -1 {
+		if i = strings.LastIndex(name, "."); i == -1 {
+			return "", fmt.Errorf("method name '%v' must contain a service", name)
+		}
+		name = name[:i] + "/" + name[i+1:]
+	}
+	i := strings.LastIndex(name, "/")
+	if i == 0 || i == len(name)-1 || strings.Contains(name[:i], "/") {
+		return "", fmt.Errorf("method name '%v' is malformed", name)
+	}
+	return "/" + name, nil
+}
+
+// ResolveMethod parses a method name and, if a path to a serialized proto
+// FileDescriptorSet is provided, checks that the method exists within it and
+// reads its types and streaming modes. When descriptorPath is empty the method
+// is assumed to be unary.
+func ResolveMethod(descriptorPath, name string) (Method, error) {
+	path, err := MethodPath(name)
+	if err != nil {
+		return Method{}, err
+	}
+	m := Method{FullName: path}
+	if len(descriptorPath) == 0 {
+		return m, nil
+	}
+
+	var setBytes []byte
+	if setBytes, err = ioutil.ReadFile(descriptorPath); err != nil {
+		return m, fmt.Errorf("failed to read descriptor set: %v", err)
+	}
+	set := &descriptor.FileDescriptorSet{}
+	if err = proto.Unmarshal(setBytes, set); err != nil {
+		return m, fmt.Errorf("failed to parse descriptor set: %v", err)
+	}
+
+	sep := strings.LastIndex(path, "/")
+	serviceName, methodName := path[1:sep], path[sep+1:]
+	for _, file := range set.File {
+		for _, svc := range file.Service {
+			fullService := svc.GetName()
+			if pkg := file.GetPackage(); len(pkg) > 0 {
+				fullService = pkg + "." + fullService
+			}
+			if fullService != serviceName {
+				continue
+			}
+			for _, method := range svc.Method {
+				if method.GetName() != methodName {
+					continue
+				}
+				m.InputType = strings.TrimPrefix(method.GetInputType(), ".")
+				m.OutputType = strings.TrimPrefix(method.GetOutputType(), ".")
+				m.ClientStreaming = method.GetClientStreaming()
+				m.ServerStreaming = method.GetServerStreaming()
+				return m, nil
+			}
+			return m, fmt.Errorf("method '%v' not found in service '%v'", methodName, serviceName)
+		}
+	}
+	return m, fmt.Errorf("service '%v' not found in descriptor set", serviceName)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,150 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+//------------------------------------------------------------------------------
+
+func TestMethodPath(t *testing.T) {
+	tests := map[string]string{
+		"foo.Bar/Baz":     "/foo.Bar/Baz",
+		"/foo.Bar/Baz":    "/foo.Bar/Baz",
+		"foo.Bar.Baz":     "/foo.Bar/Baz",
+		"a.b.Service.Get": "/a.b.Service/Get",
+		"Service/Get":     "/Service/Get",
+	}
+	for input, exp := range tests {
+		act, err := MethodPath(input)
+		if err != nil {
+			t.Errorf("Unexpected error for '%v': %v", input, err)
+		}
+		if act != exp {
+			t.Errorf("Wrong result for '%v': %v != %v", input, act, exp)
+		}
+	}
+
+	for _, input := range []string{"", "foo", "/Baz", "foo.Bar/", "a/b/c"} {
+		if _, err := MethodPath(input); err == nil {
+			t.Errorf("Expected error for '%v'", input)
+		}
+	}
+}
+
+func writeTestDescriptorSet(t *testing.T) (string, func()) {
+	tmpDir, err := ioutil.TempDir("", "benthos_grpc_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set := &descriptor.FileDescriptorSet{
+		File: []*descriptor.FileDescriptorProto{
+			{
+				Name:    proto.String("foo.proto"),
+				Package: proto.String("foo.v1"),
+				Service: []*descriptor.ServiceDescriptorProto{
+					{
+						Name: proto.String("Ingest"),
+						Method: []*descriptor.MethodDescriptorProto{
+							{
+								Name:       proto.String("Send"),
+								InputType:  proto.String(".foo.v1.Event"),
+								OutputType: proto.String(".google.protobuf.Empty"),
+							},
+							{
+								Name:            proto.String("Stream"),
+								InputType:       proto.String(".foo.v1.Event"),
+								OutputType:      proto.String(".google.protobuf.Empty"),
+								ClientStreaming: proto.Bool(true),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	setBytes, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(tmpDir, "set.pb")
+	if err = ioutil.WriteFile(path, setBytes, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path, func() {
+		os.RemoveAll(tmpDir)
+	}
+}
+
+func TestResolveMethod(t *testing.T) {
+	path, cleanup := writeTestDescriptorSet(t)
+	defer cleanup()
+
+	m, err := ResolveMethod(path, "foo.v1.Ingest/Send")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Method{
+		FullName:   "/foo.v1.Ingest/Send",
+		InputType:  "foo.v1.Event",
+		OutputType: "google.protobuf.Empty",
+	}
+	if m != exp {
+		t.Errorf("Wrong method: %+v != %+v", m, exp)
+	}
+
+	if m, err = ResolveMethod(path, "foo.v1.Ingest.Stream"); err != nil {
+		t.Fatal(err)
+	}
+	if !m.ClientStreaming || m.ServerStreaming {
+		t.Errorf("Wrong streaming modes: %+v", m)
+	}
+
+	if _, err = ResolveMethod(path, "foo.v1.Ingest/Nope"); err == nil {
+		t.Error("Expected error from missing method")
+	}
+	if _, err = ResolveMethod(path, "foo.v2.Ingest/Send"); err == nil {
+		t.Error("Expected error from missing service")
+	}
+	if _, err = ResolveMethod(path+".nope", "foo.v1.Ingest/Send"); err == nil {
+		t.Error("Expected error from missing file")
+	}
+}
+
+func TestResolveMethodNoDescriptor(t *testing.T) {
+	m, err := ResolveMethod("", "foo.v1.Ingest/Stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := (Method{FullName: "/foo.v1.Ingest/Stream"}); m != exp {
+		t.Errorf("Wrong method: %+v != %+v", m, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
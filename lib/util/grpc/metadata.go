@@ -0,0 +1,58 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"strings"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"google.golang.org/grpc/metadata"
+)
+
+//------------------------------------------------------------------------------
+
+// MetadataFromGRPC copies gRPC metadata into Benthos metadata, only the first
+// value of each key is taken and HTTP/2 pseudo headers are ignored.
+func MetadataFromGRPC(md metadata.MD, meta types.Metadata) {
+	for k, v := range md {
+		if len(v) == 0 || strings.HasPrefix(k, ":") {
+			continue
+		}
+		meta.Set(k, v[0])
+	}
+}
+
+// MetadataToGRPC creates gRPC metadata from Benthos metadata. Keys are
+// lowercased and those reserved by gRPC (prefixed with grpc- or :) are ignored.
+func MetadataToGRPC(meta types.Metadata) metadata.MD {
+	md := metadata.MD{}
+	meta.Iter(func(k, v string) error {
+		k = strings.ToLower(k)
+		if len(k) == 0 || strings.HasPrefix(k, "grpc-") || strings.HasPrefix(k, ":") {
+			return nil
+		}
+		md[k] = []string{v}
+		return nil
+	})
+	return md
+}
+
+//------------------------------------------------------------------------------
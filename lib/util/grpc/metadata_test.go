@@ -0,0 +1,69 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/message/metadata"
+	gmetadata "google.golang.org/grpc/metadata"
+)
+
+//------------------------------------------------------------------------------
+
+func TestMetadataFromGRPC(t *testing.T) {
+	md := gmetadata.MD{
+		":authority": []string{"localhost"},
+		"foo":        []string{"bar", "baz"},
+		"empty":      []string{},
+	}
+	meta := metadata.New(nil)
+	MetadataFromGRPC(md, meta)
+
+	exp := map[string]string{"foo": "bar"}
+	act := map[string]string{}
+	meta.Iter(func(k, v string) error {
+		act[k] = v
+		return nil
+	})
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+}
+
+func TestMetadataToGRPC(t *testing.T) {
+	meta := metadata.New(map[string]string{
+		"Foo":          "bar",
+		"grpc-timeout": "1s",
+		"baz":          "qux",
+	})
+
+	exp := gmetadata.MD{
+		"foo": []string{"bar"},
+		"baz": []string{"qux"},
+	}
+	if act := MetadataToGRPC(meta); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -40,24 +40,26 @@ import (
 	"github.com/Jeffail/benthos/lib/util/text"
 	"github.com/Jeffail/benthos/lib/util/throttle"
 	"github.com/Jeffail/benthos/lib/util/tls"
+	"github.com/Jeffail/benthos/lib/util/tracecontext"
 )
 
 //------------------------------------------------------------------------------
 
 // Config is a configuration struct for an HTTP client.
 type Config struct {
-	URL          string            `json:"url" yaml:"url"`
-	Verb         string            `json:"verb" yaml:"verb"`
-	Headers      map[string]string `json:"headers" yaml:"headers"`
-	RateLimit    string            `json:"rate_limit" yaml:"rate_limit"`
-	TimeoutMS    int64             `json:"timeout_ms" yaml:"timeout_ms"`
-	RetryMS      int64             `json:"retry_period_ms" yaml:"retry_period_ms"`
-	MaxBackoffMS int64             `json:"max_retry_backoff_ms" yaml:"max_retry_backoff_ms"`
-	NumRetries   int               `json:"retries" yaml:"retries"`
-	BackoffOn    []int             `json:"backoff_on" yaml:"backoff_on"`
-	DropOn       []int             `json:"drop_on" yaml:"drop_on"`
-	TLS          tls.Config        `json:"tls" yaml:"tls"`
-	auth.Config  `json:",inline" yaml:",inline"`
+	URL                   string            `json:"url" yaml:"url"`
+	Verb                  string            `json:"verb" yaml:"verb"`
+	Headers               map[string]string `json:"headers" yaml:"headers"`
+	RateLimit             string            `json:"rate_limit" yaml:"rate_limit"`
+	TimeoutMS             int64             `json:"timeout_ms" yaml:"timeout_ms"`
+	RetryMS               int64             `json:"retry_period_ms" yaml:"retry_period_ms"`
+	MaxBackoffMS          int64             `json:"max_retry_backoff_ms" yaml:"max_retry_backoff_ms"`
+	NumRetries            int               `json:"retries" yaml:"retries"`
+	BackoffOn             []int             `json:"backoff_on" yaml:"backoff_on"`
+	DropOn                []int             `json:"drop_on" yaml:"drop_on"`
+	TLS                   tls.Config        `json:"tls" yaml:"tls"`
+	PropagateTraceContext bool              `json:"propagate_trace_context" yaml:"propagate_trace_context"`
+	auth.Config           `json:",inline" yaml:",inline"`
 }
 
 // NewConfig creates a new Config with default values.
@@ -68,15 +70,16 @@ func NewConfig() Config {
 		Headers: map[string]string{
 			"Content-Type": "application/octet-stream",
 		},
-		RateLimit:    "",
-		TimeoutMS:    5000,
-		RetryMS:      1000,
-		MaxBackoffMS: 300000,
-		NumRetries:   3,
-		BackoffOn:    []int{429},
-		DropOn:       []int{},
-		TLS:          tls.NewConfig(),
-		Config:       auth.NewConfig(),
+		RateLimit:             "",
+		TimeoutMS:             5000,
+		RetryMS:               1000,
+		MaxBackoffMS:          300000,
+		NumRetries:            3,
+		BackoffOn:             []int{429},
+		DropOn:                []int{},
+		TLS:                   tls.NewConfig(),
+		PropagateTraceContext: false,
+		Config:                auth.NewConfig(),
 	}
 }
 
@@ -305,6 +308,13 @@ func (h *Type) CreateRequest(msg types.Message) (req *http.Request, err error) {
 		}
 	}
 
+	if err != nil {
+		return
+	}
+	if h.conf.PropagateTraceContext && msg != nil && msg.Len() > 0 {
+		tracecontext.Inject(msg.Get(0).Metadata(), req.Header.Set)
+	}
+
 	err = h.conf.Config.Sign(req)
 	return
 }
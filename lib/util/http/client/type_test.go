@@ -71,6 +71,46 @@ func TestHTTPClientRetries(t *testing.T) {
 	}
 }
 
+func TestHTTPClientTraceContext(t *testing.T) {
+	parent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	msg := message.New([][]byte{[]byte("test")})
+	msg.Get(0).Metadata().
+		Set("traceparent", parent).
+		Set("tracestate", "foo=bar")
+
+	conf := NewConfig()
+	conf.URL = "http://localhost:4195/testpost"
+
+	h, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := h.CreateRequest(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := req.Header.Get("traceparent"); len(v) > 0 {
+		t.Errorf("Unexpected traceparent header: %v", v)
+	}
+
+	conf.PropagateTraceContext = true
+	if h, err = New(conf); err != nil {
+		t.Fatal(err)
+	}
+
+	if req, err = h.CreateRequest(msg); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := parent, req.Header.Get("traceparent"); exp != act {
+		t.Errorf("Wrong traceparent header: %v != %v", act, exp)
+	}
+	if exp, act := "foo=bar", req.Header.Get("tracestate"); exp != act {
+		t.Errorf("Wrong tracestate header: %v != %v", act, exp)
+	}
+}
+
 func TestHTTPClientSendBasic(t *testing.T) {
 	nTestLoops := 1000
 
@@ -0,0 +1,195 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sasl
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Shopify/sarama"
+)
+
+//------------------------------------------------------------------------------
+
+// Documentation is a markdown description of how and why to use SASL settings.
+const Documentation = `### SASL
+
+SASL authentication can be enabled with the ` + "`sasl`" + ` fields. It can be
+combined with TLS, in which case authentication takes place over the encrypted
+connection. Since the ` + "`PLAIN`" + ` mechanism sends credentials unencrypted
+it is strongly recommended to enable TLS alongside it:
+
+` + "``` yaml" + `
+tls:
+  enabled: true
+sasl:
+  enabled: true
+  mechanism: PLAIN
+  user: foo
+  password: bar
+` + "```" + `
+
+The mechanisms ` + "`SCRAM-SHA-256`" + ` and ` + "`SCRAM-SHA-512`" + ` use the
+same ` + "`user`" + ` and ` + "`password`" + ` fields, but the password is never
+sent to the broker.
+
+The ` + "`OAUTHBEARER`" + ` mechanism authenticates with a bearer token. A static
+token can be set with the field ` + "`access_token`" + `, alternatively the field
+` + "`token_provider`" + ` names a provider of fresh tokens that has been
+registered by a plugin with ` + "`sasl.RegisterTokenProvider`" + `:
+
+` + "``` yaml" + `
+tls:
+  enabled: true
+sasl:
+  enabled: true
+  mechanism: OAUTHBEARER
+  token_provider: my_provider
+` + "```" + `
+
+When brokers cannot be reached with SASL enabled the error returned indicates
+that the SASL credentials and TLS settings should be checked, as a failed SASL
+handshake causes the broker connection to be closed.`
+
+//------------------------------------------------------------------------------
+
+// Mechanisms of SASL authentication.
+const (
+	MechanismPlain       = "PLAIN"
+	MechanismSCRAMSHA256 = "SCRAM-SHA-256"
+	MechanismSCRAMSHA512 = "SCRAM-SHA-512"
+	MechanismOAuthBearer = "OAUTHBEARER"
+)
+
+// TokenProviderConstructor is a func that creates a provider of access tokens
+// for the OAUTHBEARER mechanism.
+type TokenProviderConstructor func() (sarama.AccessTokenProvider, error)
+
+var tokenProviders = map[string]TokenProviderConstructor{}
+
+// RegisterTokenProvider registers a named constructor of OAUTHBEARER access
+// token providers, which can then be selected with the field token_provider.
+// This should be called from an init function before any configs are parsed.
+func RegisterTokenProvider(name string, ctor TokenProviderConstructor) {
+	tokenProviders[name] = ctor
+}
+
+// staticTokenProvider provides the same access token for every request.
+type staticTokenProvider string
+
+// Token returns the access token.
+func (s staticTokenProvider) Token() (*sarama.AccessToken, error) {
+	return &sarama.AccessToken{Token: string(s)}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Config contains configuration params for SASL authentication.
+type Config struct {
+	Enabled       bool   `json:"enabled" yaml:"enabled"`
+	Mechanism     string `json:"mechanism" yaml:"mechanism"`
+	User          string `json:"user" yaml:"user"`
+	Password      string `json:"password" yaml:"password"`
+	AccessToken   string `json:"access_token" yaml:"access_token"`
+	TokenProvider string `json:"token_provider" yaml:"token_provider"`
+}
+
+// NewConfig creates a new Config with default values.
+func NewConfig() Config {
+	return Config{
+		Enabled:       false,
+		Mechanism:     MechanismPlain,
+		User:          "",
+		Password:      "",
+		AccessToken:   "",
+		TokenProvider: "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Validate checks that the config describes a usable SASL mechanism.
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	switch c.Mechanism {
+	case MechanismPlain, MechanismSCRAMSHA256, MechanismSCRAMSHA512:
+		if len(c.User) == 0 {
+			return errors.New("SASL user must not be empty")
+		}
+	case MechanismOAuthBearer:
+		if len(c.TokenProvider) > 0 {
+			if _, exists := tokenProviders[c.TokenProvider]; !exists {
+				return fmt.Errorf("SASL token provider '%v' not recognised", c.TokenProvider)
+			}
+		} else if len(c.AccessToken) == 0 {
+			return errors.New("SASL access token or token provider must be set for mechanism OAUTHBEARER")
+		}
+	default:
+		return fmt.Errorf("SASL mechanism '%v' not recognised", c.Mechanism)
+	}
+	return nil
+}
+
+// Apply sets the SASL fields of a sarama config.
+func (c Config) Apply(conf *sarama.Config) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if !c.Enabled {
+		return nil
+	}
+
+	switch c.Mechanism {
+	case MechanismOAuthBearer:
+		var provider sarama.AccessTokenProvider = staticTokenProvider(c.AccessToken)
+		if len(c.TokenProvider) > 0 {
+			var err error
+			if provider, err = tokenProviders[c.TokenProvider](); err != nil {
+				return fmt.Errorf("failed to create SASL token provider: %v", err)
+			}
+		}
+		conf.Net.SASL.TokenProvider = provider
+	case MechanismSCRAMSHA256, MechanismSCRAMSHA512:
+		conf.Net.SASL.SCRAMClientGeneratorFunc = newSCRAMClientGenerator(c.Mechanism)
+		fallthrough
+	default:
+		conf.Net.SASL.User = c.User
+		conf.Net.SASL.Password = c.Password
+	}
+
+	conf.Net.SASL.Mechanism = sarama.SASLMechanism(c.Mechanism)
+	conf.Net.SASL.Enable = true
+	return nil
+}
+
+// ConnectErr returns an error describing a failure to connect to brokers. When
+// SASL is enabled a failed handshake results in the brokers being reported as
+// unavailable, and therefore the error is annotated accordingly.
+func (c Config) ConnectErr(err error) error {
+	if c.Enabled && err == sarama.ErrOutOfBrokers {
+		return fmt.Errorf("%v, check that the SASL credentials and TLS settings are correct", err)
+	}
+	return err
+}
+
+//------------------------------------------------------------------------------
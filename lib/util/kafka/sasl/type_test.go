@@ -0,0 +1,283 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sasl
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/util/tls"
+	"github.com/Shopify/sarama"
+)
+
+//------------------------------------------------------------------------------
+
+func TestValidate(t *testing.T) {
+	tests := map[string]struct {
+		conf   Config
+		errStr string
+	}{
+		"disabled": {
+			conf: Config{Enabled: false, Mechanism: "nope"},
+		},
+		"plain": {
+			conf: Config{Enabled: true, Mechanism: MechanismPlain, User: "foo", Password: "bar"},
+		},
+		"plain no user": {
+			conf:   Config{Enabled: true, Mechanism: MechanismPlain},
+			errStr: "SASL user must not be empty",
+		},
+		"scram": {
+			conf: Config{Enabled: true, Mechanism: MechanismSCRAMSHA512, User: "foo", Password: "bar"},
+		},
+		"scram no user": {
+			conf:   Config{Enabled: true, Mechanism: MechanismSCRAMSHA256},
+			errStr: "SASL user must not be empty",
+		},
+		"oauth static": {
+			conf: Config{Enabled: true, Mechanism: MechanismOAuthBearer, AccessToken: "foo"},
+		},
+		"oauth nothing": {
+			conf:   Config{Enabled: true, Mechanism: MechanismOAuthBearer},
+			errStr: "SASL access token or token provider must be set for mechanism OAUTHBEARER",
+		},
+		"oauth unknown provider": {
+			conf:   Config{Enabled: true, Mechanism: MechanismOAuthBearer, TokenProvider: "nope"},
+			errStr: "SASL token provider 'nope' not recognised",
+		},
+		"unknown": {
+			conf:   Config{Enabled: true, Mechanism: "nope", User: "foo"},
+			errStr: "SASL mechanism 'nope' not recognised",
+		},
+	}
+
+	for name, test := range tests {
+		err := test.conf.Validate()
+		if len(test.errStr) == 0 {
+			if err != nil {
+				t.Errorf("%v: unexpected error: %v", name, err)
+			}
+		} else if err == nil {
+			t.Errorf("%v: expected error", name)
+		} else if exp, act := test.errStr, err.Error(); exp != act {
+			t.Errorf("%v: wrong error: %v != %v", name, act, exp)
+		}
+	}
+}
+
+func newTLSSaramaConfig(t *testing.T) *sarama.Config {
+	t.Helper()
+
+	tlsConf := tls.NewConfig()
+	tlsConf.Enabled = true
+	tlsConf.InsecureSkipVerify = true
+	tc, err := tlsConf.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sConf := sarama.NewConfig()
+	sConf.Version = sarama.V1_0_0_0
+	sConf.Net.TLS.Enable = true
+	sConf.Net.TLS.Config = tc
+	return sConf
+}
+
+func TestApply(t *testing.T) {
+	tlsConf := tls.NewConfig()
+	tlsConf.Enabled = true
+	tlsConf.InsecureSkipVerify = true
+	tc, err := tlsConf.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewConfig()
+	conf.Enabled = true
+	conf.User = "foo"
+	conf.Password = "bar"
+
+	sConf := sarama.NewConfig()
+	sConf.Net.TLS.Enable = true
+	sConf.Net.TLS.Config = tc
+	if err = conf.Apply(sConf); err != nil {
+		t.Fatal(err)
+	}
+	if err = sConf.Validate(); err != nil {
+		t.Error(err)
+	}
+	if !sConf.Net.SASL.Enable {
+		t.Error("Expected SASL to be enabled")
+	}
+	if exp, act := "foo", sConf.Net.SASL.User; exp != act {
+		t.Errorf("Wrong user: %v != %v", act, exp)
+	}
+	if exp, act := "bar", sConf.Net.SASL.Password; exp != act {
+		t.Errorf("Wrong password: %v != %v", act, exp)
+	}
+	if !sConf.Net.TLS.Enable || sConf.Net.TLS.Config != tc {
+		t.Error("Expected TLS config to be preserved")
+	}
+
+	conf.Mechanism = "nope"
+	sConf = sarama.NewConfig()
+	if err = conf.Apply(sConf); err == nil {
+		t.Error("Expected error from unknown mechanism")
+	}
+	if sConf.Net.SASL.Enable {
+		t.Error("Expected SASL to remain disabled")
+	}
+}
+
+func TestApplySCRAMWithTLS(t *testing.T) {
+	for _, mechanism := range []string{MechanismSCRAMSHA256, MechanismSCRAMSHA512} {
+		conf := NewConfig()
+		conf.Enabled = true
+		conf.Mechanism = mechanism
+		conf.User = "foo"
+		conf.Password = "bar"
+
+		sConf := newTLSSaramaConfig(t)
+		if err := conf.Apply(sConf); err != nil {
+			t.Fatalf("%v: %v", mechanism, err)
+		}
+		if err := sConf.Validate(); err != nil {
+			t.Errorf("%v: %v", mechanism, err)
+		}
+		if exp, act := sarama.SASLMechanism(mechanism), sConf.Net.SASL.Mechanism; exp != act {
+			t.Errorf("Wrong mechanism: %v != %v", act, exp)
+		}
+		if !sConf.Net.TLS.Enable {
+			t.Errorf("%v: expected TLS to remain enabled", mechanism)
+		}
+
+		client := sConf.Net.SASL.SCRAMClientGeneratorFunc()
+		if err := client.Begin("foo", "bar", ""); err != nil {
+			t.Fatalf("%v: %v", mechanism, err)
+		}
+		first, err := client.Step("")
+		if err != nil {
+			t.Fatalf("%v: %v", mechanism, err)
+		}
+		if !strings.HasPrefix(first, "n,,n=foo,r=") {
+			t.Errorf("%v: wrong first message: %v", mechanism, first)
+		}
+		if client.Done() {
+			t.Errorf("%v: expected conversation to be in progress", mechanism)
+		}
+	}
+}
+
+func TestApplyOAuthBearerWithTLS(t *testing.T) {
+	conf := NewConfig()
+	conf.Enabled = true
+	conf.Mechanism = MechanismOAuthBearer
+	conf.AccessToken = "foo"
+
+	sConf := newTLSSaramaConfig(t)
+	if err := conf.Apply(sConf); err != nil {
+		t.Fatal(err)
+	}
+	if err := sConf.Validate(); err != nil {
+		t.Error(err)
+	}
+	if !sConf.Net.TLS.Enable {
+		t.Error("Expected TLS to remain enabled")
+	}
+	token, err := sConf.Net.SASL.TokenProvider.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "foo", token.Token; exp != act {
+		t.Errorf("Wrong token: %v != %v", act, exp)
+	}
+}
+
+type testTokenProvider struct {
+	tokens []string
+}
+
+func (p *testTokenProvider) Token() (*sarama.AccessToken, error) {
+	if len(p.tokens) == 0 {
+		return nil, errors.New("no more tokens")
+	}
+	token := p.tokens[0]
+	p.tokens = p.tokens[1:]
+	return &sarama.AccessToken{Token: token}, nil
+}
+
+func TestApplyOAuthBearerProvider(t *testing.T) {
+	RegisterTokenProvider("test_provider", func() (sarama.AccessTokenProvider, error) {
+		return &testTokenProvider{tokens: []string{"first", "second"}}, nil
+	})
+	RegisterTokenProvider("test_broken_provider", func() (sarama.AccessTokenProvider, error) {
+		return nil, errors.New("nope")
+	})
+
+	conf := NewConfig()
+	conf.Enabled = true
+	conf.Mechanism = MechanismOAuthBearer
+	conf.TokenProvider = "test_provider"
+
+	sConf := newTLSSaramaConfig(t)
+	if err := conf.Apply(sConf); err != nil {
+		t.Fatal(err)
+	}
+	if err := sConf.Validate(); err != nil {
+		t.Error(err)
+	}
+	for _, exp := range []string{"first", "second"} {
+		token, err := sConf.Net.SASL.TokenProvider.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := token.Token; exp != act {
+			t.Errorf("Wrong token: %v != %v", act, exp)
+		}
+	}
+
+	conf.TokenProvider = "test_broken_provider"
+	if err := conf.Apply(newTLSSaramaConfig(t)); err == nil {
+		t.Error("Expected error from broken provider")
+	}
+}
+
+func TestConnectErr(t *testing.T) {
+	conf := NewConfig()
+	if exp, act := sarama.ErrOutOfBrokers, conf.ConnectErr(sarama.ErrOutOfBrokers); exp != act {
+		t.Errorf("Wrong error: %v != %v", act, exp)
+	}
+	if err := conf.ConnectErr(nil); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	conf.Enabled = true
+	exp := sarama.ErrOutOfBrokers.Error() + ", check that the SASL credentials and TLS settings are correct"
+	if act := conf.ConnectErr(sarama.ErrOutOfBrokers).Error(); exp != act {
+		t.Errorf("Wrong error: %v != %v", act, exp)
+	}
+	if exp, act := sarama.ErrNotConnected, conf.ConnectErr(sarama.ErrNotConnected); exp != act {
+		t.Errorf("Wrong error: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
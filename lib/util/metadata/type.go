@@ -0,0 +1,134 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metadata
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// Documentation is a markdown description of how and why to use metadata
+// filter settings.
+const Documentation = `### Metadata
+
+The ` + "`metadata`" + ` fields allow you to control which metadata keys of a
+message are written as headers or attributes. By default keys prefixed with
+` + "`_`" + ` and keys within the internal ` + "`benthos_`" + ` namespace (such
+as processing failure flags) are excluded in order to prevent internal state
+from leaking to downstream systems.
+
+A key is excluded if it is listed in ` + "`exclude_keys`" + ` or begins with any
+of ` + "`exclude_prefixes`" + `. If ` + "`include_patterns`" + ` is not empty
+then the remaining keys are only written if they match at least one of the
+regular expressions:
+
+` + "``` yaml" + `
+metadata:
+  exclude_prefixes: [ _, benthos_ ]
+  exclude_keys: [ kafka_key ]
+  include_patterns: [ ^trace_, ^order_ ]
+` + "```" + ``
+
+//------------------------------------------------------------------------------
+
+// Config contains configuration params for filtering metadata keys.
+type Config struct {
+	ExcludePrefixes []string `json:"exclude_prefixes" yaml:"exclude_prefixes"`
+	ExcludeKeys     []string `json:"exclude_keys" yaml:"exclude_keys"`
+	IncludePatterns []string `json:"include_patterns" yaml:"include_patterns"`
+}
+
+// NewConfig creates a new Config with default values.
+func NewConfig() Config {
+	return Config{
+		ExcludePrefixes: []string{"_", "benthos_"},
+		ExcludeKeys:     []string{},
+		IncludePatterns: []string{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Get returns a Filter based on the configuration values of Config.
+func (c *Config) Get() (*Filter, error) {
+	f := &Filter{
+		excludePrefixes: c.ExcludePrefixes,
+		excludeKeys:     map[string]struct{}{},
+	}
+	for _, k := range c.ExcludeKeys {
+		f.excludeKeys[k] = struct{}{}
+	}
+	for _, p := range c.IncludePatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile include pattern '%v': %v", p, err)
+		}
+		f.includePatterns = append(f.includePatterns, re)
+	}
+	return f, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Filter determines which metadata keys should be written by an output.
+type Filter struct {
+	excludePrefixes []string
+	excludeKeys     map[string]struct{}
+	includePatterns []*regexp.Regexp
+}
+
+// Match returns true if a metadata key should be written.
+func (f *Filter) Match(key string) bool {
+	if _, exists := f.excludeKeys[key]; exists {
+		return false
+	}
+	for _, prefix := range f.excludePrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return false
+		}
+	}
+	if len(f.includePatterns) == 0 {
+		return true
+	}
+	for _, re := range f.includePatterns {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Iter iterates each metadata key/value pair that should be written.
+func (f *Filter) Iter(m types.Metadata, fn func(k, v string) error) error {
+	return m.Iter(func(k, v string) error {
+		if !f.Match(k) {
+			return nil
+		}
+		return fn(k, v)
+	})
+}
+
+//------------------------------------------------------------------------------
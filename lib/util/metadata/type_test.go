@@ -0,0 +1,113 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metadata
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/message/metadata"
+)
+
+func TestFilterDefault(t *testing.T) {
+	conf := NewConfig()
+	f, err := conf.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		"foo":                       true,
+		"dead_letter_attempts":      true,
+		"_internal":                 false,
+		"benthos_processing_failed": false,
+		"benthos_processing_error":  false,
+	}
+	for k, exp := range tests {
+		if act := f.Match(k); act != exp {
+			t.Errorf("Wrong result for '%v': %v != %v", k, act, exp)
+		}
+	}
+}
+
+func TestFilterCustom(t *testing.T) {
+	conf := NewConfig()
+	conf.ExcludePrefixes = []string{"tmp_"}
+	conf.ExcludeKeys = []string{"order_secret"}
+	conf.IncludePatterns = []string{"^order_", "^trace"}
+
+	f, err := conf.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		"order_id":                  true,
+		"traceparent":               true,
+		"order_secret":              false,
+		"tmp_order_id":              false,
+		"foo":                       false,
+		"_order_id":                 false,
+		"benthos_processing_failed": false,
+	}
+	for k, exp := range tests {
+		if act := f.Match(k); act != exp {
+			t.Errorf("Wrong result for '%v': %v != %v", k, act, exp)
+		}
+	}
+}
+
+func TestFilterIter(t *testing.T) {
+	conf := NewConfig()
+	f, err := conf.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta := metadata.New(map[string]string{
+		"foo":                       "1",
+		"bar":                       "2",
+		"_baz":                      "3",
+		"benthos_processing_failed": "true",
+	})
+
+	act := map[string]string{}
+	f.Iter(meta, func(k, v string) error {
+		act[k] = v
+		return nil
+	})
+
+	exp := map[string]string{
+		"foo": "1",
+		"bar": "2",
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func TestFilterBadPattern(t *testing.T) {
+	conf := NewConfig()
+	conf.IncludePatterns = []string{"("}
+	if _, err := conf.Get(); err == nil {
+		t.Error("Expected error from bad pattern")
+	}
+}
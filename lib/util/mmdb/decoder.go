@@ -0,0 +1,265 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mmdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+//------------------------------------------------------------------------------
+
+type dataType int
+
+const (
+	typeExtended dataType = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// maxDecodeDepth limits the nesting of maps, arrays and pointers in order to
+// protect against corrupt databases.
+const maxDecodeDepth = 512
+
+// ErrInvalidDatabase is returned when a database is malformed.
+var ErrInvalidDatabase = errors.New("invalid MaxMind DB data")
+
+//------------------------------------------------------------------------------
+
+// decoder decodes values from a data section, where pointers are offsets from
+// the beginning of the section.
+type decoder struct {
+	buffer []byte
+}
+
+// decode returns the value at an offset of the section along with the offset
+// immediately following it.
+func (d decoder) decode(offset uint, depth int) (interface{}, uint, error) {
+	if depth > maxDecodeDepth {
+		return nil, 0, fmt.Errorf("%v: exceeded maximum data structure depth", ErrInvalidDatabase)
+	}
+	if offset >= uint(len(d.buffer)) {
+		return nil, 0, fmt.Errorf("%v: unexpected end of data", ErrInvalidDatabase)
+	}
+
+	ctrl := d.buffer[offset]
+	offset++
+
+	dType := dataType(ctrl >> 5)
+	if dType == typePointer {
+		pointer, newOffset, err := d.decodePointer(ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		v, _, err := d.decode(pointer, depth+1)
+		return v, newOffset, err
+	}
+
+	if dType == typeExtended {
+		if offset >= uint(len(d.buffer)) {
+			return nil, 0, fmt.Errorf("%v: unexpected end of data", ErrInvalidDatabase)
+		}
+		dType = dataType(7 + uint(d.buffer[offset]))
+		offset++
+	}
+
+	size, offset, err := d.decodeSize(ctrl, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	return d.decodeFromType(dType, size, offset, depth)
+}
+
+func (d decoder) decodePointer(ctrl byte, offset uint) (uint, uint, error) {
+	pointerSize := uint((ctrl>>3)&0x3) + 1
+	newOffset := offset + pointerSize
+	if newOffset > uint(len(d.buffer)) {
+		return 0, 0, fmt.Errorf("%v: unexpected end of data", ErrInvalidDatabase)
+	}
+
+	var prefix uint
+	if pointerSize != 4 {
+		prefix = uint(ctrl & 0x7)
+	}
+	pointer := uintFromBytes(prefix, d.buffer[offset:newOffset])
+
+	switch pointerSize {
+	case 2:
+		pointer += 2048
+	case 3:
+		pointer += 526336
+	}
+	return pointer, newOffset, nil
+}
+
+func (d decoder) decodeSize(ctrl byte, offset uint) (uint, uint, error) {
+	size := uint(ctrl & 0x1f)
+	if size < 29 {
+		return size, offset, nil
+	}
+
+	bytesToRead := size - 28
+	newOffset := offset + bytesToRead
+	if newOffset > uint(len(d.buffer)) {
+		return 0, 0, fmt.Errorf("%v: unexpected end of data", ErrInvalidDatabase)
+	}
+	sizeBytes := d.buffer[offset:newOffset]
+
+	switch size {
+	case 29:
+		size = 29 + uint(sizeBytes[0])
+	case 30:
+		size = 285 + uintFromBytes(0, sizeBytes)
+	default:
+		size = 65821 + uintFromBytes(0, sizeBytes)
+	}
+	return size, newOffset, nil
+}
+
+func (d decoder) decodeFromType(dType dataType, size, offset uint, depth int) (interface{}, uint, error) {
+	if dType == typeBool {
+		if size > 1 {
+			return nil, 0, fmt.Errorf("%v: invalid size of boolean: %v", ErrInvalidDatabase, size)
+		}
+		return size == 1, offset, nil
+	}
+	if dType == typeMap {
+		return d.decodeMap(size, offset, depth)
+	}
+	if dType == typeArray {
+		return d.decodeArray(size, offset, depth)
+	}
+
+	newOffset := offset + size
+	if newOffset > uint(len(d.buffer)) {
+		return nil, 0, fmt.Errorf("%v: unexpected end of data", ErrInvalidDatabase)
+	}
+	b := d.buffer[offset:newOffset]
+
+	switch dType {
+	case typeString:
+		return string(b), newOffset, nil
+	case typeBytes:
+		cpy := make([]byte, len(b))
+		copy(cpy, b)
+		return cpy, newOffset, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("%v: invalid size of double: %v", ErrInvalidDatabase, size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), newOffset, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("%v: invalid size of float: %v", ErrInvalidDatabase, size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), newOffset, nil
+	case typeUint16, typeUint32, typeUint64:
+		maxSize := uint(8)
+		switch dType {
+		case typeUint16:
+			maxSize = 2
+		case typeUint32:
+			maxSize = 4
+		}
+		if size > maxSize {
+			return nil, 0, fmt.Errorf("%v: invalid size of unsigned integer: %v", ErrInvalidDatabase, size)
+		}
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		return v, newOffset, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, fmt.Errorf("%v: invalid size of int32: %v", ErrInvalidDatabase, size)
+		}
+		var v uint32
+		for _, c := range b {
+			v = v<<8 | uint32(c)
+		}
+		return int64(int32(v)), newOffset, nil
+	case typeUint128:
+		if size > 16 {
+			return nil, 0, fmt.Errorf("%v: invalid size of uint128: %v", ErrInvalidDatabase, size)
+		}
+		return new(big.Int).SetBytes(b), newOffset, nil
+	}
+	return nil, 0, fmt.Errorf("%v: unsupported data type: %v", ErrInvalidDatabase, dType)
+}
+
+func (d decoder) decodeMap(size, offset uint, depth int) (interface{}, uint, error) {
+	m := make(map[string]interface{}, size)
+	for i := uint(0); i < size; i++ {
+		k, newOffset, err := d.decode(offset, depth+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, 0, fmt.Errorf("%v: map key of unexpected type: %T", ErrInvalidDatabase, k)
+		}
+		var v interface{}
+		if v, offset, err = d.decode(newOffset, depth+1); err != nil {
+			return nil, 0, err
+		}
+		m[key] = v
+	}
+	return m, offset, nil
+}
+
+func (d decoder) decodeArray(size, offset uint, depth int) (interface{}, uint, error) {
+	a := make([]interface{}, size)
+	for i := range a {
+		var err error
+		if a[i], offset, err = d.decode(offset, depth+1); err != nil {
+			return nil, 0, err
+		}
+	}
+	return a, offset, nil
+}
+
+//------------------------------------------------------------------------------
+
+func uintFromBytes(prefix uint, b []byte) uint {
+	v := prefix
+	for _, c := range b {
+		v = v<<8 | uint(c)
+	}
+	return v
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,239 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mmdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+
+	mmap "github.com/edsrzf/mmap-go"
+)
+
+//------------------------------------------------------------------------------
+
+var metadataStartMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// dataSectionSeparatorSize is the number of zero bytes between the search tree
+// and the data section of a database.
+const dataSectionSeparatorSize = 16
+
+// Metadata describes the contents of a database.
+type Metadata struct {
+	DatabaseType string
+	BuildEpoch   uint
+	IPVersion    uint
+	NodeCount    uint
+	RecordSize   uint
+	Languages    []string
+}
+
+//------------------------------------------------------------------------------
+
+// Reader performs lookups of IP addresses against a database.
+type Reader struct {
+	Metadata Metadata
+
+	buffer    []byte
+	data      decoder
+	ipv4Start uint
+	mapped    mmap.MMap
+}
+
+// Open memory maps a database file and returns a Reader for it. The Reader must
+// be closed in order to release the mapping.
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, err := mmap.Map(f, mmap.RDONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map file: %v", err)
+	}
+
+	r, err := FromBytes(m)
+	if err != nil {
+		m.Unmap()
+		return nil, err
+	}
+	r.mapped = m
+	return r, nil
+}
+
+// FromBytes returns a Reader for a database held in memory.
+func FromBytes(buffer []byte) (*Reader, error) {
+	metaStart := bytes.LastIndex(buffer, metadataStartMarker)
+	if metaStart == -1 {
+		return nil, fmt.Errorf("%v: metadata section not found", ErrInvalidDatabase)
+	}
+
+	meta, err := parseMetadata(decoder{buffer: buffer[metaStart+len(metadataStartMarker):]})
+	if err != nil {
+		return nil, err
+	}
+
+	treeSize := ((meta.RecordSize * 2) / 8) * meta.NodeCount
+	if treeSize+dataSectionSeparatorSize > uint(metaStart) {
+		return nil, fmt.Errorf("%v: search tree exceeds the size of the database", ErrInvalidDatabase)
+	}
+
+	r := &Reader{
+		Metadata: meta,
+		buffer:   buffer,
+		data:     decoder{buffer: buffer[treeSize+dataSectionSeparatorSize : metaStart]},
+	}
+
+	if meta.IPVersion == 6 {
+		// IPv4 addresses are stored within the subtree of ::/96.
+		node := uint(0)
+		for i := 0; i < 96 && node < meta.NodeCount; i++ {
+			if node, err = r.readNode(node, 0); err != nil {
+				return nil, err
+			}
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+func parseMetadata(d decoder) (Metadata, error) {
+	var meta Metadata
+
+	v, _, err := d.decode(0, 0)
+	if err != nil {
+		return meta, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return meta, fmt.Errorf("%v: metadata of unexpected type: %T", ErrInvalidDatabase, v)
+	}
+
+	uintField := func(key string) uint {
+		u, _ := m[key].(uint64)
+		return uint(u)
+	}
+
+	meta.DatabaseType, _ = m["database_type"].(string)
+	meta.BuildEpoch = uintField("build_epoch")
+	meta.IPVersion = uintField("ip_version")
+	meta.NodeCount = uintField("node_count")
+	meta.RecordSize = uintField("record_size")
+	if langs, ok := m["languages"].([]interface{}); ok {
+		for _, l := range langs {
+			if lStr, ok := l.(string); ok {
+				meta.Languages = append(meta.Languages, lStr)
+			}
+		}
+	}
+
+	switch meta.RecordSize {
+	case 24, 28, 32:
+	default:
+		return meta, fmt.Errorf("%v: unsupported record size: %v", ErrInvalidDatabase, meta.RecordSize)
+	}
+	if meta.IPVersion != 4 && meta.IPVersion != 6 {
+		return meta, fmt.Errorf("%v: unsupported IP version: %v", ErrInvalidDatabase, meta.IPVersion)
+	}
+	return meta, nil
+}
+
+//------------------------------------------------------------------------------
+
+// readNode returns the left (bit 0) or right (bit 1) record of a node.
+func (r *Reader) readNode(node, bit uint) (uint, error) {
+	nodeSize := r.Metadata.RecordSize / 4
+	offset := node * nodeSize
+	if offset+nodeSize > uint(len(r.buffer)) {
+		return 0, fmt.Errorf("%v: node out of bounds", ErrInvalidDatabase)
+	}
+	b := r.buffer[offset : offset+nodeSize]
+
+	switch r.Metadata.RecordSize {
+	case 24:
+		return uintFromBytes(0, b[bit*3:bit*3+3]), nil
+	case 28:
+		if bit == 0 {
+			return uintFromBytes(uint(b[3]&0xF0)>>4, b[0:3]), nil
+		}
+		return uintFromBytes(uint(b[3]&0x0F), b[4:7]), nil
+	}
+	return uint(binary.BigEndian.Uint32(b[bit*4 : bit*4+4])), nil
+}
+
+// Lookup returns the record of the network containing an IP address, or false
+// if the address is not contained within the database.
+func (r *Reader) Lookup(ip net.IP) (interface{}, bool, error) {
+	node := uint(0)
+	addr := ip.To4()
+	if addr != nil {
+		node = r.ipv4Start
+	} else if addr = ip.To16(); addr == nil {
+		return nil, false, fmt.Errorf("invalid IP address: %v", ip)
+	} else if r.Metadata.IPVersion == 4 {
+		return nil, false, fmt.Errorf("cannot look up IPv6 address %v in an IPv4 database", ip)
+	}
+
+	var err error
+	nodeCount := r.Metadata.NodeCount
+	for i := uint(0); i < uint(len(addr)*8) && node < nodeCount; i++ {
+		bit := uint(addr[i/8]>>(7-(i%8))) & 1
+		if node, err = r.readNode(node, bit); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if node == nodeCount {
+		return nil, false, nil
+	}
+	if node < nodeCount {
+		return nil, false, fmt.Errorf("%v: search tree deeper than address", ErrInvalidDatabase)
+	}
+
+	resolved := node - nodeCount - dataSectionSeparatorSize
+	if node < nodeCount+dataSectionSeparatorSize || resolved >= uint(len(r.data.buffer)) {
+		return nil, false, fmt.Errorf("%v: record pointer out of bounds", ErrInvalidDatabase)
+	}
+	v, _, err := r.data.decode(resolved, 0)
+	if err != nil {
+		return nil, false, err
+	}
+	return v, true, nil
+}
+
+// Close releases any memory mapping held by the Reader. The Reader must not be
+// used after it is closed.
+func (r *Reader) Close() error {
+	if r.mapped == nil {
+		return nil
+	}
+	m := r.mapped
+	r.mapped = nil
+	r.buffer = nil
+	r.data = decoder{}
+	return m.Unmap()
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,216 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mmdb
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+//------------------------------------------------------------------------------
+
+func testDatabase(t *testing.T, recordSize int) []byte {
+	t.Helper()
+
+	w, err := NewWriter("Test-DB", recordSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := map[string]interface{}{
+		"81.2.69.0/24": map[string]interface{}{
+			"city": map[string]interface{}{
+				"names": map[string]interface{}{"en": "London"},
+			},
+			"location": map[string]interface{}{
+				"latitude":  51.5142,
+				"longitude": -0.0931,
+			},
+		},
+		"1.128.0.0/11": map[string]interface{}{
+			"autonomous_system_number":       uint32(1221),
+			"autonomous_system_organization": "Telstra Pty Ltd",
+		},
+		"2001:218::/32": map[string]interface{}{
+			"country": map[string]interface{}{"iso_code": "JP"},
+		},
+		"10.0.0.0/8": map[string]interface{}{
+			"bool":   true,
+			"bytes":  []byte("foo"),
+			"float":  float32(1.5),
+			"int":    -5,
+			"uint16": uint16(500),
+			"uint64": uint64(1) << 40,
+			"array":  []interface{}{"a", uint32(2)},
+			"long":   strings.Repeat("x", 300),
+		},
+	}
+	for network, record := range records {
+		if err = w.Insert(network, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := w.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestReaderLookup(t *testing.T) {
+	for _, size := range []int{24, 28, 32} {
+		r, err := FromBytes(testDatabase(t, size))
+		if err != nil {
+			t.Fatalf("%v: %v", size, err)
+		}
+		if exp, act := "Test-DB", r.Metadata.DatabaseType; exp != act {
+			t.Errorf("%v: Wrong database type: %v != %v", size, act, exp)
+		}
+		if exp, act := uint(6), r.Metadata.IPVersion; exp != act {
+			t.Errorf("%v: Wrong ip version: %v != %v", size, act, exp)
+		}
+
+		tests := map[string]interface{}{
+			"81.2.69.160": map[string]interface{}{
+				"city": map[string]interface{}{
+					"names": map[string]interface{}{"en": "London"},
+				},
+				"location": map[string]interface{}{
+					"latitude":  51.5142,
+					"longitude": -0.0931,
+				},
+			},
+			"1.130.0.1": map[string]interface{}{
+				"autonomous_system_number":       uint64(1221),
+				"autonomous_system_organization": "Telstra Pty Ltd",
+			},
+			"2001:218:1::1": map[string]interface{}{
+				"country": map[string]interface{}{"iso_code": "JP"},
+			},
+			"10.1.2.3": map[string]interface{}{
+				"bool":   true,
+				"bytes":  []byte("foo"),
+				"float":  float64(1.5),
+				"int":    int64(-5),
+				"uint16": uint64(500),
+				"uint64": uint64(1) << 40,
+				"array":  []interface{}{"a", uint64(2)},
+				"long":   strings.Repeat("x", 300),
+			},
+			"81.2.70.1":  nil,
+			"2001:219::": nil,
+			"127.0.0.1":  nil,
+		}
+
+		for ip, exp := range tests {
+			act, found, err := r.Lookup(net.ParseIP(ip))
+			if err != nil {
+				t.Errorf("%v: %v: %v", size, ip, err)
+				continue
+			}
+			if found != (exp != nil) {
+				t.Errorf("%v: %v: Wrong found result: %v", size, ip, found)
+			}
+			if exp != nil && !reflect.DeepEqual(exp, act) {
+				t.Errorf("%v: %v: Wrong result: %#v != %#v", size, ip, act, exp)
+			}
+		}
+	}
+}
+
+func TestReaderOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_mmdb_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.mmdb")
+	if err = ioutil.WriteFile(path, testDatabase(t, 28), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	v, found, err := r.Lookup(net.ParseIP("1.128.0.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Fatal("Expected record to be found")
+	}
+	if exp, act := "Telstra Pty Ltd", v.(map[string]interface{})["autonomous_system_organization"]; exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	if err = r.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReaderInvalid(t *testing.T) {
+	if _, err := FromBytes([]byte("not a database")); err == nil {
+		t.Error("Expected error from invalid database")
+	}
+
+	b := testDatabase(t, 24)
+	if _, err := FromBytes(b[len(b)/2:]); err == nil {
+		t.Error("Expected error from truncated database")
+	}
+}
+
+func TestDecoderPointers(t *testing.T) {
+	// A map containing a key and value that both point to a string at the
+	// beginning of the section.
+	d := decoder{buffer: []byte{
+		(byte(typeString) << 5) | 3, 'f', 'o', 'o',
+		(byte(typeMap) << 5) | 1,
+		byte(typePointer) << 5, 0,
+		byte(typePointer) << 5, 0,
+	}}
+
+	v, offset, err := d.decode(4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := uint(len(d.buffer)), offset; exp != act {
+		t.Errorf("Wrong offset: %v != %v", act, exp)
+	}
+	if exp, act := map[string]interface{}{"foo": "foo"}, v; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	// A pointer to itself should fail rather than recurse forever.
+	d = decoder{buffer: []byte{byte(typePointer) << 5, 0}}
+	if _, _, err = d.decode(0, 0); err == nil {
+		t.Error("Expected error from recursive pointer")
+	}
+}
+
+//------------------------------------------------------------------------------
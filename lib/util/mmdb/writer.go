@@ -0,0 +1,311 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mmdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+type writerRecordKind int
+
+const (
+	writerRecordEmpty writerRecordKind = iota
+	writerRecordNode
+	writerRecordData
+)
+
+type writerRecord struct {
+	kind  writerRecordKind
+	value uint
+}
+
+// Writer builds small IPv6 databases in memory, where IPv4 networks are
+// inserted within the subtree of ::/96. It does not deduplicate records and is
+// therefore intended for tests and tooling rather than large datasets.
+type Writer struct {
+	databaseType string
+	recordSize   uint
+
+	nodes [][2]writerRecord
+	data  bytes.Buffer
+}
+
+// NewWriter creates a Writer for a database type with a record size of 24, 28
+// or 32 bits.
+func NewWriter(databaseType string, recordSize int) (*Writer, error) {
+	switch recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("unsupported record size: %v", recordSize)
+	}
+	return &Writer{
+		databaseType: databaseType,
+		recordSize:   uint(recordSize),
+		nodes:        [][2]writerRecord{{}},
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Insert adds a network in CIDR notation to the database with a record, which
+// may consist of maps with string keys, slices, strings, byte slices, floats,
+// integers and booleans. Networks must not overlap.
+func (w *Writer) Insert(network string, record interface{}) error {
+	_, ipNet, err := net.ParseCIDR(network)
+	if err != nil {
+		return err
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	addr := make(net.IP, net.IPv6len)
+	if bits == 32 {
+		copy(addr[12:], ipNet.IP.To4())
+		ones += 96
+	} else {
+		copy(addr, ipNet.IP.To16())
+	}
+	if ones == 0 {
+		return errors.New("network must have a non-zero prefix length")
+	}
+
+	offset := uint(w.data.Len())
+	if err = encodeValue(&w.data, record); err != nil {
+		return err
+	}
+
+	node := uint(0)
+	for i := 0; i < ones; i++ {
+		bit := (addr[i/8] >> (7 - uint(i%8))) & 1
+		if i == ones-1 {
+			if w.nodes[node][bit].kind != writerRecordEmpty {
+				return fmt.Errorf("network %v overlaps an existing network", network)
+			}
+			w.nodes[node][bit] = writerRecord{kind: writerRecordData, value: offset}
+			break
+		}
+		switch rec := w.nodes[node][bit]; rec.kind {
+		case writerRecordEmpty:
+			w.nodes = append(w.nodes, [2]writerRecord{})
+			next := uint(len(w.nodes) - 1)
+			w.nodes[node][bit] = writerRecord{kind: writerRecordNode, value: next}
+			node = next
+		case writerRecordNode:
+			node = rec.value
+		default:
+			return fmt.Errorf("network %v overlaps an existing network", network)
+		}
+	}
+	return nil
+}
+
+// Bytes returns the encoded database.
+func (w *Writer) Bytes() ([]byte, error) {
+	nodeCount := uint(len(w.nodes))
+	maxRecord := uint(1)<<w.recordSize - 1
+
+	resolve := func(r writerRecord) (uint, error) {
+		v := nodeCount
+		switch r.kind {
+		case writerRecordNode:
+			v = r.value
+		case writerRecordData:
+			v = nodeCount + dataSectionSeparatorSize + r.value
+		}
+		if v > maxRecord {
+			return 0, fmt.Errorf("record value %v exceeds record size", v)
+		}
+		return v, nil
+	}
+
+	var buf bytes.Buffer
+	for _, n := range w.nodes {
+		left, err := resolve(n[0])
+		if err != nil {
+			return nil, err
+		}
+		right, err := resolve(n[1])
+		if err != nil {
+			return nil, err
+		}
+		switch w.recordSize {
+		case 24:
+			buf.Write([]byte{
+				byte(left >> 16), byte(left >> 8), byte(left),
+				byte(right >> 16), byte(right >> 8), byte(right),
+			})
+		case 28:
+			buf.Write([]byte{
+				byte(left >> 16), byte(left >> 8), byte(left),
+				byte((left>>24)<<4) | byte((right>>24)&0x0F),
+				byte(right >> 16), byte(right >> 8), byte(right),
+			})
+		case 32:
+			var b [8]byte
+			binary.BigEndian.PutUint32(b[:4], uint32(left))
+			binary.BigEndian.PutUint32(b[4:], uint32(right))
+			buf.Write(b[:])
+		}
+	}
+
+	buf.Write(make([]byte, dataSectionSeparatorSize))
+	buf.Write(w.data.Bytes())
+	buf.Write(metadataStartMarker)
+
+	if err := encodeValue(&buf, map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(time.Now().Unix()),
+		"database_type":               w.databaseType,
+		"description":                 map[string]interface{}{},
+		"ip_version":                  uint16(6),
+		"languages":                   []interface{}{},
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(w.recordSize),
+	}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+//------------------------------------------------------------------------------
+
+func writeControl(buf *bytes.Buffer, dType dataType, size uint) {
+	var ctrl byte
+	if dType > 7 {
+		ctrl = byte(typeExtended) << 5
+	} else {
+		ctrl = byte(dType) << 5
+	}
+
+	var sizeBytes []byte
+	switch {
+	case size < 29:
+		ctrl |= byte(size)
+	case size < 285:
+		ctrl |= 29
+		sizeBytes = []byte{byte(size - 29)}
+	case size < 65821:
+		ctrl |= 30
+		size -= 285
+		sizeBytes = []byte{byte(size >> 8), byte(size)}
+	default:
+		ctrl |= 31
+		size -= 65821
+		sizeBytes = []byte{byte(size >> 16), byte(size >> 8), byte(size)}
+	}
+
+	buf.WriteByte(ctrl)
+	if dType > 7 {
+		buf.WriteByte(byte(dType - 7))
+	}
+	buf.Write(sizeBytes)
+}
+
+func writeUint(buf *bytes.Buffer, dType dataType, v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	i := 0
+	for i < len(b) && b[i] == 0 {
+		i++
+	}
+	writeControl(buf, dType, uint(len(b)-i))
+	buf.Write(b[i:])
+}
+
+func encodeValue(buf *bytes.Buffer, v interface{}) error {
+	switch t := v.(type) {
+	case string:
+		writeControl(buf, typeString, uint(len(t)))
+		buf.WriteString(t)
+	case []byte:
+		writeControl(buf, typeBytes, uint(len(t)))
+		buf.Write(t)
+	case float64:
+		writeControl(buf, typeDouble, 8)
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], math.Float64bits(t))
+		buf.Write(b[:])
+	case float32:
+		writeControl(buf, typeFloat, 4)
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], math.Float32bits(t))
+		buf.Write(b[:])
+	case bool:
+		var size uint
+		if t {
+			size = 1
+		}
+		writeControl(buf, typeBool, size)
+	case uint16:
+		writeUint(buf, typeUint16, uint64(t))
+	case uint32:
+		writeUint(buf, typeUint32, uint64(t))
+	case uint64:
+		writeUint(buf, typeUint64, t)
+	case int32:
+		writeControl(buf, typeInt32, 4)
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], uint32(t))
+		buf.Write(b[:])
+	case int:
+		if t < 0 {
+			if t < math.MinInt32 {
+				return fmt.Errorf("integer out of range: %v", t)
+			}
+			return encodeValue(buf, int32(t))
+		}
+		writeUint(buf, typeUint64, uint64(t))
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		writeControl(buf, typeMap, uint(len(keys)))
+		for _, k := range keys {
+			encodeValue(buf, k)
+			if err := encodeValue(buf, t[k]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		writeControl(buf, typeArray, uint(len(t)))
+		for _, e := range t {
+			if err := encodeValue(buf, e); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported value type: %T", v)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -22,6 +22,7 @@ package text
 
 import (
 	"bytes"
+	"crypto/rand"
 	"encoding/json"
 	"os"
 	"regexp"
@@ -32,6 +33,7 @@ import (
 
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/gabs"
+	"github.com/gofrs/uuid"
 )
 
 //------------------------------------------------------------------------------
@@ -43,8 +45,29 @@ type Message interface {
 	Len() int
 }
 
+// batchPart is implemented by messages that are a view of a single part within
+// a larger batch, such as those created with message.Lock.
+type batchPart interface {
+	BatchIndex() int
+	BatchLen() int
+}
+
 //------------------------------------------------------------------------------
 
+func batchIndexFunction(msg Message, arg string) []byte {
+	if bp, ok := msg.(batchPart); ok {
+		return []byte(strconv.Itoa(bp.BatchIndex()))
+	}
+	return []byte("0")
+}
+
+func batchSizeFunction(msg Message, arg string) []byte {
+	if bp, ok := msg.(batchPart); ok {
+		return []byte(strconv.Itoa(bp.BatchLen()))
+	}
+	return []byte(strconv.Itoa(msg.Len()))
+}
+
 func jsonFieldFunction(msg Message, arg string) []byte {
 	args := strings.Split(arg, ",")
 	part := 0
@@ -71,6 +94,34 @@ func jsonFieldFunction(msg Message, arg string) []byte {
 	return gPart.Bytes()
 }
 
+func jsonFieldTimestampFunction(msg Message, arg string) []byte {
+	args := strings.SplitN(arg, ",", 2)
+	layout := time.RFC3339
+	if len(args) == 2 && len(args[1]) > 0 {
+		layout = args[1]
+	}
+
+	jPart, err := msg.Get(0).JSON()
+	if err != nil {
+		return []byte("")
+	}
+	gPart, _ := gabs.Consume(jPart)
+
+	var ts time.Time
+	switch t := gPart.Path(args[0]).Data().(type) {
+	case string:
+		if ts, err = time.Parse(time.RFC3339, t); err != nil {
+			return []byte("")
+		}
+	case float64:
+		secs := int64(t)
+		ts = time.Unix(secs, int64((t-float64(secs))*1e9))
+	default:
+		return []byte("")
+	}
+	return []byte(ts.UTC().Format(layout))
+}
+
 func metadataFunction(msg Message, arg string) []byte {
 	if len(arg) == 0 {
 		return []byte("")
@@ -107,6 +158,58 @@ func metadataMapFunction(msg Message, arg string) []byte {
 	return result
 }
 
+var regexCache = map[string]*regexp.Regexp{}
+var regexCacheMux = &sync.RWMutex{}
+
+// cachedRegexp compiles a regular expression pattern once and reuses the result
+// for subsequent calls.
+func cachedRegexp(pattern string) (*regexp.Regexp, error) {
+	regexCacheMux.RLock()
+	re, exists := regexCache[pattern]
+	regexCacheMux.RUnlock()
+	if exists {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	regexCacheMux.Lock()
+	regexCache[pattern] = re
+	regexCacheMux.Unlock()
+	return re, nil
+}
+
+func contentRegexFunction(msg Message, arg string) []byte {
+	pattern, group := arg, "0"
+	if i := strings.LastIndexByte(arg, ':'); i != -1 {
+		pattern, group = arg[:i], arg[i+1:]
+	}
+
+	re, err := cachedRegexp(pattern)
+	if err != nil {
+		return []byte("")
+	}
+
+	index, err := strconv.Atoi(group)
+	if err != nil {
+		if index = re.SubexpIndex(group); index == -1 {
+			return []byte("")
+		}
+	}
+	if index < 0 || index > re.NumSubexp() {
+		return []byte("")
+	}
+
+	matches := re.FindSubmatchIndex(msg.Get(0).Get())
+	if matches == nil || matches[index*2] == -1 {
+		return []byte("")
+	}
+	return msg.Get(0).Get()[matches[index*2]:matches[index*2+1]]
+}
+
 func contentFunction(msg Message, arg string) []byte {
 	part := 0
 	if len(arg) > 0 {
@@ -118,18 +221,84 @@ func contentFunction(msg Message, arg string) []byte {
 	return msg.Get(part).Get()
 }
 
+const nanoidAlphabet = "_-0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// nanoid generates a random ID of a length from an alphabet of up to 256
+// characters using a cryptographically secure source.
+func nanoid(length int, alphabet []rune) (string, error) {
+	if len(alphabet) > 256 {
+		alphabet = alphabet[:256]
+	}
+
+	// Random bytes are masked to the smallest power of two that covers the
+	// alphabet and values outside of it are discarded, which avoids the bias
+	// of a modulo.
+	mask := 1
+	for mask < len(alphabet) {
+		mask <<= 1
+	}
+	mask--
+
+	id := make([]rune, 0, length)
+	buf := make([]byte, length)
+	for len(id) < length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if i := int(b) & mask; i < len(alphabet) {
+				id = append(id, alphabet[i])
+				if len(id) == length {
+					break
+				}
+			}
+		}
+	}
+	return string(id), nil
+}
+
+func nanoidFunction(_ Message, arg string) []byte {
+	length := 21
+	alphabet := nanoidAlphabet
+	if len(arg) > 0 {
+		args := strings.SplitN(arg, ",", 2)
+		if l, err := strconv.Atoi(args[0]); err == nil && l > 0 {
+			length = l
+		}
+		if len(args) == 2 && len(args[1]) > 0 {
+			alphabet = args[1]
+		}
+	}
+	id, err := nanoid(length, []rune(alphabet))
+	if err != nil {
+		return []byte("")
+	}
+	return []byte(id)
+}
+
+func uuidV4Function(_ Message, arg string) []byte {
+	u, err := uuid.NewV4()
+	if err != nil {
+		return []byte("")
+	}
+	return []byte(u.String())
+}
+
 //------------------------------------------------------------------------------
 
 var functionRegex *regexp.Regexp
 
 func init() {
 	var err error
-	functionRegex, err = regexp.Compile(`\${![a-z_]+(:[^}]+)?}`)
+	functionRegex, err = regexp.Compile(`\${![a-z0-9_]+(:[^}]+)?}`)
 	if err != nil {
 		panic(err)
 	}
 }
 
+var hostname string
+var hostnameOnce sync.Once
+
 var counters = map[string]uint64{}
 var countersMux = &sync.Mutex{}
 
@@ -157,8 +326,17 @@ var functionVars = map[string]func(msg Message, arg string) []byte{
 		return []byte(time.Now().Format(arg))
 	},
 	"hostname": func(_ Message, arg string) []byte {
-		hn, _ := os.Hostname()
-		return []byte(hn)
+		hostnameOnce.Do(func() {
+			hostname, _ = os.Hostname()
+		})
+		return []byte(hostname)
+	},
+	"env": func(_ Message, arg string) []byte {
+		args := strings.SplitN(arg, ":", 2)
+		if v := os.Getenv(args[0]); len(v) > 0 || len(args) == 1 {
+			return []byte(v)
+		}
+		return []byte(args[1])
 	},
 	"echo": func(_ Message, arg string) []byte {
 		return []byte(arg)
@@ -179,10 +357,16 @@ var functionVars = map[string]func(msg Message, arg string) []byte{
 
 		return []byte(strconv.FormatUint(count, 10))
 	},
+	"batch_index":          batchIndexFunction,
+	"batch_size":           batchSizeFunction,
 	"content":              contentFunction,
+	"content_regex":        contentRegexFunction,
 	"json_field":           jsonFieldFunction,
+	"json_field_timestamp": jsonFieldTimestampFunction,
 	"metadata":             metadataFunction,
 	"metadata_json_object": metadataMapFunction,
+	"nanoid":               nanoidFunction,
+	"uuid_v4":              uuidV4Function,
 }
 
 // ContainsFunctionVariables returns true if inBytes contains function variable
@@ -23,7 +23,9 @@ package text
 import (
 	"fmt"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -271,6 +273,68 @@ func TestJSONFunction(t *testing.T) {
 	}
 }
 
+func TestJSONTimestampFunction(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		arg    string
+		result string
+	}{
+		{
+			name:   "rfc3339 string",
+			input:  `{"@timestamp":"2018-11-12T14:05:00+02:00"}`,
+			arg:    "logs-${!json_field_timestamp:@timestamp,2006.01.02-15}",
+			result: "logs-2018.11.12-12",
+		},
+		{
+			name:   "unix number",
+			input:  `{"foo":{"ts":1542031500}}`,
+			arg:    "logs-${!json_field_timestamp:foo.ts,2006.01.02}",
+			result: "logs-2018.11.12",
+		},
+		{
+			name:   "default layout",
+			input:  `{"ts":1542031500.5}`,
+			arg:    "${!json_field_timestamp:ts}",
+			result: "2018-11-12T14:05:00Z",
+		},
+		{
+			name:   "layout with comma",
+			input:  `{"ts":1542031500}`,
+			arg:    "${!json_field_timestamp:ts,Jan 2, 2006}",
+			result: "Nov 12, 2018",
+		},
+		{
+			name:   "missing field",
+			input:  `{"foo":"bar"}`,
+			arg:    "logs-${!json_field_timestamp:ts,2006.01.02}",
+			result: "logs-",
+		},
+		{
+			name:   "bad timestamp",
+			input:  `{"ts":"yesterday"}`,
+			arg:    "logs-${!json_field_timestamp:ts,2006.01.02}",
+			result: "logs-",
+		},
+		{
+			name:   "not json",
+			input:  `not json`,
+			arg:    "logs-${!json_field_timestamp:ts,2006.01.02}",
+			result: "logs-",
+		},
+	}
+
+	for _, test := range tests {
+		act := string(ReplaceFunctionVariables(
+			message.New([][]byte{[]byte(test.input)}),
+			[]byte(test.arg),
+		))
+		if act != test.result {
+			t.Errorf("Wrong result for test '%v': %v != %v", test.name, act, test.result)
+		}
+	}
+}
+
 func TestFunctionSwapping(t *testing.T) {
 	hostname, _ := os.Hostname()
 
@@ -379,3 +443,140 @@ func TestCountersFunction(t *testing.T) {
 		}
 	}
 }
+
+func TestUUIDV4Function(t *testing.T) {
+	uuidRegex := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	seen := map[string]struct{}{}
+	for i := 0; i < 100; i++ {
+		res := strings.Split(string(ReplaceFunctionVariables(nil, []byte("${!uuid_v4} ${!uuid_v4}"))), " ")
+		if len(res) != 2 {
+			t.Fatalf("Wrong count of results: %v", res)
+		}
+		for _, id := range res {
+			if !uuidRegex.MatchString(id) {
+				t.Errorf("Invalid UUID: %v", id)
+			}
+			if _, exists := seen[id]; exists {
+				t.Errorf("Duplicate UUID: %v", id)
+			}
+			seen[id] = struct{}{}
+		}
+	}
+}
+
+func TestNanoidFunction(t *testing.T) {
+	tests := map[string]*regexp.Regexp{
+		"${!nanoid}":         regexp.MustCompile(`^[_\-0-9a-zA-Z]{21}$`),
+		"${!nanoid:8}":       regexp.MustCompile(`^[_\-0-9a-zA-Z]{8}$`),
+		"${!nanoid:nope}":    regexp.MustCompile(`^[_\-0-9a-zA-Z]{21}$`),
+		"${!nanoid:12,abc}":  regexp.MustCompile(`^[abc]{12}$`),
+		"${!nanoid:5,a,b}":   regexp.MustCompile(`^[a,b]{5}$`),
+		"${!nanoid:4,日本}":    regexp.MustCompile(`^[日本]{4}$`),
+		"foo-${!nanoid:3}-x": regexp.MustCompile(`^foo-[_\-0-9a-zA-Z]{3}-x$`),
+	}
+
+	for input, exp := range tests {
+		act := string(ReplaceFunctionVariables(nil, []byte(input)))
+		if !exp.MatchString(act) {
+			t.Errorf("Wrong results for input (%v): %v does not match %v", input, act, exp)
+		}
+	}
+
+	seen := map[string]struct{}{}
+	for i := 0; i < 1000; i++ {
+		id := string(ReplaceFunctionVariables(nil, []byte("${!nanoid}")))
+		if _, exists := seen[id]; exists {
+			t.Errorf("Duplicate ID: %v", id)
+		}
+		seen[id] = struct{}{}
+	}
+}
+
+func TestEnvFunction(t *testing.T) {
+	os.Setenv("BENTHOS_TEST_ENV_FUNCTION", "foo")
+	defer os.Unsetenv("BENTHOS_TEST_ENV_FUNCTION")
+
+	tests := map[string]string{
+		"${!env:BENTHOS_TEST_ENV_FUNCTION}":             "foo",
+		"${!env:BENTHOS_TEST_ENV_FUNCTION:bar}":         "foo",
+		"${!env:BENTHOS_TEST_ENV_NOT_EXIST}":            "",
+		"${!env:BENTHOS_TEST_ENV_NOT_EXIST:bar}":        "bar",
+		"${!env:BENTHOS_TEST_ENV_NOT_EXIST:bar:baz}":    "bar:baz",
+		"a ${!env:BENTHOS_TEST_ENV_FUNCTION} b":         "a foo b",
+		"${!env:BENTHOS_TEST_ENV_NOT_EXIST:http://foo}": "http://foo",
+	}
+
+	for input, exp := range tests {
+		act := string(ReplaceFunctionVariables(nil, []byte(input)))
+		if exp != act {
+			t.Errorf("Wrong results for input (%v): %v != %v", input, act, exp)
+		}
+	}
+}
+
+func TestHostnameFunction(t *testing.T) {
+	exp, err := os.Hostname()
+	if err != nil {
+		t.Skip(err)
+	}
+	for i := 0; i < 2; i++ {
+		if act := string(ReplaceFunctionVariables(nil, []byte("${!hostname}"))); exp != act {
+			t.Errorf("Wrong hostname: %v != %v", act, exp)
+		}
+	}
+}
+
+func TestBatchFunctions(t *testing.T) {
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar"), []byte("baz")})
+
+	tests := []struct {
+		msg Message
+		exp string
+	}{
+		{msg: msg, exp: "0 3"},
+		{msg: message.Lock(msg, 0), exp: "0 3"},
+		{msg: message.Lock(msg, 1), exp: "1 3"},
+		{msg: message.Lock(msg, -1), exp: "2 3"},
+	}
+
+	for i, test := range tests {
+		act := string(ReplaceFunctionVariables(test.msg, []byte("${!batch_index} ${!batch_size}")))
+		if act != test.exp {
+			t.Errorf("Wrong result for test %v: %v != %v", i, act, test.exp)
+		}
+	}
+}
+
+func TestContentRegexFunction(t *testing.T) {
+	msg := message.New([][]byte{
+		[]byte(`user=ash id=12345 path=/a:b`),
+		[]byte(`user=bob id=678`),
+	})
+
+	tests := map[string]string{
+		`${!content_regex:id=(\d+):1}`:                    "12345",
+		`${!content_regex:id=(?P<id>\d+):id}`:             "12345",
+		`${!content_regex:user=(\w+) id=(\d+):2}`:         "12345",
+		`${!content_regex:id=\d+}`:                        "id=12345",
+		`${!content_regex:path=(\S+):1}`:                  "/a:b",
+		`${!content_regex:path=/a:(\w+):1}`:               "b",
+		`${!content_regex:nope=(\d+):1}`:                  "",
+		`${!content_regex:id=(\d+):2}`:                    "",
+		`${!content_regex:id=(\d+):missing}`:              "",
+		`${!content_regex:id=(\d+)|user=(\w+):1}`:         "",
+		`${!content_regex:id=([:1}`:                       "",
+		`key-${!content_regex:user=(\w+):1}-${!count:cr}`: "key-ash-1",
+	}
+
+	for input, exp := range tests {
+		act := string(ReplaceFunctionVariables(msg, []byte(input)))
+		if exp != act {
+			t.Errorf("Wrong results for input (%v): %v != %v", input, act, exp)
+		}
+	}
+
+	if exp, act := "bob", string(ReplaceFunctionVariables(message.Lock(msg, 1), []byte(`${!content_regex:user=(\w+):1}`))); exp != act {
+		t.Errorf("Wrong result for locked message: %v != %v", act, exp)
+	}
+}
@@ -23,6 +23,8 @@ package throttle
 import (
 	"sync/atomic"
 	"time"
+
+	"github.com/Jeffail/benthos/lib/util/clock"
 )
 
 //------------------------------------------------------------------------------
@@ -50,6 +52,9 @@ type Type struct {
 
 	// closeChan can interrupt a throttle when closed.
 	closeChan <-chan struct{}
+
+	// clock is used for waiting out throttle periods.
+	clock clock.Clock
 }
 
 // New creates a new throttle, which permits a static number of consecutive
@@ -61,6 +66,7 @@ func New(options ...func(*Type)) *Type {
 		baseThrottlePeriod:   int64(time.Second),
 		maxExponentialPeriod: int64(time.Minute),
 		closeChan:            nil,
+		clock:                clock.Real(),
 	}
 	t.throttlePeriod = t.baseThrottlePeriod
 	for _, option := range options {
@@ -103,6 +109,14 @@ func OptCloseChan(c <-chan struct{}) func(*Type) {
 	}
 }
 
+// OptClock sets the clock used for waiting out throttle periods, which allows
+// the throttle to be tested with a fake clock.
+func OptClock(c clock.Clock) func(*Type) {
+	return func(t *Type) {
+		t.clock = c
+	}
+}
+
 //------------------------------------------------------------------------------
 
 // Retry indicates that a retry is about to occur and, if appropriate, will
@@ -113,7 +127,7 @@ func (t *Type) Retry() bool {
 		return true
 	}
 	select {
-	case <-time.After(time.Duration(atomic.LoadInt64(&t.throttlePeriod))):
+	case <-t.clock.After(time.Duration(atomic.LoadInt64(&t.throttlePeriod))):
 	case <-t.closeChan:
 		return false
 	}
@@ -23,6 +23,8 @@ package throttle
 import (
 	"testing"
 	"time"
+
+	"github.com/Jeffail/benthos/lib/util/clock"
 )
 
 func TestBasicThrottle(t *testing.T) {
@@ -71,6 +73,50 @@ func TestThrottleReset(t *testing.T) {
 	}
 }
 
+func TestThrottleExponentFakeClock(t *testing.T) {
+	fakeClock := clock.NewFake(time.Unix(0, 0))
+
+	throt := New(
+		OptMaxUnthrottledRetries(1),
+		OptMaxExponentPeriod(time.Second*4),
+		OptThrottlePeriod(time.Second),
+		OptClock(fakeClock),
+	)
+
+	if !throt.ExponentialRetry() {
+		t.Fatal("Throttle blocked early")
+	}
+
+	for i, exp := range []time.Duration{
+		time.Second * 2,
+		time.Second * 4,
+		time.Second * 4,
+	} {
+		done := make(chan bool)
+		go func() {
+			done <- throt.ExponentialRetry()
+		}()
+
+		fakeClock.BlockUntil(1)
+		fakeClock.Add(exp - time.Millisecond)
+		select {
+		case <-done:
+			t.Fatalf("Retry %v returned before %v", i, exp)
+		default:
+		}
+
+		fakeClock.Add(time.Millisecond)
+		select {
+		case res := <-done:
+			if !res {
+				t.Errorf("Retry %v was interrupted", i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for retry %v", i)
+		}
+	}
+}
+
 func TestThrottleLinear(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,125 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracecontext
+
+import (
+	"strings"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+const (
+	// TraceParentKey is the header and metadata key of a W3C traceparent.
+	TraceParentKey = "traceparent"
+
+	// TraceStateKey is the header and metadata key of a W3C tracestate.
+	TraceStateKey = "tracestate"
+)
+
+// Documentation is a markdown description of how trace context propagation
+// works for components that support it.
+const Documentation = `### Trace Context
+
+When ` + "`propagate_trace_context`" + ` is set to ` + "`true`" + ` W3C trace
+context headers (` + "`traceparent` and `tracestate`" + `) are carried
+through the component. Inputs extract them into the metadata keys
+` + "`traceparent` and `tracestate`" + `, and outputs inject those metadata
+keys back into the headers of sent messages. A ` + "`tracestate`" + ` is only
+propagated alongside a valid ` + "`traceparent`" + `.`
+
+//------------------------------------------------------------------------------
+
+// ValidTraceParent returns true if a string is a well formed W3C traceparent
+// value, i.e. `{version}-{trace-id}-{parent-id}-{trace-flags}` in lowercase
+// hex where neither the trace nor parent IDs are all zeroes.
+func ValidTraceParent(v string) bool {
+	parts := strings.Split(v, "-")
+	if len(parts) < 4 {
+		return false
+	}
+	if !isHex(parts[0], 2) || parts[0] == "ff" {
+		return false
+	}
+	// Version 00 must contain exactly four fields, future versions may append
+	// more.
+	if parts[0] == "00" && len(parts) != 4 {
+		return false
+	}
+	if !isHex(parts[1], 32) || isZeroes(parts[1]) {
+		return false
+	}
+	if !isHex(parts[2], 16) || isZeroes(parts[2]) {
+		return false
+	}
+	return isHex(parts[3], 2)
+}
+
+func isHex(v string, size int) bool {
+	if len(v) != size {
+		return false
+	}
+	for _, c := range v {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isZeroes(v string) bool {
+	return strings.Trim(v, "0") == ""
+}
+
+//------------------------------------------------------------------------------
+
+// Extract reads trace context values from a header source by calling get with
+// each header key, and if a valid traceparent is found it is written to meta
+// along with any tracestate. Returns true if a trace context was extracted.
+func Extract(get func(key string) string, meta types.Metadata) bool {
+	parent := strings.TrimSpace(get(TraceParentKey))
+	if !ValidTraceParent(parent) {
+		return false
+	}
+	meta.Set(TraceParentKey, parent)
+	if state := strings.TrimSpace(get(TraceStateKey)); len(state) > 0 {
+		meta.Set(TraceStateKey, state)
+	}
+	return true
+}
+
+// Inject reads trace context values from meta and, if a valid traceparent is
+// found, calls set for the traceparent and any tracestate. Returns true if a
+// trace context was injected.
+func Inject(meta types.Metadata, set func(key, value string)) bool {
+	parent := meta.Get(TraceParentKey)
+	if !ValidTraceParent(parent) {
+		return false
+	}
+	set(TraceParentKey, parent)
+	if state := meta.Get(TraceStateKey); len(state) > 0 {
+		set(TraceStateKey, state)
+	}
+	return true
+}
+
+//------------------------------------------------------------------------------
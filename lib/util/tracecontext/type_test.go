@@ -0,0 +1,119 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracecontext
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/message/metadata"
+)
+
+const testParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestValidTraceParent(t *testing.T) {
+	tests := map[string]bool{
+		testParent: true,
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-foo": true,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-foo": false,
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":     false,
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01":     false,
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01":     false,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01":     false,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7":        false,
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01":      false,
+		"": false,
+	}
+
+	for input, exp := range tests {
+		if act := ValidTraceParent(input); act != exp {
+			t.Errorf("Wrong result for '%v': %v != %v", input, act, exp)
+		}
+	}
+}
+
+func TestExtractHTTPHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("Traceparent", testParent)
+	header.Set("Tracestate", "foo=bar")
+
+	meta := metadata.New(nil)
+	if !Extract(header.Get, meta) {
+		t.Fatal("Expected extraction")
+	}
+
+	exp := map[string]string{
+		"traceparent": testParent,
+		"tracestate":  "foo=bar",
+	}
+	act := map[string]string{}
+	meta.Iter(func(k, v string) error {
+		act[k] = v
+		return nil
+	})
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func TestExtractInvalid(t *testing.T) {
+	header := http.Header{}
+	header.Set("Traceparent", "not a trace parent")
+	header.Set("Tracestate", "foo=bar")
+
+	meta := metadata.New(nil)
+	if Extract(header.Get, meta) {
+		t.Error("Unexpected extraction")
+	}
+	if v := meta.Get(TraceStateKey); len(v) > 0 {
+		t.Errorf("Unexpected tracestate: %v", v)
+	}
+}
+
+func TestInject(t *testing.T) {
+	meta := metadata.New(map[string]string{
+		"traceparent": testParent,
+		"tracestate":  "foo=bar",
+		"baz":         "qux",
+	})
+
+	header := http.Header{}
+	if !Inject(meta, header.Set) {
+		t.Fatal("Expected injection")
+	}
+
+	exp := http.Header{
+		"Traceparent": []string{testParent},
+		"Tracestate":  []string{"foo=bar"},
+	}
+	if !reflect.DeepEqual(exp, header) {
+		t.Errorf("Wrong result: %v != %v", header, exp)
+	}
+
+	header = http.Header{}
+	if Inject(metadata.New(nil), header.Set) {
+		t.Error("Unexpected injection")
+	}
+	if len(header) > 0 {
+		t.Errorf("Unexpected headers: %v", header)
+	}
+}
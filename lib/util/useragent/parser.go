@@ -0,0 +1,255 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package useragent
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+//------------------------------------------------------------------------------
+
+// Agent describes the browser or client of a User-Agent.
+type Agent struct {
+	Family string `json:"family"`
+	Major  string `json:"major"`
+	Minor  string `json:"minor"`
+	Patch  string `json:"patch"`
+}
+
+// OS describes the operating system of a User-Agent.
+type OS struct {
+	Family string `json:"family"`
+	Major  string `json:"major"`
+	Minor  string `json:"minor"`
+	Patch  string `json:"patch"`
+}
+
+// Device describes the device of a User-Agent.
+type Device struct {
+	Family string `json:"family"`
+	Brand  string `json:"brand"`
+	Model  string `json:"model"`
+}
+
+// Result is the result of parsing a User-Agent.
+type Result struct {
+	Agent
+	OS     OS     `json:"os"`
+	Device Device `json:"device"`
+	IsBot  bool   `json:"is_bot"`
+}
+
+//------------------------------------------------------------------------------
+
+type regexesFile struct {
+	UserAgentParsers []struct {
+		Regex             string `yaml:"regex"`
+		FamilyReplacement string `yaml:"family_replacement"`
+		V1Replacement     string `yaml:"v1_replacement"`
+		V2Replacement     string `yaml:"v2_replacement"`
+		V3Replacement     string `yaml:"v3_replacement"`
+	} `yaml:"user_agent_parsers"`
+	OSParsers []struct {
+		Regex           string `yaml:"regex"`
+		OSReplacement   string `yaml:"os_replacement"`
+		OSV1Replacement string `yaml:"os_v1_replacement"`
+		OSV2Replacement string `yaml:"os_v2_replacement"`
+		OSV3Replacement string `yaml:"os_v3_replacement"`
+	} `yaml:"os_parsers"`
+	DeviceParsers []struct {
+		Regex             string `yaml:"regex"`
+		RegexFlag         string `yaml:"regex_flag"`
+		DeviceReplacement string `yaml:"device_replacement"`
+		BrandReplacement  string `yaml:"brand_replacement"`
+		ModelReplacement  string `yaml:"model_replacement"`
+	} `yaml:"device_parsers"`
+}
+
+// pattern is a compiled regular expression along with replacements for each
+// field it extracts, where an empty replacement results in a capture group
+// being used instead.
+type pattern struct {
+	re           *regexp.Regexp
+	replacements [4]string
+	groups       [4]int
+}
+
+var (
+	// Agent and OS fields default to the capture group of their position.
+	positionalGroups = [4]int{1, 2, 3, 4}
+
+	// Device family and model default to the first capture group, whereas the
+	// brand is only ever taken from its replacement.
+	deviceGroups = [4]int{1, 0, 1, 0}
+)
+
+// match returns the fields extracted from a string, or false if the pattern
+// does not match.
+func (p *pattern) match(str string) ([4]string, bool) {
+	var fields [4]string
+	groups := p.re.FindStringSubmatch(str)
+	if groups == nil {
+		return fields, false
+	}
+	for i, r := range p.replacements {
+		if len(r) > 0 {
+			fields[i] = expandReplacement(r, groups)
+		} else if g := p.groups[i]; g > 0 && g < len(groups) {
+			fields[i] = groups[g]
+		}
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	return fields, true
+}
+
+// expandReplacement replaces occurrences of $1 to $9 within a replacement
+// string with the corresponding capture groups.
+func expandReplacement(r string, groups []string) string {
+	if !strings.Contains(r, "$") {
+		return r
+	}
+	for i := 1; i <= 9; i++ {
+		var group string
+		if i < len(groups) {
+			group = groups[i]
+		}
+		r = strings.Replace(r, fmt.Sprintf("$%v", i), group, -1)
+	}
+	return r
+}
+
+//------------------------------------------------------------------------------
+
+// Parser parses User-Agent strings.
+type Parser struct {
+	agents  []*pattern
+	oses    []*pattern
+	devices []*pattern
+
+	// InvalidPatterns is the number of patterns that were skipped because they
+	// could not be compiled, which occurs when a pattern uses features not
+	// supported by the Go regular expression syntax, such as lookarounds.
+	InvalidPatterns int
+}
+
+// New creates a Parser from the contents of a regexes.yaml file.
+func New(regexes []byte) (*Parser, error) {
+	var file regexesFile
+	if err := yaml.Unmarshal(regexes, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse regexes: %v", err)
+	}
+
+	p := &Parser{}
+	compile := func(expr string, replacements [4]string, groups [4]int) *pattern {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			p.InvalidPatterns++
+			return nil
+		}
+		return &pattern{re: re, replacements: replacements, groups: groups}
+	}
+
+	for _, a := range file.UserAgentParsers {
+		if pat := compile(a.Regex, [4]string{
+			a.FamilyReplacement, a.V1Replacement, a.V2Replacement, a.V3Replacement,
+		}, positionalGroups); pat != nil {
+			p.agents = append(p.agents, pat)
+		}
+	}
+	for _, o := range file.OSParsers {
+		if pat := compile(o.Regex, [4]string{
+			o.OSReplacement, o.OSV1Replacement, o.OSV2Replacement, o.OSV3Replacement,
+		}, positionalGroups); pat != nil {
+			p.oses = append(p.oses, pat)
+		}
+	}
+	for _, d := range file.DeviceParsers {
+		expr := d.Regex
+		if d.RegexFlag == "i" {
+			expr = "(?i)" + expr
+		}
+		if pat := compile(expr, [4]string{
+			d.DeviceReplacement, d.BrandReplacement, d.ModelReplacement,
+		}, deviceGroups); pat != nil {
+			p.devices = append(p.devices, pat)
+		}
+	}
+
+	if len(p.agents)+len(p.oses)+len(p.devices) == 0 {
+		return nil, fmt.Errorf("no valid patterns found")
+	}
+	return p, nil
+}
+
+// NewDefault creates a Parser from the regexes embedded within Benthos.
+func NewDefault() (*Parser, error) {
+	return New([]byte(defaultRegexes))
+}
+
+//------------------------------------------------------------------------------
+
+// Parse extracts the browser, operating system and device of a User-Agent,
+// fields that cannot be determined are left empty and families that cannot be
+// determined are set to "Other".
+func (p *Parser) Parse(ua string) Result {
+	res := Result{
+		Agent:  Agent{Family: "Other"},
+		OS:     OS{Family: "Other"},
+		Device: Device{Family: "Other"},
+	}
+
+	for _, pat := range p.agents {
+		if fields, ok := pat.match(ua); ok {
+			if len(fields[0]) > 0 {
+				res.Agent.Family = fields[0]
+			}
+			res.Agent.Major, res.Agent.Minor, res.Agent.Patch = fields[1], fields[2], fields[3]
+			break
+		}
+	}
+	for _, pat := range p.oses {
+		if fields, ok := pat.match(ua); ok {
+			if len(fields[0]) > 0 {
+				res.OS.Family = fields[0]
+			}
+			res.OS.Major, res.OS.Minor, res.OS.Patch = fields[1], fields[2], fields[3]
+			break
+		}
+	}
+	for _, pat := range p.devices {
+		if fields, ok := pat.match(ua); ok {
+			if len(fields[0]) > 0 {
+				res.Device.Family = fields[0]
+			}
+			res.Device.Brand, res.Device.Model = fields[1], fields[2]
+			break
+		}
+	}
+
+	res.IsBot = res.Device.Family == "Spider"
+	return res
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,163 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package useragent
+
+import (
+	"reflect"
+	"testing"
+)
+
+//------------------------------------------------------------------------------
+
+var testUserAgents = map[string]Result{
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_14_1) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/70.0.3538.102 Safari/537.36": {
+		Agent:  Agent{Family: "Chrome", Major: "70", Minor: "0", Patch: "3538"},
+		OS:     OS{Family: "Mac OS X", Major: "10", Minor: "14", Patch: "1"},
+		Device: Device{Family: "Mac", Brand: "Apple", Model: "Mac"},
+	},
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/70.0.3538.102 Safari/537.36 Edge/18.17763": {
+		Agent:  Agent{Family: "Edge", Major: "18", Minor: "17763"},
+		OS:     OS{Family: "Windows", Major: "10"},
+		Device: Device{Family: "Other"},
+	},
+	"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:63.0) Gecko/20100101 Firefox/63.0": {
+		Agent:  Agent{Family: "Firefox", Major: "63", Minor: "0"},
+		OS:     OS{Family: "Ubuntu"},
+		Device: Device{Family: "Other"},
+	},
+	"Mozilla/5.0 (iPhone; CPU iPhone OS 12_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.0 Mobile/15E148 Safari/604.1": {
+		Agent:  Agent{Family: "Mobile Safari", Major: "12", Minor: "0"},
+		OS:     OS{Family: "iOS", Major: "12", Minor: "1"},
+		Device: Device{Family: "iPhone", Brand: "Apple", Model: "iPhone"},
+	},
+	"Mozilla/5.0 (Linux; Android 9; SM-G960F Build/PPR1.180610.011) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/70.0.3538.80 Mobile Safari/537.36": {
+		Agent:  Agent{Family: "Chrome Mobile", Major: "70", Minor: "0", Patch: "3538"},
+		OS:     OS{Family: "Android", Major: "9"},
+		Device: Device{Family: "Samsung SM-G960F", Brand: "Samsung", Model: "SM-G960F"},
+	},
+	"Mozilla/5.0 (Linux; Android 9; Pixel 3) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/70.0.3538.80 Mobile Safari/537.36": {
+		Agent:  Agent{Family: "Chrome Mobile", Major: "70", Minor: "0", Patch: "3538"},
+		OS:     OS{Family: "Android", Major: "9"},
+		Device: Device{Family: "Pixel 3", Brand: "Google", Model: "Pixel 3"},
+	},
+	"Mozilla/5.0 (Windows NT 6.1; WOW64; Trident/7.0; rv:11.0) like Gecko": {
+		Agent:  Agent{Family: "IE", Major: "11", Minor: "0"},
+		OS:     OS{Family: "Windows", Major: "7"},
+		Device: Device{Family: "Other"},
+	},
+	"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)": {
+		Agent:  Agent{Family: "Googlebot", Major: "2", Minor: "1"},
+		OS:     OS{Family: "Other"},
+		Device: Device{Family: "Spider", Brand: "Spider", Model: "Desktop"},
+		IsBot:  true,
+	},
+	"curl/7.58.0": {
+		Agent:  Agent{Family: "curl", Major: "7", Minor: "58", Patch: "0"},
+		OS:     OS{Family: "Other"},
+		Device: Device{Family: "Other"},
+	},
+	"": {
+		Agent:  Agent{Family: "Other"},
+		OS:     OS{Family: "Other"},
+		Device: Device{Family: "Other"},
+	},
+}
+
+func TestParserDefault(t *testing.T) {
+	p, err := NewDefault()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.InvalidPatterns != 0 {
+		t.Errorf("Default regexes contain %v invalid patterns", p.InvalidPatterns)
+	}
+
+	for ua, exp := range testUserAgents {
+		if act := p.Parse(ua); !reflect.DeepEqual(exp, act) {
+			t.Errorf("Wrong result for '%v': %+v != %+v", ua, act, exp)
+		}
+	}
+}
+
+func TestParserCustom(t *testing.T) {
+	p, err := New([]byte(`
+user_agent_parsers:
+  - regex: '(?=lookahead)'
+  - regex: '(FooBrowser)/(\d+)\.(\d+)'
+    family_replacement: 'Foo $1'
+    v2_replacement: '9'
+os_parsers:
+  - regex: 'FooOS (\d+)'
+    os_replacement: 'Foo OS'
+    os_v1_replacement: '$1'
+device_parsers:
+  - regex: 'foophone ([a-z]+)'
+    regex_flag: 'i'
+    device_replacement: 'FooPhone'
+    brand_replacement: 'Foo'
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 1, p.InvalidPatterns; exp != act {
+		t.Errorf("Wrong count of invalid patterns: %v != %v", act, exp)
+	}
+
+	exp := Result{
+		Agent:  Agent{Family: "Foo FooBrowser", Major: "3", Minor: "9"},
+		OS:     OS{Family: "Foo OS", Major: "4"},
+		Device: Device{Family: "FooPhone", Brand: "Foo", Model: "X"},
+	}
+	if act := p.Parse("FooBrowser/3.2 (FooOS 4; FOOPHONE X)"); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %+v != %+v", act, exp)
+	}
+}
+
+func TestParserBadRegexes(t *testing.T) {
+	if _, err := New([]byte(`not: [valid`)); err == nil {
+		t.Error("Expected error from invalid yaml")
+	}
+	if _, err := New([]byte(`user_agent_parsers: []`)); err == nil {
+		t.Error("Expected error from empty regexes")
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func BenchmarkParserDefault(b *testing.B) {
+	p, err := NewDefault()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	uas := make([]string, 0, len(testUserAgents))
+	for ua := range testUserAgents {
+		uas = append(uas, ua)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Parse(uas[i%len(uas)])
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,143 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package useragent
+
+// defaultRegexes is a compact set of patterns in the regexes.yaml format that
+// covers common browsers, HTTP clients, crawlers, operating systems and
+// devices. The full database maintained by the ua-parser project can be loaded
+// instead for more thorough coverage.
+const defaultRegexes = `
+user_agent_parsers:
+  # Crawlers
+  - regex: '(Googlebot|bingbot|Baiduspider|YandexBot|DuckDuckBot|AhrefsBot|SemrushBot|Applebot|Twitterbot|facebookexternalhit|LinkedInBot|PetalBot|MJ12bot)/(\d+)(?:\.(\d+))?(?:\.(\d+))?'
+  - regex: '(Yahoo! Slurp)'
+
+  # HTTP clients and libraries
+  - regex: '(curl|Wget|python-requests|Go-http-client|PostmanRuntime|okhttp|Apache-HttpClient|Python-urllib|axios|node-fetch)/(\d+)(?:\.(\d+))?(?:\.(\d+))?'
+  - regex: '^(Java)/(\d+)\.(\d+)(?:\.(\d+))?'
+
+  # Browsers that identify themselves as Chrome must precede it
+  - regex: '(Edge|Edg|EdgA|EdgiOS)/(\d+)(?:\.(\d+))?(?:\.(\d+))?'
+    family_replacement: 'Edge'
+  - regex: '(OPR|OPiOS)/(\d+)\.(\d+)(?:\.(\d+))?'
+    family_replacement: 'Opera'
+  - regex: '(Opera)/.+Version/(\d+)\.(\d+)'
+  - regex: '(SamsungBrowser)/(\d+)\.(\d+)'
+    family_replacement: 'Samsung Internet'
+  - regex: '(YaBrowser)/(\d+)\.(\d+)\.(\d+)'
+    family_replacement: 'Yandex Browser'
+  - regex: '(Vivaldi)/(\d+)\.(\d+)\.(\d+)'
+  - regex: '(UCBrowser)/(\d+)\.(\d+)\.(\d+)'
+    family_replacement: 'UC Browser'
+  - regex: '(CriOS)/(\d+)\.(\d+)\.(\d+)'
+    family_replacement: 'Chrome Mobile iOS'
+  - regex: '(FxiOS)/(\d+)\.(\d+)(?:\.(\d+))?'
+    family_replacement: 'Firefox iOS'
+  - regex: '; wv\).+(Chrome)/(\d+)\.(\d+)\.(\d+)'
+    family_replacement: 'Chrome Mobile WebView'
+  - regex: '(Chrome)/(\d+)\.(\d+)\.(\d+)[\d.]* Mobile'
+    family_replacement: 'Chrome Mobile'
+  - regex: '(HeadlessChrome)/(\d+)\.(\d+)\.(\d+)'
+  - regex: '(Chromium|Chrome)/(\d+)\.(\d+)\.(\d+)'
+
+  # Firefox
+  - regex: '(?:Mobile|Tablet);.+(Firefox)/(\d+)\.(\d+)'
+    family_replacement: 'Firefox Mobile'
+  - regex: '(Firefox)/(\d+)\.(\d+)(?:\.(\d+))?'
+
+  # Internet Explorer
+  - regex: '(Trident)/7\.0.*rv:(\d+)\.(\d+)'
+    family_replacement: 'IE'
+  - regex: '(MSIE) (\d+)\.(\d+)'
+    family_replacement: 'IE'
+
+  # Safari and the Android browser
+  - regex: '(Android) [\d.]+;.*Version/(\d+)\.(\d+)(?:\.(\d+))?.*Safari'
+    family_replacement: 'Android'
+  - regex: '(Version)/(\d+)\.(\d+)(?:\.(\d+))?.*Mobile.*Safari/'
+    family_replacement: 'Mobile Safari'
+  - regex: '(Version)/(\d+)\.(\d+)(?:\.(\d+))?.*Safari/'
+    family_replacement: 'Safari'
+  - regex: '(iPhone|iPad|iPod).*AppleWebKit'
+    family_replacement: 'Mobile Safari UI/WKWebView'
+
+os_parsers:
+  - regex: '(Windows Phone)(?: OS)? (\d+)\.(\d+)'
+  - regex: 'Windows NT 10\.0'
+    os_replacement: 'Windows'
+    os_v1_replacement: '10'
+  - regex: 'Windows NT 6\.3'
+    os_replacement: 'Windows'
+    os_v1_replacement: '8'
+    os_v2_replacement: '1'
+  - regex: 'Windows NT 6\.2'
+    os_replacement: 'Windows'
+    os_v1_replacement: '8'
+  - regex: 'Windows NT 6\.1'
+    os_replacement: 'Windows'
+    os_v1_replacement: '7'
+  - regex: 'Windows NT 6\.0'
+    os_replacement: 'Windows'
+    os_v1_replacement: 'Vista'
+  - regex: 'Windows NT 5\.[12]'
+    os_replacement: 'Windows'
+    os_v1_replacement: 'XP'
+  - regex: '(Windows)'
+  - regex: '(CPU OS|iPhone OS|CPU iPhone OS) (\d+)_(\d+)(?:_(\d+))?'
+    os_replacement: 'iOS'
+  - regex: '(iPhone|iPad|iPod)'
+    os_replacement: 'iOS'
+  - regex: '(Mac OS X) (\d+)[_.](\d+)(?:[_.](\d+))?'
+  - regex: '(Mac OS X)'
+  - regex: '(Android)[ /](\d+)(?:\.(\d+))?(?:\.(\d+))?'
+  - regex: '(Android)'
+  - regex: '(CrOS) [a-z0-9_]+ (\d+)\.(\d+)(?:\.(\d+))?'
+    os_replacement: 'Chrome OS'
+  - regex: '(Ubuntu)(?:[ /](\d+)\.(\d+))?'
+  - regex: '(Fedora|Debian|FreeBSD|OpenBSD|NetBSD)'
+  - regex: '(Linux)'
+
+device_parsers:
+  - regex: '(bot|spider|crawl|slurp|facebookexternalhit)'
+    regex_flag: 'i'
+    device_replacement: 'Spider'
+    brand_replacement: 'Spider'
+    model_replacement: 'Desktop'
+  - regex: '(iPhone)'
+    brand_replacement: 'Apple'
+  - regex: '(iPad)'
+    brand_replacement: 'Apple'
+  - regex: '(iPod)'
+    brand_replacement: 'Apple'
+  - regex: '(Macintosh)'
+    device_replacement: 'Mac'
+    brand_replacement: 'Apple'
+    model_replacement: 'Mac'
+  - regex: '; *(SM-[A-Z0-9]+)'
+    device_replacement: 'Samsung $1'
+    brand_replacement: 'Samsung'
+  - regex: '; *(Pixel[^;)]*?)(?: Build|\))'
+    brand_replacement: 'Google'
+  - regex: '; *(Nexus[^;)]*?)(?: Build|\))'
+    brand_replacement: 'Google'
+  - regex: 'Android [\d.]+; *(?:[a-z]{2}[-_][a-z]{2}; *)?([^;)]+?)(?: Build|\))'
+    brand_replacement: 'Generic_Android'
+`